@@ -0,0 +1,272 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/l1jgo/server/internal/net/packet"
+	"github.com/l1jgo/server/internal/testclient"
+)
+
+// ============================================================
+// botConn — 背景讀取的客戶端（情境腳本、壓測共用）
+// ============================================================
+
+// inboxLimit 是收件匣保留的封包上限。壓測 bot 只等待少數回應，
+// 其餘封包（周圍玩家移動、天氣…）超過上限時丟棄最舊的。
+const inboxLimit = 512
+
+// errTimeout 表示在期限內沒有收到符合條件的封包（連線仍正常）。
+var errTimeout = errors.New("逾時")
+
+// botConn 在 testclient.Client 上加入背景讀取：收到的封包進入收件匣，
+// 呼叫端以條件等待需要的回應，多個客戶端同時運作時不會因讀取互相卡住。
+// dialBot 完成握手後即開始背景讀取，之後不可再直接呼叫 ReadPacket / Drain。
+type botConn struct {
+	*testclient.Client
+
+	charNames []string
+	charName  string
+
+	notify chan struct{} // 有新封包或讀取結束
+
+	mu       sync.Mutex
+	inbox    []received
+	err      error   // 讀取結束原因
+	charID   int32   // S_STATUS 中的自己
+	objects  []int32 // 可見物件（S_PUT_OBJECT 加入、S_REMOVE_OBJECT 移除）
+	lastItem int32   // 最近一個 S_ADD_ITEM 的物品
+	received int
+}
+
+// received 是收件匣中的封包與收到的時間。
+type received struct {
+	pkt *testclient.Packet
+	at  time.Time
+}
+
+func dialBot(addr, label string, verbose bool) (*botConn, error) {
+	c, err := testclient.Dial(addr, label)
+	if err != nil {
+		return nil, err
+	}
+	c.Verbose = verbose
+	if err := c.Handshake(5 * time.Second); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("握手: %w", err)
+	}
+	b := &botConn{Client: c, notify: make(chan struct{}, 1)}
+	go b.readLoop()
+	return b, nil
+}
+
+func (b *botConn) readLoop() {
+	for {
+		p, err := b.ReadPacket(0)
+		b.mu.Lock()
+		if err != nil {
+			b.err = readError(err)
+			b.mu.Unlock()
+			b.signal()
+			return
+		}
+		b.observe(p)
+		if len(b.inbox) >= inboxLimit {
+			b.inbox = slices.Delete(b.inbox, 0, 1)
+		}
+		b.inbox = append(b.inbox, received{pkt: p, at: time.Now()})
+		b.received++
+		b.mu.Unlock()
+		b.signal()
+	}
+}
+
+// readError 去掉連線位址等細節，讓壓測能依原因彙整斷線。
+func readError(err error) error {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		err = opErr.Err
+	}
+	if errors.Is(err, io.EOF) {
+		return errors.New("伺服器關閉連線")
+	}
+	return err
+}
+
+func (b *botConn) signal() {
+	select {
+	case b.notify <- struct{}{}:
+	default:
+	}
+}
+
+// observe 記錄情境步驟會用到的狀態。呼叫時持有 mu。
+func (b *botConn) observe(p *testclient.Packet) {
+	r := p.NewReader()
+	switch p.Opcode() {
+	case packet.S_OPCODE_STATUS:
+		b.charID = r.ReadD()
+	case packet.S_OPCODE_PUT_OBJECT:
+		r.ReadH() // x
+		r.ReadH() // y
+		id := r.ReadD()
+		b.objects = slices.DeleteFunc(b.objects, func(v int32) bool { return v == id })
+		b.objects = append(b.objects, id)
+	case packet.S_OPCODE_REMOVE_OBJECT:
+		id := r.ReadD()
+		b.objects = slices.DeleteFunc(b.objects, func(v int32) bool { return v == id })
+	case packet.S_OPCODE_ADD_ITEM:
+		b.lastItem = r.ReadD()
+	}
+}
+
+// clear 清空收件匣，之後的等待只比對新收到的封包。
+func (b *botConn) clear() {
+	b.mu.Lock()
+	b.inbox = b.inbox[:0]
+	b.mu.Unlock()
+}
+
+// readErr 回傳連線中斷的原因；連線正常時為 nil。
+func (b *botConn) readErr() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.err
+}
+
+// state 回傳自己的 charID、最近看到的其他物件與最近加入背包的物品。
+func (b *botConn) state() (charID, target, item int32) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := len(b.objects) - 1; i >= 0; i-- {
+		if b.objects[i] != b.charID {
+			target = b.objects[i]
+			break
+		}
+	}
+	return b.charID, target, b.lastItem
+}
+
+// receivedCount 回傳已收到的封包數。
+func (b *botConn) receivedCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.received
+}
+
+// wait 等待收件匣中第一個符合 match 的封包並將其取出，一併回傳收到的時間。
+// 逾時回傳 errTimeout；連線中斷回傳其他錯誤。
+func (b *botConn) wait(timeout time.Duration, match func(*testclient.Packet) bool) (*testclient.Packet, time.Time, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		b.mu.Lock()
+		for i, rp := range b.inbox {
+			if match(rp.pkt) {
+				b.inbox = slices.Delete(b.inbox, i, i+1)
+				b.mu.Unlock()
+				return rp.pkt, rp.at, nil
+			}
+		}
+		err := b.err
+		b.mu.Unlock()
+		if err != nil {
+			return nil, time.Time{}, fmt.Errorf("連線中斷: %w", err)
+		}
+		select {
+		case <-b.notify:
+		case <-timer.C:
+			return nil, time.Time{}, errTimeout
+		}
+	}
+}
+
+// waitOp 等待指定 opcode 的封包。
+func (b *botConn) waitOp(timeout time.Duration, opcode byte) (*testclient.Packet, error) {
+	p, _, err := b.waitOpAt(timeout, opcode)
+	return p, err
+}
+
+// waitOpAt 等待指定 opcode 的封包，一併回傳收到的時間。
+func (b *botConn) waitOpAt(timeout time.Duration, opcode byte) (*testclient.Packet, time.Time, error) {
+	p, at, err := b.wait(timeout, func(p *testclient.Packet) bool { return p.Opcode() == opcode })
+	if errors.Is(err, errTimeout) {
+		return nil, at, fmt.Errorf("%w: 未收到 opcode %d (0x%02X)", errTimeout, opcode, opcode)
+	}
+	return p, at, err
+}
+
+// login 完成版本交換、登入並讀取角色列表（可為空）。
+func (b *botConn) login(account, password string) error {
+	if err := b.Version(); err != nil {
+		return err
+	}
+	if _, err := b.waitOp(5*time.Second, packet.S_OPCODE_VERSION_CHECK); err != nil {
+		return fmt.Errorf("版本交換: %w", err)
+	}
+	if err := b.Login(account, password); err != nil {
+		return err
+	}
+	lp, err := b.waitOp(10*time.Second, packet.S_OPCODE_LOGIN_CHECK)
+	if err != nil {
+		return fmt.Errorf("登入: %w", err)
+	}
+	if reason := lp.NewReader().ReadH(); reason != 0 {
+		return fmt.Errorf("登入失敗: reason=%d", reason)
+	}
+
+	np, err := b.waitOp(10*time.Second, packet.S_OPCODE_NUM_CHARACTER)
+	if err != nil {
+		return fmt.Errorf("角色列表: %w", err)
+	}
+	count := int(np.NewReader().ReadC())
+	b.charNames = nil
+	for i := 0; i < count; i++ {
+		cp, err := b.waitOp(5*time.Second, packet.S_OPCODE_CHARACTER_INFO)
+		if err != nil {
+			return fmt.Errorf("讀取角色 #%d: %w", i+1, err)
+		}
+		b.charNames = append(b.charNames, cp.NewReader().ReadS())
+	}
+	return nil
+}
+
+// createChar 建立角色並加入角色列表。
+func (b *botConn) createChar(name string, classType, sex byte, st testclient.Stats) error {
+	if err := b.CreateChar(name, classType, sex, st); err != nil {
+		return err
+	}
+	p, err := b.waitOp(10*time.Second, packet.S_OPCODE_CREATE_CHARACTER_CHECK)
+	if err != nil {
+		return fmt.Errorf("建立角色: %w", err)
+	}
+	if code := p.NewReader().ReadC(); code != 0x02 {
+		reasons := map[byte]string{0x06: "名稱已存在", 0x09: "名稱無效", 0x15: "能力值錯誤"}
+		return fmt.Errorf("建立角色 %s 失敗: code=0x%02X (%s)", name, code, reasons[code])
+	}
+	b.charNames = append(b.charNames, name)
+	return nil
+}
+
+// enter 以指定角色進入世界，等待 S_ENTER_WORLD_CHECK 與自己的 S_STATUS。
+func (b *botConn) enter(name string) error {
+	if err := b.EnterWorld(name); err != nil {
+		return err
+	}
+	if _, err := b.waitOp(10*time.Second, packet.S_OPCODE_ENTER_WORLD_CHECK); err != nil {
+		return fmt.Errorf("進入世界: %w", err)
+	}
+	if _, err := b.waitOp(5*time.Second, packet.S_OPCODE_STATUS); err != nil {
+		return fmt.Errorf("進入世界: %w", err)
+	}
+	b.charName = name
+	return nil
+}
+
+// knightStats 是騎士建角的合法能力分配（總和 75）。
+var knightStats = testclient.Stats{Str: 20, Dex: 12, Con: 14, Wis: 9, Cha: 12, Int: 8}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/l1jgo/server/internal/net/packet"
+	"github.com/l1jgo/server/internal/testclient"
+)
+
+// ============================================================
+// 多 bot 壓測（-bots）
+// ============================================================
+
+const (
+	botReplyTimeout = 5 * time.Second        // 單一動作等待回應的上限，超過計為逾時
+	botMoveGap      = 800 * time.Millisecond // 兩次移動的最短間隔，避免觸發反加速
+)
+
+// 壓測量測的動作（回報順序）。移動沒有對應回應，只計入送出封包數。
+const (
+	actLogin  = "登入"
+	actCreate = "建立角色"
+	actEnter  = "進入世界"
+	actChat   = "聊天"
+	actAlive  = "心跳"
+)
+
+var loadActions = []string{actLogin, actCreate, actEnter, actChat, actAlive}
+
+// loadConfig 是 -bots 壓測的參數。
+type loadConfig struct {
+	addr     string
+	bots     int
+	prefix   string // 帳號與角色名稱 = prefix + 編號
+	password string
+	duration time.Duration
+	ramp     time.Duration // 在此期間內平均啟動所有 bot
+	think    time.Duration // 動作間的平均間隔（實際為 0.5–1.5 倍）
+	seed     int64
+	verbose  bool
+}
+
+// loadStats 彙整所有 bot 的量測結果。
+type loadStats struct {
+	mu          sync.Mutex
+	latency     map[string][]time.Duration
+	timeouts    map[string]int
+	entered     int
+	failures    map[string]int // 未能進入世界的原因
+	disconnects map[string]int // 進入世界後斷線的原因
+	sent        int
+	received    int
+}
+
+func (st *loadStats) observe(action string, d time.Duration) {
+	st.mu.Lock()
+	st.latency[action] = append(st.latency[action], d)
+	st.mu.Unlock()
+}
+
+func (st *loadStats) timeout(action string) {
+	st.mu.Lock()
+	st.timeouts[action]++
+	st.mu.Unlock()
+}
+
+func (st *loadStats) fail(reasons map[string]int, err error) {
+	st.mu.Lock()
+	reasons[err.Error()]++
+	st.mu.Unlock()
+}
+
+// runLoad 依 cfg 啟動 bot，每個 bot 以自己的帳號登入（沒有角色時建立騎士）、
+// 進入世界後隨機移動、聊天、送心跳直到結束，最後印出延遲百分位、逾時、斷線與流量。
+//
+// 所有 bot 來自同一 IP：壓測的伺服器需關閉 [rate_limit]（或調高每 IP 連線數與
+// 登入次數上限），並開啟 auto_create_accounts 或預先建立帳號。
+func runLoad(cfg loadConfig) error {
+	st := &loadStats{
+		latency:     make(map[string][]time.Duration),
+		timeouts:    make(map[string]int),
+		failures:    make(map[string]int),
+		disconnects: make(map[string]int),
+	}
+	start := time.Now()
+	until := start.Add(cfg.ramp + cfg.duration)
+
+	var wg sync.WaitGroup
+	for i := 1; i <= cfg.bots; i++ {
+		if i > 1 && cfg.ramp > 0 {
+			time.Sleep(cfg.ramp / time.Duration(cfg.bots))
+		}
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			runBot(cfg, id, until, st)
+		}(i)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	st.report(cfg, elapsed)
+	if n := cfg.bots - st.entered; n > 0 {
+		return fmt.Errorf("%d 個 bot 未能進入世界", n)
+	}
+	if n := sumCounts(st.disconnects); n > 0 {
+		return fmt.Errorf("%d 個 bot 斷線", n)
+	}
+	return nil
+}
+
+// runBot 是單一 bot 的完整生命週期。
+func runBot(cfg loadConfig, id int, until time.Time, st *loadStats) {
+	rng := rand.New(rand.NewSource(cfg.seed + int64(id)))
+	account := fmt.Sprintf("%s%d", cfg.prefix, id)
+
+	b, err := dialBot(cfg.addr, account, cfg.verbose)
+	if err != nil {
+		st.fail(st.failures, err)
+		return
+	}
+	sent := 0
+	defer func() {
+		b.Close()
+		st.mu.Lock()
+		st.sent += sent
+		st.received += b.receivedCount()
+		st.mu.Unlock()
+	}()
+
+	t := time.Now()
+	if err := b.login(account, cfg.password); err != nil {
+		st.fail(st.failures, err)
+		return
+	}
+	sent += 2
+	st.observe(actLogin, time.Since(t))
+
+	if len(b.charNames) == 0 {
+		t = time.Now()
+		if err := b.createChar(account, 1, byte(id%2), knightStats); err != nil {
+			st.fail(st.failures, err)
+			return
+		}
+		sent++
+		st.observe(actCreate, time.Since(t))
+	}
+
+	t = time.Now()
+	if err := b.enter(b.charNames[0]); err != nil {
+		st.fail(st.failures, err)
+		return
+	}
+	sent++
+	st.observe(actEnter, time.Since(t))
+	st.mu.Lock()
+	st.entered++
+	st.mu.Unlock()
+	charID, _, _ := b.state()
+
+	var lastMove time.Time
+	for n := 0; ; n++ {
+		time.Sleep(cfg.think/2 + time.Duration(rng.Int63n(int64(cfg.think)+1)))
+		if time.Now().After(until) {
+			return
+		}
+
+		// 量測的動作先清空收件匣，避免比對到先前的回應
+		b.clear()
+		action := ""
+		var reply time.Time
+		sentAt := time.Now()
+		switch r := rng.Intn(100); {
+		case r < 50 && sentAt.Sub(lastMove) >= botMoveGap:
+			err = b.Move(byte(rng.Intn(8)))
+			lastMove = sentAt
+		case r < 80:
+			action = actChat
+			nonce := fmt.Sprintf("lt%d-%d", id, n)
+			if err = b.Chat("壓測 " + nonce); err == nil {
+				_, reply, err = b.wait(botReplyTimeout, isOwnSay(charID, nonce))
+			}
+		default:
+			action = actAlive
+			if err = b.Alive(); err == nil {
+				_, reply, err = b.waitOpAt(botReplyTimeout, packet.S_OPCODE_TIME)
+			}
+		}
+		sent++
+
+		switch {
+		case errors.Is(err, errTimeout):
+			st.timeout(action)
+		case err != nil:
+			st.fail(st.disconnects, err)
+			return
+		case action != "":
+			st.observe(action, reply.Sub(sentAt))
+		}
+		if err := b.readErr(); err != nil {
+			st.fail(st.disconnects, err)
+			return
+		}
+	}
+}
+
+// isOwnSay 比對自己送出、內容含 nonce 的 S_SAY。
+func isOwnSay(charID int32, nonce string) func(*testclient.Packet) bool {
+	return func(p *testclient.Packet) bool {
+		if p.Opcode() != packet.S_OPCODE_SAY {
+			return false
+		}
+		r := p.NewReader()
+		r.ReadC() // chatType
+		return r.ReadD() == charID && strings.Contains(r.ReadS(), nonce)
+	}
+}
+
+// report 印出壓測結果。
+func (st *loadStats) report(cfg loadConfig, elapsed time.Duration) {
+	secs := elapsed.Seconds()
+	fmt.Println()
+	fmt.Println("========================================")
+	fmt.Printf("  壓測結果：%d 個 bot，歷時 %s\n", cfg.bots, elapsed.Round(time.Second))
+	fmt.Println("========================================")
+	fmt.Printf("  進入世界 %d/%d，斷線 %d\n", st.entered, cfg.bots, sumCounts(st.disconnects))
+	fmt.Printf("  封包: 送出 %d（%.1f/秒），收到 %d（%.1f/秒）\n\n", st.sent, float64(st.sent)/secs, st.received, float64(st.received)/secs)
+
+	fmt.Println("  動作            次數   逾時      p50      p90      p99      max")
+	for _, action := range loadActions {
+		lat := st.latency[action]
+		if len(lat) == 0 && st.timeouts[action] == 0 {
+			continue
+		}
+		sort.Slice(lat, func(i, j int) bool { return lat[i] < lat[j] })
+		fmt.Printf("  %s%s %6d %6d %8s %8s %8s %8s\n", action, strings.Repeat(" ", 12-2*len([]rune(action))),
+			len(lat), st.timeouts[action],
+			fmtLatency(percentile(lat, 0.50)), fmtLatency(percentile(lat, 0.90)),
+			fmtLatency(percentile(lat, 0.99)), fmtLatency(percentile(lat, 1)))
+	}
+
+	printReasons("未能進入世界", st.failures)
+	printReasons("斷線", st.disconnects)
+	fmt.Println()
+}
+
+// percentile 回傳已排序樣本的第 p 百分位（nearest-rank）。
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[max(i, 0)]
+}
+
+func fmtLatency(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond))
+}
+
+func sumCounts(m map[string]int) int {
+	n := 0
+	for _, c := range m {
+		n += c
+	}
+	return n
+}
+
+// printReasons 依次數由多到少列出錯誤原因（最多 5 種）。
+func printReasons(title string, reasons map[string]int) {
+	if len(reasons) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(reasons))
+	for k := range reasons {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if reasons[keys[i]] != reasons[keys[j]] {
+			return reasons[keys[i]] > reasons[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	fmt.Printf("\n  %s:\n", title)
+	for i, k := range keys {
+		if i == 5 {
+			fmt.Printf("    …另有 %d 種\n", len(keys)-5)
+			break
+		}
+		fmt.Printf("    %4d × %s\n", reasons[k], k)
+	}
+}
@@ -1,33 +1,29 @@
 // testbot 是一個無頭遊戲客戶端，用於自動化功能驗證。
 // 模擬 3.80C 客戶端行為：握手 → 登入 → 選角 → 進入世界，
 // 並可執行移動、戰鬥、NPC 互動、雙人交易等功能測試。
+// -replay 重送伺服器封包擷取檔中的客戶端封包，並依 opcode 比對伺服器回應。
+// -scenario 執行 YAML 情境腳本（範例見 scenarios/）；-bots N 啟動 N 個隨機行動的
+// bot 壓測伺服器，結束時回報延遲百分位、逾時、斷線與流量。
 package main
 
 import (
-	"encoding/binary"
 	"flag"
 	"fmt"
-	"io"
-	"net"
 	"os"
-	"sort"
 	"strings"
 	"time"
 
-	l1net "github.com/l1jgo/server/internal/net"
 	"github.com/l1jgo/server/internal/net/packet"
+	"github.com/l1jgo/server/internal/testclient"
 )
 
 // ============================================================
 // TestClient — 無頭遊戲客戶端
 // ============================================================
 
-// TestClient 模擬 3.80C 客戶端的封包收發。
+// TestClient 在 testclient.Client 的封包層上加入測試場景狀態。
 type TestClient struct {
-	conn    net.Conn
-	cipher  *l1net.Cipher
-	verbose bool
-	label   string // 識別標籤（如 "主帳號"、"副帳號"）
+	*testclient.Client
 
 	// 進入世界後的狀態
 	charNames []string
@@ -35,187 +31,29 @@ type TestClient struct {
 }
 
 // ReceivedPacket 保存接收到的封包原始資料，可多次建立 Reader 解析欄位。
-type ReceivedPacket struct {
-	Raw []byte // 解密後的完整 payload（含 opcode）
-}
-
-// Opcode 回傳封包 opcode。
-func (rp *ReceivedPacket) Opcode() byte {
-	if len(rp.Raw) == 0 {
-		return 0
-	}
-	return rp.Raw[0]
-}
-
-// NewReader 建立新的 Reader，每次呼叫都從頭開始讀取。
-func (rp *ReceivedPacket) NewReader() *packet.Reader {
-	return packet.NewReader(rp.Raw)
-}
+type ReceivedPacket = testclient.Packet
 
 // ============================================================
 // 連線層
 // ============================================================
 
 func dialServer(addr, label string) (*TestClient, error) {
-	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
-	if err != nil {
-		return nil, fmt.Errorf("連線失敗: %w", err)
-	}
-	return &TestClient{conn: conn, label: label}, nil
-}
-
-// readFrame 讀取一個 L1J 框架：[2B LE total_length][payload]
-func (tc *TestClient) readFrame(timeout time.Duration) ([]byte, error) {
-	tc.conn.SetReadDeadline(time.Now().Add(timeout))
-
-	var header [2]byte
-	if _, err := io.ReadFull(tc.conn, header[:]); err != nil {
-		return nil, fmt.Errorf("讀取框架標頭失敗: %w", err)
-	}
-
-	totalLen := int(binary.LittleEndian.Uint16(header[:]))
-	if totalLen < 3 {
-		return nil, fmt.Errorf("框架長度無效: %d", totalLen)
-	}
-
-	payload := make([]byte, totalLen-2)
-	if _, err := io.ReadFull(tc.conn, payload); err != nil {
-		return nil, fmt.Errorf("讀取 payload 失敗: %w", err)
-	}
-
-	return payload, nil
-}
-
-// readPacketRaw 讀取並解密一個封包，回傳 ReceivedPacket 保留原始資料。
-func (tc *TestClient) readPacketRaw(timeout time.Duration) (*ReceivedPacket, error) {
-	payload, err := tc.readFrame(timeout)
+	c, err := testclient.Dial(addr, label)
 	if err != nil {
 		return nil, err
 	}
-
-	if tc.cipher != nil {
-		tc.cipher.Decrypt(payload)
-	}
-
-	if tc.verbose {
-		fmt.Printf("  [%s] ← RX opcode=%d (0x%02X) len=%d\n", tc.label, payload[0], payload[0], len(payload))
-	}
-
-	return &ReceivedPacket{Raw: payload}, nil
-}
-
-// readPacketExpect 讀取封包並驗證 opcode。
-func (tc *TestClient) readPacketExpect(timeout time.Duration, expectedOpcode byte) (*ReceivedPacket, error) {
-	rp, err := tc.readPacketRaw(timeout)
-	if err != nil {
-		return nil, err
-	}
-	if rp.Opcode() != expectedOpcode {
-		return nil, fmt.Errorf("預期 opcode %d (0x%02X), 收到 %d (0x%02X)",
-			expectedOpcode, expectedOpcode, rp.Opcode(), rp.Opcode())
-	}
-	return rp, nil
-}
-
-// sendPacket 加密並發送封包。
-func (tc *TestClient) sendPacket(w *packet.Writer) error {
-	data := w.Bytes()
-
-	if tc.verbose {
-		fmt.Printf("  [%s] → TX opcode=%d (0x%02X) len=%d\n", tc.label, data[0], data[0], len(data))
-	}
-
-	encrypted := make([]byte, len(data))
-	copy(encrypted, data)
-	if tc.cipher != nil {
-		tc.cipher.Encrypt(encrypted)
-	}
-
-	totalLen := uint16(len(encrypted) + 2)
-	var header [2]byte
-	binary.LittleEndian.PutUint16(header[:], totalLen)
-
-	tc.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
-	if _, err := tc.conn.Write(header[:]); err != nil {
-		return fmt.Errorf("寫入標頭失敗: %w", err)
-	}
-	if _, err := tc.conn.Write(encrypted); err != nil {
-		return fmt.Errorf("寫入 payload 失敗: %w", err)
-	}
-	return nil
-}
-
-// drainPackets 在指定時間內持續讀取所有封包，按 opcode 分類。
-// 返回 ReceivedPacket 保留原始位元組，可多次建立 Reader 解析欄位。
-func (tc *TestClient) drainPackets(duration time.Duration) map[byte][]*ReceivedPacket {
-	result := make(map[byte][]*ReceivedPacket)
-	deadline := time.Now().Add(duration)
-	for time.Now().Before(deadline) {
-		remaining := time.Until(deadline)
-		if remaining <= 0 {
-			break
-		}
-		rp, err := tc.readPacketRaw(remaining)
-		if err != nil {
-			break
-		}
-		result[rp.Opcode()] = append(result[rp.Opcode()], rp)
-	}
-	return result
-}
-
-func (tc *TestClient) close() {
-	tc.conn.Close()
-}
-
-// ============================================================
-// 封包發送 — 各類遊戲指令
-// ============================================================
-
-// sendChat 發送 C_CHAT (opcode 40)。GM 指令以 "." 開頭。
-func (tc *TestClient) sendChat(text string) error {
-	w := packet.NewWriterWithOpcode(packet.C_OPCODE_CHAT)
-	w.WriteC(0) // chatType = 0 (normal)
-	w.WriteS(text)
-	return tc.sendPacket(w)
+	return &TestClient{Client: c}, nil
 }
 
 // sendGMCommand 發送 GM 指令並等待回應。
 func (tc *TestClient) sendGMCommand(cmd string, waitTime time.Duration) (map[byte][]*ReceivedPacket, error) {
-	if tc.verbose {
-		fmt.Printf("  [%s] GM> %s\n", tc.label, cmd)
+	if tc.Verbose {
+		fmt.Printf("  [%s] GM> %s\n", tc.Label, cmd)
 	}
-	if err := tc.sendChat(cmd); err != nil {
+	if err := tc.Chat(cmd); err != nil {
 		return nil, fmt.Errorf("發送 GM 指令失敗: %w", err)
 	}
-	return tc.drainPackets(waitTime), nil
-}
-
-// sendMove 發送 C_MOVE (opcode 29)。heading 0-7 代表八方向。
-// 3.80C 客戶端對 heading 做 XOR 0x49 編碼。
-func (tc *TestClient) sendMove(heading byte) error {
-	w := packet.NewWriterWithOpcode(packet.C_OPCODE_MOVE)
-	w.WriteH(0) // clientX（伺服器忽略）
-	w.WriteH(0) // clientY（伺服器忽略）
-	w.WriteC(heading ^ 0x49)
-	return tc.sendPacket(w)
-}
-
-// sendAttack 發送 C_ATTACK (opcode 229) 近戰攻擊。
-func (tc *TestClient) sendAttack(targetID int32) error {
-	w := packet.NewWriterWithOpcode(packet.C_OPCODE_ATTACK)
-	w.WriteD(targetID)
-	w.WriteH(0) // x（伺服器忽略）
-	w.WriteH(0) // y（伺服器忽略）
-	return tc.sendPacket(w)
-}
-
-// sendNPCAction 發送 C_NPCAction (opcode 125)。
-func (tc *TestClient) sendNPCAction(objectID int32, action string) error {
-	w := packet.NewWriterWithOpcode(packet.C_OPCODE_HACTION)
-	w.WriteD(objectID)
-	w.WriteS(action)
-	return tc.sendPacket(w)
+	return tc.Drain(waitTime), nil
 }
 
 // ============================================================
@@ -224,37 +62,18 @@ func (tc *TestClient) sendNPCAction(objectID int32, action string) error {
 
 // TestConnection 驗證 TCP 連線和握手。
 func (tc *TestClient) TestConnection() error {
-	payload, err := tc.readFrame(5 * time.Second)
-	if err != nil {
-		return fmt.Errorf("未收到 InitPacket: %w", err)
-	}
-
-	if len(payload) < 16 {
-		return fmt.Errorf("InitPacket 長度不足: %d (預期 16)", len(payload))
-	}
-
-	if payload[0] != packet.S_OPCODE_INITPACKET {
-		return fmt.Errorf("InitPacket opcode 錯誤: %d (預期 %d)", payload[0], packet.S_OPCODE_INITPACKET)
-	}
-
-	seed := int32(binary.LittleEndian.Uint32(payload[1:5]))
-	if seed <= 0 {
-		return fmt.Errorf("seed 無效: %d", seed)
-	}
-
-	tc.cipher = l1net.NewCipher(seed)
-	return nil
+	return tc.Handshake(5 * time.Second)
 }
 
 // TestLogin 驗證版本交換和登入。
 func (tc *TestClient) TestLogin(account, password string) error {
 	// 發送 C_VERSION
-	if err := tc.sendPacket(packet.NewWriterWithOpcode(packet.C_OPCODE_VERSION)); err != nil {
+	if err := tc.Version(); err != nil {
 		return fmt.Errorf("發送版本失敗: %w", err)
 	}
 
 	// 等待 S_VERSION_CHECK — 驗證欄位值
-	vp, err := tc.readPacketExpect(5*time.Second, packet.S_OPCODE_VERSION_CHECK)
+	vp, err := tc.ReadExpect(5*time.Second, packet.S_OPCODE_VERSION_CHECK)
 	if err != nil {
 		return fmt.Errorf("版本交換失敗: %w", err)
 	}
@@ -273,15 +92,12 @@ func (tc *TestClient) TestLogin(account, password string) error {
 	}
 
 	// 發送 C_LOGIN
-	lw := packet.NewWriterWithOpcode(packet.C_OPCODE_LOGIN)
-	lw.WriteS(account)
-	lw.WriteS(password)
-	if err := tc.sendPacket(lw); err != nil {
+	if err := tc.Login(account, password); err != nil {
 		return fmt.Errorf("發送登入失敗: %w", err)
 	}
 
 	// 等待 S_LOGIN_CHECK — 驗證欄位值
-	lp, err := tc.readPacketExpect(5*time.Second, packet.S_OPCODE_LOGIN_CHECK)
+	lp, err := tc.ReadExpect(5*time.Second, packet.S_OPCODE_LOGIN_CHECK)
 	if err != nil {
 		return fmt.Errorf("登入回應失敗: %w", err)
 	}
@@ -300,7 +116,7 @@ func (tc *TestClient) TestLogin(account, password string) error {
 
 // TestCharList 驗證角色列表。
 func (tc *TestClient) TestCharList() error {
-	np, err := tc.readPacketExpect(10*time.Second, packet.S_OPCODE_NUM_CHARACTER)
+	np, err := tc.ReadExpect(10*time.Second, packet.S_OPCODE_NUM_CHARACTER)
 	if err != nil {
 		return fmt.Errorf("未收到角色數量: %w", err)
 	}
@@ -311,7 +127,7 @@ func (tc *TestClient) TestCharList() error {
 
 	tc.charNames = nil
 	for i := 0; i < int(charCount); i++ {
-		cp, err := tc.readPacketExpect(5*time.Second, packet.S_OPCODE_CHARACTER_INFO)
+		cp, err := tc.ReadExpect(5*time.Second, packet.S_OPCODE_CHARACTER_INFO)
 		if err != nil {
 			return fmt.Errorf("讀取角色 #%d 失敗: %w", i+1, err)
 		}
@@ -333,13 +149,11 @@ func (tc *TestClient) TestEnterWorld() error {
 
 	tc.charName = tc.charNames[0]
 
-	ew := packet.NewWriterWithOpcode(packet.C_OPCODE_ENTER_WORLD)
-	ew.WriteS(tc.charName)
-	if err := tc.sendPacket(ew); err != nil {
+	if err := tc.EnterWorld(tc.charName); err != nil {
 		return fmt.Errorf("發送進入世界失敗: %w", err)
 	}
 
-	packets := tc.drainPackets(10 * time.Second)
+	packets := tc.Drain(10 * time.Second)
 
 	required := []struct {
 		opcode byte
@@ -359,19 +173,19 @@ func (tc *TestClient) TestEnterWorld() error {
 	}
 
 	if len(missing) > 0 {
-		return fmt.Errorf("缺少關鍵封包: %s\n  收到: %s", strings.Join(missing, ", "), formatOpcodeMap(packets))
+		return fmt.Errorf("缺少關鍵封包: %s\n  收到: %s", strings.Join(missing, ", "), testclient.FormatOpcodes(packets))
 	}
 
 	// 驗證 S_WORLD (opcode 206) 的 mapID
 	// 格式: WriteH(mapID) + WriteC(underwater)
 	if wr := getFirstPacket(packets, packet.S_OPCODE_WORLD); wr != nil {
 		mapID := wr.ReadH()
-		if tc.verbose {
+		if tc.Verbose {
 			fmt.Printf("  [驗證] S_WORLD mapID=%d ✓\n", mapID)
 		}
 	}
 
-	if tc.verbose {
+	if tc.Verbose {
 		fmt.Printf("  [驗證] 進入世界封包齊全 ✓\n")
 	}
 	return nil
@@ -383,22 +197,22 @@ func loginFull(addr, account, password, label string, verbose bool) (*TestClient
 	if err != nil {
 		return nil, err
 	}
-	tc.verbose = verbose
+	tc.Verbose = verbose
 
 	if err := tc.TestConnection(); err != nil {
-		tc.close()
+		tc.Close()
 		return nil, fmt.Errorf("握手: %w", err)
 	}
 	if err := tc.TestLogin(account, password); err != nil {
-		tc.close()
+		tc.Close()
 		return nil, fmt.Errorf("登入: %w", err)
 	}
 	if err := tc.TestCharList(); err != nil {
-		tc.close()
+		tc.Close()
 		return nil, fmt.Errorf("角色列表: %w", err)
 	}
 	if err := tc.TestEnterWorld(); err != nil {
-		tc.close()
+		tc.Close()
 		return nil, fmt.Errorf("進入世界: %w", err)
 	}
 	return tc, nil
@@ -412,10 +226,10 @@ func loginFull(addr, account, password, label string, verbose bool) (*TestClient
 // S_SAY (opcode 81) 格式: ReadC(chatType) + ReadD(senderID) + ReadS(message)
 func (tc *TestClient) TestChat() error {
 	testMsg := "testbot 自動測試"
-	if err := tc.sendChat(testMsg); err != nil {
+	if err := tc.Chat(testMsg); err != nil {
 		return fmt.Errorf("發送聊天失敗: %w", err)
 	}
-	pkts := tc.drainPackets(3 * time.Second)
+	pkts := tc.Drain(3 * time.Second)
 	if err := waitForOpcode(pkts, packet.S_OPCODE_SAY, "S_SAY"); err != nil {
 		return err
 	}
@@ -437,7 +251,7 @@ func (tc *TestClient) TestChat() error {
 	if !strings.Contains(msg, expectedSuffix) {
 		return fmt.Errorf("S_SAY 訊息不符: got=%q, 預期包含 %q", msg, expectedSuffix)
 	}
-	if tc.verbose {
+	if tc.Verbose {
 		fmt.Printf("  [驗證] S_SAY chatType=%d senderID=%d msg=%q ✓\n", chatType, senderID, msg)
 	}
 	return nil
@@ -480,7 +294,7 @@ func (tc *TestClient) TestGMItem() error {
 	if invGfx == 0 {
 		return fmt.Errorf("S_AddItem invGfx=0 (圖形 ID 不應為零)")
 	}
-	if tc.verbose {
+	if tc.Verbose {
 		fmt.Printf("  [驗證] S_AddItem objectID=%d count=%d invGfx=%d name=%q ✓\n",
 			objectID, count, invGfx, name)
 	}
@@ -522,12 +336,12 @@ func (tc *TestClient) TestMovement() error {
 	if err != nil {
 		return fmt.Errorf("取得初始位置失敗: %w", err)
 	}
-	if tc.verbose {
+	if tc.Verbose {
 		fmt.Printf("  [驗證] 移動前位置: (%d, %d)\n", x1, y1)
 	}
 
 	// 向南移動（heading 4）
-	if err := tc.sendMove(4); err != nil {
+	if err := tc.Move(4); err != nil {
 		return fmt.Errorf("發送移動失敗: %w", err)
 	}
 
@@ -543,7 +357,7 @@ func (tc *TestClient) TestMovement() error {
 	if err != nil {
 		return fmt.Errorf("取得移動後位置失敗: %w", err)
 	}
-	if tc.verbose {
+	if tc.Verbose {
 		fmt.Printf("  [驗證] 移動後位置: (%d, %d)\n", x2, y2)
 	}
 
@@ -551,7 +365,7 @@ func (tc *TestClient) TestMovement() error {
 	if x1 == x2 && y1 == y2 {
 		return fmt.Errorf("移動後座標未變化: (%d, %d) → (%d, %d)", x1, y1, x2, y2)
 	}
-	if tc.verbose {
+	if tc.Verbose {
 		fmt.Printf("  [驗證] 座標變化確認 (%d,%d)→(%d,%d) ✓\n", x1, y1, x2, y2)
 	}
 	return nil
@@ -571,7 +385,7 @@ func (tc *TestClient) TestCombat() error {
 	_ = spawnPkts
 
 	// 向附近移動以確保在 AOI 範圍內
-	if err := tc.sendMove(0); err != nil {
+	if err := tc.Move(0); err != nil {
 		return err
 	}
 	time.Sleep(300 * time.Millisecond)
@@ -614,26 +428,26 @@ func TestDualClient(addr string, verbose bool) error {
 	if err != nil {
 		return fmt.Errorf("帳號1 登入失敗: %w", err)
 	}
-	defer client1.close()
+	defer client1.Close()
 
 	// 第二個帳號登入
 	client2, err := loginFull(addr, "testbot2", "testbot123", "帳號2", verbose)
 	if err != nil {
 		return fmt.Errorf("帳號2 登入失敗: %w", err)
 	}
-	defer client2.close()
+	defer client2.Close()
 
 	// 把兩個帳號傳送到同一位置
 	client1.sendGMCommand(".move 32630 32744 4", 2*time.Second)
 	client2.sendGMCommand(".move 32630 32744 4", 2*time.Second)
 
 	// 帳號1 移動一步，帳號2 應該收到 S_MoveObject
-	if err := client1.sendMove(0); err != nil {
+	if err := client1.Move(0); err != nil {
 		return fmt.Errorf("帳號1 移動失敗: %w", err)
 	}
 
 	// 帳號2 等待接收 S_MoveObject (opcode 10)
-	pkts := client2.drainPackets(3 * time.Second)
+	pkts := client2.Drain(3 * time.Second)
 	if err := waitForOpcode(pkts, packet.S_OPCODE_MOVE_OBJECT, "S_MoveObject"); err != nil {
 		// 可能兩人不在同一地圖上，不算致命錯誤
 		fmt.Printf("  ⚠️  帳號2 未收到帳號1 的移動封包（可能不在同一地圖）\n")
@@ -648,26 +462,25 @@ func TestDualClient_Trade(addr string, verbose bool) error {
 	if err != nil {
 		return fmt.Errorf("交易方1 登入失敗: %w", err)
 	}
-	defer client1.close()
+	defer client1.Close()
 
 	client2, err := loginFull(addr, "testbot2", "testbot123", "交易方2", verbose)
 	if err != nil {
 		return fmt.Errorf("交易方2 登入失敗: %w", err)
 	}
-	defer client2.close()
+	defer client2.Close()
 
 	// 傳送到同一位置，面對面（heading 互為相反）
 	client1.sendGMCommand(".move 32630 32744 4", 2*time.Second)
 	client2.sendGMCommand(".move 32630 32745 4", 2*time.Second)
 
 	// 帳號1 發起交易（C_ASK_XCHG opcode 2，無額外欄位）
-	w := packet.NewWriterWithOpcode(packet.C_OPCODE_ASK_XCHG)
-	if err := client1.sendPacket(w); err != nil {
+	if err := client1.AskTrade(); err != nil {
 		return fmt.Errorf("發送交易請求失敗: %w", err)
 	}
 
 	// 帳號2 應收到 S_YES_NO (opcode 219) 交易確認
-	pkts := client2.drainPackets(3 * time.Second)
+	pkts := client2.Drain(3 * time.Second)
 	if err := waitForOpcode(pkts, packet.S_OPCODE_YES_NO, "S_YesNo 交易確認"); err != nil {
 		// 交易需要面對面，位置可能不對
 		fmt.Printf("  ⚠️  未收到交易確認（需兩角色面對面站立）\n")
@@ -683,7 +496,7 @@ func TestDualClient_Trade(addr string, verbose bool) error {
 // waitForOpcode 驗證封包 map 中是否存在指定 opcode。
 func waitForOpcode(packets map[byte][]*ReceivedPacket, opcode byte, name string) error {
 	if _, ok := packets[opcode]; !ok {
-		return fmt.Errorf("未收到 %s (opcode %d), 收到: %s", name, opcode, formatOpcodeMap(packets))
+		return fmt.Errorf("未收到 %s (opcode %d), 收到: %s", name, opcode, testclient.FormatOpcodes(packets))
 	}
 	return nil
 }
@@ -696,23 +509,6 @@ func getFirstPacket(packets map[byte][]*ReceivedPacket, opcode byte) *packet.Rea
 	return nil
 }
 
-// formatOpcodeMap 格式化封包 map 供除錯輸出。
-func formatOpcodeMap(packets map[byte][]*ReceivedPacket) string {
-	if len(packets) == 0 {
-		return "(無)"
-	}
-	var keys []int
-	for k := range packets {
-		keys = append(keys, int(k))
-	}
-	sort.Ints(keys)
-	var parts []string
-	for _, k := range keys {
-		parts = append(parts, fmt.Sprintf("%d(0x%02X)×%d", k, k, len(packets[byte(k)])))
-	}
-	return strings.Join(parts, ", ")
-}
-
 // ============================================================
 // 主程式
 // ============================================================
@@ -723,8 +519,82 @@ func main() {
 	password := flag.String("password", "testbot123", "測試密碼")
 	verbose := flag.Bool("v", false, "顯示詳細封包資訊")
 	dual := flag.Bool("dual", false, "執行雙帳號測試（需要 testbot2 帳號+角色）")
+	replay := flag.String("replay", "", "重播封包擷取檔（.l1cap）並比對伺服器回應；帳號需有擷取檔中的角色")
+	speed := flag.Float64("speed", 1, "重播速度倍率（2 = 兩倍速）")
+	settle := flag.Duration("settle", 2*time.Second, "重播進入世界後與送完封包後等待回應的時間")
+	scenario := flag.String("scenario", "", "執行 YAML 情境腳本（範例見 cmd/testbot/scenarios/）")
+	bots := flag.Int("bots", 0, "壓測：同時啟動的 bot 數（伺服器需關閉 rate_limit）")
+	botPrefix := flag.String("bot-prefix", "loadbot", "壓測 bot 的帳號與角色名稱前綴（後接編號）")
+	duration := flag.Duration("duration", time.Minute, "壓測：全部 bot 啟動後持續的時間")
+	ramp := flag.Duration("ramp", 10*time.Second, "壓測：在此期間內平均啟動所有 bot")
+	think := flag.Duration("think", time.Second, "壓測：bot 動作間的平均間隔")
+	seed := flag.Int64("seed", 0, "壓測：bot 行為的亂數種子（0 = 以目前時間為種子）")
 	flag.Parse()
 
+	if *scenario != "" {
+		sc, err := loadScenario(*scenario, *account, *password)
+		if err != nil {
+			fmt.Printf("❌ 情境: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("========================================")
+		fmt.Println("  L1JGO Test Bot — 情境腳本")
+		fmt.Printf("  伺服器: %s\n", *addr)
+		fmt.Printf("  腳本:   %s\n", *scenario)
+		fmt.Println("========================================")
+		fmt.Println()
+		if err := runScenario(*addr, sc, *verbose); err != nil {
+			fmt.Printf("\n❌ 情境: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("✅ 情境通過")
+		return
+	}
+
+	if *bots > 0 {
+		if *seed == 0 {
+			*seed = time.Now().UnixNano()
+		}
+		fmt.Println("========================================")
+		fmt.Println("  L1JGO Test Bot — 多 bot 壓測")
+		fmt.Printf("  伺服器: %s\n", *addr)
+		fmt.Printf("  bot:    %d 個（%s1…%s%d），%s 內啟動，持續 %s\n", *bots, *botPrefix, *botPrefix, *bots, *ramp, *duration)
+		fmt.Printf("  種子:   %d\n", *seed)
+		fmt.Println("========================================")
+		err := runLoad(loadConfig{
+			addr:     *addr,
+			bots:     *bots,
+			prefix:   *botPrefix,
+			password: *password,
+			duration: *duration,
+			ramp:     *ramp,
+			think:    *think,
+			seed:     *seed,
+			verbose:  *verbose,
+		})
+		if err != nil {
+			fmt.Printf("❌ 壓測: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("✅ 壓測完成，無斷線")
+		return
+	}
+
+	if *replay != "" {
+		fmt.Println("========================================")
+		fmt.Println("  L1JGO Test Bot — 封包擷取重播")
+		fmt.Printf("  伺服器: %s\n", *addr)
+		fmt.Printf("  帳號:   %s\n", *account)
+		fmt.Println("========================================")
+		fmt.Println()
+		if err := runReplay(*addr, *account, *password, *replay, *speed, *settle, *verbose); err != nil {
+			fmt.Printf("❌ 重播: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("✅ 重播回應一致")
+		return
+	}
+
 	fmt.Println("========================================")
 	fmt.Println("  L1JGO Test Bot — 自動化功能驗證")
 	fmt.Printf("  伺服器: %s\n", *addr)
@@ -737,8 +607,8 @@ func main() {
 		fmt.Printf("❌ 無法連線到伺服器: %v\n", err)
 		os.Exit(1)
 	}
-	defer client.close()
-	client.verbose = *verbose
+	defer client.Close()
+	client.Verbose = *verbose
 
 	type testCase struct {
 		name string
@@ -774,7 +644,7 @@ func main() {
 
 	// === 雙帳號測試（可選）===
 	if *dual {
-		client.close() // 關閉第一個連線，雙帳號測試自己管理連線
+		client.Close() // 關閉第一個連線，雙帳號測試自己管理連線
 
 		dualTests := []testCase{
 			{"D1 雙帳號同時在線", func() error { return TestDualClient(*addr, *verbose) }},
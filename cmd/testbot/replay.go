@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	l1net "github.com/l1jgo/server/internal/net"
+	"github.com/l1jgo/server/internal/net/packet"
+)
+
+// ============================================================
+// 擷取重播（-replay）
+// ============================================================
+
+// runReplay 登入指定帳號並以擷取檔的角色進入世界，依原始間隔（除以 speed）
+// 重送擷取到的客戶端封包，最後依 opcode 比對伺服器回應數量。
+//
+// 擷取從角色進入世界（capture_characters）或 GM .capture 開始，因此擷取檔
+// 不含登入封包；帳號需擁有與擷取檔同名的角色。物件 ID（攻擊目標、物品）
+// 在不同伺服器執行間不同，相關回應的差異是預期的。
+func runReplay(addr, account, password, path string, speed float64, settle time.Duration, verbose bool) error {
+	capture, err := l1net.ReadCapture(path)
+	if capture == nil {
+		return err
+	}
+	if err != nil {
+		fmt.Printf("  ⚠️  擷取檔尾端不完整（%v），重播已讀到的 %d 筆\n", err, len(capture.Records))
+	}
+	if speed <= 0 {
+		speed = 1
+	}
+
+	var sends []l1net.CaptureRecord
+	var prelude, expected []byte
+	for _, rec := range capture.Records {
+		if len(rec.Payload) == 0 {
+			continue
+		}
+		switch {
+		case rec.Dir == l1net.CaptureC2S:
+			sends = append(sends, rec)
+		case len(sends) == 0:
+			prelude = append(prelude, rec.Payload[0])
+		default:
+			expected = append(expected, rec.Payload[0])
+		}
+	}
+	// 擷取在進入世界時開始：第一個客戶端封包之前的回應就是進入世界的回應，一併比對
+	withEnter := false
+	for _, op := range prelude {
+		if op == packet.S_OPCODE_ENTER_WORLD_CHECK {
+			withEnter = true
+		}
+	}
+	if withEnter {
+		expected = append(prelude, expected...)
+	}
+
+	fmt.Printf("  擷取檔: %s\n", path)
+	fmt.Printf("  角色:   %s（擷取於 %s）\n", capture.Label, capture.Start.Format("2006-01-02 15:04:05"))
+	fmt.Printf("  客戶端封包 %d 個，伺服器回應 %d 個\n\n", len(sends), len(expected))
+
+	tc, err := dialServer(addr, "重播")
+	if err != nil {
+		return err
+	}
+	defer tc.Close()
+	tc.Verbose = verbose
+	if err := tc.TestConnection(); err != nil {
+		return fmt.Errorf("握手: %w", err)
+	}
+	if err := tc.TestLogin(account, password); err != nil {
+		return fmt.Errorf("登入: %w", err)
+	}
+	if err := tc.TestCharList(); err != nil {
+		return fmt.Errorf("角色列表: %w", err)
+	}
+	found := false
+	for _, name := range tc.charNames {
+		found = found || name == capture.Label
+	}
+	if !found {
+		return fmt.Errorf("帳號 %s 沒有角色 %s", account, capture.Label)
+	}
+
+	// 背景讀取所有回應
+	var mu sync.Mutex
+	var got []byte
+	enterDone := make(chan struct{})
+	go func() {
+		entered := false
+		for {
+			p, err := tc.ReadPacket(0)
+			if err != nil {
+				if !entered {
+					close(enterDone)
+				}
+				return
+			}
+			mu.Lock()
+			got = append(got, p.Opcode())
+			mu.Unlock()
+			if !entered && p.Opcode() == packet.S_OPCODE_ENTER_WORLD_CHECK {
+				entered = true
+				close(enterDone)
+			}
+		}
+	}()
+
+	if err := tc.EnterWorld(capture.Label); err != nil {
+		return fmt.Errorf("進入世界: %w", err)
+	}
+	select {
+	case <-enterDone:
+	case <-time.After(10 * time.Second):
+		return fmt.Errorf("進入世界: 未收到 S_ENTER_WORLD_CHECK")
+	}
+	// 進入世界的其餘封包在同一 tick 送出，等待收齊
+	time.Sleep(settle)
+	if !withEnter {
+		mu.Lock()
+		got = got[:0]
+		mu.Unlock()
+	}
+
+	start := time.Now()
+	base := time.Duration(0)
+	if len(sends) > 0 {
+		base = sends[0].At
+	}
+	for i, rec := range sends {
+		due := time.Duration(float64(rec.At-base) / speed)
+		if wait := due - time.Since(start); wait > 0 {
+			time.Sleep(wait)
+		}
+		if err := tc.SendRaw(rec.Payload); err != nil {
+			return fmt.Errorf("重送第 %d 個封包（opcode %d）失敗: %w", i+1, rec.Payload[0], err)
+		}
+	}
+	time.Sleep(settle)
+
+	mu.Lock()
+	replayed := append([]byte(nil), got...)
+	mu.Unlock()
+	return diffOpcodes(expected, replayed)
+}
+
+// diffOpcodes 依 opcode 比對擷取與重播的伺服器回應數量，有差異時回傳錯誤。
+func diffOpcodes(expected, replayed []byte) error {
+	var want, have [256]int
+	for _, op := range expected {
+		want[op]++
+	}
+	for _, op := range replayed {
+		have[op]++
+	}
+	var diffs []int
+	same := 0
+	for op := 0; op < 256; op++ {
+		switch {
+		case want[op] != have[op]:
+			diffs = append(diffs, op)
+		case want[op] > 0:
+			same++
+		}
+	}
+	sort.Ints(diffs)
+
+	fmt.Printf("  伺服器回應: 擷取 %d 個，重播 %d 個；%d 種 opcode 數量一致\n", len(expected), len(replayed), same)
+	if len(diffs) == 0 {
+		return nil
+	}
+	fmt.Println()
+	fmt.Println("  opcode        擷取   重播   差")
+	for _, op := range diffs {
+		fmt.Printf("  %3d (0x%02X)  %5d  %5d  %+4d\n", op, op, want[op], have[op], have[op]-want[op])
+	}
+	fmt.Println()
+	return fmt.Errorf("%d 種 opcode 的回應數量不同", len(diffs))
+}
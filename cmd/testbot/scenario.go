@@ -0,0 +1,449 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/l1jgo/server/internal/net/packet"
+	"github.com/l1jgo/server/internal/testclient"
+)
+
+// ============================================================
+// 情境腳本（-scenario）
+// ============================================================
+
+// Scenario 是一份 YAML 情境腳本：一或多個客戶端依序執行步驟。
+// 範例見 cmd/testbot/scenarios/。
+type Scenario struct {
+	Name    string           `yaml:"name"`
+	Clients []ScenarioClient `yaml:"clients"` // 省略時使用 -account / -password 的單一客戶端
+	Steps   []Step           `yaml:"steps"`
+}
+
+// ScenarioClient 是情境中的一個連線。
+type ScenarioClient struct {
+	Label    string `yaml:"label"`
+	Account  string `yaml:"account"`
+	Password string `yaml:"password"`
+}
+
+// Step 是一個步驟：client 指定執行的客戶端（省略為第一個），其餘欄位恰好填一個。
+// 送出封包的步驟會先清空該客戶端的收件匣，之後的 expect 只比對這之後的回應。
+type Step struct {
+	Client string `yaml:"client"`
+
+	Login  bool          `yaml:"login"`  // 版本交換、登入、讀取角色列表
+	Create *CreateStep   `yaml:"create"` // 建立角色（已存在同名角色時略過）
+	Enter  *string       `yaml:"enter"`  // 以角色進入世界；空字串為第一個角色
+	Walk   *WalkStep     `yaml:"walk"`
+	Attack *AttackStep   `yaml:"attack"`
+	Chat   string        `yaml:"chat"`
+	GM     string        `yaml:"gm"` // GM 指令（含開頭的 "."）
+	Trade  *TradeStep    `yaml:"trade"`
+	Expect *ExpectStep   `yaml:"expect"`
+	Wait   time.Duration `yaml:"wait"`
+}
+
+// CreateStep 建立角色。class 0=王族 1=騎士…（預設騎士）；stats 依序為
+// 力 敏 體 精 魅 智，省略時使用騎士的能力分配。
+type CreateStep struct {
+	Name  string `yaml:"name"`
+	Class *byte  `yaml:"class"`
+	Sex   byte   `yaml:"sex"`
+	Stats []byte `yaml:"stats"`
+}
+
+// WalkStep 依序朝 path 的方向（0-7，0=北、順時針）各走一步。
+type WalkStep struct {
+	Path     []byte        `yaml:"path"`
+	Interval time.Duration `yaml:"interval"` // 每步間隔，預設 800ms（低於外型步行速度會被反加速拒絕）
+}
+
+// AttackStep 近戰攻擊。target 為 0 時攻擊最近出現在畫面上的其他物件。
+type AttackStep struct {
+	Target   int32         `yaml:"target"`
+	Times    int           `yaml:"times"`    // 預設 1
+	Interval time.Duration `yaml:"interval"` // 預設 1s
+}
+
+// TradeStep 與 with 客戶端交易（兩角色需面對面）：發起、對方同意、放上物品、雙方確認，
+// 並驗證雙方收到交易完成。item 為 0 時交易最近一個 S_ADD_ITEM 的物品。
+type TradeStep struct {
+	With  string `yaml:"with"`
+	Item  int32  `yaml:"item"`
+	Count int32  `yaml:"count"` // 預設 1
+}
+
+// ExpectStep 等待 opcodes 中的每個封包（順序不拘），共用 within 期限（預設 3s）。
+type ExpectStep struct {
+	Opcodes []byte        `yaml:"opcodes"`
+	Within  time.Duration `yaml:"within"`
+}
+
+// loadScenario 讀取並檢查情境腳本。
+func loadScenario(path, account, password string) (*Scenario, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var sc Scenario
+	if err := yaml.Unmarshal(raw, &sc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if sc.Name == "" {
+		sc.Name = path
+	}
+	if len(sc.Clients) == 0 {
+		sc.Clients = []ScenarioClient{{Label: "主帳號", Account: account, Password: password}}
+	}
+	labels := make(map[string]bool)
+	for i, c := range sc.Clients {
+		if c.Label == "" || c.Account == "" {
+			return nil, fmt.Errorf("%s: 第 %d 個客戶端缺少 label 或 account", path, i+1)
+		}
+		if labels[c.Label] {
+			return nil, fmt.Errorf("%s: 客戶端 %q 重複", path, c.Label)
+		}
+		labels[c.Label] = true
+	}
+	if len(sc.Steps) == 0 {
+		return nil, fmt.Errorf("%s: 沒有步驟", path)
+	}
+	for i := range sc.Steps {
+		st := &sc.Steps[i]
+		if st.Client == "" {
+			st.Client = sc.Clients[0].Label
+		}
+		if err := st.check(labels); err != nil {
+			return nil, fmt.Errorf("%s: 步驟 %d: %w", path, i+1, err)
+		}
+	}
+	return &sc, nil
+}
+
+func (st *Step) check(labels map[string]bool) error {
+	if !labels[st.Client] {
+		return fmt.Errorf("未定義的客戶端 %q", st.Client)
+	}
+	actions := 0
+	for _, set := range []bool{
+		st.Login, st.Create != nil, st.Enter != nil, st.Walk != nil, st.Attack != nil,
+		st.Chat != "", st.GM != "", st.Trade != nil, st.Expect != nil, st.Wait > 0,
+	} {
+		if set {
+			actions++
+		}
+	}
+	if actions != 1 {
+		return fmt.Errorf("每個步驟需恰好一個動作（目前 %d 個）", actions)
+	}
+	switch {
+	case st.Create != nil:
+		if st.Create.Name == "" {
+			return errors.New("create 缺少 name")
+		}
+		if len(st.Create.Stats) != 0 && len(st.Create.Stats) != 6 {
+			return errors.New("create.stats 需為六項能力值")
+		}
+		if len(st.Create.Stats) == 0 && st.Create.Class != nil && *st.Create.Class != 1 {
+			return errors.New("非騎士職業需指定 create.stats")
+		}
+	case st.Walk != nil:
+		for _, h := range st.Walk.Path {
+			if h > 7 {
+				return fmt.Errorf("walk 方向 %d 超出 0-7", h)
+			}
+		}
+	case st.Trade != nil:
+		if !labels[st.Trade.With] || st.Trade.With == st.Client {
+			return fmt.Errorf("trade.with %q 需為另一個客戶端", st.Trade.With)
+		}
+	case st.Expect != nil:
+		if len(st.Expect.Opcodes) == 0 {
+			return errors.New("expect 缺少 opcodes")
+		}
+	}
+	return nil
+}
+
+// String 回傳步驟的簡短描述。
+func (st *Step) String() string {
+	switch {
+	case st.Login:
+		return "登入"
+	case st.Create != nil:
+		return fmt.Sprintf("建立角色 %s", st.Create.Name)
+	case st.Enter != nil:
+		if *st.Enter == "" {
+			return "進入世界（第一個角色）"
+		}
+		return fmt.Sprintf("進入世界 %s", *st.Enter)
+	case st.Walk != nil:
+		return fmt.Sprintf("行走 %v", st.Walk.Path)
+	case st.Attack != nil:
+		return fmt.Sprintf("攻擊 ×%d", max(st.Attack.Times, 1))
+	case st.Chat != "":
+		return fmt.Sprintf("聊天 %q", st.Chat)
+	case st.GM != "":
+		return fmt.Sprintf("GM %s", st.GM)
+	case st.Trade != nil:
+		return fmt.Sprintf("與 %s 交易", st.Trade.With)
+	case st.Expect != nil:
+		return fmt.Sprintf("等待 opcode %v", st.Expect.Opcodes)
+	default:
+		return fmt.Sprintf("等待 %s", st.Wait)
+	}
+}
+
+// scenarioRun 是執行中的情境：每個客戶端一個連線，並記錄最後一次送出封包的時間，
+// expect 步驟以此計算回應延遲。
+type scenarioRun struct {
+	conns    map[string]*botConn
+	accounts map[string]ScenarioClient
+	lastSent map[string]time.Time
+}
+
+// runScenario 連線情境中的所有客戶端並依序執行步驟，第一個失敗的步驟即中止。
+func runScenario(addr string, sc *Scenario, verbose bool) error {
+	run := &scenarioRun{
+		conns:    make(map[string]*botConn),
+		accounts: make(map[string]ScenarioClient),
+		lastSent: make(map[string]time.Time),
+	}
+	defer func() {
+		for _, b := range run.conns {
+			b.Close()
+		}
+	}()
+	for _, c := range sc.Clients {
+		b, err := dialBot(addr, c.Label, verbose)
+		if err != nil {
+			return fmt.Errorf("%s: %w", c.Label, err)
+		}
+		run.conns[c.Label] = b
+		run.accounts[c.Label] = c
+	}
+
+	fmt.Printf("情境: %s（%d 個客戶端，%d 個步驟）\n\n", sc.Name, len(sc.Clients), len(sc.Steps))
+	start := time.Now()
+	for i := range sc.Steps {
+		st := &sc.Steps[i]
+		stepStart := time.Now()
+		note, err := run.step(st)
+		if err != nil {
+			fmt.Printf("❌ [%2d] %s %s: %v\n", i+1, st.Client, st, err)
+			return fmt.Errorf("步驟 %d 失敗", i+1)
+		}
+		line := fmt.Sprintf("✅ [%2d] %s %s（%s）", i+1, st.Client, st, time.Since(stepStart).Round(time.Millisecond))
+		if note != "" {
+			line += "  " + note
+		}
+		fmt.Println(line)
+	}
+	fmt.Printf("\n%d 個步驟全部通過，耗時 %s\n", len(sc.Steps), time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// sent 在送出封包前清空收件匣並記錄時間。
+func (run *scenarioRun) sent(label string) *botConn {
+	b := run.conns[label]
+	b.clear()
+	run.lastSent[label] = time.Now()
+	return b
+}
+
+// step 執行一個步驟，回傳附加說明。
+func (run *scenarioRun) step(st *Step) (string, error) {
+	b := run.conns[st.Client]
+	switch {
+	case st.Login:
+		acc := run.accounts[st.Client]
+		if err := run.sent(st.Client).login(acc.Account, acc.Password); err != nil {
+			return "", err
+		}
+		if len(b.charNames) == 0 {
+			return "沒有角色", nil
+		}
+		return fmt.Sprintf("角色: %s", strings.Join(b.charNames, ", ")), nil
+
+	case st.Create != nil:
+		for _, name := range b.charNames {
+			if name == st.Create.Name {
+				return "已存在，略過", nil
+			}
+		}
+		class, stats := byte(1), knightStats
+		if st.Create.Class != nil {
+			class = *st.Create.Class
+		}
+		if s := st.Create.Stats; len(s) == 6 {
+			stats = testclient.Stats{Str: s[0], Dex: s[1], Con: s[2], Wis: s[3], Cha: s[4], Int: s[5]}
+		}
+		return "", run.sent(st.Client).createChar(st.Create.Name, class, st.Create.Sex, stats)
+
+	case st.Enter != nil:
+		name := *st.Enter
+		if name == "" {
+			if len(b.charNames) == 0 {
+				return "", errors.New("帳號沒有角色")
+			}
+			name = b.charNames[0]
+		}
+		if err := run.sent(st.Client).enter(name); err != nil {
+			return "", err
+		}
+		charID, _, _ := b.state()
+		return fmt.Sprintf("charID=%d", charID), nil
+
+	case st.Walk != nil:
+		interval := st.Walk.Interval
+		if interval <= 0 {
+			interval = 800 * time.Millisecond
+		}
+		run.sent(st.Client)
+		for i, h := range st.Walk.Path {
+			if i > 0 {
+				time.Sleep(interval)
+			}
+			if err := b.Move(h); err != nil {
+				return "", err
+			}
+		}
+		return "", nil
+
+	case st.Attack != nil:
+		target := st.Attack.Target
+		if target == 0 {
+			_, target, _ = b.state()
+			if target == 0 {
+				return "", errors.New("畫面上沒有可攻擊的物件")
+			}
+		}
+		interval := st.Attack.Interval
+		if interval <= 0 {
+			interval = time.Second
+		}
+		run.sent(st.Client)
+		for i := 0; i < max(st.Attack.Times, 1); i++ {
+			if i > 0 {
+				time.Sleep(interval)
+			}
+			if err := b.Attack(target); err != nil {
+				return "", err
+			}
+		}
+		return fmt.Sprintf("目標 %d", target), nil
+
+	case st.Chat != "":
+		return "", run.sent(st.Client).Chat(st.Chat)
+
+	case st.GM != "":
+		return "", run.sent(st.Client).Chat(st.GM)
+
+	case st.Trade != nil:
+		return run.trade(st.Client, st.Trade)
+
+	case st.Expect != nil:
+		return run.expect(st.Client, st.Expect)
+
+	default:
+		time.Sleep(st.Wait)
+		return "", nil
+	}
+}
+
+// trade 執行完整交易流程，對應伺服器的 S_YES_NO(252) → S_TRADE → S_TRADEADDITEM → S_TRADESTATUS。
+func (run *scenarioRun) trade(label string, ts *TradeStep) (string, error) {
+	item := ts.Item
+	if item == 0 {
+		_, _, item = run.conns[label].state()
+		if item == 0 {
+			return "", errors.New("沒有可交易的物品（先以 .item 取得或指定 item）")
+		}
+	}
+	count := ts.Count
+	if count <= 0 {
+		count = 1
+	}
+	a := run.sent(label)
+	b := run.sent(ts.With)
+
+	if err := a.AskTrade(); err != nil {
+		return "", err
+	}
+	if _, _, err := b.wait(3*time.Second, isYesNo(252)); err != nil {
+		return "", fmt.Errorf("%s 未收到交易確認（兩角色需面對面）: %w", ts.With, err)
+	}
+	if err := b.Answer(252, true); err != nil {
+		return "", err
+	}
+	for _, c := range []*botConn{a, b} {
+		if _, err := c.waitOp(3*time.Second, packet.S_OPCODE_TRADE); err != nil {
+			return "", fmt.Errorf("%s 交易視窗: %w", c.Label, err)
+		}
+	}
+	if err := a.AddTrade(item, count); err != nil {
+		return "", err
+	}
+	if _, err := b.waitOp(3*time.Second, packet.S_OPCODE_TRADEADDITEM); err != nil {
+		return "", fmt.Errorf("%s 未看到交易物品: %w", ts.With, err)
+	}
+	if err := a.AcceptTrade(); err != nil {
+		return "", err
+	}
+	if err := b.AcceptTrade(); err != nil {
+		return "", err
+	}
+	for _, c := range []*botConn{a, b} {
+		p, err := c.waitOp(3*time.Second, packet.S_OPCODE_TRADESTATUS)
+		if err != nil {
+			return "", fmt.Errorf("%s 交易結果: %w", c.Label, err)
+		}
+		if status := p.NewReader().ReadC(); status != 0 {
+			return "", fmt.Errorf("%s 交易未完成: status=%d", c.Label, status)
+		}
+	}
+	return fmt.Sprintf("物品 %d ×%d", item, count), nil
+}
+
+// isYesNo 比對指定訊息編號的 S_YES_NO 對話框。
+func isYesNo(msgType uint16) func(*testclient.Packet) bool {
+	return func(p *testclient.Packet) bool {
+		if p.Opcode() != packet.S_OPCODE_YES_NO {
+			return false
+		}
+		r := p.NewReader()
+		r.ReadH()
+		r.ReadD() // counter
+		return r.ReadH() == msgType
+	}
+}
+
+// expect 等待每個 opcode，並回報距該客戶端上一次送出封包的延遲。
+func (run *scenarioRun) expect(label string, ex *ExpectStep) (string, error) {
+	within := ex.Within
+	if within <= 0 {
+		within = 3 * time.Second
+	}
+	b := run.conns[label]
+	deadline := time.Now().Add(within)
+	var lat []string
+	for _, op := range ex.Opcodes {
+		_, at, err := b.waitOpAt(max(time.Until(deadline), 0), op)
+		if err != nil {
+			return "", err
+		}
+		if sent, ok := run.lastSent[label]; ok {
+			lat = append(lat, fmt.Sprintf("%d:%s", op, at.Sub(sent).Round(time.Millisecond)))
+		}
+	}
+	if len(lat) == 0 {
+		return "", nil
+	}
+	return "距送出 " + strings.Join(lat, " "), nil
+}
@@ -0,0 +1,74 @@
+// Package admin 提供本機管理用的 HTTP/JSON API（線上玩家、踢人、公告、給物品、傳送、存檔）。
+//
+// HTTP handler 在各自的 goroutine 中執行，絕不直接觸碰 world.State：
+// 每個請求包裝成 job 放入佇列，由 system.AdminSystem 在遊戲迴圈 tick 中
+// 呼叫 Drain 執行，結果再回傳給等待中的 HTTP handler。存檔另以資料庫工作
+// 執行，工作完成後才回傳結果，不阻塞遊戲迴圈。
+package admin
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Actions 是管理操作在遊戲迴圈端的實作（system.AdminActions）。
+// 所有方法只在遊戲迴圈 goroutine 上呼叫。
+type Actions interface {
+	// Players 列出線上玩家。
+	Players() []Player
+	// Kick 斷開玩家連線（斷線流程照常存檔並移出世界）。
+	Kick(name string) error
+	// Announce 對全體線上玩家廣播公告，回傳收到的人數。
+	Announce(text string) int
+	// GiveItem 給予線上玩家物品。
+	GiveItem(name string, itemID, count int32, enchant int8) error
+	// Teleport 傳送線上玩家。
+	Teleport(name string, x, y int32, mapID int16) error
+	// Save 以資料庫工作存檔；name 為空時存檔所有線上玩家。done 於遊戲迴圈收到存檔人數。
+	Save(name string, done func(saved int, err error))
+}
+
+// Player 是 /api/players 回傳的玩家摘要。
+type Player struct {
+	CharID    int32  `json:"char_id"`
+	Name      string `json:"name"`
+	Account   string `json:"account"`
+	IP        string `json:"ip"`
+	Level     int16  `json:"level"`
+	ClassType int16  `json:"class_type"`
+	X         int32  `json:"x"`
+	Y         int32  `json:"y"`
+	MapID     int16  `json:"map_id"`
+	HP        int32  `json:"hp"`
+	MaxHP     int32  `json:"max_hp"`
+	Dead      bool   `json:"dead"`
+	ClanName  string `json:"clan_name,omitempty"`
+}
+
+// Error 是帶 HTTP 狀態碼的操作錯誤。
+type Error struct {
+	Status int
+	Msg    string
+}
+
+func (e *Error) Error() string { return e.Msg }
+
+// NotFound 回傳 404 錯誤（例如玩家不在線）。
+func NotFound(format string, args ...any) error {
+	return &Error{Status: http.StatusNotFound, Msg: fmt.Sprintf(format, args...)}
+}
+
+// BadRequest 回傳 400 錯誤（參數無效）。
+func BadRequest(format string, args ...any) error {
+	return &Error{Status: http.StatusBadRequest, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Conflict 回傳 409 錯誤（玩家狀態不允許，例如背包已滿）。
+func Conflict(format string, args ...any) error {
+	return &Error{Status: http.StatusConflict, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Internal 回傳 500 錯誤（例如資料庫寫入失敗）。
+func Internal(format string, args ...any) error {
+	return &Error{Status: http.StatusInternalServerError, Msg: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,291 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	queueSize   = 64               // 等待遊戲迴圈處理的請求上限
+	waitTimeout = 30 * time.Second // 等待 tick 執行結果的上限（含全體存檔）
+	maxBodySize = 64 << 10
+)
+
+type result struct {
+	value any
+	err   error
+}
+
+// job 是一個排入遊戲迴圈的管理操作。fn 以 reply 回傳結果，可在之後的 tick 才呼叫
+// （例如存檔的資料庫工作完成時）。done 有緩衝，逾時離開的 handler 不會卡住遊戲迴圈。
+type job struct {
+	name string
+	fn   func(a Actions, reply func(any, error))
+	done chan result
+}
+
+// reply 回傳結果；只有第一次呼叫有效。
+func (j *job) reply(v any, err error) {
+	select {
+	case j.done <- result{value: v, err: err}:
+	default:
+	}
+}
+
+// Server 是管理 API 端點。
+type Server struct {
+	srv   *http.Server
+	ln    net.Listener
+	token string
+	queue chan *job
+	log   *zap.Logger
+}
+
+// NewServer 在 addr 上監聽。token 為必填，請求須帶 "Authorization: Bearer <token>"。
+func NewServer(addr, token string, log *zap.Logger) (*Server, error) {
+	if token == "" {
+		return nil, errors.New("admin api: token is required")
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("admin listen %s: %w", addr, err)
+	}
+	if tcp, ok := ln.Addr().(*net.TCPAddr); ok && !tcp.IP.IsLoopback() {
+		log.Warn("管理 API 未綁定本機位址，請確認防火牆設定", zap.String("addr", tcp.String()))
+	}
+
+	s := &Server{
+		ln:    ln,
+		token: token,
+		queue: make(chan *job, queueSize),
+		log:   log,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/players", s.handlePlayers)
+	mux.HandleFunc("POST /api/kick", s.handleKick)
+	mux.HandleFunc("POST /api/announce", s.handleAnnounce)
+	mux.HandleFunc("POST /api/give", s.handleGive)
+	mux.HandleFunc("POST /api/teleport", s.handleTeleport)
+	mux.HandleFunc("POST /api/save", s.handleSave)
+	s.srv = &http.Server{
+		Handler:           s.auth(mux),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return s, nil
+}
+
+// Addr 回傳實際監聽位址。
+func (s *Server) Addr() net.Addr { return s.ln.Addr() }
+
+// Serve 阻塞直到 Shutdown。
+func (s *Server) Serve() {
+	if err := s.srv.Serve(s.ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		s.log.Error("管理 API 停止", zap.Error(err))
+	}
+}
+
+// Shutdown 關閉端點。
+func (s *Server) Shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_ = s.srv.Shutdown(ctx)
+}
+
+// Drain 執行所有排隊中的請求。只在遊戲迴圈 goroutine 呼叫（AdminSystem）。
+func (s *Server) Drain(a Actions) {
+	for {
+		select {
+		case j := <-s.queue:
+			s.run(j, a)
+		default:
+			return
+		}
+	}
+}
+
+// run 執行單一請求並攔截 panic，避免管理操作拖垮遊戲迴圈。
+func (s *Server) run(j *job, a Actions) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			s.log.Error("管理操作 panic 已恢復", zap.String("action", j.name), zap.Any("panic", rec))
+			j.reply(nil, fmt.Errorf("internal error: %v", rec))
+		}
+	}()
+	j.fn(a, j.reply)
+}
+
+// exec 把操作排入遊戲迴圈並等待 reply 回傳結果。
+func (s *Server) exec(r *http.Request, name string, fn func(Actions, func(any, error))) (any, error) {
+	j := &job{name: name, fn: fn, done: make(chan result, 1)}
+	select {
+	case s.queue <- j:
+	default:
+		return nil, &Error{Status: http.StatusServiceUnavailable, Msg: "admin queue full"}
+	}
+
+	timer := time.NewTimer(waitTimeout)
+	defer timer.Stop()
+	select {
+	case res := <-j.done:
+		return res.value, res.err
+	case <-timer.C:
+		return nil, &Error{Status: http.StatusGatewayTimeout, Msg: "game loop did not respond"}
+	case <-r.Context().Done():
+		return nil, r.Context().Err()
+	}
+}
+
+func (s *Server) auth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) != 1 {
+			s.log.Warn("管理 API 驗證失敗", zap.String("remote", r.RemoteAddr), zap.String("path", r.URL.Path))
+			writeJSON(w, http.StatusUnauthorized, map[string]any{"ok": false, "error": "unauthorized"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ---------- handlers ----------
+
+func (s *Server) handlePlayers(w http.ResponseWriter, r *http.Request) {
+	s.respond(w, r, "players", nil, func(a Actions) (any, error) {
+		players := a.Players()
+		return map[string]any{"count": len(players), "players": players}, nil
+	})
+}
+
+type kickRequest struct {
+	Name string `json:"name"`
+}
+
+func (s *Server) handleKick(w http.ResponseWriter, r *http.Request) {
+	var req kickRequest
+	s.respond(w, r, "kick", &req, func(a Actions) (any, error) {
+		if req.Name == "" {
+			return nil, BadRequest("name is required")
+		}
+		return nil, a.Kick(req.Name)
+	})
+}
+
+type announceRequest struct {
+	Message string `json:"message"`
+}
+
+func (s *Server) handleAnnounce(w http.ResponseWriter, r *http.Request) {
+	var req announceRequest
+	s.respond(w, r, "announce", &req, func(a Actions) (any, error) {
+		if strings.TrimSpace(req.Message) == "" {
+			return nil, BadRequest("message is required")
+		}
+		return map[string]any{"recipients": a.Announce(req.Message)}, nil
+	})
+}
+
+type giveRequest struct {
+	Name    string `json:"name"`
+	ItemID  int32  `json:"item_id"`
+	Count   int32  `json:"count"`
+	Enchant int8   `json:"enchant"`
+}
+
+func (s *Server) handleGive(w http.ResponseWriter, r *http.Request) {
+	var req giveRequest
+	s.respond(w, r, "give", &req, func(a Actions) (any, error) {
+		if req.Count == 0 {
+			req.Count = 1
+		}
+		if req.Name == "" || req.ItemID <= 0 || req.Count < 0 {
+			return nil, BadRequest("name, item_id and a positive count are required")
+		}
+		return nil, a.GiveItem(req.Name, req.ItemID, req.Count, req.Enchant)
+	})
+}
+
+type teleportRequest struct {
+	Name  string `json:"name"`
+	X     int32  `json:"x"`
+	Y     int32  `json:"y"`
+	MapID int16  `json:"map_id"`
+}
+
+func (s *Server) handleTeleport(w http.ResponseWriter, r *http.Request) {
+	var req teleportRequest
+	s.respond(w, r, "teleport", &req, func(a Actions) (any, error) {
+		if req.Name == "" {
+			return nil, BadRequest("name is required")
+		}
+		return nil, a.Teleport(req.Name, req.X, req.Y, req.MapID)
+	})
+}
+
+type saveRequest struct {
+	Name string `json:"name"` // 空字串 = 全體線上玩家
+}
+
+func (s *Server) handleSave(w http.ResponseWriter, r *http.Request) {
+	var req saveRequest
+	// 存檔以資料庫工作執行，不阻塞遊戲迴圈；結果於工作完成時回傳
+	s.respondAsync(w, r, "save", &req, func(a Actions, reply func(any, error)) {
+		a.Save(req.Name, func(n int, err error) {
+			if err != nil {
+				reply(nil, err)
+				return
+			}
+			reply(map[string]any{"saved": n}, nil)
+		})
+	})
+}
+
+// respond 解析請求本文（body 可為 nil）、排入遊戲迴圈、寫回 JSON 並記錄操作。
+func (s *Server) respond(w http.ResponseWriter, r *http.Request, name string, body any, fn func(Actions) (any, error)) {
+	s.respondAsync(w, r, name, body, func(a Actions, reply func(any, error)) {
+		reply(fn(a))
+	})
+}
+
+// respondAsync 與 respond 相同，但 fn 以 reply 回傳結果，可延到之後的 tick。
+func (s *Server) respondAsync(w http.ResponseWriter, r *http.Request, name string, body any, fn func(Actions, func(any, error))) {
+	if body != nil && r.ContentLength != 0 {
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(body); err != nil && !errors.Is(err, io.EOF) {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "invalid json: " + err.Error()})
+			return
+		}
+	}
+
+	v, err := s.exec(r, name, fn)
+	if err != nil {
+		status := http.StatusInternalServerError
+		var ae *Error
+		if errors.As(err, &ae) {
+			status = ae.Status
+		}
+		s.log.Info("管理操作失敗", zap.String("action", name), zap.String("remote", r.RemoteAddr), zap.Error(err))
+		writeJSON(w, status, map[string]any{"ok": false, "error": err.Error()})
+		return
+	}
+	if name != "players" {
+		s.log.Info("管理操作", zap.String("action", name), zap.String("remote", r.RemoteAddr), zap.Any("request", body))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "result": v})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
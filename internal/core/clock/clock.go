@@ -0,0 +1,53 @@
+// Package clock 提供可注入的時間來源。遊戲邏輯（遊戲時間、冷卻、攻城排程、
+// 拍賣截止等）透過 Clock 讀取時間，正式伺服器使用實際時間，模擬環境使用可手動
+// 推進的 Sim，讓同一組輸入重播出相同的結果。
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock 是時間來源。
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+// Real 回傳系統時鐘。
+func Real() Clock { return realClock{} }
+
+// Sim 是只在呼叫 Advance / Set 時才前進的時鐘。可跨 goroutine 讀取。
+type Sim struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewSim 建立從 start 開始的模擬時鐘。
+func NewSim(start time.Time) *Sim {
+	return &Sim{now: start}
+}
+
+// Now 回傳目前的模擬時間。
+func (s *Sim) Now() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.now
+}
+
+// Advance 把模擬時間往前推 d。
+func (s *Sim) Advance(d time.Duration) {
+	s.mu.Lock()
+	s.now = s.now.Add(d)
+	s.mu.Unlock()
+}
+
+// Set 把模擬時間設為 t。
+func (s *Sim) Set(t time.Time) {
+	s.mu.Lock()
+	s.now = t
+	s.mu.Unlock()
+}
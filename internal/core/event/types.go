@@ -1,6 +1,10 @@
 package event
 
-import "github.com/l1jgo/server/internal/core/ecs"
+import (
+	"time"
+
+	"github.com/l1jgo/server/internal/core/ecs"
+)
 
 // --- Session lifecycle events ---
 
@@ -44,3 +48,45 @@ type PlayerKilled struct {
 	MapID        int16
 	X, Y         int32
 }
+
+// --- Item provenance events ---
+
+// ItemMoved is emitted whenever an item instance is created, changes owner or
+// location, or is destroyed. Subscribers: ItemAuditSystem (item_events table).
+type ItemMoved struct {
+	ObjID      int32
+	ItemID     int32
+	Count      int32
+	EnchantLvl int8
+	From       string // "char:<id>", "npc:<id>", "ground", "warehouse:…" — empty when created
+	To         string // same forms — empty when destroyed
+	Reason     string // ItemReason* constant
+	MapID      int16
+	X, Y       int32
+	At         time.Time
+}
+
+// ItemMoved reasons.
+const (
+	ItemReasonDrop         = "drop"          // monster drop
+	ItemReasonShopBuy      = "shop_buy"      // bought from an NPC shop
+	ItemReasonShopSell     = "shop_sell"     // sold to an NPC shop
+	ItemReasonCraft        = "craft"         // crafted (output, bonus or residue)
+	ItemReasonCraftUse     = "craft_use"     // consumed as crafting material
+	ItemReasonGMCreate     = "gm_create"     // GM item command or admin API grant
+	ItemReasonTrade        = "trade"         // player trade
+	ItemReasonPrivateShop  = "private_shop"  // private shop purchase
+	ItemReasonWarehouseIn  = "wh_deposit"    // deposited into a warehouse
+	ItemReasonWarehouseOut = "wh_withdraw"   // withdrawn from a warehouse
+	ItemReasonGroundDrop   = "ground_drop"   // dropped on the ground (incl. PK death drop)
+	ItemReasonPickup       = "pickup"        // picked up from the ground
+	ItemReasonEnchant      = "enchant"       // enchant succeeded (enchant level is the new one)
+	ItemReasonEnchantBreak = "enchant_break" // enchant failed and the item evaporated
+	ItemReasonDestroy      = "destroy"       // deleted by the player
+	ItemReasonQuarantine   = "quarantine"    // duplicate copy moved to item_quarantine
+	ItemReasonGroundExpire = "ground_expire" // left on the ground until it expired
+	ItemReasonScript       = "script"        // granted or taken by a Lua script
+	ItemReasonQuest        = "quest"         // quest reward, or quest item handed in
+	ItemReasonNpcService   = "npc_service"   // item upgrade / refine (materials consumed, result created)
+	ItemReasonConsume      = "consume"       // used up by a feature (ticket, bait, cooking material, pet taming)
+)
@@ -0,0 +1,164 @@
+package system
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// profileWindow is the number of ticks kept for rolling percentiles
+// (300 ticks = 60 seconds at the default 200ms tick rate).
+const profileWindow = 300
+
+// slowTickLogInterval rate-limits watchdog warnings while the server is
+// hitching; ticks overrunning in between are counted and reported with the
+// next warning.
+const slowTickLogInterval = 5 * time.Second
+
+// slowTickTopN is how many systems a watchdog warning names.
+const slowTickTopN = 3
+
+// ring is a fixed-size window of durations.
+type ring struct {
+	buf  []time.Duration
+	next int
+	full bool
+}
+
+func newRing(n int) *ring {
+	return &ring{buf: make([]time.Duration, n)}
+}
+
+func (r *ring) add(d time.Duration) {
+	r.buf[r.next] = d
+	r.next++
+	if r.next == len(r.buf) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+// stats computes percentiles over the current window.
+func (r *ring) stats() (n int, p50, p95, p99, max time.Duration) {
+	n = r.next
+	if r.full {
+		n = len(r.buf)
+	}
+	if n == 0 {
+		return 0, 0, 0, 0, 0
+	}
+	s := make([]time.Duration, n)
+	copy(s, r.buf[:n])
+	sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+	pct := func(p int) time.Duration { return s[(n-1)*p/100] }
+	return n, pct(50), pct(95), pct(99), s[n-1]
+}
+
+// entry is a registered system with its timing window.
+type entry struct {
+	sys     System
+	name    string
+	last    time.Duration
+	samples *ring
+}
+
+func newEntry(s System) *entry {
+	return &entry{sys: s, name: systemName(s), samples: newRing(profileWindow)}
+}
+
+// systemName returns the bare type name, e.g. "NpcAISystem".
+func systemName(s System) string {
+	name := fmt.Sprintf("%T", s)
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.TrimPrefix(name, "*")
+}
+
+// SystemStats is a rolling timing summary for one system (or the whole tick).
+type SystemStats struct {
+	Name    string
+	Phase   Phase
+	Samples int
+	Last    time.Duration
+	P50     time.Duration
+	P95     time.Duration
+	P99     time.Duration
+	Max     time.Duration
+}
+
+// TickProfile is a snapshot of the Runner's timing statistics.
+type TickProfile struct {
+	Tick     SystemStats   // whole Tick
+	Poll     SystemStats   // Phase 0 polling between ticks
+	Systems  []SystemStats // sorted by P95, most expensive first
+	Budget   time.Duration // watchdog threshold
+	Overruns int64         // ticks that exceeded Budget since start
+}
+
+// SetWatchdog enables the slow-tick warning: a Tick taking longer than
+// budget (normally network.tick_rate) logs the top offending systems.
+func (r *Runner) SetWatchdog(budget time.Duration, log *zap.Logger) {
+	r.budget = budget
+	r.log = log
+}
+
+// Profile returns rolling per-system statistics over the last profileWindow ticks.
+func (r *Runner) Profile() TickProfile {
+	p := TickProfile{
+		Budget:   r.budget,
+		Overruns: r.overruns,
+		Systems:  make([]SystemStats, 0, len(r.systems)),
+	}
+	p.Tick = summarize("Tick", 0, r.tick, 0)
+	p.Poll = summarize("InputPoll", PhaseInput, r.poll, 0)
+	for _, e := range r.systems {
+		p.Systems = append(p.Systems, summarize(e.name, e.sys.Phase(), e.samples, e.last))
+	}
+	sort.SliceStable(p.Systems, func(i, j int) bool { return p.Systems[i].P95 > p.Systems[j].P95 })
+	return p
+}
+
+func summarize(name string, phase Phase, r *ring, last time.Duration) SystemStats {
+	n, p50, p95, p99, max := r.stats()
+	return SystemStats{Name: name, Phase: phase, Samples: n, Last: last, P50: p50, P95: p95, P99: p99, Max: max}
+}
+
+// checkOverrun logs a structured warning when a tick exceeded the budget.
+func (r *Runner) checkOverrun(total time.Duration) {
+	if r.budget <= 0 || total <= r.budget {
+		return
+	}
+	r.overruns++
+	if r.log == nil {
+		return
+	}
+	now := time.Now()
+	if now.Sub(r.lastWarn) < slowTickLogInterval {
+		r.suppressed++
+		return
+	}
+
+	top := make([]*entry, len(r.systems))
+	copy(top, r.systems)
+	sort.Slice(top, func(i, j int) bool { return top[i].last > top[j].last })
+	if len(top) > slowTickTopN {
+		top = top[:slowTickTopN]
+	}
+	offenders := make([]string, len(top))
+	for i, e := range top {
+		offenders[i] = e.name + "=" + e.last.String()
+	}
+
+	r.log.Warn("slow tick",
+		zap.Duration("took", total),
+		zap.Duration("budget", r.budget),
+		zap.Strings("top", offenders),
+		zap.Int("suppressed", r.suppressed),
+		zap.Int64("overruns", r.overruns))
+	r.lastWarn = now
+	r.suppressed = 0
+}
@@ -3,48 +3,76 @@ package system
 import (
 	"sort"
 	"time"
+
+	"go.uber.org/zap"
 )
 
 // Runner executes systems in phase order each tick.
+// Every Update is timed; see profile.go for the rolling statistics and the
+// slow-tick watchdog.
 type Runner struct {
-	systems []System
+	systems []*entry
 	sorted  bool
+
+	tick     *ring // total duration of each full Tick
+	poll     *ring // Phase 0 polling time accumulated between full ticks
+	pollAcc  time.Duration
+	overruns int64
+
+	budget     time.Duration // watchdog threshold (0 = disabled)
+	log        *zap.Logger
+	lastWarn   time.Time
+	suppressed int
 }
 
 func NewRunner() *Runner {
 	return &Runner{
-		systems: make([]System, 0, 16),
+		systems: make([]*entry, 0, 16),
+		tick:    newRing(profileWindow),
+		poll:    newRing(profileWindow),
 	}
 }
 
 func (r *Runner) Register(s System) {
-	r.systems = append(r.systems, s)
+	r.systems = append(r.systems, newEntry(s))
 	r.sorted = false
 }
 
 func (r *Runner) Tick(dt time.Duration) {
 	r.ensureSorted()
-	for _, s := range r.systems {
-		s.Update(dt)
+	start := time.Now()
+	for _, e := range r.systems {
+		t := time.Now()
+		e.sys.Update(dt)
+		e.last = time.Since(t)
+		e.samples.add(e.last)
 	}
+	total := time.Since(start)
+	r.tick.add(total)
+	r.poll.add(r.pollAcc)
+	r.pollAcc = 0
+	r.checkOverrun(total)
 }
 
 // TickPhase 只執行指定 Phase 的 System。
 // 用於高頻輸入輪詢：在系統 tick 之間只跑 Phase 0，
 // 讓封包處理延遲從 0~200ms 降至 0~2ms，同時保持架構合規。
+// 耗時累計到下一次 Tick 的輪詢統計，不計入各 System 的每 tick 取樣。
 func (r *Runner) TickPhase(phase Phase, dt time.Duration) {
 	r.ensureSorted()
-	for _, s := range r.systems {
-		if s.Phase() == phase {
-			s.Update(dt)
+	start := time.Now()
+	for _, e := range r.systems {
+		if e.sys.Phase() == phase {
+			e.sys.Update(dt)
 		}
 	}
+	r.pollAcc += time.Since(start)
 }
 
 func (r *Runner) ensureSorted() {
 	if !r.sorted {
-		sort.Slice(r.systems, func(i, j int) bool {
-			return r.systems[i].Phase() < r.systems[j].Phase()
+		sort.SliceStable(r.systems, func(i, j int) bool {
+			return r.systems[i].sys.Phase() < r.systems[j].sys.Phase()
 		})
 		r.sorted = true
 	}
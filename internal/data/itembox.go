@@ -81,7 +81,7 @@ func (t *ItemBoxTable) Count() int {
 
 // RollBox 從隨機寶箱中抽取一個物品。
 // Java: BoxRandom.runItem() — 最多嘗試 300 次。
-func (t *ItemBoxTable) RollBox(boxItemID int32) *BoxItem {
+func (t *ItemBoxTable) RollBox(rng *rand.Rand, boxItemID int32) *BoxItem {
 	items := t.boxMap[boxItemID]
 	if len(items) == 0 {
 		return nil
@@ -91,14 +91,14 @@ func (t *ItemBoxTable) RollBox(boxItemID int32) *BoxItem {
 	copy(candidates, items)
 
 	for attempt := 0; attempt < 300 && len(candidates) > 0; attempt++ {
-		idx := rand.Intn(len(candidates))
+		idx := rng.Intn(len(candidates))
 		item := &candidates[idx]
 
 		if item.RandomInt <= 0 {
 			return item
 		}
 
-		roll := rand.Int31n(item.RandomInt)
+		roll := rng.Int31n(item.RandomInt)
 		if roll < item.Random {
 			return item
 		}
@@ -110,7 +110,7 @@ func (t *ItemBoxTable) RollBox(boxItemID int32) *BoxItem {
 	}
 
 	// 300 次都沒中 → 隨機返回一個
-	idx := rand.Intn(len(items))
+	idx := rng.Intn(len(items))
 	return &items[idx]
 }
 
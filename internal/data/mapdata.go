@@ -319,6 +319,16 @@ func (t *MapDataTable) IsPassableIgnoreOccupant(mapID int16, x, y int32, heading
 	return false
 }
 
+// IsPassablePointIgnoreOccupant is like IsPassablePoint but ignores the dynamic
+// tileImpassable flag, so a tile someone is standing on still counts as passable.
+// Used to validate teleport destinations.
+func (t *MapDataTable) IsPassablePointIgnoreOccupant(mapID int16, x, y int32) bool {
+	return t.IsPassableIgnoreOccupant(mapID, x, y-1, 4) ||
+		t.IsPassableIgnoreOccupant(mapID, x+1, y, 6) ||
+		t.IsPassableIgnoreOccupant(mapID, x, y+1, 0) ||
+		t.IsPassableIgnoreOccupant(mapID, x-1, y, 2)
+}
+
 // SetImpassable sets or clears the dynamic impassable flag (for mob blocking).
 func (t *MapDataTable) SetImpassable(mapID int16, x, y int32, blocked bool) {
 	e := t.maps[mapID]
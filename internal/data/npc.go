@@ -39,6 +39,7 @@ type NpcTemplate struct {
 	WindRes      int16  `yaml:"wind_res"`   // 風抗
 	EarthRes     int16  `yaml:"earth_res"`  // 地抗
 	LightSize    int16  `yaml:"light_size"` // 光源半徑（0=無光源）
+	AI           string `yaml:"ai"`         // Lua AI 設定檔（scripts/ai 的 AI_PROFILES 名稱，空=default）
 }
 
 // SpawnEntry defines where and how many NPCs to spawn.
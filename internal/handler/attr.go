@@ -145,7 +145,7 @@ func handleResurrectionResponse(sess *net.Session, player *world.PlayerInfo, acc
 
 	sendHpUpdate(sess, player)
 	sendMpUpdate(sess, player)
-	SendPlayerStatus(sess, player)
+	SendPlayerStatus(sess, player, deps)
 	SendPutObject(sess, player)
 
 	nearbyTarget := deps.World.GetNearbyPlayersAt(player.X, player.Y, player.MapID)
@@ -4,10 +4,10 @@ import (
 	"context"
 	"fmt"
 	"strings"
-	"time"
 
 	"github.com/l1jgo/server/internal/net"
 	"github.com/l1jgo/server/internal/net/packet"
+	"github.com/l1jgo/server/internal/persist"
 	"go.uber.org/zap"
 )
 
@@ -49,13 +49,25 @@ func HandleAuthDirect(sess *net.Session, r *packet.Reader, deps *Deps) {
 	handleLogin(sess, r, deps, false)
 }
 
+// loginsInFlight 記錄正在非同步驗證中的帳號，防止同一帳號的兩個連線
+// 同時通過「未上線」檢查（遊戲迴圈專用）。
+var loginsInFlight = make(map[string]bool)
+
+// loginOutcome 是登入工作在 worker 端的結果。
+type loginOutcome struct {
+	code     byte // loginOK 或失敗碼
+	created  bool
+	banned   bool
+	ban      *persist.BanRow // bans 表中生效的封鎖（nil = 僅 accounts.banned）
+	chars    []persist.CharacterRow // nil = 角色列表載入失敗（不發送）
+	maxSlots int
+}
+
 func handleLogin(sess *net.Session, r *packet.Reader, deps *Deps, auto bool) {
 	accountName := strings.ToLower(r.ReadS())
 	password := r.ReadS()
 	ip := sess.IP
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	host := sess.Host()
 
 	// 根據來源選擇錯誤碼（Java: auto=true → 155/149, auto=false → 8）
 	noAccountCode := loginWrongPass
@@ -65,67 +77,134 @@ func handleLogin(sess *net.Session, r *packet.Reader, deps *Deps, auto bool) {
 		wrongPassCode = loginAutoWrongPass
 	}
 
-	// Load account
-	account, err := deps.AccountRepo.Load(ctx, accountName)
-	if err != nil {
-		deps.Log.Error("載入帳號資料庫錯誤", zap.Error(err))
-		sendLoginResult(sess, wrongPassCode)
+	// 每 IP 登入嘗試限制：在 bcrypt 驗證前拒絕
+	if deps.LoginLimit != nil {
+		if ok, locked := deps.LoginLimit.Allow(host, deps.Clock.Now()); !ok {
+			if locked {
+				deps.Log.Warn(fmt.Sprintf("登入嘗試過於頻繁，鎖定 IP  ip=%s  帳號=%s", host, accountName))
+			}
+			// 客戶端沒有「嘗試過於頻繁」的原因碼：回與密碼錯誤相同的失敗碼，
+			// 不讓攻擊端分辨是被鎖定還是密碼錯誤
+			sendLoginResult(sess, wrongPassCode)
+			return
+		}
+	}
+
+	if loginsInFlight[accountName] {
+		sendLoginResult(sess, loginAlreadyExists)
 		return
 	}
+	loginsInFlight[accountName] = true
+
+	// 帳號載入、bcrypt 驗證、上線標記與角色列表在 worker 執行
+	autoCreate := deps.Config.Character.AutoCreateAccounts
+	defaultSlots := deps.Config.Character.DefaultSlots
+	out := loginOutcome{code: wrongPassCode} // 工作未執行（佇列已滿）時視為失敗
+	work := func(ctx context.Context) error {
+		account, err := deps.AccountRepo.Load(ctx, accountName)
+		if err != nil {
+			out.code = wrongPassCode
+			return fmt.Errorf("載入帳號: %w", err)
+		}
 
-	// Auto-create if enabled
-	if account == nil {
-		if deps.Config.Character.AutoCreateAccounts {
+		// Auto-create if enabled
+		if account == nil {
+			if !autoCreate {
+				out.code = noAccountCode
+				return nil
+			}
 			account, err = deps.AccountRepo.Create(ctx, accountName, password, ip, ip)
 			if err != nil {
-				deps.Log.Error("建立帳號資料庫錯誤", zap.Error(err))
-				sendLoginResult(sess, wrongPassCode)
-				return
+				out.code = wrongPassCode
+				return fmt.Errorf("建立帳號: %w", err)
 			}
-			deps.Log.Info(fmt.Sprintf("自動建立帳號  帳號=%s", accountName))
-		} else {
-			sendLoginResult(sess, noAccountCode)
-			return
+			out.created = true
+		} else if !deps.AccountRepo.ValidatePassword(account.PasswordHash, password) {
+			out.code = wrongPassCode
+			return nil
 		}
-	} else {
-		// Validate password
-		if !deps.AccountRepo.ValidatePassword(account.PasswordHash, password) {
-			sendLoginResult(sess, wrongPassCode)
-			return
+
+		// Check banned（accounts.banned 舊旗標，或 bans 表中帳號 / IP 網段的生效封鎖）
+		if account.Banned {
+			out.banned = true
+			out.code = loginWrongPass
+			return nil
+		}
+		if deps.BanRepo != nil {
+			ban, err := deps.BanRepo.FindActive(ctx, accountName, host)
+			if err != nil {
+				out.code = wrongPassCode
+				return fmt.Errorf("查詢封鎖: %w", err)
+			}
+			if ban != nil {
+				out.banned, out.ban = true, ban
+				out.code = loginWrongPass
+				return nil
+			}
 		}
-	}
 
-	// Check banned
-	if account.Banned {
-		deps.Log.Info(fmt.Sprintf("被封鎖帳號嘗試登入  帳號=%s", accountName))
-		sendLoginResult(sess, loginWrongPass)
-		return
-	}
+		// Check already online
+		if account.Online {
+			out.code = loginAlreadyExists
+			return nil
+		}
 
-	// Check already online
-	if account.Online {
-		sendLoginResult(sess, loginAlreadyExists)
-		return
-	}
+		// Success — mark online
+		out.code = loginOK
+		if err := deps.AccountRepo.SetOnline(ctx, accountName, true); err != nil {
+			deps.Log.Error("設定上線狀態資料庫錯誤", zap.Error(err))
+		}
+		if err := deps.AccountRepo.UpdateLastActive(ctx, accountName, ip); err != nil {
+			deps.Log.Error("更新最後活動時間資料庫錯誤", zap.Error(err))
+		}
 
-	// Success — mark online
-	if err := deps.AccountRepo.SetOnline(ctx, accountName, true); err != nil {
-		deps.Log.Error("設定上線狀態資料庫錯誤", zap.Error(err))
-	}
-	if err := deps.AccountRepo.UpdateLastActive(ctx, accountName, ip); err != nil {
-		deps.Log.Error("更新最後活動時間資料庫錯誤", zap.Error(err))
+		chars, err := loadCharacterList(ctx, deps, accountName)
+		if err != nil {
+			deps.Log.Error("載入角色列表", zap.Error(err))
+		} else if chars == nil {
+			chars = []persist.CharacterRow{}
+		}
+		out.chars = chars
+		out.maxSlots = defaultSlots + int(account.CharacterSlot)
+		return nil
 	}
 
-	sess.AccountName = accountName
-	sendLoginResult(sess, loginOK)
+	RunDBJob(sess, deps, "login", work, func(err error) {
+		delete(loginsInFlight, accountName)
+		if err != nil {
+			deps.Log.Error("登入資料庫錯誤", zap.String("account", accountName), zap.Error(err))
+		}
+		if out.created {
+			deps.Log.Info(fmt.Sprintf("自動建立帳號  帳號=%s", accountName))
+		}
+		if b := out.ban; b != nil {
+			deps.Log.Info(fmt.Sprintf("被封鎖帳號嘗試登入  帳號=%s  ip=%s  封鎖=#%d %s  原因=%s  封鎖者=%s  %s",
+				accountName, host, b.ID, b.Target(), b.Reason, b.Issuer, banExpiry(b)))
+		} else if out.banned {
+			deps.Log.Info(fmt.Sprintf("被封鎖帳號嘗試登入  帳號=%s  ip=%s", accountName, host))
+		}
+		if out.code != loginOK {
+			sendLoginResult(sess, out.code)
+			return
+		}
 
-	// Transition to Authenticated
-	sess.SetState(packet.StateAuthenticated)
+		// 已標記上線：即使連線在等待期間關閉，也要記下帳號讓斷線清理把它標回離線
+		sess.AccountName = accountName
+		if sess.IsClosed() {
+			return
+		}
+		sendLoginResult(sess, loginOK)
 
-	// Send character list
-	sendCharacterList(sess, deps)
+		// Transition to Authenticated
+		sess.SetState(packet.StateAuthenticated)
+
+		// Send character list
+		if out.chars != nil {
+			sendCharacterPacks(sess, out.chars, out.maxSlots)
+		}
 
-	deps.Log.Info(fmt.Sprintf("登入成功  帳號=%s  ip=%s", accountName, ip))
+		deps.Log.Info(fmt.Sprintf("登入成功  帳號=%s  ip=%s", accountName, ip))
+	})
 }
 
 // sendLoginResult 發送 S_LoginResult。
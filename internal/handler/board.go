@@ -2,8 +2,8 @@ package handler
 
 import (
 	"context"
+	"fmt"
 	"strings"
-	"time"
 
 	"github.com/l1jgo/server/internal/net"
 	"github.com/l1jgo/server/internal/net/packet"
@@ -47,15 +47,24 @@ func handleBoardOpen(sess *net.Session, npcObjID int32, deps *Deps) {
 	if deps.BoardRepo == nil {
 		return
 	}
+	sendBoardPage(sess, npcObjID, 0, deps)
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	posts, err := deps.BoardRepo.ListPage(ctx, 0, deps.Config.Gameplay.BoardPageSize)
-	if err != nil {
-		deps.Log.Error("讀取佈告欄失敗", zap.Error(err))
-	}
-	sendBoardList(sess, npcObjID, posts, deps.Config.Gameplay.BoardPostCost)
+// sendBoardPage 在 DB worker 讀取 lastTopicID 之後的一頁文章，完成後發送 S_Board。
+func sendBoardPage(sess *net.Session, npcObjID, lastTopicID int32, deps *Deps) {
+	pageSize := deps.Config.Gameplay.BoardPageSize
+	postCost := deps.Config.Gameplay.BoardPostCost
+	var posts []persist.BoardPost
+	RunDBJob(sess, deps, "board_list", func(ctx context.Context) error {
+		var err error
+		posts, err = deps.BoardRepo.ListPage(ctx, lastTopicID, pageSize)
+		return err
+	}, func(err error) {
+		if err != nil {
+			deps.Log.Error("讀取佈告欄失敗", zap.Int32("lastTopicID", lastTopicID), zap.Error(err))
+		}
+		sendBoardList(sess, npcObjID, posts, postCost)
+	})
 }
 
 // HandleBoardBack processes C_BoardBack (opcode 23) — next page of board posts.
@@ -78,14 +87,7 @@ func HandleBoardBack(sess *net.Session, r *packet.Reader, deps *Deps) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	posts, err := deps.BoardRepo.ListPage(ctx, lastTopicID, deps.Config.Gameplay.BoardPageSize)
-	if err != nil {
-		deps.Log.Error("讀取佈告欄下一頁失敗", zap.Error(err))
-	}
-	sendBoardList(sess, npcObjID, posts, deps.Config.Gameplay.BoardPostCost)
+	sendBoardPage(sess, npcObjID, lastTopicID, deps)
 }
 
 // HandleBoardRead processes C_BoardRead (opcode 114) — read a single post.
@@ -103,20 +105,22 @@ func HandleBoardRead(sess *net.Session, r *packet.Reader, deps *Deps) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	post, err := deps.BoardRepo.GetByID(ctx, topicID)
-	if err != nil {
-		deps.Log.Error("讀取佈告欄文章失敗", zap.Error(err))
-		return
-	}
-	if post == nil {
-		sendServerMessage(sess, 1243) // "信件已被刪除了。"
-		return
-	}
-
-	sendBoardRead(sess, post)
+	var post *persist.BoardPost
+	RunDBJob(sess, deps, "board_read", func(ctx context.Context) error {
+		var err error
+		post, err = deps.BoardRepo.GetByID(ctx, topicID)
+		return err
+	}, func(err error) {
+		if err != nil {
+			deps.Log.Error("讀取佈告欄文章失敗", zap.Error(err))
+			return
+		}
+		if post == nil {
+			sendServerMessage(sess, 1243) // "信件已被刪除了。"
+			return
+		}
+		sendBoardRead(sess, post)
+	})
 }
 
 // HandleBoardWrite processes C_BoardWrite (opcode 141) — write a new post.
@@ -157,15 +161,17 @@ func HandleBoardWrite(sess *net.Session, r *packet.Reader, deps *Deps) {
 	}
 
 	// Format date
-	date := time.Now().Format("2006/01/02")
-
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	_, err := deps.BoardRepo.Write(ctx, player.Name, date, title, content)
-	if err != nil {
-		deps.Log.Error("寫入佈告欄失敗", zap.Error(err))
-	}
+	date := deps.Clock.Now().Format("2006/01/02")
+	name := player.Name
+
+	RunDBJob(sess, deps, "board_write", func(ctx context.Context) error {
+		_, err := deps.BoardRepo.Write(ctx, name, date, title, content)
+		return err
+	}, func(err error) {
+		if err != nil {
+			deps.Log.Error("寫入佈告欄失敗", zap.Error(err))
+		}
+	})
 }
 
 // HandleBoardDelete processes C_BoardDelete (opcode 153) — delete a post.
@@ -186,37 +192,41 @@ func HandleBoardDelete(sess *net.Session, r *packet.Reader, deps *Deps) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	post, err := deps.BoardRepo.GetByID(ctx, topicID)
-	if err != nil {
-		deps.Log.Error("讀取佈告欄文章失敗", zap.Error(err))
-		return
-	}
-	if post == nil {
-		sendServerMessage(sess, 1243) // "信件已被刪除了。"
-		return
-	}
-
-	// Only author can delete (case-insensitive)
-	if !strings.EqualFold(post.Name, player.Name) {
-		deps.Log.Debug("board delete rejected: not author",
-			zap.String("author", post.Name),
-			zap.String("player", player.Name),
-		)
-		// Client optimistically removes the post — re-send the list to refresh
-		sendServerMessageArgs(sess, 166, "只有作者才能刪除文章。")
-		posts, err := deps.BoardRepo.ListPage(ctx, 0, deps.Config.Gameplay.BoardPageSize)
-		if err == nil {
-			sendBoardList(sess, npcObjID, posts, deps.Config.Gameplay.BoardPostCost)
+	name := player.Name
+	var post *persist.BoardPost
+	RunDBJob(sess, deps, "board_delete", func(ctx context.Context) error {
+		var err error
+		post, err = deps.BoardRepo.GetByID(ctx, topicID)
+		if err != nil {
+			return fmt.Errorf("讀取佈告欄文章: %w", err)
 		}
-		return
-	}
-
-	if err := deps.BoardRepo.Delete(ctx, topicID); err != nil {
-		deps.Log.Error("刪除佈告欄文章失敗", zap.Error(err))
-	}
+		// Only author can delete (case-insensitive)
+		if post == nil || !strings.EqualFold(post.Name, name) {
+			return nil
+		}
+		if err := deps.BoardRepo.Delete(ctx, topicID); err != nil {
+			return fmt.Errorf("刪除佈告欄文章: %w", err)
+		}
+		return nil
+	}, func(err error) {
+		if err != nil {
+			deps.Log.Error("刪除佈告欄文章失敗", zap.Error(err))
+			return
+		}
+		if post == nil {
+			sendServerMessage(sess, 1243) // "信件已被刪除了。"
+			return
+		}
+		if !strings.EqualFold(post.Name, name) {
+			deps.Log.Debug("board delete rejected: not author",
+				zap.String("author", post.Name),
+				zap.String("player", name),
+			)
+			// Client optimistically removes the post — re-send the list to refresh
+			sendServerMessageArgs(sess, 166, "只有作者才能刪除文章。")
+			sendBoardPage(sess, npcObjID, 0, deps)
+		}
+	})
 }
 
 // --- Packet builders ---
@@ -401,11 +401,11 @@ func sendExpUpdate(sess *net.Session, level int16, totalExp int32) {
 // sendPlayerStatus sends S_STATUS (opcode 8) — full character status update.
 // Same format as enterworld sendOwnCharStatus but built from PlayerInfo.
 // SendPlayerStatus sends S_STATUS to a player. Exported for system package usage.
-func SendPlayerStatus(sess *net.Session, p *world.PlayerInfo) {
-	sendPlayerStatus(sess, p)
+func SendPlayerStatus(sess *net.Session, p *world.PlayerInfo, deps *Deps) {
+	sendPlayerStatus(sess, p, deps)
 }
 
-func sendPlayerStatus(sess *net.Session, p *world.PlayerInfo) {
+func sendPlayerStatus(sess *net.Session, p *world.PlayerInfo, deps *Deps) {
 	w := packet.NewWriterWithOpcode(packet.S_OPCODE_STATUS)
 	w.WriteD(p.CharID)
 	level := p.Level
@@ -428,7 +428,7 @@ func sendPlayerStatus(sess *net.Session, p *world.PlayerInfo) {
 	w.WriteD(p.MaxMP)
 	w.WriteC(byte(p.AC))
 
-	gameTime := int32(world.GameTimeNow().Seconds())
+	gameTime := int32(world.GameTimeAt(deps.Clock.Now()).Seconds())
 	gameTime = gameTime - (gameTime%300)
 	w.WriteD(gameTime)
 
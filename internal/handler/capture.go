@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/l1jgo/server/internal/net"
+	"go.uber.org/zap"
+)
+
+// StartCapture 開始把連線的解密封包寫入 [debug] capture_dir/<角色>_<時間>.l1cap。
+// 已在擷取中時沿用原本的擷取檔。
+func StartCapture(sess *net.Session, charName string, deps *Deps) (*net.Capture, error) {
+	if c := sess.Capturing(); c != nil {
+		return c, nil
+	}
+	dir := deps.Config.Debug.CaptureDir
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s_%s.l1cap", charName, time.Now().Format("20060102-150405")))
+	c, err := net.CreateCapture(path, charName)
+	if err != nil {
+		return nil, err
+	}
+	sess.StartCapture(c)
+	deps.Log.Info("開始封包擷取", zap.String("char", charName), zap.String("path", path))
+	return c, nil
+}
+
+// StopCapture 停止連線的封包擷取，回傳擷取檔（未擷取時為 nil）。
+func StopCapture(sess *net.Session, deps *Deps) *net.Capture {
+	c := sess.StopCapture()
+	if c != nil {
+		deps.Log.Info("停止封包擷取", zap.String("char", sess.CharName),
+			zap.String("path", c.Path), zap.Int("frames", c.Frames()))
+	}
+	return c
+}
+
+// startConfiguredCapture 角色進入世界時，若列於 [debug] capture_characters 則開始擷取。
+func startConfiguredCapture(sess *net.Session, charName string, deps *Deps) {
+	if !slices.Contains(deps.Config.Debug.CaptureCharacters, charName) {
+		return
+	}
+	if _, err := StartCapture(sess, charName, deps); err != nil {
+		deps.Log.Warn("封包擷取檔建立失敗", zap.String("char", charName), zap.Error(err))
+	}
+}
@@ -103,7 +103,7 @@ func HandleChangeChar(sess *net.Session, _ *packet.Reader, deps *Deps) {
 		// Save inventory
 		if deps.ItemRepo != nil {
 			ctx2, cancel2 := context.WithTimeout(context.Background(), 5*time.Second)
-			if err := deps.ItemRepo.SaveInventory(ctx2, player.CharID, player.Inv, &player.Equip); err != nil {
+			if _, err := deps.ItemRepo.SaveInventory(ctx2, player.CharID, player.Inv, &player.Equip); err != nil {
 				deps.Log.Error("切換角色時存檔背包失敗",
 					zap.String("name", player.Name), zap.Error(err))
 			}
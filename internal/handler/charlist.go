@@ -2,7 +2,7 @@ package handler
 
 import (
 	"context"
-	"time"
+	"fmt"
 
 	"github.com/l1jgo/server/internal/net"
 	"github.com/l1jgo/server/internal/net/packet"
@@ -13,31 +13,47 @@ import (
 // sendCharacterList sends S_CharAmount + S_CharPacks.
 // Java: L1CharList / C_CommonClick — 只發送 S_CharAmount + S_CharPacks，
 // 不發送 S_CharSynAck（opcode 64 SYN/ACK）。
+// 角色與帳號欄位數在 DB worker 載入，完成後於遊戲迴圈發送。
 func sendCharacterList(sess *net.Session, deps *Deps) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	accountName := sess.AccountName
+	defaultSlots := deps.Config.Character.DefaultSlots
+	var chars []persist.CharacterRow
+	var maxSlots int
 
-	// Clean expired deletions first
-	if _, err := deps.CharRepo.CleanExpiredDeletions(ctx, sess.AccountName); err != nil {
-		deps.Log.Error("清理過期刪除記錄", zap.Error(err))
-	}
+	RunDBJob(sess, deps, "char_list", func(ctx context.Context) error {
+		var err error
+		chars, err = loadCharacterList(ctx, deps, accountName)
+		if err != nil {
+			return fmt.Errorf("載入角色列表: %w", err)
+		}
 
-	// Load characters
-	chars, err := deps.CharRepo.LoadByAccount(ctx, sess.AccountName)
-	if err != nil {
-		deps.Log.Error("載入角色列表", zap.Error(err))
-		return
-	}
+		// Load account for slot info
+		account, err := deps.AccountRepo.Load(ctx, accountName)
+		if err != nil || account == nil {
+			return fmt.Errorf("載入帳號(角色列表): %v", err)
+		}
+		maxSlots = defaultSlots + int(account.CharacterSlot)
+		return nil
+	}, func(err error) {
+		if err != nil {
+			deps.Log.Error("角色列表載入失敗", zap.String("account", accountName), zap.Error(err))
+			return
+		}
+		sendCharacterPacks(sess, chars, maxSlots)
+	})
+}
 
-	// Load account for slot info
-	account, err := deps.AccountRepo.Load(ctx, sess.AccountName)
-	if err != nil || account == nil {
-		deps.Log.Error("載入帳號(角色列表)", zap.Error(err))
-		return
+// loadCharacterList 清理過期刪除記錄並載入帳號的角色（DB worker 執行）。
+func loadCharacterList(ctx context.Context, deps *Deps, accountName string) ([]persist.CharacterRow, error) {
+	// Clean expired deletions first
+	if _, err := deps.CharRepo.CleanExpiredDeletions(ctx, accountName); err != nil {
+		deps.Log.Error("清理過期刪除記錄", zap.Error(err))
 	}
+	return deps.CharRepo.LoadByAccount(ctx, accountName)
+}
 
-	maxSlots := deps.Config.Character.DefaultSlots + int(account.CharacterSlot)
-
+// sendCharacterPacks 發送 S_CharAmount + 每個角色的 S_CharPacks。
+func sendCharacterPacks(sess *net.Session, chars []persist.CharacterRow, maxSlots int) {
 	// S_CharAmount (opcode 178)
 	sendCharAmount(sess, len(chars), maxSlots)
 
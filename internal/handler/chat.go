@@ -85,7 +85,7 @@ func HandleChat(sess *net.Session, r *packet.Reader, deps *Deps) {
 			return
 		}
 		player.Food -= int16(deps.Config.Gameplay.WorldChatFoodCost)
-		sendPlayerStatus(sess, player)
+		sendPlayerStatus(sess, player, deps)
 
 		// World/Global chat: all players via S_MESSAGE (opcode 243)
 		msg := fmt.Sprintf("[%s] %s", player.Name, text)
@@ -1,8 +1,6 @@
 package handler
 
 import (
-	"time"
-
 	"github.com/l1jgo/server/internal/net"
 	"github.com/l1jgo/server/internal/net/packet"
 	"github.com/l1jgo/server/internal/world"
@@ -181,7 +179,7 @@ func handleSurvivalShout(sess *net.Session, player *world.PlayerInfo, deps *Deps
 		SendServerMessage(sess, 1974)
 		return
 	}
-	now := time.Now().Unix()
+	now := deps.Clock.Now().Unix()
 	minutes := int((now - player.FoodFullTime) / 60)
 	if minutes <= 0 {
 		SendServerMessage(sess, 1974)
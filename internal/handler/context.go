@@ -1,10 +1,13 @@
 package handler
 
 import (
+	"math/rand"
 	"time"
 
 	"github.com/l1jgo/server/internal/config"
+	"github.com/l1jgo/server/internal/core/clock"
 	"github.com/l1jgo/server/internal/core/event"
+	coresys "github.com/l1jgo/server/internal/core/system"
 	"github.com/l1jgo/server/internal/data"
 	"github.com/l1jgo/server/internal/net"
 	"github.com/l1jgo/server/internal/net/packet"
@@ -516,6 +519,28 @@ type MapTimerManager interface {
 	ResetAllMapTimers(player *world.PlayerInfo)
 }
 
+// CheatVerdict 是反作弊檢查對單一動作的處置。
+type CheatVerdict int
+
+const (
+	CheatAllow  CheatVerdict = iota // 放行（可能已記錄違規）
+	CheatReject                     // 拒絕本次動作（移動 → rejectMove 回彈）
+	CheatKick                       // 已踢除連線，呼叫端直接返回
+	CheatDrop                       // 靜默丟棄本次動作（單步過快，多為網路擠壓；不回彈）
+)
+
+// AntiCheatManager 驗證玩家動作頻率。由 system.AntiCheatSystem 實作。
+type AntiCheatManager interface {
+	// CheckMove 記錄一次移動並與目前外型、武器、加速狀態的預期步行間隔比對。
+	CheckMove(player *world.PlayerInfo) CheatVerdict
+	// CheckAttack 記錄一次攻擊並與目前外型、武器、加速狀態的預期攻擊間隔比對。
+	CheckAttack(player *world.PlayerInfo) CheatVerdict
+	// CheckCast 記錄一次施法並與施法動畫時間、技能冷卻比對。
+	CheckCast(player *world.PlayerInfo, skill *data.SkillInfo) CheatVerdict
+	// CheckTeleport 驗證玩家發起的傳送（目的地、來源地圖、授權者）；未通過回傳 false 並記錄日誌。
+	CheckTeleport(player *world.PlayerInfo, x, y int32, mapID int16, src world.TeleportSource) bool
+}
+
 // InnManager 處理旅館租房/退租邏輯。由 system.InnSystem 實作。
 type InnManager interface {
 	// ReturnRoom 處理退租。
@@ -658,9 +683,9 @@ type TrapTriggerer interface {
 
 // Deps holds shared dependencies injected into all packet handlers.
 type Deps struct {
-	AccountRepo *persist.AccountRepo
-	CharRepo    *persist.CharacterRepo
-	ItemRepo    *persist.ItemRepo
+	AccountRepo persist.AccountStore
+	CharRepo    persist.CharacterStore
+	ItemRepo    persist.ItemStore
 	Config      *config.Config
 	Log         *zap.Logger
 	World       *world.State
@@ -680,21 +705,22 @@ type Deps struct {
 	Polys          *data.PolymorphTable
 	ArmorSets      *data.ArmorSetTable
 	SprTable       *data.SprTable
-	WarehouseRepo  *persist.WarehouseRepo
-	WALRepo        *persist.WALRepo
-	ClanRepo       *persist.ClanRepo
-	BuffRepo       *persist.BuffRepo
+	WarehouseRepo  persist.WarehouseStore
+	WALRepo        persist.WALStore
+	ItemEvents     persist.ItemEventStore // 物品來源稽核（item_events）
+	ClanRepo       persist.ClanStore
+	BuffRepo       persist.BuffStore
 	Doors          *data.DoorTable
 	ItemMaking     *data.ItemMakingTable
 	SpellbookReqs  *data.SpellbookReqTable
 	BuffIcons      *data.BuffIconTable
 	NpcServices    *data.NpcServiceTable
-	QuestRepo     *persist.QuestRepo
-	BuddyRepo     *persist.BuddyRepo
-	ExcludeRepo   *persist.ExcludeRepo
-	BoardRepo     *persist.BoardRepo
-	MailRepo      *persist.MailRepo
-	PetRepo       *persist.PetRepo
+	QuestRepo     persist.QuestStore
+	BuddyRepo     persist.BuddyStore
+	ExcludeRepo   persist.ExcludeStore
+	BoardRepo     persist.BoardStore
+	MailRepo      persist.MailStore
+	PetRepo       persist.PetStore
 	PetTypes      *data.PetTypeTable
 	PetItems      *data.PetItemTable
 	Dolls         *data.DollTable
@@ -735,8 +761,8 @@ type Deps struct {
 	Hierarchs     *data.HierarchTable
 	Auction       AuctionManager        // filled after AuctionSystem is created
 	Houses        *data.HouseTable      // 住宅靜態座標資料
-	HouseRepo     *persist.HouseRepo    // 住宅動態狀態持久化
-	InnRepo       *persist.InnRepo      // 旅館房間持久化
+	HouseRepo     persist.HouseStore    // 住宅動態狀態持久化
+	InnRepo       persist.InnStore      // 旅館房間持久化
 	InnRooms      map[int32]map[int32]*persist.InnRoom // 旅館房間運行時狀態（npcID → roomNum → room）
 	Alliances     *AllianceManager      // 聯盟管理器（啟動時從 DB 載入）
 	ClanMatching  *ClanMatchingManager  // 血盟配對管理器
@@ -758,9 +784,18 @@ type Deps struct {
 	MapTimer      MapTimerManager      // 限時地圖計時（filled after MapTimerSystem is created）
 	Castles       *data.CastleTable    // 城堡靜態地理資料
 	WarGifts      *data.WarGiftTable   // 攻城戰禮物資料
-	CastleRepo    *persist.CastleRepo  // 城堡動態狀態持久化
+	CastleRepo    persist.CastleStore  // 城堡動態狀態持久化
 	Castle        CastleManager        // 城堡管理邏輯（filled after CastleSystem is created）
 	War           WarManager           // 戰爭管理邏輯（filled after WarSystem is created）
+	Runner        *coresys.Runner      // 系統 tick 分析（GM .tickstat；filled after Runner is created）
+	DBJobs        *persist.JobQueue    // 非同步資料庫工作（登入、角色列表、倉庫、信件、佈告欄）
+	LoginLimit    *LoginLimiter        // 每 IP 登入嘗試限制（nil = 不限制）
+	AntiCheat     AntiCheatManager     // 動作頻率檢查（filled after AntiCheatSystem is created）
+	CheatFlags    persist.CheatFlagStore // 反作弊標記（GM .cheatlog）
+	BanRepo       persist.BanStore     // 帳號 / IP 封鎖
+	Sessions      *net.SessionStore    // 所有連線（封鎖時斷開尚未進入世界的連線；filled after SessionStore is created）
+	Clock         clock.Clock          // 遊戲邏輯時間來源（遊戲時間、冷卻、攻城、拍賣；模擬環境可手動推進）
+	Rand          *rand.Rand           // 遊戲邏輯亂數來源（戰鬥、掉落、強化、生成；種子見 [debug] rng_seed）
 }
 
 // RegisterAll registers all packet handlers into the registry.
@@ -1270,7 +1305,7 @@ func RegisterAll(reg *packet.Registry, deps *Deps) {
 			// Java: C_KeepALIVE sends S_GameTime to keep client time synced (day/night cycle).
 			s := sess.(*net.Session)
 			if s.State() == packet.StateInWorld {
-				sendGameTime(s, world.GameTimeNow().Seconds())
+				sendGameTime(s, world.GameTimeAt(deps.Clock.Now()).Seconds())
 			}
 		},
 	)
@@ -134,7 +134,7 @@ func HandleCreateChar(sess *net.Session, r *packet.Reader, deps *Deps) {
 	initMP := int32(deps.Scripting.CalcInitMP(int(classType), int(wis)))
 
 	// Birthday as yyyyMMdd integer
-	now := time.Now()
+	now := deps.Clock.Now()
 	birthday := int32(now.Year()*10000 + int(now.Month())*100 + now.Day())
 
 	// Build row
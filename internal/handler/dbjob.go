@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/l1jgo/server/internal/net"
+	"github.com/l1jgo/server/internal/persist"
+)
+
+// dbJobTimeout 是未設定 DBJobs 時同步執行工作的逾時。
+const dbJobTimeout = 5 * time.Second
+
+// RunDBJob 在背景 worker 執行資料庫工作，完成後於遊戲迴圈 Phase 1 呼叫 done。
+// 等待期間該連線的輸入封包暫停處理（Session.JobPending），因此 done 執行時
+// 玩家狀態不會被同一連線的後續封包改變。
+//
+// work 在 worker goroutine 執行：只能使用呼叫前擷取的參數，不得讀寫 world.State
+// 或呼叫 sess.Send；結果寫入外層變數，由 done 在遊戲迴圈套用。
+// 佇列已滿時 done 立即收到 persist.ErrJobQueueFull。
+func RunDBJob(sess *net.Session, deps *Deps, name string, work func(ctx context.Context) error, done func(err error)) {
+	RunSharedDBJob([]*net.Session{sess}, deps, name, work, done)
+}
+
+// RunSharedDBJob 與 RunDBJob 相同，但等待期間暫停多個連線的輸入，用於同時
+// 改變雙方玩家狀態的工作（交易、個人商店）。nil 連線略過。
+func RunSharedDBJob(sessions []*net.Session, deps *Deps, name string, work func(ctx context.Context) error, done func(err error)) {
+	if deps.DBJobs == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), dbJobTimeout)
+		err := work(ctx)
+		cancel()
+		done(err)
+		return
+	}
+
+	for _, sess := range sessions {
+		if sess != nil {
+			sess.BeginJob()
+		}
+	}
+	finish := func(err error) {
+		for _, sess := range sessions {
+			if sess != nil {
+				sess.EndJob()
+			}
+		}
+		done(err)
+	}
+	if !deps.DBJobs.Submit(name, work, finish) {
+		finish(persist.ErrJobQueueFull)
+	}
+}
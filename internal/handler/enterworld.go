@@ -39,6 +39,7 @@ func HandleEnterWorld(sess *net.Session, r *packet.Reader, deps *Deps) {
 
 	sess.CharName = charName
 	sess.SetState(packet.StateInWorld)
+	startConfiguredCapture(sess, charName, deps)
 
 	deps.Log.Info(fmt.Sprintf("角色進入世界  帳號=%s  角色=%s", sess.AccountName, charName))
 
@@ -129,7 +130,7 @@ func HandleEnterWorld(sess *net.Session, r *packet.Reader, deps *Deps) {
 	sendInvList(sess, player.Inv, deps.Items)
 
 	// 3. S_STATUS (opcode 8) — 角色狀態（使用 PlayerInfo 即時數據）
-	sendPlayerStatus(sess, player)
+	sendPlayerStatus(sess, player, deps)
 
 	// 4. S_WORLD (opcode 206) — 地圖 ID
 	sendMapID(sess, uint16(ch.MapID), false)
@@ -307,7 +308,7 @@ func HandleEnterWorld(sess *net.Session, r *packet.Reader, deps *Deps) {
 	}
 
 	// S_GameTime — 最後發送，避免干擾客戶端初始化
-	sendGameTime(sess, world.GameTimeNow().Seconds())
+	sendGameTime(sess, world.GameTimeAt(deps.Clock.Now()).Seconds())
 }
 
 func sendLoginGame(sess *net.Session, clanID int32, clanMemberID int32) {
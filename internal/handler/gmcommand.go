@@ -3,7 +3,8 @@ package handler
 import (
 	"context"
 	"fmt"
-	"math/rand"
+	"net/netip"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -111,6 +112,28 @@ func HandleGMCommand(sess *net.Session, player *world.PlayerInfo, text string, d
 		gmSlotExpand2(sess, args)
 	case "time":
 		gmTime(sess, player, args, deps)
+	case "luastat":
+		gmLuaStat(sess, args, deps)
+	case "reloadlua":
+		gmReloadLua(sess, deps)
+	case "npcsleep":
+		gmNpcSleep(sess, args, deps)
+	case "tickstat":
+		gmTickStat(sess, args, deps)
+	case "itemhistory":
+		gmItemHistory(sess, args, deps)
+	case "loginlock":
+		gmLoginLock(sess, args, deps)
+	case "ban":
+		gmBan(sess, player, args, deps)
+	case "unban":
+		gmUnban(sess, player, args, deps)
+	case "baninfo":
+		gmBanInfo(sess, args, deps)
+	case "cheatlog":
+		gmCheatLog(sess, args, deps)
+	case "capture":
+		gmCapture(sess, args, deps)
 	default:
 		gmMsg(sess, "\\f3未知的GM指令: ."+cmd+"  輸入 .help 查看指令列表")
 	}
@@ -163,6 +186,17 @@ func gmHelp(sess *net.Session) {
 	gmMsg(sess, ".allbuff  — 套用所有常用buff")
 	gmMsg(sess, ".stresstest <npcID> [數量] [半徑]  — 壓力測試(預設10000隻,半徑50)")
 	gmMsg(sess, ".cleartest  — 清除所有壓力測試怪物")
+	gmMsg(sess, ".luastat [筆數]  — Lua 函式耗時/逾時/節流統計")
+	gmMsg(sess, ".reloadlua  — 重新載入 scripts/ 目錄的 Lua 腳本")
+	gmMsg(sess, ".npcsleep [筆數]  — 各地圖 NPC AI 活躍/休眠數量")
+	gmMsg(sess, ".tickstat [筆數]  — 各系統每 tick 耗時（近 60 秒百分位）")
+	gmMsg(sess, ".itemhistory <物品ObjID> [筆數]  — 物品來源歷史（item_events）")
+	gmMsg(sess, ".loginlock [unlock <IP>]  — 列出/解除登入嘗試過多而鎖定的 IP")
+	gmMsg(sess, ".ban <角色名|帳號|IP[/前綴]> <期間> [原因]  — 封鎖並斷線(期間 30m/12h/7d，0=永久)")
+	gmMsg(sess, ".unban <角色名|帳號|IP[/前綴]>  — 解除封鎖")
+	gmMsg(sess, ".baninfo <角色名|帳號|IP>  — 查詢封鎖記錄")
+	gmMsg(sess, ".cheatlog [角色名] [筆數]  — 反作弊標記（加速回彈/踢除）")
+	gmMsg(sess, ".capture [角色名] [on|off]  — 擷取角色連線的解密封包（無參數列出擷取中的角色）")
 }
 
 func gmLevel(sess *net.Session, player *world.PlayerInfo, args []string, deps *Deps) {
@@ -458,6 +492,77 @@ func gmAllSkill(sess *net.Session, player *world.PlayerInfo, deps *Deps) {
 	gmMsgf(sess, "已學會 %s 全部技能 (新增 %d 個)", classNames[player.ClassType], count)
 }
 
+// NewTemplateNpc 依模板建立不重生的 NPC（GM 召喚、壓力測試、Lua 腳本共用），
+// 攻擊/移動速度優先取 SprTable 的動畫速度。呼叫端負責 AddNpc。
+func NewTemplateNpc(deps *Deps, tmpl *data.NpcTemplate, x, y int32, mapID int16) *world.NpcInfo {
+	atkSpeed := tmpl.AtkSpeed
+	moveSpeed := tmpl.PassiveSpeed
+	if deps.SprTable != nil {
+		gfx := int(tmpl.GfxID)
+		if tmpl.AtkSpeed != 0 {
+			if v := deps.SprTable.GetAttackSpeed(gfx, data.ActAttack); v > 0 {
+				atkSpeed = int16(v)
+			}
+		}
+		if tmpl.PassiveSpeed != 0 {
+			if v := deps.SprTable.GetMoveSpeed(gfx, data.ActWalk); v > 0 {
+				moveSpeed = int16(v)
+			}
+		}
+	}
+
+	return &world.NpcInfo{
+		ID:           world.NextNpcID(),
+		NpcID:        tmpl.NpcID,
+		Impl:         tmpl.Impl,
+		GfxID:        tmpl.GfxID,
+		Name:         tmpl.Name,
+		NameID:       tmpl.NameID,
+		Level:        tmpl.Level,
+		X:            x,
+		Y:            y,
+		MapID:        mapID,
+		Heading:      int16(deps.Rand.Intn(8)),
+		HP:           tmpl.HP,
+		MaxHP:        tmpl.HP,
+		MP:           tmpl.MP,
+		MaxMP:        tmpl.MP,
+		AC:           tmpl.AC,
+		STR:          tmpl.STR,
+		DEX:          tmpl.DEX,
+		Exp:          tmpl.Exp,
+		Lawful:       tmpl.Lawful,
+		Size:         tmpl.Size,
+		MR:           tmpl.MR,
+		Undead:       tmpl.Undead,
+		Agro:         tmpl.Agro,
+		AtkDmg:       int32(tmpl.Level) + int32(tmpl.STR)/3,
+		Ranged:       tmpl.Ranged,
+		AtkSpeed:     atkSpeed,
+		MoveSpeed:    moveSpeed,
+		PoisonAtk:    tmpl.PoisonAtk,
+		FireRes:      tmpl.FireRes,
+		WaterRes:     tmpl.WaterRes,
+		WindRes:      tmpl.WindRes,
+		EarthRes:     tmpl.EarthRes,
+		SpawnX:       x,
+		SpawnY:       y,
+		SpawnMapID:   mapID,
+		RespawnDelay: 0, // 不重生
+	}
+}
+
+// SpawnTemplateNpc 以 NewTemplateNpc 建立 NPC、加入世界並廣播給附近玩家。
+func SpawnTemplateNpc(deps *Deps, tmpl *data.NpcTemplate, x, y int32, mapID int16) *world.NpcInfo {
+	npc := NewTemplateNpc(deps, tmpl, x, y, mapID)
+	deps.World.AddNpc(npc)
+	nearby := deps.World.GetNearbyPlayersAt(npc.X, npc.Y, npc.MapID)
+	for _, viewer := range nearby {
+		SendNpcPack(viewer.Session, npc)
+	}
+	return npc
+}
+
 func gmSpawn(sess *net.Session, player *world.PlayerInfo, args []string, deps *Deps) {
 	if len(args) < 1 {
 		gmMsg(sess, "\\f3用法: .spawn <npcID> [數量]")
@@ -489,71 +594,10 @@ func gmSpawn(sess *net.Session, player *world.PlayerInfo, args []string, deps *D
 
 	for i := 0; i < count; i++ {
 		// Spawn near player with slight random offset
-		x := player.X + int32(rand.Intn(5)) - 2
-		y := player.Y + int32(rand.Intn(5)) - 2
-
-		atkSpeed := tmpl.AtkSpeed
-		moveSpeed := tmpl.PassiveSpeed
-		if deps.SprTable != nil {
-			gfx := int(tmpl.GfxID)
-			if tmpl.AtkSpeed != 0 {
-				if v := deps.SprTable.GetAttackSpeed(gfx, data.ActAttack); v > 0 {
-					atkSpeed = int16(v)
-				}
-			}
-			if tmpl.PassiveSpeed != 0 {
-				if v := deps.SprTable.GetMoveSpeed(gfx, data.ActWalk); v > 0 {
-					moveSpeed = int16(v)
-				}
-			}
-		}
+		x := player.X + int32(deps.Rand.Intn(5)) - 2
+		y := player.Y + int32(deps.Rand.Intn(5)) - 2
 
-		npc := &world.NpcInfo{
-			ID:           world.NextNpcID(),
-			NpcID:        tmpl.NpcID,
-			Impl:         tmpl.Impl,
-			GfxID:        tmpl.GfxID,
-			Name:         tmpl.Name,
-			NameID:       tmpl.NameID,
-			Level:        tmpl.Level,
-			X:            x,
-			Y:            y,
-			MapID:        player.MapID,
-			Heading:      int16(rand.Intn(8)),
-			HP:           tmpl.HP,
-			MaxHP:        tmpl.HP,
-			MP:           tmpl.MP,
-			MaxMP:        tmpl.MP,
-			AC:           tmpl.AC,
-			STR:          tmpl.STR,
-			DEX:          tmpl.DEX,
-			Exp:          tmpl.Exp,
-			Lawful:       tmpl.Lawful,
-			Size:         tmpl.Size,
-			MR:           tmpl.MR,
-			Undead:       tmpl.Undead,
-			Agro:         tmpl.Agro,
-			AtkDmg:       int32(tmpl.Level) + int32(tmpl.STR)/3,
-			Ranged:       tmpl.Ranged,
-			AtkSpeed:     atkSpeed,
-			MoveSpeed:    moveSpeed,
-			PoisonAtk:    tmpl.PoisonAtk,
-			FireRes:      tmpl.FireRes,
-			WaterRes:     tmpl.WaterRes,
-			WindRes:      tmpl.WindRes,
-			EarthRes:     tmpl.EarthRes,
-			SpawnX:       x,
-			SpawnY:       y,
-			SpawnMapID:   player.MapID,
-			RespawnDelay: 0, // GM-spawned: no respawn
-		}
-		deps.World.AddNpc(npc)
-
-		// Broadcast to nearby players
-		nearby := deps.World.GetNearbyPlayersAt(npc.X, npc.Y, npc.MapID)
-		for _, viewer := range nearby {
-			SendNpcPack(viewer.Session, npc)
-		}
+		SpawnTemplateNpc(deps, tmpl, x, y, player.MapID)
 	}
 
 	gmMsgf(sess, "已召喚 %s (ID:%d) x%d", tmpl.Name, npcID, count)
@@ -690,7 +734,7 @@ func gmSpeed(sess *net.Session, player *world.PlayerInfo, args []string, deps *D
 	}
 
 	// 更新角色狀態（讓客戶端 buff 圖標正確顯示）
-	SendPlayerStatus(sess, player)
+	SendPlayerStatus(sess, player, deps)
 
 	names := []string{"正常", "加速", "勇敢藥水", "巧克力蛋糕", "精靈餅乾"}
 	gmMsgf(sess, "移動速度已設為: %s", names[spd])
@@ -811,7 +855,7 @@ func gmClass(sess *net.Session, player *world.PlayerInfo, args []string, deps *D
 	}
 
 	// Send visual refresh
-	sendPlayerStatus(sess, player)
+	sendPlayerStatus(sess, player, deps)
 	broadcastVisualUpdate(sess, player, deps)
 
 	// Re-send own charpack to update appearance
@@ -856,7 +900,7 @@ func gmSave(sess *net.Session, player *world.PlayerInfo, deps *Deps) {
 		gmMsgf(sess, "\\f3存檔失敗: %v", err)
 		return
 	}
-	if err := deps.ItemRepo.SaveInventory(ctx, player.CharID, player.Inv, &player.Equip); err != nil {
+	if _, err := deps.ItemRepo.SaveInventory(ctx, player.CharID, player.Inv, &player.Equip); err != nil {
 		gmMsgf(sess, "\\f3物品存檔失敗: %v", err)
 		return
 	}
@@ -890,7 +934,7 @@ func gmRez(sess *net.Session, player *world.PlayerInfo, args []string, deps *Dep
 
 	sendHpUpdate(target.Session, target)
 	sendMpUpdate(target.Session, target)
-	sendPlayerStatus(target.Session, target)
+	sendPlayerStatus(target.Session, target, deps)
 
 	// Refresh position
 	SendPutObject(target.Session, target)
@@ -1195,9 +1239,9 @@ func gmTime(sess *net.Session, _ *world.PlayerInfo, args []string, deps *Deps) {
 			gmMsg(sess, "\\f3用法: .time set <0-23>")
 			return
 		}
-		world.SetGameTimeOffset(hour)
+		world.SetGameTimeOffset(deps.Clock.Now(), hour)
 		// 廣播新的 S_GameTime 給所有在線玩家
-		gt := world.GameTimeNow()
+		gt := world.GameTimeAt(deps.Clock.Now())
 		deps.World.AllPlayers(func(p *world.PlayerInfo) {
 			sendGameTime(p.Session, gt.Seconds())
 		})
@@ -1209,7 +1253,7 @@ func gmTime(sess *net.Session, _ *world.PlayerInfo, args []string, deps *Deps) {
 		return
 	}
 
-	gt := world.GameTimeNow()
+	gt := world.GameTimeAt(deps.Clock.Now())
 	dayNight := "白天"
 	if gt.IsNight() {
 		dayNight = "夜晚"
@@ -1291,36 +1335,19 @@ func gmStressTest(sess *net.Session, player *world.PlayerInfo, args []string, de
 		return
 	}
 
-	// 查詢動畫速度（只查一次，所有 NPC 共用）
-	atkSpeed := tmpl.AtkSpeed
-	moveSpeed := tmpl.PassiveSpeed
-	if deps.SprTable != nil {
-		gfx := int(tmpl.GfxID)
-		if tmpl.AtkSpeed != 0 {
-			if v := deps.SprTable.GetAttackSpeed(gfx, data.ActAttack); v > 0 {
-				atkSpeed = int16(v)
-			}
-		}
-		if tmpl.PassiveSpeed != 0 {
-			if v := deps.SprTable.GetMoveSpeed(gfx, data.ActWalk); v > 0 {
-				moveSpeed = int16(v)
-			}
-		}
-	}
-
 	gmMsgf(sess, "開始生成 %d 隻 %s（半徑 %d 格）...", count, tmpl.Name, radius)
 
 	spawned := 0
 	for i := 0; i < count; i++ {
-		x := player.X + int32(rand.Intn(int(radius*2+1))) - radius
-		y := player.Y + int32(rand.Intn(int(radius*2+1))) - radius
+		x := player.X + int32(deps.Rand.Intn(int(radius*2+1))) - radius
+		y := player.Y + int32(deps.Rand.Intn(int(radius*2+1))) - radius
 
 		// 可行走性檢查（最多重試 3 次）
 		if deps.MapData != nil {
 			ok := deps.MapData.IsPassablePoint(player.MapID, x, y)
 			for retry := 0; !ok && retry < 3; retry++ {
-				x = player.X + int32(rand.Intn(int(radius*2+1))) - radius
-				y = player.Y + int32(rand.Intn(int(radius*2+1))) - radius
+				x = player.X + int32(deps.Rand.Intn(int(radius*2+1))) - radius
+				y = player.Y + int32(deps.Rand.Intn(int(radius*2+1))) - radius
 				ok = deps.MapData.IsPassablePoint(player.MapID, x, y)
 			}
 			if !ok {
@@ -1328,46 +1355,8 @@ func gmStressTest(sess *net.Session, player *world.PlayerInfo, args []string, de
 			}
 		}
 
-		npc := &world.NpcInfo{
-			ID:           world.NextNpcID(),
-			NpcID:        tmpl.NpcID,
-			Impl:         tmpl.Impl,
-			GfxID:        tmpl.GfxID,
-			Name:         tmpl.Name,
-			NameID:       tmpl.NameID,
-			Level:        tmpl.Level,
-			X:            x,
-			Y:            y,
-			MapID:        player.MapID,
-			Heading:      int16(rand.Intn(8)),
-			HP:           tmpl.HP,
-			MaxHP:        tmpl.HP,
-			MP:           tmpl.MP,
-			MaxMP:        tmpl.MP,
-			AC:           tmpl.AC,
-			STR:          tmpl.STR,
-			DEX:          tmpl.DEX,
-			Exp:          tmpl.Exp,
-			Lawful:       tmpl.Lawful,
-			Size:         tmpl.Size,
-			MR:           tmpl.MR,
-			Undead:       tmpl.Undead,
-			Agro:         tmpl.Agro,
-			AtkDmg:       int32(tmpl.Level) + int32(tmpl.STR)/3,
-			Ranged:       tmpl.Ranged,
-			AtkSpeed:     atkSpeed,
-			MoveSpeed:    moveSpeed,
-			PoisonAtk:    tmpl.PoisonAtk,
-			FireRes:      tmpl.FireRes,
-			WaterRes:     tmpl.WaterRes,
-			WindRes:      tmpl.WindRes,
-			EarthRes:     tmpl.EarthRes,
-			SpawnX:       x,
-			SpawnY:       y,
-			SpawnMapID:   player.MapID,
-			RespawnDelay: 0, // 壓力測試：不重生
-		}
-		deps.World.AddNpc(npc)
+		// 壓力測試：不重生，不廣播（走動即可看到）
+		deps.World.AddNpc(NewTemplateNpc(deps, tmpl, x, y, player.MapID))
 		spawned++
 	}
 
@@ -1648,3 +1637,524 @@ func gmInvisible(sess *net.Session, player *world.PlayerInfo, deps *Deps) {
 		gmMsg(sess, "\\f2GM 隱身已關閉。")
 	}
 }
+
+// gmLuaStat 顯示 Lua 函式呼叫統計（依累計耗時排序），用於找出逾時或拖慢 tick 的腳本。
+func gmLuaStat(sess *net.Session, args []string, deps *Deps) {
+	limit := 10
+	if len(args) > 0 {
+		if n, err := strconv.Atoi(args[0]); err == nil && n > 0 {
+			limit = n
+		}
+	}
+	stats := deps.Scripting.Stats()
+	if len(stats) == 0 {
+		gmMsg(sess, "尚無 Lua 呼叫記錄")
+		return
+	}
+	gmMsg(sess, "=== Lua 函式統計 ===")
+	for i, st := range stats {
+		if i >= limit {
+			break
+		}
+		avg := time.Duration(0)
+		if st.Calls > 0 {
+			avg = st.Total / time.Duration(st.Calls)
+		}
+		gmMsgf(sess, "%s 呼叫:%d 平均:%s 最大:%s 逾時:%d 超出記憶體:%d 錯誤:%d 節流:%d",
+			st.Name, st.Calls, avg, st.Max, st.Timeouts, st.OverMemory, st.Errors, st.Throttled)
+	}
+}
+
+// gmReloadLua 排程 Lua 腳本熱重載；本 tick 結束時替換 VM，結果回報給 GM。
+// 載入失敗時保留原本的腳本。
+func gmReloadLua(sess *net.Session, deps *Deps) {
+	gmMsg(sess, "Lua 腳本重新載入中...")
+	deps.Scripting.RequestReload(func(err error) {
+		if err != nil {
+			gmMsgf(sess, "\\f3Lua 重新載入失敗，沿用舊腳本: %v", err)
+			return
+		}
+		gmMsg(sess, "\\f2Lua 腳本已重新載入。")
+	})
+}
+
+// gmNpcSleep 顯示各地圖 NPC AI 活躍/休眠數量（依活躍數排序）。
+func gmNpcSleep(sess *net.Session, args []string, deps *Deps) {
+	limit := 10
+	if len(args) > 0 {
+		if n, err := strconv.Atoi(args[0]); err == nil && n > 0 {
+			limit = n
+		}
+	}
+	byMap := deps.World.NpcActivityByMap()
+	mapIDs := make([]int16, 0, len(byMap))
+	total := world.NpcActivity{}
+	for id, a := range byMap {
+		mapIDs = append(mapIDs, id)
+		total.Active += a.Active
+		total.Sleeping += a.Sleeping
+	}
+	sort.Slice(mapIDs, func(i, j int) bool {
+		a, b := byMap[mapIDs[i]], byMap[mapIDs[j]]
+		if a.Active != b.Active {
+			return a.Active > b.Active
+		}
+		return a.Sleeping > b.Sleeping
+	})
+
+	gmMsgf(sess, "=== NPC AI 活躍:%d 休眠:%d（%d 張地圖）===", total.Active, total.Sleeping, len(mapIDs))
+	for i, id := range mapIDs {
+		if i >= limit {
+			break
+		}
+		a := byMap[id]
+		gmMsgf(sess, "地圖 %d 活躍:%d 休眠:%d", id, a.Active, a.Sleeping)
+	}
+}
+
+// gmTickStat 顯示 Runner 各系統每 tick 耗時的滾動百分位（依 p95 排序）。
+func gmTickStat(sess *net.Session, args []string, deps *Deps) {
+	if deps.Runner == nil {
+		gmMsg(sess, "\\f3tick 分析未啟用")
+		return
+	}
+	limit := 10
+	if len(args) > 0 {
+		if n, err := strconv.Atoi(args[0]); err == nil && n > 0 {
+			limit = n
+		}
+	}
+	prof := deps.Runner.Profile()
+	t := prof.Tick
+	gmMsgf(sess, "=== Tick（%d 筆）p50:%s p95:%s p99:%s 最大:%s 預算:%s 超時:%d ===",
+		t.Samples, gmMs(t.P50), gmMs(t.P95), gmMs(t.P99), gmMs(t.Max), gmMs(prof.Budget), prof.Overruns)
+	gmMsgf(sess, "輸入輪詢 p50:%s p95:%s 最大:%s", gmMs(prof.Poll.P50), gmMs(prof.Poll.P95), gmMs(prof.Poll.Max))
+	for i, st := range prof.Systems {
+		if i >= limit {
+			break
+		}
+		gmMsgf(sess, "[%d] %s p50:%s p95:%s p99:%s 最大:%s",
+			st.Phase, st.Name, gmMs(st.P50), gmMs(st.P95), gmMs(st.P99), gmMs(st.Max))
+	}
+}
+
+// gmMs 將時間格式化為毫秒（小數兩位），縮短遊戲內訊息長度。
+func gmMs(d time.Duration) string {
+	return fmt.Sprintf("%.2fms", float64(d)/float64(time.Millisecond))
+}
+
+// gmItemHistory 列出指定物品實例（obj_id）的來源歷史：建立、轉手、存取倉庫、掉落撿取、衝裝、銷毀。
+func gmItemHistory(sess *net.Session, args []string, deps *Deps) {
+	if deps.ItemEvents == nil {
+		gmMsg(sess, "\\f3物品稽核未啟用")
+		return
+	}
+	if len(args) < 1 {
+		gmMsg(sess, "用法: .itemhistory <物品ObjID> [筆數]")
+		return
+	}
+	objID, err := strconv.ParseInt(args[0], 10, 32)
+	if err != nil {
+		gmMsg(sess, "\\f3無效的 ObjID")
+		return
+	}
+	limit := 50
+	if len(args) > 1 {
+		if n, err := strconv.Atoi(args[1]); err == nil && n > 0 {
+			limit = n
+		}
+	}
+
+	var events []persist.ItemEventRow
+	RunDBJob(sess, deps, "gm_item_history", func(ctx context.Context) error {
+		var err error
+		events, err = deps.ItemEvents.History(ctx, int32(objID), limit)
+		return err
+	}, func(err error) {
+		if err != nil {
+			gmMsgf(sess, "\\f3查詢失敗: %v", err)
+			return
+		}
+		if len(events) == 0 {
+			gmMsgf(sess, "物品 %d 無歷史記錄", objID)
+			return
+		}
+		gmMsgf(sess, "=== 物品 %d 歷史（%d 筆）===", objID, len(events))
+		for _, e := range events {
+			from, to := e.From, e.To
+			if from == "" {
+				from = "-"
+			}
+			if to == "" {
+				to = "-"
+			}
+			gmMsgf(sess, "%s %s 物品:%d +%d x%d %s→%s 地圖:%d (%d,%d)",
+				e.CreatedAt.Format("01-02 15:04:05"), e.Reason, e.ItemID, e.EnchantLvl, e.Count,
+				from, to, e.MapID, e.X, e.Y)
+		}
+	})
+}
+
+// gmLoginLock 列出因登入嘗試過多而鎖定的 IP，或解除指定 IP 的鎖定。
+func gmLoginLock(sess *net.Session, args []string, deps *Deps) {
+	if deps.LoginLimit == nil {
+		gmMsg(sess, "\\f3登入嘗試限制未啟用")
+		return
+	}
+	now := deps.Clock.Now()
+	if len(args) > 0 {
+		if args[0] != "unlock" || len(args) < 2 {
+			gmMsg(sess, "用法: .loginlock [unlock <IP>]")
+			return
+		}
+		if !deps.LoginLimit.Unlock(args[1], now) {
+			gmMsgf(sess, "\\f3IP %s 未被鎖定", args[1])
+			return
+		}
+		gmMsgf(sess, "已解除 IP %s 的登入鎖定", args[1])
+		deps.Log.Info(fmt.Sprintf("GM 解除登入鎖定  GM=%s  ip=%s", sess.CharName, args[1]))
+		return
+	}
+	locks := deps.LoginLimit.Locks(now)
+	if len(locks) == 0 {
+		gmMsg(sess, "目前沒有鎖定的 IP")
+		return
+	}
+	gmMsgf(sess, "=== 登入鎖定 IP（%d 筆）===", len(locks))
+	for _, l := range locks {
+		gmMsgf(sess, "%s  剩餘 %d 秒  累計鎖定 %d 次",
+			l.IP, int(l.Until.Sub(now).Seconds())+1, l.Lockouts)
+	}
+}
+
+// banInfoLimit 是 .baninfo 顯示的記錄數。
+const banInfoLimit = 10
+
+// gmBan 封鎖帳號（依角色名或帳號名）或 IP 網段，並立即斷開符合的連線。
+func gmBan(sess *net.Session, player *world.PlayerInfo, args []string, deps *Deps) {
+	if deps.BanRepo == nil {
+		gmMsg(sess, "\\f3封鎖功能未啟用")
+		return
+	}
+	if len(args) < 2 {
+		gmMsg(sess, "用法: .ban <角色名|帳號|IP[/前綴]> <期間(30m/12h/7d，0=永久)> [原因]")
+		return
+	}
+	dur, ok := parseBanDuration(args[1])
+	if !ok {
+		gmMsg(sess, "\\f3無效的期間（例: 30m、12h、7d，0 = 永久）")
+		return
+	}
+	ban := &persist.BanRow{Reason: strings.Join(args[2:], " "), Issuer: player.Name}
+	if dur > 0 {
+		expires := deps.Clock.Now().Add(dur)
+		ban.ExpiresAt = &expires
+	}
+	target := args[0]
+	if cidr, ok := parseBanCIDR(target); ok {
+		ban.CIDR = cidr
+	} else if p := deps.World.GetByName(target); p != nil {
+		ban.Account = p.Session.AccountName
+	}
+
+	RunDBJob(sess, deps, "gm_ban", func(ctx context.Context) error {
+		if ban.Target() == "" {
+			account, err := lookupBanAccount(ctx, deps, target)
+			if err != nil || account == "" {
+				return err
+			}
+			ban.Account = account
+		}
+		return deps.BanRepo.Add(ctx, ban)
+	}, func(err error) {
+		if err != nil {
+			gmMsgf(sess, "\\f3封鎖失敗: %v", err)
+			return
+		}
+		if ban.Target() == "" {
+			gmMsgf(sess, "\\f3找不到角色或帳號: %s", target)
+			return
+		}
+		kicked := kickBanned(deps, ban)
+		gmMsgf(sess, "已封鎖 %s（%s），斷開 %d 個連線", ban.Target(), banExpiry(ban), kicked)
+		deps.Log.Info(fmt.Sprintf("GM 封鎖  GM=%s  對象=%s  期間=%s  原因=%s  斷線=%d",
+			player.Name, ban.Target(), banExpiry(ban), ban.Reason, kicked))
+	})
+}
+
+// gmUnban 解除帳號或 IP 網段上所有生效中的封鎖（IP 須與封鎖時的網段完全相同）。
+func gmUnban(sess *net.Session, player *world.PlayerInfo, args []string, deps *Deps) {
+	if deps.BanRepo == nil {
+		gmMsg(sess, "\\f3封鎖功能未啟用")
+		return
+	}
+	if len(args) < 1 {
+		gmMsg(sess, "用法: .unban <角色名|帳號|IP[/前綴]>")
+		return
+	}
+	target := args[0]
+	cidr, isIP := parseBanCIDR(target)
+	var account string
+	var lifted int64
+	RunDBJob(sess, deps, "gm_unban", func(ctx context.Context) error {
+		var err error
+		if !isIP {
+			if account, err = lookupBanAccount(ctx, deps, target); err != nil || account == "" {
+				return err
+			}
+		}
+		lifted, err = deps.BanRepo.Lift(ctx, account, cidr, player.Name)
+		return err
+	}, func(err error) {
+		if err != nil {
+			gmMsgf(sess, "\\f3解除封鎖失敗: %v", err)
+			return
+		}
+		if !isIP && account == "" {
+			gmMsgf(sess, "\\f3找不到角色或帳號: %s", target)
+			return
+		}
+		if lifted == 0 {
+			gmMsgf(sess, "%s 沒有生效中的封鎖（IP 網段須完全相同，可用 .baninfo 查詢）", target)
+			return
+		}
+		gmMsgf(sess, "已解除 %s 的 %d 筆封鎖", target, lifted)
+		deps.Log.Info(fmt.Sprintf("GM 解除封鎖  GM=%s  對象=%s  筆數=%d", player.Name, target, lifted))
+	})
+}
+
+// gmBanInfo 列出帳號或 IP 的封鎖記錄（含已到期與已解除）。角色在線時一併列出其 IP 的封鎖。
+func gmBanInfo(sess *net.Session, args []string, deps *Deps) {
+	if deps.BanRepo == nil {
+		gmMsg(sess, "\\f3封鎖功能未啟用")
+		return
+	}
+	if len(args) < 1 {
+		gmMsg(sess, "用法: .baninfo <角色名|帳號|IP>")
+		return
+	}
+	target := args[0]
+	ip, isIP := parseBanCIDR(target)
+	var account string
+	if p := deps.World.GetByName(target); !isIP && p != nil {
+		account, ip = p.Session.AccountName, p.Session.Host()
+	}
+	var bans []persist.BanRow
+	RunDBJob(sess, deps, "gm_baninfo", func(ctx context.Context) error {
+		var err error
+		if !isIP && account == "" {
+			if account, err = lookupBanAccount(ctx, deps, target); err != nil || account == "" {
+				return err
+			}
+		}
+		bans, err = deps.BanRepo.History(ctx, account, ip, banInfoLimit)
+		return err
+	}, func(err error) {
+		if err != nil {
+			gmMsgf(sess, "\\f3查詢失敗: %v", err)
+			return
+		}
+		if !isIP && account == "" {
+			gmMsgf(sess, "\\f3找不到角色或帳號: %s", target)
+			return
+		}
+		if len(bans) == 0 {
+			gmMsgf(sess, "%s 無封鎖記錄", target)
+			return
+		}
+		now := deps.Clock.Now()
+		gmMsgf(sess, "=== %s 封鎖記錄（%d 筆）===", target, len(bans))
+		for i := range bans {
+			b := &bans[i]
+			state := "生效中"
+			switch {
+			case b.LiftedAt != nil:
+				state = "已由 " + b.LiftedBy + " 解除"
+			case !b.Active(now):
+				state = "已到期"
+			}
+			gmMsgf(sess, "#%d %s %s（%s）%s  封鎖者:%s  原因:%s",
+				b.ID, b.CreatedAt.Format("01-02 15:04"), b.Target(), banExpiry(b), state, b.Issuer, b.Reason)
+		}
+	})
+}
+
+// lookupBanAccount 依角色名、再依帳號名找出帳號；都找不到時回傳空字串。在 DB worker 執行。
+func lookupBanAccount(ctx context.Context, deps *Deps, name string) (string, error) {
+	ch, err := deps.CharRepo.LoadByName(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if ch != nil {
+		return ch.AccountName, nil
+	}
+	acc, err := deps.AccountRepo.Load(ctx, strings.ToLower(name))
+	if err != nil || acc == nil {
+		return "", err
+	}
+	return acc.Name, nil
+}
+
+// kickBanned 斷開符合封鎖的所有連線（含角色選擇畫面），回傳斷開數。
+// 斷線清理照常存檔並移出世界。
+func kickBanned(deps *Deps, ban *persist.BanRow) int {
+	var prefix netip.Prefix
+	if ban.CIDR != "" {
+		var err error
+		if prefix, err = netip.ParsePrefix(ban.CIDR); err != nil {
+			return 0
+		}
+	}
+	n := 0
+	kick := func(s *net.Session) {
+		if s.IsClosed() {
+			return
+		}
+		if ban.Account != "" {
+			if s.AccountName != ban.Account {
+				return
+			}
+		} else if addr, err := netip.ParseAddr(s.Host()); err != nil || !prefix.Contains(addr.Unmap()) {
+			return
+		}
+		s.Close()
+		n++
+	}
+	if deps.Sessions != nil {
+		deps.Sessions.ForEach(kick)
+	} else {
+		deps.World.AllPlayers(func(p *world.PlayerInfo) { kick(p.Session) })
+	}
+	return n
+}
+
+// parseBanCIDR 將 IP 或 IP/前綴正規化為網段字串（單一 IP 為 /32 或 /128）。
+func parseBanCIDR(s string) (string, bool) {
+	if prefix, err := netip.ParsePrefix(s); err == nil {
+		return prefix.Masked().String(), true
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return "", false
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()).String(), true
+}
+
+// parseBanDuration 解析封鎖期間：<數字>m / h / d，0 表示永久。
+func parseBanDuration(s string) (time.Duration, bool) {
+	if s == "0" {
+		return 0, true
+	}
+	if len(s) < 2 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	switch s[len(s)-1] {
+	case 'm':
+		return time.Duration(n) * time.Minute, true
+	case 'h':
+		return time.Duration(n) * time.Hour, true
+	case 'd':
+		return time.Duration(n) * 24 * time.Hour, true
+	}
+	return 0, false
+}
+
+// banExpiry 描述封鎖期限。
+func banExpiry(b *persist.BanRow) string {
+	if b.ExpiresAt == nil {
+		return "永久"
+	}
+	return "至 " + b.ExpiresAt.Format("2006-01-02 15:04")
+}
+
+// gmCheatLog 列出最近的反作弊標記（可指定角色）。
+func gmCheatLog(sess *net.Session, args []string, deps *Deps) {
+	if deps.CheatFlags == nil {
+		gmMsg(sess, "\\f3反作弊標記未啟用")
+		return
+	}
+	var name string
+	limit := 20
+	for _, a := range args {
+		if n, err := strconv.Atoi(a); err == nil && n > 0 {
+			limit = n
+		} else {
+			name = a
+		}
+	}
+
+	var flags []persist.CheatFlagRow
+	RunDBJob(sess, deps, "gm_cheat_log", func(ctx context.Context) error {
+		var err error
+		flags, err = deps.CheatFlags.Recent(ctx, name, limit)
+		return err
+	}, func(err error) {
+		if err != nil {
+			gmMsgf(sess, "\\f3查詢失敗: %v", err)
+			return
+		}
+		if len(flags) == 0 {
+			gmMsg(sess, "無反作弊標記")
+			return
+		}
+		gmMsgf(sess, "=== 反作弊標記（%d 筆）===", len(flags))
+		for _, f := range flags {
+			gmMsgf(sess, "%s %s [%s/%s] 違規%d 帳號:%s ip:%s 地圖:%d (%d,%d) %s",
+				f.CreatedAt.Format("01-02 15:04:05"), f.CharName, f.Kind, f.Action, f.Violations,
+				f.Account, f.IP, f.MapID, f.X, f.Y, f.Detail)
+		}
+	})
+}
+
+// gmCapture 切換指定角色連線的封包擷取；無參數時列出擷取中的角色。
+func gmCapture(sess *net.Session, args []string, deps *Deps) {
+	if len(args) == 0 {
+		n := 0
+		deps.World.AllPlayers(func(p *world.PlayerInfo) {
+			if c := p.Session.Capturing(); c != nil {
+				gmMsgf(sess, "%s → %s（%d 個封包）", p.Name, c.Path, c.Frames())
+				n++
+			}
+		})
+		if n == 0 {
+			gmMsg(sess, "目前沒有擷取中的連線")
+		}
+		return
+	}
+	target := deps.World.GetByName(args[0])
+	if target == nil {
+		gmMsgf(sess, "\\f3角色 %s 不在線上", args[0])
+		return
+	}
+	on := target.Session.Capturing() == nil
+	if len(args) >= 2 {
+		switch args[1] {
+		case "on":
+			on = true
+		case "off":
+			on = false
+		default:
+			gmMsg(sess, "用法: .capture [角色名] [on|off]")
+			return
+		}
+	}
+	if !on {
+		if c := StopCapture(target.Session, deps); c != nil {
+			gmMsgf(sess, "已停止擷取 %s：%s（%d 個封包）", target.Name, c.Path, c.Frames())
+		} else {
+			gmMsgf(sess, "%s 未在擷取中", target.Name)
+		}
+		return
+	}
+	c, err := StartCapture(target.Session, target.Name, deps)
+	if err != nil {
+		gmMsgf(sess, "\\f3擷取檔建立失敗: %v", err)
+		return
+	}
+	gmMsgf(sess, "開始擷取 %s：%s", target.Name, c.Path)
+}
@@ -75,7 +75,7 @@ func handleInnRoom(sess *net.Session, player *world.PlayerInfo, npcObjID, npcID
 		return
 	}
 
-	now := time.Now()
+	now := deps.Clock.Now()
 	canRent := false
 	findRoom := false
 	isRented := false
@@ -144,7 +144,7 @@ func handleInnHall(sess *net.Session, player *world.PlayerInfo, npcObjID, npcID
 		return
 	}
 
-	now := time.Now()
+	now := deps.Clock.Now()
 	canRent := false
 	findRoom := false
 	isRented := false
@@ -213,7 +213,7 @@ func handleInnEnter(sess *net.Session, player *world.PlayerInfo, npcID int32, de
 	}
 
 	rooms := deps.InnRooms[npcID]
-	now := time.Now()
+	now := deps.Clock.Now()
 
 	for _, item := range player.Inv.Items {
 		if item.InnNpcID != npcID {
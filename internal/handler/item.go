@@ -37,11 +37,11 @@ func hasItemDelay(player *world.PlayerInfo, delayID int, now time.Time) bool {
 
 // setItemDelay 設定物品使用延遲到期時間。
 // delayTimeMs 為延遲毫秒數。
-func setItemDelay(player *world.PlayerInfo, delayID int, delayTimeMs int) {
+func setItemDelay(player *world.PlayerInfo, delayID int, delayTimeMs int, now time.Time) {
 	if player.ItemDelays == nil {
 		player.ItemDelays = make(map[int]time.Time)
 	}
-	player.ItemDelays[delayID] = time.Now().Add(time.Duration(delayTimeMs) * time.Millisecond)
+	player.ItemDelays[delayID] = now.Add(time.Duration(delayTimeMs) * time.Millisecond)
 }
 
 // Virtual SkillIDs for potion-based buffs (matching Java L1SkillId.java STATUS_* constants).
@@ -430,7 +430,7 @@ func handleUseEtcItem(sess *net.Session, r *packet.Reader, player *world.PlayerI
 	}
 
 	// 物品使用延遲檢查（Java: L1ItemDelay）
-	now := time.Now()
+	now := deps.Clock.Now()
 	if itemInfo.DelayID != 0 {
 		if hasItemDelay(player, itemInfo.DelayID, now) {
 			return // 冷卻中 → 靜默拒絕（與 Java 行為一致）
@@ -455,7 +455,7 @@ func handleUseEtcItem(sess *net.Session, r *packet.Reader, player *world.PlayerI
 	if deps.ItemUse != nil {
 		consumed := deps.ItemUse.UseConsumable(sess, player, invItem, itemInfo)
 		if consumed && itemInfo.DelayID != 0 && itemInfo.DelayTime != 0 {
-			setItemDelay(player, itemInfo.DelayID, itemInfo.DelayTime)
+			setItemDelay(player, itemInfo.DelayID, itemInfo.DelayTime, now)
 		}
 	}
 }
@@ -916,7 +916,7 @@ func openItemBox(sess *net.Session, player *world.PlayerInfo, invItem *world.Inv
 		// 消耗寶箱
 		deps.ItemUse.ConsumeBoxItem(sess, player, invItem)
 		// 抽取物品
-		rolled := deps.ItemBoxes.RollBox(itemID)
+		rolled := deps.ItemBoxes.RollBox(deps.Rand, itemID)
 		if rolled == nil {
 			return true
 		}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/l1jgo/server/internal/core/event"
+	"github.com/l1jgo/server/internal/world"
+)
+
+// ItemLocGround 是地面物品的位置字串。
+const ItemLocGround = "ground"
+
+// ItemLocShopCn 是天寶幣商城的位置字串（item_events 的 from/to 與 WAL target）。
+const ItemLocShopCn = "shop_cn"
+
+// CharLoc 回傳角色背包的位置字串（item_events 的 from/to）。
+func CharLoc(charID int32) string {
+	return fmt.Sprintf("char:%d", charID)
+}
+
+// NpcLoc 回傳 NPC（商店、製作）的位置字串。
+func NpcLoc(npcID int32) string {
+	return fmt.Sprintf("npc:%d", npcID)
+}
+
+// EmitItemMoved 發出物品來源稽核事件（system.ItemAuditSystem 寫入 item_events）。
+// count 為本次移動的數量；from/to 為空字串表示物品被建立/銷毀。at 提供地圖座標，可為 nil。
+// 金幣不記錄（金幣流向由經濟 WAL 追蹤）。須在物品仍帶有移動時狀態時呼叫（例如衝裝後的強化值）。
+func EmitItemMoved(deps *Deps, reason string, it *world.InvItem, count int32, from, to string, at *world.PlayerInfo) {
+	if deps.Bus == nil || it == nil || it.ItemID == world.AdenaItemID || count <= 0 {
+		return
+	}
+	ev := event.ItemMoved{
+		ObjID:      it.ObjectID,
+		ItemID:     it.ItemID,
+		Count:      count,
+		EnchantLvl: it.EnchantLvl,
+		From:       from,
+		To:         to,
+		Reason:     reason,
+		At:         deps.Clock.Now(),
+	}
+	if at != nil {
+		ev.MapID, ev.X, ev.Y = at.MapID, at.X, at.Y
+	}
+	event.Emit(deps.Bus, ev)
+}
+
+// EmitGroundItemExpired 發出地面物品逾時消失的稽核事件（座標為物品所在位置）。
+// 堆疊物品在地面上沒有原 ObjectID（GroundItem.ObjID = 0），事件只記錄物品種類與數量。
+func EmitGroundItemExpired(deps *Deps, g *world.GroundItem) {
+	if deps.Bus == nil || g.ItemID == world.AdenaItemID || g.Count <= 0 {
+		return
+	}
+	event.Emit(deps.Bus, event.ItemMoved{
+		ObjID:      g.ObjID,
+		ItemID:     g.ItemID,
+		Count:      g.Count,
+		EnchantLvl: g.EnchantLvl,
+		From:       ItemLocGround,
+		Reason:     event.ItemReasonGroundExpire,
+		MapID:      g.MapID,
+		X:          g.X,
+		Y:          g.Y,
+		At:         deps.Clock.Now(),
+	})
+}
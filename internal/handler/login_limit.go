@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"sort"
+	"time"
+)
+
+// loginLimitWindow 是登入嘗試計數的滑動視窗長度。
+const loginLimitWindow = time.Minute
+
+// LoginLimiter 以滑動視窗限制每個 IP 的登入嘗試次數（rate_limit.login_attempts_per_minute）。
+// 視窗內嘗試數達上限的 IP 被鎖定 lockout，期間的登入直接拒絕、不執行 bcrypt 驗證。
+// 每次嘗試（不論成敗）都計入。遊戲迴圈專用。
+type LoginLimiter struct {
+	maxAttempts int
+	lockout     time.Duration
+	ips         map[string]*loginAttempts
+	lastSweep   time.Time
+}
+
+// loginAttempts 是單一 IP 的嘗試記錄。
+type loginAttempts struct {
+	times       []time.Time // 視窗內的嘗試時間（由舊到新）
+	lockedUntil time.Time
+	lockouts    int // 累計鎖定次數
+}
+
+// LoginLock 是一筆鎖定中的 IP（GM 查詢用）。
+type LoginLock struct {
+	IP       string
+	Until    time.Time
+	Lockouts int
+}
+
+func NewLoginLimiter(attemptsPerMinute int, lockout time.Duration) *LoginLimiter {
+	return &LoginLimiter{
+		maxAttempts: attemptsPerMinute,
+		lockout:     lockout,
+		ips:         make(map[string]*loginAttempts),
+	}
+}
+
+// Allow 記錄 ip 的一次登入嘗試並回傳是否放行。locked 表示本次嘗試觸發了新的鎖定。
+func (l *LoginLimiter) Allow(ip string, now time.Time) (ok, locked bool) {
+	l.sweep(now)
+	a := l.ips[ip]
+	if a == nil {
+		a = &loginAttempts{}
+		l.ips[ip] = a
+	}
+	if now.Before(a.lockedUntil) {
+		return false, false
+	}
+	a.prune(now)
+	if len(a.times) >= l.maxAttempts {
+		a.lockedUntil = now.Add(l.lockout)
+		a.lockouts++
+		a.times = a.times[:0]
+		return false, true
+	}
+	a.times = append(a.times, now)
+	return true, false
+}
+
+// Locks 回傳目前鎖定中的 IP，依解鎖時間排序。
+func (l *LoginLimiter) Locks(now time.Time) []LoginLock {
+	var locks []LoginLock
+	for ip, a := range l.ips {
+		if now.Before(a.lockedUntil) {
+			locks = append(locks, LoginLock{IP: ip, Until: a.lockedUntil, Lockouts: a.lockouts})
+		}
+	}
+	sort.Slice(locks, func(i, j int) bool { return locks[i].Until.Before(locks[j].Until) })
+	return locks
+}
+
+// Unlock 解除 ip 的鎖定並清空其嘗試記錄；ip 原本未鎖定時回傳 false。
+func (l *LoginLimiter) Unlock(ip string, now time.Time) bool {
+	a := l.ips[ip]
+	if a == nil || !now.Before(a.lockedUntil) {
+		return false
+	}
+	delete(l.ips, ip)
+	return true
+}
+
+// prune 移除視窗外的嘗試。
+func (a *loginAttempts) prune(now time.Time) {
+	cutoff := now.Add(-loginLimitWindow)
+	n := 0
+	for n < len(a.times) && !a.times[n].After(cutoff) {
+		n++
+	}
+	a.times = a.times[n:]
+}
+
+// sweep 每個視窗長度清理一次已無嘗試、也未鎖定的 IP，避免記錄無限成長。
+func (l *LoginLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < loginLimitWindow {
+		return
+	}
+	l.lastSweep = now
+	for ip, a := range l.ips {
+		a.prune(now)
+		if len(a.times) == 0 && !now.Before(a.lockedUntil) {
+			delete(l.ips, ip)
+		}
+	}
+}
@@ -1,9 +1,6 @@
 package handler
 
 import (
-	"math/rand"
-	"time"
-
 	"github.com/l1jgo/server/internal/net"
 	"github.com/l1jgo/server/internal/net/packet"
 	"github.com/l1jgo/server/internal/world"
@@ -47,17 +44,18 @@ func HandleMove(sess *net.Session, r *packet.Reader, deps *Deps) {
 	}
 
 	// --- 移動速度驗證（反加速外掛） ---
-	// 一般走路 ~200ms，加速 ~133ms。套用 50% 容許值（避免 tick 批次處理導致誤判）。
-	// 誤判時靜默丟棄（不觸發 rejectMove），避免全畫面彈回造成卡頓。
-	now := time.Now().UnixNano()
-	minInterval := int64(100_000_000) // 200ms * 50% = 100ms
-	if player.MoveSpeed == 1 {
-		minInterval = 66_000_000 // 133ms * 50% = 66ms
-	}
-	if player.LastMoveTime > 0 && (now-player.LastMoveTime) < minInterval {
-		return // 靜默丟棄：不發封包、不更新座標、不更新 LastMoveTime
+	// 依外型的步行動畫時間與加速狀態判定（system.AntiCheatSystem）；
+	// 單步快於預期一半靜默丟棄（不回彈，避免網路擠壓時全畫面彈回）；
+	// 整體節奏違規累積到門檻後回彈，再繼續則踢除。
+	if deps.AntiCheat != nil {
+		switch deps.AntiCheat.CheckMove(player) {
+		case CheatReject:
+			rejectMove(sess, player, ws, deps)
+			return
+		case CheatKick, CheatDrop:
+			return
+		}
 	}
-	player.LastMoveTime = now
 
 	// 永遠使用伺服器端座標（安全性考量，所有語系統一）
 	curX := player.X
@@ -79,7 +77,7 @@ func HandleMove(sess *net.Session, r *packet.Reader, deps *Deps) {
 	if deps.Portals != nil {
 		if portal := deps.Portals.Get(destX, destY, player.MapID); portal != nil {
 			// 船舶碼頭需額外驗證航線時間和船票
-			isDock, allowed := CheckShipDock(destX, destY, player.MapID, player)
+			isDock, allowed := CheckShipDock(destX, destY, player.MapID, player, deps)
 			if !isDock || allowed {
 				// 一般傳送門或碼頭驗證通過 → 傳送（不移動到 destX/destY）
 				cancelTradeIfActive(player, deps)
@@ -94,7 +92,7 @@ func HandleMove(sess *net.Session, r *packet.Reader, deps *Deps) {
 	// Java: C_MoveChar → DungeonRTable.dg() 在 DungeonTable 之後檢查
 	if deps.RandomPortals != nil {
 		if rp := deps.RandomPortals.Get(destX, destY, player.MapID); rp != nil && len(rp.Destinations) > 0 {
-			idx := rand.Intn(len(rp.Destinations))
+			idx := deps.Rand.Intn(len(rp.Destinations))
 			dst := rp.Destinations[idx]
 			cancelTradeIfActive(player, deps)
 			teleportPlayer(sess, player, dst.X, dst.Y, dst.MapID, rp.DstHeading, deps)
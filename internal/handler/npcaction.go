@@ -302,7 +302,7 @@ func HandleNpcAction(sess *net.Session, r *packet.Reader, deps *Deps) {
 		// Check teleport destinations (handles "teleport xxx" and other
 		// action names like "Strange21", "goto battle ring", "a"/"b"/etc.)
 		if deps.Teleports.Get(npc.NpcID, action) != nil {
-			handleTeleport(sess, player, npc.NpcID, action, deps)
+			handleTeleport(sess, player, npc.NpcID, objID, action, deps)
 			return
 		}
 
@@ -524,7 +524,7 @@ func sendNoSell(sess *net.Session, objID int32) {
 
 // handleTeleport processes a "teleport xxx" action from the NPC dialog.
 // Looks up the destination, checks adena cost, and teleports the player.
-func handleTeleport(sess *net.Session, player *world.PlayerInfo, npcID int32, action string, deps *Deps) {
+func handleTeleport(sess *net.Session, player *world.PlayerInfo, npcID, objID int32, action string, deps *Deps) {
 	dest := deps.Teleports.Get(npcID, action)
 	if dest == nil {
 		deps.Log.Debug("teleport destination not found",
@@ -536,7 +536,7 @@ func handleTeleport(sess *net.Session, player *world.PlayerInfo, npcID int32, ac
 
 	// 委派給 NpcServiceSystem 處理扣費 + 傳送
 	if deps.NpcSvc != nil {
-		deps.NpcSvc.NpcTeleportWithCost(sess, player, dest, 0)
+		deps.NpcSvc.NpcTeleportWithCost(sess, player, dest, objID)
 	}
 
 	deps.Log.Info(fmt.Sprintf("玩家傳送  角色=%s  動作=%s  x=%d  y=%d  地圖=%d  花費=%d", player.Name, action, dest.X, dest.Y, dest.MapID, dest.Price))
@@ -558,7 +558,24 @@ func TeleportPlayer(sess *net.Session, player *world.PlayerInfo, x, y int32, map
 	teleportPlayer(sess, player, x, y, mapID, heading, deps)
 }
 
+// TeleportPlayerFrom 處理玩家發起的傳送：src 描述來源路徑與授權者，先經 AntiCheatManager.CheckTeleport
+// 驗證。未通過時解除客戶端傳送鎖定並回傳 false（呼叫端不應消耗傳送物品）。
+func TeleportPlayerFrom(sess *net.Session, player *world.PlayerInfo, x, y int32, mapID, heading int16, src world.TeleportSource, deps *Deps) bool {
+	return teleportPlayerFrom(sess, player, x, y, mapID, heading, src, deps)
+}
+
 func teleportPlayer(sess *net.Session, player *world.PlayerInfo, x, y int32, mapID, heading int16, deps *Deps) {
+	teleportPlayerFrom(sess, player, x, y, mapID, heading, world.TeleportSource{}, deps)
+}
+
+func teleportPlayerFrom(sess *net.Session, player *world.PlayerInfo, x, y int32, mapID, heading int16, src world.TeleportSource, deps *Deps) bool {
+	// 玩家發起的傳送（零值來源為伺服器主動傳送，不驗證）
+	if src.Path != "" && deps.AntiCheat != nil && !deps.AntiCheat.CheckTeleport(player, x, y, mapID, src) {
+		sendServerMessage(sess, 79) // "沒有任何事情發生"
+		sendTeleportUnlock(sess)
+		return false
+	}
+
 	// 傳送時釋放血盟倉庫鎖定（Java: Teleportation.java 行 122-123）
 	if player.ClanID != 0 {
 		if clan := deps.World.Clans.GetClan(player.ClanID); clan != nil {
@@ -775,6 +792,7 @@ func teleportPlayer(sess *net.Session, player *world.PlayerInfo, x, y int32, map
 
 	// Release client teleport lock (Java: S_Paralysis always sent in finally block).
 	sendTeleportUnlock(sess)
+	return true
 }
 
 // handleYesNoResponse processes S_Message_YN dialog responses.
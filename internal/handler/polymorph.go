@@ -1,7 +1,7 @@
 package handler
 
 import (
-	"math/rand/v2"
+	"math/rand"
 
 	"github.com/l1jgo/server/internal/net"
 	"github.com/l1jgo/server/internal/net/packet"
@@ -146,14 +146,14 @@ func HandleHypertextInputResult(sess *net.Session, r *packet.Reader, deps *Deps)
 // PolyScrollDuration returns the polymorph duration in seconds for the given scroll type.
 // Java: C_ItemUSe.usePolyScroll() lines 3166-3174
 // Exported for system package usage.
-func PolyScrollDuration(itemID int32) int {
+func PolyScrollDuration(rng *rand.Rand, itemID int32) int {
 	switch itemID {
 	case ItemPolyScroll, ItemIvoryTowerPoly:
 		return 1800 // 30 minutes
 	case ItemBlessedPolyScroll:
 		return 2100 // 35 minutes
 	case ItemWelfarePolyPotion:
-		return 2401 + rand.IntN(2400) // 2401-4800 seconds (40-80 min)
+		return 2401 + rng.Intn(2400) // 2401-4800 seconds (40-80 min)
 	}
 	return 1800
 }
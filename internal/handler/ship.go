@@ -2,6 +2,7 @@ package handler
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/l1jgo/server/internal/net"
 	"github.com/l1jgo/server/internal/net/packet"
@@ -51,8 +52,8 @@ var shipGroupBWindows = [][2]int{
 
 // isShipScheduleOpen 檢查指定航線群組是否在運營時間內。
 // Java: DungeonTable.dg() — (servertime % 86400) 對照時間窗口。
-func isShipScheduleOpen(group shipScheduleGroup) bool {
-	nowtime := world.GameTimeNow().Seconds() % 86400
+func isShipScheduleOpen(group shipScheduleGroup, now time.Time) bool {
+	nowtime := world.GameTimeAt(now).Seconds() % 86400
 	var windows [][2]int
 	if group == shipGroupA {
 		windows = shipGroupAWindows
@@ -152,13 +153,13 @@ var shipDocks = map[shipDockKey]shipDockInfo{
 // CheckShipDock 檢查指定座標是否為船舶碼頭，若是則驗證航線時間和船票。
 // 返回 (是否為碼頭, 是否通過驗證)。
 // Java: DungeonTable.dg() 中的 DungeonType 判定 + 時間/物品檢查。
-func CheckShipDock(x, y int32, mapID int16, player *world.PlayerInfo) (isDock, allowed bool) {
+func CheckShipDock(x, y int32, mapID int16, player *world.PlayerInfo, deps *Deps) (isDock, allowed bool) {
 	info, ok := shipDocks[shipDockKey{x, y, mapID}]
 	if !ok {
 		return false, false
 	}
 	// 檢查航線排程時間
-	if !isShipScheduleOpen(info.group) {
+	if !isShipScheduleOpen(info.group, deps.Clock.Now()) {
 		return true, false
 	}
 	// 檢查船票（只檢查不消耗，Java: checkItem）
@@ -56,7 +56,7 @@ func handlePagedTeleportAction(sess *net.Session, player *world.PlayerInfo, npc
 			return
 		}
 		dest := &dests[idx]
-		executeTeleportPage(sess, player, dest, deps)
+		executeTeleportPage(sess, player, npc.ID, dest, deps)
 
 	default:
 		// Category selection — action is the category name (e.g., "A", "B", "H01")
@@ -119,25 +119,17 @@ func showTelePage(sess *net.Session, player *world.PlayerInfo, npcObjID int32, d
 
 // executeTeleportPage performs the actual teleport for a paginated destination.
 // Checks level restriction, item cost (adena), and teleports the player.
-func executeTeleportPage(sess *net.Session, player *world.PlayerInfo, dest *data.TeleportPageDest, deps *Deps) {
+func executeTeleportPage(sess *net.Session, player *world.PlayerInfo, npcObjID int32, dest *data.TeleportPageDest, deps *Deps) {
 	// Level check
 	if dest.MaxLevel > 0 && player.Level > dest.MaxLevel {
 		sendServerMessage(sess, 79) // "沒有任何事情發生"
 		return
 	}
 
-	// Item cost check (item_id 40308 = adena)
-	if dest.Price > 0 {
-		currentGold := player.Inv.GetAdena()
-		if currentGold < dest.Price {
-			sendServerMessage(sess, 189) // "金幣不足"
-			return
-		}
-
-		// Deduct adena
-		if !deps.NpcSvc.ConsumeAdena(sess, player, dest.Price) {
-			return
-		}
+	// Item cost check (item_id 40308 = adena)；費用在傳送通過驗證後才扣除（ScrollTeleportSystem）
+	if dest.Price > 0 && player.Inv.GetAdena() < dest.Price {
+		sendServerMessage(sess, 189) // "金幣不足"
+		return
 	}
 
 	// Clear teleport session state
@@ -155,6 +147,8 @@ func executeTeleportPage(sess *net.Session, player *world.PlayerInfo, dest *data
 	player.ScrollTPX = dest.X
 	player.ScrollTPY = dest.Y
 	player.ScrollTPMap = dest.MapID
+	player.ScrollTPSrc = world.TeleportSource{Path: world.TeleportPathNpc, NpcObjID: npcObjID}
+	player.ScrollTPFee = dest.Price
 
 	deps.Log.Info(fmt.Sprintf("分頁傳送  角色=%s  目的地=%s  x=%d  y=%d  地圖=%d  花費=%d",
 		player.Name, dest.Name, dest.X, dest.Y, dest.MapID, dest.Price))
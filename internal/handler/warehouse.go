@@ -3,7 +3,6 @@ package handler
 import (
 	"context"
 	"fmt"
-	"time"
 
 	"github.com/l1jgo/server/internal/net"
 	"github.com/l1jgo/server/internal/net/packet"
@@ -243,11 +242,7 @@ func handleWarehousePasswordChange(sess *net.Session, player *world.PlayerInfo,
 	if pass1 < 0 && player.WarehousePassword == 0 {
 		// 首次設定密碼（帳號尚無倉庫密碼）
 		player.WarehousePassword = pass2
-		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-		defer cancel()
-		if err := deps.AccountRepo.UpdateWarehousePassword(ctx, sess.AccountName, pass2); err != nil {
-			deps.Log.Error("更新倉庫密碼資料庫錯誤", zap.Error(err))
-		}
+		saveWarehousePassword(sess, pass2, deps)
 		SendSystemMessage(sess, "倉庫密碼設定完成，請牢記您的新密碼。")
 		return
 	}
@@ -260,14 +255,22 @@ func handleWarehousePasswordChange(sess *net.Session, player *world.PlayerInfo,
 		}
 		// 變更密碼
 		player.WarehousePassword = pass2
-		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-		defer cancel()
-		if err := deps.AccountRepo.UpdateWarehousePassword(ctx, sess.AccountName, pass2); err != nil {
-			deps.Log.Error("更新倉庫密碼資料庫錯誤", zap.Error(err))
-		}
+		saveWarehousePassword(sess, pass2, deps)
 		return
 	}
 
 	// 密碼錯誤
 	SendServerMessage(sess, 835)
 }
+
+// saveWarehousePassword 在 DB worker 寫入帳號倉庫密碼。
+func saveWarehousePassword(sess *net.Session, pass int32, deps *Deps) {
+	accountName := sess.AccountName
+	RunDBJob(sess, deps, "warehouse_password", func(ctx context.Context) error {
+		return deps.AccountRepo.UpdateWarehousePassword(ctx, accountName, pass)
+	}, func(err error) {
+		if err != nil {
+			deps.Log.Error("更新倉庫密碼資料庫錯誤", zap.Error(err))
+		}
+	})
+}
@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// QueryTracer 是 pgx 的追蹤器（persist.NewDB 設定於連線池），記錄每次
+// Exec / Query / QueryRow / CopyFrom 的耗時。在執行查詢的 goroutine 上呼叫，只做 atomic 累加。
+type QueryTracer struct{}
+
+type queryStartKey struct{}
+
+func (QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryStartKey{}, time.Now())
+}
+
+func (QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	observeQuery(ctx, data.Err)
+}
+
+func (QueryTracer) TraceCopyFromStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceCopyFromStartData) context.Context {
+	return context.WithValue(ctx, queryStartKey{}, time.Now())
+}
+
+func (QueryTracer) TraceCopyFromEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	observeQuery(ctx, data.Err)
+}
+
+func observeQuery(ctx context.Context, err error) {
+	start, ok := ctx.Value(queryStartKey{}).(time.Time)
+	if !ok {
+		return
+	}
+	d := time.Since(start)
+	i := 0
+	for i < len(dbQueryBuckets) && d.Seconds() > dbQueryBuckets[i] {
+		i++
+	}
+	dbQueryCounts[i].Add(1)
+	dbQueryNanos.Add(uint64(d))
+	if err != nil {
+		dbQueryErrors.Add(1)
+	}
+}
@@ -0,0 +1,57 @@
+// Package metrics 收集伺服器執行指標，並以 Prometheus 文字格式經 HTTP 輸出。
+//
+// 指標分兩類：
+//   - 計數器（封包收發、輸出佇列斷線、資料庫查詢耗時）：由網路 goroutine、遊戲迴圈與
+//     資料庫 worker 直接以 atomic 累加。
+//   - 量測值（線上人數、連線狀態、NPC 數量、tick 耗時）：只能在遊戲迴圈讀取，
+//     由 system.MetricsSystem 定期建立 Snapshot 發布，HTTP 端只讀取最新快照。
+package metrics
+
+import (
+	"sync/atomic"
+	"time"
+
+	coresys "github.com/l1jgo/server/internal/core/system"
+)
+
+var (
+	packetsIn           [256]atomic.Uint64 // 依操作碼統計的收到封包數
+	packetsOut          [256]atomic.Uint64 // 依操作碼統計的送出封包數
+	outQueueDisconnects atomic.Uint64      // 輸出佇列滿載而斷線的次數
+	snapshot            atomic.Pointer[Snapshot]
+)
+
+// dbQueryBuckets 是資料庫查詢耗時直方圖的區間上界（秒）。
+var dbQueryBuckets = [...]float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+var (
+	dbQueryCounts [len(dbQueryBuckets) + 1]atomic.Uint64 // 各區間（非累積）的查詢數，最後一格為 +Inf
+	dbQueryNanos  atomic.Uint64                          // 查詢耗時總和（奈秒）
+	dbQueryErrors atomic.Uint64                          // 回傳錯誤的查詢數
+)
+
+// PacketIn 記錄一個收到的封包（packet.Registry.Dispatch 呼叫）。
+func PacketIn(opcode byte) { packetsIn[opcode].Add(1) }
+
+// PacketOut 記錄一個送出的封包（Session.encryptFrame 呼叫）。
+func PacketOut(opcode byte) { packetsOut[opcode].Add(1) }
+
+// OutQueueDisconnect 記錄一次輸出佇列滿載斷線（Session.FlushOutput 呼叫）。
+func OutQueueDisconnect() { outQueueDisconnects.Add(1) }
+
+// Snapshot 是遊戲迴圈狀態的唯讀快照，發布後不得再修改。
+type Snapshot struct {
+	Taken         time.Time
+	OnlinePlayers int
+	Sessions      map[string]int // packet.SessionState 名稱 → 連線數
+	NpcTotal      int
+	NpcActive     int // 本 tick 執行 AI 的 NPC
+	NpcSleeping   int // 附近無玩家而休眠的 NPC
+	Tick          coresys.TickProfile
+}
+
+// Publish 發布新的快照（遊戲迴圈呼叫）。
+func Publish(s *Snapshot) { snapshot.Store(s) }
+
+// Latest 回傳最新快照；尚未發布時為 nil。
+func Latest() *Snapshot { return snapshot.Load() }
@@ -0,0 +1,177 @@
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	coresys "github.com/l1jgo/server/internal/core/system"
+	"go.uber.org/zap"
+)
+
+// Server 是指標 HTTP 端點。只讀取 atomic 計數器、最新快照與 pgx 連線池統計，
+// 不觸碰任何遊戲狀態，因此可安全地在獨立 goroutine 中服務。
+type Server struct {
+	srv  *http.Server
+	ln   net.Listener
+	pool *pgxpool.Pool
+	log  *zap.Logger
+}
+
+// NewServer 在 addr 上監聽，path 為指標路徑（例如 "/metrics"）。pool 可為 nil。
+func NewServer(addr, path string, pool *pgxpool.Pool, log *zap.Logger) (*Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("metrics listen %s: %w", addr, err)
+	}
+	s := &Server{ln: ln, pool: pool, log: log}
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, s.handle)
+	s.srv = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      10 * time.Second,
+	}
+	return s, nil
+}
+
+// Addr 回傳實際監聽位址。
+func (s *Server) Addr() net.Addr { return s.ln.Addr() }
+
+// Serve 阻塞直到 Shutdown。
+func (s *Server) Serve() {
+	if err := s.srv.Serve(s.ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		s.log.Error("指標端點停止", zap.Error(err))
+	}
+}
+
+// Shutdown 關閉端點。
+func (s *Server) Shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_ = s.srv.Shutdown(ctx)
+}
+
+func (s *Server) handle(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	bw := bufio.NewWriter(w)
+	s.write(bw)
+	_ = bw.Flush()
+}
+
+func (s *Server) write(w *bufio.Writer) {
+	help := func(name, typ, text string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, text, name, typ)
+	}
+
+	help("l1jgo_packets_in_total", "counter", "Packets received, by opcode.")
+	writeOpcodes(w, "l1jgo_packets_in_total", &packetsIn)
+	help("l1jgo_packets_out_total", "counter", "Packets sent, by opcode.")
+	writeOpcodes(w, "l1jgo_packets_out_total", &packetsOut)
+	help("l1jgo_outqueue_disconnects_total", "counter", "Sessions closed because their output queue was full.")
+	fmt.Fprintf(w, "l1jgo_outqueue_disconnects_total %d\n", outQueueDisconnects.Load())
+
+	if snap := Latest(); snap != nil {
+		help("l1jgo_snapshot_age_seconds", "gauge", "Age of the game-loop snapshot behind the gauges below.")
+		fmt.Fprintf(w, "l1jgo_snapshot_age_seconds %g\n", time.Since(snap.Taken).Seconds())
+
+		help("l1jgo_players_online", "gauge", "Players in the world.")
+		fmt.Fprintf(w, "l1jgo_players_online %d\n", snap.OnlinePlayers)
+
+		help("l1jgo_sessions", "gauge", "Client sessions, by protocol state.")
+		states := make([]string, 0, len(snap.Sessions))
+		for st := range snap.Sessions {
+			states = append(states, st)
+		}
+		sort.Strings(states)
+		for _, st := range states {
+			fmt.Fprintf(w, "l1jgo_sessions{state=%q} %d\n", st, snap.Sessions[st])
+		}
+
+		help("l1jgo_npcs", "gauge", "NPCs in the world.")
+		fmt.Fprintf(w, "l1jgo_npcs %d\n", snap.NpcTotal)
+		help("l1jgo_npcs_ai", "gauge", "NPCs by AI activity in the last tick.")
+		fmt.Fprintf(w, "l1jgo_npcs_ai{state=\"active\"} %d\n", snap.NpcActive)
+		fmt.Fprintf(w, "l1jgo_npcs_ai{state=\"sleeping\"} %d\n", snap.NpcSleeping)
+
+		writeTick(w, help, snap.Tick)
+	}
+
+	if s.pool != nil {
+		st := s.pool.Stat()
+		help("l1jgo_db_pool_conns", "gauge", "Database pool connections, by state.")
+		fmt.Fprintf(w, "l1jgo_db_pool_conns{state=\"acquired\"} %d\n", st.AcquiredConns())
+		fmt.Fprintf(w, "l1jgo_db_pool_conns{state=\"idle\"} %d\n", st.IdleConns())
+		fmt.Fprintf(w, "l1jgo_db_pool_conns{state=\"constructing\"} %d\n", st.ConstructingConns())
+		help("l1jgo_db_pool_max_conns", "gauge", "Database pool size limit.")
+		fmt.Fprintf(w, "l1jgo_db_pool_max_conns %d\n", st.MaxConns())
+		help("l1jgo_db_pool_acquires_total", "counter", "Successful connection acquires.")
+		fmt.Fprintf(w, "l1jgo_db_pool_acquires_total %d\n", st.AcquireCount())
+		help("l1jgo_db_pool_empty_acquires_total", "counter", "Acquires that had to wait for a connection.")
+		fmt.Fprintf(w, "l1jgo_db_pool_empty_acquires_total %d\n", st.EmptyAcquireCount())
+		help("l1jgo_db_pool_canceled_acquires_total", "counter", "Acquires canceled by their context.")
+		fmt.Fprintf(w, "l1jgo_db_pool_canceled_acquires_total %d\n", st.CanceledAcquireCount())
+		help("l1jgo_db_pool_acquire_seconds_total", "counter", "Total time spent acquiring connections.")
+		fmt.Fprintf(w, "l1jgo_db_pool_acquire_seconds_total %g\n", st.AcquireDuration().Seconds())
+		writeDBQueries(w, help)
+	}
+
+	help("l1jgo_goroutines", "gauge", "Running goroutines.")
+	fmt.Fprintf(w, "l1jgo_goroutines %d\n", runtime.NumGoroutine())
+}
+
+// writeOpcodes 只輸出出現過的操作碼，避免 256 條全零序列。
+func writeOpcodes(w *bufio.Writer, name string, counters *[256]atomic.Uint64) {
+	for op := range counters {
+		if n := counters[op].Load(); n > 0 {
+			fmt.Fprintf(w, "%s{opcode=\"%d\"} %d\n", name, op, n)
+		}
+	}
+}
+
+// writeTick 以 quantile 標籤輸出整體 tick 與各系統的滾動百分位數（秒）。
+func writeTick(w *bufio.Writer, help func(name, typ, text string), p coresys.TickProfile) {
+	quantiles := func(name, labels string, st coresys.SystemStats) {
+		fmt.Fprintf(w, "%s{%squantile=\"0.5\"} %g\n", name, labels, st.P50.Seconds())
+		fmt.Fprintf(w, "%s{%squantile=\"0.95\"} %g\n", name, labels, st.P95.Seconds())
+		fmt.Fprintf(w, "%s{%squantile=\"0.99\"} %g\n", name, labels, st.P99.Seconds())
+		fmt.Fprintf(w, "%s{%squantile=\"1\"} %g\n", name, labels, st.Max.Seconds())
+	}
+
+	help("l1jgo_tick_seconds", "gauge", "Full game tick duration over the rolling window.")
+	quantiles("l1jgo_tick_seconds", "", p.Tick)
+	help("l1jgo_input_poll_seconds", "gauge", "Phase 0 input polling time between ticks over the rolling window.")
+	quantiles("l1jgo_input_poll_seconds", "", p.Poll)
+	help("l1jgo_system_tick_seconds", "gauge", "Per-system update duration over the rolling window.")
+	for _, st := range p.Systems {
+		quantiles("l1jgo_system_tick_seconds", fmt.Sprintf("system=%q,phase=\"%d\",", st.Name, st.Phase), st)
+	}
+	help("l1jgo_tick_budget_seconds", "gauge", "Slow-tick watchdog threshold.")
+	fmt.Fprintf(w, "l1jgo_tick_budget_seconds %g\n", p.Budget.Seconds())
+	help("l1jgo_tick_overruns_total", "counter", "Ticks that exceeded the budget.")
+	fmt.Fprintf(w, "l1jgo_tick_overruns_total %d\n", p.Overruns)
+}
+
+// writeDBQueries 輸出資料庫查詢耗時直方圖（QueryTracer 累加）與錯誤數。
+func writeDBQueries(w *bufio.Writer, help func(name, typ, text string)) {
+	help("l1jgo_db_query_seconds", "histogram", "Database query duration (Exec, Query, QueryRow, CopyFrom).")
+	var total uint64
+	for i, le := range dbQueryBuckets {
+		total += dbQueryCounts[i].Load()
+		fmt.Fprintf(w, "l1jgo_db_query_seconds_bucket{le=\"%g\"} %d\n", le, total)
+	}
+	total += dbQueryCounts[len(dbQueryBuckets)].Load()
+	fmt.Fprintf(w, "l1jgo_db_query_seconds_bucket{le=\"+Inf\"} %d\n", total)
+	fmt.Fprintf(w, "l1jgo_db_query_seconds_sum %g\n", time.Duration(dbQueryNanos.Load()).Seconds())
+	fmt.Fprintf(w, "l1jgo_db_query_seconds_count %d\n", total)
+	help("l1jgo_db_query_errors_total", "counter", "Database queries that returned an error.")
+	fmt.Fprintf(w, "l1jgo_db_query_errors_total %d\n", dbQueryErrors.Load())
+}
@@ -0,0 +1,205 @@
+package net
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// 封包擷取檔格式（little-endian）：
+//
+//	header: "L1CAP" [1B version] [8B 開始時間 unix 奈秒] [uvarint 長度][標籤（角色名稱）]
+//	record: [1B 方向] [uvarint 距開始的微秒數] [uvarint 長度][解密後的 payload（含 opcode）]
+//
+// 紀錄依寫入順序排列，時間戳不遞減。
+const (
+	captureMagic   = "L1CAP"
+	captureVersion = 1
+)
+
+// CaptureDir 是封包方向。
+type CaptureDir byte
+
+const (
+	CaptureC2S CaptureDir = 0 // 客戶端 → 伺服器（readLoop 解密後）
+	CaptureS2C CaptureDir = 1 // 伺服器 → 客戶端（encryptFrame 加密前）
+)
+
+func (d CaptureDir) String() string {
+	if d == CaptureC2S {
+		return "C→S"
+	}
+	return "S→C"
+}
+
+// Capture 把一個連線的解密封包寫入擷取檔。readLoop 與 writeLoop 同時寫入，
+// 以鎖保證紀錄完整且時間戳不遞減。
+type Capture struct {
+	Path string
+
+	mu      sync.Mutex
+	f       *os.File
+	w       *bufio.Writer
+	start   time.Time
+	frames  int
+	err     error
+	closed  bool
+	scratch [2*binary.MaxVarintLen64 + 1]byte
+}
+
+// CreateCapture 建立擷取檔並寫入檔頭。label 通常是角色名稱，重播時用來進入世界。
+func CreateCapture(path, label string) (*Capture, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Capture{Path: path, f: f, w: bufio.NewWriter(f), start: time.Now()}
+	var hdr []byte
+	hdr = append(hdr, captureMagic...)
+	hdr = append(hdr, captureVersion)
+	hdr = binary.LittleEndian.AppendUint64(hdr, uint64(c.start.UnixNano()))
+	hdr = binary.AppendUvarint(hdr, uint64(len(label)))
+	hdr = append(hdr, label...)
+	if _, err := c.w.Write(hdr); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Record 寫入一個封包。寫入失敗後停止記錄，錯誤由 Close 回傳。
+func (c *Capture) Record(dir CaptureDir, payload []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed || c.err != nil {
+		return
+	}
+	b := c.scratch[:0]
+	b = append(b, byte(dir))
+	b = binary.AppendUvarint(b, uint64(time.Since(c.start).Microseconds()))
+	b = binary.AppendUvarint(b, uint64(len(payload)))
+	if _, err := c.w.Write(b); err != nil {
+		c.err = err
+		return
+	}
+	if _, err := c.w.Write(payload); err != nil {
+		c.err = err
+		return
+	}
+	c.frames++
+}
+
+// Frames 回傳已記錄的封包數。
+func (c *Capture) Frames() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.frames
+}
+
+// Close 寫出緩衝並關閉檔案。可重複呼叫。
+func (c *Capture) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return c.err
+	}
+	c.closed = true
+	if err := c.w.Flush(); err != nil && c.err == nil {
+		c.err = err
+	}
+	if err := c.f.Close(); err != nil && c.err == nil {
+		c.err = err
+	}
+	return c.err
+}
+
+// CaptureRecord 是擷取檔中的一個封包。
+type CaptureRecord struct {
+	Dir     CaptureDir
+	At      time.Duration // 距擷取開始的時間
+	Payload []byte        // 解密後的 payload（含 opcode）
+}
+
+// CaptureFile 是讀回的擷取檔。
+type CaptureFile struct {
+	Label   string
+	Start   time.Time
+	Records []CaptureRecord
+}
+
+// ReadCapture 讀取整個擷取檔。伺服器未正常關閉時檔尾可能不完整，
+// 讀到的完整紀錄仍會回傳，並附上 io.ErrUnexpectedEOF。
+func ReadCapture(path string) (*CaptureFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+
+	magic := make([]byte, len(captureMagic)+1)
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic[:len(captureMagic)]) != captureMagic {
+		return nil, fmt.Errorf("%s: 不是封包擷取檔", path)
+	}
+	if magic[len(captureMagic)] != captureVersion {
+		return nil, fmt.Errorf("%s: 不支援的擷取檔版本 %d", path, magic[len(captureMagic)])
+	}
+	var startNano [8]byte
+	if _, err := io.ReadFull(r, startNano[:]); err != nil {
+		return nil, fmt.Errorf("%s: 檔頭不完整: %w", path, err)
+	}
+	label, err := readCaptureBytes(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: 檔頭不完整: %w", path, err)
+	}
+	cf := &CaptureFile{
+		Label: string(label),
+		Start: time.Unix(0, int64(binary.LittleEndian.Uint64(startNano[:]))),
+	}
+
+	for {
+		dir, err := r.ReadByte()
+		if err == io.EOF {
+			return cf, nil
+		}
+		if err != nil {
+			return cf, err
+		}
+		if dir != byte(CaptureC2S) && dir != byte(CaptureS2C) {
+			return cf, fmt.Errorf("%s: 第 %d 筆紀錄方向錯誤 %d", path, len(cf.Records)+1, dir)
+		}
+		us, err := binary.ReadUvarint(r)
+		if err != nil {
+			return cf, io.ErrUnexpectedEOF
+		}
+		payload, err := readCaptureBytes(r)
+		if err != nil {
+			return cf, io.ErrUnexpectedEOF
+		}
+		cf.Records = append(cf.Records, CaptureRecord{
+			Dir:     CaptureDir(dir),
+			At:      time.Duration(us) * time.Microsecond,
+			Payload: payload,
+		})
+	}
+}
+
+func readCaptureBytes(r *bufio.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if n > 0xFFFF {
+		return nil, errors.New("紀錄長度超過框架上限")
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/hex"
 	"fmt"
 
+	"github.com/l1jgo/server/internal/metrics"
 	"go.uber.org/zap"
 )
 
@@ -80,6 +81,7 @@ func (reg *Registry) Dispatch(sess any, state SessionState, data []byte) error {
 		return fmt.Errorf("empty packet")
 	}
 	opcode := data[0]
+	metrics.PacketIn(opcode)
 	// 臨時 debug：印出封包原始位元組（前 32 bytes），用於分析客戶端回應
 	hexLen := len(data)
 	if hexLen > 32 {
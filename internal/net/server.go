@@ -3,6 +3,7 @@ package net
 import (
 	"fmt"
 	"net"
+	"sync"
 	"sync/atomic"
 
 	"go.uber.org/zap"
@@ -20,15 +21,27 @@ type Server struct {
 	pktPerSec int
 	log       *zap.Logger
 	closeCh   chan struct{}
+
+	// 每 IP 同時連線上限（0 = 不限制）。計數在 accept 時增加、Session.Close 時減少。
+	maxPerIP int
+	ipMu     sync.Mutex
+	ipConns  map[string]int
 }
 
-func NewServer(bindAddr string, inSize, outSize, pktPerSec int, log *zap.Logger) (*Server, error) {
+func NewServer(bindAddr string, inSize, outSize, pktPerSec, maxPerIP int, log *zap.Logger) (*Server, error) {
 	ln, err := net.Listen("tcp", bindAddr)
 	if err != nil {
 		return nil, err
 	}
-	s := &Server{
-		listener:  ln,
+	s := NewLocalServer(inSize, outSize, pktPerSec, maxPerIP, log)
+	s.listener = ln
+	return s, nil
+}
+
+// NewLocalServer 建立不監聽任何位址的 Server，連線由呼叫端以 ServeConn 交入
+// （模擬測試以 net.Pipe 連線）。不可呼叫 AcceptLoop。
+func NewLocalServer(inSize, outSize, pktPerSec, maxPerIP int, log *zap.Logger) *Server {
+	return &Server{
 		newConns:  make(chan *Session, 64),
 		deadCh:    make(chan uint64, 64),
 		inSize:    inSize,
@@ -36,8 +49,9 @@ func NewServer(bindAddr string, inSize, outSize, pktPerSec int, log *zap.Logger)
 		pktPerSec: pktPerSec,
 		log:       log,
 		closeCh:   make(chan struct{}),
+		maxPerIP:  maxPerIP,
+		ipConns:   make(map[string]int),
 	}
-	return s, nil
 }
 
 // AcceptLoop runs in its own goroutine. It accepts connections, creates
@@ -61,19 +75,58 @@ func (s *Server) AcceptLoop() {
 			tc.SetNoDelay(true)
 		}
 
-		id := s.nextID.Add(1)
-		sess := NewSession(conn, id, s.inSize, s.outSize, s.pktPerSec, s.log)
-		sess.Start()
+		s.ServeConn(conn)
+	}
+}
 
-		s.log.Info(fmt.Sprintf("玩家連線  session=%d  ip=%s", id, sess.IP))
+// ServeConn 為已建立的連線建立 Session：檢查每 IP 上限、發送初始封包、啟動讀寫
+// goroutine，再交給遊戲迴圈（下一次 Phase 0 由 InputSystem 接收）。
+// 初始封包為同步寫入，呼叫端的對端必須同時讀取（net.Pipe）。連線被拒絕時回傳 nil。
+func (s *Server) ServeConn(conn net.Conn) *Session {
+	host := hostOf(conn.RemoteAddr().String())
+	if !s.acquireIP(host) {
+		s.log.Warn(fmt.Sprintf("同一 IP 連線數已達上限，拒絕連線  ip=%s  上限=%d", host, s.maxPerIP))
+		conn.Close()
+		return nil
+	}
 
-		select {
-		case s.newConns <- sess:
-		default:
-			s.log.Warn("連線佇列已滿，拒絕新連線")
-			sess.Close()
-		}
+	id := s.nextID.Add(1)
+	sess := NewSession(conn, id, s.inSize, s.outSize, s.pktPerSec, s.log)
+	sess.onClose = func() { s.releaseIP(host) }
+	sess.Start()
+
+	s.log.Info(fmt.Sprintf("玩家連線  session=%d  ip=%s", id, sess.IP))
+
+	select {
+	case s.newConns <- sess:
+		return sess
+	default:
+		s.log.Warn("連線佇列已滿，拒絕新連線")
+		sess.Close()
+		return nil
+	}
+}
+
+// acquireIP 為 host 佔用一個連線名額；已達上限時回傳 false。
+func (s *Server) acquireIP(host string) bool {
+	s.ipMu.Lock()
+	defer s.ipMu.Unlock()
+	if s.maxPerIP > 0 && s.ipConns[host] >= s.maxPerIP {
+		return false
 	}
+	s.ipConns[host]++
+	return true
+}
+
+// releaseIP 釋放 host 的一個連線名額。
+func (s *Server) releaseIP(host string) {
+	s.ipMu.Lock()
+	defer s.ipMu.Unlock()
+	if s.ipConns[host] <= 1 {
+		delete(s.ipConns, host)
+		return
+	}
+	s.ipConns[host]--
 }
 
 // NewSessions returns the channel of newly connected sessions.
@@ -97,10 +150,15 @@ func (s *Server) DeadSessions() <-chan uint64 {
 // Shutdown stops accepting new connections.
 func (s *Server) Shutdown() {
 	close(s.closeCh)
-	s.listener.Close()
+	if s.listener != nil {
+		s.listener.Close()
+	}
 }
 
-// Addr returns the listener's address.
+// Addr returns the listener's address (nil for a local server).
 func (s *Server) Addr() net.Addr {
+	if s.listener == nil {
+		return nil
+	}
 	return s.listener.Addr()
 }
@@ -9,6 +9,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/l1jgo/server/internal/metrics"
 	"github.com/l1jgo/server/internal/net/packet"
 	"go.uber.org/zap"
 )
@@ -37,11 +38,20 @@ type Session struct {
 	AccountName string
 	CharName    string
 
-	outBuf [][]byte // buffered packets, flushed by OutputSystem (game loop only)
+	outBuf  [][]byte // buffered packets, flushed by OutputSystem (game loop only)
+	flushed uint64   // packets handed to writeLoop so far (game loop only)
+
+	// Async DB jobs in flight for this session (game loop only). While > 0 the
+	// InputSystem leaves packets queued and defers disconnect cleanup.
+	jobs int
 
 	closeCh   chan struct{}
 	closeOnce sync.Once
 	closed    atomic.Bool
+	onClose   func() // Server 的每 IP 連線計數釋放（Close 時呼叫一次）
+
+	// 解密封包擷取（nil = 未擷取）；遊戲迴圈切換，readLoop / writeLoop 寫入
+	capture atomic.Pointer[Capture]
 
 	// Per-second packet rate limiter (readLoop goroutine only, no lock needed)
 	pktPerSec  int   // max packets/sec (0 = unlimited)
@@ -113,6 +123,19 @@ func (s *Session) Send(data []byte) {
 	s.outBuf = append(s.outBuf, data)
 }
 
+// BeginJob marks an async DB job as pending. Game loop only.
+func (s *Session) BeginJob() { s.jobs++ }
+
+// EndJob marks an async DB job as finished. Game loop only.
+func (s *Session) EndJob() {
+	if s.jobs > 0 {
+		s.jobs--
+	}
+}
+
+// JobPending reports whether input is blocked on an async DB job. Game loop only.
+func (s *Session) JobPending() bool { return s.jobs > 0 }
+
 // FlushOutput drains the output buffer to OutQueue for the writeLoop goroutine.
 // Called by OutputSystem at Phase 4 (once per tick).
 // Non-blocking: if OutQueue is full, the session is disconnected (backpressure).
@@ -120,8 +143,10 @@ func (s *Session) FlushOutput() {
 	for _, data := range s.outBuf {
 		select {
 		case s.OutQueue <- data:
+			s.flushed++
 		default:
 			s.log.Warn("輸出佇列已滿，斷開慢速連線")
+			metrics.OutQueueDisconnect()
 			s.Close()
 			s.outBuf = s.outBuf[:0]
 			return
@@ -130,6 +155,33 @@ func (s *Session) FlushOutput() {
 	s.outBuf = s.outBuf[:0]
 }
 
+// Flushed 回傳已交給 writeLoop 發送的封包總數（遊戲迴圈專用）。
+// 模擬測試以此等待客戶端收齊一個 tick 送出的封包。
+func (s *Session) Flushed() uint64 { return s.flushed }
+
+// StartCapture 開始把此連線的解密封包寫入 c；已在擷取時先關閉舊的擷取檔。
+func (s *Session) StartCapture(c *Capture) {
+	if old := s.capture.Swap(c); old != nil {
+		old.Close()
+	}
+}
+
+// StopCapture 停止擷取並關閉擷取檔，回傳被停止的擷取（未擷取時為 nil）。
+func (s *Session) StopCapture() *Capture {
+	c := s.capture.Swap(nil)
+	if c != nil {
+		if err := c.Close(); err != nil {
+			s.log.Warn("封包擷取檔寫入失敗", zap.String("path", c.Path), zap.Error(err))
+		}
+	}
+	return c
+}
+
+// Capturing 回傳進行中的擷取（nil = 未擷取）。
+func (s *Session) Capturing() *Capture {
+	return s.capture.Load()
+}
+
 // Close gracefully shuts down the session.
 func (s *Session) Close() {
 	s.closeOnce.Do(func() {
@@ -137,6 +189,10 @@ func (s *Session) Close() {
 		s.SetState(packet.StateDisconnecting)
 		close(s.closeCh)
 		s.conn.Close()
+		s.StopCapture()
+		if s.onClose != nil {
+			s.onClose()
+		}
 	})
 }
 
@@ -144,6 +200,20 @@ func (s *Session) IsClosed() bool {
 	return s.closed.Load()
 }
 
+// Host 回傳連線來源 IP（不含埠號），作為每 IP 限制的鍵。
+func (s *Session) Host() string {
+	return hostOf(s.IP)
+}
+
+// hostOf 去除位址的埠號；無法解析時原樣回傳。
+func hostOf(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
 // readLoop runs in its own goroutine. It reads frames from the TCP connection,
 // decrypts them, and pushes them onto InQueue for the game loop to consume.
 func (s *Session) readLoop() {
@@ -165,6 +235,9 @@ func (s *Session) readLoop() {
 		}
 
 		decrypted := s.cipher.Decrypt(payload)
+		if c := s.capture.Load(); c != nil {
+			c.Record(CaptureC2S, decrypted)
+		}
 
 		// Per-second packet rate limiter
 		if s.pktPerSec > 0 {
@@ -241,7 +314,11 @@ drain:
 
 // encryptFrame 加密單一封包並回傳含長度標頭的完整 frame [2B LE length][encrypted payload]。
 func (s *Session) encryptFrame(data []byte) []byte {
+	if c := s.capture.Load(); c != nil {
+		c.Record(CaptureS2C, data)
+	}
 	if len(data) > 0 {
+		metrics.PacketOut(data[0])
 		s.log.Debug("TX",
 			zap.String("op", fmt.Sprintf("0x%02X(%d)", data[0], data[0])),
 			zap.Int("len", len(data)),
@@ -53,12 +53,14 @@ func (r *AuctionRepo) LoadAll(ctx context.Context) ([]AuctionEntry, error) {
 	return result, rows.Err()
 }
 
-// UpdateBid 更新出價資料。
-func (r *AuctionRepo) UpdateBid(ctx context.Context, houseID int32, price int64, bidder string, bidderID int32) error {
-	_, err := r.db.Pool.Exec(ctx,
-		`UPDATE auction_board SET price=$1, bidder=$2, bidder_id=$3 WHERE house_id=$4`,
-		price, bidder, bidderID, houseID)
-	return err
+// UpdateBid 更新出價資料。wal 為出價扣款/前一位競標者退款，與出價同一交易寫入。
+func (r *AuctionRepo) UpdateBid(ctx context.Context, houseID int32, price int64, bidder string, bidderID int32, wal ...WALEntry) error {
+	return withWAL(ctx, r.db, wal, func(q querier) error {
+		_, err := q.Exec(ctx,
+			`UPDATE auction_board SET price=$1, bidder=$2, bidder_id=$3 WHERE house_id=$4`,
+			price, bidder, bidderID, houseID)
+		return err
+	})
 }
 
 // UpdateDeadline 延期拍賣。
@@ -69,11 +71,13 @@ func (r *AuctionRepo) UpdateDeadline(ctx context.Context, houseID int32, deadlin
 	return err
 }
 
-// DeleteAuction 刪除拍賣記錄（結標後）。
-func (r *AuctionRepo) DeleteAuction(ctx context.Context, houseID int32) error {
-	_, err := r.db.Pool.Exec(ctx,
-		`DELETE FROM auction_board WHERE house_id=$1`, houseID)
-	return err
+// DeleteAuction 刪除拍賣記錄（結標後）。wal 為線上原屋主的結算金額，與刪除同一交易寫入。
+func (r *AuctionRepo) DeleteAuction(ctx context.Context, houseID int32, wal ...WALEntry) error {
+	return withWAL(ctx, r.db, wal, func(q querier) error {
+		_, err := q.Exec(ctx,
+			`DELETE FROM auction_board WHERE house_id=$1`, houseID)
+		return err
+	})
 }
 
 // InsertAuction 新增拍賣記錄（出售小屋用）。
@@ -89,10 +93,7 @@ func (r *AuctionRepo) InsertAuction(ctx context.Context, e *AuctionEntry) error
 	return err
 }
 
-// RefundOfflineGold 離線玩家退回金幣（直接 DB UPDATE）。
+// RefundOfflineGold 離線玩家退回金幣（直接寫入 character_items 的金幣列）。
 func (r *AuctionRepo) RefundOfflineGold(ctx context.Context, charID int32, amount int64) error {
-	_, err := r.db.Pool.Exec(ctx,
-		`UPDATE characters SET adena = adena + $1 WHERE id = $2`,
-		amount, charID)
-	return err
+	return addCharacterGold(ctx, r.db.Pool, charID, amount)
 }
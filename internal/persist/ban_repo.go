@@ -0,0 +1,117 @@
+package persist
+
+import (
+	"context"
+	"time"
+)
+
+// BanRow is one account or IP-range ban. Exactly one of Account and CIDR is set.
+type BanRow struct {
+	ID        int32
+	Account   string // banned account name ("" for an IP ban)
+	CIDR      string // banned network in CIDR form ("" for an account ban)
+	Reason    string
+	Issuer    string
+	CreatedAt time.Time
+	ExpiresAt *time.Time // nil = permanent
+	LiftedAt  *time.Time
+	LiftedBy  string
+}
+
+// Target returns the banned account or network.
+func (b *BanRow) Target() string {
+	if b.Account != "" {
+		return b.Account
+	}
+	return b.CIDR
+}
+
+// Active reports whether the ban is in force at now: not lifted and not expired.
+func (b *BanRow) Active(now time.Time) bool {
+	return b.LiftedAt == nil && (b.ExpiresAt == nil || now.Before(*b.ExpiresAt))
+}
+
+// BanRepo stores bans. Expiry needs no sweeping: every lookup only matches
+// bans whose expires_at is still in the future.
+type BanRepo struct {
+	db *DB
+}
+
+func NewBanRepo(db *DB) *BanRepo {
+	return &BanRepo{db: db}
+}
+
+const banColumns = `id, COALESCE(account_name, ''), COALESCE(ip_cidr::TEXT, ''), reason, issuer,
+	created_at, expires_at, lifted_at, COALESCE(lifted_by, '')`
+
+// banActive matches bans that are neither lifted nor expired.
+const banActive = `lifted_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())`
+
+// Add inserts a ban and fills in its ID and CreatedAt.
+func (r *BanRepo) Add(ctx context.Context, b *BanRow) error {
+	return r.db.Pool.QueryRow(ctx,
+		`INSERT INTO bans (account_name, ip_cidr, reason, issuer, expires_at)
+		 VALUES (NULLIF($1, ''), NULLIF($2, '')::CIDR, $3, $4, $5)
+		 RETURNING id, created_at`,
+		b.Account, b.CIDR, b.Reason, b.Issuer, b.ExpiresAt,
+	).Scan(&b.ID, &b.CreatedAt)
+}
+
+// FindActive returns the active ban covering the account or the IP address,
+// preferring the one that lasts longest; nil when neither is banned.
+// Either argument may be empty.
+func (r *BanRepo) FindActive(ctx context.Context, account, ip string) (*BanRow, error) {
+	rows, err := r.query(ctx,
+		`SELECT `+banColumns+` FROM bans
+		 WHERE `+banActive+`
+		   AND (account_name = NULLIF($1, '') OR ip_cidr >>= NULLIF($2, '')::INET)
+		 ORDER BY expires_at DESC NULLS FIRST
+		 LIMIT 1`, account, ip)
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return &rows[0], nil
+}
+
+// Lift ends every active ban on the account or on exactly the given network
+// and returns how many were lifted.
+func (r *BanRepo) Lift(ctx context.Context, account, cidr, by string) (int64, error) {
+	tag, err := r.db.Pool.Exec(ctx,
+		`UPDATE bans SET lifted_at = NOW(), lifted_by = $3
+		 WHERE `+banActive+`
+		   AND (account_name = NULLIF($1, '') OR ip_cidr = NULLIF($2, '')::CIDR)`,
+		account, cidr, by)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// History returns the newest bans (active or not) on the account or on any
+// network containing ip, at most limit rows.
+func (r *BanRepo) History(ctx context.Context, account, ip string, limit int) ([]BanRow, error) {
+	return r.query(ctx,
+		`SELECT `+banColumns+` FROM bans
+		 WHERE account_name = NULLIF($1, '') OR ip_cidr >>= NULLIF($2, '')::INET
+		 ORDER BY created_at DESC
+		 LIMIT $3`, account, ip, limit)
+}
+
+func (r *BanRepo) query(ctx context.Context, sql string, args ...any) ([]BanRow, error) {
+	rows, err := r.db.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []BanRow
+	for rows.Next() {
+		var b BanRow
+		if err := rows.Scan(&b.ID, &b.Account, &b.CIDR, &b.Reason, &b.Issuer,
+			&b.CreatedAt, &b.ExpiresAt, &b.LiftedAt, &b.LiftedBy); err != nil {
+			return nil, err
+		}
+		result = append(result, b)
+	}
+	return result, rows.Err()
+}
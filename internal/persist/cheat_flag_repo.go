@@ -0,0 +1,74 @@
+package persist
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CheatFlagRow is one cheat_flags record: a session escalated by the
+// anti-cheat checks (rubber-banded or kicked).
+type CheatFlagRow struct {
+	ID         int64
+	CharID     int32
+	CharName   string
+	Account    string
+	IP         string
+	Kind       string // "speed", "attack", "cast"
+	Action     string // "reject" or "kick"
+	Violations int32
+	Detail     string
+	MapID      int16
+	X, Y       int32
+	CreatedAt  time.Time
+}
+
+// CheatFlagRepo stores anti-cheat flags for GM review.
+type CheatFlagRepo struct {
+	db *DB
+}
+
+func NewCheatFlagRepo(db *DB) *CheatFlagRepo {
+	return &CheatFlagRepo{db: db}
+}
+
+// Append inserts flags in one COPY. ID is ignored.
+func (r *CheatFlagRepo) Append(ctx context.Context, flags []CheatFlagRow) error {
+	if len(flags) == 0 {
+		return nil
+	}
+	_, err := r.db.Pool.CopyFrom(ctx,
+		pgx.Identifier{"cheat_flags"},
+		[]string{"char_id", "char_name", "account_name", "ip", "kind", "action", "violations", "detail", "map_id", "x", "y", "created_at"},
+		pgx.CopyFromSlice(len(flags), func(i int) ([]any, error) {
+			f := &flags[i]
+			return []any{f.CharID, f.CharName, f.Account, f.IP, f.Kind, f.Action, f.Violations, f.Detail, f.MapID, f.X, f.Y, f.CreatedAt}, nil
+		}),
+	)
+	return err
+}
+
+// Recent returns the newest flags, newest first, capped at limit. An empty
+// charName returns flags of every character.
+func (r *CheatFlagRepo) Recent(ctx context.Context, charName string, limit int) ([]CheatFlagRow, error) {
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT id, char_id, char_name, account_name, ip, kind, action, violations, detail, map_id, x, y, created_at
+		 FROM cheat_flags WHERE $1 = '' OR char_name = $1 ORDER BY id DESC LIMIT $2`,
+		charName, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []CheatFlagRow
+	for rows.Next() {
+		var f CheatFlagRow
+		if err := rows.Scan(&f.ID, &f.CharID, &f.CharName, &f.Account, &f.IP, &f.Kind, &f.Action,
+			&f.Violations, &f.Detail, &f.MapID, &f.X, &f.Y, &f.CreatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, f)
+	}
+	return result, rows.Err()
+}
@@ -3,10 +3,12 @@ package persist
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/l1jgo/server/internal/config"
+	"github.com/l1jgo/server/internal/metrics"
 	"go.uber.org/zap"
 )
 
@@ -14,6 +16,11 @@ import (
 type DB struct {
 	Pool *pgxpool.Pool
 	log  *zap.Logger
+
+	// WAL 序號與非同步寫入器（WALRepo 設定）。ItemRepo.SaveInventory 以此
+	// 在存檔交易中標記已套用的 WAL 條目。
+	walSeq    atomic.Int64
+	walWriter *walWriter
 }
 
 func NewDB(ctx context.Context, cfg config.DatabaseConfig, log *zap.Logger) (*DB, error) {
@@ -24,6 +31,7 @@ func NewDB(ctx context.Context, cfg config.DatabaseConfig, log *zap.Logger) (*DB
 	poolCfg.MaxConns = int32(cfg.MaxOpenConns)
 	poolCfg.MinConns = int32(cfg.MaxIdleConns)
 	poolCfg.MaxConnLifetime = cfg.ConnMaxLifetime
+	poolCfg.ConnConfig.Tracer = metrics.QueryTracer{}
 
 	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
 	if err != nil {
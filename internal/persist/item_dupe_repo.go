@@ -0,0 +1,170 @@
+package persist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrQuarantineStale is returned by Quarantine when, after skipping copies
+// already gone, fewer than two copies remain: the obj_id is no longer
+// duplicated (e.g. the warehouse row was withdrawn after FindCopies), so
+// nothing is quarantined.
+var ErrQuarantineStale = errors.New("quarantine: obj_id no longer duplicated")
+
+// Tables holding persisted item copies.
+const (
+	TableCharacterItems = "character_items"
+	TableWarehouseItems = "warehouse_items"
+)
+
+// ItemCopy is one persisted row carrying an item instance's obj_id.
+type ItemCopy struct {
+	Table      string // TableCharacterItems or TableWarehouseItems
+	RowID      int32
+	ObjID      int32
+	CharID     int32  // character_items only
+	Account    string // warehouse_items only: account_name (clan name for the clan warehouse)
+	WhType     int16  // warehouse_items only
+	ItemID     int32
+	Count      int32
+	EnchantLvl int16
+	Bless      int16
+	Identified bool
+}
+
+// Loc describes where the copy is stored, in the same form as WAL targets and
+// item_events locations.
+func (c ItemCopy) Loc() string {
+	if c.Table == TableCharacterItems {
+		return fmt.Sprintf("char:%d", c.CharID)
+	}
+	if c.WhType == 5 { // 血盟倉庫
+		return "clan_warehouse:" + c.Account
+	}
+	return fmt.Sprintf("warehouse:%s:%d", c.Account, c.WhType)
+}
+
+// QuarantineRow is one item copy moved into item_quarantine.
+type QuarantineRow struct {
+	ObjID      int32
+	ItemID     int32
+	Count      int32
+	EnchantLvl int16
+	Bless      int16
+	Identified bool
+	Source     string
+	Reason     string
+}
+
+// ItemDupeRepo finds obj_ids stored more than once and quarantines copies.
+type ItemDupeRepo struct {
+	db *DB
+}
+
+func NewItemDupeRepo(db *DB) *ItemDupeRepo {
+	return &ItemDupeRepo{db: db}
+}
+
+// itemCopiesCTE unions the obj_id-carrying rows of both item tables.
+const itemCopiesCTE = `WITH copies AS (
+	SELECT 'character_items' AS tbl, id, obj_id, char_id, '' AS account_name, 0::SMALLINT AS wh_type,
+	       item_id, count, enchant_lvl, bless, identified
+	FROM character_items WHERE obj_id <> 0
+	UNION ALL
+	SELECT 'warehouse_items', id, obj_id, 0, account_name, wh_type,
+	       item_id, count, enchant_lvl, bless, identified
+	FROM warehouse_items WHERE obj_id <> 0
+)`
+
+// FindDuplicates returns every copy of obj_ids stored more than once across
+// character_items and warehouse_items (at most limit obj_ids), ordered by obj_id.
+func (r *ItemDupeRepo) FindDuplicates(ctx context.Context, limit int) ([]ItemCopy, error) {
+	return r.queryCopies(ctx, itemCopiesCTE+`
+		SELECT tbl, id, obj_id, char_id, account_name, wh_type, item_id, count, enchant_lvl, bless, identified
+		FROM copies
+		WHERE obj_id IN (SELECT obj_id FROM copies GROUP BY obj_id HAVING COUNT(*) > 1 LIMIT $1)
+		ORDER BY obj_id, tbl, id`, limit)
+}
+
+// FindCopies returns every persisted copy of the given obj_ids.
+func (r *ItemDupeRepo) FindCopies(ctx context.Context, objIDs []int32) ([]ItemCopy, error) {
+	if len(objIDs) == 0 {
+		return nil, nil
+	}
+	return r.queryCopies(ctx, itemCopiesCTE+`
+		SELECT tbl, id, obj_id, char_id, account_name, wh_type, item_id, count, enchant_lvl, bless, identified
+		FROM copies WHERE obj_id = ANY($1)
+		ORDER BY obj_id, tbl, id`, objIDs)
+}
+
+func (r *ItemDupeRepo) queryCopies(ctx context.Context, sql string, arg any) ([]ItemCopy, error) {
+	rows, err := r.db.Pool.Query(ctx, sql, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []ItemCopy
+	for rows.Next() {
+		var c ItemCopy
+		if err := rows.Scan(&c.Table, &c.RowID, &c.ObjID, &c.CharID, &c.Account, &c.WhType,
+			&c.ItemID, &c.Count, &c.EnchantLvl, &c.Bless, &c.Identified); err != nil {
+			return nil, err
+		}
+		result = append(result, c)
+	}
+	return result, rows.Err()
+}
+
+// Quarantine deletes the given persisted copies and records them, plus the
+// in-memory copies in held, in item_quarantine — all in one transaction.
+// A copy already gone (withdrawn or deleted meanwhile) is not recorded; if
+// that leaves fewer than two copies the transaction is rolled back and
+// ErrQuarantineStale returned.
+func (r *ItemDupeRepo) Quarantine(ctx context.Context, copies []ItemCopy, held []QuarantineRow, reason string) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	rows := append([]QuarantineRow(nil), held...)
+	for _, c := range copies {
+		var sql string
+		switch c.Table {
+		case TableCharacterItems:
+			sql = `DELETE FROM character_items WHERE id = $1 AND obj_id = $2`
+		case TableWarehouseItems:
+			sql = `DELETE FROM warehouse_items WHERE id = $1 AND obj_id = $2`
+		default:
+			return fmt.Errorf("quarantine: unknown table %q", c.Table)
+		}
+		tag, err := tx.Exec(ctx, sql, c.RowID, c.ObjID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			continue
+		}
+		rows = append(rows, QuarantineRow{
+			ObjID: c.ObjID, ItemID: c.ItemID, Count: c.Count,
+			EnchantLvl: c.EnchantLvl, Bless: c.Bless, Identified: c.Identified,
+			Source: c.Loc(), Reason: reason,
+		})
+	}
+	if len(rows) < 2 {
+		return ErrQuarantineStale
+	}
+
+	for _, q := range rows {
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO item_quarantine (obj_id, item_id, count, enchant_lvl, bless, identified, source, reason)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			q.ObjID, q.ItemID, q.Count, q.EnchantLvl, q.Bless, q.Identified, q.Source, q.Reason,
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,72 @@
+package persist
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ItemEventRow is one item_events record: an item instance created, moved
+// between owners/locations, or destroyed.
+type ItemEventRow struct {
+	ID         int64
+	ObjID      int32
+	ItemID     int32
+	Count      int32
+	EnchantLvl int16
+	From       string // empty = created
+	To         string // empty = destroyed
+	Reason     string
+	MapID      int16
+	X, Y       int32
+	CreatedAt  time.Time
+}
+
+// ItemEventRepo stores the append-only item provenance log.
+type ItemEventRepo struct {
+	db *DB
+}
+
+func NewItemEventRepo(db *DB) *ItemEventRepo {
+	return &ItemEventRepo{db: db}
+}
+
+// Append inserts events in one COPY. ID is ignored.
+func (r *ItemEventRepo) Append(ctx context.Context, events []ItemEventRow) error {
+	if len(events) == 0 {
+		return nil
+	}
+	_, err := r.db.Pool.CopyFrom(ctx,
+		pgx.Identifier{"item_events"},
+		[]string{"obj_id", "item_id", "count", "enchant_lvl", "from_loc", "to_loc", "reason", "map_id", "x", "y", "created_at"},
+		pgx.CopyFromSlice(len(events), func(i int) ([]any, error) {
+			e := &events[i]
+			return []any{e.ObjID, e.ItemID, e.Count, e.EnchantLvl, e.From, e.To, e.Reason, e.MapID, e.X, e.Y, e.CreatedAt}, nil
+		}),
+	)
+	return err
+}
+
+// History returns every event of an item instance, oldest first, capped at limit.
+func (r *ItemEventRepo) History(ctx context.Context, objID int32, limit int) ([]ItemEventRow, error) {
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT id, obj_id, item_id, count, enchant_lvl, from_loc, to_loc, reason, map_id, x, y, created_at
+		 FROM item_events WHERE obj_id = $1 ORDER BY id LIMIT $2`,
+		objID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []ItemEventRow
+	for rows.Next() {
+		var e ItemEventRow
+		if err := rows.Scan(&e.ID, &e.ObjID, &e.ItemID, &e.Count, &e.EnchantLvl,
+			&e.From, &e.To, &e.Reason, &e.MapID, &e.X, &e.Y, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, e)
+	}
+	return result, rows.Err()
+}
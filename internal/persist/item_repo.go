@@ -4,6 +4,8 @@ import (
 	"context"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/l1jgo/server/internal/world"
 )
 
@@ -71,59 +73,138 @@ func (r *ItemRepo) LoadByCharID(ctx context.Context, charID int32) ([]ItemRow, e
 	return result, rows.Err()
 }
 
-// MaxObjID returns the maximum obj_id across all character items.
-// Used on startup to initialize the ObjectID counter above all persisted values.
+// MaxObjID returns the maximum obj_id across character and warehouse items.
+// Used on startup to initialize the ObjectID counter above all persisted values;
+// warehoused non-stackable items keep their obj_id and reuse it on withdraw.
 func (r *ItemRepo) MaxObjID(ctx context.Context) (int32, error) {
 	var maxID int32
 	err := r.db.Pool.QueryRow(ctx,
-		`SELECT COALESCE(MAX(obj_id), 0) FROM character_items`,
+		`SELECT GREATEST(
+		    (SELECT COALESCE(MAX(obj_id), 0) FROM character_items),
+		    (SELECT COALESCE(MAX(obj_id), 0) FROM warehouse_items))`,
 	).Scan(&maxID)
 	return maxID, err
 }
 
-// SaveInventory replaces all items for a character (delete + bulk insert).
-// Persists item.ObjectID as obj_id for shortcut bar reference stability.
-func (r *ItemRepo) SaveInventory(ctx context.Context, charID int32, inv *world.Inventory, equip *world.Equipment) error {
+// InventorySaveStats reports the rows one SaveInventory call wrote.
+type InventorySaveStats struct {
+	Items    int  // items in the inventory
+	Upserted int  // rows inserted or updated
+	Deleted  int  // rows deleted
+	Full     bool // first save after login: every item written, every other row deleted
+}
+
+const upsertItemSQL = `INSERT INTO character_items (char_id, item_id, count, enchant_lvl, bless, equipped, identified, equip_slot, obj_id, durability, attr_enchant_kind, attr_enchant_level, inn_key_id, inn_npc_id, inn_hall, inn_due_time, charge_count)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+	ON CONFLICT (obj_id) WHERE obj_id <> 0 DO UPDATE SET
+		char_id = EXCLUDED.char_id, item_id = EXCLUDED.item_id, count = EXCLUDED.count,
+		enchant_lvl = EXCLUDED.enchant_lvl, bless = EXCLUDED.bless, equipped = EXCLUDED.equipped,
+		identified = EXCLUDED.identified, equip_slot = EXCLUDED.equip_slot, durability = EXCLUDED.durability,
+		attr_enchant_kind = EXCLUDED.attr_enchant_kind, attr_enchant_level = EXCLUDED.attr_enchant_level,
+		inn_key_id = EXCLUDED.inn_key_id, inn_npc_id = EXCLUDED.inn_npc_id, inn_hall = EXCLUDED.inn_hall,
+		inn_due_time = EXCLUDED.inn_due_time, charge_count = EXCLUDED.charge_count`
+
+// InventorySave is a character's pending inventory changes, taken on the game
+// loop by PrepareInventorySave and written by WriteInventory, which may run on
+// a database worker. WALSeq is the WAL sequence the snapshot contains.
+type InventorySave struct {
+	CharID  int32
+	Changes world.InventoryChanges
+	WALSeq  int64
+}
+
+// SaveInventory writes a character's inventory incrementally: new and changed
+// items are upserted by obj_id (an item traded in takes over its row), items no
+// longer carried are deleted by obj_id. The first save after login reconciles
+// instead, deleting every row of the character not in the inventory.
+// Must be called on the game loop: it reads and then updates the items' save
+// markers (world.Inventory.PendingChanges / CommitSave).
+// In the same transaction it records the WAL sequence the saved inventory
+// contains (markWALApplied), covering entries the async writer has not written
+// yet, so it never waits for the WAL queue.
+func (r *ItemRepo) SaveInventory(ctx context.Context, charID int32, inv *world.Inventory, equip *world.Equipment) (InventorySaveStats, error) {
+	save := r.PrepareInventorySave(charID, inv, equip)
+	stats, err := r.WriteInventory(ctx, save)
+	if err == nil {
+		inv.CommitSave(save.Changes)
+	}
+	return stats, err
+}
+
+// PrepareInventorySave snapshots the inventory's pending changes. Game loop
+// only; after a successful WriteInventory the caller commits them with
+// inv.CommitSave(save.Changes), also on the game loop.
+func (r *ItemRepo) PrepareInventorySave(charID int32, inv *world.Inventory, equip *world.Equipment) InventorySave {
+	// 呼叫當下（遊戲迴圈）已指定序號的 WAL 條目都已反映在記憶體背包中；
+	// 非同步模式下仍在佇列中的條目由存檔水位涵蓋，不需等待寫入。
+	walSeq := r.db.walSeq.Load()
+	return InventorySave{CharID: charID, Changes: inv.PendingChanges(charID, equip), WALSeq: walSeq}
+}
+
+// WriteInventory writes a snapshot taken by PrepareInventorySave. It does not
+// touch game state and may run on any goroutine.
+func (r *ItemRepo) WriteInventory(ctx context.Context, save InventorySave) (InventorySaveStats, error) {
+	charID, changes := save.CharID, save.Changes
+	stats := InventorySaveStats{Items: changes.Total, Full: changes.Full}
+
 	tx, err := r.db.Pool.Begin(ctx)
 	if err != nil {
-		return err
+		return stats, err
 	}
 	defer tx.Rollback(ctx)
 
-	// Delete all existing items for this character
-	if _, err := tx.Exec(ctx, `DELETE FROM character_items WHERE char_id = $1`, charID); err != nil {
-		return err
-	}
-
-	// Insert current inventory with persisted ObjectID
-	for _, item := range inv.Items {
-		equipSlot := int16(0)
-		if item.Equipped {
-			// Find which slot this item is in
-			for s := world.EquipSlot(1); s < world.SlotMax; s++ {
-				if equip.Get(s) == item {
-					equipSlot = int16(s)
-					break
-				}
+	if len(changes.Upserts) > 0 {
+		batch := &pgx.Batch{}
+		for _, rec := range changes.Upserts {
+			// 旅館鑰匙到期時間：0 → NULL，非零 → 轉換為 timestamptz
+			var innDueTime interface{}
+			if rec.InnDueTime != 0 {
+				innDueTime = time.Unix(rec.InnDueTime, 0)
 			}
+			batch.Queue(upsertItemSQL,
+				charID, rec.ItemID, rec.Count, int16(rec.EnchantLvl), int16(rec.Bless),
+				rec.Equipped, rec.Identified, int16(rec.EquipSlot), rec.ObjID, int16(rec.Durability),
+				int16(rec.AttrEnchantKind), int16(rec.AttrEnchantLevel),
+				rec.InnKeyID, rec.InnNpcID, rec.InnHall, innDueTime,
+				rec.ChargeCount,
+			)
 		}
-		// 旅館鑰匙到期時間：0 → NULL，非零 → 轉換為 timestamptz
-		var innDueTime interface{}
-		if item.InnDueTime != 0 {
-			innDueTime = time.Unix(item.InnDueTime, 0)
+		br := tx.SendBatch(ctx, batch)
+		for range changes.Upserts {
+			if _, err := br.Exec(); err != nil {
+				br.Close()
+				return stats, err
+			}
 		}
-		if _, err := tx.Exec(ctx,
-			`INSERT INTO character_items (char_id, item_id, count, enchant_lvl, bless, equipped, identified, equip_slot, obj_id, durability, attr_enchant_kind, attr_enchant_level, inn_key_id, inn_npc_id, inn_hall, inn_due_time, charge_count)
-			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)`,
-			charID, item.ItemID, item.Count, int16(item.EnchantLvl), int16(item.Bless),
-			item.Equipped, item.Identified, equipSlot, item.ObjectID, int16(item.Durability),
-			int16(item.AttrEnchantKind), int16(item.AttrEnchantLevel),
-			item.InnKeyID, item.InnNpcID, item.InnHall, innDueTime,
-			item.ChargeCount,
-		); err != nil {
-			return err
+		if err := br.Close(); err != nil {
+			return stats, err
 		}
+		stats.Upserted = len(changes.Upserts)
 	}
 
-	return tx.Commit(ctx)
+	var tag pgconn.CommandTag
+	if changes.Full {
+		keep := make([]int32, len(changes.Upserts))
+		for i, rec := range changes.Upserts {
+			keep[i] = rec.ObjID
+		}
+		tag, err = tx.Exec(ctx,
+			`DELETE FROM character_items WHERE char_id = $1 AND NOT (obj_id = ANY($2))`, charID, keep)
+	} else if len(changes.Deleted) > 0 {
+		tag, err = tx.Exec(ctx,
+			`DELETE FROM character_items WHERE char_id = $1 AND obj_id = ANY($2)`, charID, changes.Deleted)
+	}
+	if err != nil {
+		return stats, err
+	}
+	stats.Deleted = int(tag.RowsAffected())
+
+	if err := markWALApplied(ctx, tx, charID, save.WALSeq); err != nil {
+		return stats, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return stats, err
+	}
+	return stats, nil
 }
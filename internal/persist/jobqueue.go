@@ -0,0 +1,177 @@
+package persist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrJobQueueFull is passed to a job's callback when it could not be queued.
+var ErrJobQueueFull = errors.New("db job queue full")
+
+// slowJobThreshold logs jobs whose work took longer than this.
+const slowJobThreshold = time.Second
+
+// dbJob is one unit of database work plus its game-loop completion.
+type dbJob struct {
+	name string
+	work func(ctx context.Context) error
+	done func(err error)
+	err  error
+}
+
+// JobQueue runs blocking database work on background worker goroutines so a
+// slow query never stalls the game loop. Work functions must not touch game
+// state; they capture their inputs and write results into variables the
+// completion callback reads. Completions are delivered back on the game loop
+// by RunCompletions (system.DBJobSystem, Phase 1).
+type JobQueue struct {
+	jobs    chan *dbJob
+	done    chan *dbJob
+	timeout time.Duration
+	log     *zap.Logger
+	wg      sync.WaitGroup
+	once    sync.Once
+	closed  bool // Close 之後 Submit 一律失敗（遊戲迴圈專用）
+
+	inline bool     // NewInlineJobQueue：Submit 直接在呼叫端執行工作
+	ready  []*dbJob // inline 模式已完成、待 RunCompletions 的工作（遊戲迴圈專用）
+}
+
+// NewJobQueue starts workers goroutines. size bounds the number of queued
+// jobs; timeout is the context deadline given to each work function.
+func NewJobQueue(workers, size int, timeout time.Duration, log *zap.Logger) *JobQueue {
+	if workers < 1 {
+		workers = 1
+	}
+	if size < workers {
+		size = workers
+	}
+	q := &JobQueue{
+		jobs:    make(chan *dbJob, size),
+		done:    make(chan *dbJob, size+workers),
+		timeout: timeout,
+		log:     log,
+	}
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go q.worker()
+	}
+	return q
+}
+
+// NewInlineJobQueue 建立不啟動 worker 的佇列：Submit 在呼叫端（遊戲迴圈）直接
+// 執行工作，回呼仍延到下一次 RunCompletions，順序與非同步佇列相同但結果可重現。
+// 供記憶體資料庫的模擬測試使用。
+func NewInlineJobQueue(timeout time.Duration, log *zap.Logger) *JobQueue {
+	return &JobQueue{timeout: timeout, log: log, inline: true}
+}
+
+// Submit queues work. done is called on the game loop with work's error.
+// Returns false (without calling done) if the queue is full or closed.
+func (q *JobQueue) Submit(name string, work func(ctx context.Context) error, done func(err error)) bool {
+	if q.closed {
+		q.log.Warn("資料庫工作佇列已關閉", zap.String("job", name))
+		return false
+	}
+	if q.inline {
+		j := &dbJob{name: name, work: work, done: done}
+		j.err = q.run(j)
+		q.ready = append(q.ready, j)
+		return true
+	}
+	select {
+	case q.jobs <- &dbJob{name: name, work: work, done: done}:
+		return true
+	default:
+		q.log.Warn("資料庫工作佇列已滿", zap.String("job", name))
+		return false
+	}
+}
+
+// RunCompletions invokes the callbacks of all finished jobs. Game loop only.
+// Returns the number of callbacks run.
+func (q *JobQueue) RunCompletions() int {
+	n := 0
+	if q.inline {
+		// 回呼中送出的新工作留到下一次
+		batch := q.ready
+		q.ready = nil
+		for _, j := range batch {
+			j.done(j.err)
+			n++
+		}
+		return n
+	}
+	for {
+		select {
+		case j := <-q.done:
+			j.done(j.err)
+			n++
+		default:
+			return n
+		}
+	}
+}
+
+// Pending returns the number of jobs queued but not yet picked up by a worker.
+func (q *JobQueue) Pending() int {
+	return len(q.jobs)
+}
+
+// Close stops accepting work, waits for in-flight jobs and runs their
+// completions. Called on the game loop during shutdown, before the final save.
+// Completions are drained while waiting: done holds only size+workers jobs, and
+// a worker blocked on a full done channel would otherwise never exit.
+func (q *JobQueue) Close() {
+	q.once.Do(func() {
+		q.closed = true
+		if !q.inline {
+			close(q.jobs)
+			stopped := make(chan struct{})
+			go func() {
+				q.wg.Wait()
+				close(stopped)
+			}()
+		wait:
+			for {
+				select {
+				case j := <-q.done:
+					j.done(j.err)
+				case <-stopped:
+					break wait
+				}
+			}
+		}
+		q.RunCompletions()
+	})
+}
+
+func (q *JobQueue) worker() {
+	defer q.wg.Done()
+	for j := range q.jobs {
+		start := time.Now()
+		j.err = q.run(j)
+		if took := time.Since(start); took > slowJobThreshold {
+			q.log.Warn("資料庫工作耗時過長", zap.String("job", j.name), zap.Duration("took", took))
+		}
+		q.done <- j
+	}
+}
+
+// run executes one job with its deadline and panic recovery.
+func (q *JobQueue) run(j *dbJob) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			q.log.Error("資料庫工作 panic 已恢復", zap.String("job", j.name), zap.Any("panic", rec))
+			err = fmt.Errorf("db job %s panic: %v", j.name, rec)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), q.timeout)
+	defer cancel()
+	return j.work(ctx)
+}
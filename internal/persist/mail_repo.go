@@ -67,14 +67,17 @@ func (r *MailRepo) GetByID(ctx context.Context, id int32) (*MailRow, error) {
 	return &m, nil
 }
 
-// Write inserts a new mail record and returns the generated ID.
-func (r *MailRepo) Write(ctx context.Context, m *MailRow) (int32, error) {
+// Write inserts a new mail record and returns the generated ID. WAL entries
+// (the sending fee), if given, are written in the same transaction.
+func (r *MailRepo) Write(ctx context.Context, m *MailRow, wal ...WALEntry) (int32, error) {
 	var id int32
-	err := r.db.Pool.QueryRow(ctx,
-		`INSERT INTO mail (type, sender, receiver, date, read_status, inbox_id, subject, content)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
-		m.Type, m.Sender, m.Receiver, m.Date, m.ReadStatus, m.InboxID, m.Subject, m.Content,
-	).Scan(&id)
+	err := withWAL(ctx, r.db, wal, func(q querier) error {
+		return q.QueryRow(ctx,
+			`INSERT INTO mail (type, sender, receiver, date, read_status, inbox_id, subject, content)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
+			m.Type, m.Sender, m.Receiver, m.Date, m.ReadStatus, m.InboxID, m.Subject, m.Content,
+		).Scan(&id)
+	})
 	return id, err
 }
 
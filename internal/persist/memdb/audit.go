@@ -0,0 +1,234 @@
+package memdb
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"sort"
+	"time"
+
+	"github.com/l1jgo/server/internal/persist"
+)
+
+// ItemEventRepo implements persist.ItemEventStore.
+type ItemEventRepo struct{ s *Store }
+
+// Append inserts events. ID is ignored.
+func (r *ItemEventRepo) Append(_ context.Context, events []persist.ItemEventRow) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, e := range events {
+		r.s.nextEventID++
+		e.ID = r.s.nextEventID
+		r.s.itemEvents = append(r.s.itemEvents, e)
+	}
+	return nil
+}
+
+// History returns every event of an item instance, oldest first, capped at limit.
+func (r *ItemEventRepo) History(_ context.Context, objID int32, limit int) ([]persist.ItemEventRow, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var result []persist.ItemEventRow
+	for _, e := range r.s.itemEvents {
+		if len(result) >= limit {
+			break
+		}
+		if e.ObjID == objID {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+// CheatFlagRepo implements persist.CheatFlagStore.
+type CheatFlagRepo struct{ s *Store }
+
+// Append inserts flags. ID is ignored.
+func (r *CheatFlagRepo) Append(_ context.Context, flags []persist.CheatFlagRow) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, f := range flags {
+		r.s.nextFlagID++
+		f.ID = r.s.nextFlagID
+		r.s.cheatFlags = append(r.s.cheatFlags, f)
+	}
+	return nil
+}
+
+// Recent returns the newest flags, newest first, capped at limit. An empty
+// charName returns flags of every character.
+func (r *CheatFlagRepo) Recent(_ context.Context, charName string, limit int) ([]persist.CheatFlagRow, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var result []persist.CheatFlagRow
+	for i := len(r.s.cheatFlags) - 1; i >= 0 && len(result) < limit; i-- {
+		if f := r.s.cheatFlags[i]; charName == "" || f.CharName == charName {
+			result = append(result, f)
+		}
+	}
+	return result, nil
+}
+
+// BanRepo implements persist.BanStore.
+type BanRepo struct{ s *Store }
+
+// parseNet parses an address or network the way the CIDR / INET casts do: a
+// bare address is a single-host network. strict rejects host bits set past
+// the prefix length, which CIDR does and INET does not.
+func parseNet(s string, strict bool) (netip.Prefix, error) {
+	if addr, err := netip.ParseAddr(s); err == nil {
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	p, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid network %q", s)
+	}
+	if p.Addr().Is4In6() {
+		if p.Bits() < 96 {
+			return netip.Prefix{}, fmt.Errorf("invalid network %q", s)
+		}
+		p = netip.PrefixFrom(p.Addr().Unmap(), p.Bits()-96)
+	}
+	if strict && p != p.Masked() {
+		return netip.Prefix{}, fmt.Errorf("invalid cidr %q: value has bits set to right of mask", s)
+	}
+	return p, nil
+}
+
+// banCovers reports whether ban b applies to the account or the network q
+// (account_name = $1 OR ip_cidr >>= $2).
+func banCovers(b *persist.BanRow, account string, q netip.Prefix) bool {
+	if account != "" && b.Account == account {
+		return true
+	}
+	if b.CIDR == "" || !q.IsValid() {
+		return false
+	}
+	n, err := netip.ParsePrefix(b.CIDR)
+	return err == nil && n.Bits() <= q.Bits() && n.Contains(q.Addr())
+}
+
+// matchBans returns the bans covering account or ip; active limits them to
+// bans in force.
+func (s *Store) matchBans(account, ip string, active bool) ([]*persist.BanRow, error) {
+	var q netip.Prefix
+	if ip != "" {
+		var err error
+		if q, err = parseNet(ip, false); err != nil {
+			return nil, err
+		}
+	}
+	now := time.Now()
+	var result []*persist.BanRow
+	for _, b := range s.bans {
+		if active && !b.Active(now) {
+			continue
+		}
+		if banCovers(b, account, q) {
+			result = append(result, b)
+		}
+	}
+	return result, nil
+}
+
+// Add inserts a ban and fills in its ID and CreatedAt.
+func (r *BanRepo) Add(_ context.Context, b *persist.BanRow) error {
+	if (b.Account == "") == (b.CIDR == "") {
+		return fmt.Errorf("ban needs exactly one of account and cidr: %w", ErrCheckViolation)
+	}
+	row := *b
+	if b.CIDR != "" {
+		p, err := parseNet(b.CIDR, true)
+		if err != nil {
+			return err
+		}
+		row.CIDR = p.String()
+	}
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.nextBanID++
+	row.ID = r.s.nextBanID
+	row.CreatedAt = time.Now()
+	r.s.bans = append(r.s.bans, &row)
+	b.ID, b.CreatedAt = row.ID, row.CreatedAt
+	return nil
+}
+
+// FindActive returns the active ban covering the account or the IP address,
+// preferring the one that lasts longest; nil when neither is banned.
+// Either argument may be empty.
+func (r *BanRepo) FindActive(_ context.Context, account, ip string) (*persist.BanRow, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	bans, err := r.s.matchBans(account, ip, true)
+	if err != nil || len(bans) == 0 {
+		return nil, err
+	}
+	best := bans[0]
+	for _, b := range bans[1:] {
+		if best.ExpiresAt != nil && (b.ExpiresAt == nil || b.ExpiresAt.After(*best.ExpiresAt)) {
+			best = b
+		}
+	}
+	row := *best
+	return &row, nil
+}
+
+// Lift ends every active ban on the account or on exactly the given network
+// and returns how many were lifted.
+func (r *BanRepo) Lift(_ context.Context, account, cidr, by string) (int64, error) {
+	var target netip.Prefix
+	if cidr != "" {
+		var err error
+		if target, err = parseNet(cidr, true); err != nil {
+			return 0, err
+		}
+	}
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := time.Now()
+	var n int64
+	for _, b := range r.s.bans {
+		if !b.Active(now) {
+			continue
+		}
+		match := account != "" && b.Account == account
+		if !match && b.CIDR != "" && target.IsValid() {
+			p, err := netip.ParsePrefix(b.CIDR)
+			match = err == nil && p == target
+		}
+		if match {
+			lifted := now
+			b.LiftedAt, b.LiftedBy = &lifted, by
+			n++
+		}
+	}
+	return n, nil
+}
+
+// History returns the newest bans (active or not) on the account or on any
+// network containing ip, at most limit rows.
+func (r *BanRepo) History(_ context.Context, account, ip string, limit int) ([]persist.BanRow, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	bans, err := r.s.matchBans(account, ip, false)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(bans, func(i, j int) bool {
+		if !bans[i].CreatedAt.Equal(bans[j].CreatedAt) {
+			return bans[i].CreatedAt.After(bans[j].CreatedAt)
+		}
+		return bans[i].ID > bans[j].ID
+	})
+	var result []persist.BanRow
+	for _, b := range bans {
+		if len(result) >= limit {
+			break
+		}
+		result = append(result, *b)
+	}
+	return result, nil
+}
@@ -0,0 +1,571 @@
+package memdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/l1jgo/server/internal/persist"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// AccountRepo implements persist.AccountStore.
+type AccountRepo struct{ s *Store }
+
+func (r *AccountRepo) Load(_ context.Context, name string) (*persist.AccountRow, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	a := r.s.accounts[name]
+	if a == nil {
+		return nil, nil
+	}
+	row := *a
+	if a.LastActive != nil {
+		t := *a.LastActive
+		row.LastActive = &t
+	}
+	return &row, nil
+}
+
+func (r *AccountRepo) Create(_ context.Context, name, rawPassword, ip, host string) (*persist.AccountRow, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(rawPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	row := &persist.AccountRow{
+		Name:         name,
+		PasswordHash: string(hash),
+		IP:           ip,
+		Host:         host,
+		CreatedAt:    now,
+		LastActive:   &now,
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if r.s.accounts[name] != nil {
+		return nil, fmt.Errorf("account %q: %w", name, ErrUniqueViolation)
+	}
+	stored := *row
+	last := now
+	stored.LastActive = &last
+	r.s.accounts[name] = &stored
+	return row, nil
+}
+
+func (r *AccountRepo) ValidatePassword(hash string, rawPassword string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(rawPassword)) == nil
+}
+
+func (r *AccountRepo) UpdateLastActive(_ context.Context, name, ip string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if a := r.s.accounts[name]; a != nil {
+		now := time.Now()
+		a.LastActive = &now
+		a.IP = ip
+	}
+	return nil
+}
+
+func (r *AccountRepo) SetOnline(_ context.Context, name string, online bool) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if a := r.s.accounts[name]; a != nil {
+		a.Online = online
+	}
+	return nil
+}
+
+func (r *AccountRepo) UpdateWarehousePassword(_ context.Context, name string, pass int32) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if a := r.s.accounts[name]; a != nil {
+		a.WarehousePassword = pass
+	}
+	return nil
+}
+
+// CharacterRepo implements persist.CharacterStore.
+type CharacterRepo struct{ s *Store }
+
+// charRowOut copies a row for the caller.
+func charRowOut(c *charRow) persist.CharacterRow {
+	row := c.row
+	if c.row.DeletedAt != nil {
+		t := *c.row.DeletedAt
+		row.DeletedAt = &t
+	}
+	return row
+}
+
+// byName returns the character named name. With live set, soft-deleted
+// characters are skipped (deleted_at IS NULL).
+func (s *Store) byName(name string, live bool) *charRow {
+	for _, c := range s.chars {
+		if c.row.Name == name && (!live || c.row.DeletedAt == nil) {
+			return c
+		}
+	}
+	return nil
+}
+
+func (r *CharacterRepo) LoadByAccount(_ context.Context, accountName string) ([]persist.CharacterRow, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var result []persist.CharacterRow
+	for _, id := range sortedKeys(r.s.chars) {
+		c := r.s.chars[id]
+		if c.row.AccountName == accountName && c.row.DeletedAt == nil {
+			result = append(result, charRowOut(c))
+		}
+	}
+	return result, nil
+}
+
+func (r *CharacterRepo) LoadByName(_ context.Context, name string) (*persist.CharacterRow, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	c := r.s.byName(name, true)
+	if c == nil {
+		return nil, nil
+	}
+	row := charRowOut(c)
+	return &row, nil
+}
+
+func (r *CharacterRepo) Create(_ context.Context, c *persist.CharacterRow) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if r.s.accounts[c.AccountName] == nil {
+		return fmt.Errorf("character account %q: %w", c.AccountName, ErrForeignKeyViolation)
+	}
+	if r.s.byName(c.Name, false) != nil {
+		return fmt.Errorf("character name %q: %w", c.Name, ErrUniqueViolation)
+	}
+	r.s.nextCharID++
+	row := *c
+	row.ID = r.s.nextCharID
+	row.DeletedAt = nil
+	r.s.chars[row.ID] = &charRow{
+		row:         row,
+		bookmarks:   []byte("[]"),
+		knownSpells: []byte("[]"),
+		mapTimes:    []byte("{}"),
+	}
+	c.ID = row.ID
+	return nil
+}
+
+func (r *CharacterRepo) NameExists(_ context.Context, name string) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return r.s.byName(name, false) != nil, nil
+}
+
+func (r *CharacterRepo) CountByAccount(_ context.Context, accountName string) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	n := 0
+	for _, c := range r.s.chars {
+		if c.row.AccountName == accountName && c.row.DeletedAt == nil {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *CharacterRepo) SoftDelete(_ context.Context, name string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if c := r.s.byName(name, true); c != nil {
+		t := time.Now().Add(7 * 24 * time.Hour)
+		c.row.DeletedAt = &t
+	}
+	return nil
+}
+
+func (r *CharacterRepo) HardDelete(_ context.Context, name string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	c := r.s.byName(name, false)
+	if c == nil {
+		return nil
+	}
+	return r.s.deleteChars([]int32{c.row.ID})
+}
+
+func (r *CharacterRepo) CleanExpiredDeletions(_ context.Context, accountName string) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := time.Now()
+	var ids []int32
+	for id, c := range r.s.chars {
+		if c.row.AccountName == accountName && c.row.DeletedAt != nil && !c.row.DeletedAt.After(now) {
+			ids = append(ids, id)
+		}
+	}
+	if err := r.s.deleteChars(ids); err != nil {
+		return 0, err
+	}
+	return int64(len(ids)), nil
+}
+
+// deleteChars deletes characters in one statement: clan rows still
+// referencing any of them fail the whole delete; items, buffs and quests
+// cascade.
+func (s *Store) deleteChars(ids []int32) error {
+	del := make(map[int32]bool, len(ids))
+	for _, id := range ids {
+		del[id] = true
+	}
+	for _, cl := range s.clans {
+		if del[cl.LeaderID] {
+			return fmt.Errorf("character %d leads clan %d: %w", cl.LeaderID, cl.ClanID, ErrForeignKeyViolation)
+		}
+	}
+	for k := range s.members {
+		if del[k.charID] {
+			return fmt.Errorf("character %d is in clan %d: %w", k.charID, k.clanID, ErrForeignKeyViolation)
+		}
+	}
+	for id, it := range s.items {
+		if del[it.CharID] {
+			s.deleteItem(id)
+		}
+	}
+	for id := range del {
+		delete(s.buffs, id)
+		delete(s.quests, id)
+		delete(s.chars, id)
+	}
+	return nil
+}
+
+func (r *CharacterRepo) SavePosition(_ context.Context, name string, x, y int32, mapID, heading int16) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if c := r.s.byName(name, false); c != nil {
+		c.row.X, c.row.Y, c.row.MapID, c.row.Heading = x, y, mapID, heading
+	}
+	return nil
+}
+
+func (r *CharacterRepo) SaveCharacter(_ context.Context, in *persist.CharacterRow) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	c := r.s.byName(in.Name, false)
+	if c == nil {
+		return nil
+	}
+	row := &c.row
+	row.Level, row.Exp, row.HP, row.MP, row.MaxHP, row.MaxMP = in.Level, in.Exp, in.HP, in.MP, in.MaxHP, in.MaxMP
+	row.X, row.Y, row.MapID, row.Heading = in.X, in.Y, in.MapID, in.Heading
+	row.Lawful, row.Str, row.Dex, row.Con, row.Wis, row.Cha, row.Intel = in.Lawful, in.Str, in.Dex, in.Con, in.Wis, in.Cha, in.Intel
+	row.BonusStats, row.ElixirStats = in.BonusStats, in.ElixirStats
+	row.ClanID, row.ClanName, row.ClanRank = in.ClanID, in.ClanName, in.ClanRank
+	row.Title, row.Karma, row.PKCount, row.Food = in.Title, in.Karma, in.PKCount, in.Food
+	return nil
+}
+
+// loadJSON decodes column col of the live character named name into v;
+// found is false when there is no such character.
+func (s *Store) loadJSON(name string, col func(*charRow) []byte, v any) (found bool, err error) {
+	s.mu.Lock()
+	c := s.byName(name, true)
+	var raw []byte
+	if c != nil {
+		raw = cloneBytes(col(c))
+	}
+	s.mu.Unlock()
+	if c == nil {
+		return false, nil
+	}
+	return true, json.Unmarshal(raw, v)
+}
+
+// saveJSON encodes v into column col of every character named name.
+func (s *Store) saveJSON(name string, col func(*charRow) *[]byte, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c := s.byName(name, false); c != nil {
+		*col(c) = data
+	}
+	return nil
+}
+
+func (r *CharacterRepo) LoadBookmarks(_ context.Context, name string) ([]persist.BookmarkRow, error) {
+	var bookmarks []persist.BookmarkRow
+	if found, err := r.s.loadJSON(name, func(c *charRow) []byte { return c.bookmarks }, &bookmarks); !found || err != nil {
+		return nil, err
+	}
+	return bookmarks, nil
+}
+
+func (r *CharacterRepo) SaveBookmarks(_ context.Context, name string, bookmarks []persist.BookmarkRow) error {
+	return r.s.saveJSON(name, func(c *charRow) *[]byte { return &c.bookmarks }, bookmarks)
+}
+
+func (r *CharacterRepo) LoadKnownSpells(_ context.Context, name string) ([]int32, error) {
+	var spells []int32
+	if found, err := r.s.loadJSON(name, func(c *charRow) []byte { return c.knownSpells }, &spells); !found || err != nil {
+		return nil, err
+	}
+	return spells, nil
+}
+
+func (r *CharacterRepo) SaveKnownSpells(_ context.Context, name string, spells []int32) error {
+	if spells == nil {
+		spells = []int32{}
+	}
+	return r.s.saveJSON(name, func(c *charRow) *[]byte { return &c.knownSpells }, spells)
+}
+
+func (r *CharacterRepo) LoadCharConfig(_ context.Context, charID int32) ([]byte, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	c := r.s.chars[charID]
+	if c == nil || c.row.DeletedAt != nil {
+		return nil, nil
+	}
+	return cloneBytes(c.charConfig), nil
+}
+
+func (r *CharacterRepo) SaveCharConfig(_ context.Context, charID int32, data []byte) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if c := r.s.chars[charID]; c != nil {
+		c.charConfig = cloneBytes(data)
+	}
+	return nil
+}
+
+func (r *CharacterRepo) LoadMapTimes(_ context.Context, name string) (map[int]int, error) {
+	var m map[string]int
+	if found, err := r.s.loadJSON(name, func(c *charRow) []byte { return c.mapTimes }, &m); !found || err != nil {
+		return nil, err
+	}
+	result := make(map[int]int, len(m))
+	for k, v := range m {
+		var oid int
+		if _, err := fmt.Sscanf(k, "%d", &oid); err == nil {
+			result[oid] = v
+		}
+	}
+	return result, nil
+}
+
+func (r *CharacterRepo) SaveMapTimes(_ context.Context, name string, mapTimes map[int]int) error {
+	m := make(map[string]int, len(mapTimes))
+	for k, v := range mapTimes {
+		m[fmt.Sprintf("%d", k)] = v
+	}
+	return r.s.saveJSON(name, func(c *charRow) *[]byte { return &c.mapTimes }, m)
+}
+
+func (r *CharacterRepo) ResetAllMapTimes(_ context.Context) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, c := range r.s.chars {
+		if c.row.DeletedAt == nil {
+			c.mapTimes = []byte("{}")
+		}
+	}
+	return nil
+}
+
+// BuffRepo implements persist.BuffStore.
+type BuffRepo struct{ s *Store }
+
+func (r *BuffRepo) LoadByCharID(_ context.Context, charID int32) ([]persist.BuffRow, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return append([]persist.BuffRow(nil), r.s.buffs[charID]...), nil
+}
+
+func (r *BuffRepo) SaveBuffs(_ context.Context, charID int32, buffs []persist.BuffRow) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if len(buffs) > 0 && r.s.chars[charID] == nil {
+		return fmt.Errorf("insert buff skill=%d: %w", buffs[0].SkillID, ErrForeignKeyViolation)
+	}
+	seen := make(map[int32]bool, len(buffs))
+	rows := make([]persist.BuffRow, len(buffs))
+	for i, b := range buffs {
+		if seen[b.SkillID] {
+			return fmt.Errorf("insert buff skill=%d: %w", b.SkillID, ErrUniqueViolation)
+		}
+		seen[b.SkillID] = true
+		b.CharID = charID
+		rows[i] = b
+	}
+	if len(rows) == 0 {
+		delete(r.s.buffs, charID)
+		return nil
+	}
+	r.s.buffs[charID] = rows
+	return nil
+}
+
+func (r *BuffRepo) DeleteByCharID(_ context.Context, charID int32) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	delete(r.s.buffs, charID)
+	return nil
+}
+
+// QuestRepo implements persist.QuestStore.
+type QuestRepo struct{ s *Store }
+
+func (r *QuestRepo) LoadAll(_ context.Context, charID int32) (map[int32]int32, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	result := make(map[int32]int32, len(r.s.quests[charID]))
+	for qid, step := range r.s.quests[charID] {
+		result[qid] = step
+	}
+	return result, nil
+}
+
+func (r *QuestRepo) SetStep(_ context.Context, charID, questID, step int32) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if r.s.chars[charID] == nil {
+		return fmt.Errorf("quest char %d: %w", charID, ErrForeignKeyViolation)
+	}
+	if r.s.quests[charID] == nil {
+		r.s.quests[charID] = make(map[int32]int32)
+	}
+	r.s.quests[charID][questID] = step
+	return nil
+}
+
+func (r *QuestRepo) SetCompleted(ctx context.Context, charID int32, questID int32) error {
+	return r.SetStep(ctx, charID, questID, 255)
+}
+
+func (r *QuestRepo) DeleteQuest(_ context.Context, charID, questID int32) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	delete(r.s.quests[charID], questID)
+	return nil
+}
+
+// BuddyRepo implements persist.BuddyStore.
+type BuddyRepo struct{ s *Store }
+
+func (r *BuddyRepo) LoadByCharID(_ context.Context, charID int32) ([]persist.BuddyRow, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var result []persist.BuddyRow
+	for _, b := range r.s.buddies {
+		if b.CharID == charID {
+			result = append(result, b)
+		}
+	}
+	return result, nil
+}
+
+func (r *BuddyRepo) Add(_ context.Context, charID, buddyID int32, buddyName string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, b := range r.s.buddies {
+		if b.CharID == charID && b.BuddyID == buddyID {
+			return nil // ON CONFLICT DO NOTHING
+		}
+	}
+	r.s.buddies = append(r.s.buddies, persist.BuddyRow{CharID: charID, BuddyID: buddyID, BuddyName: buddyName})
+	return nil
+}
+
+func (r *BuddyRepo) Remove(_ context.Context, charID int32, buddyName string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	kept := r.s.buddies[:0]
+	for _, b := range r.s.buddies {
+		if b.CharID != charID || !strings.EqualFold(b.BuddyName, buddyName) {
+			kept = append(kept, b)
+		}
+	}
+	r.s.buddies = kept
+	return nil
+}
+
+// ExcludeRepo implements persist.ExcludeStore.
+type ExcludeRepo struct{ s *Store }
+
+func (r *ExcludeRepo) LoadByCharID(_ context.Context, charID int32) ([]string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var result []string
+	for _, e := range r.s.excludes {
+		if e.charID == charID {
+			result = append(result, e.name)
+		}
+	}
+	return result, nil
+}
+
+func (r *ExcludeRepo) Add(_ context.Context, charID int32, excludeName string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, e := range r.s.excludes {
+		if e.charID == charID && e.name == excludeName {
+			return nil // ON CONFLICT DO NOTHING
+		}
+	}
+	r.s.excludes = append(r.s.excludes, excludeRow{charID: charID, name: excludeName})
+	return nil
+}
+
+func (r *ExcludeRepo) Remove(_ context.Context, charID int32, excludeName string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	kept := r.s.excludes[:0]
+	for _, e := range r.s.excludes {
+		if e.charID != charID || !strings.EqualFold(e.name, excludeName) {
+			kept = append(kept, e)
+		}
+	}
+	r.s.excludes = kept
+	return nil
+}
+
+// PetRepo implements persist.PetStore.
+type PetRepo struct{ s *Store }
+
+// LoadByItemObjID returns pgx.ErrNoRows when there is no such pet, like the
+// PostgreSQL repo.
+func (r *PetRepo) LoadByItemObjID(_ context.Context, itemObjID int32) (*persist.PetRow, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	p := r.s.pets[itemObjID]
+	if p == nil {
+		return nil, pgx.ErrNoRows
+	}
+	row := *p
+	return &row, nil
+}
+
+func (r *PetRepo) Save(_ context.Context, p *persist.PetRow) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	row := *p
+	r.s.pets[p.ItemObjID] = &row
+	return nil
+}
+
+func (r *PetRepo) Delete(_ context.Context, itemObjID int32) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	delete(r.s.pets, itemObjID)
+	return nil
+}
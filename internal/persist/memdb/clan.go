@@ -0,0 +1,315 @@
+package memdb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/l1jgo/server/internal/persist"
+)
+
+// ClanRepo implements persist.ClanStore.
+type ClanRepo struct{ s *Store }
+
+func copyClan(c *persist.ClanRow) persist.ClanRow {
+	row := *c
+	row.Announcement = cloneBytes(c.Announcement)
+	return row
+}
+
+// LoadAll loads all clans ordered by clan_id and their members ordered by
+// clan_id, char_id.
+func (r *ClanRepo) LoadAll(_ context.Context) ([]persist.ClanRow, []persist.ClanMemberRow, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var clans []persist.ClanRow
+	for _, id := range sortedKeys(r.s.clans) {
+		clans = append(clans, copyClan(r.s.clans[id]))
+	}
+	var members []persist.ClanMemberRow
+	for _, m := range r.s.members {
+		row := *m
+		row.Notes = cloneBytes(m.Notes)
+		members = append(members, row)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].ClanID != members[j].ClanID {
+			return members[i].ClanID < members[j].ClanID
+		}
+		return members[i].CharID < members[j].CharID
+	})
+	return clans, members, nil
+}
+
+// setCharClan updates the clan columns of a character row.
+func (s *Store) setCharClan(charID, clanID int32, clanName string, rank int16) {
+	if c := s.chars[charID]; c != nil {
+		c.row.ClanID, c.row.ClanName, c.row.ClanRank = clanID, clanName, rank
+	}
+}
+
+// CreateClan creates a clan with its leader as rank 10 member and returns the
+// new clan ID.
+func (r *ClanRepo) CreateClan(_ context.Context, leaderCharID int32, leaderName, clanName string, foundDate int32) (int32, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, c := range r.s.clans {
+		if c.ClanName == clanName {
+			return 0, fmt.Errorf("clan name %q: %w", clanName, ErrUniqueViolation)
+		}
+	}
+	if r.s.chars[leaderCharID] == nil {
+		return 0, fmt.Errorf("clan leader %d: %w", leaderCharID, ErrForeignKeyViolation)
+	}
+	r.s.nextClanID++
+	clanID := r.s.nextClanID
+	r.s.clans[clanID] = &persist.ClanRow{
+		ClanID:       clanID,
+		ClanName:     clanName,
+		LeaderID:     leaderCharID,
+		LeaderName:   leaderName,
+		FoundDate:    foundDate,
+		Announcement: []byte{},
+	}
+	r.s.members[memberKey{clanID, leaderCharID}] = &persist.ClanMemberRow{
+		ClanID: clanID, CharID: leaderCharID, CharName: leaderName, Rank: 10, Notes: []byte{},
+	}
+	r.s.setCharClan(leaderCharID, clanID, clanName, 10)
+	return clanID, nil
+}
+
+func (r *ClanRepo) AddMember(_ context.Context, clanID int32, clanName string, charID int32, charName string, rank int16) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if r.s.clans[clanID] == nil || r.s.chars[charID] == nil {
+		return fmt.Errorf("clan member %d/%d: %w", clanID, charID, ErrForeignKeyViolation)
+	}
+	key := memberKey{clanID, charID}
+	if r.s.members[key] != nil {
+		return fmt.Errorf("clan member %d/%d: %w", clanID, charID, ErrUniqueViolation)
+	}
+	r.s.members[key] = &persist.ClanMemberRow{
+		ClanID: clanID, CharID: charID, CharName: charName, Rank: rank, Notes: []byte{},
+	}
+	r.s.setCharClan(charID, clanID, clanName, rank)
+	return nil
+}
+
+func (r *ClanRepo) RemoveMember(_ context.Context, clanID, charID int32) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	delete(r.s.members, memberKey{clanID, charID})
+	r.s.setCharClan(charID, 0, "", 0)
+	return nil
+}
+
+// DissolveClan removes a clan and its members and clears their characters'
+// clan columns.
+func (r *ClanRepo) DissolveClan(_ context.Context, clanID int32) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for k := range r.s.members {
+		if k.clanID == clanID {
+			r.s.setCharClan(k.charID, 0, "", 0)
+			delete(r.s.members, k)
+		}
+	}
+	delete(r.s.clans, clanID)
+	return nil
+}
+
+func (r *ClanRepo) UpdateAnnouncement(_ context.Context, clanID int32, announcement []byte) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if c := r.s.clans[clanID]; c != nil {
+		c.Announcement = cloneBytes(announcement)
+	}
+	return nil
+}
+
+func (r *ClanRepo) UpdateMemberNotes(_ context.Context, clanID, charID int32, notes []byte) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if m := r.s.members[memberKey{clanID, charID}]; m != nil {
+		m.Notes = cloneBytes(notes)
+	}
+	return nil
+}
+
+func (r *ClanRepo) UpdateMemberRank(_ context.Context, clanID, charID int32, rank int16) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if m := r.s.members[memberKey{clanID, charID}]; m != nil {
+		m.Rank = rank
+	}
+	if c := r.s.chars[charID]; c != nil {
+		c.row.ClanRank = rank
+	}
+	return nil
+}
+
+// LoadOfflineCharClan returns charID, clanID, clanName and clanRank of the
+// live character named charName, or pgx.ErrNoRows.
+func (r *ClanRepo) LoadOfflineCharClan(_ context.Context, charName string) (int32, int32, string, int16, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	c := r.s.byName(charName, true)
+	if c == nil {
+		return 0, 0, "", 0, pgx.ErrNoRows
+	}
+	return c.row.ID, c.row.ClanID, c.row.ClanName, c.row.ClanRank, nil
+}
+
+func (r *ClanRepo) UpdateEmblemID(_ context.Context, clanID, emblemID int32) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if c := r.s.clans[clanID]; c != nil {
+		c.EmblemID, c.EmblemStatus = emblemID, 1
+	}
+	return nil
+}
+
+func (r *ClanRepo) MaxEmblemID(_ context.Context) (int32, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var maxID int32
+	for _, c := range r.s.clans {
+		if c.EmblemID > maxID {
+			maxID = c.EmblemID
+		}
+	}
+	return maxID, nil
+}
+
+func (r *ClanRepo) UpdateHasCastle(_ context.Context, clanID int32, castleID int32) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if c := r.s.clans[clanID]; c != nil {
+		c.HasCastle = castleID
+	}
+	return nil
+}
+
+// HouseRepo implements persist.HouseStore.
+type HouseRepo struct{ s *Store }
+
+func (r *HouseRepo) LoadAll(_ context.Context) ([]persist.HouseState, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	result := make([]persist.HouseState, 0, len(r.s.houses))
+	for _, id := range sortedKeys(r.s.houses) {
+		result = append(result, *r.s.houses[id])
+	}
+	return result, nil
+}
+
+// updateHouse applies fn to the house, if it exists.
+func (s *Store) updateHouse(houseID int32, fn func(h *persist.HouseState)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if h := s.houses[houseID]; h != nil {
+		fn(h)
+	}
+	return nil
+}
+
+func (r *HouseRepo) UpdateBasement(_ context.Context, houseID int32, purchased bool) error {
+	return r.s.updateHouse(houseID, func(h *persist.HouseState) { h.IsPurchaseBasement = purchased })
+}
+
+func (r *HouseRepo) UpdateName(_ context.Context, houseID int32, name string) error {
+	return r.s.updateHouse(houseID, func(h *persist.HouseState) { h.HouseName = name })
+}
+
+func (r *HouseRepo) UpdateTaxDeadline(_ context.Context, houseID int32, deadline time.Time) error {
+	return r.s.updateHouse(houseID, func(h *persist.HouseState) { h.TaxDeadline = deadline })
+}
+
+func (r *HouseRepo) SyncKeeperID(_ context.Context, houseID int32, keeperID int32) error {
+	return r.s.updateHouse(houseID, func(h *persist.HouseState) { h.KeeperID = keeperID })
+}
+
+// InnRepo implements persist.InnStore.
+type InnRepo struct{ s *Store }
+
+// LoadAll returns copies of every room, ordered by NPC and room number.
+func (r *InnRepo) LoadAll(_ context.Context) ([]*persist.InnRoom, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	result := make([]*persist.InnRoom, 0, len(r.s.inns))
+	for _, room := range r.s.inns {
+		cp := *room
+		result = append(result, &cp)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].NpcID != result[j].NpcID {
+			return result[i].NpcID < result[j].NpcID
+		}
+		return result[i].RoomNumber < result[j].RoomNumber
+	})
+	return result, nil
+}
+
+func (r *InnRepo) UpdateRoom(_ context.Context, room *persist.InnRoom) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if old := r.s.inns[innKey{room.NpcID, room.RoomNumber}]; old != nil {
+		old.KeyID, old.LodgerID, old.Hall, old.DueTime = room.KeyID, room.LodgerID, room.Hall, room.DueTime
+	}
+	return nil
+}
+
+// EnsureRooms creates the NPC's 16 rooms that do not exist yet.
+func (r *InnRepo) EnsureRooms(_ context.Context, npcID int32) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for i := int32(0); i < 16; i++ {
+		key := innKey{npcID, i}
+		if r.s.inns[key] == nil {
+			r.s.inns[key] = &persist.InnRoom{NpcID: npcID, RoomNumber: i, DueTime: time.Unix(0, 0).UTC()}
+		}
+	}
+	return nil
+}
+
+// CastleRepo implements persist.CastleStore.
+type CastleRepo struct{ s *Store }
+
+func (r *CastleRepo) LoadAll(_ context.Context) ([]persist.CastleState, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	result := make([]persist.CastleState, 0, len(r.s.castles))
+	for _, id := range sortedKeys(r.s.castles) {
+		result = append(result, *r.s.castles[id])
+	}
+	return result, nil
+}
+
+// updateCastle applies fn to the castle, if it exists.
+func (s *Store) updateCastle(castleID int32, fn func(c *persist.CastleState)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c := s.castles[castleID]; c != nil {
+		fn(c)
+	}
+	return nil
+}
+
+func (r *CastleRepo) UpdateTaxRate(_ context.Context, castleID int32, rate int32) error {
+	return r.s.updateCastle(castleID, func(c *persist.CastleState) { c.TaxRate = rate })
+}
+
+func (r *CastleRepo) UpdatePublicMoney(_ context.Context, castleID int32, money int64) error {
+	return r.s.updateCastle(castleID, func(c *persist.CastleState) { c.PublicMoney = money })
+}
+
+func (r *CastleRepo) UpdateWarTime(_ context.Context, castleID int32, warTime time.Time) error {
+	return r.s.updateCastle(castleID, func(c *persist.CastleState) { c.WarTime = warTime })
+}
+
+// ResetForWar resets the tax rate to 10 and empties the treasury.
+func (r *CastleRepo) ResetForWar(_ context.Context, castleID int32) error {
+	return r.s.updateCastle(castleID, func(c *persist.CastleState) { c.TaxRate, c.PublicMoney = 10, 0 })
+}
@@ -0,0 +1,331 @@
+package memdb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/l1jgo/server/internal/persist"
+	"github.com/l1jgo/server/internal/world"
+)
+
+// ItemRepo implements persist.ItemStore.
+type ItemRepo struct{ s *Store }
+
+func (r *ItemRepo) LoadByCharID(_ context.Context, charID int32) ([]persist.ItemRow, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var result []persist.ItemRow
+	for _, id := range sortedKeys(r.s.items) {
+		if it := r.s.items[id]; it.CharID == charID {
+			result = append(result, *it)
+		}
+	}
+	return result, nil
+}
+
+// MaxObjID returns the maximum obj_id across character and warehouse items.
+func (r *ItemRepo) MaxObjID(_ context.Context) (int32, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var maxID int32
+	for _, it := range r.s.items {
+		maxID = max(maxID, it.ObjID)
+	}
+	for _, w := range r.s.warehouse {
+		maxID = max(maxID, w.ObjID)
+	}
+	return maxID, nil
+}
+
+// SaveInventory writes the inventory like the PostgreSQL repo: upserts by
+// obj_id (an existing row of another character is taken over), deletes the
+// character's rows no longer carried (every other row on the first save after
+// login), and sets the apply markers of the character's WAL entries written
+// so far. Must be called on the game loop.
+func (r *ItemRepo) SaveInventory(ctx context.Context, charID int32, inv *world.Inventory, equip *world.Equipment) (persist.InventorySaveStats, error) {
+	save := r.PrepareInventorySave(charID, inv, equip)
+	stats, err := r.WriteInventory(ctx, save)
+	if err == nil {
+		inv.CommitSave(save.Changes)
+	}
+	return stats, err
+}
+
+// PrepareInventorySave snapshots the inventory's pending changes and the WAL
+// entries written so far. Game loop only.
+func (r *ItemRepo) PrepareInventorySave(charID int32, inv *world.Inventory, equip *world.Equipment) persist.InventorySave {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return persist.InventorySave{CharID: charID, Changes: inv.PendingChanges(charID, equip), WALSeq: r.s.walSeq}
+}
+
+// WriteInventory writes a snapshot taken by PrepareInventorySave.
+func (r *ItemRepo) WriteInventory(_ context.Context, save persist.InventorySave) (persist.InventorySaveStats, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	charID, changes := save.CharID, save.Changes
+	stats := persist.InventorySaveStats{Items: changes.Total, Full: changes.Full}
+	if len(changes.Upserts) > 0 && r.s.chars[charID] == nil {
+		return stats, fmt.Errorf("character_items char %d: %w", charID, ErrForeignKeyViolation)
+	}
+
+	for _, rec := range changes.Upserts {
+		r.s.upsertItem(itemRowFromRecord(charID, rec))
+	}
+	stats.Upserted = len(changes.Upserts)
+
+	var drop []int32
+	if changes.Full {
+		keep := make(map[int32]bool, len(changes.Upserts))
+		for _, rec := range changes.Upserts {
+			keep[rec.ObjID] = true
+		}
+		for id, it := range r.s.items {
+			if it.CharID == charID && !keep[it.ObjID] {
+				drop = append(drop, id)
+			}
+		}
+	} else if len(changes.Deleted) > 0 {
+		del := make(map[int32]bool, len(changes.Deleted))
+		for _, objID := range changes.Deleted {
+			del[objID] = true
+		}
+		for id, it := range r.s.items {
+			if it.CharID == charID && del[it.ObjID] {
+				drop = append(drop, id)
+			}
+		}
+	}
+	for _, id := range drop {
+		r.s.deleteItem(id)
+	}
+	stats.Deleted = len(drop)
+
+	r.s.markWALApplied(charID, save.WALSeq)
+	return stats, nil
+}
+
+// itemRowFromRecord converts an inventory record to its character_items row.
+func itemRowFromRecord(charID int32, rec world.ItemRecord) persist.ItemRow {
+	return persist.ItemRow{
+		CharID:           charID,
+		ItemID:           rec.ItemID,
+		Count:            rec.Count,
+		EnchantLvl:       int16(rec.EnchantLvl),
+		Bless:            int16(rec.Bless),
+		Equipped:         rec.Equipped,
+		Identified:       rec.Identified,
+		EquipSlot:        int16(rec.EquipSlot),
+		ObjID:            rec.ObjID,
+		Durability:       int16(rec.Durability),
+		AttrEnchantKind:  int16(rec.AttrEnchantKind),
+		AttrEnchantLevel: int16(rec.AttrEnchantLevel),
+		InnKeyID:         rec.InnKeyID,
+		InnNpcID:         rec.InnNpcID,
+		InnHall:          rec.InnHall,
+		InnDueTime:       rec.InnDueTime,
+		ChargeCount:      rec.ChargeCount,
+	}
+}
+
+// upsertItem inserts row, or overwrites the row holding the same non-zero
+// obj_id (ON CONFLICT (obj_id) WHERE obj_id <> 0), keeping its id.
+func (s *Store) upsertItem(row persist.ItemRow) {
+	if row.ObjID != 0 {
+		if id, ok := s.objItems[row.ObjID]; ok {
+			row.ID = id
+			*s.items[id] = row
+			return
+		}
+	}
+	s.insertItem(row)
+}
+
+// insertItem adds row under a new id. The caller has checked the character.
+func (s *Store) insertItem(row persist.ItemRow) {
+	s.nextItemID++
+	row.ID = s.nextItemID
+	s.items[row.ID] = &row
+	if row.ObjID != 0 {
+		s.objItems[row.ObjID] = row.ID
+	}
+}
+
+func (s *Store) deleteItem(id int32) {
+	it := s.items[id]
+	if it == nil {
+		return
+	}
+	if it.ObjID != 0 && s.objItems[it.ObjID] == id {
+		delete(s.objItems, it.ObjID)
+	}
+	delete(s.items, id)
+}
+
+// findItem returns the first row of charID, in id order, accepted by match.
+func (s *Store) findItem(charID int32, match func(*persist.ItemRow) bool) *persist.ItemRow {
+	var found *persist.ItemRow
+	for _, it := range s.items {
+		if it.CharID == charID && match(it) && (found == nil || it.ID < found.ID) {
+			found = it
+		}
+	}
+	return found
+}
+
+// addCharacterGold adds adena to a character's saved inventory: the first
+// adena row, or a new one.
+func (s *Store) addCharacterGold(charID int32, amount int64) error {
+	if it := s.findItem(charID, func(it *persist.ItemRow) bool { return it.ItemID == world.AdenaItemID }); it != nil {
+		it.Count += int32(amount)
+		return nil
+	}
+	if s.chars[charID] == nil {
+		return fmt.Errorf("character_items char %d: %w", charID, ErrForeignKeyViolation)
+	}
+	s.insertItem(persist.ItemRow{CharID: charID, ItemID: world.AdenaItemID, Count: int32(amount), Bless: 1, Identified: true})
+	return nil
+}
+
+// snapshotItems copies the item table so a failed multi-row write can be
+// rolled back.
+func (s *Store) snapshotItems() func() {
+	items := make(map[int32]persist.ItemRow, len(s.items))
+	for id, it := range s.items {
+		items[id] = *it
+	}
+	nextID := s.nextItemID
+	return func() {
+		s.items = make(map[int32]*persist.ItemRow, len(items))
+		s.objItems = make(map[int32]int32, len(items))
+		for id, it := range items {
+			it := it
+			s.items[id] = &it
+			if it.ObjID != 0 {
+				s.objItems[it.ObjID] = id
+			}
+		}
+		s.nextItemID = nextID
+	}
+}
+
+// ItemDupeRepo implements persist.ItemDupeStore.
+type ItemDupeRepo struct{ s *Store }
+
+// copies returns every obj_id-carrying row of both item tables accepted by
+// keep, ordered by obj_id, table, id.
+func (s *Store) copies(keep func(objID int32) bool) []persist.ItemCopy {
+	var result []persist.ItemCopy
+	for _, it := range s.items {
+		if it.ObjID != 0 && keep(it.ObjID) {
+			result = append(result, persist.ItemCopy{
+				Table: persist.TableCharacterItems, RowID: it.ID, ObjID: it.ObjID, CharID: it.CharID,
+				ItemID: it.ItemID, Count: it.Count, EnchantLvl: it.EnchantLvl, Bless: it.Bless, Identified: it.Identified,
+			})
+		}
+	}
+	for _, w := range s.warehouse {
+		if w.ObjID != 0 && keep(w.ObjID) {
+			result = append(result, persist.ItemCopy{
+				Table: persist.TableWarehouseItems, RowID: w.ID, ObjID: w.ObjID, Account: w.AccountName, WhType: w.WhType,
+				ItemID: w.ItemID, Count: w.Count, EnchantLvl: w.EnchantLvl, Bless: w.Bless, Identified: w.Identified,
+			})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.ObjID != b.ObjID {
+			return a.ObjID < b.ObjID
+		}
+		if a.Table != b.Table {
+			return a.Table < b.Table
+		}
+		return a.RowID < b.RowID
+	})
+	return result
+}
+
+// FindDuplicates returns every copy of obj_ids stored more than once (at most
+// limit obj_ids, the lowest ones), ordered by obj_id.
+func (r *ItemDupeRepo) FindDuplicates(_ context.Context, limit int) ([]persist.ItemCopy, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	counts := make(map[int32]int)
+	for _, c := range r.s.copies(func(int32) bool { return true }) {
+		counts[c.ObjID]++
+	}
+	var dupes []int32
+	for objID, n := range counts {
+		if n > 1 {
+			dupes = append(dupes, objID)
+		}
+	}
+	sort.Slice(dupes, func(i, j int) bool { return dupes[i] < dupes[j] })
+	if len(dupes) > limit {
+		dupes = dupes[:limit]
+	}
+	want := make(map[int32]bool, len(dupes))
+	for _, objID := range dupes {
+		want[objID] = true
+	}
+	return r.s.copies(func(objID int32) bool { return want[objID] }), nil
+}
+
+func (r *ItemDupeRepo) FindCopies(_ context.Context, objIDs []int32) ([]persist.ItemCopy, error) {
+	if len(objIDs) == 0 {
+		return nil, nil
+	}
+	want := make(map[int32]bool, len(objIDs))
+	for _, objID := range objIDs {
+		want[objID] = true
+	}
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return r.s.copies(func(objID int32) bool { return want[objID] }), nil
+}
+
+// Quarantine deletes the given persisted copies and records them, plus held,
+// in the quarantine table. A copy already gone is not recorded; if fewer than
+// two copies remain nothing changes and persist.ErrQuarantineStale is returned.
+func (r *ItemDupeRepo) Quarantine(_ context.Context, copies []persist.ItemCopy, held []persist.QuarantineRow, reason string) error {
+	for _, c := range copies {
+		if c.Table != persist.TableCharacterItems && c.Table != persist.TableWarehouseItems {
+			return fmt.Errorf("quarantine: unknown table %q", c.Table)
+		}
+	}
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	present := make([]persist.ItemCopy, 0, len(copies))
+	for _, c := range copies {
+		switch c.Table {
+		case persist.TableCharacterItems:
+			if it := r.s.items[c.RowID]; it == nil || it.ObjID != c.ObjID {
+				continue
+			}
+		case persist.TableWarehouseItems:
+			if w := r.s.warehouse[c.RowID]; w == nil || w.ObjID != c.ObjID {
+				continue
+			}
+		}
+		present = append(present, c)
+	}
+	if len(held)+len(present) < 2 {
+		return persist.ErrQuarantineStale
+	}
+	rows := append([]persist.QuarantineRow(nil), held...)
+	for _, c := range present {
+		if c.Table == persist.TableCharacterItems {
+			r.s.deleteItem(c.RowID)
+		} else {
+			delete(r.s.warehouse, c.RowID)
+		}
+		rows = append(rows, persist.QuarantineRow{
+			ObjID: c.ObjID, ItemID: c.ItemID, Count: c.Count,
+			EnchantLvl: c.EnchantLvl, Bless: c.Bless, Identified: c.Identified,
+			Source: c.Loc(), Reason: reason,
+		})
+	}
+	r.s.quarantine = append(r.s.quarantine, rows...)
+	return nil
+}
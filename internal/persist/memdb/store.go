@@ -0,0 +1,210 @@
+// Package memdb implements the persist repository interfaces in memory
+// (database.driver = "memory"), for local development and automated tests.
+//
+// Every repo of one Store shares the same tables under a single mutex, so the
+// cross-table behaviour of the PostgreSQL schema carries over: WAL entries
+// written by warehouse, auction and mail writes commit together with the row
+// they describe, SaveInventory sets the character's WAL apply markers,
+// RecoverWAL replays unapplied character sides into the item table, deleting
+// a character cascades to its items, buffs and quests, and foreign keys and
+// unique constraints fail the same writes. Nothing survives a restart.
+package memdb
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/l1jgo/server/internal/persist"
+)
+
+// Constraint errors, standing in for the ones PostgreSQL reports.
+var (
+	ErrUniqueViolation     = errors.New("memdb: unique constraint violation")
+	ErrForeignKeyViolation = errors.New("memdb: foreign key violation")
+	ErrCheckViolation      = errors.New("memdb: check constraint violation")
+)
+
+// Store holds every table. Create it with New and hand out its repos with
+// Stores.
+type Store struct {
+	mu sync.Mutex
+
+	accounts map[string]*persist.AccountRow
+	chars    map[int32]*charRow
+	items    map[int32]*persist.ItemRow
+	objItems map[int32]int32 // obj_id → character_items id (obj_id <> 0, unique)
+
+	warehouse map[int32]*persist.WarehouseItem
+	whHistory []*whHistoryRow
+	wal       []*walRow
+	walSeq    int64
+	walSaved  map[int32]int64 // wal_char_saved: char_id → saved high-water mark
+
+	itemEvents []persist.ItemEventRow
+	quarantine []persist.QuarantineRow
+
+	clans   map[int32]*persist.ClanRow
+	members map[memberKey]*persist.ClanMemberRow
+
+	buffs    map[int32][]persist.BuffRow
+	quests   map[int32]map[int32]int32
+	buddies  []persist.BuddyRow
+	excludes []excludeRow
+	board    map[int32]*persist.BoardPost
+	mail     map[int32]*persist.MailRow
+	pets     map[int32]*persist.PetRow
+
+	houses   map[int32]*persist.HouseState
+	auctions map[int32]*persist.AuctionEntry
+	inns     map[innKey]*persist.InnRoom
+	castles  map[int32]*persist.CastleState
+
+	bans       []*persist.BanRow
+	cheatFlags []persist.CheatFlagRow
+
+	// SERIAL counters
+	nextCharID, nextItemID, nextWhID, nextHistID int32
+	nextClanID, nextBoardID, nextMailID          int32
+	nextBanID                                    int32
+	nextWALID, nextEventID, nextFlagID           int64
+}
+
+// charRow is a characters row with its JSONB / BYTEA columns, kept in their
+// stored form so loads decode exactly like the PostgreSQL repo.
+type charRow struct {
+	row         persist.CharacterRow
+	bookmarks   []byte // JSONB, default '[]'
+	knownSpells []byte // JSONB, default '[]'
+	mapTimes    []byte // JSONB, default '{}'
+	charConfig  []byte // BYTEA, default NULL
+}
+
+type memberKey struct{ clanID, charID int32 }
+
+type innKey struct{ npcID, room int32 }
+
+type excludeRow struct {
+	charID int32
+	name   string
+}
+
+type whHistoryRow struct {
+	id        int32
+	clanID    int32
+	charName  string
+	histType  int
+	itemName  string
+	itemCount int32
+	at        time.Time
+}
+
+type walRow struct {
+	id          int64
+	entry       persist.WALEntry
+	fromApplied bool
+	toApplied   bool
+	processed   bool
+}
+
+// castleSeeds are the castles inserted by migration 026.
+var castleSeeds = []string{"肯特", "妖魔", "風木", "奇巖", "海音", "侏儒", "亞丁", "狄亞得"}
+
+// New creates an empty store seeded like a freshly migrated database: the
+// houses and their auction board rows (023) and the castles (026).
+func New() (*Store, error) {
+	houses, err := persist.SeedHouses()
+	if err != nil {
+		return nil, fmt.Errorf("seed houses: %w", err)
+	}
+	s := &Store{
+		accounts:  make(map[string]*persist.AccountRow),
+		chars:     make(map[int32]*charRow),
+		items:     make(map[int32]*persist.ItemRow),
+		objItems:  make(map[int32]int32),
+		warehouse: make(map[int32]*persist.WarehouseItem),
+		walSaved:  make(map[int32]int64),
+		clans:     make(map[int32]*persist.ClanRow),
+		members:   make(map[memberKey]*persist.ClanMemberRow),
+		buffs:     make(map[int32][]persist.BuffRow),
+		quests:    make(map[int32]map[int32]int32),
+		board:     make(map[int32]*persist.BoardPost),
+		mail:      make(map[int32]*persist.MailRow),
+		pets:      make(map[int32]*persist.PetRow),
+		houses:    make(map[int32]*persist.HouseState),
+		auctions:  make(map[int32]*persist.AuctionEntry),
+		inns:      make(map[innKey]*persist.InnRoom),
+		castles:   make(map[int32]*persist.CastleState),
+	}
+
+	now := time.Now()
+	for _, h := range houses {
+		h.TaxDeadline = now.Add(30 * 24 * time.Hour)
+		s.houses[h.HouseID] = &h
+		s.auctions[h.HouseID] = &persist.AuctionEntry{
+			HouseID:   h.HouseID,
+			HouseName: h.HouseName,
+			HouseArea: h.HouseArea,
+			Deadline:  now.Add(30 * 24 * time.Hour),
+			Price:     100000,
+			Location:  h.Location,
+		}
+	}
+	for i, name := range castleSeeds {
+		id := int32(i + 1)
+		s.castles[id] = &persist.CastleState{
+			CastleID:   id,
+			CastleName: name,
+			TaxRate:    10,
+			WarTime:    now.Add(7 * 24 * time.Hour),
+		}
+	}
+	return s, nil
+}
+
+// Stores returns the store's repositories.
+func (s *Store) Stores() *persist.Stores {
+	return &persist.Stores{
+		Accounts:   &AccountRepo{s},
+		Characters: &CharacterRepo{s},
+		Items:      &ItemRepo{s},
+		Warehouse:  &WarehouseRepo{s},
+		WAL:        &WALRepo{s},
+		ItemEvents: &ItemEventRepo{s},
+		ItemDupes:  &ItemDupeRepo{s},
+		Clans:      &ClanRepo{s},
+		Buffs:      &BuffRepo{s},
+		Quests:     &QuestRepo{s},
+		Buddies:    &BuddyRepo{s},
+		Excludes:   &ExcludeRepo{s},
+		Board:      &BoardRepo{s},
+		Mail:       &MailRepo{s},
+		Pets:       &PetRepo{s},
+		Auctions:   &AuctionRepo{s},
+		Houses:     &HouseRepo{s},
+		Inns:       &InnRepo{s},
+		Castles:    &CastleRepo{s},
+		Bans:       &BanRepo{s},
+		CheatFlags: &CheatFlagRepo{s},
+	}
+}
+
+// sortedKeys returns the keys of m in ascending order (ORDER BY id).
+func sortedKeys[K int32 | int64, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+// cloneBytes copies b, keeping nil as nil.
+func cloneBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
@@ -0,0 +1,263 @@
+package memdb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/l1jgo/server/internal/persist"
+	"github.com/l1jgo/server/internal/world"
+	"go.uber.org/zap"
+)
+
+// WALRepo implements persist.WALStore. Writes are immediate, so there is no
+// async writer: StartAsync and Close do nothing.
+type WALRepo struct{ s *Store }
+
+func (r *WALRepo) WriteWAL(_ context.Context, entries []persist.WALEntry) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.insertWAL(entries)
+	return nil
+}
+
+// insertWAL appends entries, giving those without a sequence number the next
+// ones. A character side already covered by that character's saved high-water
+// mark is inserted applied. The caller's slice is not modified.
+func (s *Store) insertWAL(entries []persist.WALEntry) {
+	for _, e := range entries {
+		if e.Seq == 0 {
+			s.walSeq++
+			e.Seq = s.walSeq
+		}
+		s.nextWALID++
+		s.wal = append(s.wal, &walRow{
+			id:          s.nextWALID,
+			entry:       e,
+			fromApplied: s.walCovered(e.FromChar, e.Seq),
+			toApplied:   s.walCovered(e.ToChar, e.Seq),
+		})
+	}
+}
+
+// walCovered reports whether charID's saved high-water mark covers seq.
+func (s *Store) walCovered(charID int32, seq int64) bool {
+	return charID != 0 && s.walSaved[charID] >= seq
+}
+
+// markWALApplied raises charID's saved high-water mark to seq and sets the
+// apply markers of every entry touching charID up to seq (called by
+// SaveInventory).
+func (s *Store) markWALApplied(charID int32, seq int64) {
+	if seq == 0 {
+		return
+	}
+	s.walSaved[charID] = max(s.walSaved[charID], seq)
+	for _, w := range s.wal {
+		if w.entry.Seq > seq {
+			continue
+		}
+		if w.entry.FromChar == charID {
+			w.fromApplied = true
+		}
+		if w.entry.ToChar == charID {
+			w.toApplied = true
+		}
+	}
+}
+
+// MarkProcessed marks entries processed once every character side has been
+// applied. Returns the number of entries processed.
+func (r *WALRepo) MarkProcessed(_ context.Context) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var n int64
+	for _, w := range r.s.wal {
+		if !w.processed && (w.entry.FromChar == 0 || w.fromApplied) && (w.entry.ToChar == 0 || w.toApplied) {
+			w.processed = true
+			n++
+		}
+	}
+	return n, nil
+}
+
+// RecoverWAL replays the unapplied character sides of every unprocessed entry
+// into the item table, in seq order, and marks the entries processed; then the
+// sequence resumes after the highest seq written or covered by a saved
+// high-water mark. Any replay error rolls everything back, like the
+// PostgreSQL transaction.
+func (r *WALRepo) RecoverWAL(_ context.Context) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var pending []*walRow
+	var maxSeq int64
+	for _, w := range r.s.wal {
+		if !w.processed {
+			pending = append(pending, w)
+		}
+		if w.entry.Seq > maxSeq {
+			maxSeq = w.entry.Seq
+		}
+	}
+	for _, seq := range r.s.walSaved {
+		maxSeq = max(maxSeq, seq)
+	}
+	r.s.walSeq = maxSeq
+	if len(pending) == 0 {
+		return 0, nil
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		if pending[i].entry.Seq != pending[j].entry.Seq {
+			return pending[i].entry.Seq < pending[j].entry.Seq
+		}
+		return pending[i].id < pending[j].id
+	})
+
+	rollback := r.s.snapshotItems()
+	for _, w := range pending {
+		e := w.entry
+		if w.fromApplied {
+			e.FromChar = 0
+		}
+		if w.toApplied {
+			e.ToChar = 0
+		}
+		if e.FromChar != 0 || e.ToChar != 0 {
+			if err := r.s.replayWAL(e, w.entry); err != nil {
+				rollback()
+				return 0, fmt.Errorf("wal recover %s (id=%d): %w", w.entry.TxType, w.id, err)
+			}
+		}
+	}
+	for _, w := range pending {
+		w.fromApplied, w.toApplied, w.processed = true, true, true
+	}
+	return len(pending), nil
+}
+
+func (r *WALRepo) StartAsync(_ *zap.Logger) {}
+
+func (r *WALRepo) Close() {}
+
+// replayWAL applies the unapplied character sides of one entry (e, with
+// applied sides zeroed) according to its tx_type, validated on orig.
+func (s *Store) replayWAL(e, orig persist.WALEntry) error {
+	switch e.TxType {
+	case persist.WALTrade, persist.WALPrivateShop:
+		if orig.FromChar == 0 || orig.ToChar == 0 {
+			return fmt.Errorf("character-to-character entry without both characters")
+		}
+	case persist.WALShopBuy, persist.WALShopSell, persist.WALWarehouseDeposit, persist.WALWarehouseWithdraw,
+		persist.WALClanWarehouseDeposit, persist.WALClanWarehouseWithdraw, persist.WALAuction, persist.WALMail:
+		if orig.FromChar == 0 && orig.ToChar == 0 {
+			return fmt.Errorf("entry has no character side")
+		}
+	default:
+		return fmt.Errorf("unknown tx_type %q", e.TxType)
+	}
+
+	if e.GoldAmount > 0 {
+		if e.FromChar > 0 {
+			s.replayRemove(e.FromChar, persist.WALEntry{ItemID: world.AdenaItemID, Count: int32(e.GoldAmount), Stackable: true})
+		}
+		if e.ToChar > 0 {
+			if err := s.replayAdd(e.ToChar, persist.WALEntry{ItemID: world.AdenaItemID, Count: int32(e.GoldAmount), Bless: 1, Identified: true, Stackable: true}); err != nil {
+				return fmt.Errorf("gold add: %w", err)
+			}
+		}
+		return nil
+	}
+
+	if e.ItemID <= 0 || e.Count <= 0 {
+		return nil
+	}
+
+	// 不可堆疊物品在兩個角色之間移動：直接轉移整列
+	if e.FromChar > 0 && e.ToChar > 0 && !e.Stackable && e.ObjID > 0 {
+		if it := s.findItem(e.FromChar, func(it *persist.ItemRow) bool {
+			return it.ObjID == e.ObjID && it.ItemID == e.ItemID
+		}); it != nil {
+			if s.chars[e.ToChar] == nil {
+				return fmt.Errorf("item move: character_items char %d: %w", e.ToChar, ErrForeignKeyViolation)
+			}
+			it.CharID, it.Equipped, it.EquipSlot = e.ToChar, false, 0
+			return nil
+		}
+		return s.replayAdd(e.ToChar, e)
+	}
+
+	if e.FromChar > 0 {
+		s.replayRemove(e.FromChar, e)
+	}
+	if e.ToChar > 0 {
+		if err := s.replayAdd(e.ToChar, e); err != nil {
+			return fmt.Errorf("item add: %w", err)
+		}
+	}
+	return nil
+}
+
+// replayRemove takes e.Count of the item away from a character's saved
+// inventory: the exact obj_id row, else the largest stack, else the first
+// unequipped row with the same enchant level.
+func (s *Store) replayRemove(charID int32, e persist.WALEntry) {
+	var row *persist.ItemRow
+	switch {
+	case e.ObjID > 0:
+		row = s.findItem(charID, func(it *persist.ItemRow) bool { return it.ObjID == e.ObjID && it.ItemID == e.ItemID })
+	case e.Stackable:
+		for _, it := range s.items {
+			if it.CharID == charID && it.ItemID == e.ItemID &&
+				(row == nil || it.Count > row.Count || (it.Count == row.Count && it.ID < row.ID)) {
+				row = it
+			}
+		}
+	default:
+		for _, it := range s.items {
+			if it.CharID != charID || it.ItemID != e.ItemID || it.EnchantLvl != e.EnchantLvl {
+				continue
+			}
+			if row == nil || (!it.Equipped && row.Equipped) || (it.Equipped == row.Equipped && it.ID < row.ID) {
+				row = it
+			}
+		}
+	}
+	if row == nil {
+		return
+	}
+	if row.Count > e.Count {
+		row.Count -= e.Count
+	} else {
+		s.deleteItem(row.ID)
+	}
+}
+
+// replayAdd gives e.Count of the item to a character: stackables merge into
+// the first stack, non-stackables get one row each with obj_id 0.
+func (s *Store) replayAdd(charID int32, e persist.WALEntry) error {
+	if e.Stackable {
+		if it := s.findItem(charID, func(it *persist.ItemRow) bool { return it.ItemID == e.ItemID }); it != nil {
+			it.Count += e.Count
+			return nil
+		}
+		return s.insertReplayItem(charID, e, e.Count)
+	}
+	for i := int32(0); i < e.Count; i++ {
+		if err := s.insertReplayItem(charID, e, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) insertReplayItem(charID int32, e persist.WALEntry, count int32) error {
+	if s.chars[charID] == nil {
+		return fmt.Errorf("character_items char %d: %w", charID, ErrForeignKeyViolation)
+	}
+	s.insertItem(persist.ItemRow{
+		CharID: charID, ItemID: e.ItemID, Count: count,
+		EnchantLvl: e.EnchantLvl, Bless: e.Bless, Identified: e.Identified,
+	})
+	return nil
+}
@@ -0,0 +1,65 @@
+package memdb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/l1jgo/server/internal/persist"
+	"github.com/l1jgo/server/internal/world"
+)
+
+// 存檔水位高於已寫入的最大 seq（崩潰時仍在非同步佇列中的條目）：重啟後序號須從水位之後
+// 接續，新條目不可被水位視為已套用，崩潰恢復時照常重播。
+func TestRecoverWALResumesAboveSavedMark(t *testing.T) {
+	ctx := context.Background()
+	s, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := s.Stores()
+	if _, err := st.Accounts.Create(ctx, "acc", "pw", "127.0.0.1", "localhost"); err != nil {
+		t.Fatal(err)
+	}
+	char := &persist.CharacterRow{AccountName: "acc", Name: "tester"}
+	if err := st.Characters.Create(ctx, char); err != nil {
+		t.Fatal(err)
+	}
+
+	// seq 1 已寫入；seq 2~5 已指定但崩潰時未寫入，存檔水位卻已涵蓋
+	buy := persist.WALEntry{TxType: persist.WALShopBuy, ToChar: char.ID, ItemID: 40010, Count: 1,
+		Identified: true, Stackable: true, Target: "npc:70012"}
+	if err := st.WAL.WriteWAL(ctx, []persist.WALEntry{buy}); err != nil {
+		t.Fatal(err)
+	}
+	s.walSeq = 5
+	if _, err := st.Items.SaveInventory(ctx, char.ID, world.NewInventory(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重啟
+	if _, err := st.WAL.RecoverWAL(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if s.walSeq != 5 {
+		t.Fatalf("sequence resumed at %d, want the saved mark 5", s.walSeq)
+	}
+
+	// 重啟後、下次存檔前的轉移在崩潰後必須重播
+	if err := st.WAL.WriteWAL(ctx, []persist.WALEntry{buy}); err != nil {
+		t.Fatal(err)
+	}
+	w := s.wal[len(s.wal)-1]
+	if w.entry.Seq != 6 || w.toApplied {
+		t.Fatalf("new entry seq %d applied %v, want seq 6 unapplied", w.entry.Seq, w.toApplied)
+	}
+	if n, err := st.WAL.RecoverWAL(ctx); err != nil || n != 1 {
+		t.Fatalf("recovered %d entries (err %v), want 1", n, err)
+	}
+	rows, err := st.Items.LoadByCharID(ctx, char.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].ItemID != 40010 || rows[0].Count != 1 {
+		t.Fatalf("character items %+v, want one replayed potion", rows)
+	}
+}
@@ -0,0 +1,320 @@
+package memdb
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/l1jgo/server/internal/persist"
+)
+
+// WarehouseRepo implements persist.WarehouseStore.
+type WarehouseRepo struct{ s *Store }
+
+// loadWarehouse returns the rows of whType accepted by match, in id order.
+func (s *Store) loadWarehouse(whType int16, match func(*persist.WarehouseItem) bool) []persist.WarehouseItem {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var result []persist.WarehouseItem
+	for _, id := range sortedKeys(s.warehouse) {
+		if w := s.warehouse[id]; w.WhType == whType && match(w) {
+			result = append(result, *w)
+		}
+	}
+	return result
+}
+
+// Load returns all warehouse items for an account (the clan name for the
+// clan warehouse) and warehouse type.
+func (r *WarehouseRepo) Load(_ context.Context, accountName string, whType int16) ([]persist.WarehouseItem, error) {
+	return r.s.loadWarehouse(whType, func(w *persist.WarehouseItem) bool { return w.AccountName == accountName }), nil
+}
+
+// LoadByCharName returns the character warehouse (wh_type=6) of charName.
+func (r *WarehouseRepo) LoadByCharName(_ context.Context, charName string, whType int16) ([]persist.WarehouseItem, error) {
+	return r.s.loadWarehouse(whType, func(w *persist.WarehouseItem) bool { return w.CharName == charName }), nil
+}
+
+// Deposit inserts a new item, together with its WAL entries.
+func (r *WarehouseRepo) Deposit(_ context.Context, item persist.WarehouseItem, wal ...persist.WALEntry) (int32, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.insertWAL(wal)
+	r.s.nextWhID++
+	item.ID = r.s.nextWhID
+	r.s.warehouse[item.ID] = &item
+	return item.ID, nil
+}
+
+// AddToStack increases the count of a stackable item, together with its WAL
+// entries. A missing row is not an error (UPDATE of no rows).
+func (r *WarehouseRepo) AddToStack(_ context.Context, whItemID int32, addCount int32, wal ...persist.WALEntry) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.insertWAL(wal)
+	if w := r.s.warehouse[whItemID]; w != nil {
+		w.Count += addCount
+	}
+	return nil
+}
+
+// Withdraw removes an item or decrements its count; true if fully removed.
+// A missing row fails with pgx.ErrNoRows and writes no WAL entries.
+func (r *WarehouseRepo) Withdraw(_ context.Context, whItemID int32, count int32, wal ...persist.WALEntry) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	w := r.s.warehouse[whItemID]
+	if w == nil {
+		return false, pgx.ErrNoRows
+	}
+	r.s.insertWAL(wal)
+	w.Count -= count
+	if w.Count <= 0 {
+		delete(r.s.warehouse, whItemID)
+		return true, nil
+	}
+	return false, nil
+}
+
+func (r *WarehouseRepo) InsertClanWarehouseHistory(_ context.Context, clanID int32, charName string, histType int, itemName string, itemCount int32) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.nextHistID++
+	r.s.whHistory = append(r.s.whHistory, &whHistoryRow{
+		id: r.s.nextHistID, clanID: clanID, charName: charName,
+		histType: histType, itemName: itemName, itemCount: itemCount, at: time.Now(),
+	})
+	return nil
+}
+
+// LoadClanWarehouseHistory returns the clan's history of the last 3 days,
+// newest first, after deleting its older records.
+func (r *WarehouseRepo) LoadClanWarehouseHistory(_ context.Context, clanID int32) ([]persist.ClanWarehouseHistoryEntry, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := time.Now()
+	cutoff := now.Add(-3 * 24 * time.Hour)
+	kept := r.s.whHistory[:0]
+	for _, h := range r.s.whHistory {
+		if h.clanID != clanID || !h.at.Before(cutoff) {
+			kept = append(kept, h)
+		}
+	}
+	r.s.whHistory = kept
+
+	var result []persist.ClanWarehouseHistoryEntry
+	for i := len(r.s.whHistory) - 1; i >= 0; i-- {
+		h := r.s.whHistory[i]
+		if h.clanID != clanID {
+			continue
+		}
+		result = append(result, persist.ClanWarehouseHistoryEntry{
+			CharName:   h.charName,
+			Type:       h.histType,
+			ItemName:   h.itemName,
+			ItemCount:  h.itemCount,
+			MinutesAgo: int32(math.Round(now.Sub(h.at).Seconds())) / 60,
+		})
+	}
+	return result, nil
+}
+
+// MailRepo implements persist.MailStore.
+type MailRepo struct{ s *Store }
+
+func copyMail(m *persist.MailRow) *persist.MailRow {
+	row := *m
+	row.Subject = cloneBytes(m.Subject)
+	row.Content = cloneBytes(m.Content)
+	return &row
+}
+
+// LoadByInbox returns the inbox's mails of mailType, newest first.
+func (r *MailRepo) LoadByInbox(_ context.Context, inboxID int32, mailType int16) ([]persist.MailRow, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	ids := sortedKeys(r.s.mail)
+	var result []persist.MailRow
+	for i := len(ids) - 1; i >= 0; i-- {
+		if m := r.s.mail[ids[i]]; m.InboxID == inboxID && m.Type == mailType {
+			result = append(result, *copyMail(m))
+		}
+	}
+	return result, nil
+}
+
+func (r *MailRepo) GetByID(_ context.Context, id int32) (*persist.MailRow, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	m := r.s.mail[id]
+	if m == nil {
+		return nil, nil
+	}
+	return copyMail(m), nil
+}
+
+// Write inserts a mail, together with its WAL entries (the sending fee).
+func (r *MailRepo) Write(_ context.Context, m *persist.MailRow, wal ...persist.WALEntry) (int32, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.insertWAL(wal)
+	r.s.nextMailID++
+	row := copyMail(m)
+	row.ID = r.s.nextMailID
+	r.s.mail[row.ID] = row
+	return row.ID, nil
+}
+
+func (r *MailRepo) SetReadStatus(_ context.Context, id int32) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if m := r.s.mail[id]; m != nil {
+		m.ReadStatus = 1
+	}
+	return nil
+}
+
+func (r *MailRepo) SetType(_ context.Context, id int32, newType int16) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if m := r.s.mail[id]; m != nil {
+		m.Type = newType
+	}
+	return nil
+}
+
+func (r *MailRepo) Delete(_ context.Context, id int32) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	delete(r.s.mail, id)
+	return nil
+}
+
+func (r *MailRepo) CountByInbox(_ context.Context, inboxID int32, mailType int16) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	n := 0
+	for _, m := range r.s.mail {
+		if m.InboxID == inboxID && m.Type == mailType {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// BoardRepo implements persist.BoardStore.
+type BoardRepo struct{ s *Store }
+
+// ListPage returns up to limit posts with ID <= beforeID (any ID if
+// beforeID <= 0), newest first, without content.
+func (r *BoardRepo) ListPage(_ context.Context, beforeID int32, limit int) ([]persist.BoardPost, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	ids := sortedKeys(r.s.board)
+	var result []persist.BoardPost
+	for i := len(ids) - 1; i >= 0 && len(result) < limit; i-- {
+		if beforeID > 0 && ids[i] > beforeID {
+			continue
+		}
+		p := *r.s.board[ids[i]]
+		p.Content = ""
+		result = append(result, p)
+	}
+	return result, nil
+}
+
+func (r *BoardRepo) GetByID(_ context.Context, id int32) (*persist.BoardPost, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	p := r.s.board[id]
+	if p == nil {
+		return nil, nil
+	}
+	post := *p
+	return &post, nil
+}
+
+func (r *BoardRepo) Write(_ context.Context, name, date, title, content string) (int32, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.nextBoardID++
+	id := r.s.nextBoardID
+	r.s.board[id] = &persist.BoardPost{ID: id, Name: name, Date: date, Title: title, Content: content}
+	return id, nil
+}
+
+func (r *BoardRepo) Delete(_ context.Context, id int32) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	delete(r.s.board, id)
+	return nil
+}
+
+// AuctionRepo implements persist.AuctionStore.
+type AuctionRepo struct{ s *Store }
+
+func (r *AuctionRepo) LoadAll(_ context.Context) ([]persist.AuctionEntry, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	result := make([]persist.AuctionEntry, 0, len(r.s.auctions))
+	for _, id := range sortedKeys(r.s.auctions) {
+		result = append(result, *r.s.auctions[id])
+	}
+	return result, nil
+}
+
+// UpdateBid updates the bid, together with its WAL entries.
+func (r *AuctionRepo) UpdateBid(_ context.Context, houseID int32, price int64, bidder string, bidderID int32, wal ...persist.WALEntry) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.insertWAL(wal)
+	if e := r.s.auctions[houseID]; e != nil {
+		e.Price, e.Bidder, e.BidderID = price, bidder, bidderID
+	}
+	return nil
+}
+
+func (r *AuctionRepo) UpdateDeadline(_ context.Context, houseID int32, deadline time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if e := r.s.auctions[houseID]; e != nil {
+		e.Deadline = deadline
+	}
+	return nil
+}
+
+// DeleteAuction removes a finished auction, together with its WAL entries.
+func (r *AuctionRepo) DeleteAuction(_ context.Context, houseID int32, wal ...persist.WALEntry) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.insertWAL(wal)
+	delete(r.s.auctions, houseID)
+	return nil
+}
+
+// InsertAuction adds an auction; an existing one of the house keeps its
+// name, area and location and takes the rest.
+func (r *AuctionRepo) InsertAuction(_ context.Context, e *persist.AuctionEntry) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if r.s.houses[e.HouseID] == nil {
+		return fmt.Errorf("auction house %d: %w", e.HouseID, ErrForeignKeyViolation)
+	}
+	if old := r.s.auctions[e.HouseID]; old != nil {
+		old.Deadline, old.Price = e.Deadline, e.Price
+		old.OldOwner, old.OldOwnerID, old.Bidder, old.BidderID = e.OldOwner, e.OldOwnerID, e.Bidder, e.BidderID
+		return nil
+	}
+	row := *e
+	r.s.auctions[e.HouseID] = &row
+	return nil
+}
+
+// RefundOfflineGold adds gold to an offline character's saved adena row.
+func (r *AuctionRepo) RefundOfflineGold(_ context.Context, charID int32, amount int64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return r.s.addCharacterGold(charID, amount)
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"embed"
 	"fmt"
+	"regexp"
+	"strconv"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jackc/pgx/v5/stdlib"
@@ -30,3 +32,35 @@ func RunMigrations(ctx context.Context, pool *pgxpool.Pool) error {
 
 	return nil
 }
+
+// houseSeedRe matches one (house_id,'house_name',house_area,'location') row of
+// the houses insert in 023_auction.sql.
+var houseSeedRe = regexp.MustCompile(`\((\d+),'([^']*)',(\d+),'([^']*)'\)`)
+
+// SeedHouses returns the houses seeded by migration 023, in house_id order,
+// for stores that start empty instead of running the migrations (memdb).
+// KeeperID and TaxDeadline are left zero.
+func SeedHouses() ([]HouseState, error) {
+	raw, err := migrations.ReadFile("migrations/023_auction.sql")
+	if err != nil {
+		return nil, err
+	}
+	var result []HouseState
+	for _, m := range houseSeedRe.FindAllStringSubmatch(string(raw), -1) {
+		id, err := strconv.ParseInt(m[1], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("house seed %q: %w", m[0], err)
+		}
+		area, err := strconv.ParseInt(m[3], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("house seed %q: %w", m[0], err)
+		}
+		result = append(result, HouseState{
+			HouseID:   int32(id),
+			HouseName: m[2],
+			HouseArea: int32(area),
+			Location:  m[4],
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,18 @@
+-- +goose Up
+
+-- WAL 涵蓋所有物品/金幣轉移路徑：記錄來源物件、堆疊屬性與非角色端目標（倉庫、拍賣、NPC、信件）
+ALTER TABLE economic_wal ALTER COLUMN tx_type TYPE VARCHAR(32);
+ALTER TABLE economic_wal ADD COLUMN obj_id     INT NOT NULL DEFAULT 0;
+ALTER TABLE economic_wal ADD COLUMN bless      SMALLINT NOT NULL DEFAULT 0;
+ALTER TABLE economic_wal ADD COLUMN identified BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE economic_wal ADD COLUMN stackable  BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE economic_wal ADD COLUMN target     VARCHAR(64) NOT NULL DEFAULT '';
+
+-- +goose Down
+
+ALTER TABLE economic_wal DROP COLUMN IF EXISTS target;
+ALTER TABLE economic_wal DROP COLUMN IF EXISTS stackable;
+ALTER TABLE economic_wal DROP COLUMN IF EXISTS identified;
+ALTER TABLE economic_wal DROP COLUMN IF EXISTS bless;
+ALTER TABLE economic_wal DROP COLUMN IF EXISTS obj_id;
+ALTER TABLE economic_wal ALTER COLUMN tx_type TYPE VARCHAR(16);
@@ -0,0 +1,21 @@
+-- +goose Up
+
+-- WAL 逐筆套用標記：seq 為寫入順序；from_applied / to_applied 表示該角色端的變更
+-- 已隨角色存檔（SaveInventory）或崩潰重播寫入 character_items，重播時不再套用。
+ALTER TABLE economic_wal ADD COLUMN seq          BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE economic_wal ADD COLUMN from_applied BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE economic_wal ADD COLUMN to_applied   BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE economic_wal SET seq = id;
+UPDATE economic_wal SET from_applied = TRUE, to_applied = TRUE WHERE processed;
+
+CREATE INDEX idx_wal_from_unapplied ON economic_wal(from_char) WHERE NOT from_applied;
+CREATE INDEX idx_wal_to_unapplied ON economic_wal(to_char) WHERE NOT to_applied;
+
+-- +goose Down
+
+DROP INDEX IF EXISTS idx_wal_to_unapplied;
+DROP INDEX IF EXISTS idx_wal_from_unapplied;
+ALTER TABLE economic_wal DROP COLUMN IF EXISTS to_applied;
+ALTER TABLE economic_wal DROP COLUMN IF EXISTS from_applied;
+ALTER TABLE economic_wal DROP COLUMN IF EXISTS seq;
@@ -0,0 +1,34 @@
+-- +goose Up
+
+-- 背包改為增量存檔（以 obj_id UPSERT），非零 obj_id 必須唯一。
+-- 既有重複者保留最新一列，其餘移入複製物品隔離區（item_quarantine）待 GM 處理，
+-- 不重新配發 ObjectID（否則複製品會成為合法物品，偵測也無從查起）。
+CREATE TABLE IF NOT EXISTS item_quarantine (
+    id          SERIAL PRIMARY KEY,
+    obj_id      INT NOT NULL,
+    item_id     INT NOT NULL,
+    count       INT NOT NULL,
+    enchant_lvl SMALLINT NOT NULL DEFAULT 0,
+    bless       SMALLINT NOT NULL DEFAULT 0,
+    identified  BOOLEAN NOT NULL DEFAULT TRUE,
+    source      VARCHAR(64) NOT NULL,     -- 原位置（char:<id>、warehouse:<帳號>:<類型>…）
+    reason      VARCHAR(32) NOT NULL,     -- online / autosave / db_scan / migration
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO item_quarantine (obj_id, item_id, count, enchant_lvl, bless, identified, source, reason)
+SELECT c.obj_id, c.item_id, c.count, c.enchant_lvl, c.bless, c.identified, 'char:' || c.char_id, 'migration'
+  FROM character_items c
+ WHERE c.obj_id <> 0
+   AND EXISTS (SELECT 1 FROM character_items d WHERE d.obj_id = c.obj_id AND d.id > c.id);
+
+DELETE FROM character_items c
+ WHERE c.obj_id <> 0
+   AND EXISTS (SELECT 1 FROM character_items d WHERE d.obj_id = c.obj_id AND d.id > c.id);
+
+CREATE UNIQUE INDEX idx_character_items_obj ON character_items(obj_id) WHERE obj_id <> 0;
+
+-- +goose Down
+
+DROP INDEX IF EXISTS idx_character_items_obj;
+DROP TABLE IF EXISTS item_quarantine;
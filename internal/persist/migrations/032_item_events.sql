@@ -0,0 +1,36 @@
+-- +goose Up
+
+-- 物品來源稽核：每次物品建立、轉手、換位置、銷毀各一列，只新增不修改。
+CREATE TABLE item_events (
+    id          BIGSERIAL PRIMARY KEY,
+    obj_id      INT NOT NULL,
+    item_id     INT NOT NULL,
+    count       INT NOT NULL,
+    enchant_lvl SMALLINT NOT NULL DEFAULT 0,
+    from_loc    VARCHAR(64) NOT NULL DEFAULT '',   -- 空字串 = 建立
+    to_loc      VARCHAR(64) NOT NULL DEFAULT '',   -- 空字串 = 銷毀
+    reason      VARCHAR(32) NOT NULL,
+    map_id      SMALLINT NOT NULL DEFAULT 0,
+    x           INT NOT NULL DEFAULT 0,
+    y           INT NOT NULL DEFAULT 0,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_item_events_obj ON item_events(obj_id, id);
+
+-- +goose StatementBegin
+CREATE FUNCTION item_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'item_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER trg_item_events_append_only
+    BEFORE UPDATE OR DELETE ON item_events
+    FOR EACH ROW EXECUTE FUNCTION item_events_append_only();
+
+-- +goose Down
+
+DROP TABLE IF EXISTS item_events;
+DROP FUNCTION IF EXISTS item_events_append_only();
@@ -0,0 +1,14 @@
+-- +goose Up
+
+-- 倉庫保存非堆疊物品的 ObjectID，複製物品偵測可跨 character_items / warehouse_items 比對。
+ALTER TABLE warehouse_items ADD COLUMN obj_id INT NOT NULL DEFAULT 0;
+CREATE INDEX idx_warehouse_obj ON warehouse_items(obj_id) WHERE obj_id <> 0;
+
+-- 複製物品隔離區（031 已建立，遷移時的重複列已移入）：偵測到同一 obj_id 出現多份時，所有副本移到此處待 GM 處理。
+CREATE INDEX idx_item_quarantine_obj ON item_quarantine(obj_id);
+
+-- +goose Down
+
+DROP INDEX IF EXISTS idx_item_quarantine_obj;
+DROP INDEX IF EXISTS idx_warehouse_obj;
+ALTER TABLE warehouse_items DROP COLUMN IF EXISTS obj_id;
@@ -0,0 +1,23 @@
+-- +goose Up
+
+-- 封鎖記錄：對象為帳號或 IP 網段（CIDR）其一。expires_at 為 NULL 表示永久；
+-- 解除封鎖只寫入 lifted_at / lifted_by，保留歷史供 .baninfo 查詢。
+CREATE TABLE bans (
+    id           SERIAL PRIMARY KEY,
+    account_name VARCHAR(32),
+    ip_cidr      CIDR,
+    reason       VARCHAR(255) NOT NULL DEFAULT '',
+    issuer       VARCHAR(32) NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at   TIMESTAMPTZ,
+    lifted_at    TIMESTAMPTZ,
+    lifted_by    VARCHAR(32),
+    CHECK ((account_name IS NULL) <> (ip_cidr IS NULL))
+);
+
+CREATE INDEX idx_bans_account ON bans(account_name) WHERE account_name IS NOT NULL;
+CREATE INDEX idx_bans_ip ON bans USING gist (ip_cidr inet_ops) WHERE ip_cidr IS NOT NULL;
+
+-- +goose Down
+
+DROP TABLE IF EXISTS bans;
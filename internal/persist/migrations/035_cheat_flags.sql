@@ -0,0 +1,24 @@
+-- +goose Up
+
+-- 反作弊標記：動作頻率異常（移動加速等）升級到回彈或踢除時各記錄一列，供 GM 審查。
+CREATE TABLE cheat_flags (
+    id           BIGSERIAL PRIMARY KEY,
+    char_id      INT NOT NULL,
+    char_name    VARCHAR(32) NOT NULL,
+    account_name VARCHAR(32) NOT NULL DEFAULT '',
+    ip           VARCHAR(45) NOT NULL DEFAULT '',
+    kind         VARCHAR(16) NOT NULL,   -- speed
+    action       VARCHAR(16) NOT NULL,   -- reject / kick
+    violations   INT NOT NULL,           -- 滾動視窗內的違規次數
+    detail       VARCHAR(255) NOT NULL DEFAULT '',
+    map_id       SMALLINT NOT NULL DEFAULT 0,
+    x            INT NOT NULL DEFAULT 0,
+    y            INT NOT NULL DEFAULT 0,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_cheat_flags_char ON cheat_flags(char_name, id);
+
+-- +goose Down
+
+DROP TABLE IF EXISTS cheat_flags;
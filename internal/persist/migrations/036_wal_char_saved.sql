@@ -0,0 +1,12 @@
+-- +goose Up
+
+-- WAL 存檔水位：角色最後一次成功存檔的背包已包含 seq 以內、涉及該角色的所有 WAL 條目。
+-- 與背包同一交易寫入；非同步模式下存檔後才寫入的條目（seq 不超過水位）視為已套用，重播時略過。
+CREATE TABLE wal_char_saved (
+    char_id INT    PRIMARY KEY,
+    seq     BIGINT NOT NULL
+);
+
+-- +goose Down
+
+DROP TABLE IF EXISTS wal_char_saved;
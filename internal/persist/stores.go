@@ -0,0 +1,285 @@
+package persist
+
+import (
+	"context"
+	"time"
+
+	"github.com/l1jgo/server/internal/world"
+	"go.uber.org/zap"
+)
+
+// The interfaces below are the repository contracts the game depends on. The
+// PostgreSQL repos in this package implement them; package memdb provides an
+// in-memory implementation with the same semantics (database.driver = "memory").
+
+// AccountStore is implemented by AccountRepo.
+type AccountStore interface {
+	Load(ctx context.Context, name string) (*AccountRow, error)
+	Create(ctx context.Context, name, rawPassword, ip, host string) (*AccountRow, error)
+	ValidatePassword(hash string, rawPassword string) bool
+	UpdateLastActive(ctx context.Context, name, ip string) error
+	SetOnline(ctx context.Context, name string, online bool) error
+	UpdateWarehousePassword(ctx context.Context, name string, pass int32) error
+}
+
+// CharacterStore is implemented by CharacterRepo.
+type CharacterStore interface {
+	LoadByAccount(ctx context.Context, accountName string) ([]CharacterRow, error)
+	LoadByName(ctx context.Context, name string) (*CharacterRow, error)
+	Create(ctx context.Context, c *CharacterRow) error
+	NameExists(ctx context.Context, name string) (bool, error)
+	CountByAccount(ctx context.Context, accountName string) (int, error)
+	SoftDelete(ctx context.Context, name string) error
+	HardDelete(ctx context.Context, name string) error
+	CleanExpiredDeletions(ctx context.Context, accountName string) (int64, error)
+	SavePosition(ctx context.Context, name string, x, y int32, mapID, heading int16) error
+	SaveCharacter(ctx context.Context, c *CharacterRow) error
+	LoadBookmarks(ctx context.Context, name string) ([]BookmarkRow, error)
+	SaveBookmarks(ctx context.Context, name string, bookmarks []BookmarkRow) error
+	LoadKnownSpells(ctx context.Context, name string) ([]int32, error)
+	SaveKnownSpells(ctx context.Context, name string, spells []int32) error
+	LoadCharConfig(ctx context.Context, charID int32) ([]byte, error)
+	SaveCharConfig(ctx context.Context, charID int32, data []byte) error
+	LoadMapTimes(ctx context.Context, name string) (map[int]int, error)
+	SaveMapTimes(ctx context.Context, name string, mapTimes map[int]int) error
+	ResetAllMapTimes(ctx context.Context) error
+}
+
+// ItemStore is implemented by ItemRepo.
+type ItemStore interface {
+	LoadByCharID(ctx context.Context, charID int32) ([]ItemRow, error)
+	MaxObjID(ctx context.Context) (int32, error)
+	SaveInventory(ctx context.Context, charID int32, inv *world.Inventory, equip *world.Equipment) (InventorySaveStats, error)
+	PrepareInventorySave(charID int32, inv *world.Inventory, equip *world.Equipment) InventorySave
+	WriteInventory(ctx context.Context, save InventorySave) (InventorySaveStats, error)
+}
+
+// WarehouseStore is implemented by WarehouseRepo.
+type WarehouseStore interface {
+	Load(ctx context.Context, accountName string, whType int16) ([]WarehouseItem, error)
+	LoadByCharName(ctx context.Context, charName string, whType int16) ([]WarehouseItem, error)
+	Deposit(ctx context.Context, item WarehouseItem, wal ...WALEntry) (int32, error)
+	AddToStack(ctx context.Context, whItemID int32, addCount int32, wal ...WALEntry) error
+	Withdraw(ctx context.Context, whItemID int32, count int32, wal ...WALEntry) (bool, error)
+	InsertClanWarehouseHistory(ctx context.Context, clanID int32, charName string, histType int, itemName string, itemCount int32) error
+	LoadClanWarehouseHistory(ctx context.Context, clanID int32) ([]ClanWarehouseHistoryEntry, error)
+}
+
+// WALStore is implemented by WALRepo.
+type WALStore interface {
+	WriteWAL(ctx context.Context, entries []WALEntry) error
+	MarkProcessed(ctx context.Context) (int64, error)
+	RecoverWAL(ctx context.Context) (int, error)
+	StartAsync(log *zap.Logger)
+	Close()
+}
+
+// ItemEventStore is implemented by ItemEventRepo.
+type ItemEventStore interface {
+	Append(ctx context.Context, events []ItemEventRow) error
+	History(ctx context.Context, objID int32, limit int) ([]ItemEventRow, error)
+}
+
+// ItemDupeStore is implemented by ItemDupeRepo.
+type ItemDupeStore interface {
+	FindDuplicates(ctx context.Context, limit int) ([]ItemCopy, error)
+	FindCopies(ctx context.Context, objIDs []int32) ([]ItemCopy, error)
+	Quarantine(ctx context.Context, copies []ItemCopy, held []QuarantineRow, reason string) error
+}
+
+// ClanStore is implemented by ClanRepo.
+type ClanStore interface {
+	LoadAll(ctx context.Context) ([]ClanRow, []ClanMemberRow, error)
+	CreateClan(ctx context.Context, leaderCharID int32, leaderName, clanName string, foundDate int32) (int32, error)
+	AddMember(ctx context.Context, clanID int32, clanName string, charID int32, charName string, rank int16) error
+	RemoveMember(ctx context.Context, clanID, charID int32) error
+	DissolveClan(ctx context.Context, clanID int32) error
+	UpdateAnnouncement(ctx context.Context, clanID int32, announcement []byte) error
+	UpdateMemberNotes(ctx context.Context, clanID, charID int32, notes []byte) error
+	UpdateMemberRank(ctx context.Context, clanID, charID int32, rank int16) error
+	LoadOfflineCharClan(ctx context.Context, charName string) (int32, int32, string, int16, error)
+	UpdateEmblemID(ctx context.Context, clanID, emblemID int32) error
+	MaxEmblemID(ctx context.Context) (int32, error)
+	UpdateHasCastle(ctx context.Context, clanID int32, castleID int32) error
+}
+
+// BuffStore is implemented by BuffRepo.
+type BuffStore interface {
+	LoadByCharID(ctx context.Context, charID int32) ([]BuffRow, error)
+	SaveBuffs(ctx context.Context, charID int32, buffs []BuffRow) error
+	DeleteByCharID(ctx context.Context, charID int32) error
+}
+
+// QuestStore is implemented by QuestRepo.
+type QuestStore interface {
+	LoadAll(ctx context.Context, charID int32) (map[int32]int32, error)
+	SetStep(ctx context.Context, charID, questID, step int32) error
+	SetCompleted(ctx context.Context, charID int32, questID int32) error
+	DeleteQuest(ctx context.Context, charID, questID int32) error
+}
+
+// BuddyStore is implemented by BuddyRepo.
+type BuddyStore interface {
+	LoadByCharID(ctx context.Context, charID int32) ([]BuddyRow, error)
+	Add(ctx context.Context, charID, buddyID int32, buddyName string) error
+	Remove(ctx context.Context, charID int32, buddyName string) error
+}
+
+// ExcludeStore is implemented by ExcludeRepo.
+type ExcludeStore interface {
+	LoadByCharID(ctx context.Context, charID int32) ([]string, error)
+	Add(ctx context.Context, charID int32, excludeName string) error
+	Remove(ctx context.Context, charID int32, excludeName string) error
+}
+
+// BoardStore is implemented by BoardRepo.
+type BoardStore interface {
+	ListPage(ctx context.Context, beforeID int32, limit int) ([]BoardPost, error)
+	GetByID(ctx context.Context, id int32) (*BoardPost, error)
+	Write(ctx context.Context, name, date, title, content string) (int32, error)
+	Delete(ctx context.Context, id int32) error
+}
+
+// MailStore is implemented by MailRepo.
+type MailStore interface {
+	LoadByInbox(ctx context.Context, inboxID int32, mailType int16) ([]MailRow, error)
+	GetByID(ctx context.Context, id int32) (*MailRow, error)
+	Write(ctx context.Context, m *MailRow, wal ...WALEntry) (int32, error)
+	SetReadStatus(ctx context.Context, id int32) error
+	SetType(ctx context.Context, id int32, newType int16) error
+	Delete(ctx context.Context, id int32) error
+	CountByInbox(ctx context.Context, inboxID int32, mailType int16) (int, error)
+}
+
+// PetStore is implemented by PetRepo.
+type PetStore interface {
+	LoadByItemObjID(ctx context.Context, itemObjID int32) (*PetRow, error)
+	Save(ctx context.Context, p *PetRow) error
+	Delete(ctx context.Context, itemObjID int32) error
+}
+
+// AuctionStore is implemented by AuctionRepo.
+type AuctionStore interface {
+	LoadAll(ctx context.Context) ([]AuctionEntry, error)
+	UpdateBid(ctx context.Context, houseID int32, price int64, bidder string, bidderID int32, wal ...WALEntry) error
+	UpdateDeadline(ctx context.Context, houseID int32, deadline time.Time) error
+	DeleteAuction(ctx context.Context, houseID int32, wal ...WALEntry) error
+	InsertAuction(ctx context.Context, e *AuctionEntry) error
+	RefundOfflineGold(ctx context.Context, charID int32, amount int64) error
+}
+
+// HouseStore is implemented by HouseRepo.
+type HouseStore interface {
+	LoadAll(ctx context.Context) ([]HouseState, error)
+	UpdateBasement(ctx context.Context, houseID int32, purchased bool) error
+	UpdateName(ctx context.Context, houseID int32, name string) error
+	UpdateTaxDeadline(ctx context.Context, houseID int32, deadline time.Time) error
+	SyncKeeperID(ctx context.Context, houseID int32, keeperID int32) error
+}
+
+// InnStore is implemented by InnRepo.
+type InnStore interface {
+	LoadAll(ctx context.Context) ([]*InnRoom, error)
+	UpdateRoom(ctx context.Context, room *InnRoom) error
+	EnsureRooms(ctx context.Context, npcID int32) error
+}
+
+// CastleStore is implemented by CastleRepo.
+type CastleStore interface {
+	LoadAll(ctx context.Context) ([]CastleState, error)
+	UpdateTaxRate(ctx context.Context, castleID int32, rate int32) error
+	UpdatePublicMoney(ctx context.Context, castleID int32, money int64) error
+	UpdateWarTime(ctx context.Context, castleID int32, warTime time.Time) error
+	ResetForWar(ctx context.Context, castleID int32) error
+}
+
+// BanStore is implemented by BanRepo.
+type BanStore interface {
+	Add(ctx context.Context, b *BanRow) error
+	FindActive(ctx context.Context, account, ip string) (*BanRow, error)
+	Lift(ctx context.Context, account, cidr, by string) (int64, error)
+	History(ctx context.Context, account, ip string, limit int) ([]BanRow, error)
+}
+
+// CheatFlagStore is implemented by CheatFlagRepo.
+type CheatFlagStore interface {
+	Append(ctx context.Context, flags []CheatFlagRow) error
+	Recent(ctx context.Context, charName string, limit int) ([]CheatFlagRow, error)
+}
+
+// Stores bundles one implementation of every repository.
+type Stores struct {
+	Accounts   AccountStore
+	Characters CharacterStore
+	Items      ItemStore
+	Warehouse  WarehouseStore
+	WAL        WALStore
+	ItemEvents ItemEventStore
+	ItemDupes  ItemDupeStore
+	Clans      ClanStore
+	Buffs      BuffStore
+	Quests     QuestStore
+	Buddies    BuddyStore
+	Excludes   ExcludeStore
+	Board      BoardStore
+	Mail       MailStore
+	Pets       PetStore
+	Auctions   AuctionStore
+	Houses     HouseStore
+	Inns       InnStore
+	Castles    CastleStore
+	Bans       BanStore
+	CheatFlags CheatFlagStore
+}
+
+// NewStores creates the PostgreSQL repositories on db.
+func NewStores(db *DB) *Stores {
+	return &Stores{
+		Accounts:   NewAccountRepo(db),
+		Characters: NewCharacterRepo(db),
+		Items:      NewItemRepo(db),
+		Warehouse:  NewWarehouseRepo(db),
+		WAL:        NewWALRepo(db),
+		ItemEvents: NewItemEventRepo(db),
+		ItemDupes:  NewItemDupeRepo(db),
+		Clans:      NewClanRepo(db),
+		Buffs:      NewBuffRepo(db),
+		Quests:     NewQuestRepo(db),
+		Buddies:    NewBuddyRepo(db),
+		Excludes:   NewExcludeRepo(db),
+		Board:      NewBoardRepo(db),
+		Mail:       NewMailRepo(db),
+		Pets:       NewPetRepo(db),
+		Auctions:   NewAuctionRepo(db),
+		Houses:     NewHouseRepo(db),
+		Inns:       NewInnRepo(db),
+		Castles:    NewCastleRepo(db),
+		Bans:       NewBanRepo(db),
+		CheatFlags: NewCheatFlagRepo(db),
+	}
+}
+
+// Compile-time checks that the PostgreSQL repos satisfy the interfaces.
+var (
+	_ AccountStore   = (*AccountRepo)(nil)
+	_ CharacterStore = (*CharacterRepo)(nil)
+	_ ItemStore      = (*ItemRepo)(nil)
+	_ WarehouseStore = (*WarehouseRepo)(nil)
+	_ WALStore       = (*WALRepo)(nil)
+	_ ItemEventStore = (*ItemEventRepo)(nil)
+	_ ItemDupeStore  = (*ItemDupeRepo)(nil)
+	_ ClanStore      = (*ClanRepo)(nil)
+	_ BuffStore      = (*BuffRepo)(nil)
+	_ QuestStore     = (*QuestRepo)(nil)
+	_ BuddyStore     = (*BuddyRepo)(nil)
+	_ ExcludeStore   = (*ExcludeRepo)(nil)
+	_ BoardStore     = (*BoardRepo)(nil)
+	_ MailStore      = (*MailRepo)(nil)
+	_ PetStore       = (*PetRepo)(nil)
+	_ AuctionStore   = (*AuctionRepo)(nil)
+	_ HouseStore     = (*HouseRepo)(nil)
+	_ InnStore       = (*InnRepo)(nil)
+	_ CastleStore    = (*CastleRepo)(nil)
+	_ BanStore       = (*BanRepo)(nil)
+	_ CheatFlagStore = (*CheatFlagRepo)(nil)
+)
@@ -2,18 +2,86 @@ package persist
 
 import (
 	"context"
+	"errors"
 	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/l1jgo/server/internal/world"
+)
+
+// WAL transaction types. Every path that moves items or gold between a
+// character and anything else writes one of these before (or atomically with)
+// the change, so a crash before the next PersistenceSystem save can be replayed.
+const (
+	WALTrade                 = "trade"            // 玩家交易：角色 ↔ 角色
+	WALPrivateShop           = "private_shop"     // 個人商店：角色 ↔ 角色
+	WALShopBuy               = "shop_buy"         // NPC 商店購買：金幣 → NPC，物品 → 角色
+	WALShopSell              = "shop_sell"        // NPC 商店販賣：物品 → NPC，金幣 → 角色
+	WALWarehouseDeposit      = "wh_deposit"       // 個人/妖精/角色倉庫存入：角色 → warehouse_items
+	WALWarehouseWithdraw     = "wh_withdraw"      // 個人/妖精/角色倉庫領出：warehouse_items → 角色（含費用）
+	WALClanWarehouseDeposit  = "clan_wh_deposit"  // 血盟倉庫存入
+	WALClanWarehouseWithdraw = "clan_wh_withdraw" // 血盟倉庫領出
+	WALAuction               = "auction"          // 小屋拍賣：出價 → auction_board，退款/結算 → 角色
+	WALMail                  = "mail"             // 寄信費用：角色 → 系統
+	WALScript                = "script"           // Lua 腳本給予/收回物品：系統 ↔ 角色
 )
 
-// WALEntry represents one economic write-ahead log entry.
+// WALEntry represents one economic write-ahead log entry: a single movement of
+// one item stack or one gold amount. FromChar/ToChar are character IDs; 0 means
+// the other side is not a character (NPC, warehouse, auction board, mail fee),
+// described by Target.
 type WALEntry struct {
-	TxType     string // "trade", "shop", "auction"
+	TxType     string // WALTrade, WALShopBuy, ...
 	FromChar   int32
 	ToChar     int32
 	ItemID     int32
 	Count      int32
 	EnchantLvl int16
-	GoldAmount int64
+	GoldAmount int64 // > 0 for gold movements (ItemID = AdenaItemID)
+	ObjID      int32 // source item ObjectID (character side), 0 if none
+	Bless      int16
+	Identified bool
+	Stackable  bool
+	Target     string // non-character side, e.g. "warehouse:acc:3", "auction:262145", "npc:70012"
+	Seq        int64  // write order, assigned by WriteWAL / the repo write that carries the entry
+}
+
+// ItemWAL builds an entry moving count of item from one side to the other.
+func ItemWAL(txType string, from, to int32, item *world.InvItem, count int32) WALEntry {
+	return WALEntry{
+		TxType:     txType,
+		FromChar:   from,
+		ToChar:     to,
+		ItemID:     item.ItemID,
+		Count:      count,
+		EnchantLvl: int16(item.EnchantLvl),
+		ObjID:      item.ObjectID,
+		Bless:      int16(item.Bless),
+		Identified: item.Identified,
+		Stackable:  item.Stackable,
+	}
+}
+
+// GoldWAL builds an entry moving amount adena from one side to the other.
+func GoldWAL(txType string, from, to int32, amount int64) WALEntry {
+	return WALEntry{
+		TxType:     txType,
+		FromChar:   from,
+		ToChar:     to,
+		ItemID:     world.AdenaItemID,
+		Count:      1,
+		GoldAmount: amount,
+		Identified: true,
+		Stackable:  true,
+	}
+}
+
+// querier is satisfied by both *pgxpool.Pool and pgx.Tx, so repo writes can
+// run standalone or inside the transaction that records their WAL entries.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
 }
 
 type WALRepo struct {
@@ -26,60 +94,170 @@ func NewWALRepo(db *DB) *WALRepo {
 
 // WriteWAL atomically writes a batch of WAL entries in a single transaction.
 // Returns nil on success. If it fails, the caller should cancel the operation.
+//
+// In async mode (StartAsync) the entries are only sequenced and queued for the
+// background writer, and WriteWAL returns nil immediately — unless the queue
+// is full or the writer is failing, in which case they are written here.
 func (r *WALRepo) WriteWAL(ctx context.Context, entries []WALEntry) error {
-	tx, err := r.db.Pool.Begin(ctx)
+	if w := r.db.walWriter; w != nil {
+		batch := make([]WALEntry, len(entries))
+		copy(batch, entries)
+		assignWALSeq(r.db, batch)
+		if w.enqueue(batch) {
+			return nil
+		}
+		// 退回同步寫入（seq 已指定，寫入順序不影響存檔水位判定）
+		entries = batch
+	}
+	return withWAL(ctx, r.db, entries, func(querier) error { return nil })
+}
+
+// assignWALSeq gives entries without a sequence number the next ones.
+func assignWALSeq(db *DB, entries []WALEntry) {
+	for i := range entries {
+		if entries[i].Seq == 0 {
+			entries[i].Seq = db.walSeq.Add(1)
+		}
+	}
+}
+
+// withWAL runs fn. With WAL entries, fn runs in the same transaction that
+// inserts them, so the non-character side of the transfer (warehouse row,
+// auction bid, mail) and its WAL record commit or roll back together.
+func withWAL(ctx context.Context, db *DB, entries []WALEntry, fn func(q querier) error) error {
+	if len(entries) == 0 {
+		return fn(db.Pool)
+	}
+
+	tx, err := db.Pool.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("wal begin: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
+	if err := insertWAL(ctx, tx, db, entries); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// insertWAL inserts entries. A character side already covered by that
+// character's saved high-water mark (an async entry written after the save
+// that contains it) is inserted applied.
+func insertWAL(ctx context.Context, tx pgx.Tx, db *DB, entries []WALEntry) error {
 	for _, e := range entries {
+		if e.Seq == 0 {
+			e.Seq = db.walSeq.Add(1)
+		}
 		if _, err := tx.Exec(ctx,
-			`INSERT INTO economic_wal (tx_type, from_char, to_char, item_id, count, enchant_lvl, gold_amount)
-			 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			`INSERT INTO economic_wal (tx_type, from_char, to_char, item_id, count, enchant_lvl, gold_amount,
+			                           obj_id, bless, identified, stackable, target, seq, from_applied, to_applied)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13,
+			         EXISTS (SELECT 1 FROM wal_char_saved WHERE char_id = $2 AND seq >= $13),
+			         EXISTS (SELECT 1 FROM wal_char_saved WHERE char_id = $3 AND seq >= $13))`,
 			e.TxType, e.FromChar, e.ToChar, e.ItemID, e.Count, e.EnchantLvl, e.GoldAmount,
+			e.ObjID, e.Bless, e.Identified, e.Stackable, e.Target, e.Seq,
 		); err != nil {
 			return fmt.Errorf("wal insert: %w", err)
 		}
 	}
+	return nil
+}
 
-	return tx.Commit(ctx)
+// markWALApplied records that charID's saved inventory contains every WAL
+// entry up to seq. Called inside ItemRepo.SaveInventory's transaction, so the
+// record commits atomically with the inventory: it raises the character's
+// high-water mark in wal_char_saved and sets the apply markers of the entries
+// already written. Entries up to seq still queued in the async writer are
+// covered by the mark — insertWAL writes them applied, and RecoverWAL and
+// MarkProcessed consult the mark too, so replay never applies them twice.
+func markWALApplied(ctx context.Context, tx pgx.Tx, charID int32, seq int64) error {
+	if seq == 0 {
+		return nil
+	}
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO wal_char_saved (char_id, seq) VALUES ($1, $2)
+		 ON CONFLICT (char_id) DO UPDATE SET seq = GREATEST(wal_char_saved.seq, EXCLUDED.seq)`,
+		charID, seq); err != nil {
+		return fmt.Errorf("wal saved mark: %w", err)
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE economic_wal SET from_applied = TRUE
+		 WHERE from_char = $1 AND seq <= $2 AND NOT from_applied`, charID, seq); err != nil {
+		return fmt.Errorf("wal mark from_applied: %w", err)
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE economic_wal SET to_applied = TRUE
+		 WHERE to_char = $1 AND seq <= $2 AND NOT to_applied`, charID, seq); err != nil {
+		return fmt.Errorf("wal mark to_applied: %w", err)
+	}
+	return nil
 }
 
-// MarkProcessed marks all WAL entries as processed (called during batch flush).
-func (r *WALRepo) MarkProcessed(ctx context.Context) error {
-	_, err := r.db.Pool.Exec(ctx,
-		`UPDATE economic_wal SET processed = TRUE WHERE processed = FALSE`,
+// walSideApplied is the SQL condition for an entry side (from / to) being
+// applied: its marker is set or the character's saved high-water mark covers it.
+const walSideApplied = `(%[1]s_applied OR EXISTS (SELECT 1 FROM wal_char_saved s
+	WHERE s.char_id = economic_wal.%[1]s_char AND s.seq >= economic_wal.seq))`
+
+// MarkProcessed marks entries processed once every character side has been
+// applied. Apply markers and high-water marks are set per character by
+// SaveInventory, so an entry is only processed when the specific characters it
+// touches were saved. Returns the number of entries processed.
+func (r *WALRepo) MarkProcessed(ctx context.Context) (int64, error) {
+	tag, err := r.db.Pool.Exec(ctx, fmt.Sprintf(
+		`UPDATE economic_wal SET processed = TRUE
+		 WHERE NOT processed
+		   AND (from_char = 0 OR %s)
+		   AND (to_char = 0 OR %s)`,
+		fmt.Sprintf(walSideApplied, "from"), fmt.Sprintf(walSideApplied, "to")),
 	)
-	return err
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
 }
 
 // RecoverWAL reads all unprocessed WAL entries and replays them.
 // Called once at server startup before the game loop begins.
-// Each entry is replayed idempotently:
-//   - gold_amount > 0: deduct from from_char, add to to_char
-//   - item_id > 0: transfer item ownership from from_char to to_char
-// After replay, entries are marked processed.
+//
+// A character side whose apply marker is unset and that is above the
+// character's saved high-water mark was never saved, so it is
+// re-applied to character_items (gold is the adena row) and marked applied in
+// the same transaction — each side is applied exactly once, even if recovery
+// itself is interrupted. The non-character side needs no replay:
+// warehouse_items, auction_board and mail rows were written in the same
+// transaction as the entry, and NPC shops / fees are sources and sinks.
+// After replay, entries are marked processed and the sequence counter resumes
+// after the highest seq.
 func (r *WALRepo) RecoverWAL(ctx context.Context) (int, error) {
-	rows, err := r.db.Pool.Query(ctx,
-		`SELECT id, tx_type, from_char, to_char, item_id, count, enchant_lvl, gold_amount
-		 FROM economic_wal WHERE processed = FALSE ORDER BY id`)
+	rows, err := r.db.Pool.Query(ctx, fmt.Sprintf(
+		`SELECT id, tx_type, from_char, to_char, item_id, count, enchant_lvl, gold_amount,
+		        obj_id, bless, identified, stackable, target, seq, %s, %s
+		 FROM economic_wal WHERE processed = FALSE ORDER BY seq, id`,
+		fmt.Sprintf(walSideApplied, "from"), fmt.Sprintf(walSideApplied, "to")))
 	if err != nil {
 		return 0, fmt.Errorf("wal recover query: %w", err)
 	}
 	defer rows.Close()
 
-	var entries []struct {
-		id    int64
-		entry WALEntry
+	type walRow struct {
+		id          int64
+		entry       WALEntry
+		fromApplied bool
+		toApplied   bool
 	}
+	var entries []walRow
 	for rows.Next() {
-		var e struct {
-			id    int64
-			entry WALEntry
-		}
-		if err := rows.Scan(&e.id, &e.entry.TxType, &e.entry.FromChar, &e.entry.ToChar,
-			&e.entry.ItemID, &e.entry.Count, &e.entry.EnchantLvl, &e.entry.GoldAmount); err != nil {
+		var e walRow
+		w := &e.entry
+		if err := rows.Scan(&e.id, &w.TxType, &w.FromChar, &w.ToChar,
+			&w.ItemID, &w.Count, &w.EnchantLvl, &w.GoldAmount,
+			&w.ObjID, &w.Bless, &w.Identified, &w.Stackable, &w.Target, &w.Seq,
+			&e.fromApplied, &e.toApplied); err != nil {
 			return 0, fmt.Errorf("wal recover scan: %w", err)
 		}
 		entries = append(entries, e)
@@ -88,6 +266,9 @@ func (r *WALRepo) RecoverWAL(ctx context.Context) (int, error) {
 		return 0, fmt.Errorf("wal recover rows: %w", err)
 	}
 
+	if err := r.loadSeq(ctx); err != nil {
+		return 0, err
+	}
 	if len(entries) == 0 {
 		return 0, nil
 	}
@@ -101,34 +282,23 @@ func (r *WALRepo) RecoverWAL(ctx context.Context) (int, error) {
 
 	for _, e := range entries {
 		wal := e.entry
-
-		// Replay gold transfer
-		if wal.GoldAmount > 0 && wal.FromChar > 0 && wal.ToChar > 0 {
-			// Idempotent: use the WAL entry ID as dedup key by only processing unprocessed
-			if _, err := tx.Exec(ctx,
-				`UPDATE characters SET adena = adena - $1 WHERE id = $2`,
-				wal.GoldAmount, wal.FromChar); err != nil {
-				return 0, fmt.Errorf("wal recover gold deduct (id=%d): %w", e.id, err)
-			}
-			if _, err := tx.Exec(ctx,
-				`UPDATE characters SET adena = adena + $1 WHERE id = $2`,
-				wal.GoldAmount, wal.ToChar); err != nil {
-				return 0, fmt.Errorf("wal recover gold add (id=%d): %w", e.id, err)
-			}
+		// 已套用的一端視為不存在，只重播尚未存檔的角色端
+		if e.fromApplied {
+			wal.FromChar = 0
 		}
-
-		// Replay item transfer
-		if wal.ItemID > 0 && wal.FromChar > 0 && wal.ToChar > 0 {
-			if _, err := tx.Exec(ctx,
-				`UPDATE character_items SET char_id = $1 WHERE id = $2 AND char_id = $3`,
-				wal.ToChar, wal.ItemID, wal.FromChar); err != nil {
-				return 0, fmt.Errorf("wal recover item transfer (id=%d): %w", e.id, err)
+		if e.toApplied {
+			wal.ToChar = 0
+		}
+		if wal.FromChar != 0 || wal.ToChar != 0 {
+			if err := replayWAL(ctx, tx, wal, e.entry); err != nil {
+				return 0, fmt.Errorf("wal recover %s (id=%d): %w", e.entry.TxType, e.id, err)
 			}
 		}
 
-		// Mark this entry as processed
+		// Mark this entry as applied and processed
 		if _, err := tx.Exec(ctx,
-			`UPDATE economic_wal SET processed = TRUE WHERE id = $1`, e.id); err != nil {
+			`UPDATE economic_wal SET from_applied = TRUE, to_applied = TRUE, processed = TRUE
+			 WHERE id = $1`, e.id); err != nil {
 			return 0, fmt.Errorf("wal recover mark (id=%d): %w", e.id, err)
 		}
 	}
@@ -139,3 +309,169 @@ func (r *WALRepo) RecoverWAL(ctx context.Context) (int, error) {
 
 	return len(entries), nil
 }
+
+// loadSeq resumes the WAL sequence after the highest one written or covered
+// by a saved high-water mark. A mark can exceed every written seq (entries
+// still queued in the async writer at a crash, failed sync writes); reusing
+// numbers at or below it would insert new entries already applied.
+func (r *WALRepo) loadSeq(ctx context.Context) error {
+	var seq int64
+	if err := r.db.Pool.QueryRow(ctx,
+		`SELECT GREATEST(
+		     (SELECT COALESCE(MAX(seq), 0) FROM economic_wal),
+		     (SELECT COALESCE(MAX(seq), 0) FROM wal_char_saved))`).Scan(&seq); err != nil {
+		return fmt.Errorf("wal load seq: %w", err)
+	}
+	r.db.walSeq.Store(seq)
+	return nil
+}
+
+// replayWAL applies the unapplied character sides of one entry (e, with
+// applied sides zeroed) according to its tx_type, validated on orig.
+func replayWAL(ctx context.Context, tx pgx.Tx, e, orig WALEntry) error {
+	switch e.TxType {
+	case WALTrade, WALPrivateShop:
+		// 角色 ↔ 角色：雙方皆只存在記憶體
+		if orig.FromChar == 0 || orig.ToChar == 0 {
+			return fmt.Errorf("character-to-character entry without both characters")
+		}
+	case WALShopBuy, WALShopSell, WALWarehouseDeposit, WALWarehouseWithdraw,
+		WALClanWarehouseDeposit, WALClanWarehouseWithdraw, WALAuction, WALMail, WALScript:
+		// 只有一端是角色：另一端為 NPC、倉庫、拍賣佈告欄或系統（費用、腳本），不需重播
+		if orig.FromChar == 0 && orig.ToChar == 0 {
+			return fmt.Errorf("entry has no character side")
+		}
+	default:
+		return fmt.Errorf("unknown tx_type %q", e.TxType)
+	}
+
+	if e.GoldAmount > 0 {
+		if e.FromChar > 0 {
+			if err := replayRemove(ctx, tx, e.FromChar, WALEntry{ItemID: world.AdenaItemID, Count: int32(e.GoldAmount), Stackable: true}); err != nil {
+				return fmt.Errorf("gold deduct: %w", err)
+			}
+		}
+		if e.ToChar > 0 {
+			if err := replayAdd(ctx, tx, e.ToChar, WALEntry{ItemID: world.AdenaItemID, Count: int32(e.GoldAmount), Bless: 1, Identified: true, Stackable: true}); err != nil {
+				return fmt.Errorf("gold add: %w", err)
+			}
+		}
+		return nil
+	}
+
+	if e.ItemID <= 0 || e.Count <= 0 {
+		return nil
+	}
+
+	// 不可堆疊物品在兩個角色之間移動：直接轉移整列，保留耐久、屬性等欄位
+	if e.FromChar > 0 && e.ToChar > 0 && !e.Stackable && e.ObjID > 0 {
+		tag, err := tx.Exec(ctx,
+			`UPDATE character_items SET char_id = $1, equipped = FALSE, equip_slot = 0
+			 WHERE char_id = $2 AND obj_id = $3 AND item_id = $4`,
+			e.ToChar, e.FromChar, e.ObjID, e.ItemID)
+		if err != nil {
+			return fmt.Errorf("item move: %w", err)
+		}
+		if tag.RowsAffected() > 0 {
+			return nil
+		}
+		// 來源在上次存檔時尚未持有此物品：只補目標端
+		return replayAdd(ctx, tx, e.ToChar, e)
+	}
+
+	if e.FromChar > 0 {
+		if err := replayRemove(ctx, tx, e.FromChar, e); err != nil {
+			return fmt.Errorf("item remove: %w", err)
+		}
+	}
+	if e.ToChar > 0 {
+		if err := replayAdd(ctx, tx, e.ToChar, e); err != nil {
+			return fmt.Errorf("item add: %w", err)
+		}
+	}
+	return nil
+}
+
+// replayRemove takes e.Count of the item away from a character's saved
+// inventory. With ObjID the exact row is used; if that row was never saved
+// there is nothing to take. Gold and legacy entries (ObjID 0) match by item.
+func replayRemove(ctx context.Context, tx pgx.Tx, charID int32, e WALEntry) error {
+	var rowID, count int32
+	var err error
+	switch {
+	case e.ObjID > 0:
+		err = tx.QueryRow(ctx,
+			`SELECT id, count FROM character_items WHERE char_id = $1 AND obj_id = $2 AND item_id = $3`,
+			charID, e.ObjID, e.ItemID).Scan(&rowID, &count)
+	case e.Stackable:
+		err = tx.QueryRow(ctx,
+			`SELECT id, count FROM character_items WHERE char_id = $1 AND item_id = $2
+			 ORDER BY count DESC LIMIT 1`,
+			charID, e.ItemID).Scan(&rowID, &count)
+	default:
+		err = tx.QueryRow(ctx,
+			`SELECT id, count FROM character_items WHERE char_id = $1 AND item_id = $2 AND enchant_lvl = $3
+			 ORDER BY equipped, id LIMIT 1`,
+			charID, e.ItemID, e.EnchantLvl).Scan(&rowID, &count)
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if count > e.Count {
+		_, err = tx.Exec(ctx, `UPDATE character_items SET count = count - $1 WHERE id = $2`, e.Count, rowID)
+	} else {
+		_, err = tx.Exec(ctx, `DELETE FROM character_items WHERE id = $1`, rowID)
+	}
+	return err
+}
+
+// replayAdd gives e.Count of the item to a character. Stackables merge into an
+// existing stack; non-stackables get one row each. obj_id 0 lets enterworld
+// assign a fresh ObjectID on load.
+func replayAdd(ctx context.Context, tx pgx.Tx, charID int32, e WALEntry) error {
+	if e.Stackable {
+		tag, err := tx.Exec(ctx,
+			`UPDATE character_items SET count = count + $1
+			 WHERE id = (SELECT id FROM character_items WHERE char_id = $2 AND item_id = $3 ORDER BY id LIMIT 1)`,
+			e.Count, charID, e.ItemID)
+		if err != nil || tag.RowsAffected() > 0 {
+			return err
+		}
+		return insertReplayItem(ctx, tx, charID, e, e.Count)
+	}
+	for i := int32(0); i < e.Count; i++ {
+		if err := insertReplayItem(ctx, tx, charID, e, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertReplayItem(ctx context.Context, tx pgx.Tx, charID int32, e WALEntry, count int32) error {
+	_, err := tx.Exec(ctx,
+		`INSERT INTO character_items (char_id, item_id, count, enchant_lvl, bless, identified, obj_id)
+		 VALUES ($1, $2, $3, $4, $5, $6, 0)`,
+		charID, e.ItemID, count, e.EnchantLvl, e.Bless, e.Identified)
+	return err
+}
+
+// addCharacterGold adds adena to a character's saved inventory (offline
+// characters; gold is the adena row in character_items).
+func addCharacterGold(ctx context.Context, q querier, charID int32, amount int64) error {
+	tag, err := q.Exec(ctx,
+		`UPDATE character_items SET count = count + $1
+		 WHERE id = (SELECT id FROM character_items WHERE char_id = $2 AND item_id = $3 ORDER BY id LIMIT 1)`,
+		amount, charID, world.AdenaItemID)
+	if err != nil || tag.RowsAffected() > 0 {
+		return err
+	}
+	_, err = q.Exec(ctx,
+		`INSERT INTO character_items (char_id, item_id, count, enchant_lvl, bless, identified, obj_id)
+		 VALUES ($1, $2, $3, 0, 1, TRUE, 0)`,
+		charID, world.AdenaItemID, amount)
+	return err
+}
@@ -0,0 +1,150 @@
+package persist
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	walWriterQueue    = 4096 // 等待寫入的批次上限；滿時 WriteWAL 退回同步寫入
+	walWriterMaxBatch = 512  // 單一交易最多寫入的條目數
+	walWriterRetries  = 3    // 連續失敗此次數後改為同步寫入並發出警報（批次保留繼續重試）
+	walWriterMaxDelay = 5 * time.Second
+)
+
+// walWriter batches WAL inserts on a background goroutine
+// (persistence.wal_sync_mode = "async"). Transfers no longer wait for the
+// database; in exchange a crash can lose the entries still queued, together
+// with the unsaved in-memory changes they describe.
+//
+// A batch that keeps failing is never dropped: the writer retries it with
+// backoff, and after walWriterRetries failures switches WriteWAL back to
+// synchronous inserts (so new transfers fail visibly instead of silently
+// losing crash protection) until the backlog is written. Only Close gives up
+// on a failing batch, after the shutdown save has covered its entries.
+type walWriter struct {
+	reqs    chan []WALEntry
+	stop    chan struct{} // closed by Close: stop retrying a failing batch
+	done    chan struct{}
+	log     *zap.Logger
+	insert  func(ctx context.Context, batch []WALEntry) error
+	delay   time.Duration // first retry delay, doubled per attempt up to walWriterMaxDelay
+	failing atomic.Bool   // set while a batch keeps failing; WriteWAL writes synchronously
+}
+
+func newWALWriter(insert func(ctx context.Context, batch []WALEntry) error, log *zap.Logger) *walWriter {
+	return &walWriter{
+		reqs:   make(chan []WALEntry, walWriterQueue),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		log:    log,
+		insert: insert,
+		delay:  100 * time.Millisecond,
+	}
+}
+
+// StartAsync switches WriteWAL to the background writer. Call after
+// RecoverWAL and before the game loop starts.
+func (r *WALRepo) StartAsync(log *zap.Logger) {
+	if r.db.walWriter != nil {
+		return
+	}
+	w := newWALWriter(func(ctx context.Context, batch []WALEntry) error {
+		return withWAL(ctx, r.db, batch, func(querier) error { return nil })
+	}, log)
+	r.db.walWriter = w
+	go w.run()
+}
+
+// Close writes the queued entries and stops the background writer. Safe to
+// call in sync mode.
+func (r *WALRepo) Close() {
+	w := r.db.walWriter
+	if w == nil {
+		return
+	}
+	r.db.walWriter = nil
+	w.close()
+}
+
+// enqueue hands entries to the writer. Returns false if the caller must write
+// them synchronously: the queue is full or the writer is failing.
+func (w *walWriter) enqueue(entries []WALEntry) bool {
+	if w.failing.Load() {
+		return false
+	}
+	select {
+	case w.reqs <- entries:
+		return true
+	default:
+		w.log.Warn("WAL 非同步佇列已滿，改為同步寫入", zap.Int("條目", len(entries)))
+		return false
+	}
+}
+
+func (w *walWriter) close() {
+	close(w.stop)
+	close(w.reqs)
+	<-w.done
+}
+
+func (w *walWriter) run() {
+	defer close(w.done)
+	for entries := range w.reqs {
+		batch := entries
+	collect:
+		for len(batch) < walWriterMaxBatch {
+			select {
+			case more, ok := <-w.reqs:
+				if !ok {
+					break collect
+				}
+				batch = append(batch, more...)
+			default:
+				break collect
+			}
+		}
+		w.write(batch)
+	}
+}
+
+// write inserts batch, retrying until it succeeds or Close gives up on it.
+func (w *walWriter) write(batch []WALEntry) {
+	delay := w.delay
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := w.insert(ctx, batch)
+		cancel()
+		if err == nil {
+			if w.failing.Swap(false) {
+				w.log.Warn("WAL 非同步寫入已恢復", zap.Int("條目", len(batch)), zap.Int("嘗試", attempt))
+			}
+			return
+		}
+		fields := []zap.Field{
+			zap.Int("條目", len(batch)),
+			zap.Int64("seq_from", batch[0].Seq),
+			zap.Int64("seq_to", batch[len(batch)-1].Seq),
+			zap.Int("嘗試", attempt),
+			zap.Error(err),
+		}
+		if attempt == walWriterRetries && !w.failing.Swap(true) {
+			// 警報：之後的轉移同步寫入 WAL（失敗即取消），積壓的批次保留繼續重試
+			w.log.Error("WAL 非同步寫入持續失敗，改為同步寫入並保留批次重試", fields...)
+		}
+		select {
+		case <-w.stop:
+			if attempt >= walWriterRetries {
+				// 關機：最終存檔已涵蓋這些條目（存檔水位），放棄不影響崩潰恢復
+				w.log.Error("關機時 WAL 非同步寫入仍失敗，放棄此批次", fields...)
+				return
+			}
+		default:
+		}
+		time.Sleep(delay)
+		delay = min(delay*2, walWriterMaxDelay)
+	}
+}
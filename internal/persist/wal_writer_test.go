@@ -0,0 +1,143 @@
+package persist
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// fakeWALDB 記錄寫入的條目；down 為 true 時寫入失敗。
+type fakeWALDB struct {
+	mu       sync.Mutex
+	written  []int64 // seq，依寫入順序
+	attempts int
+	down     atomic.Bool
+}
+
+func (f *fakeWALDB) insert(_ context.Context, batch []WALEntry) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.attempts++
+	if f.down.Load() {
+		return errors.New("db down")
+	}
+	for _, e := range batch {
+		f.written = append(f.written, e.Seq)
+	}
+	return nil
+}
+
+func (f *fakeWALDB) seqs() []int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]int64(nil), f.written...)
+}
+
+func startTestWriter(db *fakeWALDB) *walWriter {
+	w := newWALWriter(db.insert, zap.NewNop())
+	w.delay = time.Millisecond
+	go w.run()
+	return w
+}
+
+func walBatch(seqs ...int64) []WALEntry {
+	batch := make([]WALEntry, len(seqs))
+	for i, seq := range seqs {
+		batch[i] = WALEntry{TxType: WALTrade, FromChar: 1, ToChar: 2, ItemID: 40001, Count: 1, Seq: seq}
+	}
+	return batch
+}
+
+// waitFor 輪詢 cond 直到成立或逾時。
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWALWriterWritesInOrder(t *testing.T) {
+	db := &fakeWALDB{}
+	w := startTestWriter(db)
+	var want []int64
+	for seq := int64(1); seq <= 100; seq++ {
+		if !w.enqueue(walBatch(seq)) {
+			t.Fatalf("enqueue %d refused", seq)
+		}
+		want = append(want, seq)
+	}
+	w.close()
+
+	got := db.seqs()
+	if len(got) != len(want) {
+		t.Fatalf("wrote %d entries, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("entry %d has seq %d, want %d", i, got[i], want[i])
+		}
+	}
+}
+
+// 寫入持續失敗時批次不可丟棄：轉為同步模式，資料庫恢復後積壓的批次照常寫入。
+func TestWALWriterKeepsFailingBatch(t *testing.T) {
+	db := &fakeWALDB{}
+	db.down.Store(true)
+	w := startTestWriter(db)
+
+	if !w.enqueue(walBatch(1, 2)) {
+		t.Fatal("enqueue refused before any failure")
+	}
+	waitFor(t, "writer to report failing", w.failing.Load)
+	if w.enqueue(walBatch(3)) {
+		t.Fatal("enqueue accepted while failing; caller must write synchronously")
+	}
+	if n := len(db.seqs()); n != 0 {
+		t.Fatalf("%d entries written while the database is down", n)
+	}
+
+	db.down.Store(false)
+	waitFor(t, "writer to recover", func() bool { return !w.failing.Load() })
+	if !w.enqueue(walBatch(4)) {
+		t.Fatal("enqueue refused after recovery")
+	}
+	w.close()
+
+	got := db.seqs()
+	if len(got) != 3 || got[0] != 1 || got[1] != 2 || got[2] != 4 {
+		t.Fatalf("wrote seqs %v, want [1 2 4]", got)
+	}
+}
+
+// 關機時仍失敗的批次在重試上限後放棄，Close 不會無限等待。
+func TestWALWriterCloseGivesUp(t *testing.T) {
+	db := &fakeWALDB{}
+	db.down.Store(true)
+	w := startTestWriter(db)
+	w.enqueue(walBatch(1))
+
+	closed := make(chan struct{})
+	go func() {
+		w.close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("close blocked on a failing batch")
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.attempts < walWriterRetries {
+		t.Fatalf("%d attempts before giving up, want at least %d", db.attempts, walWriterRetries)
+	}
+}
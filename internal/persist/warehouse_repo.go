@@ -15,6 +15,7 @@ type WarehouseItem struct {
 	EnchantLvl  int16
 	Bless       int16
 	Identified  bool
+	ObjID       int32 // 非堆疊物品的 ObjectID（領出時沿用）；0 = 堆疊或舊資料
 }
 
 type WarehouseRepo struct {
@@ -28,7 +29,7 @@ func NewWarehouseRepo(db *DB) *WarehouseRepo {
 // Load returns all warehouse items for an account + warehouse type.
 func (r *WarehouseRepo) Load(ctx context.Context, accountName string, whType int16) ([]WarehouseItem, error) {
 	rows, err := r.db.Pool.Query(ctx,
-		`SELECT id, account_name, char_name, wh_type, item_id, count, enchant_lvl, bless, identified
+		`SELECT id, account_name, char_name, wh_type, item_id, count, enchant_lvl, bless, identified, obj_id
 		 FROM warehouse_items WHERE account_name = $1 AND wh_type = $2`, accountName, whType,
 	)
 	if err != nil {
@@ -41,7 +42,7 @@ func (r *WarehouseRepo) Load(ctx context.Context, accountName string, whType int
 		var it WarehouseItem
 		if err := rows.Scan(
 			&it.ID, &it.AccountName, &it.CharName, &it.WhType,
-			&it.ItemID, &it.Count, &it.EnchantLvl, &it.Bless, &it.Identified,
+			&it.ItemID, &it.Count, &it.EnchantLvl, &it.Bless, &it.Identified, &it.ObjID,
 		); err != nil {
 			return nil, err
 		}
@@ -50,51 +51,62 @@ func (r *WarehouseRepo) Load(ctx context.Context, accountName string, whType int
 	return result, rows.Err()
 }
 
-// Deposit inserts a new item into the warehouse.
-func (r *WarehouseRepo) Deposit(ctx context.Context, item WarehouseItem) (int32, error) {
+// Deposit inserts a new item into the warehouse. WAL entries, if given, are
+// written in the same transaction.
+func (r *WarehouseRepo) Deposit(ctx context.Context, item WarehouseItem, wal ...WALEntry) (int32, error) {
 	var id int32
-	err := r.db.Pool.QueryRow(ctx,
-		`INSERT INTO warehouse_items (account_name, char_name, wh_type, item_id, count, enchant_lvl, bless, identified)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
-		item.AccountName, item.CharName, item.WhType, item.ItemID, item.Count,
-		item.EnchantLvl, item.Bless, item.Identified,
-	).Scan(&id)
+	err := withWAL(ctx, r.db, wal, func(q querier) error {
+		return q.QueryRow(ctx,
+			`INSERT INTO warehouse_items (account_name, char_name, wh_type, item_id, count, enchant_lvl, bless, identified, obj_id)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`,
+			item.AccountName, item.CharName, item.WhType, item.ItemID, item.Count,
+			item.EnchantLvl, item.Bless, item.Identified, item.ObjID,
+		).Scan(&id)
+	})
 	return id, err
 }
 
-// AddToStack increases the count of a stackable warehouse item.
-func (r *WarehouseRepo) AddToStack(ctx context.Context, whItemID int32, addCount int32) error {
-	_, err := r.db.Pool.Exec(ctx,
-		`UPDATE warehouse_items SET count = count + $1 WHERE id = $2`,
-		addCount, whItemID,
-	)
-	return err
+// AddToStack increases the count of a stackable warehouse item. WAL entries,
+// if given, are written in the same transaction.
+func (r *WarehouseRepo) AddToStack(ctx context.Context, whItemID int32, addCount int32, wal ...WALEntry) error {
+	return withWAL(ctx, r.db, wal, func(q querier) error {
+		_, err := q.Exec(ctx,
+			`UPDATE warehouse_items SET count = count + $1 WHERE id = $2`,
+			addCount, whItemID,
+		)
+		return err
+	})
 }
 
 // Withdraw removes a warehouse item or decrements count for stackable.
-// Returns true if fully removed.
-func (r *WarehouseRepo) Withdraw(ctx context.Context, whItemID int32, count int32) (bool, error) {
-	var remaining int32
-	err := r.db.Pool.QueryRow(ctx,
-		`UPDATE warehouse_items SET count = count - $1 WHERE id = $2 RETURNING count`,
-		count, whItemID,
-	).Scan(&remaining)
-	if err != nil {
-		return false, err
-	}
+// Returns true if fully removed. WAL entries, if given, are written in the
+// same transaction.
+func (r *WarehouseRepo) Withdraw(ctx context.Context, whItemID int32, count int32, wal ...WALEntry) (bool, error) {
+	var removed bool
+	err := withWAL(ctx, r.db, wal, func(q querier) error {
+		var remaining int32
+		if err := q.QueryRow(ctx,
+			`UPDATE warehouse_items SET count = count - $1 WHERE id = $2 RETURNING count`,
+			count, whItemID,
+		).Scan(&remaining); err != nil {
+			return err
+		}
 
-	if remaining <= 0 {
-		_, err = r.db.Pool.Exec(ctx, `DELETE FROM warehouse_items WHERE id = $1`, whItemID)
-		return true, err
-	}
-	return false, nil
+		if remaining <= 0 {
+			removed = true
+			_, err := q.Exec(ctx, `DELETE FROM warehouse_items WHERE id = $1`, whItemID)
+			return err
+		}
+		return nil
+	})
+	return removed, err
 }
 
 // LoadByCharName 載入角色專屬倉庫（wh_type=6），以 char_name 為主鍵。
 // Java 角色倉庫以 character ID 為鍵，每個角色獨立。
 func (r *WarehouseRepo) LoadByCharName(ctx context.Context, charName string, whType int16) ([]WarehouseItem, error) {
 	rows, err := r.db.Pool.Query(ctx,
-		`SELECT id, account_name, char_name, wh_type, item_id, count, enchant_lvl, bless, identified
+		`SELECT id, account_name, char_name, wh_type, item_id, count, enchant_lvl, bless, identified, obj_id
 		 FROM warehouse_items WHERE char_name = $1 AND wh_type = $2`, charName, whType,
 	)
 	if err != nil {
@@ -107,7 +119,7 @@ func (r *WarehouseRepo) LoadByCharName(ctx context.Context, charName string, whT
 		var it WarehouseItem
 		if err := rows.Scan(
 			&it.ID, &it.AccountName, &it.CharName, &it.WhType,
-			&it.ItemID, &it.Count, &it.EnchantLvl, &it.Bless, &it.Identified,
+			&it.ItemID, &it.Count, &it.EnchantLvl, &it.Bless, &it.Identified, &it.ObjID,
 		); err != nil {
 			return nil, err
 		}
@@ -0,0 +1,80 @@
+package scripting
+
+import (
+	lua "github.com/yuin/gopher-lua"
+	"go.uber.org/zap"
+)
+
+// aiProfilesGlobal is the Lua table AI profile scripts register into:
+//
+//	AI_PROFILES.dragon_boss = function(ctx, state) ... end
+//
+// Profiles are selected per NPC template via `ai:` in npc_list.yaml.
+// Templates without `ai:` (or with "default") use the global npc_ai.
+const aiProfilesGlobal = "AI_PROFILES"
+
+// defaultAIProfile names the built-in profile backed by the global npc_ai.
+const defaultAIProfile = "default"
+
+// npcAIState is the persistent Lua table one NPC carries across ticks for
+// its current profile. Scripts use it for phases, timers, last skill used, etc.
+type npcAIState struct {
+	profile string
+	tbl     *lua.LTable
+}
+
+// resolveAIProfile returns the Lua function and stats name for a profile,
+// falling back to npc_ai for empty, "default" or unknown profiles. Unknown
+// profiles are logged once per VM.
+func (e *Engine) resolveAIProfile(profile string) (lua.LValue, string, string) {
+	if profile == "" || profile == defaultAIProfile {
+		return e.vm.GetGlobal("npc_ai"), "npc_ai", defaultAIProfile
+	}
+	if profiles, ok := e.vm.GetGlobal(aiProfilesGlobal).(*lua.LTable); ok {
+		if fn, ok := profiles.RawGetString(profile).(*lua.LFunction); ok {
+			return fn, "npc_ai:" + profile, profile
+		}
+	}
+	if !e.aiWarned[profile] {
+		e.aiWarned[profile] = true
+		e.log.Warn("unknown lua ai profile, using default", zap.String("profile", profile))
+	}
+	return e.vm.GetGlobal("npc_ai"), "npc_ai", defaultAIProfile
+}
+
+// npcAIStateTable returns the persistent state table for an NPC, creating a
+// fresh one on first use or when the NPC switched profile.
+func (e *Engine) npcAIStateTable(objID int, profile string) *lua.LTable {
+	st, ok := e.aiStates[objID]
+	if !ok || st.profile != profile {
+		st = npcAIState{profile: profile, tbl: e.vm.NewTable()}
+		e.aiStates[objID] = st
+	}
+	return st.tbl
+}
+
+// ResetNpcAIState drops an NPC's AI state (e.g. on respawn), so the next
+// tick starts from an empty table.
+func (e *Engine) ResetNpcAIState(objID int) {
+	delete(e.aiStates, objID)
+}
+
+// PruneNpcAIState drops the state of every NPC for which alive returns false.
+// Called periodically by NpcAISystem to reclaim state of removed NPCs.
+func (e *Engine) PruneNpcAIState(alive func(objID int) bool) int {
+	n := 0
+	for id := range e.aiStates {
+		if !alive(id) {
+			delete(e.aiStates, id)
+			n++
+		}
+	}
+	return n
+}
+
+// resetAIProfiles clears per-VM AI bookkeeping after the VM is replaced.
+// State tables belong to the old VM and are not carried over.
+func (e *Engine) resetAIProfiles() {
+	clear(e.aiStates)
+	clear(e.aiWarned)
+}
@@ -0,0 +1,206 @@
+package scripting
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+	"go.uber.org/zap"
+)
+
+// errThrottled is returned by pcall when the per-tick Lua budget is exhausted.
+var errThrottled = errors.New("lua tick budget exhausted")
+
+// luaValueSize approximates the Go memory held by one registry slot
+// (an LValue interface header). Used to convert memory_limit_mb into a
+// RegistryMaxSize cap.
+const luaValueSize = 16
+
+// CallStats holds cumulative counters for one Lua global function.
+type CallStats struct {
+	Name       string
+	Calls      int64
+	Errors     int64 // runtime errors (excluding timeouts)
+	Timeouts   int64 // calls cancelled by the per-call deadline
+	OverMemory int64 // calls cancelled for allocating more than memory_limit_mb
+	Throttled  int64 // calls skipped because the tick budget was exhausted
+	Total      time.Duration
+	Max        time.Duration
+}
+
+// budget tracks per-call timeout, per-tick cumulative Lua time and
+// per-function statistics. Owned by Engine; game-loop goroutine only.
+type budget struct {
+	timeout    time.Duration // per-call deadline (0 = unbounded)
+	tickBudget time.Duration // cumulative Lua time allowed per tick (0 = unbounded)
+
+	tickUsed      time.Duration
+	tickThrottled int
+	tickCost      map[string]time.Duration
+
+	stats map[string]*CallStats
+}
+
+func newBudget(timeout time.Duration, tickRate time.Duration, budgetPct float64) *budget {
+	b := &budget{
+		timeout:  timeout,
+		tickCost: make(map[string]time.Duration),
+		stats:    make(map[string]*CallStats),
+	}
+	if budgetPct > 0 && tickRate > 0 {
+		b.tickBudget = time.Duration(float64(tickRate) * budgetPct)
+	}
+	return b
+}
+
+// registryMaxSize converts a memory limit in MB into a registry slot cap,
+// bounding the VM data stack (runaway recursion, huge varargs/unpack).
+// Tables and strings live on the Go heap; memGuard bounds what one call
+// allocates there.
+func registryMaxSize(memoryLimitMB int) int {
+	if memoryLimitMB <= 0 {
+		return 0
+	}
+	n := memoryLimitMB * 1024 * 1024 / luaValueSize
+	if n < lua.RegistrySize {
+		n = lua.RegistrySize
+	}
+	return n
+}
+
+func (b *budget) stat(name string) *CallStats {
+	s := b.stats[name]
+	if s == nil {
+		s = &CallStats{Name: name}
+		b.stats[name] = s
+	}
+	return s
+}
+
+// pcall runs fn in protected mode under the per-call deadline and tick budget.
+// On success the nret results are left on the stack for the caller to pop.
+// Errors, timeouts and throttling are logged and counted here, so callers
+// only need to return their safe default when err != nil.
+func (e *Engine) pcall(name string, fn lua.LValue, nret int, args ...lua.LValue) error {
+	b := e.budget
+	st := b.stat(name)
+
+	if b.tickBudget > 0 && b.tickUsed >= b.tickBudget {
+		st.Throttled++
+		b.tickThrottled++
+		return errThrottled
+	}
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if b.timeout > 0 || e.mem != nil {
+		var abort context.CancelCauseFunc
+		ctx, abort = context.WithCancelCause(context.Background())
+		cancel = func() { abort(nil) }
+		if b.timeout > 0 {
+			var stop context.CancelFunc
+			ctx, stop = context.WithTimeout(ctx, b.timeout)
+			cancel = func() { stop(); abort(nil) }
+		}
+		if e.mem != nil {
+			e.mem.begin(abort)
+		}
+		e.vm.SetContext(ctx)
+	}
+
+	start := time.Now()
+	err := e.vm.CallByParam(lua.P{
+		Fn:      fn,
+		NRet:    nret,
+		Protect: true,
+	}, args...)
+	elapsed := time.Since(start)
+
+	timedOut, overMemory := false, false
+	if cancel != nil {
+		if e.mem != nil {
+			e.mem.end()
+		}
+		timedOut = ctx.Err() == context.DeadlineExceeded
+		overMemory = context.Cause(ctx) == errMemoryLimit
+		e.vm.RemoveContext()
+		cancel()
+	}
+
+	st.Calls++
+	st.Total += elapsed
+	if elapsed > st.Max {
+		st.Max = elapsed
+	}
+	b.tickUsed += elapsed
+	b.tickCost[name] += elapsed
+
+	if err != nil {
+		if overMemory {
+			st.OverMemory++
+			e.log.Warn("lua call exceeded memory limit",
+				zap.String("func", name),
+				zap.Uint64("limit_bytes", e.mem.limit),
+				zap.Int64("aborts", st.OverMemory))
+			return err
+		}
+		if timedOut {
+			st.Timeouts++
+			e.log.Warn("lua call timed out",
+				zap.String("func", name),
+				zap.Duration("timeout", b.timeout),
+				zap.Int64("timeouts", st.Timeouts))
+		} else {
+			st.Errors++
+			e.log.Error("lua call error", zap.String("func", name), zap.Error(err))
+		}
+		return err
+	}
+	return nil
+}
+
+// EndTick closes the current tick window: logs a warning naming the most
+// expensive functions if the tick budget was exceeded, then resets the
+// per-tick counters. Called once per tick by system.LuaBudgetSystem.
+func (e *Engine) EndTick() {
+	b := e.budget
+	if b.tickBudget > 0 && (b.tickUsed > b.tickBudget || b.tickThrottled > 0) {
+		e.log.Warn("lua tick budget exceeded",
+			zap.Duration("used", b.tickUsed),
+			zap.Duration("budget", b.tickBudget),
+			zap.Int("throttled", b.tickThrottled),
+			zap.Strings("top", topCosts(b.tickCost, 3)))
+	}
+	b.tickUsed = 0
+	b.tickThrottled = 0
+	clear(b.tickCost)
+}
+
+// Stats returns a snapshot of per-function counters, most expensive first.
+func (e *Engine) Stats() []CallStats {
+	out := make([]CallStats, 0, len(e.budget.stats))
+	for _, s := range e.budget.stats {
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Total > out[j].Total })
+	return out
+}
+
+// topCosts returns "name=duration" for the n largest entries.
+func topCosts(m map[string]time.Duration, n int) []string {
+	names := make([]string, 0, len(m))
+	for k := range m {
+		names = append(names, k)
+	}
+	sort.Slice(names, func(i, j int) bool { return m[names[i]] > m[names[j]] })
+	if len(names) > n {
+		names = names[:n]
+	}
+	out := make([]string, len(names))
+	for i, k := range names {
+		out[i] = k + "=" + m[k].String()
+	}
+	return out
+}
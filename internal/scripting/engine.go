@@ -1,59 +1,155 @@
 package scripting
 
 import (
+	"context"
 	"fmt"
+	"math/rand"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/l1jgo/server/internal/config"
 	lua "github.com/yuin/gopher-lua"
 	"go.uber.org/zap"
 )
 
 // Engine wraps a single gopher-lua VM for game logic execution.
-// Single-goroutine access only (game loop). Hot-reload planned via atomic swap.
+// Single-goroutine access only (game loop). Hot-reload swaps in a freshly
+// loaded VM between ticks (see RequestReload).
+// Every call runs under the [lua] limits: a per-call deadline, a cumulative
+// per-tick time budget and memory_limit_mb, which caps both the registry
+// (data stack) and the heap memory one call may allocate (memGuard).
 type Engine struct {
-	vm  *lua.LState
-	log *zap.Logger
+	vm     *lua.LState
+	log    *zap.Logger
+	budget *budget
+	rng    *rand.Rand // backs math.random (the server's seeded game RNG)
+
+	scriptsDir    string
+	memoryLimitMB int
+	mem           *memGuard     // per-call allocation cap (nil = unlimited)
+	reloads       []func(error) // pending reload requests, applied by ApplyPendingReload
+
+	api GameAPI // host behind the Lua `game` module (nil until SetGameAPI)
+
+	aiStates map[int]npcAIState // per-NPC AI state, keyed by NPC object ID
+	aiWarned map[string]bool    // unknown AI profiles already logged
+}
+
+// requiredGlobals lists the Lua functions the Go bridges call. A VM missing
+// any of them is rejected at startup and on reload.
+var requiredGlobals = []string{
+	"calc_melee_attack", "calc_ranged_attack", "calc_skill_damage", "calc_heal_amount",
+	"calc_npc_melee", "calc_npc_ranged", "npc_ai",
+	"calc_enchant", "calc_durability_damage", "get_potion_effect",
+	"get_buff_effect", "is_non_cancellable",
+	"level_from_exp", "exp_for_level", "calc_level_up_hp", "calc_level_up_mp", "calc_death_exp_penalty",
+	"get_char_create_data", "calc_init_hp", "calc_init_mp",
+	"get_hp_regen_interval", "calc_hp_regen_amount", "calc_mp_regen_amount",
+	"get_resurrect_effect", "get_spell_tiers",
+	"get_respawn_location", "get_home_scroll_location",
+	"calc_pk_lawful_penalty", "calc_pk_item_drop", "get_pk_timers", "get_pk_thresholds",
 }
 
+// scriptLoadTimeout bounds top-level script execution while building a VM,
+// so a script with a runaway top-level loop cannot hang startup or reload.
+const scriptLoadTimeout = 5 * time.Second
+
 // NewEngine creates a Lua engine and loads all scripts from the given directory.
-func NewEngine(scriptsDir string, log *zap.Logger) (*Engine, error) {
+// tickRate is the game loop interval used to turn tick_budget_pct into a duration.
+func NewEngine(scriptsDir string, cfg config.LuaConfig, tickRate time.Duration, rng *rand.Rand, log *zap.Logger) (*Engine, error) {
+	e := &Engine{
+		log:           log,
+		budget:        newBudget(cfg.Timeout, tickRate, cfg.TickBudgetPct),
+		rng:           rng,
+		scriptsDir:    scriptsDir,
+		memoryLimitMB: cfg.MemoryLimitMB,
+		aiStates:      make(map[int]npcAIState),
+		aiWarned:      make(map[string]bool),
+	}
+	vm, err := e.buildVM()
+	if err != nil {
+		return nil, err
+	}
+	e.vm = vm
+	e.mem = newMemGuard(cfg.MemoryLimitMB)
+	return e, nil
+}
+
+// buildVM creates a new LState, loads every script directory and verifies
+// that all required globals are defined. The caller owns the returned VM.
+func (e *Engine) buildVM() (*lua.LState, error) {
 	vm := lua.NewState(lua.Options{
-		SkipOpenLibs: false,
+		SkipOpenLibs:    false,
+		RegistryMaxSize: registryMaxSize(e.memoryLimitMB),
 	})
 
 	// Set API version global
 	vm.SetGlobal("API_VERSION", lua.LNumber(1))
+	e.registerGameModule(vm)
+	e.registerMathRandom(vm)
+	vm.SetGlobal(aiProfilesGlobal, vm.NewTable())
 
-	e := &Engine{vm: vm, log: log}
+	ctx, cancel := context.WithTimeout(context.Background(), scriptLoadTimeout)
+	defer cancel()
+	vm.SetContext(ctx)
+	defer vm.RemoveContext()
 
 	// Load core scripts first, then feature scripts
-	corePath := filepath.Join(scriptsDir, "core")
-	if err := e.loadDir(corePath); err != nil {
-		vm.Close()
-		return nil, fmt.Errorf("load core scripts: %w", err)
+	for _, sub := range []string{"core", "combat", "item", "character", "skill", "world", "ai"} {
+		p := filepath.Join(e.scriptsDir, sub)
+		if err := e.loadDir(vm, p); err != nil {
+			vm.Close()
+			return nil, fmt.Errorf("load %s scripts: %w", sub, err)
+		}
 	}
 
-	combatPath := filepath.Join(scriptsDir, "combat")
-	if err := e.loadDir(combatPath); err != nil {
+	var missing []string
+	for _, name := range requiredGlobals {
+		if _, ok := vm.GetGlobal(name).(*lua.LFunction); !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
 		vm.Close()
-		return nil, fmt.Errorf("load combat scripts: %w", err)
+		return nil, fmt.Errorf("missing lua functions: %s", strings.Join(missing, ", "))
 	}
 
-	// Load optional feature script directories
-	for _, sub := range []string{"item", "character", "skill", "world", "ai"} {
-		p := filepath.Join(scriptsDir, sub)
-		if err := e.loadDir(p); err != nil {
-			vm.Close()
-			return nil, fmt.Errorf("load %s scripts: %w", sub, err)
-		}
-	}
+	return vm, nil
+}
 
-	return e, nil
+// registerMathRandom replaces math.random / math.randomseed so scripts draw
+// from the engine's RNG instead of the global math/rand source; combat, drop
+// and enchant rolls then follow the server seed. Semantics match gopher-lua:
+// random() in [0,1), random(n) in [1,n], random(m, n) in [m,n].
+func (e *Engine) registerMathRandom(vm *lua.LState) {
+	mathLib, ok := vm.GetGlobal("math").(*lua.LTable)
+	if !ok {
+		return
+	}
+	vm.SetField(mathLib, "random", vm.NewFunction(func(L *lua.LState) int {
+		switch L.GetTop() {
+		case 0:
+			L.Push(lua.LNumber(e.rng.Float64()))
+		case 1:
+			n := L.CheckInt(1)
+			L.Push(lua.LNumber(e.rng.Intn(n) + 1))
+		default:
+			lo := L.CheckInt(1)
+			hi := L.CheckInt(2) + 1
+			L.Push(lua.LNumber(e.rng.Intn(hi-lo) + lo))
+		}
+		return 1
+	}))
+	vm.SetField(mathLib, "randomseed", vm.NewFunction(func(L *lua.LState) int {
+		e.rng.Seed(L.CheckInt64(1))
+		return 0
+	}))
 }
 
 // loadDir loads all .lua files in a directory.
-func (e *Engine) loadDir(dir string) error {
+func (e *Engine) loadDir(vm *lua.LState, dir string) error {
 	entries, err := os.ReadDir(dir)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -66,7 +162,7 @@ func (e *Engine) loadDir(dir string) error {
 			continue
 		}
 		path := filepath.Join(dir, entry.Name())
-		if err := e.vm.DoFile(path); err != nil {
+		if err := vm.DoFile(path); err != nil {
 			return fmt.Errorf("load %s: %w", path, err)
 		}
 		e.log.Debug("loaded lua script", zap.String("file", path))
@@ -74,6 +170,41 @@ func (e *Engine) loadDir(dir string) error {
 	return nil
 }
 
+// RequestReload queues a hot-reload of the scripts directory. The new VM is
+// built and swapped in by ApplyPendingReload at the end of the current tick;
+// done is then called with nil on success, or with the load error (the old
+// VM stays active). Game-loop goroutine only.
+func (e *Engine) RequestReload(done func(error)) {
+	e.reloads = append(e.reloads, done)
+}
+
+// ApplyPendingReload performs a queued reload, if any. Multiple requests in
+// the same tick share one rebuild. Called between ticks by system.LuaBudgetSystem.
+func (e *Engine) ApplyPendingReload() {
+	if len(e.reloads) == 0 {
+		return
+	}
+	waiters := e.reloads
+	e.reloads = nil
+
+	start := time.Now()
+	vm, err := e.buildVM()
+	if err != nil {
+		e.log.Error("lua reload failed, keeping current scripts", zap.Error(err))
+	} else {
+		old := e.vm
+		e.vm = vm
+		old.Close()
+		e.resetAIProfiles()
+		e.log.Info("lua scripts reloaded", zap.Duration("took", time.Since(start)))
+	}
+	for _, done := range waiters {
+		if done != nil {
+			done(err)
+		}
+	}
+}
+
 // CombatContext holds pre-packed data for a melee attack calculation.
 type CombatContext struct {
 	AttackerLevel  int
@@ -136,12 +267,7 @@ func (e *Engine) CalcMeleeAttack(ctx CombatContext) CombatResult {
 	tgt.RawSetString("class_type", lua.LNumber(ctx.TargetClassType))
 	t.RawSetString("target", tgt)
 
-	if err := e.vm.CallByParam(lua.P{
-		Fn:      fn,
-		NRet:    1,
-		Protect: true,
-	}, t); err != nil {
-		e.log.Error("lua calc_melee_attack error", zap.Error(err))
+	if err := e.pcall("calc_melee_attack", fn, 1, t); err != nil {
 		return CombatResult{IsHit: true, Damage: 1}
 	}
 
@@ -187,12 +313,7 @@ func (e *Engine) CalcRangedAttack(ctx RangedCombatContext) CombatResult {
 	tgt.RawSetString("class_type", lua.LNumber(ctx.TargetClassType))
 	t.RawSetString("target", tgt)
 
-	if err := e.vm.CallByParam(lua.P{
-		Fn:      fn,
-		NRet:    1,
-		Protect: true,
-	}, t); err != nil {
-		e.log.Error("lua calc_ranged_attack error", zap.Error(err))
+	if err := e.pcall("calc_ranged_attack", fn, 1, t); err != nil {
 		return CombatResult{IsHit: true, Damage: 1}
 	}
 
@@ -298,12 +419,7 @@ func (e *Engine) CalcSkillDamage(ctx SkillDamageContext) SkillDamageResult {
 	tgt.RawSetString("mp", lua.LNumber(ctx.TargetMP))
 	t.RawSetString("target", tgt)
 
-	if err := e.vm.CallByParam(lua.P{
-		Fn:      fn,
-		NRet:    1,
-		Protect: true,
-	}, t); err != nil {
-		e.log.Error("lua calc_skill_damage error", zap.Error(err))
+	if err := e.pcall("calc_skill_damage", fn, 1, t); err != nil {
 		return SkillDamageResult{Damage: 1, HitCount: 1}
 	}
 
@@ -370,12 +486,7 @@ func (e *Engine) GetBuffEffect(skillID, targetLevel int) *BuffEffect {
 		return nil
 	}
 
-	if err := e.vm.CallByParam(lua.P{
-		Fn:      fn,
-		NRet:    1,
-		Protect: true,
-	}, lua.LNumber(skillID), lua.LNumber(targetLevel)); err != nil {
-		e.log.Error("lua get_buff_effect error", zap.Error(err), zap.Int("skill_id", skillID))
+	if err := e.pcall("get_buff_effect", fn, 1, lua.LNumber(skillID), lua.LNumber(targetLevel)); err != nil {
 		return nil
 	}
 
@@ -446,11 +557,7 @@ func (e *Engine) IsNonCancellable(skillID int) bool {
 		return false
 	}
 
-	if err := e.vm.CallByParam(lua.P{
-		Fn:      fn,
-		NRet:    1,
-		Protect: true,
-	}, lua.LNumber(skillID)); err != nil {
+	if err := e.pcall("is_non_cancellable", fn, 1, lua.LNumber(skillID)); err != nil {
 		return false
 	}
 
@@ -496,12 +603,7 @@ func (e *Engine) GetPotionEffect(itemID int) *PotionEffect {
 		return nil
 	}
 
-	if err := e.vm.CallByParam(lua.P{
-		Fn:      fn,
-		NRet:    1,
-		Protect: true,
-	}, lua.LNumber(itemID)); err != nil {
-		e.log.Error("lua get_potion_effect error", zap.Error(err))
+	if err := e.pcall("get_potion_effect", fn, 1, lua.LNumber(itemID)); err != nil {
 		return nil
 	}
 
@@ -554,12 +656,7 @@ func (e *Engine) GetCharCreateData(classType int) *CharCreateData {
 		return nil
 	}
 
-	if err := e.vm.CallByParam(lua.P{
-		Fn:      fn,
-		NRet:    1,
-		Protect: true,
-	}, lua.LNumber(classType)); err != nil {
-		e.log.Error("lua get_char_create_data error", zap.Error(err))
+	if err := e.pcall("get_char_create_data", fn, 1, lua.LNumber(classType)); err != nil {
 		return nil
 	}
 
@@ -625,12 +722,7 @@ func (e *Engine) GetResurrectEffect(skillID int) *ResurrectResult {
 		return nil
 	}
 
-	if err := e.vm.CallByParam(lua.P{
-		Fn:      fn,
-		NRet:    1,
-		Protect: true,
-	}, lua.LNumber(skillID)); err != nil {
-		e.log.Error("lua get_resurrect_effect error", zap.Error(err))
+	if err := e.pcall("get_resurrect_effect", fn, 1, lua.LNumber(skillID)); err != nil {
 		return nil
 	}
 
@@ -670,12 +762,7 @@ func (e *Engine) GetSpellTiers(classType int) []SpellTierInfo {
 		return nil
 	}
 
-	if err := e.vm.CallByParam(lua.P{
-		Fn:      fn,
-		NRet:    1,
-		Protect: true,
-	}, lua.LNumber(classType)); err != nil {
-		e.log.Error("lua get_spell_tiers error", zap.Error(err))
+	if err := e.pcall("get_spell_tiers", fn, 1, lua.LNumber(classType)); err != nil {
 		return nil
 	}
 
@@ -720,12 +807,7 @@ func (e *Engine) GetRespawnLocation(mapID int) *RespawnLocation {
 		return nil
 	}
 
-	if err := e.vm.CallByParam(lua.P{
-		Fn:      fn,
-		NRet:    1,
-		Protect: true,
-	}, lua.LNumber(mapID)); err != nil {
-		e.log.Error("lua get_respawn_location error", zap.Error(err))
+	if err := e.pcall("get_respawn_location", fn, 1, lua.LNumber(mapID)); err != nil {
 		return nil
 	}
 
@@ -756,12 +838,7 @@ func (e *Engine) GetHomeScrollLocation(mapID, x, y int) *RespawnLocation {
 		return nil
 	}
 
-	if err := e.vm.CallByParam(lua.P{
-		Fn:      fn,
-		NRet:    1,
-		Protect: true,
-	}, lua.LNumber(mapID), lua.LNumber(x), lua.LNumber(y)); err != nil {
-		e.log.Error("lua get_home_scroll_location error", zap.Error(err))
+	if err := e.pcall("get_home_scroll_location", fn, 1, lua.LNumber(mapID), lua.LNumber(x), lua.LNumber(y)); err != nil {
 		return nil
 	}
 
@@ -823,12 +900,7 @@ func (e *Engine) CalcEnchant(ctx EnchantContext) EnchantResult {
 	t.RawSetString("weapon_chance", lua.LNumber(ctx.WeaponChance))
 	t.RawSetString("armor_chance", lua.LNumber(ctx.ArmorChance))
 
-	if err := e.vm.CallByParam(lua.P{
-		Fn:      fn,
-		NRet:    1,
-		Protect: true,
-	}, t); err != nil {
-		e.log.Error("lua calc_enchant error", zap.Error(err))
+	if err := e.pcall("calc_enchant", fn, 1, t); err != nil {
 		return EnchantResult{Result: "fail"}
 	}
 
@@ -869,6 +941,8 @@ type MobSkillEntry struct {
 // AIContext holds pre-packed data for NPC AI decisions.
 type AIContext struct {
 	NpcID      int
+	ObjID      int    // NPC object ID (keys the persistent AI state table)
+	AIProfile  string // npc_list.yaml `ai:` profile ("" = default)
 	X, Y       int
 	MapID      int
 	HP, MaxHP  int
@@ -914,9 +988,11 @@ type AICommand struct {
 	SummonMax    int
 }
 
-// RunNpcAI calls Lua npc_ai(ctx) and returns a list of commands.
+// RunNpcAI calls the NPC's AI profile function (AI_PROFILES[profile], or the
+// global npc_ai for default/unknown profiles) as fn(ctx, state) and returns a
+// list of commands. state is a per-NPC table that persists across ticks.
 func (e *Engine) RunNpcAI(ctx AIContext) []AICommand {
-	fn := e.vm.GetGlobal("npc_ai")
+	fn, name, profile := e.resolveAIProfile(ctx.AIProfile)
 	if fn == lua.LNil {
 		return nil
 	}
@@ -924,6 +1000,7 @@ func (e *Engine) RunNpcAI(ctx AIContext) []AICommand {
 	// Build context table
 	t := e.vm.NewTable()
 	t.RawSetString("npc_id", lua.LNumber(ctx.NpcID))
+	t.RawSetString("obj_id", lua.LNumber(ctx.ObjID))
 	t.RawSetString("x", lua.LNumber(ctx.X))
 	t.RawSetString("y", lua.LNumber(ctx.Y))
 	t.RawSetString("map_id", lua.LNumber(ctx.MapID))
@@ -984,12 +1061,8 @@ func (e *Engine) RunNpcAI(ctx AIContext) []AICommand {
 	}
 	t.RawSetString("skills", skillsTbl)
 
-	if err := e.vm.CallByParam(lua.P{
-		Fn:      fn,
-		NRet:    1,
-		Protect: true,
-	}, t); err != nil {
-		e.log.Error("lua npc_ai error", zap.Error(err), zap.Int("npc_id", ctx.NpcID))
+	state := e.npcAIStateTable(ctx.ObjID, profile)
+	if err := e.pcall(name, fn, 1, t, state); err != nil {
 		return nil
 	}
 
@@ -1046,12 +1119,7 @@ func (e *Engine) CalcNpcMelee(ctx CombatContext) CombatResult {
 	tgt.RawSetString("mr", lua.LNumber(ctx.TargetMR))
 	t.RawSetString("target", tgt)
 
-	if err := e.vm.CallByParam(lua.P{
-		Fn:      fn,
-		NRet:    1,
-		Protect: true,
-	}, t); err != nil {
-		e.log.Error("lua calc_npc_melee error", zap.Error(err))
+	if err := e.pcall("calc_npc_melee", fn, 1, t); err != nil {
 		return CombatResult{IsHit: true, Damage: 1}
 	}
 
@@ -1093,12 +1161,7 @@ func (e *Engine) CalcNpcRanged(ctx CombatContext) CombatResult {
 	tgt.RawSetString("mr", lua.LNumber(ctx.TargetMR))
 	t.RawSetString("target", tgt)
 
-	if err := e.vm.CallByParam(lua.P{
-		Fn:      fn,
-		NRet:    1,
-		Protect: true,
-	}, t); err != nil {
-		e.log.Error("lua calc_npc_ranged error", zap.Error(err))
+	if err := e.pcall("calc_npc_ranged", fn, 1, t); err != nil {
 		return CombatResult{IsHit: true, Damage: 1}
 	}
 
@@ -1135,12 +1198,7 @@ func (e *Engine) CalcPKLawfulPenalty(killerLevel int, killerLawful int32) PKLawf
 	t.RawSetString("killer_level", lua.LNumber(killerLevel))
 	t.RawSetString("killer_lawful", lua.LNumber(killerLawful))
 
-	if err := e.vm.CallByParam(lua.P{
-		Fn:      fn,
-		NRet:    1,
-		Protect: true,
-	}, t); err != nil {
-		e.log.Error("lua calc_pk_lawful_penalty error", zap.Error(err))
+	if err := e.pcall("calc_pk_lawful_penalty", fn, 1, t); err != nil {
 		return PKLawfulResult{NewLawful: killerLawful - 1000}
 	}
 
@@ -1173,12 +1231,7 @@ func (e *Engine) CalcPKItemDrop(victimLawful int32) PKItemDropResult {
 	t := e.vm.NewTable()
 	t.RawSetString("victim_lawful", lua.LNumber(victimLawful))
 
-	if err := e.vm.CallByParam(lua.P{
-		Fn:      fn,
-		NRet:    1,
-		Protect: true,
-	}, t); err != nil {
-		e.log.Error("lua calc_pk_item_drop error", zap.Error(err))
+	if err := e.pcall("calc_pk_item_drop", fn, 1, t); err != nil {
 		return PKItemDropResult{}
 	}
 
@@ -1209,12 +1262,7 @@ func (e *Engine) GetPKTimers() PKTimers {
 		return PKTimers{PinkNameTicks: 900, WantedTicks: 432000}
 	}
 
-	if err := e.vm.CallByParam(lua.P{
-		Fn:      fn,
-		NRet:    1,
-		Protect: true,
-	}); err != nil {
-		e.log.Error("lua get_pk_timers error", zap.Error(err))
+	if err := e.pcall("get_pk_timers", fn, 1); err != nil {
 		return PKTimers{PinkNameTicks: 900, WantedTicks: 432000}
 	}
 
@@ -1245,12 +1293,7 @@ func (e *Engine) GetPKThresholds() PKThresholds {
 		return PKThresholds{Warning: 5, Punish: 10}
 	}
 
-	if err := e.vm.CallByParam(lua.P{
-		Fn:      fn,
-		NRet:    1,
-		Protect: true,
-	}); err != nil {
-		e.log.Error("lua get_pk_thresholds error", zap.Error(err))
+	if err := e.pcall("get_pk_thresholds", fn, 1); err != nil {
 		return PKThresholds{Warning: 5, Punish: 10}
 	}
 
@@ -1295,12 +1338,7 @@ func (e *Engine) CalcDurabilityDamage(ctx DurabilityContext) DurabilityResult {
 	t.RawSetString("bless", lua.LNumber(ctx.Bless))
 	t.RawSetString("current_durability", lua.LNumber(ctx.CurrentDurability))
 
-	if err := e.vm.CallByParam(lua.P{
-		Fn:      fn,
-		NRet:    1,
-		Protect: true,
-	}, t); err != nil {
-		e.log.Error("lua calc_durability_damage error", zap.Error(err))
+	if err := e.pcall("calc_durability_damage", fn, 1, t); err != nil {
 		return DurabilityResult{ShouldDamage: false, MaxDurability: ctx.EnchantLvl + 5}
 	}
 
@@ -1361,12 +1399,7 @@ func (e *Engine) CalcHPRegenAmount(ctx HPRegenContext) int {
 		t.RawSetString("has_additional_fire", lua.LFalse)
 	}
 
-	if err := e.vm.CallByParam(lua.P{
-		Fn:      fn,
-		NRet:    1,
-		Protect: true,
-	}, t); err != nil {
-		e.log.Error("lua calc_hp_regen_amount error", zap.Error(err))
+	if err := e.pcall("calc_hp_regen_amount", fn, 1, t); err != nil {
 		return 1
 	}
 
@@ -1420,12 +1453,7 @@ func (e *Engine) CalcMPRegenAmount(ctx MPRegenContext) int {
 		t.RawSetString("has_blue_potion", lua.LFalse)
 	}
 
-	if err := e.vm.CallByParam(lua.P{
-		Fn:      fn,
-		NRet:    1,
-		Protect: true,
-	}, t); err != nil {
-		e.log.Error("lua calc_mp_regen_amount error", zap.Error(err))
+	if err := e.pcall("calc_mp_regen_amount", fn, 1, t); err != nil {
 		return 1
 	}
 
@@ -1465,12 +1493,7 @@ func (e *Engine) callIntFunc(name string, args ...int) int {
 		lArgs[i] = lua.LNumber(a)
 	}
 
-	if err := e.vm.CallByParam(lua.P{
-		Fn:      fn,
-		NRet:    1,
-		Protect: true,
-	}, lArgs...); err != nil {
-		e.log.Error("lua call error", zap.String("func", name), zap.Error(err))
+	if err := e.pcall(name, fn, 1, lArgs...); err != nil {
 		return 0
 	}
 
@@ -1481,5 +1504,8 @@ func (e *Engine) callIntFunc(name string, args ...int) int {
 
 // Close shuts down the Lua VM.
 func (e *Engine) Close() {
+	if e.mem != nil {
+		e.mem.close()
+	}
 	e.vm.Close()
 }
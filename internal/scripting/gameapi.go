@@ -0,0 +1,167 @@
+package scripting
+
+import (
+	lua "github.com/yuin/gopher-lua"
+)
+
+// GameAPI is the host side of the Lua `game` module: the world actions scripts
+// may perform. Implemented by system.ScriptGameAPI, which is backed by
+// world.State and handler.Deps. All methods run on the game-loop goroutine
+// (scripts are only ever invoked from there) and apply immediately.
+type GameAPI interface {
+	// SendMessage sends a system chat line to an online player.
+	SendMessage(charID int32, text string) bool
+	// GiveItem adds items to an online player's inventory (stacks when possible).
+	GiveItem(charID, itemID, count int32, enchant int8) bool
+	// TakeItem removes count of itemID; fails without change if the player has fewer.
+	TakeItem(charID, itemID, count int32) bool
+	// CountItem returns how many of itemID the player carries.
+	CountItem(charID, itemID int32) int32
+	// SpawnNpc spawns an NPC template at a point and returns its object ID (0 on failure).
+	SpawnNpc(npcID, x, y int32, mapID int16) int32
+	// Teleport moves an online player to a point.
+	Teleport(charID, x, y int32, mapID int16) bool
+	// QuestStep returns the player's progress for a quest (0 = not started).
+	QuestStep(charID, questID int32) int32
+	// SetQuestStep sets and persists the player's progress for a quest.
+	SetQuestStep(charID, questID, step int32) bool
+	// FindPlayer returns the char ID of an online player by name (0 = offline).
+	FindPlayer(name string) int32
+	// PlayerPos returns an online player's position.
+	PlayerPos(charID int32) (x, y int32, mapID int16, ok bool)
+}
+
+// SetGameAPI installs the host implementation behind the `game` module.
+// May be called after NewEngine; the module resolves the API on every call,
+// so it also survives hot-reload.
+func (e *Engine) SetGameAPI(api GameAPI) {
+	e.api = api
+}
+
+// registerGameModule exposes the `game` table as a global and as
+// require("game"). Called by buildVM before any script is loaded.
+//
+// Lua API (char_id = character ID, map_id = map number):
+//
+//	game.message(char_id, text)                   -> bool
+//	game.give_item(char_id, item_id [, count [, enchant]]) -> bool
+//	game.take_item(char_id, item_id [, count])    -> bool
+//	game.count_item(char_id, item_id)             -> int
+//	game.spawn_npc(npc_id, x, y, map_id)          -> npc object id (0 = failed)
+//	game.teleport(char_id, x, y, map_id)          -> bool
+//	game.quest_step(char_id, quest_id)            -> int
+//	game.set_quest_step(char_id, quest_id, step)  -> bool
+//	game.find_player(name)                        -> char_id (0 = offline)
+//	game.player_pos(char_id)                      -> x, y, map_id (nil if offline)
+func (e *Engine) registerGameModule(vm *lua.LState) {
+	mod := vm.SetFuncs(vm.NewTable(), map[string]lua.LGFunction{
+		"message": func(L *lua.LState) int {
+			api := e.requireAPI(L)
+			L.Push(lua.LBool(api.SendMessage(int32(L.CheckInt(1)), L.CheckString(2))))
+			return 1
+		},
+		"give_item": func(L *lua.LState) int {
+			api := e.requireAPI(L)
+			count := int32(L.OptInt(3, 1))
+			if count <= 0 {
+				L.ArgError(3, "count must be positive")
+			}
+			L.Push(lua.LBool(api.GiveItem(int32(L.CheckInt(1)), int32(L.CheckInt(2)), count, int8(L.OptInt(4, 0)))))
+			return 1
+		},
+		"take_item": func(L *lua.LState) int {
+			api := e.requireAPI(L)
+			count := int32(L.OptInt(3, 1))
+			if count <= 0 {
+				L.ArgError(3, "count must be positive")
+			}
+			L.Push(lua.LBool(api.TakeItem(int32(L.CheckInt(1)), int32(L.CheckInt(2)), count)))
+			return 1
+		},
+		"count_item": func(L *lua.LState) int {
+			api := e.requireAPI(L)
+			L.Push(lua.LNumber(api.CountItem(int32(L.CheckInt(1)), int32(L.CheckInt(2)))))
+			return 1
+		},
+		"spawn_npc": func(L *lua.LState) int {
+			api := e.requireAPI(L)
+			id := api.SpawnNpc(int32(L.CheckInt(1)), int32(L.CheckInt(2)), int32(L.CheckInt(3)), int16(L.CheckInt(4)))
+			L.Push(lua.LNumber(id))
+			return 1
+		},
+		"teleport": func(L *lua.LState) int {
+			api := e.requireAPI(L)
+			L.Push(lua.LBool(api.Teleport(int32(L.CheckInt(1)), int32(L.CheckInt(2)), int32(L.CheckInt(3)), int16(L.CheckInt(4)))))
+			return 1
+		},
+		"quest_step": func(L *lua.LState) int {
+			api := e.requireAPI(L)
+			L.Push(lua.LNumber(api.QuestStep(int32(L.CheckInt(1)), int32(L.CheckInt(2)))))
+			return 1
+		},
+		"set_quest_step": func(L *lua.LState) int {
+			api := e.requireAPI(L)
+			L.Push(lua.LBool(api.SetQuestStep(int32(L.CheckInt(1)), int32(L.CheckInt(2)), int32(L.CheckInt(3)))))
+			return 1
+		},
+		"find_player": func(L *lua.LState) int {
+			api := e.requireAPI(L)
+			L.Push(lua.LNumber(api.FindPlayer(L.CheckString(1))))
+			return 1
+		},
+		"player_pos": func(L *lua.LState) int {
+			api := e.requireAPI(L)
+			x, y, mapID, ok := api.PlayerPos(int32(L.CheckInt(1)))
+			if !ok {
+				L.Push(lua.LNil)
+				return 1
+			}
+			L.Push(lua.LNumber(x))
+			L.Push(lua.LNumber(y))
+			L.Push(lua.LNumber(mapID))
+			return 3
+		},
+	})
+	vm.SetGlobal("game", mod)
+	vm.PreloadModule("game", func(L *lua.LState) int {
+		L.Push(mod)
+		return 1
+	})
+}
+
+// requireAPI returns the installed GameAPI or raises a Lua error.
+func (e *Engine) requireAPI(L *lua.LState) GameAPI {
+	if e.api == nil {
+		L.RaiseError("game api not available")
+	}
+	return e.api
+}
+
+// NpcKilledContext describes an NPC death for the on_npc_killed hook.
+type NpcKilledContext struct {
+	NpcTemplateID int
+	NpcObjID      int
+	KillerCharID  int
+	MapID         int
+	X, Y          int
+}
+
+// OnNpcKilled calls the optional Lua hook on_npc_killed(ev). Scripts use it
+// together with the `game` module for encounter and event logic; a missing
+// hook is silently skipped.
+func (e *Engine) OnNpcKilled(ctx NpcKilledContext) {
+	fn := e.vm.GetGlobal("on_npc_killed")
+	if fn == lua.LNil {
+		return
+	}
+
+	t := e.vm.NewTable()
+	t.RawSetString("npc_id", lua.LNumber(ctx.NpcTemplateID))
+	t.RawSetString("npc_obj_id", lua.LNumber(ctx.NpcObjID))
+	t.RawSetString("killer_id", lua.LNumber(ctx.KillerCharID))
+	t.RawSetString("map_id", lua.LNumber(ctx.MapID))
+	t.RawSetString("x", lua.LNumber(ctx.X))
+	t.RawSetString("y", lua.LNumber(ctx.Y))
+
+	_ = e.pcall("on_npc_killed", fn, 0, t)
+}
@@ -0,0 +1,98 @@
+package scripting
+
+import (
+	"context"
+	"errors"
+	"runtime/metrics"
+	"sync"
+	"time"
+)
+
+// errMemoryLimit is the cancellation cause of a Lua call that allocated more
+// than memory_limit_mb.
+var errMemoryLimit = errors.New("lua call exceeded memory_limit_mb")
+
+// memGuardInterval is how often the watchdog samples heap allocation while a
+// call is running.
+const memGuardInterval = 2 * time.Millisecond
+
+// heapAllocsMetric is the runtime's cumulative count of bytes allocated on the
+// heap. Reading it does not stop the world.
+const heapAllocsMetric = "/gc/heap/allocs:bytes"
+
+// memGuard bounds the memory one Lua call may allocate. gopher-lua allocates
+// tables and strings on the Go heap and cannot meter them itself, so a
+// watchdog goroutine samples the cumulative heap allocation counter while a
+// call runs and cancels the call's context once it has grown by more than
+// limit bytes; the VM checks the context between instructions and aborts the
+// call. The counter is process-wide, so allocations by other goroutines during
+// the call count as well — the limit is a per-call ceiling, not a measure of
+// what the VM retains across calls.
+type memGuard struct {
+	limit  uint64
+	sample []metrics.Sample // game-loop side (begin)
+
+	mu     sync.Mutex
+	cancel context.CancelCauseFunc // running call; nil when idle
+	base   uint64                  // heap allocation counter when the call started
+
+	stop chan struct{}
+}
+
+// newMemGuard starts a watchdog for limitMB megabytes per call. Returns nil
+// (no limit) for limitMB <= 0.
+func newMemGuard(limitMB int) *memGuard {
+	if limitMB <= 0 {
+		return nil
+	}
+	g := &memGuard{
+		limit:  uint64(limitMB) << 20,
+		sample: []metrics.Sample{{Name: heapAllocsMetric}},
+		stop:   make(chan struct{}),
+	}
+	go g.run()
+	return g
+}
+
+// begin arms the guard for a call whose context is cancelled by cancel.
+func (g *memGuard) begin(cancel context.CancelCauseFunc) {
+	metrics.Read(g.sample)
+	base := g.sample[0].Value.Uint64()
+	g.mu.Lock()
+	g.cancel, g.base = cancel, base
+	g.mu.Unlock()
+}
+
+// end disarms the guard after the call returned.
+func (g *memGuard) end() {
+	g.mu.Lock()
+	g.cancel = nil
+	g.mu.Unlock()
+}
+
+func (g *memGuard) run() {
+	sample := []metrics.Sample{{Name: heapAllocsMetric}}
+	ticker := time.NewTicker(memGuardInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-g.stop:
+			return
+		case <-ticker.C:
+		}
+		g.mu.Lock()
+		if g.cancel != nil {
+			metrics.Read(sample)
+			if sample[0].Value.Uint64()-g.base > g.limit {
+				g.cancel(errMemoryLimit)
+				g.cancel = nil
+			}
+		}
+		g.mu.Unlock()
+	}
+}
+
+// close stops the watchdog.
+func (g *memGuard) close() {
+	close(g.stop)
+}
@@ -0,0 +1,286 @@
+package server
+
+import (
+	"context"
+	"math/rand"
+
+	"github.com/l1jgo/server/internal/data"
+	"github.com/l1jgo/server/internal/persist"
+	"github.com/l1jgo/server/internal/world"
+	"go.uber.org/zap"
+)
+
+// loadClans loads all clans and members from DB into world state.
+func loadClans(ctx context.Context, ws *world.State, clanRepo persist.ClanStore) (int, error) {
+	clans, members, err := clanRepo.LoadAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// Build clan map
+	clanMap := make(map[int32]*world.ClanInfo, len(clans))
+	for _, c := range clans {
+		clanMap[c.ClanID] = &world.ClanInfo{
+			ClanID:       c.ClanID,
+			ClanName:     c.ClanName,
+			LeaderID:     c.LeaderID,
+			LeaderName:   c.LeaderName,
+			FoundDate:    c.FoundDate,
+			HasCastle:    c.HasCastle,
+			HasHouse:     c.HasHouse,
+			Announcement: c.Announcement,
+			EmblemID:     c.EmblemID,
+			EmblemStatus: c.EmblemStatus,
+			Members:      make(map[int32]*world.ClanMember),
+		}
+	}
+
+	// Assign members
+	for _, m := range members {
+		clan, ok := clanMap[m.ClanID]
+		if !ok {
+			continue
+		}
+		clan.Members[m.CharID] = &world.ClanMember{
+			CharID:   m.CharID,
+			CharName: m.CharName,
+			Rank:     m.Rank,
+			Notes:    m.Notes,
+		}
+	}
+
+	// Register all clans
+	for _, clan := range clanMap {
+		ws.Clans.AddClan(clan)
+	}
+
+	return len(clans), nil
+}
+
+// loadInnRooms 載入旅館房間資料。若 NPC 沒有房間記錄，自動建立 16 間。
+// Java: InnTable — 啟動時從 房間資料數據 載入。
+func loadInnRooms(ctx context.Context, innRepo persist.InnStore) (map[int32]map[int32]*persist.InnRoom, error) {
+	// 9 個旅館 NPC
+	innNpcIDs := []int32{70012, 70019, 70031, 70065, 70070, 70075, 70084, 70054, 70096}
+
+	// 確保所有旅館 NPC 都有 16 間房間記錄
+	for _, npcID := range innNpcIDs {
+		if err := innRepo.EnsureRooms(ctx, npcID); err != nil {
+			return nil, err
+		}
+	}
+
+	// 載入所有房間
+	rooms, err := innRepo.LoadAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 建立 npcID → roomNumber → room 對照表
+	result := make(map[int32]map[int32]*persist.InnRoom)
+	for _, room := range rooms {
+		if result[room.NpcID] == nil {
+			result[room.NpcID] = make(map[int32]*persist.InnRoom)
+		}
+		result[room.NpcID][room.RoomNumber] = room
+	}
+	return result, nil
+}
+
+// spawnNpcs creates NPC instances from spawn list and adds them to world state.
+// sprTable may be nil (speeds fall back to YAML template values).
+func spawnNpcs(ws *world.State, rng *rand.Rand, npcTable *data.NpcTable, spawns []data.SpawnEntry, maps *data.MapDataTable, sprTable *data.SprTable, mobGroups *data.MobGroupTable, log *zap.Logger) int {
+	total := 0
+	for _, spawn := range spawns {
+		tmpl := npcTable.Get(spawn.NpcID)
+		if tmpl == nil {
+			log.Warn("生成: 未知的 NPC ID", zap.Int32("npc_id", spawn.NpcID))
+			continue
+		}
+		for i := 0; i < spawn.Count; i++ {
+			x := spawn.X
+			y := spawn.Y
+			// spread: "point" → 精確座標，無隨機偏移（NPC、Boss）
+			if spawn.Spread != "point" {
+				rx := spawn.RandomX
+				ry := spawn.RandomY
+				// 多隻怪物同座標時，按數量比例自動套用隨機範圍避免聚堆
+				if rx == 0 && ry == 0 && spawn.Count > 1 {
+					rx = int32(spawn.Count)
+					if rx > 25 {
+						rx = 25
+					}
+					ry = rx
+				}
+				if rx > 0 {
+					x += int32(rng.Intn(int(rx*2+1))) - rx
+				}
+				if ry > 0 {
+					y += int32(rng.Intn(int(ry*2+1))) - ry
+				}
+			}
+
+			leader := createNpcFromTemplate(tmpl, x, y, spawn.MapID, spawn.Heading, spawn.RespawnDelay, sprTable)
+			leader.MobGroupID = spawn.MobGroupID
+			ws.AddNpc(leader)
+			if maps != nil {
+				maps.SetImpassable(leader.MapID, leader.X, leader.Y, true)
+			}
+			total++
+
+			// 群體生成（Java: L1MobGroupSpawn.doSpawn）
+			if spawn.MobGroupID > 0 && mobGroups != nil {
+				group := mobGroups.Get(spawn.MobGroupID)
+				if group != nil {
+					total += spawnMobGroup(ws, rng, leader, group, npcTable, maps, sprTable)
+				}
+			}
+		}
+	}
+	return total
+}
+
+// createNpcFromTemplate 從模板建立 NPC 實體。
+func createNpcFromTemplate(tmpl *data.NpcTemplate, x, y int32, mapID, heading int16, respawnDelay int, sprTable *data.SprTable) *world.NpcInfo {
+	atkSpeed := tmpl.AtkSpeed
+	moveSpeed := tmpl.PassiveSpeed
+	if sprTable != nil {
+		gfx := int(tmpl.GfxID)
+		if tmpl.AtkSpeed != 0 {
+			if v := sprTable.GetAttackSpeed(gfx, data.ActAttack); v > 0 {
+				atkSpeed = int16(v)
+			}
+		}
+		if tmpl.PassiveSpeed != 0 {
+			if v := sprTable.GetMoveSpeed(gfx, data.ActWalk); v > 0 {
+				moveSpeed = int16(v)
+			}
+		}
+	}
+	return &world.NpcInfo{
+		ID:           world.NextNpcID(),
+		NpcID:        tmpl.NpcID,
+		Impl:         tmpl.Impl,
+		GfxID:        tmpl.GfxID,
+		LightSize:    byte(tmpl.LightSize),
+		Name:         tmpl.Name,
+		NameID:       tmpl.NameID,
+		Level:        tmpl.Level,
+		X:            x,
+		Y:            y,
+		MapID:        mapID,
+		Heading:      heading,
+		HP:           tmpl.HP,
+		MaxHP:        tmpl.HP,
+		MP:           tmpl.MP,
+		MaxMP:        tmpl.MP,
+		AC:           tmpl.AC,
+		STR:          tmpl.STR,
+		DEX:          tmpl.DEX,
+		Exp:          tmpl.Exp,
+		Lawful:       tmpl.Lawful,
+		Size:         tmpl.Size,
+		MR:           tmpl.MR,
+		Undead:       tmpl.Undead,
+		Agro:         tmpl.Agro,
+		AtkDmg:       int32(tmpl.Level) + int32(tmpl.STR)/3,
+		Ranged:       tmpl.Ranged,
+		AtkSpeed:     atkSpeed,
+		MoveSpeed:    moveSpeed,
+		PoisonAtk:    tmpl.PoisonAtk,
+		FireRes:      tmpl.FireRes,
+		WaterRes:     tmpl.WaterRes,
+		WindRes:      tmpl.WindRes,
+		EarthRes:     tmpl.EarthRes,
+		SpawnX:       x,
+		SpawnY:       y,
+		SpawnMapID:   mapID,
+		RespawnDelay: respawnDelay,
+	}
+}
+
+// spawnMobGroup 生成怪物群體的隊員。
+// Java: L1MobGroupSpawn.doSpawn — 在 leader 周圍 ±2 格生成 minion。
+func spawnMobGroup(ws *world.State, rng *rand.Rand, leader *world.NpcInfo, group *data.MobGroup, npcTable *data.NpcTable, maps *data.MapDataTable, sprTable *data.SprTable) int {
+	groupInfo := &world.MobGroupInfo{
+		Leader:             leader,
+		Members:            []*world.NpcInfo{leader},
+		RemoveGroupOnDeath: group.RemoveGroupIfLeaderDie,
+	}
+	leader.GroupInfo = groupInfo
+
+	spawned := 0
+	for _, minion := range group.Minions {
+		if minion.NpcID == 0 || minion.Count == 0 {
+			continue
+		}
+		mTmpl := npcTable.Get(minion.NpcID)
+		if mTmpl == nil {
+			continue
+		}
+		for j := 0; j < minion.Count; j++ {
+			// Java: leader 座標 ±2 格（random.nextInt(5) - 2）
+			mx := leader.X + int32(rng.Intn(5)) - 2
+			my := leader.Y + int32(rng.Intn(5)) - 2
+
+			mob := createNpcFromTemplate(mTmpl, mx, my, leader.MapID, leader.Heading, 0, sprTable)
+			mob.IsMinion = true       // 隊員不獨立重生
+			mob.GroupInfo = groupInfo // 回指群體資訊
+			mob.SpawnX = leader.SpawnX
+			mob.SpawnY = leader.SpawnY
+			mob.SpawnMapID = leader.SpawnMapID
+
+			ws.AddNpc(mob)
+			if maps != nil {
+				maps.SetImpassable(mob.MapID, mob.X, mob.Y, true)
+			}
+			groupInfo.Members = append(groupInfo.Members, mob)
+			spawned++
+		}
+	}
+	return spawned
+}
+
+// spawnDoors creates door instances from door spawn data and adds them to world state.
+func spawnDoors(ws *world.State, doorTable *data.DoorTable) int {
+	total := 0
+	for _, spawn := range doorTable.Spawns() {
+		gfx := doorTable.GetGfx(spawn.GfxID)
+		if gfx == nil {
+			continue
+		}
+
+		// Calculate absolute edge locations from base position + offset
+		var baseLoc int32
+		if gfx.Direction == 0 {
+			baseLoc = spawn.X
+		} else {
+			baseLoc = spawn.Y
+		}
+
+		door := &world.DoorInfo{
+			ID:        world.NextDoorID(),
+			DoorID:    spawn.ID,
+			GfxID:     spawn.GfxID,
+			X:         spawn.X,
+			Y:         spawn.Y,
+			MapID:     spawn.MapID,
+			MaxHP:     spawn.HP,
+			HP:        spawn.HP,
+			KeeperID:  spawn.Keeper,
+			Direction: gfx.Direction,
+			LeftEdge:  baseLoc + int32(gfx.LeftEdgeOffset),
+			RightEdge: baseLoc + int32(gfx.RightEdgeOffset),
+		}
+
+		if spawn.IsOpening {
+			door.OpenStatus = world.DoorActionOpen
+		} else {
+			door.OpenStatus = world.DoorActionClose
+		}
+
+		ws.AddDoor(door)
+		total++
+	}
+	return total
+}
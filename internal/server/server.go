@@ -0,0 +1,758 @@
+// Package server 組裝遊戲伺服器：載入資料表、生成 NPC 與門、建立 handler 依賴與
+// 所有 System，並依 Phase 註冊到 Runner。cmd/l1jgo 以計時器驅動 Tick；模擬測試
+// （internal/sim）以同一組裝手動 Tick，兩者執行完全相同的遊戲邏輯。
+package server
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/l1jgo/server/internal/config"
+	"github.com/l1jgo/server/internal/core/clock"
+	"github.com/l1jgo/server/internal/core/ecs"
+	"github.com/l1jgo/server/internal/core/event"
+	coresys "github.com/l1jgo/server/internal/core/system"
+	"github.com/l1jgo/server/internal/data"
+	"github.com/l1jgo/server/internal/handler"
+	gonet "github.com/l1jgo/server/internal/net"
+	"github.com/l1jgo/server/internal/net/packet"
+	"github.com/l1jgo/server/internal/persist"
+	"github.com/l1jgo/server/internal/scripting"
+	"github.com/l1jgo/server/internal/system"
+	"github.com/l1jgo/server/internal/world"
+	"go.uber.org/zap"
+)
+
+// Options 是組裝伺服器所需的外部元件。資料檔（data/yaml、scripts）以工作目錄
+// 為基準讀取。
+type Options struct {
+	Config *config.Config
+	Stores *persist.Stores
+	Net    *gonet.Server     // 連線來源（TCP 監聽或 NewLocalServer）
+	DBJobs *persist.JobQueue // 非同步資料庫工作佇列
+	Clock  clock.Clock       // 遊戲邏輯時間來源（nil = 系統時鐘）
+	Log    *zap.Logger
+
+	// 啟動進度顯示（nil = 不顯示）
+	Stat func(label string, count int)
+	OK   func(msg string)
+}
+
+// Server 是組裝完成的遊戲伺服器。所有欄位只在遊戲迴圈上存取。
+type Server struct {
+	Config   *config.Config
+	Log      *zap.Logger
+	Stores   *persist.Stores
+	Net      *gonet.Server
+	DBJobs   *persist.JobQueue
+	Clock    clock.Clock
+	Rand     *rand.Rand // 遊戲邏輯亂數來源（種子為 Seed）
+	Seed     int64
+	World    *world.State
+	Deps     *handler.Deps
+	Runner   *coresys.Runner
+	Sessions *gonet.SessionStore
+	Lua      *scripting.Engine
+	Persist  *system.PersistenceSystem
+
+	eventDispatch *system.EventDispatchSystem
+	itemAudit     *system.ItemAuditSystem
+	stat          func(label string, count int)
+	ok            func(msg string)
+}
+
+// New 執行 WAL 崩潰恢復、載入資料並組裝所有 System。
+func New(opts Options) (*Server, error) {
+	s := &Server{
+		Config: opts.Config,
+		Log:    opts.Log,
+		Stores: opts.Stores,
+		Net:    opts.Net,
+		DBJobs: opts.DBJobs,
+		Clock:  opts.Clock,
+		stat:   opts.Stat,
+		ok:     opts.OK,
+	}
+	if s.Clock == nil {
+		s.Clock = clock.Real()
+	}
+	if s.stat == nil {
+		s.stat = func(string, int) {}
+	}
+	if s.ok == nil {
+		s.ok = func(string) {}
+	}
+	if err := s.build(); err != nil {
+		if s.Lua != nil {
+			s.Lua.Close()
+		}
+		return nil, err
+	}
+	return s, nil
+}
+
+// Tick 執行一次完整 tick（Phase 0-6）。
+func (s *Server) Tick() {
+	s.Runner.Tick(s.Config.Network.TickRate)
+}
+
+// PollInput 只執行 Phase 0（系統 tick 之間的高頻輸入輪詢）。
+func (s *Server) PollInput() {
+	s.Runner.TickPhase(coresys.PhaseInput, 0)
+}
+
+// Shutdown 依序等待資料庫工作、派送最後的事件、寫出物品稽核、存檔所有玩家、
+// 寫出 WAL，最後停止連線接收並關閉 Lua。遊戲迴圈上呼叫一次。
+func (s *Server) Shutdown() {
+	// 等待進行中的資料庫工作完成並執行回呼（回呼可能發出物品事件）
+	s.DBJobs.Close()
+	// 派送最後一個 tick 的事件，寫出尚未送出的物品稽核事件
+	s.eventDispatch.Update(0)
+	s.itemAudit.Flush()
+	// Save all players before stopping
+	s.Persist.SaveAllPlayers()
+	// 寫出佇列中剩餘的 WAL 條目
+	s.Stores.WAL.Close()
+	s.Net.Shutdown()
+	s.Lua.Close()
+}
+
+func (s *Server) build() error {
+	cfg := s.Config
+	log := s.Log
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// 4. Repositories
+	stores := s.Stores
+	accountRepo := stores.Accounts
+	charRepo := stores.Characters
+	itemRepo := stores.Items
+	warehouseRepo := stores.Warehouse
+	walRepo := stores.WAL
+	itemEventRepo := stores.ItemEvents
+	clanRepo := stores.Clans
+	buffRepo := stores.Buffs
+	questRepo := stores.Quests
+	houseRepo := stores.Houses
+	innRepo := stores.Inns
+	buddyRepo := stores.Buddies
+	excludeRepo := stores.Excludes
+	boardRepo := stores.Board
+	mailRepo := stores.Mail
+	petRepo := stores.Pets
+	auctionRepo := stores.Auctions
+	castleRepo := stores.Castles
+	banRepo := stores.Bans
+	cheatFlagRepo := stores.CheatFlags
+
+	// 4a. WAL crash recovery — replay unprocessed economic transactions
+	{
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		recovered, err := walRepo.RecoverWAL(ctx)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("WAL crash recovery: %w", err)
+		}
+		if recovered > 0 {
+			log.Warn("WAL 崩潰恢復完成", zap.Int("重播筆數", recovered))
+		}
+	}
+	switch cfg.Persistence.WALSyncMode {
+	case "async":
+		walRepo.StartAsync(log)
+		log.Info("WAL 非同步寫入已啟用")
+	case "sync", "":
+	default:
+		log.Warn("未知的 wal_sync_mode，改用同步寫入", zap.String("mode", cfg.Persistence.WALSyncMode))
+	}
+
+	// 5. Create ECS World and game World State
+	ecsWorld := ecs.NewWorld()
+	worldState := world.NewState()
+
+	// 5-1. 遊戲邏輯亂數（戰鬥、掉落、強化、生成、遊走）共用同一個種子；
+	// 把日誌中的種子填入 [debug] rng_seed 即可重播同一場模擬
+	s.Seed = cfg.Debug.RNGSeed
+	if s.Seed == 0 {
+		s.Seed = time.Now().UnixNano()
+	}
+	s.Rand = rand.New(rand.NewSource(s.Seed))
+	log.Info("遊戲亂數種子", zap.Int64("seed", s.Seed))
+	s.ok(fmt.Sprintf("亂數種子 %d", s.Seed))
+
+	// 5a. Load NPC data and spawn NPCs
+
+	npcTable, err := data.LoadNpcTable("data/yaml/npc_list.yaml")
+	if err != nil {
+		return fmt.Errorf("load npc table: %w", err)
+	}
+	s.stat("NPC 模板", npcTable.Count())
+
+	spawnList, err := data.LoadSpawnList("data/yaml/spawn_list.yaml")
+	if err != nil {
+		return fmt.Errorf("load spawn list: %w", err)
+	}
+
+	lightSpawnList, err := data.LoadLightSpawnList("data/yaml/light_spawn_list.yaml")
+	if err != nil {
+		return fmt.Errorf("load light spawn list: %w", err)
+	}
+	s.stat("路燈點位", len(lightSpawnList))
+
+	mapDataTable, err := data.LoadMapData("data/yaml/map_list.yaml", "map")
+	if err != nil {
+		return fmt.Errorf("load map data: %w", err)
+	}
+	s.stat("地圖資料", mapDataTable.Count())
+
+	sprTable, err := data.LoadSprTable("data/yaml/spr_action.yaml")
+	if err != nil {
+		return fmt.Errorf("load spr table: %w", err)
+	}
+	s.stat("精靈動作", sprTable.Count())
+
+	mobGroupTable, err := data.LoadMobGroupTable("data/yaml/mobgroup_list.yaml")
+	if err != nil {
+		return fmt.Errorf("load mob group: %w", err)
+	}
+	s.stat("怪物群體", mobGroupTable.Count())
+
+	npcCount := spawnNpcs(worldState, s.Rand, npcTable, spawnList, mapDataTable, sprTable, mobGroupTable, log)
+	s.stat("NPC 生成", npcCount)
+
+	npcActionTable, err := data.LoadNpcActionTable("data/yaml/npc_action_list.yaml")
+	if err != nil {
+		return fmt.Errorf("load npc actions: %w", err)
+	}
+	s.stat("NPC 動作", npcActionTable.Count())
+
+	// 5c. Load item templates and shop data
+	itemTable, err := data.LoadItemTable(
+		"data/yaml/weapon_list.yaml",
+		"data/yaml/armor_list.yaml",
+		"data/yaml/etcitem_list.yaml",
+	)
+	if err != nil {
+		return fmt.Errorf("load item table: %w", err)
+	}
+	s.stat("道具模板", itemTable.Count())
+
+	shopTable, err := data.LoadShopTable("data/yaml/shop_list.yaml")
+	if err != nil {
+		return fmt.Errorf("load shop table: %w", err)
+	}
+	s.stat("商店", shopTable.Count())
+
+	dropTable, err := data.LoadDropTable("data/yaml/drop_list.yaml")
+	if err != nil {
+		return fmt.Errorf("load drop table: %w", err)
+	}
+	s.stat("掉寶表", dropTable.Count())
+
+	teleportTable, err := data.LoadTeleportTable("data/yaml/teleport_list.yaml")
+	if err != nil {
+		return fmt.Errorf("load teleport table: %w", err)
+	}
+	s.stat("傳送點", teleportTable.Count())
+
+	teleportHtmlTable, err := data.LoadTeleportHtmlTable("data/yaml/teleport_html.yaml")
+	if err != nil {
+		return fmt.Errorf("load teleport html: %w", err)
+	}
+	s.stat("傳送選單", teleportHtmlTable.Count())
+
+	portalTable, err := data.LoadPortalTable("data/yaml/portal_list.yaml")
+	if err != nil {
+		return fmt.Errorf("load portal table: %w", err)
+	}
+	s.stat("傳送門", portalTable.Count())
+
+	randomPortalTable, err := data.LoadRandomPortalTable("data/yaml/portal_random_list.yaml")
+	if err != nil {
+		return fmt.Errorf("load random portal table: %w", err)
+	}
+	s.stat("隨機傳送門", randomPortalTable.Count())
+
+	skillTable, err := data.LoadSkillTable("data/yaml/skill_list.yaml")
+	if err != nil {
+		return fmt.Errorf("load skill table: %w", err)
+	}
+	s.stat("技能", skillTable.Count())
+
+	mobSkillTable, err := data.LoadMobSkillTable("data/yaml/mob_skill_list.yaml")
+	if err != nil {
+		return fmt.Errorf("load mob skill table: %w", err)
+	}
+	s.stat("怪物技能", mobSkillTable.Count())
+
+	polymorphTable, err := data.LoadPolymorphTable("data/yaml/polymorph_list.yaml")
+	if err != nil {
+		return fmt.Errorf("load polymorph table: %w", err)
+	}
+	s.stat("變身形態", polymorphTable.Count())
+
+	armorSetTable, err := data.LoadArmorSetTable("data/yaml/armor_set_list.yaml")
+	if err != nil {
+		return fmt.Errorf("load armor set table: %w", err)
+	}
+	s.stat("套裝定義", armorSetTable.Count())
+
+	itemMakingTable, err := data.LoadItemMakingTable("data/yaml/item_making_list.yaml")
+	if err != nil {
+		return fmt.Errorf("load item making table: %w", err)
+	}
+	s.stat("製作配方", itemMakingTable.Count())
+
+	fireCrystalTable, err := data.LoadFireCrystalTable("data/yaml/fire_crystal_list.yaml")
+	if err != nil {
+		return fmt.Errorf("load fire crystal table: %w", err)
+	}
+	s.stat("火結晶表", fireCrystalTable.Count())
+
+	spellbookReqs, err := data.LoadSpellbookReqTable("data/yaml/spellbook_level_req.yaml")
+	if err != nil {
+		return fmt.Errorf("load spellbook reqs: %w", err)
+	}
+	s.stat("魔法書需求", spellbookReqs.Count())
+
+	buffIconTable, err := data.LoadBuffIconTable("data/yaml/buff_icon_map.yaml")
+	if err != nil {
+		return fmt.Errorf("load buff icons: %w", err)
+	}
+	s.stat("Buff圖示", buffIconTable.Count())
+
+	npcServiceTable, err := data.LoadNpcServiceTable("data/yaml/npc_services.yaml")
+	if err != nil {
+		return fmt.Errorf("load npc services: %w", err)
+	}
+	s.stat("NPC服務", npcServiceTable.Count())
+
+	petTypeTable, err := data.LoadPetTypeTable("data/yaml/pet_types.yaml")
+	if err != nil {
+		return fmt.Errorf("load pet types: %w", err)
+	}
+	s.stat("寵物種類", petTypeTable.Count())
+
+	petItemTable, err := data.LoadPetItemTable("data/yaml/pet_items.yaml")
+	if err != nil {
+		return fmt.Errorf("load pet items: %w", err)
+	}
+	s.stat("寵物裝備", petItemTable.Count())
+
+	dollTable, err := data.LoadDollTable("data/yaml/dolls.yaml")
+	if err != nil {
+		return fmt.Errorf("load dolls: %w", err)
+	}
+	s.stat("魔法娃娃", dollTable.Count())
+
+	hierarchTable, err := data.LoadHierarchTable("data/yaml/hierarchs.yaml")
+	if err != nil {
+		return fmt.Errorf("load hierarchs: %w", err)
+	}
+	s.stat("隨身祭司", hierarchTable.Count())
+
+	teleportPageTable, err := data.LoadTeleportPageTable("data/yaml/npc_teleport_page.yaml")
+	if err != nil {
+		return fmt.Errorf("load teleport pages: %w", err)
+	}
+	s.stat("分頁傳送", teleportPageTable.Count())
+
+	weaponSkillTable, err := data.LoadWeaponSkillTable("data/yaml/weapon_skill.yaml")
+	if err != nil {
+		return fmt.Errorf("load weapon skills: %w", err)
+	}
+	s.stat("武器技能", weaponSkillTable.Count())
+
+	doorTable, err := data.LoadDoorTable("data/yaml/door_gfx.yaml", "data/yaml/door_spawn.yaml")
+	if err != nil {
+		return fmt.Errorf("load door table: %w", err)
+	}
+	doorCount := spawnDoors(worldState, doorTable)
+	s.stat("門", doorCount)
+
+	itemBoxTable, err := data.LoadItemBoxTable("data/yaml/item_box.yaml")
+	if err != nil {
+		return fmt.Errorf("load item box: %w", err)
+	}
+	s.stat("物品箱", itemBoxTable.Count())
+
+	itemUpgradeTable, err := data.LoadItemUpgradeTable("data/yaml/item_upgrade.yaml")
+	if err != nil {
+		return fmt.Errorf("load item upgrade: %w", err)
+	}
+	s.stat("物品升級", itemUpgradeTable.Count())
+
+	itemVIPTable, err := data.LoadItemVIPTable("data/yaml/item_vip.yaml")
+	if err != nil {
+		return fmt.Errorf("load item vip: %w", err)
+	}
+	s.stat("VIP物品", itemVIPTable.Count())
+
+	npcChatTable, err := data.LoadNpcChatTable("data/yaml/npc_chat.yaml")
+	if err != nil {
+		return fmt.Errorf("load npc chat: %w", err)
+	}
+	s.stat("NPC聊天", npcChatTable.Count())
+
+	houseTable, err := data.LoadHouseTable("data/yaml/house_list.yaml")
+	if err != nil {
+		return fmt.Errorf("load house table: %w", err)
+	}
+	s.stat("住宅", houseTable.Count())
+
+	// 旅館房間載入
+	innRooms, err := loadInnRooms(ctx, innRepo)
+	if err != nil {
+		return fmt.Errorf("load inn rooms: %w", err)
+	}
+	s.stat("旅館房間", len(innRooms))
+
+	questData, err := data.LoadQuestTable("data/yaml/quests.yaml")
+	if err != nil {
+		return fmt.Errorf("load quest table: %w", err)
+	}
+	s.stat("任務範本", questData.Count())
+
+	trapData, err := data.LoadTrapData("data/yaml")
+	if err != nil {
+		return fmt.Errorf("load trap data: %w", err)
+	}
+	s.stat("陷阱範本", len(trapData.Templates))
+	s.stat("陷阱生成點", len(trapData.Spawns))
+
+	castleTable, err := data.LoadCastleTable("data/yaml/castles.yaml")
+	if err != nil {
+		return fmt.Errorf("load castles: %w", err)
+	}
+	s.stat("城堡", castleTable.Count())
+
+	warGiftTable, err := data.LoadWarGiftTable("data/yaml/castle_war_gifts.yaml")
+	if err != nil {
+		return fmt.Errorf("load war gifts: %w", err)
+	}
+	s.stat("攻城禮物", warGiftTable.Count())
+
+	// 5d-1. 建立陷阱管理器（tile-based O(1) 查詢）
+	trapMgr := world.NewTrapManager(trapData, mapDataTable, s.Rand)
+	s.stat("陷阱實例", trapMgr.Count())
+
+	// 5b. Initialize Lua scripting engine
+	luaEngine, err := scripting.NewEngine("scripts", cfg.Lua, cfg.Network.TickRate, s.Rand, log)
+	if err != nil {
+		return fmt.Errorf("lua engine: %w", err)
+	}
+	s.Lua = luaEngine
+	s.ok("Lua 腳本載入完成")
+
+	// 5d. Load clans from DB
+	clanCount, err := loadClans(ctx, worldState, clanRepo)
+	if err != nil {
+		return fmt.Errorf("load clans: %w", err)
+	}
+	s.stat("血盟", clanCount)
+
+	// 5e. Initialize item ObjectID counter from DB to avoid collisions
+	maxObjID, err := itemRepo.MaxObjID(ctx)
+	if err != nil {
+		return fmt.Errorf("query max obj_id: %w", err)
+	}
+	if maxObjID >= 500_000_000 {
+		world.SetItemObjIDStart(maxObjID)
+	}
+
+	// 5f. Initialize emblem ID counter from DB and ensure emblem directory exists
+	maxEmblemID, err := clanRepo.MaxEmblemID(ctx)
+	if err != nil {
+		return fmt.Errorf("query max emblem_id: %w", err)
+	}
+	if maxEmblemID > 0 {
+		world.SetEmblemIDStart(maxEmblemID)
+	}
+	if err := os.MkdirAll("emblem", 0755); err != nil {
+		return fmt.Errorf("create emblem dir: %w", err)
+	}
+
+	// 6. Create packet handler registry and register handlers
+	pktReg := packet.NewRegistry(log)
+	deps := &handler.Deps{
+		AccountRepo:   accountRepo,
+		CharRepo:      charRepo,
+		ItemRepo:      itemRepo,
+		Config:        cfg,
+		Log:           log,
+		World:         worldState,
+		Scripting:     luaEngine,
+		Clock:         s.Clock,
+		Rand:          s.Rand,
+		NpcActions:    npcActionTable,
+		Items:         itemTable,
+		Shops:         shopTable,
+		Drops:         dropTable,
+		Teleports:     teleportTable,
+		TeleportHtml:  teleportHtmlTable,
+		Portals:       portalTable,
+		RandomPortals: randomPortalTable,
+		Skills:        skillTable,
+		Npcs:          npcTable,
+		MobSkills:     mobSkillTable,
+		MapData:       mapDataTable,
+		Polys:         polymorphTable,
+		ArmorSets:     armorSetTable,
+		SprTable:      sprTable,
+		WarehouseRepo: warehouseRepo,
+		WALRepo:       walRepo,
+		ItemEvents:    itemEventRepo,
+		BanRepo:       banRepo,
+		CheatFlags:    cheatFlagRepo,
+		ClanRepo:      clanRepo,
+		BuffRepo:      buffRepo,
+		Doors:         doorTable,
+		ItemMaking:    itemMakingTable,
+		FireCrystals:  fireCrystalTable,
+		SpellbookReqs: spellbookReqs,
+		BuffIcons:     buffIconTable,
+		NpcServices:   npcServiceTable,
+		QuestRepo:     questRepo,
+		BuddyRepo:     buddyRepo,
+		ExcludeRepo:   excludeRepo,
+		BoardRepo:     boardRepo,
+		MailRepo:      mailRepo,
+		PetRepo:       petRepo,
+		PetTypes:      petTypeTable,
+		PetItems:      petItemTable,
+		Dolls:         dollTable,
+		Hierarchs:     hierarchTable,
+		TeleportPages: teleportPageTable,
+		WeaponSkills:  weaponSkillTable,
+		ItemBoxes:     itemBoxTable,
+		ItemUpgrades:  itemUpgradeTable,
+		ItemVIPs:      itemVIPTable,
+		NpcChats:      npcChatTable,
+		MobGroups:     mobGroupTable,
+		Houses:        houseTable,
+		HouseRepo:     houseRepo,
+		InnRepo:       innRepo,
+		InnRooms:      innRooms,
+		QuestData:     questData,
+		ClanMatching:  handler.NewClanMatchingManager(),
+		Alliances:     handler.NewAllianceManager(),
+		TrapMgr:       trapMgr,
+		Castles:       castleTable,
+		WarGifts:      warGiftTable,
+		CastleRepo:    castleRepo,
+	}
+	// 非同步資料庫工作佇列：登入、角色列表、倉庫、信件、佈告欄的 DB 存取不阻塞遊戲迴圈
+	dbJobs := s.DBJobs
+	deps.DBJobs = dbJobs
+	handler.RegisterAll(pktReg, deps)
+	handler.SetShowNpcID(cfg.Debug.ShowNpcID)
+
+	// 7. Login rate limit (packet rate and per-IP limits are applied by the net server)
+	if cfg.RateLimit.Enabled && cfg.RateLimit.LoginAttemptsPerMinute > 0 {
+		deps.LoginLimit = handler.NewLoginLimiter(cfg.RateLimit.LoginAttemptsPerMinute,
+			time.Duration(cfg.RateLimit.LoginLockoutSeconds)*time.Second)
+	}
+
+	// 8. Create event bus, session store, and systems
+	eventBus := event.NewBus()
+	sessStore := gonet.NewSessionStore()
+	deps.Sessions = sessStore
+	runner := coresys.NewRunner()
+	// 慢 tick 監視：單次 tick 超過 tick_rate 時記錄耗時最高的系統
+	runner.SetWatchdog(cfg.Network.TickRate, log)
+	deps.Runner = runner
+	// Phase 0: Input — 註冊到 Runner，並由 inputPoll 以 2ms 頻率高頻驅動
+	// （透過 Runner.TickPhase 在系統 tick 之間只跑 Phase 0，消除 0~200ms 的輸入延遲）
+	inputSys := system.NewInputSystem(s.Net, pktReg, sessStore, cfg.Network.MaxPacketsPerTick, accountRepo, charRepo, itemRepo, buffRepo, worldState, mapDataTable, petRepo, log)
+	runner.Register(inputSys)
+	// Phase 1: Event dispatch (double-buffer swap + deliver previous tick's events)
+	eventDispatch := system.NewEventDispatchSystem(eventBus)
+	runner.Register(eventDispatch)
+	// Phase 1: 資料庫工作完成回呼（在遊戲迴圈上套用 worker 的查詢結果）
+	runner.Register(system.NewDBJobSystem(dbJobs))
+	// Phase 1: 卷軸延遲傳送（特效後延遲 1 tick 執行傳送）
+	runner.Register(system.NewScrollTeleportSystem(worldState, deps))
+	// Phase 1: 製作交易視窗延遲物品發送（S_Trade 後 1 tick 發送 S_TradeAddItem）
+	runner.Register(system.NewCraftTradeSystem(worldState, deps))
+	// Wire event bus into handler deps (for EntityKilled emission, etc.)
+	deps.Bus = eventBus
+	// Subscribe to game events (proves event bus pipeline end-to-end)
+	event.Subscribe(eventBus, func(ev event.EntityKilled) {
+		log.Debug("event: EntityKilled",
+			zap.Uint64("killer_session", ev.KillerSessionID),
+			zap.Int32("npc_template", ev.NpcTemplateID),
+			zap.Int32("exp", ev.ExpGained),
+		)
+		// Lua 事件腳本（scripts/world/events.lua 的 on_npc_killed，未定義則略過）
+		luaEngine.OnNpcKilled(scripting.NpcKilledContext{
+			NpcTemplateID: int(ev.NpcTemplateID),
+			NpcObjID:      int(ev.NpcID),
+			KillerCharID:  int(ev.KillerCharID),
+			MapID:         int(ev.MapID),
+			X:             int(ev.X),
+			Y:             int(ev.Y),
+		})
+	})
+	event.Subscribe(eventBus, func(ev event.PlayerDied) {
+		log.Debug("event: PlayerDied",
+			zap.Int32("char_id", ev.CharID),
+			zap.Int16("map", ev.MapID),
+		)
+	})
+	event.Subscribe(eventBus, func(ev event.PlayerKilled) {
+		log.Info("event: PlayerKilled (PK)",
+			zap.Int32("killer", ev.KillerCharID),
+			zap.Int32("victim", ev.VictimCharID),
+			zap.Int16("map", ev.MapID),
+		)
+	})
+
+	// 交易系統（直接呼叫，非 Phase 系統）
+	deps.Trade = system.NewTradeSystem(deps)
+	// 隊伍系統（直接呼叫，非 Phase 系統）
+	deps.Party = system.NewPartySystem(deps)
+	// 血盟系統（直接呼叫，非 Phase 系統）
+	deps.Clan = system.NewClanSystem(deps)
+	// 裝備系統（直接呼叫，非 Phase 系統）
+	deps.Equip = system.NewEquipSystem(deps)
+	// 物品使用系統（直接呼叫，非 Phase 系統）
+	deps.ItemUse = system.NewItemUseSystem(deps)
+	// 信件系統（直接呼叫，非 Phase 系統）
+	deps.Mail = system.NewMailSystem(deps)
+	// 商店系統（直接呼叫，非 Phase 系統）
+	deps.Shop = system.NewShopSystem(deps)
+	// 製作系統（直接呼叫，非 Phase 系統）
+	deps.Craft = system.NewCraftSystem(deps)
+	// 物品地面操作系統（銷毀、掉落、撿取）
+	deps.ItemGround = system.NewItemGroundSystem(deps)
+	// 寵物生命週期系統（召喚/收回/解放/死亡/經驗/指令）
+	deps.PetLife = system.NewPetSystem(deps)
+	// 魔法娃娃系統（召喚/解散/屬性加成）
+	deps.DollMgr = system.NewDollSystem(deps)
+	// 隨身祭司系統（召喚/解散/自動增益）
+	deps.HierarchMgr = system.NewHierarchSystem(deps)
+	// 寵物比賽系統（報名/比賽/獎勵）
+	deps.PetMatch = system.NewPetMatchSystem(deps)
+	// 任務動作系統（直接呼叫，非 Phase 系統）
+	deps.Quest = system.NewQuestSystem(deps)
+	// Lua `game` 模組（腳本存取世界狀態：訊息、物品、生成 NPC、傳送、任務步驟）
+	luaEngine.SetGameAPI(system.NewScriptGameAPI(deps))
+	// 陷阱觸發系統（直接呼叫，非 Phase 系統）
+	deps.Trap = system.NewTrapSystem(deps)
+	// 倉庫系統（直接呼叫，非 Phase 系統）
+	deps.Warehouse = system.NewWarehouseSystem(deps)
+	// PvP 系統（直接呼叫，非 Phase 系統）
+	deps.PvP = system.NewPvPSystem(deps)
+	// 天寶幣商城系統（直接呼叫，非 Phase 系統）
+	deps.ShopCnMgr = system.NewShopCnSystem(deps)
+	// 強化物品購買系統（直接呼叫，非 Phase 系統）
+	deps.PowerItemMgr = system.NewPowerItemSystem(deps)
+	// 魔法商店系統（直接呼叫，非 Phase 系統）
+	deps.SpellShopMgr = system.NewSpellShopSystem(deps)
+	// GM 命令系統（直接呼叫，非 Phase 系統）
+	deps.GMCmd = system.NewGMCommandSystem(deps)
+	// 個人商店交易系統（直接呼叫，非 Phase 系統）
+	deps.PrivShop = system.NewPrivateShopSystem(deps)
+	// 城堡管理系統（直接呼叫，非 Phase 系統）
+	castleSys := system.NewCastleSystem(deps)
+	deps.Castle = castleSys
+	// 啟動時生成所有城堡的投石車（Java: ServerWarExecutor 啟動後生成）
+	for _, c := range castleTable.All() {
+		if len(c.Catapults) > 0 {
+			castleSys.SpawnCatapults(c.ID)
+		}
+	}
+	// 戰爭系統（直接呼叫，非 Phase 系統）
+	deps.War = system.NewWarSystem(deps)
+
+	// Phase 2: Game logic
+	combatSys := system.NewCombatSystem(deps)
+	deps.Combat = combatSys
+	runner.Register(combatSys)
+	skillSys := system.NewSkillSystem(deps)
+	deps.Skill = skillSys
+	runner.Register(skillSys)
+	deathSys := system.NewDeathSystem(deps)
+	deps.Death = deathSys
+	polySys := system.NewPolymorphSystem(deps)
+	deps.Polymorph = polySys
+	npcSvcSys := system.NewNpcServiceSystem(deps)
+	deps.NpcSvc = npcSvcSys
+	charResetSys := system.NewCharResetSystem(deps)
+	deps.CharReset = charResetSys
+	statAllocSys := system.NewStatAllocSystem(deps)
+	deps.StatAlloc = statAllocSys
+	marriageSys := system.NewMarriageSystem(deps)
+	deps.Marriage = marriageSys
+	innSys := system.NewInnSystem(deps)
+	deps.Inn = innSys
+	summonSys := system.NewSummonSystem(deps)
+	deps.Summon = summonSys
+	runner.Register(system.NewBuffTickSystem(worldState, deps))
+	runner.Register(system.NewNpcRespawnSystem(worldState, mapDataTable, deps))
+	runner.Register(system.NewNpcAISystem(worldState, deps))
+	runner.Register(system.NewCompanionAISystem(worldState, deps))
+	// Phase 3: Post-update
+	runner.Register(system.NewRegenSystem(worldState, luaEngine, houseTable, cfg))
+	runner.Register(system.NewWeatherSystem(worldState, deps))
+	runner.Register(system.NewLightSpawnSystem(worldState, lightSpawnList, npcTable, deps))
+	mapTimerSys := system.NewMapTimerSystem(worldState, deps)
+	deps.MapTimer = mapTimerSys
+	runner.Register(mapTimerSys)
+	hauntedHouseSys := system.NewHauntedHouseSystem(worldState, deps)
+	deps.HauntedHouse = hauntedHouseSys
+	inputSys.SetHauntedHouse(hauntedHouseSys)
+	runner.Register(hauntedHouseSys)
+	dragonDoorSys := system.NewDragonDoorSystem(worldState, deps)
+	deps.DragonDoor = dragonDoorSys
+	runner.Register(dragonDoorSys)
+	runner.Register(system.NewNpcChatSystem(worldState, deps))
+	runner.Register(system.NewGroundItemSystem(worldState, deps))
+	runner.Register(system.NewPartyRefreshSystem(worldState, deps, 10)) // 10 ticks = 2 seconds
+	rankingSys := system.NewRankingSystem(worldState, deps)
+	deps.Ranking = rankingSys
+	runner.Register(rankingSys)
+	auctionSys := system.NewAuctionSystem(worldState, deps, auctionRepo)
+	deps.Auction = auctionSys
+	runner.Register(auctionSys)
+	deps.Fishing = system.NewFishingSystem(deps)
+	runner.Register(system.NewTrapRespawnSystem(trapMgr, s.Clock))
+	runner.Register(system.NewCastleWarTickSystem(deps.Castle))
+	runner.Register(system.NewVisibilitySystem(worldState, deps))
+	// Phase 4: Output — flush buffered packets to TCP
+	runner.Register(system.NewOutputSystem(sessStore))
+	// Phase 5: Persistence (auto-save interval from config)
+	persistSys := system.NewPersistenceSystem(worldState, charRepo, itemRepo, buffRepo, walRepo, log, cfg.Persistence.BatchIntervalTicks)
+	runner.Register(persistSys)
+	// Phase 5: 複製物品偵測（定期掃描 + 自動存檔前檢查）
+	if cfg.AntiCheat.DuplicateItemCheck {
+		dupeCheck := system.NewDupeCheckSystem(deps, stores.ItemDupes, cfg.AntiCheat.DuplicateScanTicks)
+		runner.Register(dupeCheck)
+		persistSys.SetDupeCheck(dupeCheck)
+	}
+	// Phase 5: 動作頻率反作弊（移動加速），標記批次寫入 cheat_flags
+	antiCheat := system.NewAntiCheatSystem(deps, cheatFlagRepo, cfg.AntiCheat)
+	deps.AntiCheat = antiCheat
+	runner.Register(antiCheat)
+	// Phase 5: 物品來源稽核事件批次寫入 item_events
+	itemAudit := system.NewItemAuditSystem(eventBus, itemEventRepo, dbJobs, log)
+	runner.Register(itemAudit)
+	// Phase 6: Cleanup
+	runner.Register(system.NewCleanupSystem(ecsWorld))
+	runner.Register(system.NewLuaBudgetSystem(luaEngine))
+	runner.Register(system.NewMetricsSystem(sessStore, worldState, runner))
+
+	s.World = worldState
+	s.Deps = deps
+	s.Runner = runner
+	s.Sessions = sessStore
+	s.Persist = persistSys
+	s.eventDispatch = eventDispatch
+	s.itemAudit = itemAudit
+	return nil
+}
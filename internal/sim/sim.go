@@ -0,0 +1,373 @@
+// Package sim 是無頭的行程內模擬環境：以 internal/server 組裝與正式伺服器相同的
+// Runner 與所有 System，搭配記憶體資料庫與同步的資料庫工作佇列；客戶端以
+// net.Pipe 連線，使用正式的 Cipher 與封包編碼。世界由測試手動 Tick，每次 Tick
+// 前等待客戶端送出的封包全部進入佇列，Tick 後等待伺服器送出的封包全部被客戶端
+// 收到，因此 go test 可以對收到的封包與 world.State 做確定性的斷言。
+//
+// 遊戲邏輯的時間來源是模擬時鐘（從 Start 開始，每次 Tick 推進一個 tick_rate），
+// 亂數種子固定為 Seed，同一組輸入會得到相同的戰鬥、掉落與生成結果。
+//
+// 資料表與腳本以模組根目錄為基準讀取，New 會切換工作目錄到模組根目錄。
+package sim
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/l1jgo/server/internal/config"
+	"github.com/l1jgo/server/internal/core/clock"
+	gonet "github.com/l1jgo/server/internal/net"
+	"github.com/l1jgo/server/internal/net/packet"
+	"github.com/l1jgo/server/internal/persist"
+	"github.com/l1jgo/server/internal/persist/memdb"
+	"github.com/l1jgo/server/internal/server"
+	"github.com/l1jgo/server/internal/testclient"
+	"github.com/l1jgo/server/internal/world"
+	"go.uber.org/zap"
+)
+
+// syncTimeout 是等待封包在 pipe 兩端完成傳遞的上限；逾時表示伺服器或客戶端卡住，
+// 測試失敗而不是無限等待。
+const syncTimeout = 5 * time.Second
+
+// Start 是模擬時鐘的起點（遊戲時間正午）；Seed 是預設的遊戲亂數種子。
+var Start = time.Date(2025, time.June, 1, 14, 0, 0, 0, time.UTC)
+
+const Seed = 1
+
+// Sim 是一個行程內伺服器與其連線中的模擬客戶端。只能由測試 goroutine 使用
+// （它就是遊戲迴圈）。
+type Sim struct {
+	T      testing.TB
+	Server *server.Server
+	World  *world.State
+	Clock  *clock.Sim
+
+	clients  []*Client
+	nextPort int
+}
+
+// New 組裝行程內伺服器，並在測試結束時關閉所有客戶端與伺服器。
+// configure 可在組裝前調整設定（nil = 使用 config/server.toml 加上模擬預設值，
+// 亂數種子為 Seed）。
+func New(t testing.TB, configure func(cfg *config.Config)) *Sim {
+	t.Helper()
+	root, err := moduleRoot()
+	if err != nil {
+		t.Fatalf("sim: %v", err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("sim: %v", err)
+	}
+
+	cfg, err := config.Load(filepath.Join(root, "config", "server.toml"))
+	if err != nil {
+		t.Fatalf("sim: %v", err)
+	}
+	cfg.Database.Driver = "memory"
+	cfg.Character.AutoCreateAccounts = true
+	cfg.RateLimit.Enabled = false
+	cfg.Metrics.Enabled = false
+	cfg.Admin.Enabled = false
+	cfg.Debug.RNGSeed = Seed
+	if configure != nil {
+		configure(cfg)
+	}
+	packet.InitEncoding(cfg.Character.ClientLanguageCode)
+
+	log := zap.NewNop()
+	clk := clock.NewSim(Start)
+	mem, err := memdb.New()
+	if err != nil {
+		t.Fatalf("sim: memory database: %v", err)
+	}
+	srv, err := server.New(server.Options{
+		Config: cfg,
+		Stores: mem.Stores(),
+		Net:    gonet.NewLocalServer(cfg.Network.InQueueSize, cfg.Network.OutQueueSize, 0, 0, log),
+		DBJobs: persist.NewInlineJobQueue(5*time.Second, log),
+		Clock:  clk,
+		Log:    log,
+	})
+	if err != nil {
+		t.Fatalf("sim: build server: %v", err)
+	}
+
+	s := &Sim{T: t, Server: srv, World: srv.World, Clock: clk, nextPort: 40000}
+	t.Cleanup(s.close)
+	return s
+}
+
+// moduleRoot 從工作目錄往上尋找 go.mod。
+func moduleRoot() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("go.mod not found")
+		}
+		dir = parent
+	}
+}
+
+func (s *Sim) close() {
+	for _, c := range s.clients {
+		c.Close()
+	}
+	s.Server.Shutdown()
+}
+
+// Tick 把模擬時鐘推進一個 tick_rate 後執行一次完整 tick（Phase 0-6）：先等待
+// 所有客戶端已送出的封包進入伺服器輸入佇列，tick 後等待伺服器本 tick 送出的
+// 封包全部被客戶端收到。
+func (s *Sim) Tick() {
+	s.T.Helper()
+	for _, c := range s.clients {
+		c.awaitQueued()
+	}
+	s.Clock.Advance(s.Server.Config.Network.TickRate)
+	s.Server.Tick()
+	for _, c := range s.clients {
+		c.awaitReceived()
+		c.queuedBase = len(c.Sess.InQueue)
+		c.sentBase = c.conn.frames.Load()
+	}
+}
+
+// TickN 執行 n 次 Tick。
+func (s *Sim) TickN(n int) {
+	s.T.Helper()
+	for i := 0; i < n; i++ {
+		s.Tick()
+	}
+}
+
+// Advance 推進模擬時鐘而不執行 tick（例如跳過冷卻、等待拍賣截止或攻城時間）。
+// 以時間計算的邏輯在下一次 Tick 看到新的時間。
+func (s *Sim) Advance(d time.Duration) {
+	s.Clock.Advance(d)
+}
+
+// Connect 以 net.Pipe 建立新連線並完成握手；連線在下一次 Tick 由 InputSystem 接收。
+func (s *Sim) Connect(label string) *Client {
+	s.T.Helper()
+	srvConn, cliConn := net.Pipe()
+	s.nextPort++
+	counted := &countingConn{Conn: cliConn}
+	c := &Client{
+		Client: testclient.New(counted, label),
+		sim:    s,
+		conn:   counted,
+	}
+
+	// ServeConn 同步寫出初始封包，必須與客戶端握手同時進行
+	served := make(chan *gonet.Session, 1)
+	go func() {
+		served <- s.Server.Net.ServeConn(&pipeConn{
+			Conn:   srvConn,
+			remote: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: s.nextPort},
+		})
+	}()
+	if err := c.Handshake(syncTimeout); err != nil {
+		s.T.Fatalf("sim: %s: %v", label, err)
+	}
+	c.Sess = <-served
+	if c.Sess == nil {
+		s.T.Fatalf("sim: %s: connection rejected", label)
+	}
+
+	go c.readLoop()
+	s.clients = append(s.clients, c)
+	return c
+}
+
+// Player 回傳在線角色，不存在時測試失敗。
+func (s *Sim) Player(name string) *world.PlayerInfo {
+	s.T.Helper()
+	p := s.World.GetByName(name)
+	if p == nil {
+		s.T.Fatalf("sim: player %q not in world", name)
+	}
+	return p
+}
+
+// pipeConn 讓 net.Pipe 的伺服器端回報 TCP 位址：登入時的封鎖檢查與每 IP 計數
+// 需要可解析的 IP。
+type pipeConn struct {
+	net.Conn
+	remote net.Addr
+}
+
+func (c *pipeConn) RemoteAddr() net.Addr { return c.remote }
+
+// countingConn 計算客戶端寫出的框架數（testclient 每個框架只呼叫一次 Write）。
+type countingConn struct {
+	net.Conn
+	frames atomic.Uint64
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if err == nil {
+		c.frames.Add(1)
+	}
+	return n, err
+}
+
+// Client 是連線到 Sim 的模擬客戶端。封包發送沿用 testclient.Client 的方法；
+// 收到的封包由背景 goroutine 解密後放入收件匣。
+type Client struct {
+	*testclient.Client
+	Sess *gonet.Session
+
+	sim  *Sim
+	conn *countingConn
+
+	// Tick 結束時的輸入佇列長度與已送出框架數；兩次 Tick 之間沒有人取出佇列，
+	// 因此下一次 Tick 前佇列長度應達到 queuedBase + (已送出 - sentBase)。
+	queuedBase int
+	sentBase   uint64
+
+	mu       sync.Mutex
+	inbox    []*testclient.Packet
+	received uint64
+	readErr  error
+}
+
+func (c *Client) readLoop() {
+	for {
+		p, err := c.ReadPacket(0)
+		c.mu.Lock()
+		if err != nil {
+			c.readErr = err
+			c.mu.Unlock()
+			return
+		}
+		c.inbox = append(c.inbox, p)
+		c.received++
+		c.mu.Unlock()
+	}
+}
+
+// awaitQueued 等待客戶端送出的封包全部進入伺服器輸入佇列。
+func (c *Client) awaitQueued() {
+	c.sim.T.Helper()
+	want := c.queuedBase + int(c.conn.frames.Load()-c.sentBase)
+	c.waitFor(func() bool { return len(c.Sess.InQueue) >= want || c.Sess.IsClosed() },
+		"server to queue %d packets (queued %d)", want, len(c.Sess.InQueue))
+}
+
+// awaitReceived 等待伺服器已送出的封包全部被客戶端收到。
+func (c *Client) awaitReceived() {
+	c.sim.T.Helper()
+	want := c.Sess.Flushed()
+	c.waitFor(func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return c.received >= want || c.readErr != nil
+	}, "client to receive %d packets", want)
+}
+
+func (c *Client) waitFor(done func() bool, format string, args ...any) {
+	c.sim.T.Helper()
+	deadline := time.Now().Add(syncTimeout)
+	for !done() {
+		if time.Now().After(deadline) {
+			c.sim.T.Fatalf("sim: %s: timed out waiting for "+format, append([]any{c.Label}, args...)...)
+		}
+		time.Sleep(100 * time.Microsecond)
+	}
+}
+
+// Packets 回傳收件匣中的所有封包（不移除）。
+func (c *Client) Packets() []*testclient.Packet {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*testclient.Packet(nil), c.inbox...)
+}
+
+// Clear 清空收件匣。
+func (c *Client) Clear() {
+	c.mu.Lock()
+	c.inbox = nil
+	c.mu.Unlock()
+}
+
+// Take 取出收件匣中所有指定 opcode 的封包，依收到順序。
+func (c *Client) Take(opcode byte) []*testclient.Packet {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var taken []*testclient.Packet
+	kept := c.inbox[:0]
+	for _, p := range c.inbox {
+		if p.Opcode() == opcode {
+			taken = append(taken, p)
+		} else {
+			kept = append(kept, p)
+		}
+	}
+	c.inbox = kept
+	return taken
+}
+
+// Expect 取出收件匣中第一個指定 opcode 的封包，沒有時測試失敗。
+func (c *Client) Expect(opcode byte) *testclient.Packet {
+	c.sim.T.Helper()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, p := range c.inbox {
+		if p.Opcode() == opcode {
+			c.inbox = append(c.inbox[:i], c.inbox[i+1:]...)
+			return p
+		}
+	}
+	c.sim.T.Fatalf("sim: %s: no packet with opcode %d, inbox: %s", c.Label, opcode, c.formatInbox())
+	return nil
+}
+
+// Await 持續 Tick（最多 maxTicks 次）直到收件匣出現指定 opcode 的封包並取出。
+func (c *Client) Await(opcode byte, maxTicks int) *testclient.Packet {
+	c.sim.T.Helper()
+	for i := 0; i < maxTicks; i++ {
+		c.sim.Tick()
+		if c.peek(opcode) != nil {
+			return c.Expect(opcode)
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sim.T.Fatalf("sim: %s: no packet with opcode %d after %d ticks, inbox: %s", c.Label, opcode, maxTicks, c.formatInbox())
+	return nil
+}
+
+func (c *Client) peek(opcode byte) *testclient.Packet {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, p := range c.inbox {
+		if p.Opcode() == opcode {
+			return p
+		}
+	}
+	return nil
+}
+
+// formatInbox 格式化收件匣供失敗訊息使用。呼叫端需持有 c.mu。
+func (c *Client) formatInbox() string {
+	byOp := make(map[byte][]*testclient.Packet)
+	for _, p := range c.inbox {
+		byOp[p.Opcode()] = append(byOp[p.Opcode()], p)
+	}
+	return testclient.FormatOpcodes(byOp)
+}
@@ -0,0 +1,500 @@
+package sim
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/l1jgo/server/internal/config"
+	gonet "github.com/l1jgo/server/internal/net"
+	"github.com/l1jgo/server/internal/net/packet"
+	"github.com/l1jgo/server/internal/persist"
+	"github.com/l1jgo/server/internal/testclient"
+	"github.com/l1jgo/server/internal/world"
+)
+
+// knight 是合法的騎士配點（基礎 16/12/14/9/12/8，共 75 點）。
+var knight = testclient.Stats{Str: 20, Dex: 12, Con: 14, Wis: 9, Cha: 12, Int: 8}
+
+// login 連線、交換版本並登入（帳號不存在時自動建立），回傳角色數量。
+func login(t *testing.T, s *Sim, account string) (*Client, byte) {
+	t.Helper()
+	c := s.Connect(account)
+	if err := c.Version(); err != nil {
+		t.Fatal(err)
+	}
+	c.Await(packet.S_OPCODE_VERSION_CHECK, 3)
+	if err := c.Login(account, "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if reason := c.Await(packet.S_OPCODE_LOGIN_CHECK, 5).NewReader().ReadH(); reason != 0 {
+		t.Fatalf("%s: login rejected, reason %d", account, reason)
+	}
+	return c, c.Await(packet.S_OPCODE_NUM_CHARACTER, 5).NewReader().ReadC()
+}
+
+// enter 以新帳號建立騎士並進入世界，回傳客戶端與線上角色。
+func enter(t *testing.T, s *Sim, account, name string) (*Client, *world.PlayerInfo) {
+	t.Helper()
+	c, _ := login(t, s, account)
+	if err := c.CreateChar(name, 1, 0, knight); err != nil {
+		t.Fatal(err)
+	}
+	if result := c.Await(packet.S_OPCODE_CREATE_CHARACTER_CHECK, 5).NewReader().ReadC(); result != 0x02 {
+		t.Fatalf("%s: create character failed, result %d", name, result)
+	}
+	if err := c.EnterWorld(name); err != nil {
+		t.Fatal(err)
+	}
+	c.Await(packet.S_OPCODE_ENTER_WORLD_CHECK, 5)
+	s.Tick()
+	c.Clear()
+	return c, s.Player(name)
+}
+
+// gm 把角色設為 GM 並執行指令。
+func gm(t *testing.T, s *Sim, c *Client, p *world.PlayerInfo, cmd string) {
+	t.Helper()
+	p.AccessLevel = 200
+	if err := c.Chat(cmd); err != nil {
+		t.Fatal(err)
+	}
+	s.Tick()
+}
+
+func itemCount(p *world.PlayerInfo, itemID int32) int32 {
+	var n int32
+	for _, it := range p.Inv.Items {
+		if it.ItemID == itemID {
+			n += it.Count
+		}
+	}
+	return n
+}
+
+func TestLoginCreateEnterWorld(t *testing.T) {
+	s := New(t, nil)
+	c, count := login(t, s, "simlogin")
+	if count != 0 {
+		t.Fatalf("new account has %d characters", count)
+	}
+
+	if err := c.CreateChar("SimKnight", 1, 0, knight); err != nil {
+		t.Fatal(err)
+	}
+	if result := c.Await(packet.S_OPCODE_CREATE_CHARACTER_CHECK, 5).NewReader().ReadC(); result != 0x02 {
+		t.Fatalf("create character result %d", result)
+	}
+	if name := c.Expect(packet.S_OPCODE_NEW_CHAR_INFO).NewReader().ReadS(); name != "SimKnight" {
+		t.Fatalf("S_NEW_CHAR_INFO name %q", name)
+	}
+
+	if err := c.EnterWorld("SimKnight"); err != nil {
+		t.Fatal(err)
+	}
+	c.Await(packet.S_OPCODE_ENTER_WORLD_CHECK, 5)
+	c.Expect(packet.S_OPCODE_STATUS)
+	mapID := c.Expect(packet.S_OPCODE_WORLD).NewReader().ReadH()
+
+	p := s.Player("SimKnight")
+	if p.Session != c.Sess {
+		t.Fatal("player is not bound to the client session")
+	}
+	if int16(mapID) != p.MapID {
+		t.Fatalf("S_WORLD map %d, player on map %d", mapID, p.MapID)
+	}
+	if p.ClassType != 1 || p.Str != int16(knight.Str) {
+		t.Fatalf("class %d str %d", p.ClassType, p.Str)
+	}
+	var self bool
+	for _, pk := range c.Take(packet.S_OPCODE_PUT_OBJECT) {
+		r := pk.NewReader()
+		x, y, id := r.ReadH(), r.ReadH(), r.ReadD()
+		if id == p.CharID && int32(x) == p.X && int32(y) == p.Y {
+			self = true
+		}
+	}
+	if !self {
+		t.Fatal("no S_PUT_OBJECT for the player at its position")
+	}
+}
+
+func TestMoveSeenByNearby(t *testing.T) {
+	s := New(t, nil)
+	a, pa := enter(t, s, "simmove1", "SimWalker")
+	b, _ := enter(t, s, "simmove2", "SimWatcher")
+	b.Clear()
+
+	x, y := pa.X, pa.Y
+	if err := a.Move(4); err != nil { // 南
+		t.Fatal(err)
+	}
+	s.Tick()
+
+	if pa.X != x || pa.Y != y+1 || pa.Heading != 4 {
+		t.Fatalf("after moving south from (%d,%d): (%d,%d) heading %d", x, y, pa.X, pa.Y, pa.Heading)
+	}
+	r := b.Expect(packet.S_OPCODE_MOVE_OBJECT).NewReader()
+	if id := r.ReadD(); id != pa.CharID {
+		t.Fatalf("S_MOVE_OBJECT for object %d, want %d", id, pa.CharID)
+	}
+}
+
+func TestMoveBurstDropped(t *testing.T) {
+	s := New(t, nil)
+	c, p := enter(t, s, "simburst", "SimSprinter")
+
+	// 同一 tick 送出 8 步：只有第一步生效，其餘低於單步下限而靜默丟棄（不回彈）
+	x, y := p.X, p.Y
+	for i := 0; i < 8; i++ {
+		if err := c.Move(4); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.Tick()
+	if p.X != x || p.Y != y+1 {
+		t.Fatalf("burst of 8 steps from (%d,%d) ended at (%d,%d), want one step", x, y, p.X, p.Y)
+	}
+
+	s.Advance(time.Second)
+	if err := c.Move(4); err != nil {
+		t.Fatal(err)
+	}
+	s.Tick()
+	if p.Y != y+2 {
+		t.Fatalf("step after pause: y=%d, want %d", p.Y, y+2)
+	}
+}
+
+func TestAttackSpawnedNpc(t *testing.T) {
+	s := New(t, nil)
+	c, p := enter(t, s, "simfight", "SimFighter")
+
+	const anemone = 45006 // 海葵：不會移動
+	gm(t, s, c, p, ".spawn 45006")
+	var npc *world.NpcInfo
+	for _, pk := range c.Take(packet.S_OPCODE_PUT_OBJECT) {
+		r := pk.NewReader()
+		r.ReadH()
+		r.ReadH()
+		if n := s.World.GetNpc(r.ReadD()); n != nil && n.NpcID == anemone {
+			npc = n
+		}
+	}
+	if npc == nil {
+		t.Fatal("spawned NPC not shown to the player")
+	}
+	c.Clear()
+
+	if err := c.Attack(npc.ID); err != nil {
+		t.Fatal(err)
+	}
+	s.Tick()
+
+	r := c.Expect(packet.S_OPCODE_ATTACK).NewReader()
+	r.ReadC() // action
+	attacker, target, damage := r.ReadD(), r.ReadD(), int32(r.ReadH())
+	if attacker != p.CharID || target != npc.ID {
+		t.Fatalf("S_ATTACK %d -> %d, want %d -> %d", attacker, target, p.CharID, npc.ID)
+	}
+	if want := max(npc.MaxHP-damage, 0); npc.HP != want {
+		t.Fatalf("NPC HP %d after %d damage, want %d", npc.HP, damage, want)
+	}
+}
+
+// trade 讓兩個角色面對面，由 A 把一件物品交給 B，雙方確認後完成交易。
+func trade(t *testing.T, s *Sim, a *Client, pa *world.PlayerInfo, b *Client, pb *world.PlayerInfo, objID int32) {
+	t.Helper()
+	// 面對面：A 在 (x, y) 朝南，B 在 (x, y+1) 朝北
+	gm(t, s, a, pa, ".move 32630 32743 4")
+	gm(t, s, b, pb, ".move 32630 32746 4")
+	if err := a.Move(4); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Move(0); err != nil {
+		t.Fatal(err)
+	}
+	s.Tick()
+	if pa.Y+1 != pb.Y || pa.Heading != 4 || pb.Heading != 0 {
+		t.Fatalf("not face to face: A (%d,%d) h%d, B (%d,%d) h%d", pa.X, pa.Y, pa.Heading, pb.X, pb.Y, pb.Heading)
+	}
+	a.Clear()
+	b.Clear()
+
+	if err := a.AskTrade(); err != nil {
+		t.Fatal(err)
+	}
+	s.Tick()
+	yn := b.Expect(packet.S_OPCODE_YES_NO).NewReader()
+	yn.ReadH()
+	yn.ReadD() // counter
+	if msgType, from := yn.ReadH(), yn.ReadS(); msgType != 252 || from != pa.Name {
+		t.Fatalf("S_YES_NO type %d from %q", msgType, from)
+	}
+	if err := b.Answer(252, true); err != nil {
+		t.Fatal(err)
+	}
+	s.Tick()
+	if name := a.Expect(packet.S_OPCODE_TRADE).NewReader().ReadS(); name != pb.Name {
+		t.Fatalf("seller's trade window partner %q", name)
+	}
+	if name := b.Expect(packet.S_OPCODE_TRADE).NewReader().ReadS(); name != pa.Name {
+		t.Fatalf("buyer's trade window partner %q", name)
+	}
+
+	if err := a.AddTrade(objID, 1); err != nil {
+		t.Fatal(err)
+	}
+	s.Tick()
+	if panel := b.Expect(packet.S_OPCODE_TRADEADDITEM).NewReader().ReadC(); panel != 1 {
+		t.Fatalf("buyer sees item on panel %d", panel)
+	}
+
+	if err := a.AcceptTrade(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.AcceptTrade(); err != nil {
+		t.Fatal(err)
+	}
+	s.Tick()
+	for _, c := range []*Client{a, b} {
+		if status := c.Expect(packet.S_OPCODE_TRADESTATUS).NewReader().ReadC(); status != 0 {
+			t.Fatalf("%s: trade status %d", c.Label, status)
+		}
+	}
+}
+
+func TestTrade(t *testing.T) {
+	s := New(t, nil)
+	a, pa := enter(t, s, "simtrade1", "SimSeller")
+	b, pb := enter(t, s, "simtrade2", "SimBuyer")
+
+	const lamp = 40001
+	gm(t, s, a, pa, ".item 40001 1")
+	objID := a.Expect(packet.S_OPCODE_ADD_ITEM).NewReader().ReadD()
+	before := itemCount(pb, lamp)
+
+	trade(t, s, a, pa, b, pb, objID)
+
+	if pa.Inv.FindByObjectID(objID) != nil {
+		t.Fatal("seller still holds the traded item")
+	}
+	if got := itemCount(pb, lamp); got != before+1 {
+		t.Fatalf("buyer holds %d lamps, want %d", got, before+1)
+	}
+	if pa.TradePartnerID != 0 || pb.TradePartnerID != 0 {
+		t.Fatal("trade state not cleared")
+	}
+}
+
+// TestInventorySaveIncremental 以記憶體資料庫驗證背包存檔：登入後第一次存檔寫入全部物品
+// 並刪除角色其他的列，之後只寫入新增與變更的物品、依 obj_id 刪除交易 / 存倉 / 銷毀的物品，
+// 交易得到的物品接手原本屬於對方的列。
+func TestInventorySaveIncremental(t *testing.T) {
+	s := New(t, nil)
+	a, pa := enter(t, s, "siminv1", "SimKeeper")
+	b, pb := enter(t, s, "siminv2", "SimTaker")
+	repo := s.Server.Stores.Items
+	ctx := context.Background()
+
+	save := func(p *world.PlayerInfo) persist.InventorySaveStats {
+		t.Helper()
+		st, err := repo.SaveInventory(ctx, p.CharID, p.Inv, &p.Equip)
+		if err != nil {
+			t.Fatalf("%s: save inventory: %v", p.Name, err)
+		}
+		return st
+	}
+	// rows 回傳角色在資料庫中的物品（obj_id → 數量）
+	rows := func(p *world.PlayerInfo) map[int32]int32 {
+		t.Helper()
+		list, err := repo.LoadByCharID(ctx, p.CharID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := make(map[int32]int32, len(list))
+		for _, r := range list {
+			m[r.ObjID] = r.Count
+		}
+		return m
+	}
+	// matches 確認資料庫中的列與記憶體背包一致
+	matches := func(p *world.PlayerInfo) {
+		t.Helper()
+		got := rows(p)
+		want := make(map[int32]int32, len(p.Inv.Items))
+		for _, it := range p.Inv.Items {
+			want[it.ObjectID] = it.Count
+		}
+		if !maps.Equal(got, want) {
+			t.Fatalf("%s: rows %v, inventory %v", p.Name, got, want)
+		}
+	}
+	give := func(cmd string) int32 {
+		t.Helper()
+		gm(t, s, a, pa, cmd)
+		return a.Expect(packet.S_OPCODE_ADD_ITEM).NewReader().ReadD()
+	}
+
+	const potion = 40010
+	traded := give(".item 40001 1")
+	deposited := give(".item 40001 1")
+	destroyed := give(".item 40001 1")
+	give(".item 40010 5")
+
+	if st := save(pa); !st.Full || st.Upserted != len(pa.Inv.Items) || st.Deleted != 0 {
+		t.Fatalf("first save: %+v, want full with %d upserts", st, len(pa.Inv.Items))
+	}
+	matches(pa)
+	if st := save(pa); st.Full || st.Upserted != 0 || st.Deleted != 0 {
+		t.Fatalf("unchanged save: %+v, want nothing written", st)
+	}
+
+	// 堆疊數量變更只寫入該物品
+	gm(t, s, a, pa, ".item 40010 5")
+	if n := itemCount(pa, potion); n != 10 {
+		t.Fatalf("potions %d, want 10 in one stack", n)
+	}
+	if st := save(pa); st.Upserted != 1 || st.Deleted != 0 {
+		t.Fatalf("stack change: %+v, want 1 upsert", st)
+	}
+	matches(pa)
+
+	// B 在資料庫中有一列不在記憶體背包中（例如登入時略過的未知物品），第一次存檔須刪除
+	stray := world.NewInventory()
+	stray.AddItem(40001, 1, "燈", 3, 0, false, 1)
+	if _, err := repo.SaveInventory(ctx, pb.CharID, stray, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	trade(t, s, a, pa, b, pb, traded)
+	if err := a.Deposit(0, deposited, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.DestroyItem(destroyed, 1); err != nil {
+		t.Fatal(err)
+	}
+	s.TickN(2)
+	for _, objID := range []int32{traded, deposited, destroyed} {
+		if pa.Inv.FindByObjectID(objID) != nil {
+			t.Fatalf("item %d still in %s's inventory", objID, pa.Name)
+		}
+	}
+
+	// B 先存檔：交易得到的燈接手 A 的列，殘留的列被刪除
+	if st := save(pb); !st.Full || st.Deleted != 1 {
+		t.Fatalf("%s first save: %+v, want full with 1 delete", pb.Name, st)
+	}
+	matches(pb)
+	if _, ok := rows(pa)[traded]; ok {
+		t.Fatalf("traded item %d still saved under %s", traded, pa.Name)
+	}
+
+	// A 再存檔：只刪除存倉與銷毀的燈，已屬於 B 的列不受影響
+	if st := save(pa); st.Full || st.Upserted != 0 || st.Deleted != 2 {
+		t.Fatalf("%s save after trade: %+v, want 2 deletes", pa.Name, st)
+	}
+	matches(pa)
+	matches(pb)
+}
+
+// attackRolls 在新的模擬中對海葵攻擊 n 次，回傳每次 S_ATTACK 的傷害。
+func attackRolls(t *testing.T, seed int64, n int) []int32 {
+	s := New(t, func(cfg *config.Config) { cfg.Debug.RNGSeed = seed })
+	c, p := enter(t, s, "simreplay", "SimReplay")
+	gm(t, s, c, p, ".spawn 45006")
+	var npc *world.NpcInfo
+	for _, pk := range c.Take(packet.S_OPCODE_PUT_OBJECT) {
+		r := pk.NewReader()
+		r.ReadH()
+		r.ReadH()
+		if n := s.World.GetNpc(r.ReadD()); n != nil && n.NpcID == 45006 {
+			npc = n
+		}
+	}
+	if npc == nil {
+		t.Fatal("spawned NPC not shown to the player")
+	}
+	npc.HP, npc.MaxHP = 1_000_000, 1_000_000
+
+	var rolls []int32
+	for i := 0; i < n; i++ {
+		c.Clear()
+		if err := c.Attack(npc.ID); err != nil {
+			t.Fatal(err)
+		}
+		s.Tick()
+		r := c.Expect(packet.S_OPCODE_ATTACK).NewReader()
+		r.ReadC()
+		r.ReadD()
+		r.ReadD()
+		rolls = append(rolls, int32(r.ReadH()))
+		s.TickN(5) // 等過攻擊間隔
+	}
+	return rolls
+}
+
+func TestSeededSessionReplays(t *testing.T) {
+	first := attackRolls(t, 42, 8)
+	second := attackRolls(t, 42, 8)
+	if !slices.Equal(first, second) {
+		t.Fatalf("same seed, different rolls: %v vs %v", first, second)
+	}
+}
+
+func TestGameTimeFollowsSimClock(t *testing.T) {
+	s := New(t, nil)
+	c, _ := enter(t, s, "simclock", "SimClock")
+
+	s.TickN(3)
+	if len(c.Take(packet.S_OPCODE_WEATHER)) != 0 {
+		t.Fatal("weather changed without the game hour changing")
+	}
+	s.Advance(10 * time.Minute) // 遊戲時間 6 倍速：一個遊戲小時
+	s.Tick()
+	c.Expect(packet.S_OPCODE_WEATHER)
+}
+
+func TestCaptureRecordsSession(t *testing.T) {
+	dir := t.TempDir()
+	s := New(t, func(cfg *config.Config) {
+		cfg.Debug.CaptureCharacters = []string{"SimCapture"}
+		cfg.Debug.CaptureDir = dir
+	})
+	c, _ := enter(t, s, "simcapture", "SimCapture")
+	if err := c.Move(4); err != nil {
+		t.Fatal(err)
+	}
+	s.Tick()
+
+	capture := c.Sess.StopCapture()
+	if capture == nil {
+		t.Fatal("configured character is not being captured")
+	}
+	cf, err := gonet.ReadCapture(capture.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cf.Label != "SimCapture" {
+		t.Fatalf("capture label %q", cf.Label)
+	}
+	var enterCheck, move bool
+	var last time.Duration
+	for _, rec := range cf.Records {
+		if rec.At < last {
+			t.Fatal("capture timestamps go backwards")
+		}
+		last = rec.At
+		switch {
+		case rec.Dir == gonet.CaptureS2C && rec.Payload[0] == packet.S_OPCODE_ENTER_WORLD_CHECK:
+			enterCheck = true
+		case rec.Dir == gonet.CaptureC2S && rec.Payload[0] == packet.C_OPCODE_MOVE:
+			move = true
+		}
+	}
+	if !enterCheck || !move || len(cf.Records) != capture.Frames() {
+		t.Fatalf("capture has enter-world %v, move %v, %d of %d records", enterCheck, move, len(cf.Records), capture.Frames())
+	}
+}
@@ -0,0 +1,157 @@
+package system
+
+import (
+	"time"
+
+	"github.com/l1jgo/server/internal/admin"
+	"github.com/l1jgo/server/internal/core/event"
+	coresys "github.com/l1jgo/server/internal/core/system"
+	"github.com/l1jgo/server/internal/handler"
+	"github.com/l1jgo/server/internal/world"
+	"go.uber.org/zap"
+)
+
+// AdminSystem 在每個 tick 執行管理 API 排隊中的請求。
+// Phase 1（PreUpdate）：與前一 tick 的事件一起處理，結果在同一 tick 的 Phase 4 送出封包。
+type AdminSystem struct {
+	srv     *admin.Server
+	actions *AdminActions
+}
+
+func NewAdminSystem(srv *admin.Server, actions *AdminActions) *AdminSystem {
+	return &AdminSystem{srv: srv, actions: actions}
+}
+
+func (s *AdminSystem) Phase() coresys.Phase { return coresys.PhasePreUpdate }
+
+func (s *AdminSystem) Update(_ time.Duration) {
+	s.srv.Drain(s.actions)
+}
+
+// AdminActions 是 admin.Actions 的遊戲迴圈端實作。
+type AdminActions struct {
+	deps    *handler.Deps
+	persist *PersistenceSystem
+	script  *ScriptGameAPI // 共用物品給予邏輯（堆疊、背包上限）
+}
+
+func NewAdminActions(deps *handler.Deps, persist *PersistenceSystem) *AdminActions {
+	return &AdminActions{deps: deps, persist: persist, script: NewScriptGameAPI(deps)}
+}
+
+func (a *AdminActions) player(name string) (*world.PlayerInfo, error) {
+	p := a.deps.World.GetByName(name)
+	if p == nil {
+		return nil, admin.NotFound("player %q is not online", name)
+	}
+	return p, nil
+}
+
+// Players 列出線上玩家。
+func (a *AdminActions) Players() []admin.Player {
+	list := make([]admin.Player, 0, a.deps.World.PlayerCount())
+	a.deps.World.AllPlayers(func(p *world.PlayerInfo) {
+		list = append(list, admin.Player{
+			CharID:    p.CharID,
+			Name:      p.Name,
+			Account:   p.Session.AccountName,
+			IP:        p.Session.IP,
+			Level:     p.Level,
+			ClassType: p.ClassType,
+			X:         p.X,
+			Y:         p.Y,
+			MapID:     p.MapID,
+			HP:        p.HP,
+			MaxHP:     p.MaxHP,
+			Dead:      p.Dead,
+			ClanName:  p.ClanName,
+		})
+	})
+	return list
+}
+
+// Kick 關閉連線；InputSystem 於下一次輪詢執行斷線清理與存檔。
+func (a *AdminActions) Kick(name string) error {
+	p, err := a.player(name)
+	if err != nil {
+		return err
+	}
+	a.deps.Log.Info("管理 API 踢除玩家", zap.String("name", p.Name), zap.String("account", p.Session.AccountName))
+	p.Session.Close()
+	return nil
+}
+
+// Announce 以綠色公告廣播給全體線上玩家。
+func (a *AdminActions) Announce(text string) int {
+	data := handler.BuildGreenMessage(text)
+	n := 0
+	a.deps.World.AllPlayers(func(p *world.PlayerInfo) {
+		p.Session.Send(data)
+		n++
+	})
+	return n
+}
+
+// GiveItem 給予物品（與腳本 game.give_item 相同規則，物品事件記為 gm_create）。
+func (a *AdminActions) GiveItem(name string, itemID, count int32, enchant int8) error {
+	p, err := a.player(name)
+	if err != nil {
+		return err
+	}
+	if a.deps.Items.Get(itemID) == nil {
+		return admin.BadRequest("item %d does not exist", itemID)
+	}
+	if !a.script.giveItem(p.CharID, itemID, count, enchant, event.ItemReasonGMCreate, walTargetAdmin) {
+		return admin.Conflict("inventory of %q is full", p.Name)
+	}
+	return nil
+}
+
+// Teleport 傳送玩家；目標地圖不存在或座標不可通行時拒絕。
+func (a *AdminActions) Teleport(name string, x, y int32, mapID int16) error {
+	p, err := a.player(name)
+	if err != nil {
+		return err
+	}
+	if p.Dead {
+		return admin.Conflict("player %q is dead", p.Name)
+	}
+	if !a.script.Teleport(p.CharID, x, y, mapID) {
+		return admin.BadRequest("destination (%d,%d) on map %d is not passable", x, y, mapID)
+	}
+	return nil
+}
+
+// Save 以資料庫工作存檔指定玩家或全體線上玩家（有進行中資料庫工作的玩家略過，
+// 由之後的自動存檔處理）。
+func (a *AdminActions) Save(name string, done func(saved int, err error)) {
+	var players []*world.PlayerInfo
+	if name == "" {
+		a.deps.World.AllPlayers(func(p *world.PlayerInfo) {
+			if !jobPending(p) {
+				players = append(players, p)
+			}
+		})
+	} else {
+		p, err := a.player(name)
+		if err != nil {
+			done(0, err)
+			return
+		}
+		if jobPending(p) {
+			done(0, admin.Conflict("player %q has a database operation in progress, retry later", p.Name))
+			return
+		}
+		players = append(players, p)
+	}
+	a.persist.SaveAsync(a.deps, players, func(saved int, err error) {
+		switch {
+		case err != nil:
+			done(0, admin.Internal("save failed: %v", err))
+		case name != "" && saved == 0:
+			done(0, admin.Internal("save of %q failed, see server log", name))
+		default:
+			done(saved, nil)
+		}
+	})
+}
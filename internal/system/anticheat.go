@@ -0,0 +1,410 @@
+package system
+
+// anticheat.go — 動作頻率反作弊（移動加速、攻擊速度、施法速度）與傳送驗證。
+// 預期間隔取自 SprTable 的動畫時間（依目前外型與武器），再套用加速狀態倍率，
+// 與 Java AcceleratorChecker 相同；判定改以滾動視窗的總時間比對，容許網路延遲造成的封包擠壓。
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/l1jgo/server/internal/config"
+	coresys "github.com/l1jgo/server/internal/core/system"
+	"github.com/l1jgo/server/internal/data"
+	"github.com/l1jgo/server/internal/handler"
+	"github.com/l1jgo/server/internal/persist"
+	"github.com/l1jgo/server/internal/world"
+	"go.uber.org/zap"
+)
+
+// 加速倍率（Java AcceleratorChecker）
+const (
+	hasteRate       = 0.75  // 一段加速 / 勇敢藥水 / 神聖疾走等
+	waffleRate      = 0.87  // 精靈餅乾
+	doubleHasteRate = 0.375 // 超級勇敢（巧克力蛋糕）
+)
+
+// 反作弊標記種類與處置（cheat_flags.kind / action）
+const (
+	cheatKindSpeed    = "speed"
+	cheatKindAttack   = "attack"
+	cheatKindCast     = "cast"
+	cheatActionReject = "reject"
+	cheatActionKick   = "kick"
+)
+
+// cheatFlagMaxPending 是寫入受阻時最多保留的標記數。
+const cheatFlagMaxPending = 1000
+
+// moveFloorRatio 是單步移動間隔的下限（預期步行間隔的比例）。低於下限的步伐靜默丟棄
+// （不回彈、不計入視窗），同原本 HandleMove 的 50% 容許值；持續加速仍由違規累積處置。
+const moveFloorRatio = 0.5
+
+// 外型步行時間未知（無 SprTable 或外型無資料）時沿用原本 HandleMove 的固定下限：
+// 一般步行 200ms、加速 133ms 的 50%。
+const (
+	moveFallbackFloor      = int64(100 * time.Millisecond)
+	moveFallbackHasteFloor = int64(66 * time.Millisecond)
+)
+
+// teleportAuthRange 是授權傳送的 NPC 與玩家的最大距離（對話距離 5 格，加上延遲傳送期間的移動）。
+const teleportAuthRange = 7
+
+// AntiCheatSystem 實作 handler.AntiCheatManager：記錄玩家動作節奏，違規在滾動視窗內累積，
+// 依次數升級處置 — 記錄日誌 → 拒絕（回彈）→ 踢除。升級到拒絕與踢除時寫入 cheat_flags
+// 並通知線上 GM。Phase 5（Persist）批次寫入標記。
+type AntiCheatSystem struct {
+	deps    *handler.Deps
+	repo    persist.CheatFlagStore
+	cfg     config.AntiCheatConfig
+	window  int64 // 違規計數視窗（ns）
+	pending []persist.CheatFlagRow
+}
+
+func NewAntiCheatSystem(deps *handler.Deps, repo persist.CheatFlagStore, cfg config.AntiCheatConfig) *AntiCheatSystem {
+	if cfg.SpeedTolerance < 1 {
+		cfg.SpeedTolerance = 1
+	}
+	if cfg.ViolationWindowSec <= 0 {
+		cfg.ViolationWindowSec = 60
+	}
+	return &AntiCheatSystem{
+		deps:   deps,
+		repo:   repo,
+		cfg:    cfg,
+		window: int64(cfg.ViolationWindowSec) * int64(time.Second),
+	}
+}
+
+func (s *AntiCheatSystem) Phase() coresys.Phase { return coresys.PhasePersist }
+
+func (s *AntiCheatSystem) Update(_ time.Duration) {
+	if len(s.pending) == 0 || s.repo == nil || s.deps.DBJobs == nil {
+		return
+	}
+	batch := s.pending
+	if !s.deps.DBJobs.Submit("cheat_flags", func(ctx context.Context) error {
+		return s.repo.Append(ctx, batch)
+	}, func(err error) {
+		if err != nil {
+			s.deps.Log.Error("寫入反作弊標記失敗", zap.Int("筆數", len(batch)), zap.Error(err))
+		}
+	}) {
+		return // 佇列已滿，下個 tick 再送
+	}
+	s.pending = nil
+}
+
+// paceCheck 描述一種動作的節奏判定參數。
+type paceCheck struct {
+	kind     string
+	win      *world.ActionWindow
+	strikes  *world.StrikeCounter
+	expected int64   // 本次動作距上一次的預期間隔（ns，0 = 未知，只套用 maxRate）
+	maxRate  float64 // 不論狀態的絕對上限（次/秒，0 = 不限）
+	unit     string  // 日誌單位（格 / 次）
+}
+
+// CheckMove 記錄一次移動並判定步行節奏；超過 speed_threshold 格/秒一律視為違規。
+// 距上一步不到預期間隔 moveFloorRatio 的步伐回傳 CheatDrop（不更新 LastMoveTime）；
+// 步行時間未知時改用固定下限 moveFallbackFloor。
+func (s *AntiCheatSystem) CheckMove(p *world.PlayerInfo) handler.CheatVerdict {
+	if p.LastMoveTime == 0 {
+		p.MoveWindow.Reset() // 傳送、復活後重新取樣
+	}
+	now := s.deps.Clock.Now().UnixNano()
+	expected := s.scaledInterval(p, cheatKindSpeed, s.moveMs(p))
+	floor := int64(float64(expected) * moveFloorRatio)
+	if expected == 0 {
+		floor = moveFallbackFloor
+		if p.MoveSpeed == 1 {
+			floor = moveFallbackHasteFloor
+		}
+	}
+	if prev := p.MoveWindow.Last(); prev > 0 && now-prev < floor {
+		return handler.CheatDrop
+	}
+	p.LastMoveTime = now
+	return s.checkPace(p, paceCheck{
+		kind:     cheatKindSpeed,
+		win:      &p.MoveWindow,
+		strikes:  &p.SpeedStrikes,
+		expected: expected,
+		maxRate:  s.cfg.SpeedThreshold,
+		unit:     "格",
+	})
+}
+
+// CheckAttack 記錄一次攻擊並與目前外型、武器的攻擊動畫時間比對。
+func (s *AntiCheatSystem) CheckAttack(p *world.PlayerInfo) handler.CheatVerdict {
+	ms := 0
+	if s.deps.SprTable != nil {
+		ms = s.deps.SprTable.GetAttackSpeed(int(handler.PlayerGfx(p)), int(p.CurrentWeapon)+1)
+	}
+	return s.checkPace(p, paceCheck{
+		kind:     cheatKindAttack,
+		win:      &p.AttackWindow,
+		strikes:  &p.AttackStrikes,
+		expected: s.scaledInterval(p, cheatKindAttack, ms),
+		unit:     "次",
+	})
+}
+
+// CheckCast 記錄一次施法。所有技能共用的視窗以施法動畫時間（有方向 / 無方向，套用加速倍率）
+// 比對施法節奏；各技能另有視窗，以該技能的冷卻（ReuseDelay）比對同一技能的重複施放。
+// 冷卻只約束同一技能的下一次施放，不能套用在其他技能之後的施法上。
+func (s *AntiCheatSystem) CheckCast(p *world.PlayerInfo, skill *data.SkillInfo) handler.CheatVerdict {
+	var anim int64
+	if skill != nil {
+		ms := 0
+		if s.deps.SprTable != nil {
+			gfx := int(handler.PlayerGfx(p))
+			if skill.ActionID == data.ActSkillAttack {
+				ms = s.deps.SprTable.GetDirSpellSpeed(gfx)
+			} else {
+				ms = s.deps.SprTable.GetNodirSpellSpeed(gfx)
+			}
+		}
+		anim = s.scaledInterval(p, cheatKindCast, ms)
+	}
+	if v := s.checkPace(p, paceCheck{
+		kind:     cheatKindCast,
+		win:      &p.CastWindow,
+		strikes:  &p.CastStrikes,
+		expected: anim,
+		unit:     "次",
+	}); v != handler.CheatAllow || skill == nil {
+		return v
+	}
+
+	reuse := skill.ReuseDelay
+	if reuse <= 0 {
+		reuse = 1000 // 同 SkillSystem 的預設冷卻
+	}
+	if p.SkillWindows == nil {
+		p.SkillWindows = make(map[int32]*world.ActionWindow)
+	}
+	win := p.SkillWindows[skill.SkillID]
+	if win == nil {
+		win = &world.ActionWindow{}
+		p.SkillWindows[skill.SkillID] = win
+	}
+	return s.checkPace(p, paceCheck{
+		kind:     cheatKindCast,
+		win:      win,
+		strikes:  &p.CastStrikes,
+		expected: max(anim, int64(reuse)*int64(time.Millisecond)),
+		unit:     "次",
+	})
+}
+
+// checkPace 記錄一次動作。視窗記滿後比對實際與預期的總時間，快於 speed_tolerance 倍
+// 或超過 maxRate 即為一次違規，並重新取樣（同一段連續動作只計一次）。
+// 違規次數已達回彈門檻時，間隔過短的單次動作也直接拒絕。
+func (s *AntiCheatSystem) checkPace(p *world.PlayerInfo, c paceCheck) handler.CheatVerdict {
+	if p.Session.IsClosed() {
+		return handler.CheatKick // 已踢除，斷線前殘留的封包不再處理
+	}
+	now := s.deps.Clock.Now().UnixNano()
+	prev := c.win.Last()
+	c.win.Add(now, c.expected)
+
+	strikes := c.strikes.Count(now, s.window)
+	if s.cfg.RejectViolations > 0 && strikes >= s.cfg.RejectViolations && prev > 0 &&
+		float64(now-prev)*s.cfg.SpeedTolerance < float64(c.expected) {
+		return handler.CheatReject
+	}
+	if !c.win.Full() {
+		return handler.CheatAllow
+	}
+
+	elapsed, want := c.win.Span()
+	if elapsed <= 0 {
+		elapsed = 1
+	}
+	steps := float64(world.ActionWindowSize - 1)
+	rate := steps / (float64(elapsed) / float64(time.Second))
+	tooFast := want > 0 && float64(want) > float64(elapsed)*s.cfg.SpeedTolerance
+	overCap := c.maxRate > 0 && rate > c.maxRate
+	if !tooFast && !overCap {
+		return handler.CheatAllow
+	}
+
+	c.win.Reset()
+	c.win.Add(now, c.expected)
+	strikes = c.strikes.Add(now, s.window)
+	var expectRate float64
+	if want > 0 {
+		expectRate = steps / (float64(want) / float64(time.Second))
+	}
+	detail := fmt.Sprintf("%.1f %s/秒，預期 %.1f %s/秒（外型 %d 武器 %d 加速 %d/%d）",
+		rate, c.unit, expectRate, c.unit, handler.PlayerGfx(p), p.CurrentWeapon, p.MoveSpeed, p.BraveSpeed)
+	return s.escalate(p, c.kind, strikes, detail)
+}
+
+// moveMs 回傳目前外型與武器的步行動畫時間（ms）；外型不在 SprTable 時回傳 0。
+func (s *AntiCheatSystem) moveMs(p *world.PlayerInfo) int {
+	if s.deps.SprTable == nil {
+		return 0
+	}
+	return s.deps.SprTable.GetMoveSpeed(int(handler.PlayerGfx(p)), int(p.CurrentWeapon))
+}
+
+// scaledInterval 將動畫時間（ms）套用加速狀態倍率，回傳預期間隔（ns）；ms <= 0 時回傳 0。
+// 神聖疾走類（BraveSpeed 4）只加快移動，血之渴望（6）只加快攻擊。
+func (s *AntiCheatSystem) scaledInterval(p *world.PlayerInfo, kind string, ms int) int64 {
+	if ms <= 0 {
+		return 0
+	}
+	rate := 1.0
+	switch p.MoveSpeed {
+	case 1:
+		rate *= hasteRate
+	case 2: // 緩速
+		rate /= hasteRate
+	}
+	switch p.BraveSpeed {
+	case 1: // 勇敢藥水
+		rate *= hasteRate
+	case 3:
+		rate *= waffleRate
+	case 4: // 神聖疾走 / 行走加速 / 風之疾走 / 暴風疾走
+		if kind == cheatKindSpeed {
+			rate *= hasteRate
+		}
+	case 5:
+		rate *= doubleHasteRate
+	case 6: // 血之渴望
+		if kind == cheatKindAttack {
+			rate *= hasteRate
+		}
+	}
+	return int64(float64(ms) * rate * float64(time.Millisecond))
+}
+
+// escalate 依視窗內違規次數決定處置：未達門檻只記錄日誌；達 reject_violations 拒絕動作
+// （首次達到時寫入標記）；達 kick_violations 寫入標記並踢除。
+func (s *AntiCheatSystem) escalate(p *world.PlayerInfo, kind string, strikes int, detail string) handler.CheatVerdict {
+	fields := []zap.Field{
+		zap.String("kind", kind),
+		zap.String("char", p.Name),
+		zap.String("account", p.Session.AccountName),
+		zap.String("ip", p.Session.Host()),
+		zap.Int("violations", strikes),
+		zap.String("detail", detail),
+	}
+	switch {
+	case s.cfg.KickViolations > 0 && strikes >= s.cfg.KickViolations:
+		s.deps.Log.Warn("動作頻率異常，踢除連線", fields...)
+		s.flag(p, kind, cheatActionKick, strikes, detail)
+		p.Session.Close()
+		return handler.CheatKick
+	case s.cfg.RejectViolations > 0 && strikes >= s.cfg.RejectViolations:
+		s.deps.Log.Warn("動作頻率異常，拒絕動作", fields...)
+		if strikes == s.cfg.RejectViolations {
+			s.flag(p, kind, cheatActionReject, strikes, detail)
+		}
+		return handler.CheatReject
+	}
+	s.deps.Log.Info("動作頻率異常", fields...)
+	return handler.CheatAllow
+}
+
+// flag 記錄一筆反作弊標記並通知線上 GM。
+func (s *AntiCheatSystem) flag(p *world.PlayerInfo, kind, action string, strikes int, detail string) {
+	if len(s.pending) >= cheatFlagMaxPending {
+		s.pending = s.pending[1:]
+	}
+	s.pending = append(s.pending, persist.CheatFlagRow{
+		CharID:     p.CharID,
+		CharName:   p.Name,
+		Account:    p.Session.AccountName,
+		IP:         p.Session.Host(),
+		Kind:       kind,
+		Action:     action,
+		Violations: int32(strikes),
+		Detail:     detail,
+		MapID:      p.MapID,
+		X:          p.X,
+		Y:          p.Y,
+		CreatedAt:  s.deps.Clock.Now(),
+	})
+	handler.BroadcastToGMs(s.deps.World, fmt.Sprintf("反作弊 [%s/%s] %s 違規 %d 次：%s",
+		kind, action, p.Name, strikes, detail))
+}
+
+// CheckTeleport 驗證玩家發起的傳送（anti_cheat.teleport_validation）：目的地圖存在、
+// 目的座標在地圖內且地形可通行、來源地圖允許此類傳送離開、授權的物品 / NPC / 書籤確實存在。
+// 未通過時記錄來源路徑與原因並回傳 false。
+func (s *AntiCheatSystem) CheckTeleport(p *world.PlayerInfo, x, y int32, mapID int16, src world.TeleportSource) bool {
+	if !s.cfg.TeleportValidation {
+		return true
+	}
+	reason := s.teleportReject(p, x, y, mapID, src)
+	if reason == "" {
+		return true
+	}
+	s.deps.Log.Warn("傳送驗證失敗",
+		zap.String("path", src.Path),
+		zap.String("reason", reason),
+		zap.String("char", p.Name),
+		zap.String("account", p.Session.AccountName),
+		zap.String("from", fmt.Sprintf("%d:%d,%d", p.MapID, p.X, p.Y)),
+		zap.String("to", fmt.Sprintf("%d:%d,%d", mapID, x, y)),
+		zap.Int32("item", src.ItemObjID),
+		zap.Int32("npc", src.NpcObjID),
+		zap.Int32("bookmark", src.BookmarkID),
+	)
+	return false
+}
+
+// teleportReject 回傳傳送不合法的原因；合法時回傳空字串。
+func (s *AntiCheatSystem) teleportReject(p *world.PlayerInfo, x, y int32, mapID int16, src world.TeleportSource) string {
+	if md := s.deps.MapData; md != nil {
+		if md.GetInfo(mapID) == nil {
+			return "目的地圖不存在"
+		}
+		if !md.IsInMap(mapID, x, y) || !md.IsPassablePointIgnoreOccupant(mapID, x, y) {
+			return "目的地不可通行"
+		}
+		if from := md.GetInfo(p.MapID); from != nil {
+			switch src.Path {
+			case world.TeleportPathBookmark, world.TeleportPathScroll:
+				if !from.Escapable {
+					return "來源地圖禁止脫出"
+				}
+			case world.TeleportPathRandom:
+				if !from.Teleportable {
+					return "來源地圖禁止傳送"
+				}
+			}
+		}
+	}
+	if src.ItemObjID != 0 && p.Inv.FindByObjectID(src.ItemObjID) == nil {
+		return "授權物品不在背包中"
+	}
+	if src.NpcObjID != 0 {
+		npc := s.deps.World.GetNpc(src.NpcObjID)
+		if npc == nil || npc.Dead || npc.MapID != p.MapID ||
+			chebyshevDist(p.X, p.Y, npc.X, npc.Y) > teleportAuthRange {
+			return "授權 NPC 不在附近"
+		}
+	}
+	if src.BookmarkID != 0 {
+		var bm *world.Bookmark
+		for i := range p.Bookmarks {
+			if p.Bookmarks[i].ID == src.BookmarkID {
+				bm = &p.Bookmarks[i]
+				break
+			}
+		}
+		if bm == nil {
+			return "書籤不存在"
+		}
+		if bm.X != x || bm.Y != y || bm.MapID != mapID {
+			return "目的地與書籤不符"
+		}
+	}
+	return ""
+}
@@ -2,6 +2,7 @@ package system
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	coresys "github.com/l1jgo/server/internal/core/system"
@@ -20,7 +21,7 @@ const auctionSettleTicks = 1500
 type AuctionSystem struct {
 	deps    *handler.Deps
 	ws      *world.State
-	repo    *persist.AuctionRepo
+	repo    persist.AuctionStore
 	log     *zap.Logger
 	elapsed int
 
@@ -29,7 +30,7 @@ type AuctionSystem struct {
 }
 
 // NewAuctionSystem 建構拍賣系統（啟動時從 DB 載入所有拍賣記錄）。
-func NewAuctionSystem(ws *world.State, deps *handler.Deps, repo *persist.AuctionRepo) *AuctionSystem {
+func NewAuctionSystem(ws *world.State, deps *handler.Deps, repo persist.AuctionStore) *AuctionSystem {
 	s := &AuctionSystem{
 		deps:    deps,
 		ws:      ws,
@@ -120,9 +121,29 @@ func (s *AuctionSystem) PlaceBid(sess *net.Session, player *world.PlayerInfo, ho
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	// 出價扣款與線上前一位競標者的退款與出價同一交易寫入 WAL
+	target := fmt.Sprintf("auction:%d", houseID)
+	bid := persist.GoldWAL(persist.WALAuction, player.CharID, 0, int64(bidAmount))
+	bid.Target = target
+	wal := []persist.WALEntry{bid}
+	var prevBidder *world.PlayerInfo
+	if entry.BidderID != 0 {
+		prevBidder = s.ws.GetByName(entry.Bidder)
+		if prevBidder != nil {
+			refund := persist.GoldWAL(persist.WALAuction, 0, prevBidder.CharID, entry.Price)
+			refund.Target = target
+			wal = append(wal, refund)
+		}
+	}
+
+	// 更新 DB
+	if err := s.repo.UpdateBid(ctx, houseID, amount, player.Name, player.CharID, wal...); err != nil {
+		s.log.Error("更新拍賣出價失敗", zap.Error(err))
+		return false
+	}
+
 	// 退還前一位競標者的金幣
 	if entry.BidderID != 0 {
-		prevBidder := s.ws.GetByName(entry.Bidder)
 		if prevBidder != nil {
 			// 線上玩家：直接加記憶體
 			addAdena(prevBidder, int32(entry.Price))
@@ -138,12 +159,6 @@ func (s *AuctionSystem) PlaceBid(sess *net.Session, player *world.PlayerInfo, ho
 		}
 	}
 
-	// 更新 DB
-	if err := s.repo.UpdateBid(ctx, houseID, amount, player.Name, player.CharID); err != nil {
-		s.log.Error("更新拍賣出價失敗", zap.Error(err))
-		return false
-	}
-
 	// 扣金幣（直接在 system 層操作，不再反向呼叫 handler）
 	deductAdena(player, bidAmount)
 	handler.SendAdenaUpdate(sess, player)
@@ -176,7 +191,7 @@ func (s *AuctionSystem) IsAlreadyBidding(charName string) bool {
 // 3. 有原屋主 + 無競標者 → 取消拍賣，小屋歸還原屋主
 // 4. 無原屋主 + 無競標者 → 延期 1 天
 func (s *AuctionSystem) settleExpired() {
-	now := time.Now()
+	now := s.deps.Clock.Now()
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
@@ -207,13 +222,31 @@ func (s *AuctionSystem) settleWithOwnerAndBidder(ctx context.Context, entry *per
 	// 原屋主得 price × 0.9（10% 手續費）
 	refund := int64(float64(entry.Price) * 0.9)
 
-	// 退金幣給原屋主
+	// 線上原屋主的結算金額與刪除拍賣記錄同一交易寫入 WAL
 	oldOwner := s.ws.GetByName(entry.OldOwner)
+	var wal []persist.WALEntry
+	if oldOwner != nil {
+		e := persist.GoldWAL(persist.WALAuction, 0, oldOwner.CharID, refund)
+		e.Target = fmt.Sprintf("auction:%d", entry.HouseID)
+		wal = append(wal, e)
+	}
+
+	// 刪除拍賣記錄
+	if err := s.repo.DeleteAuction(ctx, entry.HouseID, wal...); err != nil {
+		s.log.Error("刪除拍賣記錄失敗", zap.Int32("houseID", entry.HouseID), zap.Error(err))
+		return
+	}
+	delete(s.entries, entry.HouseID)
+
+	// 退金幣給原屋主
 	if oldOwner != nil {
 		addAdena(oldOwner, int32(refund))
 		handler.SendAdenaUpdate(oldOwner.Session, oldOwner)
-	} else {
-		_ = s.repo.RefundOfflineGold(ctx, entry.OldOwnerID, refund)
+	} else if err := s.repo.RefundOfflineGold(ctx, entry.OldOwnerID, refund); err != nil {
+		s.log.Error("結算金額退還離線原屋主失敗",
+			zap.Int32("charID", entry.OldOwnerID),
+			zap.Int64("金額", refund),
+			zap.Error(err))
 	}
 
 	// 更新競標者的血盟 HasHouse
@@ -233,13 +266,6 @@ func (s *AuctionSystem) settleWithOwnerAndBidder(ctx context.Context, entry *per
 		}
 	}
 
-	// 刪除拍賣記錄
-	if err := s.repo.DeleteAuction(ctx, entry.HouseID); err != nil {
-		s.log.Error("刪除拍賣記錄失敗", zap.Int32("houseID", entry.HouseID), zap.Error(err))
-		return
-	}
-	delete(s.entries, entry.HouseID)
-
 	s.log.Info("拍賣結標：轉讓",
 		zap.Int32("houseID", entry.HouseID),
 		zap.String("原屋主", entry.OldOwner),
@@ -287,7 +313,7 @@ func (s *AuctionSystem) settleCancelAuction(ctx context.Context, entry *persist.
 
 // extendDeadline 無屋主+無競標者：延期 1 天。
 func (s *AuctionSystem) extendDeadline(ctx context.Context, entry *persist.AuctionEntry) {
-	newDeadline := auctionNextMidnight(time.Now())
+	newDeadline := auctionNextMidnight(s.deps.Clock.Now())
 	if err := s.repo.UpdateDeadline(ctx, entry.HouseID, newDeadline); err != nil {
 		s.log.Error("延期拍賣失敗", zap.Int32("houseID", entry.HouseID), zap.Error(err))
 		return
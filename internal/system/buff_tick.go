@@ -29,7 +29,7 @@ func (s *BuffTickSystem) Update(_ time.Duration) {
 		tickItemMagicEnchants(p, s.deps)
 		TickPlayerPoison(p, s.deps)
 		TickPlayerCurse(p, s.deps)
-		TickCatapultSilence(p)
+		TickCatapultSilence(p, s.deps)
 		if len(p.ActiveBuffs) < prevBuffCount {
 			p.Dirty = true
 		}
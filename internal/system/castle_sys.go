@@ -2,7 +2,6 @@ package system
 
 import (
 	"context"
-	"math/rand"
 	"time"
 
 	coresys "github.com/l1jgo/server/internal/core/system"
@@ -282,7 +281,7 @@ func (s *CastleSystem) TransferCastle(castleID int32, newClanID int32) {
 
 // TickWar 攻城戰排程 tick。
 func (s *CastleSystem) TickWar() {
-	now := time.Now()
+	now := s.deps.Clock.Now()
 
 	for i := int32(1); i <= 8; i++ {
 		ci := s.castles[i]
@@ -951,7 +950,7 @@ func (s *CastleSystem) HandleCatapultAction(sess *net.Session, player *world.Pla
 		return
 	}
 
-	curtime := time.Now().Unix()
+	curtime := s.deps.Clock.Now().Unix()
 
 	// 冷卻檢查（10 秒共用冷卻）
 	if (npc.ShellDamageTime+10 > curtime) || (npc.ShellSilenceTime+10 > curtime) {
@@ -984,8 +983,8 @@ func (s *CastleSystem) HandleCatapultAction(sess *net.Session, player *world.Pla
 	}
 
 	// 計算著彈點（隨機偏移）
-	hitX := tgt.baseX + rand.Int31n(tgt.randW)
-	hitY := tgt.baseY + rand.Int31n(tgt.randH)
+	hitX := tgt.baseX + s.deps.Rand.Int31n(tgt.randW)
+	hitY := tgt.baseY + s.deps.Rand.Int31n(tgt.randH)
 
 	// 建構視覺封包（S_EffectLocation + S_DoActionGfx）
 	effectPkt := buildEffectLocation(hitX, hitY, tgt.effectID)
@@ -1049,7 +1048,7 @@ func (s *CastleSystem) applyCatapultSilence(target *world.PlayerInfo) {
 		return
 	}
 	target.Silenced = true
-	target.CatapultSilenceEnd = time.Now().Unix() + 15
+	target.CatapultSilenceEnd = s.deps.Clock.Now().Unix() + 15
 
 	// 沉默音效（GFX 2177）
 	effectPkt := handler.BuildSkillEffect(target.CharID, 2177)
@@ -79,7 +79,7 @@ func (s *CharResetSystem) Start(sess *net.Session, player *world.PlayerInfo) {
 	handler.SendResetFreeze(sess, 4, true)
 
 	// 發送狀態更新
-	handler.SendPlayerStatus(sess, player)
+	handler.SendPlayerStatus(sess, player, s.deps)
 	handler.SendAbilityScores(sess, player)
 	handler.SendMagicStatus(sess, byte(player.SP), uint16(player.MR))
 
@@ -318,7 +318,7 @@ func (s *CharResetSystem) finishCharReset(sess *net.Session, player *world.Playe
 	handler.SendResetFreeze(sess, 4, false)
 
 	// 發送更新封包
-	handler.SendPlayerStatus(sess, player)
+	handler.SendPlayerStatus(sess, player, s.deps)
 	handler.SendAbilityScores(sess, player)
 	handler.SendMagicStatus(sess, byte(player.SP), uint16(player.MR))
 
@@ -64,7 +64,7 @@ func (s *ClanSystem) Create(sess *net.Session, player *world.PlayerInfo, clanNam
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	foundDate := int32(time.Now().Unix())
+	foundDate := int32(s.deps.Clock.Now().Unix())
 	clanID, err := s.deps.ClanRepo.CreateClan(ctx, player.CharID, player.Name, clanName, foundDate)
 	if err != nil {
 		s.deps.Log.Error(fmt.Sprintf("建立血盟失敗  player=%s  clan=%s  err=%v", player.Name, clanName, err))
@@ -2,6 +2,7 @@ package system
 
 import (
 	"fmt"
+	"math/rand"
 	"time"
 
 	"github.com/l1jgo/server/internal/core/event"
@@ -27,7 +28,14 @@ func NewCombatSystem(deps *handler.Deps) *CombatSystem {
 func (s *CombatSystem) Phase() coresys.Phase { return coresys.PhaseUpdate }
 
 // QueueAttack implements handler.CombatQueue.
+// 入列前依封包到達時間檢查攻速，過快的攻擊直接丟棄。
 func (s *CombatSystem) QueueAttack(req handler.AttackRequest) {
+	if s.deps.AntiCheat != nil {
+		if p := s.deps.World.GetBySession(req.AttackerSessionID); p != nil &&
+			s.deps.AntiCheat.CheckAttack(p) != handler.CheatAllow {
+			return
+		}
+	}
 	s.requests = append(s.requests, req)
 }
 
@@ -228,7 +236,7 @@ func (s *CombatSystem) processMeleeAttack(sessID uint64, targetID int32) *handle
 		damageWeaponDurability(player, s.deps)
 
 		// 武器吸血/吸魔（Java: L1AttackPc.commit — dice_hp/sucking_hp/dice_mp/sucking_mp）
-		applyWeaponDrain(player, npc)
+		applyWeaponDrain(s.deps.Rand, player, npc)
 
 		// 受傷累加仇恨（Java: L1HateList.add）
 		AddHate(npc, sessID, damage)
@@ -454,7 +462,7 @@ func (s *CombatSystem) processRangedAttack(sessID uint64, targetID int32) *handl
 		damageWeaponDurability(player, s.deps)
 
 		// 武器吸血/吸魔（Java: L1AttackPc.commit）
-		applyWeaponDrain(player, npc)
+		applyWeaponDrain(s.deps.Rand, player, npc)
 
 		// 受傷累加仇恨
 		AddHate(npc, sessID, damage)
@@ -702,7 +710,7 @@ func addExp(player *world.PlayerInfo, expGain int32, deps *handler.Deps) {
 	if leveledUp {
 		player.Dirty = true
 		// 發送完整狀態更新（客戶端偵測等級變化後自動播放升級特效）
-		handler.SendPlayerStatus(player.Session, player)
+		handler.SendPlayerStatus(player.Session, player, deps)
 
 		// 51 級以上顯示加點對話框
 		if player.Level >= bonusStatMinLevel {
@@ -872,9 +880,9 @@ func damageWeaponDurability(player *world.PlayerInfo, deps *handler.Deps) {
 
 // applyWeaponDrain 武器吸血/吸魔判定（Java: L1AttackPc.commit — dice_hp/sucking_hp/dice_mp/sucking_mp）。
 // 每次命中後機率觸發，從 NPC 吸取 HP/MP 加到玩家。
-func applyWeaponDrain(player *world.PlayerInfo, npc *world.NpcInfo) {
+func applyWeaponDrain(rng *rand.Rand, player *world.PlayerInfo, npc *world.NpcInfo) {
 	// HP 吸取
-	if player.DrainDiceHP > 0 && world.RandInt(100)+1 <= player.DrainDiceHP {
+	if player.DrainDiceHP > 0 && world.RandInt(rng, 100)+1 <= player.DrainDiceHP {
 		drain := int32(player.DrainSuckingHP)
 		if drain > npc.HP {
 			drain = npc.HP
@@ -889,7 +897,7 @@ func applyWeaponDrain(player *world.PlayerInfo, npc *world.NpcInfo) {
 		}
 	}
 	// MP 吸取
-	if player.DrainDiceMP > 0 && world.RandInt(100)+1 <= player.DrainDiceMP {
+	if player.DrainDiceMP > 0 && world.RandInt(rng, 100)+1 <= player.DrainDiceMP {
 		drain := int32(player.DrainSuckingMP)
 		if drain > npc.MP {
 			drain = npc.MP
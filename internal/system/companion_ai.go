@@ -17,15 +17,37 @@ import (
 type CompanionAISystem struct {
 	world *world.State
 	deps  *handler.Deps
+	ticks int
+
+	// A* 路徑快取（key = 同伴物件 ID），長時間未使用者定期清除
+	paths map[int32]*companionPath
+}
+
+// companionPath 是單一同伴的路徑快取。
+type companionPath struct {
+	cache    world.PathCache
+	lastTick int
 }
 
+// companionPathIdleTicks 路徑快取超過此 tick 數未使用即清除（同伴已消失或停止移動）。
+const companionPathIdleTicks = 50
+
 func NewCompanionAISystem(ws *world.State, deps *handler.Deps) *CompanionAISystem {
-	return &CompanionAISystem{world: ws, deps: deps}
+	return &CompanionAISystem{world: ws, deps: deps, paths: make(map[int32]*companionPath)}
 }
 
 func (s *CompanionAISystem) Phase() coresys.Phase { return coresys.PhaseUpdate }
 
 func (s *CompanionAISystem) Update(_ time.Duration) {
+	s.ticks++
+	if s.ticks%companionPathIdleTicks == 0 {
+		for id, p := range s.paths {
+			if s.ticks-p.lastTick > companionPathIdleTicks {
+				delete(s.paths, id)
+			}
+		}
+	}
+
 	s.tickSummons()
 	s.tickDolls()
 	s.tickFollowers()
@@ -238,7 +260,7 @@ func (s *CompanionAISystem) summonAttackTarget(sum *world.SummonInfo) {
 	if dmg <= 0 {
 		dmg = int32(sum.Level)/2 + 1
 	}
-	variance := int32(world.RandInt(int(dmg/4+1))) - dmg/8
+	variance := int32(world.RandInt(s.deps.Rand, int(dmg/4+1))) - dmg/8
 	dmg += variance
 	if dmg < 1 {
 		dmg = 1
@@ -359,7 +381,7 @@ func (s *CompanionAISystem) tickDolls() {
 			}
 			sendCompanionSoundEffect(master.Session, doll.ID, 5936) // dismiss sound
 			sendDollTimerClear(master.Session)
-			handler.SendPlayerStatus(master.Session, master)
+			handler.SendPlayerStatus(master.Session, master, s.deps)
 		}
 		nearby := ws.GetNearbyPlayersAt(doll.X, doll.Y, doll.MapID)
 		for _, viewer := range nearby {
@@ -380,8 +402,8 @@ func (s *CompanionAISystem) dollTeleportToMaster(doll *world.DollInfo, master *w
 	}
 
 	// 在主人附近隨機位置
-	newX := master.X + int32(world.RandInt(3)) - 1
-	newY := master.Y + int32(world.RandInt(3)) - 1
+	newX := master.X + int32(world.RandInt(s.deps.Rand, 3)) - 1
+	newY := master.Y + int32(world.RandInt(s.deps.Rand, 3)) - 1
 
 	// 使用 TeleportDoll 正確更新 MapID + AOI 網格
 	ws.TeleportDoll(doll.ID, newX, newY, master.MapID, master.Heading)
@@ -689,7 +711,7 @@ func (s *CompanionAISystem) petAttackTarget(pet *world.PetInfo) {
 	if dmg <= 0 {
 		dmg = int32(pet.Level)/2 + 1
 	}
-	variance := int32(world.RandInt(int(dmg/4+1))) - dmg/8
+	variance := int32(world.RandInt(s.deps.Rand, int(dmg/4+1))) - dmg/8
 	dmg += variance
 	if dmg < 1 {
 		dmg = 1
@@ -716,7 +738,7 @@ func (s *CompanionAISystem) petAttackTarget(pet *world.PetInfo) {
 		if retalDmg < 1 {
 			retalDmg = 1
 		}
-		retalDmg += int32(world.RandInt(int(retalDmg/2 + 1)))
+		retalDmg += int32(world.RandInt(s.deps.Rand, int(retalDmg/2 + 1)))
 		// 寵物 AC 減傷
 		acReduction := int32(-pet.AC) / 3
 		retalDmg -= acReduction
@@ -805,7 +827,7 @@ func (s *CompanionAISystem) petAttackPetTarget(pet *world.PetInfo) {
 	if dmg <= 0 {
 		dmg = int32(pet.Level)/2 + 1
 	}
-	variance := int32(world.RandInt(int(dmg/4+1))) - dmg/8
+	variance := int32(world.RandInt(s.deps.Rand, int(dmg/4+1))) - dmg/8
 	dmg += variance
 
 	// 目標 AC 減傷
@@ -974,8 +996,8 @@ func (s *CompanionAISystem) hierarchTeleportToMaster(h *world.HierarchInfo, mast
 	}
 
 	// 瞬移到主人附近
-	newX := master.X + int32(world.RandInt(3)) - 1
-	newY := master.Y + int32(world.RandInt(3)) - 1
+	newX := master.X + int32(world.RandInt(s.deps.Rand, 3)) - 1
+	newY := master.Y + int32(world.RandInt(s.deps.Rand, 3)) - 1
 	ws.TeleportHierarch(h.ID, newX, newY, master.MapID, master.Heading)
 
 	// 對新位置觀察者發送出生封包
@@ -1020,7 +1042,7 @@ func (s *CompanionAISystem) hierarchAutoBuff(h *world.HierarchInfo, master *worl
 		threshold := master.MaxHP * int32(h.HealThreshold) / 10
 		if master.HP < threshold {
 			// 回復量 = 30 + 隨機 0-75（Java: lawful + random(75)）
-			heal := int32(30 + world.RandInt(76))
+			heal := int32(30 + world.RandInt(s.deps.Rand, 76))
 			master.HP += heal
 			if master.HP > master.MaxHP {
 				master.HP = master.MaxHP
@@ -1028,7 +1050,7 @@ func (s *CompanionAISystem) hierarchAutoBuff(h *world.HierarchInfo, master *worl
 			h.MP -= 15
 
 			// 更新主人 HP + 治療特效
-			handler.SendPlayerStatus(master.Session, master)
+			handler.SendPlayerStatus(master.Session, master, s.deps)
 			nearby := s.world.GetNearbyPlayersAt(master.X, master.Y, master.MapID)
 			gfxData := handler.BuildSkillEffect(master.CharID, 6321)
 			handler.BroadcastToPlayers(nearby, gfxData)
@@ -1043,7 +1065,8 @@ func (s *CompanionAISystem) hierarchAutoBuff(h *world.HierarchInfo, master *worl
 type updatePosFunc func(id int32, x, y int32, heading int16)
 
 // companionMoveToward moves a companion one step toward the target position.
-// Uses the same pathfinding logic as npcMoveToward.
+// Uses the same cached A* pathfinding as npcMoveToward, with a greedy step
+// as fallback when no path is found.
 func (s *CompanionAISystem) companionMoveToward(objID int32, curX, curY int32, mapID int16, tx, ty int32, updatePos updatePosFunc) {
 	dx := tx - curX
 	dy := ty - curY
@@ -1051,6 +1074,24 @@ func (s *CompanionAISystem) companionMoveToward(objID int32, curX, curY int32, m
 		return
 	}
 
+	if s.deps.MapData != nil {
+		p := s.paths[objID]
+		if p == nil {
+			p = &companionPath{}
+			s.paths[objID] = p
+		}
+		p.lastTick = s.ticks
+		if step, ok := nextPathStep(s.world, s.deps.MapData, &p.cache, mapID, curX, curY, tx, ty); ok {
+			h := calcNpcHeading(curX, curY, step.X, step.Y)
+			updatePos(objID, step.X, step.Y, h)
+			nearby := s.world.GetNearbyPlayersAt(step.X, step.Y, mapID)
+			for _, viewer := range nearby {
+				sendCompanionMovePacket(viewer.Session, objID, curX, curY, h)
+			}
+			return
+		}
+	}
+
 	type candidate struct{ x, y int32 }
 	candidates := make([]candidate, 0, 3)
 
@@ -1119,8 +1160,8 @@ func (s *CompanionAISystem) companionMoveToward(objID int32, curX, curY int32, m
 
 // companionTeleportToMaster moves a companion directly to the master (cross-map or long distance).
 func (s *CompanionAISystem) companionTeleportToMaster(objID int32, master *world.PlayerInfo, updatePos updatePosFunc) {
-	newX := master.X + int32(world.RandInt(3)) - 1
-	newY := master.Y + int32(world.RandInt(3)) - 1
+	newX := master.X + int32(world.RandInt(s.deps.Rand, 3)) - 1
+	newY := master.Y + int32(world.RandInt(s.deps.Rand, 3)) - 1
 	updatePos(objID, newX, newY, master.Heading)
 }
 
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math"
 
+	"github.com/l1jgo/server/internal/core/event"
 	"github.com/l1jgo/server/internal/data"
 	"github.com/l1jgo/server/internal/handler"
 	"github.com/l1jgo/server/internal/net"
@@ -178,6 +179,7 @@ func (s *CraftSystem) ExecuteCraft(sess *net.Session, player *world.PlayerInfo,
 			if take > slot.Count {
 				take = slot.Count
 			}
+			handler.EmitItemMoved(s.deps, event.ItemReasonCraftUse, slot, take, handler.CharLoc(player.CharID), "", player)
 			removed := player.Inv.RemoveItem(slot.ObjectID, take)
 			if removed {
 				handler.SendRemoveInventoryItem(sess, slot.ObjectID)
@@ -212,7 +214,7 @@ func (s *CraftSystem) ExecuteCraft(sess *net.Session, player *world.PlayerInfo,
 
 	if recipe.AllInOnce || amount == 1 {
 		// 一次判定模式（或單件製作）
-		if successRate >= 100 || world.RandInt(1000) < int(successRate)*10 {
+		if successRate >= 100 || world.RandInt(s.deps.Rand, 1000) < int(successRate)*10 {
 			s.produceItems(sess, player, npc, recipe, amount, npcName)
 		} else {
 			s.produceFailed(sess, player, recipe, npcObjID)
@@ -221,7 +223,7 @@ func (s *CraftSystem) ExecuteCraft(sess *net.Session, player *world.PlayerInfo,
 		// 逐件判定模式
 		var successCount, failCount int32
 		for i := int32(0); i < amount; i++ {
-			if successRate >= 100 || world.RandInt(1000) < int(successRate)*10 {
+			if successRate >= 100 || world.RandInt(s.deps.Rand, 1000) < int(successRate)*10 {
 				successCount++
 			} else {
 				failCount++
@@ -259,6 +261,7 @@ func (s *CraftSystem) produceItems(sess *net.Session, player *world.PlayerInfo,
 			if out.EnchantLvl > 0 {
 				item.EnchantLvl = int8(out.EnchantLvl)
 			}
+			handler.EmitItemMoved(s.deps, event.ItemReasonCraft, item, totalCount, "", handler.CharLoc(player.CharID), player)
 			handler.SendAddItem(sess, item, outInfo)
 		} else {
 			for i := int32(0); i < totalCount; i++ {
@@ -273,6 +276,7 @@ func (s *CraftSystem) produceItems(sess *net.Session, player *world.PlayerInfo,
 					item.EnchantLvl = int8(out.EnchantLvl)
 				}
 				item.Identified = true
+				handler.EmitItemMoved(s.deps, event.ItemReasonCraft, item, 1, "", handler.CharLoc(player.CharID), player)
 				handler.SendAddItem(sess, item, outInfo)
 			}
 		}
@@ -291,12 +295,14 @@ func (s *CraftSystem) produceItems(sess *net.Session, player *world.PlayerInfo,
 				item := player.Inv.AddItem(recipe.BonusItemID, totalBonus, bonusInfo.Name,
 					bonusInfo.InvGfx, bonusInfo.Weight, true, byte(bonusInfo.Bless))
 				item.UseType = data.UseTypeToID(bonusInfo.UseType)
+				handler.EmitItemMoved(s.deps, event.ItemReasonCraft, item, totalBonus, "", handler.CharLoc(player.CharID), player)
 				handler.SendAddItem(sess, item, bonusInfo)
 			} else {
 				for i := int32(0); i < totalBonus; i++ {
 					item := player.Inv.AddItem(recipe.BonusItemID, 1, bonusInfo.Name,
 						bonusInfo.InvGfx, bonusInfo.Weight, false, byte(bonusInfo.Bless))
 					item.UseType = data.UseTypeToID(bonusInfo.UseType)
+					handler.EmitItemMoved(s.deps, event.ItemReasonCraft, item, 1, "", handler.CharLoc(player.CharID), player)
 					handler.SendAddItem(sess, item, bonusInfo)
 				}
 			}
@@ -342,12 +348,14 @@ func (s *CraftSystem) produceResidueItems(sess *net.Session, player *world.Playe
 		item := player.Inv.AddItem(recipe.ResidueItemID, totalRes, resInfo.Name,
 			resInfo.InvGfx, resInfo.Weight, true, byte(resInfo.Bless))
 		item.UseType = data.UseTypeToID(resInfo.UseType)
+		handler.EmitItemMoved(s.deps, event.ItemReasonCraft, item, totalRes, "", handler.CharLoc(player.CharID), player)
 		handler.SendAddItem(sess, item, resInfo)
 	} else {
 		for i := int32(0); i < totalRes; i++ {
 			item := player.Inv.AddItem(recipe.ResidueItemID, 1, resInfo.Name,
 				resInfo.InvGfx, resInfo.Weight, false, byte(resInfo.Bless))
 			item.UseType = data.UseTypeToID(resInfo.UseType)
+			handler.EmitItemMoved(s.deps, event.ItemReasonCraft, item, 1, "", handler.CharLoc(player.CharID), player)
 			handler.SendAddItem(sess, item, resInfo)
 		}
 	}
@@ -0,0 +1,25 @@
+package system
+
+import (
+	"time"
+
+	coresys "github.com/l1jgo/server/internal/core/system"
+	"github.com/l1jgo/server/internal/persist"
+)
+
+// DBJobSystem 在遊戲迴圈上執行已完成資料庫工作的回呼（handler.RunDBJob）。
+// Phase 1（PreUpdate）：回呼送出的封包於同一 tick 的 Phase 4 發送，
+// 而回呼結束後連線恢復輸入，下一次輸入輪詢即處理後續封包。
+type DBJobSystem struct {
+	queue *persist.JobQueue
+}
+
+func NewDBJobSystem(queue *persist.JobQueue) *DBJobSystem {
+	return &DBJobSystem{queue: queue}
+}
+
+func (s *DBJobSystem) Phase() coresys.Phase { return coresys.PhasePreUpdate }
+
+func (s *DBJobSystem) Update(_ time.Duration) {
+	s.queue.RunCompletions()
+}
@@ -141,7 +141,7 @@ func (s *DeathSystem) ProcessRestart(sess *net.Session, player *world.PlayerInfo
 	handler.SendPutObject(sess, player)
 
 	// 發送狀態更新
-	handler.SendPlayerStatus(sess, player)
+	handler.SendPlayerStatus(sess, player, s.deps)
 
 	// 重置 Known 集合
 	if player.Known == nil {
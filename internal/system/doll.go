@@ -62,8 +62,8 @@ func (s *DollSystem) UseDoll(sess *net.Session, player *world.PlayerInfo, invIte
 		GfxID:       dollDef.GfxID,
 		NameID:      dollDef.NameID,
 		Name:        dollDef.Name,
-		X:           player.X + int32(world.RandInt(5)) - 2,
-		Y:           player.Y + int32(world.RandInt(5)) - 2,
+		X:           player.X + int32(world.RandInt(s.deps.Rand, 5)) - 2,
+		Y:           player.Y + int32(world.RandInt(s.deps.Rand, 5)) - 2,
 		MapID:       player.MapID,
 		Heading:     player.Heading,
 		TimerTicks:  dollDef.Duration * 5, // 秒 → ticks（5 ticks/sec）
@@ -128,7 +128,7 @@ func (s *DollSystem) UseDoll(sess *net.Session, player *world.PlayerInfo, invIte
 
 	// 套用屬性加成
 	s.applyDollBonuses(player, doll)
-	handler.SendPlayerStatus(sess, player)
+	handler.SendPlayerStatus(sess, player, s.deps)
 
 	// 註冊到世界
 	ws.AddDoll(doll)
@@ -165,7 +165,7 @@ func (s *DollSystem) DismissDoll(doll *world.DollInfo, player *world.PlayerInfo)
 	// 解散音效 + 清除計時器 + 更新狀態
 	handler.SendCompanionEffect(player.Session, doll.ID, 5936) // 解散音效
 	handler.SendDollTimer(player.Session, 0)                    // 清除計時器
-	handler.SendPlayerStatus(player.Session, player)
+	handler.SendPlayerStatus(player.Session, player, s.deps)
 }
 
 // RemoveDollBonuses 僅還原娃娃屬性加成（不移除世界實體）。
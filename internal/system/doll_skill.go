@@ -27,7 +27,7 @@ func processDollSkillProc(player *world.PlayerInfo, npc *world.NpcInfo, nearby [
 		}
 
 		// Java: random.nextInt(100) <= _r（包含邊界）
-		if world.RandInt(100) > doll.SkillChance {
+		if world.RandInt(deps.Rand, 100) > doll.SkillChance {
 			continue
 		}
 
@@ -40,7 +40,7 @@ func processDollSkillProc(player *world.PlayerInfo, npc *world.NpcInfo, nearby [
 		// 計算傷害（Java: L1Magic.calcMagicDamage 上限 200）
 		dmg := int32(skillInfo.DamageValue)
 		if skillInfo.DamageDice > 0 {
-			dmg += int32(world.RandInt(skillInfo.DamageDice + 1))
+			dmg += int32(world.RandInt(deps.Rand, skillInfo.DamageDice+1))
 		}
 		if dmg > 200 {
 			dmg = 200
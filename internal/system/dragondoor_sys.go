@@ -12,7 +12,6 @@ package system
 // 計時器以 tick 計數驅動（5 ticks ≈ 1 秒），Phase 3（PostUpdate）。
 
 import (
-	"math/rand"
 	"time"
 
 	coresys "github.com/l1jgo/server/internal/core/system"
@@ -161,8 +160,8 @@ func (s *DragonDoorSystem) SpawnKeeper(sess *net.Session, player *world.PlayerIn
 	}
 
 	// 計算生成座標（玩家附近 ±2）
-	x := player.X + int32(rand.Intn(5)) - 2
-	y := player.Y + int32(rand.Intn(5)) - 2
+	x := player.X + int32(s.deps.Rand.Intn(5)) - 2
+	y := player.Y + int32(s.deps.Rand.Intn(5)) - 2
 
 	// 解析動畫速度
 	atkSpeed := tmpl.AtkSpeed
@@ -198,7 +197,7 @@ func (s *DragonDoorSystem) SpawnKeeper(sess *net.Session, player *world.PlayerIn
 		X:            x,
 		Y:            y,
 		MapID:        player.MapID,
-		Heading:      int16(rand.Intn(8)),
+		Heading:      int16(s.deps.Rand.Intn(8)),
 		HP:           tmpl.HP,
 		MaxHP:        tmpl.HP,
 		MP:           tmpl.MP,
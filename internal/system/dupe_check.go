@@ -0,0 +1,301 @@
+package system
+
+// dupe_check.go — 複製物品偵測（anti_cheat.duplicate_item_check）。
+// 同一 obj_id 只能存在一份：線上背包之間、character_items 與 warehouse_items 之間
+// 出現多份即視為複製，所有副本移入 item_quarantine 待 GM 處理。
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/l1jgo/server/internal/core/event"
+	coresys "github.com/l1jgo/server/internal/core/system"
+	"github.com/l1jgo/server/internal/handler"
+	"github.com/l1jgo/server/internal/persist"
+	"github.com/l1jgo/server/internal/world"
+	"go.uber.org/zap"
+)
+
+// dupeScanLimit 是每次全面掃描最多處理的重複 obj_id 數。
+const dupeScanLimit = 500
+
+// 隔離原因（item_quarantine.reason）
+const (
+	dupeReasonOnline   = "online"   // 線上背包之間重複
+	dupeReasonAutosave = "autosave" // 自動存檔檢查
+	dupeReasonDBScan   = "db_scan"  // 資料庫全面掃描
+)
+
+// itemLocQuarantine 是隔離區的位置字串（item_events.to_loc）。
+const itemLocQuarantine = "quarantine"
+
+// dupeHolder 是線上背包中持有某 obj_id 的一份物品。
+type dupeHolder struct {
+	player *world.PlayerInfo
+	item   *world.InvItem
+}
+
+// DupeCheckSystem 每 interval 個 tick 掃描一次線上背包，並以資料庫工作找出
+// 兩張物品表中重複的 obj_id；自動存檔時另由 PersistenceSystem 呼叫 CheckPlayers
+// 檢查存檔中的角色。Phase 5（Persist）。
+//
+// 判定時線上角色以記憶體為準：線上角色的 character_items 列可能是尚未存檔的舊資料
+// （已交易、存入倉庫、掉落），只計算記憶體中的物品；離線角色的列與倉庫列照實計算。
+// 查詢與隔離都以資料庫工作（DBJobs）執行，判定與移除物品在 Phase 1 回呼中進行：
+// 回呼時重建線上索引，隔離期間持有者的連線暫停輸入（Session.BeginJob），
+// 資料庫副本在查詢後已不存在（例如已被領出）時由 Quarantine 回報 ErrQuarantineStale 而不隔離。
+type DupeCheckSystem struct {
+	deps      *handler.Deps
+	repo      persist.ItemDupeStore
+	interval  int
+	ticks     int
+	scanning  bool           // 全面掃描工作進行中
+	resolving map[int32]bool // 隔離工作進行中的 obj_id
+}
+
+func NewDupeCheckSystem(deps *handler.Deps, repo persist.ItemDupeStore, intervalTicks int) *DupeCheckSystem {
+	if intervalTicks <= 0 {
+		intervalTicks = 3000
+	}
+	return &DupeCheckSystem{deps: deps, repo: repo, interval: intervalTicks, resolving: make(map[int32]bool)}
+}
+
+func (s *DupeCheckSystem) Phase() coresys.Phase { return coresys.PhasePersist }
+
+func (s *DupeCheckSystem) Update(_ time.Duration) {
+	s.ticks++
+	if s.ticks < s.interval {
+		return
+	}
+	s.ticks = 0
+
+	index := s.onlineIndex()
+	for objID, holders := range index {
+		if len(holders) > 1 {
+			s.resolve(objID, holders, nil, dupeReasonOnline)
+		}
+	}
+
+	if s.repo == nil || s.deps.DBJobs == nil || s.scanning {
+		return
+	}
+	// 全表掃描在 worker 執行，只取得候選 obj_id；回呼時重新查詢候選的現況再判定。
+	var candidates []int32
+	s.scanning = true
+	if !s.deps.DBJobs.Submit("dupe_scan", func(ctx context.Context) error {
+		copies, err := s.repo.FindDuplicates(ctx, dupeScanLimit)
+		for i, c := range copies {
+			if i == 0 || c.ObjID != copies[i-1].ObjID {
+				candidates = append(candidates, c.ObjID)
+			}
+		}
+		return err
+	}, func(err error) {
+		s.scanning = false
+		if err != nil {
+			s.deps.Log.Error("複製物品掃描失敗", zap.Error(err))
+			return
+		}
+		s.check(candidates, dupeReasonDBScan)
+	}) {
+		s.scanning = false
+	}
+}
+
+// CheckPlayers 檢查即將存檔的角色持有的物品（自動存檔時呼叫）。查詢以資料庫工作執行，
+// 隔離的物品於回呼中從背包移除，角色標記為 Dirty，由下一次存檔刪除其列。
+func (s *DupeCheckSystem) CheckPlayers(players []*world.PlayerInfo) {
+	var ids []int32
+	for _, p := range players {
+		for _, it := range p.Inv.Items {
+			if it.ObjectID != 0 {
+				ids = append(ids, it.ObjectID)
+			}
+		}
+	}
+	s.check(ids, dupeReasonAutosave)
+}
+
+// check 以資料庫工作查詢 ids 在資料庫中的副本，回呼時連同線上背包一併判定。
+func (s *DupeCheckSystem) check(ids []int32, reason string) {
+	if len(ids) == 0 {
+		return
+	}
+	if s.repo == nil {
+		s.judge(ids, nil, reason)
+		return
+	}
+	var copies []persist.ItemCopy
+	s.runJob("dupe_check", func(ctx context.Context) error {
+		var err error
+		copies, err = s.repo.FindCopies(ctx, ids)
+		return err
+	}, func(err error) {
+		if err != nil {
+			s.deps.Log.Error("複製物品檢查查詢失敗", zap.String("reason", reason), zap.Error(err))
+			return
+		}
+		s.judge(ids, copies, reason)
+	})
+}
+
+// judge 以目前的線上背包與查得的資料庫副本判定 ids 是否重複。遊戲迴圈執行。
+func (s *DupeCheckSystem) judge(ids []int32, copies []persist.ItemCopy, reason string) {
+	index := s.onlineIndex()
+	byObj := make(map[int32][]persist.ItemCopy)
+	for _, c := range copies {
+		if c.Table == persist.TableCharacterItems && s.deps.World.GetByCharID(c.CharID) != nil {
+			continue // 線上角色以記憶體為準
+		}
+		byObj[c.ObjID] = append(byObj[c.ObjID], c)
+	}
+	seen := make(map[int32]bool, len(ids))
+	for _, objID := range ids {
+		if seen[objID] {
+			continue
+		}
+		seen[objID] = true
+		holders, dbCopies := index[objID], byObj[objID]
+		if len(holders)+len(dbCopies) > 1 {
+			s.resolve(objID, holders, dbCopies, reason)
+		}
+	}
+}
+
+// runJob 以資料庫工作執行 work，done 於遊戲迴圈收到結果；未設定 DBJobs 時同步執行。
+func (s *DupeCheckSystem) runJob(name string, work func(ctx context.Context) error, done func(err error)) {
+	if s.deps.DBJobs == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := work(ctx)
+		cancel()
+		done(err)
+		return
+	}
+	if !s.deps.DBJobs.Submit(name, work, done) {
+		done(persist.ErrJobQueueFull)
+	}
+}
+
+// onlineIndex 建立 obj_id → 線上持有者的索引。
+func (s *DupeCheckSystem) onlineIndex() map[int32][]dupeHolder {
+	index := make(map[int32][]dupeHolder)
+	s.deps.World.AllPlayers(func(p *world.PlayerInfo) {
+		for _, it := range p.Inv.Items {
+			if it.ObjectID != 0 {
+				index[it.ObjectID] = append(index[it.ObjectID], dupeHolder{player: p, item: it})
+			}
+		}
+	})
+	return index
+}
+
+// resolve 隔離 objID 的所有副本：以資料庫工作刪除資料庫副本並寫入 item_quarantine
+// （與線上副本的記錄同一交易），成功後才在回呼中從線上背包移除；記錄警告、寫入物品稽核
+// 並通知線上 GM。工作進行期間持有者的輸入暫停，物品不會在隔離途中被移走。
+func (s *DupeCheckSystem) resolve(objID int32, holders []dupeHolder, copies []persist.ItemCopy, reason string) {
+	if s.resolving[objID] {
+		return
+	}
+	held := make([]persist.QuarantineRow, 0, len(holders))
+	locs := make([]string, 0, len(holders)+len(copies))
+	for _, h := range holders {
+		src := handler.CharLoc(h.player.CharID)
+		held = append(held, persist.QuarantineRow{
+			ObjID: objID, ItemID: h.item.ItemID, Count: h.item.Count,
+			EnchantLvl: int16(h.item.EnchantLvl), Bless: int16(h.item.Bless), Identified: h.item.Identified,
+			Source: src, Reason: reason,
+		})
+		locs = append(locs, src+"("+h.player.Name+")")
+	}
+	for _, c := range copies {
+		locs = append(locs, c.Loc())
+	}
+
+	if s.repo == nil {
+		s.quarantined(objID, holders, copies, locs, reason)
+		return
+	}
+	s.resolving[objID] = true
+	for _, h := range holders {
+		h.player.Session.BeginJob()
+	}
+	s.runJob("dupe_quarantine", func(ctx context.Context) error {
+		return s.repo.Quarantine(ctx, copies, held, reason)
+	}, func(err error) {
+		delete(s.resolving, objID)
+		for _, h := range holders {
+			h.player.Session.EndJob()
+		}
+		switch {
+		case errors.Is(err, persist.ErrQuarantineStale):
+			s.deps.Log.Debug("複製物品已不重複，略過隔離", zap.Int32("obj_id", objID))
+		case err != nil:
+			s.deps.Log.Error("複製物品隔離失敗", zap.Int32("obj_id", objID), zap.Error(err))
+		default:
+			s.quarantined(objID, holders, copies, locs, reason)
+		}
+	})
+}
+
+// quarantined 在隔離記錄寫入後移除線上副本與倉庫快取、寫入物品稽核並通知 GM。
+func (s *DupeCheckSystem) quarantined(objID int32, holders []dupeHolder, copies []persist.ItemCopy, locs []string, reason string) {
+	var itemID int32
+	for _, h := range holders {
+		itemID = h.item.ItemID
+		s.removeHeld(h)
+	}
+	for _, c := range copies {
+		itemID = c.ItemID
+		s.dropWarehouseCache(c)
+		handler.EmitItemMoved(s.deps, event.ItemReasonQuarantine, &world.InvItem{
+			ObjectID: c.ObjID, ItemID: c.ItemID, EnchantLvl: int8(c.EnchantLvl),
+		}, c.Count, c.Loc(), itemLocQuarantine, nil)
+	}
+
+	s.deps.Log.Warn("偵測到複製物品，已隔離",
+		zap.Int32("obj_id", objID),
+		zap.Int32("item_id", itemID),
+		zap.String("reason", reason),
+		zap.Strings("locations", locs),
+	)
+	handler.BroadcastToGMs(s.deps.World, fmt.Sprintf("複製物品已隔離 obj=%d 物品=%d 位置=%s",
+		objID, itemID, strings.Join(locs, ", ")))
+}
+
+// removeHeld 將線上副本從背包移除（已裝備則先脫下），下次存檔即刪除其 DB 列。
+// 副本已不在持有者背包（例如隔離期間由交易對方完成交易）時只記錄警告。
+func (s *DupeCheckSystem) removeHeld(h dupeHolder) {
+	p, it := h.player, h.item
+	if p.Inv.FindByObjectID(it.ObjectID) != it {
+		s.deps.Log.Warn("隔離的複製物品已不在持有者背包", zap.String("char", p.Name), zap.Int32("obj_id", it.ObjectID))
+		return
+	}
+	if it.Equipped && s.deps.Equip != nil {
+		if slot := s.deps.Equip.FindEquippedSlot(p, it); slot != world.SlotNone {
+			s.deps.Equip.UnequipSlot(p.Session, p, slot)
+		}
+	}
+	handler.EmitItemMoved(s.deps, event.ItemReasonQuarantine, it, it.Count, handler.CharLoc(p.CharID), itemLocQuarantine, p)
+	p.Inv.RemoveItem(it.ObjectID, it.Count)
+	p.Dirty = true
+	handler.SendRemoveInventoryItem(p.Session, it.ObjectID)
+	handler.SendWeightUpdate(p.Session, p)
+}
+
+// dropWarehouseCache 從線上玩家已開啟的倉庫快取移除被隔離的倉庫列，避免再被領出。
+func (s *DupeCheckSystem) dropWarehouseCache(c persist.ItemCopy) {
+	if c.Table != persist.TableWarehouseItems {
+		return
+	}
+	s.deps.World.AllPlayers(func(p *world.PlayerInfo) {
+		for i, wc := range p.WarehouseItems {
+			if wc.DbID == c.RowID {
+				p.WarehouseItems = append(p.WarehouseItems[:i], p.WarehouseItems[i+1:]...)
+				return
+			}
+		}
+	})
+}
@@ -328,7 +328,7 @@ func (s *EquipSystem) RecalcEquipStats(sess *net.Session, player *world.PlayerIn
 	applyEquipStats(player, s.deps.Items, s.deps.ArmorSets)
 
 	// 發送更新封包
-	handler.SendPlayerStatus(sess, player)
+	handler.SendPlayerStatus(sess, player, s.deps)
 	handler.SendAbilityScores(sess, player)
 	handler.SendMagicStatus(sess, byte(player.SP), uint16(player.MR))
 
@@ -116,7 +116,7 @@ func (s *FishingSystem) Tick(player *world.PlayerInfo) {
 	s.deps.NpcSvc.ConsumeItem(player.Session, player, bait.ObjectID, 1)
 
 	// 隨機獲得魚
-	fish := rollFishReward()
+	fish := rollFishReward(s.deps.Rand)
 	if fish != 0 {
 		s.deps.Log.Debug("釣到魚",
 			zap.String("player", player.Name),
@@ -127,8 +127,8 @@ func (s *FishingSystem) Tick(player *world.PlayerInfo) {
 }
 
 // rollFishReward 隨機抽取釣魚獎勵。
-func rollFishReward() int32 {
-	roll := rand.Intn(1000000)
+func rollFishReward(rng *rand.Rand) int32 {
+	roll := rng.Intn(1000000)
 	for _, reward := range fishingRewards {
 		if int32(roll) < reward.Weight {
 			return reward.ItemID
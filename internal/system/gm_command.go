@@ -3,6 +3,7 @@ package system
 import (
 	"strings"
 
+	"github.com/l1jgo/server/internal/core/event"
 	"github.com/l1jgo/server/internal/handler"
 	"github.com/l1jgo/server/internal/net"
 	"github.com/l1jgo/server/internal/world"
@@ -30,7 +31,7 @@ func (s *GMCommandSystem) SetLevel(sess *net.Session, player *world.PlayerInfo,
 	player.HP = player.MaxHP
 	player.MP = player.MaxMP
 
-	handler.SendPlayerStatus(sess, player)
+	handler.SendPlayerStatus(sess, player, s.deps)
 	handler.SendExpUpdate(sess, player.Level, player.Exp)
 	handler.SendHpUpdate(sess, player)
 	handler.SendMpUpdate(sess, player)
@@ -48,7 +49,7 @@ func (s *GMCommandSystem) SetHP(sess *net.Session, player *world.PlayerInfo, hp
 		s.deps.World.OccupyEntity(player.MapID, player.X, player.Y, player.CharID)
 	}
 	handler.SendHpUpdate(sess, player)
-	handler.SendPlayerStatus(sess, player)
+	handler.SendPlayerStatus(sess, player, s.deps)
 }
 
 // SetMP 設定玩家 MP。
@@ -58,7 +59,7 @@ func (s *GMCommandSystem) SetMP(sess *net.Session, player *world.PlayerInfo, mp
 		player.MaxMP = player.MP
 	}
 	handler.SendMpUpdate(sess, player)
-	handler.SendPlayerStatus(sess, player)
+	handler.SendPlayerStatus(sess, player, s.deps)
 }
 
 // FullHeal 補滿 HP/MP（含死亡復活處理）。
@@ -90,7 +91,7 @@ func (s *GMCommandSystem) SetStat(sess *net.Session, player *world.PlayerInfo, s
 	case "cha":
 		player.Cha = value
 	}
-	handler.SendPlayerStatus(sess, player)
+	handler.SendPlayerStatus(sess, player, s.deps)
 }
 
 // GiveItem 給予物品。
@@ -113,6 +114,7 @@ func (s *GMCommandSystem) GiveItem(sess *net.Session, player *world.PlayerInfo,
 	if itemInfo.MaxChargeCount > 0 {
 		invItem.ChargeCount = int16(itemInfo.MaxChargeCount)
 	}
+	handler.EmitItemMoved(s.deps, event.ItemReasonGMCreate, invItem, count, "", handler.CharLoc(player.CharID), player)
 
 	if wasExisting {
 		handler.SendItemCountUpdate(sess, invItem)
@@ -8,14 +8,15 @@ import (
 	"github.com/l1jgo/server/internal/world"
 )
 
-// GroundItemSystem removes expired ground items and broadcasts S_RemoveObject
-// to nearby players. Phase 3 (PostUpdate).
+// GroundItemSystem removes expired ground items, broadcasts S_RemoveObject
+// to nearby players and records the loss in item_events. Phase 3 (PostUpdate).
 type GroundItemSystem struct {
 	world *world.State
+	deps  *handler.Deps
 }
 
-func NewGroundItemSystem(ws *world.State) *GroundItemSystem {
-	return &GroundItemSystem{world: ws}
+func NewGroundItemSystem(ws *world.State, deps *handler.Deps) *GroundItemSystem {
+	return &GroundItemSystem{world: ws, deps: deps}
 }
 
 func (s *GroundItemSystem) Phase() coresys.Phase { return coresys.PhasePostUpdate }
@@ -23,6 +24,7 @@ func (s *GroundItemSystem) Phase() coresys.Phase { return coresys.PhasePostUpdat
 func (s *GroundItemSystem) Update(_ time.Duration) {
 	expired := s.world.TickGroundItems()
 	for _, g := range expired {
+		handler.EmitGroundItemExpired(s.deps, g)
 		nearby := s.world.GetNearbyPlayersAt(g.X, g.Y, g.MapID)
 		data := handler.BuildRemoveObject(g.ID)
 		handler.BroadcastToPlayers(nearby, data)
@@ -46,8 +46,8 @@ func (s *HierarchSystem) UseHierarch(sess *net.Session, player *world.PlayerInfo
 		GfxID:         def.GfxID,
 		Name:          def.Name,
 		NameID:        def.NameID,
-		X:             player.X + int32(world.RandInt(3)) - 1,
-		Y:             player.Y + int32(world.RandInt(3)) - 1,
+		X:             player.X + int32(world.RandInt(s.deps.Rand, 3)) - 1,
+		Y:             player.Y + int32(world.RandInt(s.deps.Rand, 3)) - 1,
 		MapID:         player.MapID,
 		Heading:       player.Heading,
 		HP:            def.HP,
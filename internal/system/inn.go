@@ -35,7 +35,7 @@ func (s *InnSystem) ReturnRoom(sess *net.Session, player *world.PlayerInfo, npcO
 		return
 	}
 
-	now := time.Now()
+	now := s.deps.Clock.Now()
 	price := int32(0)
 	found := false
 
@@ -47,7 +47,7 @@ func (s *InnSystem) ReturnRoom(sess *net.Session, player *world.PlayerInfo, npcO
 		if now.Before(room.DueTime) {
 			price += 60
 		}
-		room.DueTime = time.Now()
+		room.DueTime = s.deps.Clock.Now()
 		room.LodgerID = 0
 		room.KeyID = 0
 		room.Hall = false
@@ -121,7 +121,7 @@ func (s *InnSystem) RentRoom(sess *net.Session, player *world.PlayerInfo, npcObj
 	if room == nil {
 		return
 	}
-	now := time.Now()
+	now := s.deps.Clock.Now()
 	if now.Before(room.DueTime) {
 		handler.SendHypertext(sess, npcObjID, "")
 		return
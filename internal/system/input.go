@@ -22,13 +22,13 @@ type InputSystem struct {
 	store       *net.SessionStore
 	maxPerTick  int
 	log         *zap.Logger
-	accountRepo *persist.AccountRepo
-	charRepo    *persist.CharacterRepo
-	itemRepo    *persist.ItemRepo
-	buffRepo    *persist.BuffRepo
+	accountRepo persist.AccountStore
+	charRepo    persist.CharacterStore
+	itemRepo    persist.ItemStore
+	buffRepo    persist.BuffStore
 	worldState   *world.State
 	mapData      *data.MapDataTable
-	petRepo      *persist.PetRepo
+	petRepo      persist.PetStore
 	hauntedHouse handler.HauntedHouseManager // 鬼屋副本（斷線時移除成員）
 }
 
@@ -37,13 +37,13 @@ func NewInputSystem(
 	registry *packet.Registry,
 	store *net.SessionStore,
 	maxPerTick int,
-	accountRepo *persist.AccountRepo,
-	charRepo *persist.CharacterRepo,
-	itemRepo *persist.ItemRepo,
-	buffRepo *persist.BuffRepo,
+	accountRepo persist.AccountStore,
+	charRepo persist.CharacterStore,
+	itemRepo persist.ItemStore,
+	buffRepo persist.BuffStore,
 	worldState *world.State,
 	mapData *data.MapDataTable,
-	petRepo *persist.PetRepo,
+	petRepo persist.PetStore,
 	log *zap.Logger,
 ) *InputSystem {
 	return &InputSystem{
@@ -94,6 +94,10 @@ doneDead:
 
 	// Drain packets from each session (up to maxPerTick per session)
 	for id, sess := range s.store.Raw() {
+		// 非同步 DB 工作進行中：保留輸入封包，斷線清理也延後到工作完成（Phase 1 回呼）後
+		if sess.JobPending() {
+			continue
+		}
 		if sess.IsClosed() {
 			// Drain any remaining packets BEFORE cleanup (e.g. C_SAVEIO sent just before disconnect).
 			// Use the last known state so handlers like HandleCharConfig can still find the player.
@@ -370,7 +374,7 @@ func (s *InputSystem) handleDisconnect(sess *net.Session) {
 		// Save inventory items to DB
 		if s.itemRepo != nil {
 			ctx2, cancel2 := context.WithTimeout(context.Background(), 5*time.Second)
-			if err := s.itemRepo.SaveInventory(ctx2, player.CharID, player.Inv, &player.Equip); err != nil {
+			if _, err := s.itemRepo.SaveInventory(ctx2, player.CharID, player.Inv, &player.Equip); err != nil {
 				s.log.Error("斷線存檔背包失敗",
 					zap.String("name", player.Name),
 					zap.Error(err),
@@ -0,0 +1,81 @@
+package system
+
+import (
+	"context"
+	"time"
+
+	"github.com/l1jgo/server/internal/core/event"
+	coresys "github.com/l1jgo/server/internal/core/system"
+	"github.com/l1jgo/server/internal/persist"
+	"go.uber.org/zap"
+)
+
+// itemAuditMaxPending 是寫入受阻時最多保留的稽核事件數，超過則丟棄最舊的。
+const itemAuditMaxPending = 20000
+
+// ItemAuditSystem 訂閱 event.ItemMoved，於 Phase 5（Persist）把累積的事件
+// 以資料庫工作批次寫入 item_events。佇列已滿時保留到下一個 tick 再送。
+type ItemAuditSystem struct {
+	repo    persist.ItemEventStore
+	jobs    *persist.JobQueue
+	log     *zap.Logger
+	pending []persist.ItemEventRow
+}
+
+func NewItemAuditSystem(bus *event.Bus, repo persist.ItemEventStore, jobs *persist.JobQueue, log *zap.Logger) *ItemAuditSystem {
+	s := &ItemAuditSystem{repo: repo, jobs: jobs, log: log}
+	event.Subscribe(bus, s.onItemMoved)
+	return s
+}
+
+func (s *ItemAuditSystem) Phase() coresys.Phase { return coresys.PhasePersist }
+
+func (s *ItemAuditSystem) onItemMoved(ev event.ItemMoved) {
+	if len(s.pending) >= itemAuditMaxPending {
+		s.log.Warn("物品稽核事件積壓過多，丟棄最舊事件", zap.Int("pending", len(s.pending)))
+		s.pending = s.pending[1:]
+	}
+	s.pending = append(s.pending, persist.ItemEventRow{
+		ObjID:      ev.ObjID,
+		ItemID:     ev.ItemID,
+		Count:      ev.Count,
+		EnchantLvl: int16(ev.EnchantLvl),
+		From:       ev.From,
+		To:         ev.To,
+		Reason:     ev.Reason,
+		MapID:      ev.MapID,
+		X:          ev.X,
+		Y:          ev.Y,
+		CreatedAt:  ev.At,
+	})
+}
+
+func (s *ItemAuditSystem) Update(_ time.Duration) {
+	if len(s.pending) == 0 {
+		return
+	}
+	batch := s.pending
+	if !s.jobs.Submit("item_events", func(ctx context.Context) error {
+		return s.repo.Append(ctx, batch)
+	}, func(err error) {
+		if err != nil {
+			s.log.Error("寫入物品稽核事件失敗", zap.Int("筆數", len(batch)), zap.Error(err))
+		}
+	}) {
+		return // 佇列已滿，下個 tick 再送
+	}
+	s.pending = nil
+}
+
+// Flush 同步寫入尚未送出的事件。關機時於關閉資料庫工作佇列前呼叫。
+func (s *ItemAuditSystem) Flush() {
+	if len(s.pending) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.repo.Append(ctx, s.pending); err != nil {
+		s.log.Error("寫入物品稽核事件失敗", zap.Int("筆數", len(s.pending)), zap.Error(err))
+	}
+	s.pending = nil
+}
@@ -6,6 +6,7 @@ package system
 import (
 	"fmt"
 
+	"github.com/l1jgo/server/internal/core/event"
 	"github.com/l1jgo/server/internal/handler"
 	"github.com/l1jgo/server/internal/net"
 	"github.com/l1jgo/server/internal/world"
@@ -41,6 +42,7 @@ func (s *ItemGroundSystem) DestroyItem(sess *net.Session, player *world.PlayerIn
 		count = item.Count
 	}
 
+	handler.EmitItemMoved(s.deps, event.ItemReasonDestroy, item, count, handler.CharLoc(player.CharID), "", player)
 	removed := player.Inv.RemoveItem(objectID, count)
 	if removed {
 		handler.SendRemoveInventoryItem(sess, objectID)
@@ -79,7 +81,12 @@ func (s *ItemGroundSystem) DropItem(sess *net.Session, player *world.PlayerInfo,
 	itemID := item.ItemID
 	itemName := item.Name
 	enchantLvl := item.EnchantLvl
+	keepObjID := int32(0)
+	if !item.Stackable {
+		keepObjID = item.ObjectID
+	}
 
+	handler.EmitItemMoved(s.deps, event.ItemReasonGroundDrop, item, count, handler.CharLoc(player.CharID), handler.ItemLocGround, player)
 	removed := player.Inv.RemoveItem(objectID, count)
 	if removed {
 		handler.SendRemoveInventoryItem(sess, objectID)
@@ -122,6 +129,7 @@ func (s *ItemGroundSystem) DropItem(sess *net.Session, player *world.PlayerInfo,
 		OwnerID:    player.CharID,
 		TTL:        5 * 60 * 5, // 5 分鐘（200ms tick）
 		NoExpire:   inHouse,
+		ObjID:      keepObjID,
 	}
 	s.deps.World.AddGroundItem(gndItem)
 
@@ -218,7 +226,13 @@ func (s *ItemGroundSystem) PickupItem(sess *net.Session, player *world.PlayerInf
 	if itemInfo != nil {
 		bless = byte(itemInfo.Bless)
 	}
-	invItem := player.Inv.AddItem(
+	// 非堆疊物品沿用掉落前的 ObjectID（item_events 歷史連續）
+	keepObjID := int32(0)
+	if !stackable {
+		keepObjID = gndItem.ObjID
+	}
+	invItem := player.Inv.AddItemWithID(
+		keepObjID,
 		gndItem.ItemID,
 		gndItem.Count,
 		itemName,
@@ -237,6 +251,7 @@ func (s *ItemGroundSystem) PickupItem(sess *net.Session, player *world.PlayerInf
 	} else {
 		handler.SendAddItem(sess, invItem)
 	}
+	handler.EmitItemMoved(s.deps, event.ItemReasonPickup, invItem, gndItem.Count, handler.ItemLocGround, handler.CharLoc(player.CharID), player)
 
 	// 更新負重條
 	handler.SendWeightUpdate(sess, player)
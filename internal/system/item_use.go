@@ -4,8 +4,8 @@ import (
 	"fmt"
 	"math/rand"
 	"strconv"
-	"time"
 
+	"github.com/l1jgo/server/internal/core/event"
 	"github.com/l1jgo/server/internal/data"
 	"github.com/l1jgo/server/internal/handler"
 	"github.com/l1jgo/server/internal/net"
@@ -46,7 +46,7 @@ func (s *ItemUseSystem) UseConsumable(sess *net.Session, player *world.PlayerInf
 			// Java ref: Potion.UseHeallingPotion — 總是消耗、總是播放音效/訊息。
 			// 高斯隨機 ±20%: healHp *= (gaussian/5 + 1)
 			if pot.Amount > 0 {
-				healAmt := float64(pot.Amount) * (rand.NormFloat64()/5.0 + 1.0)
+				healAmt := float64(pot.Amount) * (s.deps.Rand.NormFloat64()/5.0 + 1.0)
 				if healAmt < 1 {
 					healAmt = 1
 				}
@@ -71,7 +71,7 @@ func (s *ItemUseSystem) UseConsumable(sess *net.Session, player *world.PlayerInf
 			if pot.Amount > 0 {
 				mpAmt := pot.Amount
 				if pot.Range > 0 {
-					mpAmt = pot.Amount + rand.Intn(pot.Range)
+					mpAmt = pot.Amount + s.deps.Rand.Intn(pot.Range)
 				}
 				if player.MP < player.MaxMP {
 					player.MP += int32(mpAmt)
@@ -172,7 +172,7 @@ func (s *ItemUseSystem) UseConsumable(sess *net.Session, player *world.PlayerInf
 			}
 			// 飽食度達 225 時記錄生存吶喊計時（Java: set_h_time）
 			if player.Food >= 225 {
-				player.FoodFullTime = time.Now().Unix()
+				player.FoodFullTime = s.deps.Clock.Now().Unix()
 			}
 			handler.SendFoodUpdate(sess, player.Food)
 			player.Dirty = true
@@ -268,6 +268,7 @@ func (s *ItemUseSystem) EnchantItem(sess *net.Session, r *packet.Reader, player
 	switch result.Result {
 	case "success":
 		target.EnchantLvl += int8(result.Amount)
+		handler.EmitItemMoved(s.deps, event.ItemReasonEnchant, target, target.Count, handler.CharLoc(player.CharID), handler.CharLoc(player.CharID), player)
 		handler.SendItemStatusUpdate(sess, target, targetInfo)
 		handler.SendItemNameUpdate(sess, target, targetInfo)
 		sendEffectOnPlayer(sess, player.CharID, 2583) // 衝裝成功 GFX
@@ -305,6 +306,7 @@ func (s *ItemUseSystem) EnchantItem(sess *net.Session, r *packet.Reader, player
 				s.deps.Equip.UnequipSlot(sess, player, slot)
 			}
 		}
+		handler.EmitItemMoved(s.deps, event.ItemReasonEnchantBreak, target, target.Count, handler.CharLoc(player.CharID), "", player)
 		player.Inv.RemoveItem(target.ObjectID, target.Count)
 		handler.SendRemoveInventoryItem(sess, target.ObjectID)
 		handler.SendWeightUpdate(sess, player)
@@ -314,6 +316,7 @@ func (s *ItemUseSystem) EnchantItem(sess *net.Session, r *packet.Reader, player
 	case "minus":
 		// 詛咒卷軸: -N
 		target.EnchantLvl -= int8(result.Amount)
+		handler.EmitItemMoved(s.deps, event.ItemReasonEnchant, target, target.Count, handler.CharLoc(player.CharID), handler.CharLoc(player.CharID), player)
 		handler.SendItemStatusUpdate(sess, target, targetInfo)
 		handler.SendItemNameUpdate(sess, target, targetInfo)
 
@@ -482,15 +485,7 @@ func (s *ItemUseSystem) UseTeleportScroll(sess *net.Session, r *packet.Reader, p
 	}
 
 	if target != nil {
-		// 書籤傳送
-		removed := player.Inv.RemoveItem(invItem.ObjectID, 1)
-		if removed {
-			handler.SendRemoveInventoryItem(sess, invItem.ObjectID)
-		} else {
-			handler.SendItemCountUpdate(sess, invItem)
-		}
-		handler.SendWeightUpdate(sess, player)
-
+		// 書籤傳送（驗證通過、傳送完成後才消耗卷軸）
 		// 出發特效
 		sendEffectOnPlayer(sess, player.CharID, 169)
 		bkNearby := s.deps.World.GetNearbyPlayers(player.X, player.Y, player.MapID, sess.ID)
@@ -498,19 +493,17 @@ func (s *ItemUseSystem) UseTeleportScroll(sess *net.Session, r *packet.Reader, p
 			sendEffectOnPlayer(viewer.Session, player.CharID, 169)
 		}
 
-		handler.TeleportPlayer(sess, player, target.X, target.Y, target.MapID, 5, s.deps)
+		if !handler.TeleportPlayerFrom(sess, player, target.X, target.Y, target.MapID, 5, world.TeleportSource{
+			Path: world.TeleportPathBookmark, ItemObjID: invItem.ObjectID, BookmarkID: target.ID,
+		}, s.deps) {
+			return
+		}
+		consumePlayerItem(sess, player, invItem, 1)
+		handler.SendWeightUpdate(sess, player)
 
 		s.deps.Log.Info(fmt.Sprintf("書籤傳送  角色=%s  書籤=%s  x=%d  y=%d  地圖=%d", player.Name, target.Name, target.X, target.Y, target.MapID))
 	} else {
 		// 無書籤 → 200 格內隨機傳送 (Java: randomLocation(200, true))
-		removed := player.Inv.RemoveItem(invItem.ObjectID, 1)
-		if removed {
-			handler.SendRemoveInventoryItem(sess, invItem.ObjectID)
-		} else {
-			handler.SendItemCountUpdate(sess, invItem)
-		}
-		handler.SendWeightUpdate(sess, player)
-
 		curMap := player.MapID
 		newX := player.X
 		newY := player.Y
@@ -538,8 +531,8 @@ func (s *ItemUseSystem) UseTeleportScroll(sess *net.Session, r *packet.Reader, p
 		diffY := maxRY - minRY
 		if diffX > 0 && diffY > 0 {
 			for attempt := 0; attempt < 40; attempt++ {
-				rx := minRX + int32(world.RandInt(int(diffX)+1))
-				ry := minRY + int32(world.RandInt(int(diffY)+1))
+				rx := minRX + int32(world.RandInt(s.deps.Rand, int(diffX)+1))
+				ry := minRY + int32(world.RandInt(s.deps.Rand, int(diffY)+1))
 				if s.deps.MapData != nil && s.deps.MapData.IsInMap(curMap, rx, ry) &&
 					s.deps.MapData.IsPassablePoint(curMap, rx, ry) {
 					newX = rx
@@ -556,7 +549,13 @@ func (s *ItemUseSystem) UseTeleportScroll(sess *net.Session, r *packet.Reader, p
 			sendEffectOnPlayer(viewer.Session, player.CharID, 169)
 		}
 
-		handler.TeleportPlayer(sess, player, newX, newY, curMap, 5, s.deps)
+		if !handler.TeleportPlayerFrom(sess, player, newX, newY, curMap, 5, world.TeleportSource{
+			Path: world.TeleportPathRandom, ItemObjID: invItem.ObjectID,
+		}, s.deps) {
+			return
+		}
+		consumePlayerItem(sess, player, invItem, 1)
+		handler.SendWeightUpdate(sess, player)
 
 		s.deps.Log.Info(fmt.Sprintf("隨機傳送  角色=%s  x=%d  y=%d", player.Name, newX, newY))
 	}
@@ -580,17 +579,8 @@ func (s *ItemUseSystem) UseHomeScroll(sess *net.Session, player *world.PlayerInf
 		s.deps.Trade.CancelIfActive(player)
 	}
 
-	// 消耗卷軸
-	removed := player.Inv.RemoveItem(invItem.ObjectID, 1)
-	if removed {
-		handler.SendRemoveInventoryItem(sess, invItem.ObjectID)
-	} else {
-		handler.SendItemCountUpdate(sess, invItem)
-	}
-	handler.SendWeightUpdate(sess, player)
-
 	// 出發特效 + 延遲 2 tick（400ms）傳送，讓客戶端播完特效動畫
-	// 特效在本 tick 末尾 flush 給客戶端，傳送在下一 tick 執行
+	// 特效在本 tick 末尾 flush 給客戶端，傳送在下一 tick 執行；卷軸於傳送完成時消耗
 	sendEffectOnPlayer(sess, player.CharID, 169)
 	oldNearby := s.deps.World.GetNearbyPlayers(player.X, player.Y, player.MapID, sess.ID)
 	for _, viewer := range oldNearby {
@@ -600,6 +590,8 @@ func (s *ItemUseSystem) UseHomeScroll(sess *net.Session, player *world.PlayerInf
 	player.ScrollTPX = int32(loc.X)
 	player.ScrollTPY = int32(loc.Y)
 	player.ScrollTPMap = int16(loc.Map)
+	player.ScrollTPSrc = world.TeleportSource{Path: world.TeleportPathScroll, ItemObjID: invItem.ObjectID}
+	player.ScrollTPFee = 0
 
 	s.deps.Log.Info(fmt.Sprintf("回家卷軸  角色=%s  目標=(%d,%d) 地圖=%d", player.Name, loc.X, loc.Y, loc.Map))
 }
@@ -616,16 +608,7 @@ func (s *ItemUseSystem) UseFixedTeleportScroll(sess *net.Session, player *world.
 		s.deps.Trade.CancelIfActive(player)
 	}
 
-	// 消耗卷軸
-	removed := player.Inv.RemoveItem(invItem.ObjectID, 1)
-	if removed {
-		handler.SendRemoveInventoryItem(sess, invItem.ObjectID)
-	} else {
-		handler.SendItemCountUpdate(sess, invItem)
-	}
-	handler.SendWeightUpdate(sess, player)
-
-	// 出發特效 + 延遲 2 tick（400ms）傳送，讓客戶端播完特效動畫
+	// 出發特效 + 延遲 2 tick（400ms）傳送，讓客戶端播完特效動畫；卷軸於傳送完成時消耗
 	sendEffectOnPlayer(sess, player.CharID, 169)
 	oldNearby := s.deps.World.GetNearbyPlayers(player.X, player.Y, player.MapID, sess.ID)
 	for _, viewer := range oldNearby {
@@ -635,6 +618,8 @@ func (s *ItemUseSystem) UseFixedTeleportScroll(sess *net.Session, player *world.
 	player.ScrollTPX = itemInfo.LocX
 	player.ScrollTPY = itemInfo.LocY
 	player.ScrollTPMap = itemInfo.LocMapID
+	player.ScrollTPSrc = world.TeleportSource{Path: world.TeleportPathScroll, ItemObjID: invItem.ObjectID}
+	player.ScrollTPFee = 0
 
 	s.deps.Log.Info(fmt.Sprintf("指定傳送  角色=%s  道具=%s  目標=(%d,%d) 地圖=%d",
 		player.Name, itemInfo.Name, itemInfo.LocX, itemInfo.LocY, itemInfo.LocMapID))
@@ -678,14 +663,14 @@ func (s *ItemUseSystem) GiveDrops(killer *world.PlayerInfo, npc *world.NpcInfo)
 			chance = 1000000
 		}
 
-		roll := world.RandInt(1000000)
+		roll := world.RandInt(s.deps.Rand, 1000000)
 		if roll >= chance {
 			continue
 		}
 
 		qty := int32(drop.Min)
 		if drop.Max > drop.Min {
-			qty = int32(drop.Min + world.RandInt(drop.Max-drop.Min+1))
+			qty = int32(drop.Min + world.RandInt(s.deps.Rand, drop.Max-drop.Min+1))
 		}
 		if qty <= 0 {
 			qty = 1
@@ -701,7 +686,7 @@ func (s *ItemUseSystem) GiveDrops(killer *world.PlayerInfo, npc *world.NpcInfo)
 		// 選擇接收者：自動分配 → 加權隨機；否則 → killer
 		receiver := killer
 		if len(candidates) > 1 {
-			receiver = weightedRandomByHate(candidates, npc.HateList)
+			receiver = weightedRandomByHate(s.deps.Rand, candidates, npc.HateList)
 		}
 
 		if receiver.Inv.IsFull() {
@@ -714,7 +699,7 @@ func (s *ItemUseSystem) GiveDrops(killer *world.PlayerInfo, npc *world.NpcInfo)
 			}
 		}
 
-		s.giveDropToPlayer(receiver, drop, qty)
+		s.giveDropToPlayer(receiver, npc, drop, qty)
 	}
 }
 
@@ -756,7 +741,7 @@ func (s *ItemUseSystem) collectAutoShareCandidates(killer *world.PlayerInfo, npc
 
 // weightedRandomByHate 按仇恨值加權隨機選擇一個玩家。
 // Java: DropShare — 仇恨越高的成員獲得掉落物的機率越大。
-func weightedRandomByHate(candidates []*world.PlayerInfo, hateList map[uint64]int32) *world.PlayerInfo {
+func weightedRandomByHate(rng *rand.Rand, candidates []*world.PlayerInfo, hateList map[uint64]int32) *world.PlayerInfo {
 	if len(candidates) == 0 {
 		return nil
 	}
@@ -778,11 +763,11 @@ func weightedRandomByHate(candidates []*world.PlayerInfo, hateList map[uint64]in
 
 	if totalWeight <= 0 {
 		// fallback：均等分配
-		return candidates[world.RandInt(len(candidates))]
+		return candidates[world.RandInt(rng, len(candidates))]
 	}
 
 	// 加權隨機選擇
-	roll := int32(world.RandInt(int(totalWeight)))
+	roll := int32(world.RandInt(rng, int(totalWeight)))
 	cumulative := int32(0)
 	for i, w := range weights {
 		cumulative += w
@@ -794,7 +779,7 @@ func weightedRandomByHate(candidates []*world.PlayerInfo, hateList map[uint64]in
 }
 
 // giveDropToPlayer 將掉落物品加入指定玩家背包並發送封包通知。
-func (s *ItemUseSystem) giveDropToPlayer(receiver *world.PlayerInfo, drop data.DropItem, qty int32) {
+func (s *ItemUseSystem) giveDropToPlayer(receiver *world.PlayerInfo, npc *world.NpcInfo, drop data.DropItem, qty int32) {
 	itemInfo := s.deps.Items.Get(drop.ItemID)
 	if itemInfo == nil {
 		return
@@ -819,6 +804,7 @@ func (s *ItemUseSystem) giveDropToPlayer(receiver *world.PlayerInfo, drop data.D
 	if itemInfo.Category == data.CategoryWeapon || itemInfo.Category == data.CategoryArmor {
 		item.Identified = false
 	}
+	handler.EmitItemMoved(s.deps, event.ItemReasonDrop, item, qty, handler.NpcLoc(npc.NpcID), handler.CharLoc(receiver.CharID), receiver)
 
 	if wasExisting {
 		handler.SendItemCountUpdate(receiver.Session, item)
@@ -951,7 +937,7 @@ func (s *ItemUseSystem) applyWisdom(sess *net.Session, player *world.PlayerInfo,
 	player.WisdomTicks = buff.TicksLeft
 
 	handler.SendWisdomPotionIcon(sess, uint16(durationSec))
-	handler.SendPlayerStatus(sess, player)
+	handler.SendPlayerStatus(sess, player, s.deps)
 	s.BroadcastEffect(sess, player, gfxID)
 }
 
@@ -1096,7 +1082,7 @@ func (s *ItemUseSystem) useElixir(sess *net.Session, player *world.PlayerInfo, i
 	player.ElixirStats++
 	player.Dirty = true
 
-	handler.SendPlayerStatus(sess, player)
+	handler.SendPlayerStatus(sess, player, s.deps)
 	handler.SendAbilityScores(sess, player)
 
 	s.deps.Log.Info(fmt.Sprintf("萬能藥使用  角色=%s  物品=%d  已用=%d/%d",
@@ -1193,7 +1179,7 @@ func (s *ItemUseSystem) GiveBoxReward(sess *net.Session, player *world.PlayerInf
 	// 決定數量
 	count := minCount
 	if maxCount > minCount {
-		count = minCount + rand.Int31n(maxCount-minCount+1)
+		count = minCount + s.deps.Rand.Int31n(maxCount-minCount+1)
 	}
 	if count < 1 {
 		count = 1
@@ -1346,7 +1332,7 @@ func (s *ItemUseSystem) sendVIPStatusUpdates(sess *net.Session, p *world.PlayerI
 	if vip.AddStr != 0 || vip.AddDex != 0 || vip.AddCon != 0 ||
 		vip.AddInt != 0 || vip.AddWis != 0 || vip.AddCha != 0 ||
 		vip.AddHP != 0 || vip.AddMP != 0 || vip.AddAC != 0 {
-		handler.SendPlayerStatus(sess, p)
+		handler.SendPlayerStatus(sess, p, s.deps)
 	}
 
 	// AC + 元素抗性 → S_OwnCharAttrDef
@@ -1417,7 +1403,7 @@ func (s *ItemUseSystem) useCreateMonsterWand(sess *net.Session, player *world.Pl
 	handler.BroadcastToPlayers(nearby, actionData)
 
 	// 隨機選擇怪物
-	npcID := wandMonsterIDs[rand.Intn(len(wandMonsterIDs))]
+	npcID := wandMonsterIDs[s.deps.Rand.Intn(len(wandMonsterIDs))]
 	tmpl := s.deps.Npcs.Get(npcID)
 	if tmpl == nil {
 		s.deps.Log.Warn("創造怪物魔杖：未知 NPC", zap.Int32("npc_id", npcID))
@@ -1426,8 +1412,8 @@ func (s *ItemUseSystem) useCreateMonsterWand(sess *net.Session, player *world.Pl
 	}
 
 	// 在玩家位置附近生成怪物
-	spawnX := player.X + int32(rand.Intn(3)) - 1
-	spawnY := player.Y + int32(rand.Intn(3)) - 1
+	spawnX := player.X + int32(s.deps.Rand.Intn(3)) - 1
+	spawnY := player.Y + int32(s.deps.Rand.Intn(3)) - 1
 
 	// 取得動作速度
 	atkSpeed := tmpl.AtkSpeed
@@ -1457,7 +1443,7 @@ func (s *ItemUseSystem) useCreateMonsterWand(sess *net.Session, player *world.Pl
 		X:          spawnX,
 		Y:          spawnY,
 		MapID:      player.MapID,
-		Heading:    int16(rand.Intn(8)),
+		Heading:    int16(s.deps.Rand.Intn(8)),
 		HP:         tmpl.HP,
 		MaxHP:      tmpl.HP,
 		MP:         tmpl.MP,
@@ -1524,7 +1510,7 @@ func (s *ItemUseSystem) useLightningWand(sess *net.Session, player *world.Player
 	handler.BroadcastToPlayers(nearby, actionData)
 
 	// 傷害公式（Java: random.nextInt(11) - 5 + INT）
-	dmg := int32(rand.Intn(11)-5) + int32(player.Intel)
+	dmg := int32(s.deps.Rand.Intn(11)-5) + int32(player.Intel)
 	if dmg < 1 {
 		dmg = 1
 	}
@@ -1641,7 +1627,7 @@ func (s *ItemUseSystem) usePolyMorphWand(sess *net.Session, player *world.Player
 	handler.BroadcastToPlayers(nearby, actionData)
 
 	// 隨機選擇變身 GFX
-	polyGfx := wandPolyGfxIDs[rand.Intn(len(wandPolyGfxIDs))]
+	polyGfx := wandPolyGfxIDs[s.deps.Rand.Intn(len(wandPolyGfxIDs))]
 
 	// 查找目標
 	npc := s.deps.World.GetNpc(targetObjID)
@@ -1658,7 +1644,7 @@ func (s *ItemUseSystem) usePolyMorphWand(sess *net.Session, player *world.Player
 
 		// 成功率（Java: probability = 3*(攻LV-防LV) + 100 - 防MR）
 		prob := 3*int(player.Level-npc.Level) + 100 - int(npc.MR)
-		if rand.Intn(100)+1 > prob {
+		if s.deps.Rand.Intn(100)+1 > prob {
 			handler.SendServerMessage(sess, 79) // 失敗
 		} else {
 			// 變身成功 — NPC 改變外觀
@@ -6,6 +6,7 @@ import (
 
 	coresys "github.com/l1jgo/server/internal/core/system"
 	"github.com/l1jgo/server/internal/data"
+	"github.com/l1jgo/server/internal/handler"
 	"github.com/l1jgo/server/internal/world"
 )
 
@@ -15,23 +16,25 @@ type LightSpawnSystem struct {
 	state       *world.State
 	spawns      []data.LightSpawnEntry
 	npcTable    *data.NpcTable
+	deps        *handler.Deps
 	lastIsNight bool
 	initialized bool
 	lightNpcIDs []int32 // 已生成的路燈 NPC object ID
 }
 
-func NewLightSpawnSystem(ws *world.State, spawns []data.LightSpawnEntry, npcTable *data.NpcTable) *LightSpawnSystem {
+func NewLightSpawnSystem(ws *world.State, spawns []data.LightSpawnEntry, npcTable *data.NpcTable, deps *handler.Deps) *LightSpawnSystem {
 	return &LightSpawnSystem{
 		state:    ws,
 		spawns:   spawns,
 		npcTable: npcTable,
+		deps:     deps,
 	}
 }
 
 func (s *LightSpawnSystem) Phase() coresys.Phase { return coresys.PhasePostUpdate }
 
 func (s *LightSpawnSystem) Update(_ time.Duration) {
-	isNight := world.GameTimeNow().IsNight()
+	isNight := world.GameTimeAt(s.deps.Clock.Now()).IsNight()
 	if !s.initialized {
 		s.initialized = true
 		s.lastIsNight = isNight
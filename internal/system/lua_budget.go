@@ -0,0 +1,27 @@
+package system
+
+import (
+	"time"
+
+	coresys "github.com/l1jgo/server/internal/core/system"
+	"github.com/l1jgo/server/internal/scripting"
+)
+
+// LuaBudgetSystem 在每個 tick 結束時結算 Lua 執行時間預算。
+// 超出 [lua] tick_budget_pct 時記錄耗時最高的函式，並重置下一 tick 的累計。
+// 同時套用排隊中的腳本熱重載（GM .reloadlua / SIGHUP），確保 VM 只在 tick 之間替換。
+// Phase 6（Cleanup）：統計窗口涵蓋整個 tick 間隔（含其間的高頻輸入輪詢）。
+type LuaBudgetSystem struct {
+	engine *scripting.Engine
+}
+
+func NewLuaBudgetSystem(engine *scripting.Engine) *LuaBudgetSystem {
+	return &LuaBudgetSystem{engine: engine}
+}
+
+func (s *LuaBudgetSystem) Phase() coresys.Phase { return coresys.PhaseCleanup }
+
+func (s *LuaBudgetSystem) Update(_ time.Duration) {
+	s.engine.EndTick()
+	s.engine.ApplyPendingReload()
+}
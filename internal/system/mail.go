@@ -3,7 +3,6 @@ package system
 import (
 	"context"
 	"fmt"
-	"time"
 
 	"github.com/l1jgo/server/internal/handler"
 	"github.com/l1jgo/server/internal/net"
@@ -25,47 +24,65 @@ func NewMailSystem(deps *handler.Deps) *MailSystem {
 
 // OpenMailbox 從 DB 載入信件並發送列表封包。
 func (s *MailSystem) OpenMailbox(sess *net.Session, player *world.PlayerInfo, mailType int16) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	mails, err := s.deps.MailRepo.LoadByInbox(ctx, player.CharID, mailType)
-	if err != nil {
-		s.deps.Log.Error("讀取信箱失敗", zap.Error(err))
-		return
-	}
-
-	handler.SendMailList(sess, player, mails, mailType)
+	charID := player.CharID
+	var mails []persist.MailRow
+	handler.RunDBJob(sess, s.deps, "mail_open", func(ctx context.Context) error {
+		var err error
+		mails, err = s.deps.MailRepo.LoadByInbox(ctx, charID, mailType)
+		return err
+	}, func(err error) {
+		if err != nil {
+			s.deps.Log.Error("讀取信箱失敗", zap.Error(err))
+			return
+		}
+		handler.SendMailList(sess, player, mails, mailType)
+	})
 }
 
 // ReadMail 讀取信件內容並標記已讀。
 func (s *MailSystem) ReadMail(sess *net.Session, player *world.PlayerInfo, mailID int32, mailType int16) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	mail, err := s.deps.MailRepo.GetByID(ctx, mailID)
-	if err != nil {
-		s.deps.Log.Error("讀取信件失敗", zap.Error(err))
-		return
-	}
-	if mail == nil {
-		return
-	}
-
-	// 只有信箱擁有者可以讀取
-	if mail.InboxID != player.CharID {
-		return
-	}
+	charID := player.CharID
+	var mail *persist.MailRow
+	handler.RunDBJob(sess, s.deps, "mail_read", func(ctx context.Context) error {
+		var err error
+		mail, err = s.deps.MailRepo.GetByID(ctx, mailID)
+		if err != nil {
+			return fmt.Errorf("讀取信件: %w", err)
+		}
+		// 只有信箱擁有者可以讀取
+		if mail == nil || mail.InboxID != charID {
+			mail = nil
+			return nil
+		}
 
-	// 標記已讀
-	if mail.ReadStatus == 0 {
-		if err := s.deps.MailRepo.SetReadStatus(ctx, mailID); err != nil {
-			s.deps.Log.Error("標記信件已讀失敗", zap.Error(err))
+		// 標記已讀
+		if mail.ReadStatus == 0 {
+			if err := s.deps.MailRepo.SetReadStatus(ctx, mailID); err != nil {
+				s.deps.Log.Error("標記信件已讀失敗", zap.Error(err))
+			}
 		}
-	}
+		return nil
+	}, func(err error) {
+		if err != nil {
+			s.deps.Log.Error("讀取信件失敗", zap.Error(err))
+			return
+		}
+		if mail == nil {
+			return
+		}
+
+		// 發送內容
+		readType := byte(0x10) + byte(mailType)
+		handler.SendMailContent(sess, mailID, readType, mail.Content)
+	})
+}
 
-	// 發送內容
-	readType := byte(0x10) + byte(mailType)
-	handler.SendMailContent(sess, mailID, readType, mail.Content)
+// mailSendResult 是寄信工作在 worker 端的結果。
+type mailSendResult struct {
+	noReceiver     bool // 收件人不存在
+	full           bool // 收件箱已滿
+	senderMailID   int32
+	receiverMailID int32
 }
 
 // SendMail 寄出一封一般信件。
@@ -74,184 +91,225 @@ func (s *MailSystem) SendMail(sess *net.Session, player *world.PlayerInfo, recei
 	subject, content := parseMailText(rawText)
 
 	// 扣除寄信費用
-	if !consumeAdena(player, int32(s.deps.Config.Gameplay.MailSendCost)) {
+	fee := int32(s.deps.Config.Gameplay.MailSendCost)
+	if !consumeAdena(player, fee) {
 		handler.SendServerMessage(sess, 189) // "金幣不足。"
 		return
 	}
 	sendAdenaUpdate(sess, player)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	// 查詢收件人 — 先找線上，再查 DB
-	receiver := s.deps.World.GetByName(receiverName)
+	// 查詢收件人 — 先找線上，離線者由 worker 查 DB
 	var receiverCharID int32
-
-	if receiver != nil {
+	if receiver := s.deps.World.GetByName(receiverName); receiver != nil {
 		receiverCharID = receiver.CharID
-	} else {
-		// 離線：從 DB 查詢
-		charRow, err := s.deps.CharRepo.LoadByName(ctx, receiverName)
+	}
+
+	senderName := player.Name
+	senderCharID := player.CharID
+	maxPerBox := s.deps.Config.Gameplay.MailMaxPerBox
+	var res mailSendResult
+
+	// 寄信費用的 WAL 與寄件備份同一交易寫入
+	var feeWAL []persist.WALEntry
+	if fee > 0 {
+		e := persist.GoldWAL(persist.WALMail, senderCharID, 0, int64(fee))
+		e.Target = "mail:" + receiverName
+		feeWAL = append(feeWAL, e)
+	}
+
+	handler.RunDBJob(sess, s.deps, "mail_send", func(ctx context.Context) error {
+		if receiverCharID == 0 {
+			// 離線：從 DB 查詢
+			charRow, err := s.deps.CharRepo.LoadByName(ctx, receiverName)
+			if err != nil {
+				return fmt.Errorf("查詢收件人: %w", err)
+			}
+			if charRow == nil {
+				res.noReceiver = true
+				return nil
+			}
+			receiverCharID = charRow.ID
+		}
+
+		// 檢查收件箱上限
+		count, err := s.deps.MailRepo.CountByInbox(ctx, receiverCharID, handler.MailTypeNormal)
+		if err != nil {
+			return fmt.Errorf("查詢收件箱數量: %w", err)
+		}
+		if count >= maxPerBox {
+			res.full = true
+			return nil
+		}
+
+		now := s.deps.Clock.Now()
+
+		// 寫入寄件備份
+		senderMail := &persist.MailRow{
+			Type:       handler.MailTypeNormal,
+			Sender:     senderName,
+			Receiver:   receiverName,
+			Date:       now,
+			ReadStatus: 0,
+			InboxID:    senderCharID,
+			Subject:    subject,
+			Content:    content,
+		}
+		res.senderMailID, err = s.deps.MailRepo.Write(ctx, senderMail, feeWAL...)
+		if err != nil {
+			return fmt.Errorf("寫入寄件備份: %w", err)
+		}
+
+		// 寫入收件信
+		receiverMail := &persist.MailRow{
+			Type:       handler.MailTypeNormal,
+			Sender:     senderName,
+			Receiver:   receiverName,
+			Date:       now,
+			ReadStatus: 0,
+			InboxID:    receiverCharID,
+			Subject:    subject,
+			Content:    content,
+		}
+		res.receiverMailID, err = s.deps.MailRepo.Write(ctx, receiverMail)
+		if err != nil {
+			return fmt.Errorf("寫入收件信: %w", err)
+		}
+		return nil
+	}, func(err error) {
 		if err != nil {
-			s.deps.Log.Error("查詢收件人失敗", zap.Error(err))
+			s.deps.Log.Error("寄信失敗", zap.Error(err))
 			handler.SendMailResult(sess, 0x20, false)
 			return
 		}
-		if charRow == nil {
+		if res.noReceiver {
 			handler.SendServerMessage(sess, 109) // "沒有這個人。"
 			return
 		}
-		receiverCharID = charRow.ID
-	}
-
-	// 檢查收件箱上限
-	count, err := s.deps.MailRepo.CountByInbox(ctx, receiverCharID, handler.MailTypeNormal)
-	if err != nil {
-		s.deps.Log.Error("查詢收件箱數量失敗", zap.Error(err))
-		handler.SendMailResult(sess, 0x20, false)
-		return
-	}
-	if count >= s.deps.Config.Gameplay.MailMaxPerBox {
-		handler.SendMailResult(sess, 0x20, false)
-		return
-	}
-
-	now := time.Now()
-
-	// 寫入寄件備份
-	senderMail := &persist.MailRow{
-		Type:       handler.MailTypeNormal,
-		Sender:     player.Name,
-		Receiver:   receiverName,
-		Date:       now,
-		ReadStatus: 0,
-		InboxID:    player.CharID,
-		Subject:    subject,
-		Content:    content,
-	}
-	senderMailID, err := s.deps.MailRepo.Write(ctx, senderMail)
-	if err != nil {
-		s.deps.Log.Error("寫入寄件備份失敗", zap.Error(err))
-		handler.SendMailResult(sess, 0x20, false)
-		return
-	}
-
-	// 寫入收件信
-	receiverMail := &persist.MailRow{
-		Type:       handler.MailTypeNormal,
-		Sender:     player.Name,
-		Receiver:   receiverName,
-		Date:       now,
-		ReadStatus: 0,
-		InboxID:    receiverCharID,
-		Subject:    subject,
-		Content:    content,
-	}
-	receiverMailID, err := s.deps.MailRepo.Write(ctx, receiverMail)
-	if err != nil {
-		s.deps.Log.Error("寫入收件信失敗", zap.Error(err))
-		handler.SendMailResult(sess, 0x20, false)
-		return
-	}
+		if res.full {
+			handler.SendMailResult(sess, 0x20, false)
+			return
+		}
 
-	// 通知寄件者（備份）
-	handler.SendMailNotify(sess, player.Name, senderMailID, true, subject)
+		// 通知寄件者（備份）
+		handler.SendMailNotify(sess, senderName, res.senderMailID, true, subject)
 
-	// 通知收件者（若線上）
-	if receiver != nil {
-		handler.SendMailNotify(receiver.Session, player.Name, receiverMailID, false, subject)
-		// 音效通知（skill sound 1091）
-		handler.SendMailSound(receiver.Session, receiver.CharID)
-	}
+		// 通知收件者（若線上；工作期間可能已上線或離線，重新查詢）
+		if receiver := s.deps.World.GetByCharID(receiverCharID); receiver != nil {
+			handler.SendMailNotify(receiver.Session, senderName, res.receiverMailID, false, subject)
+			// 音效通知（skill sound 1091）
+			handler.SendMailSound(receiver.Session, receiver.CharID)
+		}
 
-	s.deps.Log.Info(fmt.Sprintf("信件寄出  寄件=%s  收件=%s  senderID=%d  receiverID=%d",
-		player.Name, receiverName, senderMailID, receiverMailID))
+		s.deps.Log.Info(fmt.Sprintf("信件寄出  寄件=%s  收件=%s  senderID=%d  receiverID=%d",
+			senderName, receiverName, res.senderMailID, res.receiverMailID))
 
-	handler.SendMailResult(sess, 0x20, true)
+		handler.SendMailResult(sess, 0x20, true)
+	})
 }
 
 // DeleteMail 刪除單封信件。
 func (s *MailSystem) DeleteMail(sess *net.Session, player *world.PlayerInfo, mailID int32, subtype byte) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	// 驗證所有權
-	mail, err := s.deps.MailRepo.GetByID(ctx, mailID)
-	if err != nil {
-		s.deps.Log.Error("查詢信件失敗", zap.Error(err))
-		return
-	}
-	if mail == nil || mail.InboxID != player.CharID {
-		return
-	}
-
-	if err := s.deps.MailRepo.Delete(ctx, mailID); err != nil {
-		s.deps.Log.Error("刪除信件失敗", zap.Error(err))
-		return
-	}
-
-	handler.SendMailAck(sess, mailID, subtype)
+	charID := player.CharID
+	deleted := false
+	handler.RunDBJob(sess, s.deps, "mail_delete", func(ctx context.Context) error {
+		// 驗證所有權
+		mail, err := s.deps.MailRepo.GetByID(ctx, mailID)
+		if err != nil {
+			return fmt.Errorf("查詢信件: %w", err)
+		}
+		if mail == nil || mail.InboxID != charID {
+			return nil
+		}
+		if err := s.deps.MailRepo.Delete(ctx, mailID); err != nil {
+			return fmt.Errorf("刪除信件: %w", err)
+		}
+		deleted = true
+		return nil
+	}, func(err error) {
+		if err != nil {
+			s.deps.Log.Error("刪除信件失敗", zap.Error(err))
+			return
+		}
+		if deleted {
+			handler.SendMailAck(sess, mailID, subtype)
+		}
+	})
 }
 
 // MoveToStorage 搬移信件至保管箱。
 func (s *MailSystem) MoveToStorage(sess *net.Session, player *world.PlayerInfo, mailID int32, subtype byte) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	// 驗證所有權
-	mail, err := s.deps.MailRepo.GetByID(ctx, mailID)
-	if err != nil {
-		s.deps.Log.Error("查詢信件失敗", zap.Error(err))
-		return
-	}
-	if mail == nil || mail.InboxID != player.CharID {
-		return
-	}
-
-	// 檢查保管箱上限
-	count, err := s.deps.MailRepo.CountByInbox(ctx, player.CharID, handler.MailTypeStorage)
-	if err != nil {
-		s.deps.Log.Error("查詢保管箱數量失敗", zap.Error(err))
-		return
-	}
-	if count >= s.deps.Config.Gameplay.MailMaxPerBox {
-		return
-	}
+	charID := player.CharID
+	maxPerBox := s.deps.Config.Gameplay.MailMaxPerBox
+	moved := false
+	handler.RunDBJob(sess, s.deps, "mail_move", func(ctx context.Context) error {
+		// 驗證所有權
+		mail, err := s.deps.MailRepo.GetByID(ctx, mailID)
+		if err != nil {
+			return fmt.Errorf("查詢信件: %w", err)
+		}
+		if mail == nil || mail.InboxID != charID {
+			return nil
+		}
 
-	if err := s.deps.MailRepo.SetType(ctx, mailID, handler.MailTypeStorage); err != nil {
-		s.deps.Log.Error("移動信件至保管箱失敗", zap.Error(err))
-		return
-	}
+		// 檢查保管箱上限
+		count, err := s.deps.MailRepo.CountByInbox(ctx, charID, handler.MailTypeStorage)
+		if err != nil {
+			return fmt.Errorf("查詢保管箱數量: %w", err)
+		}
+		if count >= maxPerBox {
+			return nil
+		}
 
-	handler.SendMailAck(sess, mailID, 0x40)
+		if err := s.deps.MailRepo.SetType(ctx, mailID, handler.MailTypeStorage); err != nil {
+			return fmt.Errorf("移動信件至保管箱: %w", err)
+		}
+		moved = true
+		return nil
+	}, func(err error) {
+		if err != nil {
+			s.deps.Log.Error("搬移信件失敗", zap.Error(err))
+			return
+		}
+		if moved {
+			handler.SendMailAck(sess, mailID, 0x40)
+		}
+	})
 }
 
 // BulkDelete 批次刪除信件。
 func (s *MailSystem) BulkDelete(sess *net.Session, player *world.PlayerInfo, subtype byte, mailIDs []int32) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
+	charID := player.CharID
 	// subtype 對應刪除確認類型：0x60→0x30, 0x61→0x31, 0x62→0x32
 	deleteAckType := subtype - 0x30
+	deleted := make([]int32, 0, len(mailIDs))
+
+	handler.RunDBJob(sess, s.deps, "mail_bulk_delete", func(ctx context.Context) error {
+		for _, mailID := range mailIDs {
+			// 驗證所有權
+			mail, err := s.deps.MailRepo.GetByID(ctx, mailID)
+			if err != nil {
+				s.deps.Log.Error("批次刪除查詢失敗", zap.Error(err))
+				continue
+			}
+			if mail == nil || mail.InboxID != charID {
+				continue
+			}
 
-	for _, mailID := range mailIDs {
-		// 驗證所有權
-		mail, err := s.deps.MailRepo.GetByID(ctx, mailID)
-		if err != nil {
-			s.deps.Log.Error("批次刪除查詢失敗", zap.Error(err))
-			continue
-		}
-		if mail == nil || mail.InboxID != player.CharID {
-			continue
+			if err := s.deps.MailRepo.Delete(ctx, mailID); err != nil {
+				s.deps.Log.Error("批次刪除信件失敗", zap.Error(err))
+				continue
+			}
+			deleted = append(deleted, mailID)
 		}
-
-		if err := s.deps.MailRepo.Delete(ctx, mailID); err != nil {
+		return nil
+	}, func(err error) {
+		if err != nil {
 			s.deps.Log.Error("批次刪除信件失敗", zap.Error(err))
-			continue
 		}
-
-		handler.SendMailAck(sess, mailID, deleteAckType)
-	}
+		for _, mailID := range deleted {
+			handler.SendMailAck(sess, mailID, deleteAckType)
+		}
+	})
 }
 
 // --- 輔助函式 ---
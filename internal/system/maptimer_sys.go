@@ -23,7 +23,7 @@ func NewMapTimerSystem(ws *world.State, deps *handler.Deps) *MapTimerSystem {
 	return &MapTimerSystem{
 		world:   ws,
 		deps:    deps,
-		lastDay: time.Now().YearDay(),
+		lastDay: deps.Clock.Now().YearDay(),
 	}
 }
 
@@ -31,7 +31,7 @@ func (s *MapTimerSystem) Phase() coresys.Phase { return coresys.PhasePostUpdate
 
 func (s *MapTimerSystem) Update(_ time.Duration) {
 	// 每日重置檢查（Java: ServerResetMapTimer，每 24 小時執行一次）
-	today := time.Now().YearDay()
+	today := s.deps.Clock.Now().YearDay()
 	if today != s.lastDay {
 		s.lastDay = today
 		s.resetAllOnlinePlayers()
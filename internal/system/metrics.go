@@ -0,0 +1,53 @@
+package system
+
+import (
+	"time"
+
+	coresys "github.com/l1jgo/server/internal/core/system"
+	"github.com/l1jgo/server/internal/metrics"
+	"github.com/l1jgo/server/internal/net"
+	"github.com/l1jgo/server/internal/world"
+)
+
+// metricsSnapshotTicks 是建立指標快照的間隔（5 ticks = 1 秒）。
+const metricsSnapshotTicks = 5
+
+// MetricsSystem 定期把只能在遊戲迴圈讀取的狀態（連線、玩家、NPC、tick 耗時）
+// 複製成 metrics.Snapshot 發布給 HTTP 指標端點。
+// Phase 6（Cleanup）：快照涵蓋本 tick 所有系統的結果。
+type MetricsSystem struct {
+	store  *net.SessionStore
+	world  *world.State
+	runner *coresys.Runner
+	ticks  int
+}
+
+func NewMetricsSystem(store *net.SessionStore, ws *world.State, runner *coresys.Runner) *MetricsSystem {
+	return &MetricsSystem{store: store, world: ws, runner: runner}
+}
+
+func (s *MetricsSystem) Phase() coresys.Phase { return coresys.PhaseCleanup }
+
+func (s *MetricsSystem) Update(_ time.Duration) {
+	s.ticks++
+	if s.ticks < metricsSnapshotTicks {
+		return
+	}
+	s.ticks = 0
+
+	snap := &metrics.Snapshot{
+		Taken:         time.Now(),
+		OnlinePlayers: s.world.PlayerCount(),
+		Sessions:      make(map[string]int),
+		NpcTotal:      s.world.NpcCount(),
+		Tick:          s.runner.Profile(),
+	}
+	s.store.ForEach(func(sess *net.Session) {
+		snap.Sessions[sess.State().String()]++
+	})
+	for _, a := range s.world.NpcActivityByMap() {
+		snap.NpcActive += a.Active
+		snap.NpcSleeping += a.Sleeping
+	}
+	metrics.Publish(snap)
+}
@@ -19,8 +19,12 @@ import (
 type NpcAISystem struct {
 	world *world.State
 	deps  *handler.Deps
+	ticks int
 }
 
+// aiStatePruneTicks 每隔多少 tick 清理已移除 NPC 的 Lua AI 狀態表（約 60 秒）。
+const aiStatePruneTicks = 300
+
 func NewNpcAISystem(ws *world.State, deps *handler.Deps) *NpcAISystem {
 	return &NpcAISystem{world: ws, deps: deps}
 }
@@ -28,10 +32,39 @@ func NewNpcAISystem(ws *world.State, deps *handler.Deps) *NpcAISystem {
 func (s *NpcAISystem) Phase() coresys.Phase { return coresys.PhaseUpdate }
 
 func (s *NpcAISystem) Update(_ time.Duration) {
+	s.ticks++
+	if s.ticks%aiStatePruneTicks == 0 {
+		s.deps.Scripting.PruneNpcAIState(func(objID int) bool {
+			npc := s.world.GetNpc(int32(objID))
+			return npc != nil && !npc.Dead
+		})
+	}
+
+	// AI 休眠：所在 AOI 鄰域（3x3 格）沒有玩家的 NPC 不執行任何 AI
+	s.world.RefreshAwakeCells()
+	s.world.ResetNpcActivity()
+
 	for _, npc := range s.world.NpcList() {
 		if npc.Dead {
 			continue
 		}
+		awake := s.world.IsAwakeAt(npc.X, npc.Y, npc.MapID)
+		if !awake && s.npcReturnHome(npc) {
+			s.world.CountNpcActivity(npc.MapID, true)
+			continue
+		}
+		s.world.CountNpcActivity(npc.MapID, awake)
+		if !awake {
+			if !npc.Dormant {
+				s.npcFallAsleep(npc)
+			}
+			npc.DormantTicks++
+			continue
+		}
+		if npc.Dormant {
+			s.npcWake(npc)
+		}
+
 		// Guard AI: separate branch — simple Go logic, no Lua needed.
 		if npc.Impl == "L1Guard" {
 			s.tickGuardAI(npc)
@@ -48,6 +81,90 @@ func (s *NpcAISystem) Update(_ time.Duration) {
 	}
 }
 
+// ---------- AI Sleep ----------
+
+// npcSleepHomeDist 距出生點超過此格數（或不在出生地圖）的怪物/警衛在無玩家區域不休眠，
+// 先走回此範圍內。
+const npcSleepHomeDist = 20
+
+// npcSleepRegenTicks 休眠超過此 tick 數（約 10 秒）視為脫離戰鬥，喚醒時回滿 HP/MP。
+const npcSleepRegenTicks = 50
+
+// npcFallAsleep 讓 NPC 進入休眠：清除仇恨與移動狀態。
+func (s *NpcAISystem) npcFallAsleep(npc *world.NpcInfo) {
+	npc.Dormant = true
+	npc.DormantTicks = 0
+	npc.AggroTarget = 0
+	ClearHateList(npc)
+	npc.WanderDist = 0
+	npc.Path.Clear()
+}
+
+// npcReturnHome 讓追擊途中失去玩家、離出生點仍遠的怪物/警衛在無玩家區域繼續走回家
+// （與有人區域相同的走法，無法走回時才瞬移），回到 npcSleepHomeDist 內才休眠。
+// 回傳 false 表示 NPC 可以休眠。
+func (s *NpcAISystem) npcReturnHome(npc *world.NpcInfo) bool {
+	if npc.Dormant || npc.Paralyzed || npc.Sleeped {
+		return false
+	}
+	if npc.Impl != "L1Monster" && npc.Impl != "L1Guard" {
+		return false
+	}
+	if npc.MapID == npc.SpawnMapID && chebyshev32(npc.X, npc.Y, npc.SpawnX, npc.SpawnY) <= npcSleepHomeDist {
+		return false
+	}
+
+	npc.AggroTarget = 0
+	ClearHateList(npc)
+	if npc.MoveTimer > 0 {
+		npc.MoveTimer--
+	}
+	if npc.Impl == "L1Guard" {
+		s.guardWalkHome(npc)
+	} else {
+		s.npcWalkHome(npc)
+	}
+	return true
+}
+
+// npcWake 喚醒休眠中的 NPC，並以休眠 tick 數一次補算計時器與 debuff，
+// 取代休眠期間逐 tick 處理。
+func (s *NpcAISystem) npcWake(npc *world.NpcInfo) {
+	elapsed := npc.DormantTicks
+	npc.Dormant = false
+	npc.DormantTicks = 0
+
+	npc.AttackTimer = max(npc.AttackTimer-elapsed, 0)
+	npc.MoveTimer = max(npc.MoveTimer-elapsed, 0)
+
+	// 傷害毒：休眠期間（到毒咒 debuff 到期為止）每 15 tick 扣血一次，HP 最低 1；
+	// 到期由下方 debuff 補算清除
+	if npc.PoisonDmgAmt > 0 {
+		poisoned := min(elapsed, npc.ActiveDebuffs[11])
+		ticks := npc.PoisonDmgTimer + poisoned
+		npc.HP = max(npc.HP-int32(ticks/15)*npc.PoisonDmgAmt, 1)
+		npc.PoisonDmgTimer = ticks % 15
+		if npc.ActiveDebuffs[11] <= elapsed {
+			npc.PoisonAttackerSID = 0
+		}
+	}
+
+	for skillID, ticksLeft := range npc.ActiveDebuffs {
+		if ticksLeft <= elapsed {
+			delete(npc.ActiveDebuffs, skillID)
+			removeNpcDebuffEffect(npc, skillID, s.world)
+		} else {
+			npc.ActiveDebuffs[skillID] = ticksLeft - elapsed
+		}
+	}
+
+	// 仍在中毒的 NPC 不算脫離戰鬥，保留毒傷害後的 HP
+	if elapsed >= npcSleepRegenTicks && npc.PoisonDmgAmt == 0 {
+		npc.HP = npc.MaxHP
+		npc.MP = npc.MaxMP
+	}
+}
+
 // ---------- Non-combat NPC Random Walk ----------
 
 // tickNpcRandomWalk 處理非戰鬥 NPC 的隨機行走（鳥、村莊 NPC 等）。
@@ -61,21 +178,21 @@ func (s *NpcAISystem) tickNpcRandomWalk(npc *world.NpcInfo) {
 
 	// 距離出生點超過 8 格 → 走回家
 	if chebyshev32(npc.X, npc.Y, npc.SpawnX, npc.SpawnY) > 8 {
-		npcWander(s.world, npc, -2, s.deps.MapData)
+		npcWander(s.world, s.deps.Rand, npc, -2, s.deps.MapData)
 		return
 	}
 
 	// 還有剩餘步數 → 繼續同方向走
 	if npc.WanderDist > 0 {
-		npcWander(s.world, npc, -1, s.deps.MapData)
+		npcWander(s.world, s.deps.Rand, npc, -1, s.deps.MapData)
 		return
 	}
 
 	// 隨機選擇新動作（Java: random 0-39）
-	dir := rand.Intn(40)
+	dir := s.deps.Rand.Intn(40)
 	if dir < 8 {
 		// 0-7: 向該方向移動
-		npcWander(s.world, npc, dir, s.deps.MapData)
+		npcWander(s.world, s.deps.Rand, npc, dir, s.deps.MapData)
 	} else {
 		// 8-39: 暫停不動（機率 80%）
 		npc.MoveTimer = calcNpcMoveTicks(npc)
@@ -162,9 +279,9 @@ func (s *NpcAISystem) tickMonsterAI(npc *world.NpcInfo) {
 			nearbyPlayers = s.world.GetNearbyPlayersAt(npc.X, npc.Y, npc.MapID)
 		}
 		if len(nearbyPlayers) == 0 {
-			// 無目標 + 無附近玩家 → 傳送回出生點
-			if npc.X != npc.SpawnX || npc.Y != npc.SpawnY {
-				s.npcTeleportHome(npc)
+			// 無目標 + 無附近玩家 → 走回出生點
+			if npc.X != npc.SpawnX || npc.Y != npc.SpawnY || npc.MapID != npc.SpawnMapID {
+				s.npcWalkHome(npc)
 			}
 			npc.AggroTarget = 0
 			ClearHateList(npc)
@@ -210,8 +327,16 @@ func (s *NpcAISystem) tickMonsterAI(npc *world.NpcInfo) {
 		}
 	}
 
+	// AI 設定檔（npc_list.yaml `ai:`，未設定 = default）
+	aiProfile := ""
+	if tmpl := s.deps.Npcs.Get(npc.NpcID); tmpl != nil {
+		aiProfile = tmpl.AI
+	}
+
 	ctx := scripting.AIContext{
 		NpcID:       int(npc.NpcID),
+		ObjID:       int(npc.ID),
+		AIProfile:   aiProfile,
 		X:           int(npc.X),
 		Y:           int(npc.Y),
 		MapID:       int(npc.MapID),
@@ -276,7 +401,7 @@ func (s *NpcAISystem) tickMonsterAI(npc *world.NpcInfo) {
 				npc.MoveTimer = calcNpcMoveTicks(npc)
 			}
 		case "wander":
-			npcWander(s.world, npc, cmd.Dir, s.deps.MapData)
+			npcWander(s.world, s.deps.Rand, npc, cmd.Dir, s.deps.MapData)
 		case "lose_aggro":
 			npc.AggroTarget = 0
 		}
@@ -385,22 +510,27 @@ func (s *NpcAISystem) tickGuardAI(npc *world.NpcInfo) {
 
 	// --- No target: return home ---
 	if npc.X != npc.SpawnX || npc.Y != npc.SpawnY {
-		homeDist := chebyshev32(npc.X, npc.Y, npc.SpawnX, npc.SpawnY)
-		if homeDist > 30 {
-			s.guardTeleportHome(npc)
-			return
-		}
-		if npc.MoveTimer <= 0 {
-			npcMoveToward(s.world, npc, npc.SpawnX, npc.SpawnY, s.deps.MapData)
-			moveTicks := calcNpcMoveTicks(npc)
-			npc.MoveTimer = moveTicks
-		}
+		s.guardWalkHome(npc)
+	}
+}
+
+// guardWalkHome 讓警衛走回出生點；超過 30 格或不在出生地圖時瞬移。
+func (s *NpcAISystem) guardWalkHome(npc *world.NpcInfo) {
+	if npc.MapID != npc.SpawnMapID || chebyshev32(npc.X, npc.Y, npc.SpawnX, npc.SpawnY) > 30 {
+		s.guardTeleportHome(npc)
+		return
+	}
+	if npc.MoveTimer <= 0 {
+		npcMoveToward(s.world, npc, npc.SpawnX, npc.SpawnY, s.deps.MapData)
+		moveTicks := calcNpcMoveTicks(npc)
+		npc.MoveTimer = moveTicks
 	}
 }
 
 // guardTeleportHome instantly moves a guard back to its spawn point.
 func (s *NpcAISystem) guardTeleportHome(npc *world.NpcInfo) {
 	oldX, oldY := npc.X, npc.Y
+	npc.Path.Clear()
 
 	// 通知舊位置附近玩家：移除 NPC + 解鎖格子
 	oldNearby := s.world.GetNearbyPlayersAt(oldX, oldY, npc.MapID)
@@ -424,9 +554,53 @@ func (s *NpcAISystem) guardTeleportHome(npc *world.NpcInfo) {
 	}
 }
 
-// npcTeleportHome 將怪物瞬移回出生點（附近無玩家時觸發）。
+// npcHomeWalkMaxDist 超過此距離（或不同地圖）時不走路，直接瞬移回出生點。
+const npcHomeWalkMaxDist = 60
+
+// npcHomeStuckMoves 走回家連續卡住的移動次數上限，超過則瞬移。
+const npcHomeStuckMoves = 5
+
+// npcWalkHome 讓怪物沿 A* 路徑走回出生點（附近無玩家時觸發）。
+// 抵達時回滿 HP/MP；距離過遠、跨地圖或卡住時退回 npcTeleportHome。
+func (s *NpcAISystem) npcWalkHome(npc *world.NpcInfo) {
+	if npc.MapID != npc.SpawnMapID ||
+		chebyshev32(npc.X, npc.Y, npc.SpawnX, npc.SpawnY) > npcHomeWalkMaxDist ||
+		npc.StuckTicks >= npcHomeStuckMoves {
+		s.npcTeleportHome(npc)
+		return
+	}
+	if npc.MoveTimer > 0 {
+		return
+	}
+	// 出生點被其他物件佔住：停在相鄰格即視為到家
+	if chebyshev32(npc.X, npc.Y, npc.SpawnX, npc.SpawnY) <= 1 && s.deps.MapData != nil &&
+		!s.deps.MapData.IsPassablePoint(npc.MapID, npc.SpawnX, npc.SpawnY) {
+		npc.HP = npc.MaxHP
+		npc.MP = npc.MaxMP
+		return
+	}
+
+	oldX, oldY := npc.X, npc.Y
+	npcMoveToward(s.world, npc, npc.SpawnX, npc.SpawnY, s.deps.MapData)
+	npc.MoveTimer = calcNpcMoveTicks(npc)
+	if npc.X == oldX && npc.Y == oldY {
+		npc.StuckTicks++
+		return
+	}
+	npc.StuckTicks = 0
+
+	// 回家時回滿血（Java: NPC 回家 = 重置狀態）
+	if npc.X == npc.SpawnX && npc.Y == npc.SpawnY {
+		npc.HP = npc.MaxHP
+		npc.MP = npc.MaxMP
+	}
+}
+
+// npcTeleportHome 將怪物瞬移回出生點（無法走回家時的退路）。
 func (s *NpcAISystem) npcTeleportHome(npc *world.NpcInfo) {
 	oldX, oldY := npc.X, npc.Y
+	npc.Path.Clear()
+	npc.StuckTicks = 0
 
 	// 通知舊位置附近玩家：移除 NPC + 解鎖格子
 	oldNearby := s.world.GetNearbyPlayersAt(oldX, oldY, npc.MapID)
@@ -493,7 +667,7 @@ func (s *NpcAISystem) npcMeleeAttack(npc *world.NpcInfo, target *world.PlayerInf
 	if damage > 0 && target.HasBuff(91) {
 		// 機率判定：probability = probabilityValue(25) ，與 random(1~100) 比較
 		prob := 25 // 基礎觸發率
-		if world.RandInt(100)+1 <= prob {
+		if world.RandInt(s.deps.Rand, 100)+1 <= prob {
 			// 計算反彈傷害（Java: calcCounterBarrierDamage — NPC 版本：(STR + Level) << 1）
 			cbDmg := int32((int(npc.STR) + int(npc.Level)) << 1)
 			// 套用設定倍率（Java: ConfigSkill.COUNTER_BARRIER_DMG = 1.5）
@@ -862,7 +1036,7 @@ func (s *NpcAISystem) executeNpcSelfSkill(npc *world.NpcInfo, skillID, gfxID int
 	if skill.DamageValue > 0 || skill.DamageDice > 0 {
 		heal := int32(skill.DamageValue)
 		if skill.DamageDice > 0 {
-			heal += int32(rand.Intn(int(skill.DamageDice)) + 1)
+			heal += int32(s.deps.Rand.Intn(int(skill.DamageDice)) + 1)
 		}
 		npc.HP += heal
 		if npc.HP > npc.MaxHP {
@@ -899,7 +1073,7 @@ func (s *NpcAISystem) executeNpcSummon(npc *world.NpcInfo, summonID int32, summo
 	// 計算召喚數量
 	count := summonMin
 	if summonMax > summonMin {
-		count = summonMin + rand.Intn(summonMax-summonMin+1)
+		count = summonMin + s.deps.Rand.Intn(summonMax-summonMin+1)
 	}
 	if count <= 0 {
 		count = 1
@@ -921,8 +1095,8 @@ func (s *NpcAISystem) executeNpcSummon(npc *world.NpcInfo, summonID int32, summo
 		// 在 NPC 附近 3 格內隨機找可走位置
 		sx, sy := npc.X, npc.Y
 		for try := 0; try < 10; try++ {
-			tx := npc.X + int32(rand.Intn(7)) - 3
-			ty := npc.Y + int32(rand.Intn(7)) - 3
+			tx := npc.X + int32(s.deps.Rand.Intn(7)) - 3
+			ty := npc.Y + int32(s.deps.Rand.Intn(7)) - 3
 			if s.deps.MapData != nil && s.deps.MapData.IsPassablePoint(npc.MapID, tx, ty) {
 				if !s.world.IsOccupied(tx, ty, npc.MapID, 0) {
 					sx, sy = tx, ty
@@ -1001,9 +1175,22 @@ func (s *NpcAISystem) executeNpcSummon(npc *world.NpcInfo, summonID int32, summo
 
 // ---------- NPC Movement ----------
 
-// npcMoveToward moves NPC 1 tile toward a target position.
-// If the direct path is blocked, tries two alternate side-step directions.
+// npcMoveToward moves NPC 1 tile toward a target position along a cached
+// A* path (see pathfind.go). Falls back to a greedy step when no path is found.
 func npcMoveToward(ws *world.State, npc *world.NpcInfo, tx, ty int32, maps *data.MapDataTable) {
+	if maps != nil {
+		if step, ok := nextPathStep(ws, maps, &npc.Path, npc.MapID, npc.X, npc.Y, tx, ty); ok {
+			h := calcNpcHeading(npc.X, npc.Y, step.X, step.Y)
+			npcExecuteMove(ws, npc, step.X, step.Y, h, maps)
+			return
+		}
+	}
+	npcStepToward(ws, npc, tx, ty, maps)
+}
+
+// npcStepToward moves NPC 1 tile greedily toward a target position.
+// If the direct path is blocked, tries two alternate side-step directions.
+func npcStepToward(ws *world.State, npc *world.NpcInfo, tx, ty int32, maps *data.MapDataTable) {
 	dx := tx - npc.X
 	dy := ty - npc.Y
 
@@ -1130,17 +1317,17 @@ func npcFleeFrom(ws *world.State, npc *world.NpcInfo, tx, ty int32, maps *data.M
 }
 
 // npcWander handles idle wandering. dir: 0-7=new direction, -1=continue, -2=toward spawn.
-func npcWander(ws *world.State, npc *world.NpcInfo, dir int, maps *data.MapDataTable) {
+func npcWander(ws *world.State, rng *rand.Rand, npc *world.NpcInfo, dir int, maps *data.MapDataTable) {
 	wanderTicks := calcNpcMoveTicks(npc)
 
 	if dir == -1 {
 		// Continue current direction
 	} else if dir == -2 {
 		npc.WanderDir = calcNpcHeading(npc.X, npc.Y, npc.SpawnX, npc.SpawnY)
-		npc.WanderDist = rand.Intn(5) + 2
+		npc.WanderDist = rng.Intn(5) + 2
 	} else {
 		npc.WanderDir = int16(dir)
-		npc.WanderDist = rand.Intn(5) + 2
+		npc.WanderDist = rng.Intn(5) + 2
 	}
 
 	if npc.WanderDist <= 0 {
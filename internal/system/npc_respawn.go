@@ -1,7 +1,6 @@
 package system
 
 import (
-	"math/rand"
 	"time"
 
 	coresys "github.com/l1jgo/server/internal/core/system"
@@ -84,6 +83,9 @@ func (s *NpcRespawnSystem) respawnNpc(npc *world.NpcInfo) {
 	npc.AttackTimer = 0
 	npc.MoveTimer = 0
 	npc.StuckTicks = 0
+	npc.Path.Clear()
+	npc.Dormant = false
+	npc.DormantTicks = 0
 	npc.Paralyzed = false
 	npc.Sleeped = false
 	npc.ActiveDebuffs = nil
@@ -91,6 +93,9 @@ func (s *NpcRespawnSystem) respawnNpc(npc *world.NpcInfo) {
 	npc.PoisonDmgTimer = 0
 	npc.PoisonAttackerSID = 0
 
+	// 重生後 Lua AI 狀態從空表開始（階段、計時器等不延續到新生命）
+	s.deps.Scripting.ResetNpcAIState(int(npc.ID))
+
 	// 重置聊天計時器（重生後重新觸發出現聊天）
 	StopNpcChat(npc)
 	npc.ChatFirstAttack = false
@@ -137,8 +142,8 @@ func (s *NpcRespawnSystem) respawnMobGroup(leader *world.NpcInfo, group *data.Mo
 			continue
 		}
 		for j := 0; j < minion.Count; j++ {
-			mx := leader.X + int32(rand.Intn(5)) - 2
-			my := leader.Y + int32(rand.Intn(5)) - 2
+			mx := leader.X + int32(s.deps.Rand.Intn(5)) - 2
+			my := leader.Y + int32(s.deps.Rand.Intn(5)) - 2
 
 			mob := s.createMinion(mTmpl, mx, my, leader)
 			s.world.AddNpc(mob)
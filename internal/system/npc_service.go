@@ -1,8 +1,7 @@
 package system
 
 import (
-	"math/rand"
-
+	"github.com/l1jgo/server/internal/core/event"
 	"github.com/l1jgo/server/internal/data"
 	"github.com/l1jgo/server/internal/handler"
 	"github.com/l1jgo/server/internal/net"
@@ -51,7 +50,7 @@ func (s *NpcServiceSystem) execHeal(sess *net.Session, player *world.PlayerInfo,
 	switch h.HealType {
 	case "random":
 		healRange := h.HealMax - h.HealMin + 1
-		healAmt := int32(rand.Intn(healRange) + h.HealMin)
+		healAmt := int32(s.deps.Rand.Intn(healRange) + h.HealMin)
 		if player.HP < player.MaxHP {
 			player.HP += healAmt
 			if player.HP > player.MaxHP {
@@ -137,17 +136,10 @@ func (s *NpcServiceSystem) NpcPoly(sess *net.Session, player *world.PlayerInfo,
 
 // NpcTeleportWithCost 處理 NPC 傳送（扣費 + 出發特效 + 延遲傳送）。
 func (s *NpcServiceSystem) NpcTeleportWithCost(sess *net.Session, player *world.PlayerInfo, dest *data.TeleportDest, objID int32) {
-	// 扣費
-	if dest.Price > 0 {
-		currentGold := player.Inv.GetAdena()
-		if currentGold < dest.Price {
-			handler.SendServerMessage(sess, 189) // "金幣不足"
-			return
-		}
-		if !s.consumeAdena(sess, player, dest.Price) {
-			handler.SendServerMessage(sess, 189)
-			return
-		}
+	// 先檢查金幣；費用在傳送通過驗證後才由 ScrollTeleportSystem 扣除
+	if dest.Price > 0 && player.Inv.GetAdena() < dest.Price {
+		handler.SendServerMessage(sess, 189) // "金幣不足"
+		return
 	}
 
 	// 出發特效 + 延遲 2 tick（400ms）傳送
@@ -160,6 +152,8 @@ func (s *NpcServiceSystem) NpcTeleportWithCost(sess *net.Session, player *world.
 	player.ScrollTPX = dest.X
 	player.ScrollTPY = dest.Y
 	player.ScrollTPMap = dest.MapID
+	player.ScrollTPSrc = world.TeleportSource{Path: world.TeleportPathNpc, NpcObjID: objID}
+	player.ScrollTPFee = dest.Price
 }
 
 // NpcUpgrade 處理物品升級合成。
@@ -198,6 +192,7 @@ func (s *NpcServiceSystem) NpcUpgrade(sess *net.Session, player *world.PlayerInf
 	}
 
 	// 4. 消耗主物品
+	handler.EmitItemMoved(s.deps, event.ItemReasonNpcService, mainItem, upg.MainItemCount, handler.CharLoc(player.CharID), "", player)
 	removed := player.Inv.RemoveItem(mainItem.ObjectID, upg.MainItemCount)
 	if removed {
 		handler.SendRemoveInventoryItem(sess, mainItem.ObjectID)
@@ -214,6 +209,7 @@ func (s *NpcServiceSystem) NpcUpgrade(sess *net.Session, player *world.PlayerInf
 		if needItem == nil {
 			continue
 		}
+		handler.EmitItemMoved(s.deps, event.ItemReasonNpcService, needItem, upg.NeedCounts[i], handler.CharLoc(player.CharID), "", player)
 		r := player.Inv.RemoveItem(needItem.ObjectID, upg.NeedCounts[i])
 		if r {
 			handler.SendRemoveInventoryItem(sess, needItem.ObjectID)
@@ -227,6 +223,7 @@ func (s *NpcServiceSystem) NpcUpgrade(sess *net.Session, player *world.PlayerInf
 		if i >= len(upg.PlusCounts) {
 			break
 		}
+		handler.EmitItemMoved(s.deps, event.ItemReasonNpcService, pi, upg.PlusCounts[i], handler.CharLoc(player.CharID), "", player)
 		r := player.Inv.RemoveItem(pi.ObjectID, upg.PlusCounts[i])
 		if r {
 			handler.SendRemoveInventoryItem(sess, pi.ObjectID)
@@ -239,11 +236,11 @@ func (s *NpcServiceSystem) NpcUpgrade(sess *net.Session, player *world.PlayerInf
 
 	// 7. 機率判定
 	totalChance := upg.UpgradeChance + bonusChance
-	roll := rand.Intn(100)
+	roll := s.deps.Rand.Intn(100)
 	if roll < totalChance {
 		s.upgradeSuccess(sess, player, upg)
 	} else {
-		if upg.DeleteChance > 0 && rand.Intn(100) < upg.DeleteChance {
+		if upg.DeleteChance > 0 && s.deps.Rand.Intn(100) < upg.DeleteChance {
 			s.upgradeDelete(sess, upg)
 		} else {
 			s.upgradeFailure(sess, upg)
@@ -264,6 +261,7 @@ func (s *NpcServiceSystem) upgradeSuccess(sess *net.Session, player *world.Playe
 				itemInfo.Weight, stackable, byte(itemInfo.Bless))
 			invItem.UseType = itemInfo.UseTypeID
 			invItem.Identified = true
+			handler.EmitItemMoved(s.deps, event.ItemReasonNpcService, invItem, 1, "", handler.CharLoc(player.CharID), player)
 
 			if wasExisting {
 				handler.SendItemCountUpdate(sess, invItem)
@@ -344,6 +342,7 @@ func (s *NpcServiceSystem) ConsumeItem(sess *net.Session, player *world.PlayerIn
 	if item == nil {
 		return false
 	}
+	handler.EmitItemMoved(s.deps, event.ItemReasonConsume, item, count, handler.CharLoc(player.CharID), "", player)
 	removed := player.Inv.RemoveItem(objectID, count)
 	if removed {
 		handler.SendRemoveInventoryItem(sess, objectID)
@@ -359,6 +358,7 @@ func (s *NpcServiceSystem) ConsumeItem(sess *net.Session, player *world.PlayerIn
 func (s *NpcServiceSystem) Refine(sess *net.Session, player *world.PlayerInfo, item *world.InvItem, crystalItemID int32, crystalCount int32) {
 	// 移除原物品（item 為 nil 時表示已由呼叫方移除，僅給予結晶體）
 	if item != nil {
+		handler.EmitItemMoved(s.deps, event.ItemReasonNpcService, item, 1, handler.CharLoc(player.CharID), "", player)
 		removed := player.Inv.RemoveItem(item.ObjectID, 1)
 		if removed {
 			handler.SendRemoveInventoryItem(sess, item.ObjectID)
@@ -376,6 +376,7 @@ func (s *NpcServiceSystem) Refine(sess *net.Session, player *world.PlayerInfo, i
 		newItem := player.Inv.AddItem(crystalItemID, crystalCount, crystalInfo.Name,
 			crystalInfo.InvGfx, crystalInfo.Weight, crystalInfo.Stackable, byte(crystalInfo.Bless))
 		newItem.UseType = data.UseTypeToID(crystalInfo.UseType)
+		handler.EmitItemMoved(s.deps, event.ItemReasonNpcService, newItem, crystalCount, "", handler.CharLoc(player.CharID), player)
 
 		if wasExisting {
 			handler.SendItemCountUpdate(sess, newItem)
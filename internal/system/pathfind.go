@@ -0,0 +1,207 @@
+package system
+
+import (
+	"github.com/l1jgo/server/internal/data"
+	"github.com/l1jgo/server/internal/world"
+)
+
+// 有界 A* 尋路：怪物追擊、走回出生點、寵物/召喚獸跟隨主人共用。
+// 8 方向等成本移動（與客戶端一致），啟發函式為 Chebyshev 距離。
+// 只在遊戲迴圈 goroutine 使用，搜尋緩衝區共用以避免每次配置。
+
+const (
+	pathMaxNodes   = 600 // 單次搜尋最多展開的節點數
+	pathMaxRange   = 40  // 搜尋範圍：距起點的 Chebyshev 距離上限
+	pathRepathDist = 3   // 目標偏離快取終點超過此格數時重新計算
+	pathRetryMoves = 3   // 尋路失敗後改用貪婪步進的移動次數
+)
+
+type pathNode struct {
+	x, y   int32
+	g, h   int32
+	parent int32 // 節點索引（-1 = 起點）
+	closed bool
+}
+
+// pathOpen 是 open 堆積的項目；f/h 在推入時固定，節點改善後推入新項目，舊項目出堆時略過。
+type pathOpen struct {
+	idx  int32
+	f, h int32
+}
+
+type pathFinder struct {
+	nodes []pathNode
+	index map[int64]int32
+	open  []pathOpen // 以 (f, h) 排序的二元堆積
+}
+
+// npcPathFinder 是全域共用的搜尋器（僅遊戲迴圈使用）。
+var npcPathFinder = &pathFinder{index: make(map[int64]int32)}
+
+func pathKey(x, y int32) int64 { return int64(x)<<32 | int64(uint32(y)) }
+
+func (pf *pathFinder) less(a, b pathOpen) bool {
+	if a.f != b.f {
+		return a.f < b.f
+	}
+	return a.h < b.h
+}
+
+func (pf *pathFinder) push(i int32) {
+	n := &pf.nodes[i]
+	pf.open = append(pf.open, pathOpen{idx: i, f: n.g + n.h, h: n.h})
+	c := len(pf.open) - 1
+	for c > 0 {
+		p := (c - 1) / 2
+		if !pf.less(pf.open[c], pf.open[p]) {
+			break
+		}
+		pf.open[c], pf.open[p] = pf.open[p], pf.open[c]
+		c = p
+	}
+}
+
+func (pf *pathFinder) pop() int32 {
+	top := pf.open[0]
+	last := len(pf.open) - 1
+	pf.open[0] = pf.open[last]
+	pf.open = pf.open[:last]
+	c := 0
+	for {
+		l, r, m := 2*c+1, 2*c+2, c
+		if l < last && pf.less(pf.open[l], pf.open[m]) {
+			m = l
+		}
+		if r < last && pf.less(pf.open[r], pf.open[m]) {
+			m = r
+		}
+		if m == c {
+			break
+		}
+		pf.open[c], pf.open[m] = pf.open[m], pf.open[c]
+		c = m
+	}
+	return top.idx
+}
+
+// find 從 (sx,sy) 搜尋到距 (tx,ty) stopDist 格以內的路徑，寫入 dst 後回傳（不含起點）。
+// 目標不可達或超過節點上限時，回傳通往已探索節點中最接近目標者的部分路徑；
+// 完全無法前進時回傳空切片。
+func (pf *pathFinder) find(maps *data.MapDataTable, mapID int16, sx, sy, tx, ty, stopDist int32, dst []world.PathStep) []world.PathStep {
+	dst = dst[:0]
+	pf.nodes = pf.nodes[:0]
+	pf.open = pf.open[:0]
+	clear(pf.index)
+
+	pf.nodes = append(pf.nodes, pathNode{x: sx, y: sy, h: chebyshev32(sx, sy, tx, ty), parent: -1})
+	pf.index[pathKey(sx, sy)] = 0
+	pf.push(0)
+
+	best := int32(0)
+	expanded := 0
+	for len(pf.open) > 0 && expanded < pathMaxNodes {
+		cur := pf.pop()
+		n := &pf.nodes[cur]
+		if n.closed {
+			continue // 堆積中的舊項目（已有更短路徑）
+		}
+		n.closed = true
+		expanded++
+
+		if n.h < pf.nodes[best].h || (n.h == pf.nodes[best].h && n.g < pf.nodes[best].g) {
+			best = cur
+		}
+		if n.h <= stopDist {
+			best = cur
+			break
+		}
+
+		x, y, g := n.x, n.y, n.g
+		for h := 0; h < 8; h++ {
+			if !maps.IsPassable(mapID, x, y, h) {
+				continue
+			}
+			nx, ny := x+npcHeadingDX[h], y+npcHeadingDY[h]
+			if chebyshev32(nx, ny, sx, sy) > pathMaxRange {
+				continue
+			}
+			key := pathKey(nx, ny)
+			if idx, ok := pf.index[key]; ok {
+				nb := &pf.nodes[idx]
+				if nb.closed || nb.g <= g+1 {
+					continue
+				}
+				nb.g = g + 1
+				nb.parent = cur
+				pf.push(idx)
+				continue
+			}
+			idx := int32(len(pf.nodes))
+			pf.nodes = append(pf.nodes, pathNode{x: nx, y: ny, g: g + 1, h: chebyshev32(nx, ny, tx, ty), parent: cur})
+			pf.index[key] = idx
+			pf.push(idx)
+		}
+	}
+
+	// 終點不比起點更接近目標 → 無法前進
+	if best == 0 {
+		return dst
+	}
+	for i := best; i != 0; i = pf.nodes[i].parent {
+		dst = append(dst, world.PathStep{X: pf.nodes[i].x, Y: pf.nodes[i].y})
+	}
+	for i, j := 0, len(dst)-1; i < j; i, j = i+1, j-1 {
+		dst[i], dst[j] = dst[j], dst[i]
+	}
+	return dst
+}
+
+// pathStopDist 目標格本身被佔用（玩家、NPC）時，走到相鄰格即算抵達。
+func pathStopDist(maps *data.MapDataTable, mapID int16, tx, ty int32) int32 {
+	if maps.IsPassablePoint(mapID, tx, ty) {
+		return 0
+	}
+	return 1
+}
+
+// pathStepUsable 檢查路徑的下一格是否仍與目前位置相鄰且可通行（動態阻擋可能已改變）。
+func pathStepUsable(ws *world.State, maps *data.MapDataTable, mapID int16, x, y int32, step world.PathStep) bool {
+	if chebyshev32(x, y, step.X, step.Y) != 1 {
+		return false
+	}
+	h := calcNpcHeading(x, y, step.X, step.Y)
+	if !maps.IsPassable(mapID, x, y, int(h)) {
+		return false
+	}
+	occupant := ws.OccupantAt(step.X, step.Y, mapID)
+	return occupant <= 0 || occupant >= 200_000_000
+}
+
+// nextPathStep 取出朝 (tx,ty) 前進的下一格，必要時重新計算快取路徑。
+// 回傳 false 表示找不到路徑，呼叫端應退回貪婪步進。
+func nextPathStep(ws *world.State, maps *data.MapDataTable, c *world.PathCache, mapID int16, x, y, tx, ty int32) (world.PathStep, bool) {
+	if c.MapID != mapID {
+		c.Clear()
+		c.MapID = mapID
+	}
+
+	if len(c.Steps) == 0 ||
+		chebyshev32(tx, ty, c.GoalX, c.GoalY) > pathRepathDist ||
+		!pathStepUsable(ws, maps, mapID, x, y, c.Steps[0]) {
+		if c.Retry > 0 {
+			c.Retry--
+			return world.PathStep{}, false
+		}
+		c.Steps = npcPathFinder.find(maps, mapID, x, y, tx, ty, pathStopDist(maps, mapID, tx, ty), c.Steps)
+		c.GoalX, c.GoalY = tx, ty
+		if len(c.Steps) == 0 || !pathStepUsable(ws, maps, mapID, x, y, c.Steps[0]) {
+			c.Steps = c.Steps[:0]
+			c.Retry = pathRetryMoves
+			return world.PathStep{}, false
+		}
+	}
+
+	step := c.Steps[0]
+	c.Steps = c.Steps[1:]
+	return step, true
+}
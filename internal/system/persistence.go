@@ -6,6 +6,7 @@ import (
 
 	coresys "github.com/l1jgo/server/internal/core/system"
 	"github.com/l1jgo/server/internal/handler"
+	"github.com/l1jgo/server/internal/net"
 	"github.com/l1jgo/server/internal/persist"
 	"github.com/l1jgo/server/internal/world"
 	"go.uber.org/zap"
@@ -15,16 +16,17 @@ import (
 // inventory, bookmarks, known spells, and active buffs. Phase 5 (Persist).
 type PersistenceSystem struct {
 	world     *world.State
-	charRepo  *persist.CharacterRepo
-	itemRepo  *persist.ItemRepo
-	buffRepo  *persist.BuffRepo
-	walRepo   *persist.WALRepo
+	charRepo  persist.CharacterStore
+	itemRepo  persist.ItemStore
+	buffRepo  persist.BuffStore
+	walRepo   persist.WALStore
+	dupes     *DupeCheckSystem // nil = 不檢查複製物品
 	log       *zap.Logger
 	tickCount int
 	interval  int // auto-save every N ticks
 }
 
-func NewPersistenceSystem(ws *world.State, charRepo *persist.CharacterRepo, itemRepo *persist.ItemRepo, buffRepo *persist.BuffRepo, walRepo *persist.WALRepo, log *zap.Logger, intervalTicks int) *PersistenceSystem {
+func NewPersistenceSystem(ws *world.State, charRepo persist.CharacterStore, itemRepo persist.ItemStore, buffRepo persist.BuffStore, walRepo persist.WALStore, log *zap.Logger, intervalTicks int) *PersistenceSystem {
 	return &PersistenceSystem{
 		world:    ws,
 		charRepo: charRepo,
@@ -36,6 +38,11 @@ func NewPersistenceSystem(ws *world.State, charRepo *persist.CharacterRepo, item
 	}
 }
 
+// SetDupeCheck 啟用自動存檔前的複製物品檢查（anti_cheat.duplicate_item_check）。
+func (s *PersistenceSystem) SetDupeCheck(d *DupeCheckSystem) {
+	s.dupes = d
+}
+
 func (s *PersistenceSystem) Phase() coresys.Phase { return coresys.PhasePersist }
 
 func (s *PersistenceSystem) Update(_ time.Duration) {
@@ -49,8 +56,48 @@ func (s *PersistenceSystem) Update(_ time.Duration) {
 
 // SaveAllPlayers persists all online players immediately, ignoring dirty flags.
 // Called for graceful shutdown to ensure no data is lost.
-func (s *PersistenceSystem) SaveAllPlayers() {
-	s.savePlayers(false) // dirtyOnly=false → save all for shutdown safety
+// Returns the number of players saved.
+func (s *PersistenceSystem) SaveAllPlayers() int {
+	return s.savePlayers(false) // dirtyOnly=false → save all for shutdown safety
+}
+
+// SaveAsync 以資料庫工作存檔 players（管理 API），不阻塞遊戲迴圈。存檔內容在
+// 呼叫當下擷取；等待期間這些玩家的輸入暫停、不參與自動存檔。done 於遊戲迴圈
+// 收到成功存檔的人數；佇列已滿時 err 為 persist.ErrJobQueueFull。
+func (s *PersistenceSystem) SaveAsync(deps *handler.Deps, players []*world.PlayerInfo, done func(saved int, err error)) {
+	if len(players) == 0 {
+		done(0, nil)
+		return
+	}
+	saves := make([]*playerSave, len(players))
+	sessions := make([]*net.Session, len(players))
+	for i, p := range players {
+		saves[i] = s.snapshot(p)
+		sessions[i] = p.Session
+		p.Dirty = false // 等待期間的變更重新標記，由之後的自動存檔寫入
+	}
+	ok := make([]bool, len(saves))
+	handler.RunSharedDBJob(sessions, deps, "admin_save", func(ctx context.Context) error {
+		for i, ps := range saves {
+			ok[i] = s.write(ctx, ps)
+		}
+		s.markProcessed(ctx)
+		return nil
+	}, func(err error) {
+		count := 0
+		for i, ps := range saves {
+			if err != nil || !ok[i] {
+				ps.player.Dirty = true
+				continue
+			}
+			ps.player.Inv.CommitSave(ps.inv.Changes)
+			count++
+		}
+		if count > 0 {
+			s.log.Info("管理 API 存檔完成", zap.Int("玩家數", count))
+		}
+		done(count, err)
+	})
 }
 
 func (s *PersistenceSystem) saveAllPlayers() {
@@ -59,103 +106,191 @@ func (s *PersistenceSystem) saveAllPlayers() {
 
 // savePlayers persists player data. If dirtyOnly is true, only saves players
 // whose Dirty flag is set and resets the flag after successful save.
-func (s *PersistenceSystem) savePlayers(dirtyOnly bool) {
-	count := 0
+func (s *PersistenceSystem) savePlayers(dirtyOnly bool) int {
+	var batch []*world.PlayerInfo
 	s.world.AllPlayers(func(p *world.PlayerInfo) {
 		if dirtyOnly && !p.Dirty {
 			return // skip clean players — no state change since last save
 		}
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-
-		// 儲存時必須扣除裝備加成和 buff 加成，只保存基礎值。
-		// 否則重新登入時 InitEquipStats / loadAndRestoreBuffs 會重複疊加，造成屬性膨脹。
-		eq := p.EquipBonuses
-		var bStr, bDex, bCon, bWis, bIntel, bCha int16
-		var bMaxHP, bMaxMP int32
-		for _, b := range p.ActiveBuffs {
-			bStr += b.DeltaStr
-			bDex += b.DeltaDex
-			bCon += b.DeltaCon
-			bWis += b.DeltaWis
-			bIntel += b.DeltaIntel
-			bCha += b.DeltaCha
-			bMaxHP += b.DeltaMaxHP
-			bMaxMP += b.DeltaMaxMP
-		}
-		row := &persist.CharacterRow{
-			Name:       p.Name,
-			Level:      p.Level,
-			Exp:        int64(p.Exp),
-			HP:         p.HP,
-			MP:         p.MP,
-			MaxHP:      p.MaxHP - int32(eq.AddHP) - bMaxHP,
-			MaxMP:      p.MaxMP - int32(eq.AddMP) - bMaxMP,
-			X:          p.X,
-			Y:          p.Y,
-			MapID:      p.MapID,
-			Heading:    p.Heading,
-			Lawful:     p.Lawful,
-			Str:        p.Str - int16(eq.AddStr) - bStr,
-			Dex:        p.Dex - int16(eq.AddDex) - bDex,
-			Con:        p.Con - int16(eq.AddCon) - bCon,
-			Wis:        p.Wis - int16(eq.AddWis) - bWis,
-			Cha:        p.Cha - int16(eq.AddCha) - bCha,
-			Intel:      p.Intel - int16(eq.AddInt) - bIntel,
-			BonusStats:  p.BonusStats,
-			ElixirStats: p.ElixirStats,
-			ClanID:      p.ClanID,
-			ClanName:   p.ClanName,
-			ClanRank:   p.ClanRank,
-			Title:      p.Title,
-			Karma:      p.Karma,
-			PKCount:    p.PKCount,
-			Food:       p.Food,
+		if jobPending(p) {
+			return // stays dirty; saved by the next batch
 		}
-		if err := s.charRepo.SaveCharacter(ctx, row); err != nil {
-			s.log.Error("自動存檔角色失敗", zap.String("name", p.Name), zap.Error(err))
-			return
-		}
-		if err := s.itemRepo.SaveInventory(ctx, p.CharID, p.Inv, &p.Equip); err != nil {
-			s.log.Error("自動存檔背包失敗", zap.String("name", p.Name), zap.Error(err))
-			return
-		}
-		if err := s.charRepo.SaveBookmarks(ctx, p.Name, bookmarksToRows(p.Bookmarks)); err != nil {
-			s.log.Error("自動存檔書籤失敗", zap.String("name", p.Name), zap.Error(err))
-		}
-		if err := s.charRepo.SaveKnownSpells(ctx, p.Name, p.KnownSpells); err != nil {
-			s.log.Error("自動存檔魔法書失敗", zap.String("name", p.Name), zap.Error(err))
-		}
-		if len(p.MapTimeUsed) > 0 {
-			if err := s.charRepo.SaveMapTimes(ctx, p.Name, p.MapTimeUsed); err != nil {
-				s.log.Error("自動存檔限時地圖時間失敗", zap.String("name", p.Name), zap.Error(err))
-			}
-		}
-		// Save active buffs (including polymorph state)
-		if s.buffRepo != nil && len(p.ActiveBuffs) > 0 {
-			buffRows := handler.BuffRowsFromPlayer(p)
-			if len(buffRows) > 0 {
-				if err := s.buffRepo.SaveBuffs(ctx, p.CharID, buffRows); err != nil {
-					s.log.Error("自動存檔buff失敗", zap.String("name", p.Name), zap.Error(err))
-				}
-			}
+		batch = append(batch, p)
+	})
+	// 自動存檔時以資料庫工作檢查複製物品：被隔離的物品於回呼中移出背包，由下一次存檔刪除。
+	// 關機與管理 API 的完整存檔不檢查（關機時工作佇列已關閉）。
+	if s.dupes != nil && dirtyOnly && len(batch) > 0 {
+		s.dupes.CheckPlayers(batch)
+	}
+	count := 0
+	for _, p := range batch {
+		if !s.savePlayer(p) {
+			continue
 		}
 		p.Dirty = false
 		count++
-	})
+	}
 	if count > 0 {
 		s.log.Info("自動存檔完成", zap.Int("玩家數", count))
 	}
 
-	// Mark WAL entries as processed after successful batch save.
-	// This prevents replay of already-persisted economic transactions on crash recovery.
-	if s.walRepo != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		if err := s.walRepo.MarkProcessed(ctx); err != nil {
-			s.log.Error("WAL MarkProcessed 失敗", zap.Error(err))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s.markProcessed(ctx)
+	return count
+}
+
+// markProcessed marks WAL entries processed once every character side is
+// applied. SaveInventory set the apply markers of exactly the characters just
+// saved. Safe on a database worker.
+func (s *PersistenceSystem) markProcessed(ctx context.Context) {
+	if s.walRepo == nil {
+		return
+	}
+	n, err := s.walRepo.MarkProcessed(ctx)
+	if err != nil {
+		s.log.Error("WAL MarkProcessed 失敗", zap.Error(err))
+	} else if n > 0 {
+		s.log.Debug("WAL 條目已處理", zap.Int64("筆數", n))
+	}
+}
+
+// jobPending reports whether the player's session is waiting on a database
+// job. Such a player holds a reservation (e.g. items taken out for a warehouse
+// deposit) whose WAL entry is not written yet; saving now would mark an entry
+// applied before it exists, so the save waits for the job to finish.
+func jobPending(p *world.PlayerInfo) bool {
+	return p.Session != nil && p.Session.JobPending()
+}
+
+// playerSave 是在遊戲迴圈擷取的一名玩家存檔內容；write 只讀取這份快照，
+// 可在資料庫 worker 執行（管理 API 存檔）。
+type playerSave struct {
+	player    *world.PlayerInfo
+	row       *persist.CharacterRow
+	inv       persist.InventorySave
+	bookmarks []persist.BookmarkRow
+	spells    []int32
+	mapTimes  map[int]int
+	buffs     []persist.BuffRow
+}
+
+// savePlayer writes one player's character row, inventory, bookmarks, spells,
+// map timers and buffs. Returns false if the character or inventory save failed.
+func (s *PersistenceSystem) savePlayer(p *world.PlayerInfo) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ps := s.snapshot(p)
+	if !s.write(ctx, ps) {
+		return false
+	}
+	p.Inv.CommitSave(ps.inv.Changes)
+	return true
+}
+
+// snapshot 擷取 p 的存檔內容。只在遊戲迴圈呼叫；寫入成功後須以
+// p.Inv.CommitSave(ps.inv.Changes) 提交背包存檔標記。
+func (s *PersistenceSystem) snapshot(p *world.PlayerInfo) *playerSave {
+	// 儲存時必須扣除裝備加成和 buff 加成，只保存基礎值。
+	// 否則重新登入時 InitEquipStats / loadAndRestoreBuffs 會重複疊加，造成屬性膨脹。
+	eq := p.EquipBonuses
+	var bStr, bDex, bCon, bWis, bIntel, bCha int16
+	var bMaxHP, bMaxMP int32
+	for _, b := range p.ActiveBuffs {
+		bStr += b.DeltaStr
+		bDex += b.DeltaDex
+		bCon += b.DeltaCon
+		bWis += b.DeltaWis
+		bIntel += b.DeltaIntel
+		bCha += b.DeltaCha
+		bMaxHP += b.DeltaMaxHP
+		bMaxMP += b.DeltaMaxMP
+	}
+	ps := &playerSave{player: p}
+	ps.row = &persist.CharacterRow{
+		Name:       p.Name,
+		Level:      p.Level,
+		Exp:        int64(p.Exp),
+		HP:         p.HP,
+		MP:         p.MP,
+		MaxHP:      p.MaxHP - int32(eq.AddHP) - bMaxHP,
+		MaxMP:      p.MaxMP - int32(eq.AddMP) - bMaxMP,
+		X:          p.X,
+		Y:          p.Y,
+		MapID:      p.MapID,
+		Heading:    p.Heading,
+		Lawful:     p.Lawful,
+		Str:        p.Str - int16(eq.AddStr) - bStr,
+		Dex:        p.Dex - int16(eq.AddDex) - bDex,
+		Con:        p.Con - int16(eq.AddCon) - bCon,
+		Wis:        p.Wis - int16(eq.AddWis) - bWis,
+		Cha:        p.Cha - int16(eq.AddCha) - bCha,
+		Intel:      p.Intel - int16(eq.AddInt) - bIntel,
+		BonusStats:  p.BonusStats,
+		ElixirStats: p.ElixirStats,
+		ClanID:      p.ClanID,
+		ClanName:   p.ClanName,
+		ClanRank:   p.ClanRank,
+		Title:      p.Title,
+		Karma:      p.Karma,
+		PKCount:    p.PKCount,
+		Food:       p.Food,
+	}
+	ps.inv = s.itemRepo.PrepareInventorySave(p.CharID, p.Inv, &p.Equip)
+	ps.bookmarks = bookmarksToRows(p.Bookmarks)
+	ps.spells = append([]int32(nil), p.KnownSpells...)
+	if len(p.MapTimeUsed) > 0 {
+		ps.mapTimes = make(map[int]int, len(p.MapTimeUsed))
+		for k, v := range p.MapTimeUsed {
+			ps.mapTimes[k] = v
+		}
+	}
+	// Save active buffs (including polymorph state)
+	if s.buffRepo != nil && len(p.ActiveBuffs) > 0 {
+		ps.buffs = handler.BuffRowsFromPlayer(p)
+	}
+	return ps
+}
+
+// write 寫入快照，不讀寫遊戲狀態。角色或背包寫入失敗時回傳 false；
+// 書籤、魔法書、限時地圖與 buff 的失敗只記錄錯誤。
+func (s *PersistenceSystem) write(ctx context.Context, ps *playerSave) bool {
+	name := ps.row.Name
+	if err := s.charRepo.SaveCharacter(ctx, ps.row); err != nil {
+		s.log.Error("自動存檔角色失敗", zap.String("name", name), zap.Error(err))
+		return false
+	}
+	start := time.Now()
+	inv, err := s.itemRepo.WriteInventory(ctx, ps.inv)
+	if err != nil {
+		s.log.Error("自動存檔背包失敗", zap.String("name", name), zap.Error(err))
+		return false
+	}
+	s.log.Debug("背包存檔",
+		zap.String("name", name),
+		zap.Int("物品數", inv.Items),
+		zap.Int("寫入", inv.Upserted),
+		zap.Int("刪除", inv.Deleted),
+		zap.Bool("完整", inv.Full),
+		zap.Duration("耗時", time.Since(start)),
+	)
+	if err := s.charRepo.SaveBookmarks(ctx, name, ps.bookmarks); err != nil {
+		s.log.Error("自動存檔書籤失敗", zap.String("name", name), zap.Error(err))
+	}
+	if err := s.charRepo.SaveKnownSpells(ctx, name, ps.spells); err != nil {
+		s.log.Error("自動存檔魔法書失敗", zap.String("name", name), zap.Error(err))
+	}
+	if len(ps.mapTimes) > 0 {
+		if err := s.charRepo.SaveMapTimes(ctx, name, ps.mapTimes); err != nil {
+			s.log.Error("自動存檔限時地圖時間失敗", zap.String("name", name), zap.Error(err))
+		}
+	}
+	if len(ps.buffs) > 0 {
+		if err := s.buffRepo.SaveBuffs(ctx, ps.inv.CharID, ps.buffs); err != nil {
+			s.log.Error("自動存檔buff失敗", zap.String("name", name), zap.Error(err))
 		}
 	}
+	return true
 }
 
 // bookmarksToRows is defined in input.go (shared within the system package).
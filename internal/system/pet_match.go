@@ -248,8 +248,8 @@ func (s *PetMatchSystem) withdrawPet(sess *net.Session, player *world.PlayerInfo
 		GfxID:       tmpl.GfxID,
 		NameID:      tmpl.NameID,
 		MoveSpeed:   tmpl.PassiveSpeed,
-		X:           player.X + int32(world.RandInt(3)) - 1,
-		Y:           player.Y + int32(world.RandInt(3)) - 1,
+		X:           player.X + int32(world.RandInt(s.deps.Rand, 3)) - 1,
+		Y:           player.Y + int32(world.RandInt(s.deps.Rand, 3)) - 1,
 		MapID:       player.MapID,
 		Heading:     player.Heading,
 		Status:      world.PetStatusRest,
@@ -78,8 +78,8 @@ func (s *PetSystem) UsePetCollar(sess *net.Session, player *world.PlayerInfo, in
 	petType := s.deps.PetTypes.Get(petRow.NpcID)
 
 	// 主人附近隨機生成位置（±2 格）
-	spawnX := player.X + int32(world.RandInt(5)) - 2
-	spawnY := player.Y + int32(world.RandInt(5)) - 2
+	spawnX := player.X + int32(world.RandInt(s.deps.Rand, 5)) - 2
+	spawnY := player.Y + int32(world.RandInt(s.deps.Rand, 5)) - 2
 
 	// 計算 MaxHP/MaxMP：使用 DB 值，回退到模板值
 	maxHP := petRow.MaxHP
@@ -371,8 +371,8 @@ func (s *PetSystem) AddPetExp(pet *world.PetInfo, expGain int32) {
 		pet.Level++
 		petType := s.deps.PetTypes.Get(pet.NpcID)
 		if petType != nil {
-			hpGain := petType.HPUpMin + world.RandInt(petType.HPUpMax-petType.HPUpMin+1)
-			mpGain := petType.MPUpMin + world.RandInt(petType.MPUpMax-petType.MPUpMin+1)
+			hpGain := petType.HPUpMin + world.RandInt(s.deps.Rand, petType.HPUpMax-petType.HPUpMin+1)
+			mpGain := petType.MPUpMin + world.RandInt(s.deps.Rand, petType.MPUpMax-petType.MPUpMin+1)
 			pet.MaxHP += int32(hpGain)
 			pet.MaxMP += int32(mpGain)
 		}
@@ -643,7 +643,7 @@ func (s *PetSystem) TameNpc(sess *net.Session, player *world.PlayerInfo, npc *wo
 
 	// 特殊案例：Tiger Man (45313) 即使血量低於 1/3 也只有 1/16 機率
 	if npc.NpcID == 45313 {
-		if world.RandInt(16) != 15 {
+		if world.RandInt(s.deps.Rand, 16) != 15 {
 			log.Printf("[TameNpc] 虎男馴服機率失敗")
 			handler.SendServerMessage(sess, 324)
 			return
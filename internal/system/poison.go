@@ -1,8 +1,6 @@
 package system
 
 import (
-	"time"
-
 	"github.com/l1jgo/server/internal/handler"
 	"github.com/l1jgo/server/internal/world"
 )
@@ -25,8 +23,8 @@ import (
 
 // TickCatapultSilence 每 tick 檢查投石車沉默砲彈到期。
 // 由 BuffTickSystem (Phase 2) 呼叫。
-func TickCatapultSilence(p *world.PlayerInfo) {
-	if p.CatapultSilenceEnd > 0 && time.Now().Unix() >= p.CatapultSilenceEnd {
+func TickCatapultSilence(p *world.PlayerInfo, deps *handler.Deps) {
+	if p.CatapultSilenceEnd > 0 && deps.Clock.Now().Unix() >= p.CatapultSilenceEnd {
 		p.CatapultSilenceEnd = 0
 		// 只有在不是沉默毒的情況下才解除沉默
 		if p.PoisonType != 2 {
@@ -196,7 +194,7 @@ func ApplyNpcPoisonAttack(npc *world.NpcInfo, target *world.PlayerInfo, ws *worl
 	// 這些系統尚未實現，預留檢查位
 
 	// 15% 機率觸發（Java: if (15 >= _random.nextInt(100) + 1)）
-	if world.RandInt(100) >= 15 {
+	if world.RandInt(deps.Rand, 100) >= 15 {
 		return
 	}
 
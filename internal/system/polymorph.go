@@ -178,7 +178,7 @@ func (s *PolymorphSystem) UsePolyScroll(sess *net.Session, player *world.PlayerI
 	}
 
 	// 計算持續時間
-	duration := handler.PolyScrollDuration(invItem.ItemID)
+	duration := handler.PolyScrollDuration(s.deps.Rand, invItem.ItemID)
 
 	// 執行變身
 	s.DoPoly(player, poly.PolyID, duration, data.PolyCauseMagic)
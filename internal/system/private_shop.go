@@ -3,7 +3,10 @@ package system
 import (
 	"fmt"
 
+	"github.com/l1jgo/server/internal/core/event"
 	"github.com/l1jgo/server/internal/handler"
+	"github.com/l1jgo/server/internal/net"
+	"github.com/l1jgo/server/internal/persist"
 	"github.com/l1jgo/server/internal/world"
 )
 
@@ -53,13 +56,19 @@ func (s *PrivateShopSystem) CancelShopNotTradable(player *world.PlayerInfo) {
 	handler.BroadcastToPlayers(nearby, handler.BuildActionGfx(player.CharID, 3))
 }
 
+// privateShopDeal 是一筆已預扣、等待 WAL 寫入的個人商店成交。
+type privateShopDeal struct {
+	item  walHold // 預扣自物品來源方
+	price int32
+	tally *int32 // 成交後累加的清單數量（SoldCount / BoughtCount）
+}
+
 // ExecuteBuy 執行從個人商店購買物品（業務驗證 + 物品/金幣轉移 + 售完清理）。
+// 商店物品與買方金幣先預扣，WAL 寫入成功才交付。
 func (s *PrivateShopSystem) ExecuteBuy(buyer *world.PlayerInfo, shopPlayer *world.PlayerInfo, orders []handler.ShopBuyOrder) {
 	if shopPlayer.ShopTradingLocked {
 		return
 	}
-	shopPlayer.ShopTradingLocked = true
-	defer func() { shopPlayer.ShopTradingLocked = false }()
 
 	sellList := shopPlayer.ShopSellList
 	if len(sellList) == 0 {
@@ -71,6 +80,9 @@ func (s *PrivateShopSystem) ExecuteBuy(buyer *world.PlayerInfo, shopPlayer *worl
 		return
 	}
 
+	var deals []privateShopDeal
+	var holds []walHold
+	incoming := 0 // 已預扣、尚未放入買方背包的格數
 	for _, o := range orders {
 		if o.Order < 0 || o.Order >= len(sellList) || o.Count <= 0 {
 			continue
@@ -96,7 +108,7 @@ func (s *PrivateShopSystem) ExecuteBuy(buyer *world.PlayerInfo, shopPlayer *worl
 		totalPrice := int64(pssl.SellPrice) * int64(count)
 		if totalPrice > 2_000_000_000 {
 			handler.SendServerMessageArgs(buyer.Session, 904, "2000000000")
-			return
+			break
 		}
 		price := int32(totalPrice)
 
@@ -114,55 +126,65 @@ func (s *PrivateShopSystem) ExecuteBuy(buyer *world.PlayerInfo, shopPlayer *worl
 		}
 
 		// 驗證買方背包容量
-		if !item.Stackable && buyer.Inv.Size()+int(count) > world.MaxInventorySize {
+		if !item.Stackable && buyer.Inv.Size()+incoming+int(count) > world.MaxInventorySize {
 			handler.SendServerMessage(buyer.Session, 270) // 背包過重
 			break
 		}
 
-		// 執行物品轉移：賣方 → 買方
-		s.TransferItem(shopPlayer, buyer, item, count)
-
-		// 執行金幣轉移：買方 → 賣方
-		s.TransferGold(buyer, shopPlayer, price)
+		// 預扣：物品 賣方 → 買方、金幣 買方 → 賣方
+		d := privateShopDeal{price: price, tally: &pssl.SoldCount}
+		d.item = reserveInvItem(shopPlayer, item, count)
+		holds = append(holds, d.item)
+		if price > 0 {
+			holds = append(holds, reserveInvItem(buyer, buyer.Inv.FindByItemID(world.AdenaItemID), price))
+		}
+		if !item.Stackable {
+			incoming += int(count)
+		}
+		deals = append(deals, d)
+	}
 
+	s.settle(shopPlayer, shopPlayer, buyer, buyer, deals, holds, func() {
 		// 通知商店玩家：出售成功（Java: S_ServerMessage 877）
-		itemName := item.Name
-		if count > 1 {
-			itemName = fmt.Sprintf("%s (%d)", item.Name, count)
+		for _, d := range deals {
+			itemName := d.item.item.Name
+			if d.item.qty > 1 {
+				itemName = fmt.Sprintf("%s (%d)", d.item.item.Name, d.item.qty)
+			}
+			handler.SendServerMessageArgs(shopPlayer.Session, 877, buyer.Name, itemName)
 		}
-		handler.SendServerMessageArgs(shopPlayer.Session, 877, buyer.Name, itemName)
-
-		// 更新售出累計
-		pssl.SoldCount += count
-	}
 
-	// 清理已售完的項目（從末尾向前刪除）
-	for i := len(sellList) - 1; i >= 0; i-- {
-		if sellList[i].SoldCount >= sellList[i].SellTotal {
-			sellList = append(sellList[:i], sellList[i+1:]...)
+		// 清理已售完的項目（從末尾向前刪除）
+		sellList := shopPlayer.ShopSellList
+		for i := len(sellList) - 1; i >= 0; i-- {
+			if sellList[i].SoldCount >= sellList[i].SellTotal {
+				sellList = append(sellList[:i], sellList[i+1:]...)
+			}
 		}
-	}
-	shopPlayer.ShopSellList = sellList
+		shopPlayer.ShopSellList = sellList
 
-	// 如果所有商品都售完，自動關閉商店
-	if len(sellList) == 0 && len(shopPlayer.ShopBuyList) == 0 {
-		s.CloseShop(shopPlayer)
-	}
+		// 如果所有商品都售完，自動關閉商店
+		if shopPlayer.PrivateShop && len(sellList) == 0 && len(shopPlayer.ShopBuyList) == 0 {
+			s.CloseShop(shopPlayer)
+		}
+	})
 }
 
 // ExecuteSell 執行向個人商店出售物品（業務驗證 + 物品/金幣轉移 + 收購完成清理）。
+// 玩家物品與商店玩家金幣先預扣，WAL 寫入成功才交付。
 func (s *PrivateShopSystem) ExecuteSell(seller *world.PlayerInfo, shopPlayer *world.PlayerInfo, orders []handler.ShopSellOrder) {
 	if shopPlayer.ShopTradingLocked {
 		return
 	}
-	shopPlayer.ShopTradingLocked = true
-	defer func() { shopPlayer.ShopTradingLocked = false }()
 
 	buyList := shopPlayer.ShopBuyList
 	if len(buyList) == 0 {
 		return
 	}
 
+	var deals []privateShopDeal
+	var holds []walHold
+	incoming := 0 // 已預扣、尚未放入商店玩家背包的格數
 	for _, o := range orders {
 		if o.Order < 0 || o.Order >= len(buyList) || o.Count <= 0 {
 			continue
@@ -186,7 +208,8 @@ func (s *PrivateShopSystem) ExecuteSell(seller *world.PlayerInfo, shopPlayer *wo
 
 		// 驗證物品種類和強化等級匹配（防作弊）
 		if item.ItemID != psbl.ItemID || item.EnchantLvl != psbl.EnchantLvl {
-			return // 可能作弊
+			// 可能作弊：已預扣的成交照常完成
+			break
 		}
 
 		// 驗證物品數量
@@ -199,7 +222,7 @@ func (s *PrivateShopSystem) ExecuteSell(seller *world.PlayerInfo, shopPlayer *wo
 		totalPrice := int64(psbl.BuyPrice) * int64(count)
 		if totalPrice > 2_000_000_000 {
 			handler.SendServerMessageArgs(seller.Session, 904, "2000000000")
-			return
+			break
 		}
 		price := int32(totalPrice)
 
@@ -211,85 +234,116 @@ func (s *PrivateShopSystem) ExecuteSell(seller *world.PlayerInfo, shopPlayer *wo
 		}
 
 		// 驗證商店玩家背包容量
-		if !item.Stackable && shopPlayer.Inv.Size()+int(count) > world.MaxInventorySize {
+		if !item.Stackable && shopPlayer.Inv.Size()+incoming+int(count) > world.MaxInventorySize {
 			handler.SendServerMessage(seller.Session, 271) // 對方背包過重
 			break
 		}
 
-		// 執行物品轉移：賣方（玩家）→ 商店玩家
-		s.TransferItem(seller, shopPlayer, item, count)
-
-		// 執行金幣轉移：商店玩家 → 玩家
-		s.TransferGold(shopPlayer, seller, price)
-
-		// 更新收購累計
-		psbl.BoughtCount += count
+		// 預扣：物品 玩家 → 商店玩家、金幣 商店玩家 → 玩家
+		d := privateShopDeal{price: price, tally: &psbl.BoughtCount}
+		d.item = reserveInvItem(seller, item, count)
+		holds = append(holds, d.item)
+		if price > 0 {
+			holds = append(holds, reserveInvItem(shopPlayer, shopPlayer.Inv.FindByItemID(world.AdenaItemID), price))
+		}
+		if !item.Stackable {
+			incoming += int(count)
+		}
+		deals = append(deals, d)
 	}
 
-	// 清理已收購完的項目
-	for i := len(buyList) - 1; i >= 0; i-- {
-		if buyList[i].BoughtCount >= buyList[i].BuyTotal {
-			buyList = append(buyList[:i], buyList[i+1:]...)
+	s.settle(shopPlayer, seller, shopPlayer, seller, deals, holds, func() {
+		// 清理已收購完的項目
+		buyList := shopPlayer.ShopBuyList
+		for i := len(buyList) - 1; i >= 0; i-- {
+			if buyList[i].BoughtCount >= buyList[i].BuyTotal {
+				buyList = append(buyList[:i], buyList[i+1:]...)
+			}
 		}
-	}
-	shopPlayer.ShopBuyList = buyList
+		shopPlayer.ShopBuyList = buyList
+
+		// 所有商品收購完成 → 自動關閉商店
+		if shopPlayer.PrivateShop && len(shopPlayer.ShopSellList) == 0 && len(buyList) == 0 {
+			s.CloseShop(shopPlayer)
+		}
+	})
+}
 
-	// 所有商品收購完成 → 自動關閉商店
-	if len(shopPlayer.ShopSellList) == 0 && len(buyList) == 0 {
-		s.CloseShop(shopPlayer)
+// settle 寫入成交的 WAL（物品 itemFrom → itemTo、金幣 itemTo → itemFrom），等待期間鎖定商店。
+// 成功時交付預扣的物品與金幣、累加清單數量並呼叫 then；失敗時全部退回原主並通知 customer。
+func (s *PrivateShopSystem) settle(shopPlayer, itemFrom, itemTo, customer *world.PlayerInfo, deals []privateShopDeal, holds []walHold, then func()) {
+	wal := make([]persist.WALEntry, 0, 2*len(deals))
+	for _, d := range deals {
+		wal = append(wal,
+			persist.ItemWAL(persist.WALPrivateShop, itemFrom.CharID, itemTo.CharID, d.item.item, d.item.qty),
+			persist.GoldWAL(persist.WALPrivateShop, itemTo.CharID, itemFrom.CharID, int64(d.price)))
 	}
+
+	shopPlayer.ShopTradingLocked = true
+	writeWAL(s.deps, []*net.Session{itemFrom.Session, itemTo.Session}, wal, func(ok bool) {
+		shopPlayer.ShopTradingLocked = false
+		if !ok {
+			releaseHolds(holds)
+			handler.SendServerMessage(customer.Session, 989) // 無法交易
+			return
+		}
+		for _, d := range deals {
+			handler.EmitItemMoved(s.deps, event.ItemReasonPrivateShop, d.item.item, d.item.qty, handler.CharLoc(itemFrom.CharID), handler.CharLoc(itemTo.CharID), itemFrom)
+			s.giveItem(itemTo, d.item)
+			if d.price > 0 {
+				s.giveGold(itemFrom, d.price)
+			}
+			*d.tally += d.item.qty
+		}
+		then()
+	})
 }
 
 // TransferItem 從來源玩家背包移動物品到目標玩家背包。
 func (s *PrivateShopSystem) TransferItem(from, to *world.PlayerInfo, item *world.InvItem, count int32) {
-	info := s.deps.Items.Get(item.ItemID)
+	handler.EmitItemMoved(s.deps, event.ItemReasonPrivateShop, item, count, handler.CharLoc(from.CharID), handler.CharLoc(to.CharID), from)
+	s.giveItem(to, reserveInvItem(from, item, count))
+}
 
-	if item.Stackable && item.Count > count {
-		// 可堆疊物品：扣減來源數量 + 更新顯示
-		item.Count -= count
-		from.Dirty = true
-		handler.SendItemCountUpdate(from.Session, item)
+// giveItem 將預扣的物品放入目標玩家背包：堆疊的一部分併入目標堆疊，整件移出的物品保留原有屬性
+// （非堆疊物品沿用 ObjectID）。
+func (s *PrivateShopSystem) giveItem(to *world.PlayerInfo, h walHold) {
+	item, count := h.item, h.qty
+	info := s.deps.Items.Get(item.ItemID)
+	to.Dirty = true
 
-		// 目標：增加數量或新增
+	if !h.slotRemoved {
 		destItem := to.Inv.AddItem(item.ItemID, count, item.Name, item.InvGfx, item.Weight, true, item.Bless)
-		to.Dirty = true
 		handler.SendAddItem(to.Session, destItem, info)
-	} else {
-		// 不可堆疊或全部移出：移除整個物品
-		from.Inv.RemoveItem(item.ObjectID, count)
-		from.Dirty = true
-		handler.SendRemoveInventoryItem(from.Session, item.ObjectID)
-
-		// 目標：新增物品（保留原有屬性）
-		newItem := to.Inv.AddItemWithID(0, item.ItemID, count, item.Name, item.InvGfx, item.Weight, item.Stackable, item.Bless)
-		newItem.EnchantLvl = item.EnchantLvl
-		newItem.Identified = item.Identified
-		newItem.UseType = item.UseType
-		newItem.AttrEnchantKind = item.AttrEnchantKind
-		newItem.AttrEnchantLevel = item.AttrEnchantLevel
-		newItem.Durability = item.Durability
-		to.Dirty = true
-		handler.SendAddItem(to.Session, newItem, info)
+		return
+	}
+
+	keepObjID := int32(0)
+	if !item.Stackable {
+		keepObjID = item.ObjectID
 	}
+	newItem := to.Inv.AddItemWithID(keepObjID, item.ItemID, count, item.Name, item.InvGfx, item.Weight, item.Stackable, item.Bless)
+	newItem.EnchantLvl = item.EnchantLvl
+	newItem.Identified = item.Identified
+	newItem.UseType = item.UseType
+	newItem.AttrEnchantKind = item.AttrEnchantKind
+	newItem.AttrEnchantLevel = item.AttrEnchantLevel
+	newItem.Durability = item.Durability
+	handler.SendAddItem(to.Session, newItem, info)
 }
 
 // TransferGold 轉移金幣。
 func (s *PrivateShopSystem) TransferGold(from, to *world.PlayerInfo, amount int32) {
-	// 從來源扣除
 	fromAdena := from.Inv.FindByItemID(world.AdenaItemID)
 	if fromAdena == nil {
 		return
 	}
-	fromAdena.Count -= amount
-	from.Dirty = true
-	if fromAdena.Count <= 0 {
-		from.Inv.RemoveItem(fromAdena.ObjectID, 0)
-		handler.SendRemoveInventoryItem(from.Session, fromAdena.ObjectID)
-	} else {
-		handler.SendItemCountUpdate(from.Session, fromAdena)
-	}
+	reserveInvItem(from, fromAdena, amount)
+	s.giveGold(to, amount)
+}
 
-	// 給目標增加
+// giveGold 給目標玩家增加金幣。
+func (s *PrivateShopSystem) giveGold(to *world.PlayerInfo, amount int32) {
 	info := s.deps.Items.Get(world.AdenaItemID)
 	toAdena := to.Inv.AddItem(world.AdenaItemID, amount, "金幣", 0, 0, true, 0)
 	if info != nil {
@@ -2,7 +2,6 @@ package system
 
 import (
 	"fmt"
-	"math/rand"
 
 	"github.com/l1jgo/server/internal/core/event"
 	"github.com/l1jgo/server/internal/handler"
@@ -92,7 +91,7 @@ func (s *PvPSystem) HandlePvPAttack(attacker, target *world.PlayerInfo) {
 
 	// 反擊屏障（skill 91）：PvP 近戰機率反彈（Java: L1AttackPc.calcCounterBarrierDamage）
 	if damage > 0 && target.HasBuff(91) {
-		if world.RandInt(100)+1 <= 25 {
+		if world.RandInt(s.deps.Rand, 100)+1 <= 25 {
 			cbDmg := s.calcCounterBarrierDmg(target)
 			if cbDmg > 0 {
 				attacker.HP -= int32(cbDmg)
@@ -127,7 +126,7 @@ func (s *PvPSystem) HandlePvPAttack(attacker, target *world.PlayerInfo) {
 
 		// 尖刺盔甲（skill 89）：PvP 近戰命中時 10% 機率破壞攻擊者武器
 		// Java: L1AttackPc.damagePcWeaponDurability — hasSkillEffect(89) → 10% → receiveDamage
-		if target.HasBuff(89) && world.RandInt(100) < 10 {
+		if target.HasBuff(89) && world.RandInt(s.deps.Rand, 100) < 10 {
 			damageWeaponDurability(attacker, s.deps)
 			handler.BroadcastToPlayers(nearby, handler.BuildSkillEffect(target.CharID, 10712))
 		}
@@ -135,7 +134,7 @@ func (s *PvPSystem) HandlePvPAttack(attacker, target *world.PlayerInfo) {
 		// 武器附毒（skill 98）：PvP 近戰命中時 10% 機率對目標施加毒素
 		// Java: L1AttackPc.addPcPoisonAttack — hasSkillEffect(98) → 10% → doInfection(3000, 5)
 		if attacker.HasBuff(98) && attacker.Equip.Weapon() != nil &&
-			target.PoisonType == 0 && world.RandInt(100) < 10 {
+			target.PoisonType == 0 && world.RandInt(s.deps.Rand, 100) < 10 {
 			target.PoisonType = 1
 			target.PoisonTicksLeft = 150 // 30 秒 = 150 ticks
 			target.PoisonDmgTimer = 0
@@ -397,7 +396,7 @@ func (s *PvPSystem) processPKKill(killer, victim *world.PlayerInfo) {
 			handler.SendLawful(other.Session, killer.CharID, killer.Lawful)
 		}
 
-		handler.SendPlayerStatus(killer.Session, killer)
+		handler.SendPlayerStatus(killer.Session, killer, s.deps)
 
 		pkThresh := s.deps.Scripting.GetPKThresholds()
 		if killer.PKCount >= pkThresh.Warning && killer.PKCount < pkThresh.Punish {
@@ -446,7 +445,7 @@ func (s *PvPSystem) dropOneItem(victim *world.PlayerInfo) {
 		return
 	}
 
-	idx := rand.Intn(len(victim.Inv.Items))
+	idx := s.deps.Rand.Intn(len(victim.Inv.Items))
 	item := victim.Inv.Items[idx]
 
 	if item.ItemID == world.AdenaItemID {
@@ -482,9 +481,13 @@ func (s *PvPSystem) dropOneItem(victim *world.PlayerInfo) {
 		Y:          victim.Y,
 		MapID:      victim.MapID,
 	}
+	if !item.Stackable {
+		gndItem.ObjID = item.ObjectID
+	}
 	s.deps.World.AddGroundItem(gndItem)
 
-	victim.Inv.RemoveItem(item.ObjectID, 0)
+	handler.EmitItemMoved(s.deps, event.ItemReasonGroundDrop, item, dropCount, handler.CharLoc(victim.CharID), handler.ItemLocGround, victim)
+	victim.Inv.RemoveItem(item.ObjectID, dropCount)
 	handler.SendRemoveInventoryItem(victim.Session, item.ObjectID)
 	handler.SendWeightUpdate(victim.Session, victim)
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/l1jgo/server/internal/core/event"
 	"github.com/l1jgo/server/internal/handler"
 	"github.com/l1jgo/server/internal/net"
 	"github.com/l1jgo/server/internal/world"
@@ -131,7 +132,8 @@ func (s *QuestSystem) ExecuteQuestAction(sess *net.Session, player *world.Player
 
 	// 傳送
 	if act.TeleportTo != nil {
-		handler.TeleportPlayer(sess, player, act.TeleportTo.X, act.TeleportTo.Y, act.TeleportTo.MapID, 5, s.deps)
+		handler.TeleportPlayerFrom(sess, player, act.TeleportTo.X, act.TeleportTo.Y, act.TeleportTo.MapID, 5,
+			world.TeleportSource{Path: world.TeleportPathQuest, NpcObjID: objID}, s.deps)
 	}
 
 	// 顯示成功對話
@@ -155,6 +157,7 @@ func (s *QuestSystem) removeQuestItem(sess *net.Session, player *world.PlayerInf
 	if item == nil {
 		return
 	}
+	handler.EmitItemMoved(s.deps, event.ItemReasonQuest, item, count, handler.CharLoc(player.CharID), "", player)
 	removed := player.Inv.RemoveItem(item.ObjectID, count)
 	if removed {
 		handler.SendRemoveInventoryItem(sess, item.ObjectID)
@@ -177,6 +180,7 @@ func (s *QuestSystem) giveQuestItem(sess *net.Session, player *world.PlayerInfo,
 		existing := player.Inv.FindByItemID(itemID)
 		if existing != nil {
 			existing.Count += count
+			handler.EmitItemMoved(s.deps, event.ItemReasonQuest, existing, count, "", handler.CharLoc(player.CharID), player)
 			handler.SendItemCountUpdate(sess, existing)
 			player.Dirty = true
 			return
@@ -187,6 +191,7 @@ func (s *QuestSystem) giveQuestItem(sess *net.Session, player *world.PlayerInfo,
 		itemID, count, itemInfo.Name, itemInfo.InvGfx,
 		itemInfo.Weight, itemInfo.Stackable, 0,
 	)
+	handler.EmitItemMoved(s.deps, event.ItemReasonQuest, newItem, count, "", handler.CharLoc(player.CharID), player)
 	handler.SendAddItem(sess, newItem, itemInfo)
 	player.Dirty = true
 }
@@ -0,0 +1,315 @@
+package system
+
+import (
+	"context"
+
+	"github.com/l1jgo/server/internal/core/event"
+	"github.com/l1jgo/server/internal/data"
+	"github.com/l1jgo/server/internal/handler"
+	"github.com/l1jgo/server/internal/persist"
+	"github.com/l1jgo/server/internal/world"
+	"go.uber.org/zap"
+)
+
+// ScriptGameAPI 是 Lua `game` 模組的 Go 端實作（scripting.GameAPI）。
+// 腳本只在遊戲迴圈 goroutine 上被呼叫，因此直接操作 world.State，無需排隊。
+// 所有操作只作用於線上玩家；離線或參數無效時回傳 false / 0，不會拋錯。
+type ScriptGameAPI struct {
+	deps        *handler.Deps
+	questWrites map[questKey]int32 // 寫入中的任務步驟 → 最新步驟（遊戲迴圈專用）
+}
+
+// NewScriptGameAPI 建立腳本 API。
+func NewScriptGameAPI(deps *handler.Deps) *ScriptGameAPI {
+	return &ScriptGameAPI{deps: deps, questWrites: make(map[questKey]int32)}
+}
+
+// SendMessage 送出系統訊息給玩家。
+func (a *ScriptGameAPI) SendMessage(charID int32, text string) bool {
+	player := a.deps.World.GetByCharID(charID)
+	if player == nil {
+		return false
+	}
+	handler.SendSystemMessage(player.Session, text)
+	return true
+}
+
+// GiveItem 給予物品。可堆疊物品合併到同一格，不可堆疊物品逐件佔用欄位。
+// 背包空間不足時不給予任何物品。
+func (a *ScriptGameAPI) GiveItem(charID, itemID, count int32, enchant int8) bool {
+	return a.giveItem(charID, itemID, count, enchant, event.ItemReasonScript, walTargetScript)
+}
+
+// giveItem 是 GiveItem 的實作；reason 與 walTarget 記錄物品來源（腳本或管理 API）。
+func (a *ScriptGameAPI) giveItem(charID, itemID, count int32, enchant int8, reason, walTarget string) bool {
+	player := a.deps.World.GetByCharID(charID)
+	if player == nil {
+		return false
+	}
+	itemInfo := a.deps.Items.Get(itemID)
+	if itemInfo == nil {
+		a.deps.Log.Warn("腳本給予的物品不存在", zap.Int32("itemID", itemID))
+		return false
+	}
+
+	stackable := itemInfo.Stackable || itemID == world.AdenaItemID
+	existing := player.Inv.FindByItemID(itemID)
+	slots := int(count)
+	if stackable {
+		slots = 1
+		if existing != nil {
+			slots = 0
+		}
+	}
+	if len(player.Inv.Items)+slots > world.MaxInventorySize {
+		return false
+	}
+
+	sess := player.Session
+	if stackable {
+		invItem := player.Inv.AddItem(
+			itemID, count, itemInfo.Name, itemInfo.InvGfx,
+			itemInfo.Weight, true, byte(itemInfo.Bless),
+		)
+		if existing != nil {
+			handler.SendItemCountUpdate(sess, invItem)
+		} else {
+			a.initItem(invItem, itemInfo, enchant)
+			handler.SendAddItem(sess, invItem, itemInfo)
+		}
+		handler.EmitItemMoved(a.deps, reason, invItem, count, "", handler.CharLoc(player.CharID), player)
+	} else {
+		for i := int32(0); i < count; i++ {
+			invItem := player.Inv.AddItem(
+				itemID, 1, itemInfo.Name, itemInfo.InvGfx,
+				itemInfo.Weight, false, byte(itemInfo.Bless),
+			)
+			a.initItem(invItem, itemInfo, enchant)
+			handler.EmitItemMoved(a.deps, reason, invItem, 1, "", handler.CharLoc(player.CharID), player)
+			handler.SendAddItem(sess, invItem, itemInfo)
+		}
+	}
+	handler.SendWeightUpdate(sess, player)
+	player.Dirty = true
+	a.writeWAL(player, []persist.WALEntry{{
+		TxType:     persist.WALScript,
+		ToChar:     player.CharID,
+		ItemID:     itemID,
+		Count:      count,
+		EnchantLvl: int16(enchant),
+		Bless:      int16(itemInfo.Bless),
+		Identified: true,
+		Stackable:  stackable,
+		Target:     walTarget,
+	}})
+	return true
+}
+
+// 腳本 / 管理 API 給予物品 WAL 的非角色端。
+const (
+	walTargetScript = "script"
+	walTargetAdmin  = "admin"
+)
+
+// writeWAL 以資料庫工作寫入腳本給予/收回物品的 WAL。腳本 API 同步回傳結果，
+// 物品已先套用，因此寫入失敗只記錄錯誤；等待期間玩家不參與自動存檔，
+// 存檔水位不會越過尚未寫入的條目。
+func (a *ScriptGameAPI) writeWAL(player *world.PlayerInfo, entries []persist.WALEntry) {
+	if a.deps.WALRepo == nil {
+		return
+	}
+	handler.RunDBJob(player.Session, a.deps, "wal_"+persist.WALScript, func(ctx context.Context) error {
+		return a.deps.WALRepo.WriteWAL(ctx, entries)
+	}, func(err error) {
+		if err != nil {
+			a.deps.Log.Error("腳本物品 WAL 寫入失敗", zap.Int32("charID", player.CharID), zap.Error(err))
+		}
+	})
+}
+
+func (a *ScriptGameAPI) initItem(invItem *world.InvItem, itemInfo *data.ItemInfo, enchant int8) {
+	invItem.EnchantLvl = enchant
+	invItem.UseType = itemInfo.UseTypeID
+	if itemInfo.MaxChargeCount > 0 {
+		invItem.ChargeCount = int16(itemInfo.MaxChargeCount)
+	}
+}
+
+// TakeItem 扣除物品。持有數量不足時不做任何變更。
+// 已裝備的物品不會被扣除。
+func (a *ScriptGameAPI) TakeItem(charID, itemID, count int32) bool {
+	player := a.deps.World.GetByCharID(charID)
+	if player == nil {
+		return false
+	}
+	if a.countUnequipped(player, itemID) < count {
+		return false
+	}
+
+	sess := player.Session
+	var wal []persist.WALEntry
+	remaining := count
+	for remaining > 0 {
+		var item *world.InvItem
+		for _, it := range player.Inv.Items {
+			if it.ItemID == itemID && !it.Equipped {
+				item = it
+				break
+			}
+		}
+		if item == nil {
+			break
+		}
+		n := item.Count
+		if n > remaining {
+			n = remaining
+		}
+		e := persist.ItemWAL(persist.WALScript, player.CharID, 0, item, n)
+		e.Target = walTargetScript
+		wal = append(wal, e)
+		handler.EmitItemMoved(a.deps, event.ItemReasonScript, item, n, handler.CharLoc(player.CharID), "", player)
+		if player.Inv.RemoveItem(item.ObjectID, n) {
+			handler.SendRemoveInventoryItem(sess, item.ObjectID)
+		} else {
+			handler.SendItemCountUpdate(sess, item)
+		}
+		remaining -= n
+	}
+	handler.SendWeightUpdate(sess, player)
+	player.Dirty = true
+	a.writeWAL(player, wal)
+	return true
+}
+
+// CountItem 回傳玩家持有的物品總數（含已裝備）。
+func (a *ScriptGameAPI) CountItem(charID, itemID int32) int32 {
+	player := a.deps.World.GetByCharID(charID)
+	if player == nil {
+		return 0
+	}
+	var total int32
+	for _, it := range player.Inv.Items {
+		if it.ItemID == itemID {
+			total += it.Count
+		}
+	}
+	return total
+}
+
+func (a *ScriptGameAPI) countUnequipped(player *world.PlayerInfo, itemID int32) int32 {
+	var total int32
+	for _, it := range player.Inv.Items {
+		if it.ItemID == itemID && !it.Equipped {
+			total += it.Count
+		}
+	}
+	return total
+}
+
+// SpawnNpc 在指定座標生成 NPC（不重生），回傳物件 ID；座標不可通行時回傳 0。
+func (a *ScriptGameAPI) SpawnNpc(npcID, x, y int32, mapID int16) int32 {
+	if a.deps.Npcs == nil {
+		return 0
+	}
+	tmpl := a.deps.Npcs.Get(npcID)
+	if tmpl == nil {
+		a.deps.Log.Warn("腳本生成的 NPC 模板不存在", zap.Int32("npcID", npcID))
+		return 0
+	}
+	if a.deps.MapData != nil && !a.deps.MapData.IsPassablePoint(mapID, x, y) {
+		return 0
+	}
+
+	npc := handler.SpawnTemplateNpc(a.deps, tmpl, x, y, mapID)
+	return npc.ID
+}
+
+// Teleport 傳送玩家；目標地圖不存在、座標不可通行或傳送驗證未通過時拒絕。
+func (a *ScriptGameAPI) Teleport(charID, x, y int32, mapID int16) bool {
+	player := a.deps.World.GetByCharID(charID)
+	if player == nil || player.Dead {
+		return false
+	}
+	if a.deps.MapData != nil {
+		if a.deps.MapData.GetInfo(mapID) == nil || !a.deps.MapData.IsPassablePoint(mapID, x, y) {
+			return false
+		}
+	}
+	return handler.TeleportPlayerFrom(player.Session, player, x, y, mapID, 5,
+		world.TeleportSource{Path: world.TeleportPathScript}, a.deps)
+}
+
+// QuestStep 回傳任務進度（0 = 未開始）。
+func (a *ScriptGameAPI) QuestStep(charID, questID int32) int32 {
+	player := a.deps.World.GetByCharID(charID)
+	if player == nil {
+		return 0
+	}
+	return player.QuestStep(questID)
+}
+
+// SetQuestStep 設定任務進度，並以資料庫工作寫入 DB（不阻塞遊戲迴圈）。
+func (a *ScriptGameAPI) SetQuestStep(charID, questID, step int32) bool {
+	player := a.deps.World.GetByCharID(charID)
+	if player == nil {
+		return false
+	}
+	player.SetQuestStep(questID, step)
+	player.Dirty = true
+
+	if a.deps.QuestRepo != nil {
+		key := questKey{charID: player.CharID, questID: questID}
+		_, inFlight := a.questWrites[key]
+		a.questWrites[key] = step
+		if !inFlight {
+			a.writeQuestStep(player, key, step)
+		}
+	}
+	return true
+}
+
+// questKey 識別一筆寫入中的任務步驟。
+type questKey struct {
+	charID  int32
+	questID int32
+}
+
+// writeQuestStep 寫入任務步驟。同一任務同時只有一筆寫入：寫入期間的新步驟記在
+// questWrites，完成後再寫入最新值，避免多個 worker 讓較舊的步驟後寫入。
+func (a *ScriptGameAPI) writeQuestStep(player *world.PlayerInfo, key questKey, step int32) {
+	handler.RunDBJob(player.Session, a.deps, "script_quest_step", func(ctx context.Context) error {
+		return a.deps.QuestRepo.SetStep(ctx, key.charID, key.questID, step)
+	}, func(err error) {
+		if err != nil {
+			a.deps.Log.Error("腳本任務步驟寫入失敗",
+				zap.Int32("charID", key.charID),
+				zap.Int32("questID", key.questID),
+				zap.Int32("step", step),
+				zap.Error(err),
+			)
+		}
+		if latest := a.questWrites[key]; latest != step {
+			a.writeQuestStep(player, key, latest)
+			return
+		}
+		delete(a.questWrites, key)
+	})
+}
+
+// FindPlayer 依角色名稱查詢線上玩家的角色 ID（0 = 不在線）。
+func (a *ScriptGameAPI) FindPlayer(name string) int32 {
+	player := a.deps.World.GetByName(name)
+	if player == nil {
+		return 0
+	}
+	return player.CharID
+}
+
+// PlayerPos 回傳線上玩家的座標。
+func (a *ScriptGameAPI) PlayerPos(charID int32) (x, y int32, mapID int16, ok bool) {
+	player := a.deps.World.GetByCharID(charID)
+	if player == nil {
+		return 0, 0, 0, false
+	}
+	return player.X, player.Y, player.MapID, true
+}
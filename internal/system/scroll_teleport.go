@@ -10,6 +10,7 @@ import (
 
 // ScrollTeleportSystem 處理卷軸延遲傳送（Phase 1）。
 // 卷軸使用時先發特效，延遲 1 tick 再執行傳送，模擬 Java Thread.sleep(196ms)。
+// 傳送經 TeleportPlayerFrom 以 ScrollTPSrc 驗證；卷軸類來源與 NPC 傳送費用於傳送完成後扣除。
 // 3.80C 客戶端對瞬間移動卷軸（40100）有內建特效，其他卷軸需要伺服器端延遲。
 type ScrollTeleportSystem struct {
 	world *world.State
@@ -28,8 +29,33 @@ func (s *ScrollTeleportSystem) Update(_ time.Duration) {
 			return
 		}
 		p.ScrollTPTick--
-		if p.ScrollTPTick == 0 {
-			handler.TeleportPlayer(p.Session, p, p.ScrollTPX, p.ScrollTPY, p.ScrollTPMap, 5, s.deps)
+		if p.ScrollTPTick != 0 {
+			return
+		}
+		src, fee := p.ScrollTPSrc, p.ScrollTPFee
+		p.ScrollTPSrc = world.TeleportSource{}
+		p.ScrollTPFee = 0
+		// 卷軸在傳送完成時才消耗；等待期間已不在背包（交易、丟棄）則取消
+		var scroll *world.InvItem
+		if src.ItemObjID != 0 {
+			if scroll = p.Inv.FindByObjectID(src.ItemObjID); scroll == nil {
+				return
+			}
+		}
+		// NPC 傳送費用同理：等待期間金幣不足（交易、丟棄）則取消
+		if fee > 0 && p.Inv.GetAdena() < fee {
+			handler.SendServerMessage(p.Session, 189) // "金幣不足"
+			return
+		}
+		if !handler.TeleportPlayerFrom(p.Session, p, p.ScrollTPX, p.ScrollTPY, p.ScrollTPMap, 5, src, s.deps) {
+			return
+		}
+		if scroll != nil {
+			consumePlayerItem(p.Session, p, scroll, 1)
+			handler.SendWeightUpdate(p.Session, p)
+		}
+		if fee > 0 {
+			s.deps.NpcSvc.ConsumeAdena(p.Session, p, fee)
 		}
 	})
 }
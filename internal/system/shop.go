@@ -3,10 +3,12 @@ package system
 import (
 	"fmt"
 
+	"github.com/l1jgo/server/internal/core/event"
 	"github.com/l1jgo/server/internal/data"
 	"github.com/l1jgo/server/internal/handler"
 	"github.com/l1jgo/server/internal/net"
 	"github.com/l1jgo/server/internal/net/packet"
+	"github.com/l1jgo/server/internal/persist"
 	"github.com/l1jgo/server/internal/world"
 )
 
@@ -136,77 +138,98 @@ func (s *ShopSystem) BuyFromNpc(sess *net.Session, r *packet.Reader, count int,
 		return
 	}
 
-	// 扣除金幣（含稅）
-	adenaItem := player.Inv.FindByItemID(world.AdenaItemID)
-	if adenaItem != nil {
-		adenaItem.Count -= int32(totalPayment)
-		if adenaItem.Count <= 0 {
-			player.Inv.RemoveItem(adenaItem.ObjectID, 0)
-			handler.SendRemoveInventoryItem(sess, adenaItem.ObjectID)
-		} else {
-			handler.SendItemCountUpdate(sess, adenaItem)
-		}
+	// 先寫入 WAL：金幣（含稅）玩家 → NPC、物品 NPC → 玩家
+	target := handler.NpcLoc(shop.NpcID)
+	wal := make([]persist.WALEntry, 0, len(resolved)+1)
+	pay := persist.GoldWAL(persist.WALShopBuy, player.CharID, 0, totalPayment)
+	pay.Target = target
+	wal = append(wal, pay)
+	for _, ri := range resolved {
+		wal = append(wal, persist.WALEntry{
+			TxType:     persist.WALShopBuy,
+			ToChar:     player.CharID,
+			ItemID:     ri.itemID,
+			Count:      ri.qty,
+			Bless:      int16(ri.bless),
+			Identified: true,
+			Stackable:  ri.stack,
+			Target:     target,
+		})
 	}
-
-	// 分配稅金到各城堡寶庫（Java: L1Shop.payCastleTax/payDiadTax）
-	if s.deps.Castle != nil && totalTax > 0 {
-		// 城堡稅分配
-		netCastleTax := castleTax - nationalTax // 城堡實得 = 城堡稅 - 國稅
-		// 特殊：阿頓(7) 和 迪亞得(8) 的國稅併入城堡稅
-		if castleID == 7 || castleID == 8 {
-			netCastleTax = castleTax
-			nationalTax = 0
-		}
-		if castleID > 0 && netCastleTax > 0 {
-			s.deps.Castle.AddPublicMoney(castleID, netCastleTax)
-		}
-		// 國稅入阿頓（城堡 #7）
-		if nationalTax > 0 {
-			s.deps.Castle.AddPublicMoney(7, nationalTax)
-		}
-		// 戰爭稅中的迪亞德部分入迪亞得（城堡 #8）
-		if diadTax > 0 {
-			s.deps.Castle.AddPublicMoney(8, diadTax)
-		}
+	// 預扣金幣（含稅），WAL 寫入後才分配稅金、給予物品；失敗時退回
+	var holds []walHold
+	if adenaItem := player.Inv.FindByItemID(world.AdenaItemID); adenaItem != nil && totalPayment > 0 {
+		holds = append(holds, reserveInvItem(player, adenaItem, int32(totalPayment)))
 	}
+	writeWAL(s.deps, []*net.Session{sess}, wal, func(ok bool) {
+		if !ok {
+			releaseHolds(holds)
+			return
+		}
 
-	// 給予物品
-	for _, ri := range resolved {
-		if ri.stack {
-			// 可堆疊：一次加全部（與已有堆疊合併）
-			existing := player.Inv.FindByItemID(ri.itemID)
-			wasExisting := existing != nil
-
-			item := player.Inv.AddItem(ri.itemID, ri.qty, ri.name, ri.invGfx, ri.weight, true, ri.bless)
-			item.UseType = ri.useTypeID
-			if ri.info != nil && ri.info.MaxChargeCount > 0 {
-				item.ChargeCount = int16(ri.info.MaxChargeCount)
+		// 分配稅金到各城堡寶庫（Java: L1Shop.payCastleTax/payDiadTax）
+		if s.deps.Castle != nil && totalTax > 0 {
+			// 城堡稅分配
+			netCastleTax := castleTax - nationalTax // 城堡實得 = 城堡稅 - 國稅
+			// 特殊：阿頓(7) 和 迪亞得(8) 的國稅併入城堡稅
+			if castleID == 7 || castleID == 8 {
+				netCastleTax = castleTax
+				nationalTax = 0
 			}
-
-			if wasExisting {
-				handler.SendItemCountUpdate(sess, item)
-			} else {
-				handler.SendAddItem(sess, item, ri.info)
+			if castleID > 0 && netCastleTax > 0 {
+				s.deps.Castle.AddPublicMoney(castleID, netCastleTax)
 			}
-		} else {
-			// 不可堆疊：每個單位獨立一格
-			for j := int32(0); j < ri.qty; j++ {
-				item := player.Inv.AddItem(ri.itemID, 1, ri.name, ri.invGfx, ri.weight, false, ri.bless)
+			// 國稅入阿頓（城堡 #7）
+			if nationalTax > 0 {
+				s.deps.Castle.AddPublicMoney(7, nationalTax)
+			}
+			// 戰爭稅中的迪亞德部分入迪亞得（城堡 #8）
+			if diadTax > 0 {
+				s.deps.Castle.AddPublicMoney(8, diadTax)
+			}
+		}
+
+		// 給予物品
+		for _, ri := range resolved {
+			if ri.stack {
+				// 可堆疊：一次加全部（與已有堆疊合併）
+				existing := player.Inv.FindByItemID(ri.itemID)
+				wasExisting := existing != nil
+
+				item := player.Inv.AddItem(ri.itemID, ri.qty, ri.name, ri.invGfx, ri.weight, true, ri.bless)
 				item.UseType = ri.useTypeID
 				if ri.info != nil && ri.info.MaxChargeCount > 0 {
 					item.ChargeCount = int16(ri.info.MaxChargeCount)
 				}
-				handler.SendAddItem(sess, item, ri.info)
+
+				handler.EmitItemMoved(s.deps, event.ItemReasonShopBuy, item, ri.qty, handler.NpcLoc(shop.NpcID), handler.CharLoc(player.CharID), player)
+
+				if wasExisting {
+					handler.SendItemCountUpdate(sess, item)
+				} else {
+					handler.SendAddItem(sess, item, ri.info)
+				}
+			} else {
+				// 不可堆疊：每個單位獨立一格
+				for j := int32(0); j < ri.qty; j++ {
+					item := player.Inv.AddItem(ri.itemID, 1, ri.name, ri.invGfx, ri.weight, false, ri.bless)
+					item.UseType = ri.useTypeID
+					if ri.info != nil && ri.info.MaxChargeCount > 0 {
+						item.ChargeCount = int16(ri.info.MaxChargeCount)
+					}
+					handler.EmitItemMoved(s.deps, event.ItemReasonShopBuy, item, 1, handler.NpcLoc(shop.NpcID), handler.CharLoc(player.CharID), player)
+					handler.SendAddItem(sess, item, ri.info)
+				}
 			}
 		}
-	}
-	handler.SendWeightUpdate(sess, player)
+		handler.SendWeightUpdate(sess, player)
 
-	if totalTax > 0 {
-		s.deps.Log.Info(fmt.Sprintf("商店購買完成  角色=%s  花費=%d  稅金=%d  城堡=%d", player.Name, totalPayment, totalTax, castleID))
-	} else {
-		s.deps.Log.Info(fmt.Sprintf("商店購買完成  角色=%s  花費=%d  數量=%d", player.Name, totalCost, len(resolved)))
-	}
+		if totalTax > 0 {
+			s.deps.Log.Info(fmt.Sprintf("商店購買完成  角色=%s  花費=%d  稅金=%d  城堡=%d", player.Name, totalPayment, totalTax, castleID))
+		} else {
+			s.deps.Log.Info(fmt.Sprintf("商店購買完成  角色=%s  花費=%d  數量=%d", player.Name, totalCost, len(resolved)))
+		}
+	})
 }
 
 // SellToNpc 處理玩家向 NPC 販賣物品：移除物品、給金幣、發封包。
@@ -229,7 +252,14 @@ func (s *ShopSystem) SellToNpc(sess *net.Session, r *packet.Reader, count int, p
 		orders = append(orders, sellOrder{objectID: objID, qty: qty})
 	}
 
+	// 先規劃販賣項目，預扣物品後寫入 WAL，成功才給金幣
+	type sellPlan struct {
+		item *world.InvItem
+		qty  int32
+	}
 	var totalEarned int64
+	plans := make([]sellPlan, 0, len(orders))
+	planned := make(map[int32]int32, len(orders)) // objectID → 已規劃數量（同物品重複下單）
 
 	for _, o := range orders {
 		invItem := player.Inv.FindByObjectID(o.objectID)
@@ -252,21 +282,50 @@ func (s *ShopSystem) SellToNpc(sess *net.Session, r *packet.Reader, count int, p
 		}
 
 		sellQty := o.qty
-		if sellQty > invItem.Count {
-			sellQty = invItem.Count
+		if avail := invItem.Count - planned[o.objectID]; sellQty > avail {
+			sellQty = avail
+		}
+		if sellQty <= 0 {
+			continue
 		}
+		planned[o.objectID] += sellQty
 
 		earned := int64(purchPrice) * int64(sellQty)
 		totalEarned += earned
+		plans = append(plans, sellPlan{item: invItem, qty: sellQty})
+	}
 
-		removed := player.Inv.RemoveItem(invItem.ObjectID, sellQty)
-		if removed {
-			handler.SendRemoveInventoryItem(sess, invItem.ObjectID)
-		} else {
-			handler.SendItemCountUpdate(sess, invItem)
-		}
+	target := handler.NpcLoc(shop.NpcID)
+	wal := make([]persist.WALEntry, 0, len(plans)+1)
+	for _, p := range plans {
+		e := persist.ItemWAL(persist.WALShopSell, player.CharID, 0, p.item, p.qty)
+		e.Target = target
+		wal = append(wal, e)
+	}
+	if totalEarned > 0 {
+		e := persist.GoldWAL(persist.WALShopSell, 0, player.CharID, totalEarned)
+		e.Target = target
+		wal = append(wal, e)
 	}
+	holds := make([]walHold, 0, len(plans))
+	for _, p := range plans {
+		holds = append(holds, reserveInvItem(player, p.item, p.qty))
+	}
+	writeWAL(s.deps, []*net.Session{sess}, wal, func(ok bool) {
+		if !ok {
+			releaseHolds(holds)
+			return
+		}
+		for _, p := range plans {
+			handler.EmitItemMoved(s.deps, event.ItemReasonShopSell, p.item, p.qty, handler.CharLoc(player.CharID), handler.NpcLoc(shop.NpcID), player)
+		}
+		s.payNpcSale(sess, player, totalEarned)
+		s.deps.Log.Info(fmt.Sprintf("商店販賣完成  角色=%s  收入=%d  數量=%d", player.Name, totalEarned, count))
+	})
+}
 
+// payNpcSale 給予販賣所得金幣。
+func (s *ShopSystem) payNpcSale(sess *net.Session, player *world.PlayerInfo, totalEarned int64) {
 	if totalEarned > 0 {
 		// 給予金幣
 		adena := player.Inv.FindByItemID(world.AdenaItemID)
@@ -288,6 +347,4 @@ func (s *ShopSystem) SellToNpc(sess *net.Session, r *packet.Reader, count int, p
 		}
 	}
 	handler.SendWeightUpdate(sess, player)
-
-	s.deps.Log.Info(fmt.Sprintf("商店販賣完成  角色=%s  收入=%d  數量=%d", player.Name, totalEarned, count))
 }
@@ -3,9 +3,11 @@ package system
 import (
 	"fmt"
 
+	"github.com/l1jgo/server/internal/core/event"
 	"github.com/l1jgo/server/internal/data"
 	"github.com/l1jgo/server/internal/handler"
 	"github.com/l1jgo/server/internal/net"
+	"github.com/l1jgo/server/internal/persist"
 	"github.com/l1jgo/server/internal/world"
 )
 
@@ -42,17 +44,7 @@ func (s *ShopCnSystem) BuyCnItem(sess *net.Session, player *world.PlayerInfo, cn
 		return
 	}
 
-	// 扣除天寶幣
-	currency.Count -= price
-	player.Dirty = true
-	if currency.Count <= 0 {
-		player.Inv.RemoveItem(currency.ObjectID, 0)
-		handler.SendRemoveInventoryItem(sess, currency.ObjectID)
-	} else {
-		handler.SendItemCountUpdate(sess, currency)
-	}
-
-	// 給予物品
+	// 物品資料
 	itemInfo := s.deps.Items.Get(cnItem.ItemID)
 	itemName := fmt.Sprintf("item#%d", cnItem.ItemID)
 	gfxID := int32(0)
@@ -67,11 +59,38 @@ func (s *ShopCnSystem) BuyCnItem(sess *net.Session, player *world.PlayerInfo, cn
 		bless = byte(itemInfo.Bless)
 	}
 
-	newItem := player.Inv.AddItemWithID(0, cnItem.ItemID, actualCount, itemName, gfxID, weight, stackable, bless)
-	if cnItem.EnchantLevel > 0 {
-		newItem.EnchantLvl = int8(cnItem.EnchantLevel)
+	// 預扣天寶幣，WAL 寫入成功才給予物品：天寶幣 玩家 → 商城、物品 商城 → 玩家
+	payWAL := persist.ItemWAL(persist.WALShopBuy, player.CharID, 0, currency, price)
+	payWAL.Target = handler.ItemLocShopCn
+	wal := []persist.WALEntry{
+		payWAL,
+		{
+			TxType:     persist.WALShopBuy,
+			ToChar:     player.CharID,
+			ItemID:     cnItem.ItemID,
+			Count:      actualCount,
+			EnchantLvl: int16(cnItem.EnchantLevel),
+			Bless:      int16(bless),
+			Identified: true,
+			Stackable:  stackable,
+			Target:     handler.ItemLocShopCn,
+		},
 	}
-	handler.SendAddItem(sess, newItem, itemInfo)
+	holds := []walHold{reserveInvItem(player, currency, price)}
+	writeWAL(s.deps, []*net.Session{sess}, wal, func(ok bool) {
+		if !ok {
+			releaseHolds(holds)
+			return
+		}
+
+		// 給予物品
+		newItem := player.Inv.AddItemWithID(0, cnItem.ItemID, actualCount, itemName, gfxID, weight, stackable, bless)
+		if cnItem.EnchantLevel > 0 {
+			newItem.EnchantLvl = int8(cnItem.EnchantLevel)
+		}
+		handler.EmitItemMoved(s.deps, event.ItemReasonShopBuy, newItem, actualCount, handler.ItemLocShopCn, handler.CharLoc(player.CharID), player)
+		handler.SendAddItem(sess, newItem, itemInfo)
+	})
 }
 
 // SellCnItem 回收物品換天寶幣（移除物品+給幣）。
@@ -88,28 +107,38 @@ func (s *ShopCnSystem) SellCnItem(sess *net.Session, player *world.PlayerInfo, i
 	}
 	price := int32(totalPrice)
 
-	// 移除物品
-	if item.Stackable && item.Count > sellCount {
-		item.Count -= sellCount
-		player.Dirty = true
-		handler.SendItemCountUpdate(sess, item)
-	} else {
-		player.Inv.RemoveItem(item.ObjectID, sellCount)
-		player.Dirty = true
-		handler.SendRemoveInventoryItem(sess, item.ObjectID)
+	// 預扣物品，WAL 寫入成功才給予天寶幣：物品 玩家 → 商城、天寶幣 商城 → 玩家
+	coinWAL := persist.WALEntry{
+		TxType:     persist.WALShopSell,
+		ToChar:     player.CharID,
+		ItemID:     handler.CnCurrencyItemID,
+		Count:      price,
+		Identified: true,
+		Stackable:  true,
+		Target:     handler.ItemLocShopCn,
 	}
+	itemWAL := persist.ItemWAL(persist.WALShopSell, player.CharID, 0, item, sellCount)
+	itemWAL.Target = handler.ItemLocShopCn
+	holds := []walHold{reserveInvItem(player, item, sellCount)}
+	writeWAL(s.deps, []*net.Session{sess}, []persist.WALEntry{itemWAL, coinWAL}, func(ok bool) {
+		if !ok {
+			releaseHolds(holds)
+			return
+		}
+		handler.EmitItemMoved(s.deps, event.ItemReasonShopSell, item, sellCount, handler.CharLoc(player.CharID), handler.ItemLocShopCn, player)
 
-	// 給予天寶幣
-	currencyInfo := s.deps.Items.Get(handler.CnCurrencyItemID)
-	currencyName := "天寶"
-	gfxID := int32(0)
-	weight := int32(0)
-	if currencyInfo != nil {
-		currencyName = currencyInfo.Name
-		gfxID = currencyInfo.InvGfx
-		weight = currencyInfo.Weight
-	}
+		// 給予天寶幣
+		currencyInfo := s.deps.Items.Get(handler.CnCurrencyItemID)
+		currencyName := "天寶"
+		gfxID := int32(0)
+		weight := int32(0)
+		if currencyInfo != nil {
+			currencyName = currencyInfo.Name
+			gfxID = currencyInfo.InvGfx
+			weight = currencyInfo.Weight
+		}
 
-	coinItem := player.Inv.AddItem(handler.CnCurrencyItemID, price, currencyName, gfxID, weight, true, 0)
-	handler.SendAddItem(sess, coinItem, currencyInfo)
+		coinItem := player.Inv.AddItem(handler.CnCurrencyItemID, price, currencyName, gfxID, weight, true, 0)
+		handler.SendAddItem(sess, coinItem, currencyInfo)
+	})
 }
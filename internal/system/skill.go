@@ -62,7 +62,14 @@ func NewSkillSystem(deps *handler.Deps) *SkillSystem {
 func (s *SkillSystem) Phase() coresys.Phase { return coresys.PhaseUpdate }
 
 // QueueSkill implements handler.SkillManager.
+// 入列前依封包到達時間檢查施法速度，過快的施法直接丟棄。
 func (s *SkillSystem) QueueSkill(req handler.SkillRequest) {
+	if s.deps.AntiCheat != nil {
+		if p := s.deps.World.GetBySession(req.SessionID); p != nil &&
+			s.deps.AntiCheat.CheckCast(p, s.deps.Skills.Get(req.SkillID)) != handler.CheatAllow {
+			return
+		}
+	}
 	s.requests = append(s.requests, req)
 }
 
@@ -102,7 +109,7 @@ func (s *SkillSystem) ClearAllBuffsOnDeath(target *world.PlayerInfo) {
 			s.sendBraveToAll(target, 0, 0)
 		}
 	}
-	handler.SendPlayerStatus(target.Session, target)
+	handler.SendPlayerStatus(target.Session, target, s.deps)
 }
 
 // RemoveBuffAndRevert implements handler.SkillManager.
@@ -174,7 +181,7 @@ func (s *SkillSystem) processSkill(sessID uint64, skillID, targetID int32) {
 	}
 
 	// 全域施法冷卻
-	now := time.Now()
+	now := s.deps.Clock.Now()
 	if now.Before(player.SkillDelayUntil) {
 		return
 	}
@@ -334,7 +341,7 @@ func (s *SkillSystem) consumeSkillResources(sess *net.Session, player *world.Pla
 	if delay <= 0 {
 		delay = 1000
 	}
-	player.SkillDelayUntil = time.Now().Add(time.Duration(delay) * time.Millisecond)
+	player.SkillDelayUntil = s.deps.Clock.Now().Add(time.Duration(delay) * time.Millisecond)
 }
 
 // ========================================================================
@@ -447,7 +454,7 @@ func (s *SkillSystem) resurrectPlayer(target *world.PlayerInfo, caster *world.Pl
 
 	sendHpUpdate(target.Session, target)
 	sendMpUpdate(target.Session, target)
-	handler.SendPlayerStatus(target.Session, target)
+	handler.SendPlayerStatus(target.Session, target, s.deps)
 	handler.SendPutObject(target.Session, target)
 
 	nearbyTarget := s.deps.World.GetNearbyPlayersAt(target.X, target.Y, target.MapID)
@@ -740,7 +747,7 @@ func (s *SkillSystem) executeAttackSkill(sess *net.Session, player *world.Player
 				continue
 			}
 			if s.checkNpcMRResist(player, t.npc, 192) {
-				dur := world.RandInt(4) + 1 // 1-4 秒
+				dur := world.RandInt(s.deps.Rand, 4) + 1 // 1-4 秒
 				t.npc.Paralyzed = true
 				t.npc.AddDebuff(192, dur*5)
 			}
@@ -754,7 +761,7 @@ func (s *SkillSystem) executeAttackSkill(sess *net.Session, player *world.Player
 				continue
 			}
 			if s.checkNpcMRResist(player, t.npc, 208) {
-				dur := world.RandInt(2) + 1 // 1-2 秒
+				dur := world.RandInt(s.deps.Rand, 2) + 1 // 1-2 秒
 				t.npc.Paralyzed = true
 				t.npc.AddDebuff(208, dur*5)
 			}
@@ -812,9 +819,9 @@ func (s *SkillSystem) executeTurnUndead(sess *net.Session, player *world.PlayerI
 	}
 	probability := 0
 	for i := 0; i < diceCount; i++ {
-		probability += world.RandInt(7) + 1 // 1~7
+		probability += world.RandInt(s.deps.Rand, 7) + 1 // 1~7
 	}
-	rnd := world.RandInt(100) + 1 // 1~100
+	rnd := world.RandInt(s.deps.Rand, 100) + 1 // 1~100
 	if probability < rnd {
 		// 失敗
 		handler.SendServerMessage(sess, skillMsgCastFail)
@@ -975,7 +982,7 @@ func (s *SkillSystem) executeBuffSkill(sess *net.Session, player *world.PlayerIn
 		handler.SendCurseBlind(target.Session, 0)
 
 	case 39: // 魔力奪取
-		drain := int32(5 + world.RandInt(10))
+		drain := int32(5 + world.RandInt(s.deps.Rand, 10))
 		if target.MP >= drain {
 			target.MP -= drain
 			player.MP += drain
@@ -1052,7 +1059,7 @@ func (s *SkillSystem) executeBuffSkill(sess *net.Session, player *world.PlayerIn
 			if skill.CastGfx > 0 {
 				handler.BroadcastToPlayers(nearby, handler.BuildSkillEffect(target.CharID, skill.CastGfx))
 			}
-			handler.SendPlayerStatus(target.Session, target)
+			handler.SendPlayerStatus(target.Session, target, s.deps)
 			return
 		}
 		// 首次施放覺醒 → 走正常流程（exclusions 會先清除其他覺醒）
@@ -1066,7 +1073,7 @@ func (s *SkillSystem) executeBuffSkill(sess *net.Session, player *world.PlayerIn
 				s.applyBuffEffect(target, sk169)
 			}
 		}
-		handler.SendPlayerStatus(target.Session, target)
+		handler.SendPlayerStatus(target.Session, target, s.deps)
 		if skill.SysMsgHappen > 0 {
 			handler.SendServerMessage(target.Session, uint16(skill.SysMsgHappen))
 		}
@@ -1276,7 +1283,7 @@ func (s *SkillSystem) executeNpcDebuffSkill(sess *net.Session, player *world.Pla
 			handler.SendServerMessage(sess, skillMsgCastFail)
 			return
 		}
-		dur := 1 + world.RandInt(6)
+		dur := 1 + world.RandInt(s.deps.Rand, 6)
 		npc.Paralyzed = true
 		npc.AddDebuff(87, dur*5)
 		if skill.CastGfx > 0 {
@@ -1289,7 +1296,7 @@ func (s *SkillSystem) executeNpcDebuffSkill(sess *net.Session, player *world.Pla
 			handler.SendServerMessage(sess, skillMsgCastFail)
 			return
 		}
-		dur := 1 + world.RandInt(12)
+		dur := 1 + world.RandInt(s.deps.Rand, 12)
 		npc.Paralyzed = true
 		npc.AddDebuff(157, dur*5)
 		handler.BroadcastToPlayers(nearby, handler.BuildPoison(npc.ID, 2))
@@ -1497,7 +1504,7 @@ func (s *SkillSystem) checkNpcMRResist(caster *world.PlayerInfo, npc *world.NpcI
 	if prob > 95 {
 		prob = 95
 	}
-	return world.RandInt(100) < prob
+	return world.RandInt(s.deps.Rand, 100) < prob
 }
 
 // playerDebuffSkills 需要對玩家目標進行 MR 抗性判定的 debuff 技能。
@@ -1531,7 +1538,7 @@ func (s *SkillSystem) checkPlayerMRResist(caster, target *world.PlayerInfo) bool
 	if prob > 90 {
 		prob = 90
 	}
-	return world.RandInt(100) < prob
+	return world.RandInt(s.deps.Rand, 100) < prob
 }
 
 // calcArmorBreakProb 破壞盔甲對玩家目標的機率判定。
@@ -1562,7 +1569,7 @@ func (s *SkillSystem) calcArmorBreakProb(caster, target *world.PlayerInfo) bool
 	if prob < 1 {
 		prob = 1
 	}
-	return world.RandInt(100) < prob
+	return world.RandInt(s.deps.Rand, 100) < prob
 }
 
 // calcArmorBreakProbNpc 破壞盔甲對 NPC 目標的機率判定。
@@ -1590,7 +1597,7 @@ func (s *SkillSystem) calcArmorBreakProbNpc(caster *world.PlayerInfo, npc *world
 	if prob < 1 {
 		prob = 1
 	}
-	return world.RandInt(100) < prob
+	return world.RandInt(s.deps.Rand, 100) < prob
 }
 
 // ========================================================================
@@ -1699,7 +1706,7 @@ func (s *SkillSystem) executeSelfSkill(sess *net.Session, player *world.PlayerIn
 		if skill.CastGfx > 0 {
 			handler.BroadcastToPlayers(nearby, handler.BuildSkillEffect(player.CharID, skill.CastGfx))
 		}
-		handler.SendPlayerStatus(player.Session, player)
+		handler.SendPlayerStatus(player.Session, player, s.deps)
 		if skill.SysMsgHappen > 0 {
 			handler.SendServerMessage(sess, uint16(skill.SysMsgHappen))
 		}
@@ -2020,7 +2027,7 @@ func (s *SkillSystem) executeBringStone(sess *net.Session, player *world.PlayerI
 	handler.SendWeightUpdate(sess, player)
 
 	// 擲骰判定（Java: random.nextInt(100)+1，即 1~100）
-	if world.RandInt(100)+1 <= rate {
+	if world.RandInt(s.deps.Rand, 100)+1 <= rate {
 		// 成功：新增升級石到背包
 		resultInfo := s.deps.Items.Get(resultID)
 		if resultInfo == nil {
@@ -2069,6 +2076,7 @@ func (s *SkillSystem) executeTeleportSpell(sess *net.Session, player *world.Play
 	var destX, destY int32
 	var destMapID int16
 	var destHeading int16 = 5
+	src := world.TeleportSource{Path: world.TeleportPathRandom}
 
 	if bookmarkID != 0 {
 		// --- 書籤傳送 ---
@@ -2094,6 +2102,7 @@ func (s *SkillSystem) executeTeleportSpell(sess *net.Session, player *world.Play
 		destX = found.X
 		destY = found.Y
 		destMapID = found.MapID
+		src = world.TeleportSource{Path: world.TeleportPathBookmark, BookmarkID: bookmarkID}
 	} else {
 		// --- 隨機傳送 ---
 		if s.deps.MapData != nil {
@@ -2133,8 +2142,8 @@ func (s *SkillSystem) executeTeleportSpell(sess *net.Session, player *world.Play
 		diffY := maxRY - minRY
 		if diffX > 0 && diffY > 0 {
 			for attempt := 0; attempt < 40; attempt++ {
-				rx := minRX + int32(world.RandInt(int(diffX)+1))
-				ry := minRY + int32(world.RandInt(int(diffY)+1))
+				rx := minRX + int32(world.RandInt(s.deps.Rand, int(diffX)+1))
+				ry := minRY + int32(world.RandInt(s.deps.Rand, int(diffY)+1))
 				if s.deps.MapData != nil && s.deps.MapData.IsInMap(destMapID, rx, ry) &&
 					s.deps.MapData.IsPassablePoint(destMapID, rx, ry) {
 					destX = rx
@@ -2177,12 +2186,15 @@ func (s *SkillSystem) executeTeleportSpell(sess *net.Session, player *world.Play
 		}
 	}
 
-	handler.TeleportPlayer(sess, player, destX, destY, destMapID, destHeading, s.deps)
+	if !handler.TeleportPlayerFrom(sess, player, destX, destY, destMapID, destHeading, src, s.deps) {
+		return
+	}
 
-	// --- 集體傳送(69)：傳送血盟成員到相同目的地 ---
+	// --- 集體傳送(69)：傳送血盟成員到相同目的地（書籤屬於施法者，成員只驗證目的地與來源地圖）---
+	src.BookmarkID = 0
 	for _, member := range clanMembers {
 		handler.CancelTradeIfActive(member, s.deps)
-		handler.TeleportPlayer(member.Session, member, destX, destY, destMapID, destHeading, s.deps)
+		handler.TeleportPlayerFrom(member.Session, member, destX, destY, destMapID, destHeading, src, s.deps)
 	}
 }
 
@@ -2365,7 +2377,7 @@ func (s *SkillSystem) applyBuffEffect(target *world.PlayerInfo, skill *data.Skil
 		buff.DeltaWis != 0 || buff.DeltaIntel != 0 || buff.DeltaCha != 0 ||
 		buff.DeltaMaxHP != 0 || buff.DeltaMaxMP != 0 || buff.DeltaAC != 0 ||
 		buff.DeltaDmgMod != 0 || buff.DeltaHitMod != 0 {
-		handler.SendPlayerStatus(target.Session, target)
+		handler.SendPlayerStatus(target.Session, target, s.deps)
 	}
 
 	s.sendBuffIcon(target, skill.SkillID, uint16(skill.BuffDuration))
@@ -2436,7 +2448,7 @@ func (s *SkillSystem) ApplyGMBuff(player *world.PlayerInfo, skillID int32) bool
 		dur = 300 // 預設 5 分鐘
 	}
 	s.sendBuffIcon(player, skillID, dur)
-	handler.SendPlayerStatus(player.Session, player)
+	handler.SendPlayerStatus(player.Session, player, s.deps)
 	// 負重強化：套用時更新負重
 	if skillID == 14 || skillID == 218 {
 		handler.SendWeightUpdate(player.Session, player)
@@ -2726,7 +2738,7 @@ func (s *SkillSystem) cancelAllBuffs(target *world.PlayerInfo) {
 		target.Paralyzed = true
 	}
 
-	handler.SendPlayerStatus(target.Session, target)
+	handler.SendPlayerStatus(target.Session, target, s.deps)
 }
 
 // ========================================================================
@@ -2816,7 +2828,7 @@ func (s *SkillSystem) tickPlayerBuffs(p *world.PlayerInfo) {
 				}
 			}
 
-			handler.SendPlayerStatus(p.Session, p)
+			handler.SendPlayerStatus(p.Session, p, s.deps)
 		} else if buff.SetParalyzed && buff.TicksLeft%25 == 0 {
 			// 3.80C 客戶端灰色色調會自動淡出，每 5 秒重發維持視覺
 			switch skillID {
@@ -234,8 +234,8 @@ func (s *SummonSystem) ExecuteSummonMonster(sess *net.Session, player *world.Pla
 			Lawful:      tmpl.Lawful,
 			Size:        tmpl.Size,
 			PetCost:     petCost,
-			X:           player.X + int32(world.RandInt(5)) - 2,
-			Y:           player.Y + int32(world.RandInt(5)) - 2,
+			X:           player.X + int32(world.RandInt(s.deps.Rand, 5)) - 2,
+			Y:           player.Y + int32(world.RandInt(s.deps.Rand, 5)) - 2,
 			MapID:       player.MapID,
 			Heading:     player.Heading,
 			Status:      world.SummonAggressive,
@@ -84,7 +84,7 @@ func (s *StatAllocSystem) AllocStat(sess *net.Session, player *world.PlayerInfo,
 
 	s.deps.Log.Info(fmt.Sprintf("配點完成  角色=%s  屬性=%s  已用配點=%d", player.Name, statName, player.BonusStats))
 
-	handler.SendPlayerStatus(sess, player)
+	handler.SendPlayerStatus(sess, player, s.deps)
 	handler.SendAbilityScores(sess, player)
 
 	// 若還有剩餘配點，再次顯示配點對話框
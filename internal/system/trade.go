@@ -1,9 +1,9 @@
 package system
 
 import (
-	"context"
 	"fmt"
 
+	"github.com/l1jgo/server/internal/core/event"
 	"github.com/l1jgo/server/internal/handler"
 	"github.com/l1jgo/server/internal/net"
 	"github.com/l1jgo/server/internal/persist"
@@ -254,84 +254,60 @@ func (s *TradeSystem) CancelIfActive(player *world.PlayerInfo) {
 
 // executeTrade 執行物品+金幣交換。物品已在 AddItem 時從來源扣除。
 func (s *TradeSystem) executeTrade(p1, p2 *world.PlayerInfo) {
-	// 建構 WAL 條目
+	// 建構 WAL 條目（交易物品為來源物品的副本，ObjectID 即來源物件）
 	var walEntries []persist.WALEntry
 
 	for _, item := range p1.TradeItems {
-		walEntries = append(walEntries, persist.WALEntry{
-			TxType:     "trade",
-			FromChar:   p1.CharID,
-			ToChar:     p2.CharID,
-			ItemID:     item.ItemID,
-			Count:      item.Count,
-			EnchantLvl: int16(item.EnchantLvl),
-		})
+		walEntries = append(walEntries, persist.ItemWAL(persist.WALTrade, p1.CharID, p2.CharID, item, item.Count))
 	}
-
 	for _, item := range p2.TradeItems {
-		walEntries = append(walEntries, persist.WALEntry{
-			TxType:     "trade",
-			FromChar:   p2.CharID,
-			ToChar:     p1.CharID,
-			ItemID:     item.ItemID,
-			Count:      item.Count,
-			EnchantLvl: int16(item.EnchantLvl),
-		})
+		walEntries = append(walEntries, persist.ItemWAL(persist.WALTrade, p2.CharID, p1.CharID, item, item.Count))
 	}
-
 	if p1.TradeGold > 0 {
-		walEntries = append(walEntries, persist.WALEntry{
-			TxType:     "trade",
-			FromChar:   p1.CharID,
-			ToChar:     p2.CharID,
-			ItemID:     world.AdenaItemID,
-			GoldAmount: int64(p1.TradeGold),
-		})
+		walEntries = append(walEntries, persist.GoldWAL(persist.WALTrade, p1.CharID, p2.CharID, int64(p1.TradeGold)))
 	}
 	if p2.TradeGold > 0 {
-		walEntries = append(walEntries, persist.WALEntry{
-			TxType:     "trade",
-			FromChar:   p2.CharID,
-			ToChar:     p1.CharID,
-			ItemID:     world.AdenaItemID,
-			GoldAmount: int64(p2.TradeGold),
-		})
-	}
-
-	// 先寫入 WAL（安全保障）
-	if len(walEntries) > 0 && s.deps.WALRepo != nil {
-		ctx := context.Background()
-		if err := s.deps.WALRepo.WriteWAL(ctx, walEntries); err != nil {
-			s.deps.Log.Error("交易 WAL 寫入失敗，取消交易", zap.Error(err))
-			s.cancelTrade(p1, p2)
-			return
-		}
+		walEntries = append(walEntries, persist.GoldWAL(persist.WALTrade, p2.CharID, p1.CharID, int64(p2.TradeGold)))
 	}
 
-	// WAL 成功 — 物品已從來源扣除，現在加入接收方
+	// 交易物品與金幣已在放入時從來源預扣。先結束交易狀態（等待 WAL 期間 CancelIfActive 不會重複退回），
+	// WAL 寫入成功才交付給對方，失敗則退回原主
+	items1, gold1 := p1.TradeItems, p1.TradeGold
+	items2, gold2 := p2.TradeItems, p2.TradeGold
+	clearTradeState(p1)
+	clearTradeState(p2)
 
-	for _, item := range p1.TradeItems {
-		s.addTradeItemToPlayer(p2, item)
-	}
-	for _, item := range p2.TradeItems {
-		s.addTradeItemToPlayer(p1, item)
-	}
+	writeWAL(s.deps, []*net.Session{p1.Session, p2.Session}, walEntries, func(ok bool) {
+		if !ok {
+			s.restoreTradeItems(p1, items1, gold1)
+			s.restoreTradeItems(p2, items2, gold2)
+			sendTradeStatus(p1.Session, 1)
+			sendTradeStatus(p2.Session, 1)
+			return
+		}
 
-	if p1.TradeGold > 0 {
-		s.addGoldToPlayer(p2, p1.TradeGold)
-	}
-	if p2.TradeGold > 0 {
-		s.addGoldToPlayer(p1, p2.TradeGold)
-	}
+		for _, item := range items1 {
+			handler.EmitItemMoved(s.deps, event.ItemReasonTrade, item, item.Count, handler.CharLoc(p1.CharID), handler.CharLoc(p2.CharID), p1)
+			s.addTradeItemToPlayer(p2, item)
+		}
+		for _, item := range items2 {
+			handler.EmitItemMoved(s.deps, event.ItemReasonTrade, item, item.Count, handler.CharLoc(p2.CharID), handler.CharLoc(p1.CharID), p2)
+			s.addTradeItemToPlayer(p1, item)
+		}
 
-	// 關閉交易視窗（0 = 交易完成）
-	sendTradeStatus(p1.Session, 0)
-	sendTradeStatus(p2.Session, 0)
+		if gold1 > 0 {
+			s.addGoldToPlayer(p2, gold1)
+		}
+		if gold2 > 0 {
+			s.addGoldToPlayer(p1, gold2)
+		}
 
-	clearTradeState(p1)
-	clearTradeState(p2)
+		// 關閉交易視窗（0 = 交易完成）
+		sendTradeStatus(p1.Session, 0)
+		sendTradeStatus(p2.Session, 0)
 
-	s.deps.Log.Info(fmt.Sprintf("交易完成  玩家1=%s  玩家2=%s", p1.Name, p2.Name))
+		s.deps.Log.Info(fmt.Sprintf("交易完成  玩家1=%s  玩家2=%s", p1.Name, p2.Name))
+	})
 }
 
 // addTradeItemToPlayer 將交易物品加入接收方背包。
@@ -351,7 +327,8 @@ func (s *TradeSystem) addTradeItemToPlayer(receiver *world.PlayerInfo, item *wor
 	existing := receiver.Inv.FindByItemID(item.ItemID)
 	wasExisting := existing != nil && stackable
 
-	newItem := receiver.Inv.AddItem(item.ItemID, item.Count, name, invGfx, weight, stackable, item.Bless)
+	// 非堆疊物品沿用來源 ObjectID（與 WAL 重播整列轉移一致，item_events 歷史連續）
+	newItem := receiver.Inv.AddItemWithID(tradeObjID(item, stackable), item.ItemID, item.Count, name, invGfx, weight, stackable, item.Bless)
 	newItem.EnchantLvl = item.EnchantLvl
 	if itemInfo != nil {
 		newItem.UseType = itemInfo.UseTypeID
@@ -379,14 +356,14 @@ func (s *TradeSystem) addGoldToPlayer(receiver *world.PlayerInfo, amount int32)
 
 // cancelTrade 取消交易，歸還物品，清除狀態。
 func (s *TradeSystem) cancelTrade(p1 *world.PlayerInfo, p2 *world.PlayerInfo) {
-	s.restoreTradeItems(p1)
+	s.restoreTradeItems(p1, p1.TradeItems, p1.TradeGold)
 	if p1.TradeWindowOpen {
 		sendTradeStatus(p1.Session, 1)
 	}
 	clearTradeState(p1)
 
 	if p2 != nil {
-		s.restoreTradeItems(p2)
+		s.restoreTradeItems(p2, p2.TradeItems, p2.TradeGold)
 		if p2.TradeWindowOpen {
 			sendTradeStatus(p2.Session, 1)
 		}
@@ -397,8 +374,8 @@ func (s *TradeSystem) cancelTrade(p1 *world.PlayerInfo, p2 *world.PlayerInfo) {
 }
 
 // restoreTradeItems 歸還已扣除的交易物品和金幣回玩家背包。
-func (s *TradeSystem) restoreTradeItems(p *world.PlayerInfo) {
-	for _, item := range p.TradeItems {
+func (s *TradeSystem) restoreTradeItems(p *world.PlayerInfo, items []*world.InvItem, gold int32) {
+	for _, item := range items {
 		itemInfo := s.deps.Items.Get(item.ItemID)
 		stackable := false
 		name := item.Name
@@ -414,7 +391,7 @@ func (s *TradeSystem) restoreTradeItems(p *world.PlayerInfo) {
 		existing := p.Inv.FindByItemID(item.ItemID)
 		wasExisting := existing != nil && stackable
 
-		newItem := p.Inv.AddItem(item.ItemID, item.Count, name, invGfx, weight, stackable, item.Bless)
+		newItem := p.Inv.AddItemWithID(tradeObjID(item, stackable), item.ItemID, item.Count, name, invGfx, weight, stackable, item.Bless)
 		newItem.EnchantLvl = item.EnchantLvl
 		if itemInfo != nil {
 			newItem.UseType = itemInfo.UseTypeID
@@ -426,19 +403,28 @@ func (s *TradeSystem) restoreTradeItems(p *world.PlayerInfo) {
 		}
 	}
 
-	if p.TradeGold > 0 {
+	if gold > 0 {
 		adena := p.Inv.FindByItemID(world.AdenaItemID)
 		if adena != nil {
-			adena.Count += p.TradeGold
+			adena.Count += gold
 			handler.SendItemCountUpdate(p.Session, adena)
 		} else {
-			newItem := p.Inv.AddItem(world.AdenaItemID, p.TradeGold, "金幣", 0, 0, true, 1)
+			newItem := p.Inv.AddItem(world.AdenaItemID, gold, "金幣", 0, 0, true, 1)
 			handler.SendAddItem(p.Session, newItem)
 		}
 	}
 	handler.SendWeightUpdate(p.Session, p)
 }
 
+// tradeObjID 回傳交易物品加入背包時使用的 ObjectID：非堆疊物品沿用來源（來源已整件移出背包），
+// 堆疊物品為 0（合併或配發新 ID）。
+func tradeObjID(item *world.InvItem, stackable bool) int32 {
+	if stackable {
+		return 0
+	}
+	return item.ObjectID
+}
+
 // clearTradeState 重置所有交易相關欄位。
 func clearTradeState(p *world.PlayerInfo) {
 	p.TradePartnerID = 0
@@ -1,9 +1,9 @@
 package system
 
 import (
-	"math/rand"
 	"time"
 
+	"github.com/l1jgo/server/internal/core/clock"
 	coresys "github.com/l1jgo/server/internal/core/system"
 	"github.com/l1jgo/server/internal/handler"
 	"github.com/l1jgo/server/internal/net"
@@ -65,7 +65,7 @@ func (s *TrapSystem) TriggerTraps(sess *net.Session, player *world.PlayerInfo, t
 		}
 
 		// 停用陷阱 + 排入重生佇列
-		s.deps.TrapMgr.DisableTrap(trap)
+		s.deps.TrapMgr.DisableTrap(trap, s.deps.Clock.Now())
 
 		s.deps.Log.Debug("陷阱觸發",
 			zap.String("player", player.Name),
@@ -104,7 +104,7 @@ func (s *TrapSystem) trapDamage(sess *net.Session, player *world.PlayerInfo, tra
 	dmg := tpl.Base
 	for i := int32(0); i < tpl.DiceCount; i++ {
 		if tpl.Dice > 0 {
-			dmg += rand.Int31n(tpl.Dice) + 1
+			dmg += s.deps.Rand.Int31n(tpl.Dice) + 1
 		}
 	}
 	player.HP -= dmg
@@ -125,7 +125,7 @@ func (s *TrapSystem) trapHeal(sess *net.Session, player *world.PlayerInfo, trap
 	heal := tpl.Base
 	for i := int32(0); i < tpl.DiceCount; i++ {
 		if tpl.Dice > 0 {
-			heal += rand.Int31n(tpl.Dice) + 1
+			heal += s.deps.Rand.Int31n(tpl.Dice) + 1
 		}
 	}
 	player.HP += heal
@@ -190,11 +190,12 @@ func (s *TrapSystem) trapTeleport(sess *net.Session, player *world.PlayerInfo, t
 // Go: 每 tick 檢查到期的重生佇列，由 TrapManager.ProcessRespawns() 處理。
 type TrapRespawnSystem struct {
 	trapMgr *world.TrapManager
+	clock   clock.Clock
 }
 
 // NewTrapRespawnSystem 建立 TrapRespawnSystem。
-func NewTrapRespawnSystem(trapMgr *world.TrapManager) *TrapRespawnSystem {
-	return &TrapRespawnSystem{trapMgr: trapMgr}
+func NewTrapRespawnSystem(trapMgr *world.TrapManager, clk clock.Clock) *TrapRespawnSystem {
+	return &TrapRespawnSystem{trapMgr: trapMgr, clock: clk}
 }
 
 // Phase 回傳系統執行階段。
@@ -202,5 +203,5 @@ func (s *TrapRespawnSystem) Phase() coresys.Phase { return coresys.PhasePostUpda
 
 // Update 每 tick 處理到期的陷阱重生。
 func (s *TrapRespawnSystem) Update(_ time.Duration) {
-	s.trapMgr.ProcessRespawns(time.Now())
+	s.trapMgr.ProcessRespawns(s.clock.Now())
 }
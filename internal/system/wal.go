@@ -0,0 +1,62 @@
+package system
+
+import (
+	"context"
+
+	"github.com/l1jgo/server/internal/handler"
+	"github.com/l1jgo/server/internal/net"
+	"github.com/l1jgo/server/internal/persist"
+	"github.com/l1jgo/server/internal/world"
+	"go.uber.org/zap"
+)
+
+// writeWAL 以資料庫工作寫入 WAL，用於只存在記憶體的轉移（交易、個人商店、NPC 商店）。
+// 呼叫端先從來源背包預扣（reserveInvItem），done 於遊戲迴圈 Phase 1 收到結果：
+// ok 為 true 才交付給接收方，false 時退回預扣並取消轉移。等待期間 sessions 的輸入暫停、
+// 不參與自動存檔（jobPending），斷線清理也延到 done 之後。未設定 WALRepo 時直接以 true 呼叫 done。
+// 倉庫、拍賣、信件的 WAL 則與各自的 DB 寫入同一交易提交（persist 各 repo 的 wal 參數）。
+// 非同步模式（wal_sync_mode = "async"）下工作只排入背景寫入佇列，不等待資料庫。
+func writeWAL(deps *handler.Deps, sessions []*net.Session, entries []persist.WALEntry, done func(ok bool)) {
+	if len(entries) == 0 || deps.WALRepo == nil {
+		done(true)
+		return
+	}
+	handler.RunSharedDBJob(sessions, deps, "wal_"+entries[0].TxType, func(ctx context.Context) error {
+		return deps.WALRepo.WriteWAL(ctx, entries)
+	}, func(err error) {
+		if err != nil {
+			deps.Log.Error("WAL 寫入失敗，取消轉移", zap.String("tx_type", entries[0].TxType), zap.Error(err))
+		}
+		done(err == nil)
+	})
+}
+
+// walHold 是一筆已從來源背包預扣、等待 WAL 寫入的物品（含金幣）。
+type walHold struct {
+	player      *world.PlayerInfo
+	item        *world.InvItem
+	qty         int32
+	slotRemoved bool
+}
+
+// reserveInvItem 從 player 背包預扣 qty 個 item 並通知客戶端。WAL 寫入失敗時以 releaseHolds 退回。
+func reserveInvItem(player *world.PlayerInfo, item *world.InvItem, qty int32) walHold {
+	h := walHold{player: player, item: item, qty: qty}
+	h.slotRemoved = player.Inv.RemoveItem(item.ObjectID, qty)
+	player.Dirty = true
+	if h.slotRemoved {
+		handler.SendRemoveInventoryItem(player.Session, item.ObjectID)
+	} else {
+		handler.SendItemCountUpdate(player.Session, item)
+	}
+	return h
+}
+
+// releaseHolds 依預扣的相反順序退回物品。
+func releaseHolds(holds []walHold) {
+	for i := len(holds) - 1; i >= 0; i-- {
+		h := holds[i]
+		restoreInvItem(h.player.Session, h.player, h.item, h.qty, h.slotRemoved)
+		handler.SendWeightUpdate(h.player.Session, h.player)
+	}
+}
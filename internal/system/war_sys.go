@@ -2,7 +2,6 @@ package system
 
 import (
 	"strings"
-	"time"
 
 	"github.com/l1jgo/server/internal/handler"
 	"github.com/l1jgo/server/internal/net"
@@ -117,7 +116,7 @@ func (s *WarSystem) declareCastleWar(sess *net.Session, player *world.PlayerInfo
 			DefenceClan: defenceClan.ClanName,
 			AttackClans: map[string]bool{attackClan.ClanName: true},
 			CastleID:    castleID,
-			StartTime:   time.Now(),
+			StartTime:   s.deps.Clock.Now(),
 		}
 		s.wars = append(s.wars, w)
 	}
@@ -139,7 +138,7 @@ func (s *WarSystem) declareSimWar(sess *net.Session, player *world.PlayerInfo, a
 		DefenceClan: defenceClan.ClanName,
 		AttackClans: map[string]bool{attackClan.ClanName: true},
 		CastleID:    0,
-		StartTime:   time.Now(),
+		StartTime:   s.deps.Clock.Now(),
 	}
 	s.wars = append(s.wars, w)
 
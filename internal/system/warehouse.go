@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/l1jgo/server/internal/core/event"
+	"github.com/l1jgo/server/internal/data"
 	"github.com/l1jgo/server/internal/handler"
 	"github.com/l1jgo/server/internal/net"
 	"github.com/l1jgo/server/internal/net/packet"
@@ -26,18 +28,15 @@ func NewWarehouseSystem(deps *handler.Deps) *WarehouseSystem {
 // OpenWarehouse 從 DB 載入倉庫物品並發送列表封包。
 // 由 NPC 動作 "retrieve"、"retrieve-elven"、"retrieve-char" 呼叫。
 func (s *WarehouseSystem) OpenWarehouse(sess *net.Session, player *world.PlayerInfo, npcObjID int32, whType int16) {
-	if err := s.loadWarehouseCache(sess, player, whType); err != nil {
-		s.deps.Log.Error("倉庫載入失敗", zap.Error(err))
-		return
-	}
+	s.loadWarehouseCache(sess, player, whType, "倉庫載入失敗", func() {
+		handler.SendWarehouseList(sess, npcObjID, whType, player.WarehouseItems, int32(s.deps.Config.Gameplay.WarehousePersonalFee))
 
-	handler.SendWarehouseList(sess, npcObjID, whType, player.WarehouseItems, int32(s.deps.Config.Gameplay.WarehousePersonalFee))
-
-	s.deps.Log.Debug("warehouse opened",
-		zap.String("player", player.Name),
-		zap.Int16("type", whType),
-		zap.Int("items", len(player.WarehouseItems)),
-	)
+		s.deps.Log.Debug("warehouse opened",
+			zap.String("player", player.Name),
+			zap.Int16("type", whType),
+			zap.Int("items", len(player.WarehouseItems)),
+		)
+	})
 }
 
 // OpenWarehouseDeposit 開啟倉庫存入介面。
@@ -59,33 +58,48 @@ func (s *WarehouseSystem) OpenClanWarehouse(sess *net.Session, player *world.Pla
 		return
 	}
 
+	if !s.clanWarehouseAvailable(sess, player) {
+		return
+	}
+
+	s.loadWarehouseCache(sess, player, handler.WhTypeClan, "血盟倉庫載入失敗", func() {
+		// 載入期間可能已被其他成員開啟，重新檢查
+		if !s.clanWarehouseAvailable(sess, player) {
+			return
+		}
+		clan := s.deps.World.Clans.GetClan(player.ClanID)
+
+		// 標記此玩家正在使用
+		clan.WarehouseUsingCharID = player.CharID
+
+		handler.SendWarehouseList(sess, npcObjID, handler.WhTypeClan, player.WarehouseItems, int32(s.deps.Config.Gameplay.WarehousePersonalFee))
+
+		s.deps.Log.Debug("clan warehouse opened",
+			zap.String("player", player.Name),
+			zap.Int32("clan_id", player.ClanID),
+			zap.Int("items", len(player.WarehouseItems)),
+		)
+	})
+}
+
+// clanWarehouseAvailable 檢查血盟存在且倉庫未被他人使用（Java: S_RetrievePledgeList 行 20-28）。
+func (s *WarehouseSystem) clanWarehouseAvailable(sess *net.Session, player *world.PlayerInfo) bool {
 	clan := s.deps.World.Clans.GetClan(player.ClanID)
 	if clan == nil {
 		handler.SendServerMessage(sess, 208)
-		return
+		return false
 	}
-
-	// 單人使用鎖定（Java: S_RetrievePledgeList 行 20-28）
 	if clan.WarehouseUsingCharID != 0 && clan.WarehouseUsingCharID != player.CharID {
 		handler.SendServerMessage(sess, 209) // 血盟倉庫正被他人使用
-		return
+		return false
 	}
+	return true
+}
 
-	if err := s.loadWarehouseCache(sess, player, handler.WhTypeClan); err != nil {
-		s.deps.Log.Error("血盟倉庫載入失敗", zap.Error(err))
-		return
-	}
-
-	// 標記此玩家正在使用
-	clan.WarehouseUsingCharID = player.CharID
-
-	handler.SendWarehouseList(sess, npcObjID, handler.WhTypeClan, player.WarehouseItems, int32(s.deps.Config.Gameplay.WarehousePersonalFee))
-
-	s.deps.Log.Debug("clan warehouse opened",
-		zap.String("player", player.Name),
-		zap.Int32("clan_id", player.ClanID),
-		zap.Int("items", len(player.WarehouseItems)),
-	)
+// warehouseOrder 是客戶端送出的一筆存入/領出項目。
+type warehouseOrder struct {
+	objectID int32
+	qty      int32
 }
 
 // HandleWarehouseOp 處理倉庫存入/領出操作。
@@ -102,16 +116,44 @@ func (s *WarehouseSystem) HandleWarehouseOp(sess *net.Session, r *packet.Reader,
 		return
 	}
 
+	if count <= 0 || count > 100 {
+		if whType == handler.WhTypeClan {
+			s.releaseClanWarehouseLock(player)
+		}
+		return
+	}
+	orders := make([]warehouseOrder, 0, count)
+	for i := 0; i < count; i++ {
+		objID := r.ReadD()
+		qty := r.ReadD()
+		if qty <= 0 {
+			qty = 1
+		}
+		orders = append(orders, warehouseOrder{objectID: objID, qty: qty})
+	}
+
+	run := func() {
+		// 存入/領出的 DB 寫入完成後才解除血盟倉庫鎖定（Java: clan.setWarehouseUsingChar(0)）
+		done := func() {
+			if whType == handler.WhTypeClan {
+				s.releaseClanWarehouseLock(player)
+			}
+		}
+		if isDeposit {
+			s.handleWarehouseDeposit(sess, orders, player, whType, done)
+		} else {
+			s.handleWarehouseWithdraw(sess, orders, player, whType, done)
+		}
+	}
+
 	// 3.80C 客戶端的 "storage" 對話框「存放物品」按鈕會直接開啟存入介面，
 	// 不經過 NPC 動作（不送 "retrieve"），因此 WarehouseItems 可能尚未載入。
 	if player.WarehouseItems == nil {
 		if !isDeposit {
 			return
 		}
-		if err := s.loadWarehouseCache(sess, player, whType); err != nil {
-			s.deps.Log.Error("倉庫自動載入失敗", zap.Error(err))
-			return
-		}
+		s.loadWarehouseCache(sess, player, whType, "倉庫自動載入失敗", run)
+		return
 	}
 	if player.WarehouseType != whType {
 		if !isDeposit {
@@ -121,69 +163,75 @@ func (s *WarehouseSystem) HandleWarehouseOp(sess *net.Session, r *packet.Reader,
 			)
 			return
 		}
-		if err := s.loadWarehouseCache(sess, player, whType); err != nil {
-			s.deps.Log.Error("倉庫重新載入失敗", zap.Error(err))
-			return
-		}
-	}
-
-	if isDeposit {
-		s.handleWarehouseDeposit(sess, r, count, player, whType)
-	} else {
-		s.handleWarehouseWithdraw(sess, r, count, player, whType)
-	}
-
-	// 血盟倉庫操作完成後解除鎖定（Java: clan.setWarehouseUsingChar(0)）
-	if whType == handler.WhTypeClan {
-		s.releaseClanWarehouseLock(player)
+		s.loadWarehouseCache(sess, player, whType, "倉庫重新載入失敗", run)
+		return
 	}
+	run()
 }
 
 // SendClanWarehouseHistory 發送血盟倉庫歷史記錄。
 // Java: S_PledgeWarehouseHistory — opcode=S_OPCODE_EVENT(250), subtype=117
 func (s *WarehouseSystem) SendClanWarehouseHistory(sess *net.Session, clanID int32) {
-	ctx := context.Background()
-	entries, err := s.deps.WarehouseRepo.LoadClanWarehouseHistory(ctx, clanID)
-	if err != nil {
-		s.deps.Log.Error("血盟倉庫歷史載入失敗", zap.Error(err))
-		return
-	}
+	var entries []persist.ClanWarehouseHistoryEntry
+	handler.RunDBJob(sess, s.deps, "clan_warehouse_history", func(ctx context.Context) error {
+		var err error
+		entries, err = s.deps.WarehouseRepo.LoadClanWarehouseHistory(ctx, clanID)
+		return err
+	}, func(err error) {
+		if err != nil {
+			s.deps.Log.Error("血盟倉庫歷史載入失敗", zap.Error(err))
+			return
+		}
 
-	w := packet.NewWriterWithOpcode(packet.S_OPCODE_EVENT)
-	w.WriteC(117) // S_PacketBox.HTML_CLAN_WARHOUSE_RECORD
-	w.WriteD(int32(len(entries)))
-	for _, e := range entries {
-		w.WriteS(e.CharName)
-		w.WriteC(byte(e.Type)) // 0=存入, 1=領出
-		w.WriteS(e.ItemName)
-		w.WriteD(e.ItemCount)
-		w.WriteD(e.MinutesAgo) // 距今幾分鐘
-	}
-	sess.Send(w.Bytes())
+		w := packet.NewWriterWithOpcode(packet.S_OPCODE_EVENT)
+		w.WriteC(117) // S_PacketBox.HTML_CLAN_WARHOUSE_RECORD
+		w.WriteD(int32(len(entries)))
+		for _, e := range entries {
+			w.WriteS(e.CharName)
+			w.WriteC(byte(e.Type)) // 0=存入, 1=領出
+			w.WriteS(e.ItemName)
+			w.WriteD(e.ItemCount)
+			w.WriteD(e.MinutesAgo) // 距今幾分鐘
+		}
+		sess.Send(w.Bytes())
+	})
 }
 
 // ========================================================================
 //  內部函式
 // ========================================================================
 
-// loadWarehouseCache 從 DB 載入倉庫物品並填充玩家快取。
-func (s *WarehouseSystem) loadWarehouseCache(sess *net.Session, player *world.PlayerInfo, whType int16) error {
-	ctx := context.Background()
+// loadWarehouseCache 在 DB worker 載入倉庫物品，完成後於遊戲迴圈填充玩家快取並呼叫 then。
+// 載入失敗時以 errMsg 記錄錯誤，不呼叫 then。
+func (s *WarehouseSystem) loadWarehouseCache(sess *net.Session, player *world.PlayerInfo, whType int16, errMsg string, then func()) {
+	charName := player.Name
+	clanName := player.ClanName
+	accountName := sess.AccountName
 
 	var items []persist.WarehouseItem
-	var err error
-	switch whType {
-	case handler.WhTypeCharacter:
-		items, err = s.deps.WarehouseRepo.LoadByCharName(ctx, player.Name, whType)
-	case handler.WhTypeClan:
-		items, err = s.deps.WarehouseRepo.Load(ctx, player.ClanName, whType)
-	default: // Personal, Elf
-		items, err = s.deps.WarehouseRepo.Load(ctx, sess.AccountName, whType)
-	}
-	if err != nil {
+	handler.RunDBJob(sess, s.deps, "warehouse_load", func(ctx context.Context) error {
+		var err error
+		switch whType {
+		case handler.WhTypeCharacter:
+			items, err = s.deps.WarehouseRepo.LoadByCharName(ctx, charName, whType)
+		case handler.WhTypeClan:
+			items, err = s.deps.WarehouseRepo.Load(ctx, clanName, whType)
+		default: // Personal, Elf
+			items, err = s.deps.WarehouseRepo.Load(ctx, accountName, whType)
+		}
 		return err
-	}
+	}, func(err error) {
+		if err != nil {
+			s.deps.Log.Error(errMsg, zap.Error(err))
+			return
+		}
+		s.fillWarehouseCache(player, whType, items)
+		then()
+	})
+}
 
+// fillWarehouseCache 以 DB 資料重建玩家的倉庫快取。
+func (s *WarehouseSystem) fillWarehouseCache(player *world.PlayerInfo, whType int16, items []persist.WarehouseItem) {
 	player.WarehouseItems = make([]*world.WarehouseCache, 0, len(items))
 	player.WarehouseType = whType
 
@@ -215,40 +263,45 @@ func (s *WarehouseSystem) loadWarehouseCache(sess *net.Session, player *world.Pl
 			Name:       name,
 			InvGfx:     invGfx,
 			Weight:     weight,
+			ObjID:      it.ObjID,
 		}
 		player.WarehouseItems = append(player.WarehouseItems, wc)
 	}
-	return nil
 }
 
-// handleWarehouseDeposit 將物品從玩家背包移至倉庫。
-func (s *WarehouseSystem) handleWarehouseDeposit(sess *net.Session, r *packet.Reader, count int, player *world.PlayerInfo, whType int16) {
-	if count <= 0 || count > 100 {
-		return
-	}
-
-	type depositOrder struct {
-		objectID int32
-		qty      int32
-	}
-	orders := make([]depositOrder, 0, count)
-	for i := 0; i < count; i++ {
-		objID := r.ReadD()
-		qty := r.ReadD()
-		if qty <= 0 {
-			qty = 1
-		}
-		orders = append(orders, depositOrder{objectID: objID, qty: qty})
-	}
+// depositEntry 是一筆已從背包預扣、等待 DB 寫入的存入項目。
+type depositEntry struct {
+	item        *world.InvItem // 背包物品（整格移除時保留指標以便退回）
+	qty         int32
+	slotRemoved bool
+	stackOn     *world.WarehouseCache // 疊加到既有倉庫物品；nil = 新增一列
+	stackDbID   int32
+	row         persist.WarehouseItem
+	wal         persist.WALEntry
+	itemName    string
+	itemInfo    *data.ItemInfo
+	stackable   bool
+	useType     byte
+
+	dbID int32 // worker 結果：新增列的 ID
+	err  error // worker 結果
+}
 
+// handleWarehouseDeposit 將物品從玩家背包移至倉庫。
+// 物品先從背包預扣（避免等待 DB 期間被重複使用），DB 寫入失敗的項目於回呼中退回背包。
+func (s *WarehouseSystem) handleWarehouseDeposit(sess *net.Session, orders []warehouseOrder, player *world.PlayerInfo, whType int16, done func()) {
 	// 決定 DB 存入的 account_name 鍵
 	dbAccountName := sess.AccountName
 	if whType == handler.WhTypeClan {
 		dbAccountName = player.ClanName
 	}
+	walKey := dbAccountName
+	if whType == handler.WhTypeCharacter {
+		walKey = player.Name // 角色倉庫以 char_name 為鍵
+	}
+	walType, walTarget := warehouseWAL(whType, walKey, true)
 
-	ctx := context.Background()
-
+	entries := make([]*depositEntry, 0, len(orders))
 	for _, o := range orders {
 		invItem := player.Inv.FindByObjectID(o.objectID)
 		if invItem == nil || invItem.Equipped {
@@ -275,122 +328,166 @@ func (s *WarehouseSystem) handleWarehouseDeposit(sess *net.Session, r *packet.Re
 			itemName = itemInfo.Name
 		}
 
+		e := &depositEntry{
+			item:      invItem,
+			qty:       qty,
+			itemName:  itemName,
+			itemInfo:  itemInfo,
+			stackable: stackable,
+			useType:   useType,
+			row: persist.WarehouseItem{
+				AccountName: dbAccountName,
+				CharName:    player.Name,
+				WhType:      whType,
+				ItemID:      invItem.ItemID,
+				Count:       qty,
+				EnchantLvl:  int16(invItem.EnchantLvl),
+				Bless:       int16(invItem.Bless),
+				Identified:  invItem.Identified,
+			},
+		}
+		if !stackable {
+			e.row.ObjID = invItem.ObjectID
+		}
+
 		// 檢查倉庫中是否已有同種可堆疊物品
 		if stackable {
-			found := false
 			for _, wc := range player.WarehouseItems {
 				if wc.ItemID == invItem.ItemID {
-					err := s.deps.WarehouseRepo.AddToStack(ctx, wc.DbID, qty)
-					if err != nil {
-						s.deps.Log.Error("倉庫堆疊新增失敗", zap.Error(err))
-						continue
-					}
-					wc.Count += qty
-					found = true
+					e.stackOn = wc
+					e.stackDbID = wc.DbID
 					break
 				}
 			}
-			if found {
-				removed := player.Inv.RemoveItem(o.objectID, qty)
-				if removed {
-					handler.SendRemoveInventoryItem(sess, o.objectID)
-				} else {
-					handler.SendItemCountUpdate(sess, invItem)
-				}
-				if whType == handler.WhTypeClan {
-					_ = s.deps.WarehouseRepo.InsertClanWarehouseHistory(
-						ctx, player.ClanID, player.Name, 0, itemName, qty)
-				}
-				continue
-			}
-		}
-
-		// 新增倉庫物品
-		whItem := persist.WarehouseItem{
-			AccountName: dbAccountName,
-			CharName:    player.Name,
-			WhType:      whType,
-			ItemID:      invItem.ItemID,
-			Count:       qty,
-			EnchantLvl:  int16(invItem.EnchantLvl),
-			Bless:       int16(invItem.Bless),
-			Identified:  invItem.Identified,
 		}
 
-		dbID, err := s.deps.WarehouseRepo.Deposit(ctx, whItem)
-		if err != nil {
-			s.deps.Log.Error("倉庫存入失敗", zap.Error(err))
-			continue
-		}
+		// WAL 與倉庫寫入同一交易：重播時只需從角色背包扣除
+		e.wal = persist.ItemWAL(walType, player.CharID, 0, invItem, qty)
+		e.wal.Target = walTarget
 
-		// 從背包移除
-		removed := player.Inv.RemoveItem(o.objectID, qty)
-		if removed {
+		// 從背包預扣
+		e.slotRemoved = player.Inv.RemoveItem(o.objectID, qty)
+		if e.slotRemoved {
 			handler.SendRemoveInventoryItem(sess, o.objectID)
 		} else {
 			handler.SendItemCountUpdate(sess, invItem)
 		}
+		entries = append(entries, e)
+	}
+	if len(entries) == 0 {
+		done()
+		return
+	}
 
-		// 新增到本地快取
-		invGfx := invItem.InvGfx
-		weight := invItem.Weight
-		if itemInfo != nil {
-			invGfx = itemInfo.InvGfx
-			weight = itemInfo.Weight
+	clanID := player.ClanID
+	charName := player.Name
+	handler.RunDBJob(sess, s.deps, "warehouse_deposit", func(ctx context.Context) error {
+		for _, e := range entries {
+			if e.stackOn != nil {
+				e.err = s.deps.WarehouseRepo.AddToStack(ctx, e.stackDbID, e.qty, e.wal)
+			} else {
+				e.dbID, e.err = s.deps.WarehouseRepo.Deposit(ctx, e.row, e.wal)
+			}
+			if e.err == nil && whType == handler.WhTypeClan {
+				_ = s.deps.WarehouseRepo.InsertClanWarehouseHistory(
+					ctx, clanID, charName, 0, e.itemName, e.qty)
+			}
 		}
-
-		wc := &world.WarehouseCache{
-			TempObjID:  world.NextItemObjID(),
-			DbID:       dbID,
-			ItemID:     invItem.ItemID,
-			Count:      qty,
-			EnchantLvl: int16(invItem.EnchantLvl),
-			Bless:      int16(invItem.Bless),
-			Stackable:  stackable,
-			Identified: invItem.Identified,
-			UseType:    useType,
-			Name:       itemName,
-			InvGfx:     invGfx,
-			Weight:     weight,
+		return nil
+	}, func(err error) {
+		// err 只在工作未執行（佇列已滿）時非 nil，此時全部退回；
+		// 否則各筆獨立交易，只退回自己失敗的那筆，已提交的不可回滾
+		if err != nil {
+			s.deps.Log.Error("倉庫存入工作未執行", zap.String("player", player.Name), zap.Error(err))
 		}
-		player.WarehouseItems = append(player.WarehouseItems, wc)
+		for _, e := range entries {
+			if err != nil || e.err != nil {
+				if e.err != nil {
+					s.deps.Log.Error("倉庫存入失敗", zap.String("player", player.Name), zap.Error(e.err))
+				}
+				restoreInvItem(sess, player, e.item, e.qty, e.slotRemoved)
+				continue
+			}
+			handler.EmitItemMoved(s.deps, event.ItemReasonWarehouseIn, e.item, e.qty, handler.CharLoc(player.CharID), walTarget, player)
 
-		if whType == handler.WhTypeClan {
-			_ = s.deps.WarehouseRepo.InsertClanWarehouseHistory(
-				ctx, player.ClanID, player.Name, 0, itemName, qty)
+			if e.stackOn != nil {
+				e.stackOn.Count += e.qty
+				continue
+			}
+
+			// 新增到本地快取
+			invGfx := e.item.InvGfx
+			weight := e.item.Weight
+			if e.itemInfo != nil {
+				invGfx = e.itemInfo.InvGfx
+				weight = e.itemInfo.Weight
+			}
+			player.WarehouseItems = append(player.WarehouseItems, &world.WarehouseCache{
+				TempObjID:  world.NextItemObjID(),
+				DbID:       e.dbID,
+				ItemID:     e.item.ItemID,
+				Count:      e.qty,
+				EnchantLvl: int16(e.item.EnchantLvl),
+				Bless:      int16(e.item.Bless),
+				Stackable:  e.stackable,
+				Identified: e.item.Identified,
+				UseType:    e.useType,
+				Name:       e.itemName,
+				InvGfx:     invGfx,
+				Weight:     weight,
+				ObjID:      e.row.ObjID,
+			})
 		}
-	}
 
-	handler.SendWeightUpdate(sess, player)
+		handler.SendWeightUpdate(sess, player)
 
-	s.deps.Log.Debug("warehouse deposit",
-		zap.String("player", player.Name),
-		zap.Int16("wh_type", whType),
-		zap.Int("items", count),
-	)
+		s.deps.Log.Debug("warehouse deposit",
+			zap.String("player", player.Name),
+			zap.Int16("wh_type", whType),
+			zap.Int("items", len(entries)),
+		)
+		done()
+	})
 }
 
-// handleWarehouseWithdraw 將物品從倉庫移至玩家背包。
-func (s *WarehouseSystem) handleWarehouseWithdraw(sess *net.Session, r *packet.Reader, count int, player *world.PlayerInfo, whType int16) {
-	if count <= 0 || count > 100 {
-		return
-	}
-
-	type withdrawOrder struct {
-		objectID int32
-		qty      int32
+// restoreInvItem 退回存入失敗而預扣的背包物品。
+func restoreInvItem(sess *net.Session, player *world.PlayerInfo, item *world.InvItem, qty int32, slotRemoved bool) {
+	if !slotRemoved {
+		if cur := player.Inv.FindByObjectID(item.ObjectID); cur != nil {
+			cur.Count += qty
+			handler.SendItemCountUpdate(sess, cur)
+			return
+		}
 	}
-	orders := make([]withdrawOrder, 0, count)
-	for i := 0; i < count; i++ {
-		objID := r.ReadD()
-		qty := r.ReadD()
-		if qty <= 0 {
-			qty = 1
+	if item.Stackable {
+		if existing := player.Inv.FindByItemID(item.ItemID); existing != nil {
+			existing.Count += qty
+			handler.SendItemCountUpdate(sess, existing)
+			return
 		}
-		orders = append(orders, withdrawOrder{objectID: objID, qty: qty})
+		item.Count = qty
 	}
+	player.Inv.Items = append(player.Inv.Items, item)
+	handler.SendAddItem(sess, item)
+}
+
+// withdrawEntry 是一筆已從倉庫快取預扣、等待 DB 寫入的領出項目。
+type withdrawEntry struct {
+	wc        *world.WarehouseCache
+	dbID      int32
+	qty       int32
+	fullyTake bool // 整筆移出快取
+	name      string
+	wal       persist.WALEntry
+
+	err error // worker 結果
+}
 
+// handleWarehouseWithdraw 將物品從倉庫移至玩家背包。
+// 倉庫快取先預扣，DB 寫入成功的項目於回呼中放入背包，失敗的項目退回快取。
+func (s *WarehouseSystem) handleWarehouseWithdraw(sess *net.Session, orders []warehouseOrder, player *world.PlayerInfo, whType int16, done func()) {
 	if len(orders) == 0 {
+		done()
 		return
 	}
 
@@ -402,18 +499,41 @@ func (s *WarehouseSystem) handleWarehouseWithdraw(sess *net.Session, r *packet.R
 		mithril := player.Inv.FindByItemID(mithrilItemID)
 		if mithril == nil || mithril.Count < elfFee {
 			handler.SendServerMessage(sess, 189)
+			done()
 			return
 		}
 	} else {
 		if player.Inv.GetAdena() < personalFee {
 			handler.SendServerMessage(sess, 189)
+			done()
 			return
 		}
 	}
 
-	ctx := context.Background()
-	var transferred int
+	// 領出費用的 WAL 隨第一筆成功領出寫入（每次操作扣一次）
+	dbAccountName := sess.AccountName
+	switch whType {
+	case handler.WhTypeClan:
+		dbAccountName = player.ClanName
+	case handler.WhTypeCharacter:
+		dbAccountName = player.Name
+	}
+	walType, walTarget := warehouseWAL(whType, dbAccountName, false)
+	var feeWAL []persist.WALEntry
+	if whType == handler.WhTypeElf {
+		if elfFee > 0 {
+			fee := persist.ItemWAL(walType, player.CharID, 0, player.Inv.FindByItemID(mithrilItemID), elfFee)
+			fee.Target = walTarget
+			feeWAL = append(feeWAL, fee)
+		}
+	} else if personalFee > 0 {
+		fee := persist.GoldWAL(walType, player.CharID, 0, int64(personalFee))
+		fee.Target = walTarget
+		feeWAL = append(feeWAL, fee)
+	}
 
+	entries := make([]*withdrawEntry, 0, len(orders))
+	slots := player.Inv.Size()
 	for _, o := range orders {
 		var wc *world.WarehouseCache
 		var wcIndex int
@@ -433,85 +553,143 @@ func (s *WarehouseSystem) handleWarehouseWithdraw(sess *net.Session, r *packet.R
 			qty = wc.Count
 		}
 
-		if player.Inv.IsFull() {
+		if slots >= world.MaxInventorySize {
 			handler.SendServerMessage(sess, 263)
 			break
 		}
-
-		fullyRemoved, err := s.deps.WarehouseRepo.Withdraw(ctx, wc.DbID, qty)
-		if err != nil {
-			s.deps.Log.Error("倉庫取出失敗", zap.Error(err))
-			continue
+		if !wc.Stackable || player.Inv.FindByItemID(wc.ItemID) == nil {
+			slots++
 		}
 
-		if fullyRemoved || qty >= wc.Count {
+		// 從倉庫快取預扣
+		e := &withdrawEntry{wc: wc, dbID: wc.DbID, qty: qty, fullyTake: qty >= wc.Count, name: wc.Name}
+		e.wal = persist.WALEntry{
+			TxType:     walType,
+			ToChar:     player.CharID,
+			ItemID:     wc.ItemID,
+			Count:      qty,
+			EnchantLvl: wc.EnchantLvl,
+			Bless:      wc.Bless,
+			Identified: wc.Identified,
+			Stackable:  wc.Stackable,
+			Target:     walTarget,
+		}
+		if e.fullyTake {
 			player.WarehouseItems = append(player.WarehouseItems[:wcIndex], player.WarehouseItems[wcIndex+1:]...)
 		} else {
 			wc.Count -= qty
 		}
+		entries = append(entries, e)
+	}
+	if len(entries) == 0 {
+		handler.SendWeightUpdate(sess, player)
+		done()
+		return
+	}
 
-		existing := player.Inv.FindByItemID(wc.ItemID)
-		wasExisting := existing != nil && wc.Stackable
+	clanID := player.ClanID
+	charName := player.Name
+	handler.RunDBJob(sess, s.deps, "warehouse_withdraw", func(ctx context.Context) error {
+		for _, e := range entries {
+			wal := append([]persist.WALEntry{e.wal}, feeWAL...)
+			_, e.err = s.deps.WarehouseRepo.Withdraw(ctx, e.dbID, e.qty, wal...)
+			if e.err != nil {
+				continue
+			}
+			feeWAL = nil
+			if whType == handler.WhTypeClan {
+				_ = s.deps.WarehouseRepo.InsertClanWarehouseHistory(
+					ctx, clanID, charName, 1, e.name, e.qty)
+			}
+		}
+		return nil
+	}, func(err error) {
+		// 同存入：工作未執行時全部退回倉庫快取，否則只退回失敗的那筆
+		if err != nil {
+			s.deps.Log.Error("倉庫取出工作未執行", zap.String("player", player.Name), zap.Error(err))
+		}
+		var transferred int
+		for _, e := range entries {
+			wc := e.wc
+			if err != nil || e.err != nil {
+				if e.err != nil {
+					s.deps.Log.Error("倉庫取出失敗", zap.String("player", player.Name), zap.Error(e.err))
+				}
+				// 退回倉庫快取
+				if e.fullyTake {
+					player.WarehouseItems = append(player.WarehouseItems, wc)
+				} else {
+					wc.Count += e.qty
+				}
+				continue
+			}
 
-		item := player.Inv.AddItem(
-			wc.ItemID,
-			qty,
-			wc.Name,
-			wc.InvGfx,
-			wc.Weight,
-			wc.Stackable,
-			byte(wc.Bless),
-		)
-		item.EnchantLvl = int8(wc.EnchantLvl)
-		item.Identified = wc.Identified
-		item.UseType = wc.UseType
+			existing := player.Inv.FindByItemID(wc.ItemID)
+			wasExisting := existing != nil && wc.Stackable
 
-		if wasExisting {
-			handler.SendItemCountUpdate(sess, item)
-		} else {
-			handler.SendAddItem(sess, item)
-		}
+			// 非堆疊物品沿用存入前的 ObjectID（item_events 歷史連續）
+			keepObjID := int32(0)
+			if !wc.Stackable {
+				keepObjID = wc.ObjID
+			}
+			item := player.Inv.AddItemWithID(
+				keepObjID,
+				wc.ItemID,
+				e.qty,
+				wc.Name,
+				wc.InvGfx,
+				wc.Weight,
+				wc.Stackable,
+				byte(wc.Bless),
+			)
+			item.EnchantLvl = int8(wc.EnchantLvl)
+			item.Identified = wc.Identified
+			item.UseType = wc.UseType
+			handler.EmitItemMoved(s.deps, event.ItemReasonWarehouseOut, item, e.qty, walTarget, handler.CharLoc(player.CharID), player)
+
+			if wasExisting {
+				handler.SendItemCountUpdate(sess, item)
+			} else {
+				handler.SendAddItem(sess, item)
+			}
 
-		if whType == handler.WhTypeClan {
-			_ = s.deps.WarehouseRepo.InsertClanWarehouseHistory(
-				ctx, player.ClanID, player.Name, 1, wc.Name, qty)
+			transferred++
 		}
 
-		transferred++
-	}
-
-	// 每次操作扣一次費用（非每物品）
-	if transferred > 0 {
-		if whType == handler.WhTypeElf {
-			mithril := player.Inv.FindByItemID(mithrilItemID)
-			if mithril != nil {
-				removed := player.Inv.RemoveItem(mithril.ObjectID, elfFee)
-				if removed {
-					handler.SendRemoveInventoryItem(sess, mithril.ObjectID)
-				} else {
-					handler.SendItemCountUpdate(sess, mithril)
+		// 每次操作扣一次費用（非每物品）
+		if transferred > 0 {
+			if whType == handler.WhTypeElf {
+				mithril := player.Inv.FindByItemID(mithrilItemID)
+				if mithril != nil {
+					removed := player.Inv.RemoveItem(mithril.ObjectID, elfFee)
+					if removed {
+						handler.SendRemoveInventoryItem(sess, mithril.ObjectID)
+					} else {
+						handler.SendItemCountUpdate(sess, mithril)
+					}
 				}
-			}
-		} else {
-			adena := player.Inv.FindByItemID(world.AdenaItemID)
-			if adena != nil {
-				adena.Count -= personalFee
-				if adena.Count <= 0 {
-					player.Inv.RemoveItem(adena.ObjectID, 0)
-					handler.SendRemoveInventoryItem(sess, adena.ObjectID)
-				} else {
-					handler.SendItemCountUpdate(sess, adena)
+			} else {
+				adena := player.Inv.FindByItemID(world.AdenaItemID)
+				if adena != nil {
+					adena.Count -= personalFee
+					if adena.Count <= 0 {
+						player.Inv.RemoveItem(adena.ObjectID, 0)
+						handler.SendRemoveInventoryItem(sess, adena.ObjectID)
+					} else {
+						handler.SendItemCountUpdate(sess, adena)
+					}
 				}
 			}
 		}
-	}
-	handler.SendWeightUpdate(sess, player)
+		handler.SendWeightUpdate(sess, player)
 
-	s.deps.Log.Debug("warehouse withdraw",
-		zap.String("player", player.Name),
-		zap.Int16("wh_type", whType),
-		zap.Int("transferred", transferred),
-	)
+		s.deps.Log.Debug("warehouse withdraw",
+			zap.String("player", player.Name),
+			zap.Int16("wh_type", whType),
+			zap.Int("transferred", transferred),
+		)
+		done()
+	})
 }
 
 // releaseClanWarehouseLock 解除血盟倉庫單人使用鎖定。
@@ -524,3 +702,18 @@ func (s *WarehouseSystem) releaseClanWarehouseLock(player *world.PlayerInfo) {
 		clan.WarehouseUsingCharID = 0
 	}
 }
+
+// warehouseWAL 回傳倉庫操作的 WAL 類型與目標描述（account_name 鍵 + 倉庫類型）。
+func warehouseWAL(whType int16, key string, deposit bool) (string, string) {
+	if whType == handler.WhTypeClan {
+		if deposit {
+			return persist.WALClanWarehouseDeposit, "clan_warehouse:" + key
+		}
+		return persist.WALClanWarehouseWithdraw, "clan_warehouse:" + key
+	}
+	target := fmt.Sprintf("warehouse:%s:%d", key, whType)
+	if deposit {
+		return persist.WALWarehouseDeposit, target
+	}
+	return persist.WALWarehouseWithdraw, target
+}
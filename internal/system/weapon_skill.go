@@ -44,7 +44,7 @@ func processWeaponSkillProc(player *world.PlayerInfo, npc *world.NpcInfo, weapon
 	}
 
 	// 機率檢查（Java: Random.nextInt(100) + 1 <= probability）
-	chance := world.RandInt(100) + 1
+	chance := world.RandInt(deps.Rand, 100) + 1
 	if ws.Probability < chance {
 		return 0
 	}
@@ -83,7 +83,7 @@ func processWeaponSkillProc(player *world.PlayerInfo, npc *world.NpcInfo, weapon
 	// 計算傷害
 	var damage float64
 	if ws.RandomDamage > 0 {
-		damage = float64(world.RandInt(ws.RandomDamage))
+		damage = float64(world.RandInt(deps.Rand, ws.RandomDamage))
 	}
 	damage += float64(ws.FixDamage)
 
@@ -236,7 +236,7 @@ func isNpcFrozen(npc *world.NpcInfo) bool {
 // processBaphometStaff 乙乙乙巫師杖（item 121）：14% 觸發，(INT+SP)*1.8 傷害，地屬性。
 // Java: L1WeaponSkill.getBaphometStaffDamage()
 func processBaphometStaff(player *world.PlayerInfo, npc *world.NpcInfo, nearby []*world.PlayerInfo, deps *handler.Deps) int32 {
-	if world.RandInt(100)+1 > 14 {
+	if world.RandInt(deps.Rand, 100)+1 > 14 {
 		return 0
 	}
 	if isNpcFrozen(npc) {
@@ -249,7 +249,7 @@ func processBaphometStaff(player *world.PlayerInfo, npc *world.NpcInfo, nearby [
 	if player.HasBuff(55) { // Berserker
 		bsk = 0.2
 	}
-	dmg := float64(intel+sp)*(1.8+bsk) + float64(world.RandInt(intel+sp))*1.8
+	dmg := float64(intel+sp)*(1.8+bsk) + float64(world.RandInt(deps.Rand, intel+sp))*1.8
 
 	// GFX: 129 播在目標位置
 	for _, viewer := range nearby {
@@ -266,7 +266,7 @@ func processBaphometStaff(player *world.PlayerInfo, npc *world.NpcInfo, nearby [
 // processDiceDagger 骰子匕首（item 2）：2% 觸發，目標 HP*2/3 傷害，消耗武器。
 // Java: L1WeaponSkill.getDiceDaggerDamage()
 func processDiceDagger(player *world.PlayerInfo, npc *world.NpcInfo, nearby []*world.PlayerInfo, deps *handler.Deps) int32 {
-	if world.RandInt(100)+1 > 2 {
+	if world.RandInt(deps.Rand, 100)+1 > 2 {
 		return 0
 	}
 
@@ -304,7 +304,7 @@ func processKiringku(player *world.PlayerInfo, npc *world.NpcInfo, weaponItemID
 	// 2D5 + value
 	kiringkuDmg := 0
 	for i := 0; i < 2; i++ {
-		kiringkuDmg += world.RandInt(5) + 1
+		kiringkuDmg += world.RandInt(deps.Rand, 5) + 1
 	}
 	kiringkuDmg += value
 
@@ -366,7 +366,7 @@ func processAreaSkillWeapon(player *world.PlayerInfo, npc *world.NpcInfo, weapon
 		return 0
 	}
 
-	if world.RandInt(100)+1 > probability {
+	if world.RandInt(deps.Rand, 100)+1 > probability {
 		return 0
 	}
 	if isNpcFrozen(npc) {
@@ -379,7 +379,7 @@ func processAreaSkillWeapon(player *world.PlayerInfo, npc *world.NpcInfo, weapon
 	if player.HasBuff(55) { // Berserker
 		bsk = 0.2
 	}
-	dmg := float64(intel+sp)*(damageRate+bsk) + float64(world.RandInt(intel+sp))*damageRate
+	dmg := float64(intel+sp)*(damageRate+bsk) + float64(world.RandInt(deps.Rand, intel+sp))*damageRate
 
 	// GFX
 	effectTargetID := npc.ID
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	coresys "github.com/l1jgo/server/internal/core/system"
+	"github.com/l1jgo/server/internal/handler"
 	"github.com/l1jgo/server/internal/net/packet"
 	"github.com/l1jgo/server/internal/world"
 )
@@ -12,26 +13,27 @@ import (
 // new hour. Broadcasts S_WEATHER to all online players. Phase 3 (PostUpdate).
 type WeatherSystem struct {
 	world *world.State
+	deps  *handler.Deps
 }
 
-func NewWeatherSystem(ws *world.State) *WeatherSystem {
-	return &WeatherSystem{world: ws}
+func NewWeatherSystem(ws *world.State, deps *handler.Deps) *WeatherSystem {
+	return &WeatherSystem{world: ws, deps: deps}
 }
 
 func (s *WeatherSystem) Phase() coresys.Phase { return coresys.PhasePostUpdate }
 
 func (s *WeatherSystem) Update(_ time.Duration) {
-	gt := world.GameTimeNow()
+	gt := world.GameTimeAt(s.deps.Clock.Now())
 	curHour := gt.Hour()
 	if s.world.LastHour < 0 {
 		// First tick — initialize without broadcast
 		s.world.LastHour = curHour
-		s.world.RandomizeWeather()
+		s.world.RandomizeWeather(s.deps.Rand)
 		return
 	}
 	if curHour != s.world.LastHour {
 		s.world.LastHour = curHour
-		s.world.RandomizeWeather()
+		s.world.RandomizeWeather(s.deps.Rand)
 		weather := s.world.Weather
 		s.world.AllPlayers(func(p *world.PlayerInfo) {
 			w := packet.NewWriterWithOpcode(packet.S_OPCODE_WEATHER)
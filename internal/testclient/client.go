@@ -0,0 +1,173 @@
+// Package testclient 是無頭 3.80C 客戶端的封包層：握手、加解密、收發封包與常用
+// 指令封包的組裝。cmd/testbot（連線真實伺服器）與 internal/sim（以 net.Pipe 連線
+// 行程內伺服器）共用。
+package testclient
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	l1net "github.com/l1jgo/server/internal/net"
+	"github.com/l1jgo/server/internal/net/packet"
+)
+
+// Client 模擬 3.80C 客戶端的封包收發。解密只能由單一 goroutine 讀取；
+// 加密（Send）與解密使用各自的金鑰狀態，可分屬不同 goroutine。
+type Client struct {
+	conn    net.Conn
+	cipher  *l1net.Cipher
+	Label   string // 識別標籤（如 "主帳號"、"副帳號"）
+	Verbose bool   // 印出每個收發封包
+}
+
+// Packet 保存接收到的封包原始資料，可多次建立 Reader 解析欄位。
+type Packet struct {
+	Raw []byte // 解密後的完整 payload（含 opcode）
+}
+
+// Opcode 回傳封包 opcode。
+func (p *Packet) Opcode() byte {
+	if len(p.Raw) == 0 {
+		return 0
+	}
+	return p.Raw[0]
+}
+
+// NewReader 建立新的 Reader，跳過 opcode，每次呼叫都從頭開始讀取。
+func (p *Packet) NewReader() *packet.Reader {
+	return packet.NewReader(p.Raw)
+}
+
+// New 包裝已建立的連線。
+func New(conn net.Conn, label string) *Client {
+	return &Client{conn: conn, Label: label}
+}
+
+// Dial 以 TCP 連線伺服器。
+func Dial(addr, label string) (*Client, error) {
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("連線失敗: %w", err)
+	}
+	return New(conn, label), nil
+}
+
+// Close 關閉連線。
+func (c *Client) Close() {
+	c.conn.Close()
+}
+
+// Handshake 讀取明文 InitPacket 並以其中的 seed 初始化加解密。
+func (c *Client) Handshake(timeout time.Duration) error {
+	payload, err := c.readFrame(timeout)
+	if err != nil {
+		return fmt.Errorf("未收到 InitPacket: %w", err)
+	}
+	if len(payload) < 16 {
+		return fmt.Errorf("InitPacket 長度不足: %d (預期 16)", len(payload))
+	}
+	if payload[0] != packet.S_OPCODE_INITPACKET {
+		return fmt.Errorf("InitPacket opcode 錯誤: %d (預期 %d)", payload[0], packet.S_OPCODE_INITPACKET)
+	}
+	seed := int32(binary.LittleEndian.Uint32(payload[1:5]))
+	if seed <= 0 {
+		return fmt.Errorf("seed 無效: %d", seed)
+	}
+	c.cipher = l1net.NewCipher(seed)
+	return nil
+}
+
+// readFrame 讀取一個 L1J 框架的 payload。timeout <= 0 表示不設期限。
+func (c *Client) readFrame(timeout time.Duration) ([]byte, error) {
+	if timeout > 0 {
+		c.conn.SetReadDeadline(time.Now().Add(timeout))
+	} else {
+		c.conn.SetReadDeadline(time.Time{})
+	}
+	return l1net.ReadFrame(c.conn)
+}
+
+// ReadPacket 讀取並解密一個封包。timeout <= 0 表示不設期限。
+func (c *Client) ReadPacket(timeout time.Duration) (*Packet, error) {
+	payload, err := c.readFrame(timeout)
+	if err != nil {
+		return nil, err
+	}
+	if c.cipher != nil {
+		c.cipher.Decrypt(payload)
+	}
+	if c.Verbose {
+		fmt.Printf("  [%s] ← RX opcode=%d (0x%02X) len=%d\n", c.Label, payload[0], payload[0], len(payload))
+	}
+	return &Packet{Raw: payload}, nil
+}
+
+// ReadExpect 讀取封包並驗證 opcode。
+func (c *Client) ReadExpect(timeout time.Duration, opcode byte) (*Packet, error) {
+	p, err := c.ReadPacket(timeout)
+	if err != nil {
+		return nil, err
+	}
+	if p.Opcode() != opcode {
+		return nil, fmt.Errorf("預期 opcode %d (0x%02X), 收到 %d (0x%02X)", opcode, opcode, p.Opcode(), p.Opcode())
+	}
+	return p, nil
+}
+
+// Drain 在 duration 內持續讀取所有封包，按 opcode 分類。
+func (c *Client) Drain(duration time.Duration) map[byte][]*Packet {
+	result := make(map[byte][]*Packet)
+	deadline := time.Now().Add(duration)
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			break
+		}
+		p, err := c.ReadPacket(remaining)
+		if err != nil {
+			break
+		}
+		result[p.Opcode()] = append(result[p.Opcode()], p)
+	}
+	return result
+}
+
+// Send 加密並發送封包。
+func (c *Client) Send(w *packet.Writer) error {
+	return c.SendRaw(w.Bytes())
+}
+
+// SendRaw 加密並發送一個明文 payload（含 opcode）。data 不會被修改。
+func (c *Client) SendRaw(data []byte) error {
+	if c.Verbose && len(data) > 0 {
+		fmt.Printf("  [%s] → TX opcode=%d (0x%02X) len=%d\n", c.Label, data[0], data[0], len(data))
+	}
+	encrypted := make([]byte, len(data))
+	copy(encrypted, data)
+	if c.cipher != nil {
+		c.cipher.Encrypt(encrypted)
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	return l1net.WriteFrame(c.conn, encrypted)
+}
+
+// FormatOpcodes 格式化按 opcode 分類的封包供除錯輸出。
+func FormatOpcodes(packets map[byte][]*Packet) string {
+	if len(packets) == 0 {
+		return "(無)"
+	}
+	var keys []int
+	for k := range packets {
+		keys = append(keys, int(k))
+	}
+	sort.Ints(keys)
+	var parts []string
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%d(0x%02X)×%d", k, k, len(packets[byte(k)])))
+	}
+	return strings.Join(parts, ", ")
+}
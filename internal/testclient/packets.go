@@ -0,0 +1,138 @@
+package testclient
+
+import "github.com/l1jgo/server/internal/net/packet"
+
+// Version 發送 C_VERSION (opcode 14)。
+func (c *Client) Version() error {
+	return c.Send(packet.NewWriterWithOpcode(packet.C_OPCODE_VERSION))
+}
+
+// Login 發送 C_LOGIN (opcode 119)。
+func (c *Client) Login(account, password string) error {
+	w := packet.NewWriterWithOpcode(packet.C_OPCODE_LOGIN)
+	w.WriteS(account)
+	w.WriteS(password)
+	return c.Send(w)
+}
+
+// Stats 是建立角色時分配的六項能力值。
+type Stats struct {
+	Str, Dex, Con, Wis, Cha, Int byte
+}
+
+// CreateChar 發送 C_CREATE_CUSTOM_CHARACTER (opcode 84)。
+// classType: 0=王族 1=騎士 2=妖精 3=法師 4=黑暗妖精 5=龍騎士 6=幻術師；sex: 0=男 1=女。
+func (c *Client) CreateChar(name string, classType, sex byte, st Stats) error {
+	w := packet.NewWriterWithOpcode(packet.C_OPCODE_CREATE_CUSTOM_CHARACTER)
+	w.WriteS(name)
+	w.WriteC(classType)
+	w.WriteC(sex)
+	w.WriteC(st.Str)
+	w.WriteC(st.Dex)
+	w.WriteC(st.Con)
+	w.WriteC(st.Wis)
+	w.WriteC(st.Cha)
+	w.WriteC(st.Int)
+	return c.Send(w)
+}
+
+// EnterWorld 發送 C_ENTER_WORLD (opcode 137)。
+func (c *Client) EnterWorld(charName string) error {
+	w := packet.NewWriterWithOpcode(packet.C_OPCODE_ENTER_WORLD)
+	w.WriteS(charName)
+	return c.Send(w)
+}
+
+// Chat 發送 C_CHAT (opcode 40) 一般聊天。GM 指令以 "." 開頭。
+func (c *Client) Chat(text string) error {
+	w := packet.NewWriterWithOpcode(packet.C_OPCODE_CHAT)
+	w.WriteC(0) // chatType = 0 (normal)
+	w.WriteS(text)
+	return c.Send(w)
+}
+
+// Alive 發送 C_ALIVE (opcode 95)。在世界中時伺服器回應 S_TIME。
+func (c *Client) Alive() error {
+	return c.Send(packet.NewWriterWithOpcode(packet.C_OPCODE_ALIVE))
+}
+
+// Move 發送 C_MOVE (opcode 29)。heading 0-7 代表八方向。
+// 3.80C 客戶端對 heading 做 XOR 0x49 編碼。
+func (c *Client) Move(heading byte) error {
+	w := packet.NewWriterWithOpcode(packet.C_OPCODE_MOVE)
+	w.WriteH(0) // clientX（伺服器忽略）
+	w.WriteH(0) // clientY（伺服器忽略）
+	w.WriteC(heading ^ 0x49)
+	return c.Send(w)
+}
+
+// Attack 發送 C_ATTACK (opcode 229) 近戰攻擊。
+func (c *Client) Attack(targetID int32) error {
+	w := packet.NewWriterWithOpcode(packet.C_OPCODE_ATTACK)
+	w.WriteD(targetID)
+	w.WriteH(0) // x（伺服器忽略）
+	w.WriteH(0) // y（伺服器忽略）
+	return c.Send(w)
+}
+
+// NPCAction 發送 C_NPCAction (opcode 125)。
+func (c *Client) NPCAction(objectID int32, action string) error {
+	w := packet.NewWriterWithOpcode(packet.C_OPCODE_HACTION)
+	w.WriteD(objectID)
+	w.WriteS(action)
+	return c.Send(w)
+}
+
+// AskTrade 發送 C_ASK_XCHG (opcode 2)，向面對面的玩家提出交易。
+func (c *Client) AskTrade() error {
+	return c.Send(packet.NewWriterWithOpcode(packet.C_OPCODE_ASK_XCHG))
+}
+
+// AddTrade 發送 C_ADD_XCHG (opcode 37)，把物品放上交易視窗。
+func (c *Client) AddTrade(objectID, count int32) error {
+	w := packet.NewWriterWithOpcode(packet.C_OPCODE_ADD_XCHG)
+	w.WriteD(objectID)
+	w.WriteD(count)
+	return c.Send(w)
+}
+
+// AcceptTrade 發送 C_ACCEPT_XCHG (opcode 71)，確認交易。
+func (c *Client) AcceptTrade() error {
+	return c.Send(packet.NewWriterWithOpcode(packet.C_OPCODE_ACCEPT_XCHG))
+}
+
+// CancelTrade 發送 C_CANCEL_XCHG (opcode 86)。
+func (c *Client) CancelTrade() error {
+	return c.Send(packet.NewWriterWithOpcode(packet.C_OPCODE_CANCEL_XCHG))
+}
+
+// Answer 發送 C_ATTR (opcode 121)，回覆 S_YES_NO 對話框（mode 為對話框的訊息編號）。
+func (c *Client) Answer(mode uint16, yes bool) error {
+	w := packet.NewWriterWithOpcode(packet.C_OPCODE_ATTR)
+	w.WriteH(mode)
+	if yes {
+		w.WriteC(1)
+	} else {
+		w.WriteC(0)
+	}
+	return c.Send(w)
+}
+
+// DestroyItem 發送 C_DESTROY_ITEM (opcode 138)，刪除背包物品。
+func (c *Client) DestroyItem(objectID, count int32) error {
+	w := packet.NewWriterWithOpcode(packet.C_OPCODE_DESTROY_ITEM)
+	w.WriteD(objectID)
+	w.WriteD(count)
+	return c.Send(w)
+}
+
+// Deposit 發送 C_BUY_SELL (opcode 161, resultType 2)，把一件物品存入個人倉庫。
+func (c *Client) Deposit(npcObjID, objectID, count int32) error {
+	w := packet.NewWriterWithOpcode(packet.C_OPCODE_BUY_SELL)
+	w.WriteD(npcObjID)
+	w.WriteC(2)
+	w.WriteH(1)
+	w.WriteD(objectID)
+	w.WriteD(count)
+	return c.Send(w)
+}
@@ -0,0 +1,95 @@
+package world
+
+// ActionWindowSize 是動作節奏判定的滾動視窗筆數。
+const ActionWindowSize = 8
+
+// ActionWindow 保存最近幾次動作（移動、攻擊）的時間與當時的預期間隔。
+// 以整個視窗的總時間比對預期，網路延遲造成的封包擠在一起不會被誤判。
+type ActionWindow struct {
+	at       [ActionWindowSize]int64 // UnixNano
+	expected [ActionWindowSize]int64 // 該次動作距上一次的預期間隔（ns）
+	n        int
+	head     int // 下一筆寫入位置
+}
+
+// Add 記錄一次動作。
+func (w *ActionWindow) Add(at, expected int64) {
+	w.at[w.head] = at
+	w.expected[w.head] = expected
+	w.head = (w.head + 1) % ActionWindowSize
+	if w.n < ActionWindowSize {
+		w.n++
+	}
+}
+
+// Full 回報視窗是否已記滿。
+func (w *ActionWindow) Full() bool {
+	return w.n == ActionWindowSize
+}
+
+// Span 回傳視窗內第一筆到最後一筆的實際經過時間，與這段期間的預期時間
+// （第一筆之後各筆預期間隔的總和）。
+func (w *ActionWindow) Span() (elapsed, expected int64) {
+	if w.n < 2 {
+		return 0, 0
+	}
+	first := (w.head - w.n + ActionWindowSize) % ActionWindowSize
+	last := (w.head - 1 + ActionWindowSize) % ActionWindowSize
+	for i := 1; i < w.n; i++ {
+		expected += w.expected[(first+i)%ActionWindowSize]
+	}
+	return w.at[last] - w.at[first], expected
+}
+
+// Last 回傳最近一次動作的時間（無記錄時為 0）。
+func (w *ActionWindow) Last() int64 {
+	if w.n == 0 {
+		return 0
+	}
+	return w.at[(w.head-1+ActionWindowSize)%ActionWindowSize]
+}
+
+// Reset 清空視窗。
+func (w *ActionWindow) Reset() {
+	w.n, w.head = 0, 0
+}
+
+// StrikeCounter 記錄違規時間，計算滾動視窗內的違規次數。
+type StrikeCounter struct {
+	times []int64 // UnixNano，由舊到新
+}
+
+// Add 記錄一次違規並回傳 window（ns）內的違規次數。
+func (c *StrikeCounter) Add(now, window int64) int {
+	c.times = append(c.times, now)
+	return c.Count(now, window)
+}
+
+// Count 移除視窗外的記錄並回傳視窗內的違規次數。
+func (c *StrikeCounter) Count(now, window int64) int {
+	n := 0
+	for n < len(c.times) && now-c.times[n] > window {
+		n++
+	}
+	c.times = c.times[n:]
+	return len(c.times)
+}
+
+// 傳送來源路徑（TeleportSource.Path，傳送驗證日誌與來源地圖規則用）
+const (
+	TeleportPathBookmark = "bookmark" // 書籤（傳送卷軸 / 瞬間移動術）— 來源地圖須可脫出
+	TeleportPathRandom   = "random"   // 隨機傳送（無書籤）— 來源地圖須可傳送
+	TeleportPathScroll   = "scroll"   // 回家 / 指定傳送卷軸 — 來源地圖須可脫出
+	TeleportPathNpc      = "npc"      // NPC 傳送師
+	TeleportPathQuest    = "quest"    // 任務動作 teleport_to
+	TeleportPathScript   = "script"   // Lua 腳本 game.teleport
+)
+
+// TeleportSource 描述一次傳送的來源路徑與授權者，供 anti_cheat.teleport_validation 驗證。
+// 零值表示伺服器主動的傳送（重生、GM、活動、傳送點等），不驗證。
+type TeleportSource struct {
+	Path       string
+	ItemObjID  int32 // 授權物品（須仍在背包中；0 = 無）
+	NpcObjID   int32 // 授權 NPC（須存在且在附近；0 = 無）
+	BookmarkID int32 // 授權書籤（須存在且與目的地相符；0 = 無）
+}
@@ -103,6 +103,19 @@ func (g *AOIGrid) GetNearbyInto(x, y int32, mapID int16, buf []uint64) []uint64
 	return buf
 }
 
+// markNeighbourCells adds every cell within the 3x3 neighbourhood of an
+// occupied cell to dst. An NPC whose cell is in dst has at least one
+// session in its own 3x3 neighbourhood (the relation is symmetric).
+func (g *AOIGrid) markNeighbourCells(dst map[cellKey]struct{}) {
+	for k := range g.cells {
+		for dx := int32(-1); dx <= 1; dx++ {
+			for dy := int32(-1); dy <= 1; dy++ {
+				dst[cellKey{mapID: k.mapID, cx: k.cx + dx, cy: k.cy + dy}] = struct{}{}
+			}
+		}
+	}
+}
+
 // NpcAOIGrid tracks which NPCs are in which cells.
 // Same logic as AOIGrid but keyed by int32 NPC object IDs instead of uint64 session IDs.
 // Separate type to avoid type assertions on the hot path.
@@ -24,9 +24,10 @@ type GameTime struct {
 	seconds int
 }
 
-// GameTimeNow returns the current game time derived from the system clock.
-func GameTimeNow() GameTime {
-	t1 := time.Now().UnixMilli() - baseTimeMillis
+// GameTimeAt returns the game time corresponding to the real time now
+// (normally handler.Deps.Clock.Now()).
+func GameTimeAt(now time.Time) GameTime {
+	t1 := now.UnixMilli() - baseTimeMillis
 	t2 := int((t1 * 6) / 1000)
 	t2 += int(gameTimeOffsetSec.Load())
 	t2 -= t2 % 3 // align to 3-second boundary (matches Java)
@@ -34,9 +35,9 @@ func GameTimeNow() GameTime {
 }
 
 // SetGameTimeOffset 設定遊戲時間偏移量，使當前遊戲時間跳到指定小時。
-func SetGameTimeOffset(targetHour int) {
+func SetGameTimeOffset(now time.Time, targetHour int) {
 	// 先算出無偏移的當前遊戲時間
-	t1 := time.Now().UnixMilli() - baseTimeMillis
+	t1 := now.UnixMilli() - baseTimeMillis
 	rawSec := int((t1 * 6) / 1000)
 	rawSec -= rawSec % 3
 
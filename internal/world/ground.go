@@ -30,4 +30,5 @@ type GroundItem struct {
 	OwnerID    int32 // CharID of dropper (0 = anyone can pick up)
 	TTL        int   // ticks remaining until auto-delete (0 = permanent)
 	NoExpire   bool  // true = 不自動消失（血盟小屋內物品）
+	ObjID      int32 // 原物品 ObjectID：非堆疊物品整件掉落時撿起沿用（保留 item_events 歷史）；0 = 撿起時配發新 ID
 }
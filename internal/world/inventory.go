@@ -6,12 +6,13 @@ import (
 	"sync/atomic"
 )
 
-// RandInt returns a random int in [0, n). Safe to call from game loop goroutine.
-func RandInt(n int) int {
+// RandInt returns a random int in [0, n) drawn from rng (0 when n <= 0).
+// Game logic passes handler.Deps.Rand so a seeded session can be replayed.
+func RandInt(rng *rand.Rand, n int) int {
 	if n <= 0 {
 		return 0
 	}
-	return rand.Intn(n)
+	return rng.Intn(n)
 }
 
 const (
@@ -77,12 +78,17 @@ type InvItem struct {
 	InnNpcID  int32     // 旅館 NPC 模板 ID
 	InnHall   bool      // 是否為會議室鑰匙
 	InnDueTime int64    // 租約到期時間（Unix 秒，0=非旅館鑰匙）
+
+	saved *ItemRecord // 上次存檔寫入的內容（nil=尚未存檔），見 inventory_save.go
 }
 
 // Inventory holds a player's in-memory item list.
 // Accessed only from the game loop goroutine.
 type Inventory struct {
 	Items []*InvItem
+
+	savedIDs map[int32]struct{} // 已存檔於此角色的 obj_id（用於找出刪除的物品）
+	synced   bool               // 登入後是否已成功存檔過一次
 }
 
 // NewInventory creates an empty inventory.
@@ -0,0 +1,119 @@
+package world
+
+// ItemRecord is the persisted form of one inventory item (one character_items
+// row). Comparable with ==, so it doubles as the item's last-saved snapshot.
+type ItemRecord struct {
+	ObjID            int32
+	CharID           int32
+	ItemID           int32
+	Count            int32
+	EnchantLvl       int8
+	Bless            byte
+	Equipped         bool
+	Identified       bool
+	EquipSlot        EquipSlot
+	Durability       int8
+	AttrEnchantKind  int8
+	AttrEnchantLevel int8
+	InnKeyID         int32
+	InnNpcID         int32
+	InnHall          bool
+	InnDueTime       int64
+	ChargeCount      int16
+}
+
+// InventoryChanges is what a save has to write: new and dirty items are
+// upserted by obj_id, deleted obj_ids are removed from the character's rows.
+//
+// Full is set until the inventory's first successful save after login. Rows
+// loaded from the DB may not map one-to-one onto memory (stacks merged, obj_id
+// 0 rows renumbered, unknown templates skipped), so that save upserts every
+// item and deletes every other row of the character instead.
+type InventoryChanges struct {
+	Upserts []ItemRecord
+	Deleted []int32
+	Full    bool
+	Total   int // items in the inventory
+}
+
+// record builds the item's persisted form for the given owner.
+func (it *InvItem) record(charID int32, equip *Equipment) ItemRecord {
+	rec := ItemRecord{
+		ObjID:            it.ObjectID,
+		CharID:           charID,
+		ItemID:           it.ItemID,
+		Count:            it.Count,
+		EnchantLvl:       it.EnchantLvl,
+		Bless:            it.Bless,
+		Equipped:         it.Equipped,
+		Identified:       it.Identified,
+		Durability:       it.Durability,
+		AttrEnchantKind:  it.AttrEnchantKind,
+		AttrEnchantLevel: it.AttrEnchantLevel,
+		InnKeyID:         it.InnKeyID,
+		InnNpcID:         it.InnNpcID,
+		InnHall:          it.InnHall,
+		InnDueTime:       it.InnDueTime,
+		ChargeCount:      it.ChargeCount,
+	}
+	if it.Equipped && equip != nil {
+		for s := EquipSlot(1); s < SlotMax; s++ {
+			if equip.Get(s) == it {
+				rec.EquipSlot = s
+				break
+			}
+		}
+	}
+	return rec
+}
+
+// PendingChanges classifies the inventory against its last save: items never
+// saved (new), items whose persisted fields changed (dirty), and obj_ids saved
+// for this character that are no longer carried (deleted). An item last saved
+// under another character (traded away and back) counts as changed, since its
+// row now belongs to that character.
+func (inv *Inventory) PendingChanges(charID int32, equip *Equipment) InventoryChanges {
+	c := InventoryChanges{Full: !inv.synced, Total: len(inv.Items)}
+	present := make(map[int32]struct{}, len(inv.Items))
+	for _, it := range inv.Items {
+		present[it.ObjectID] = struct{}{}
+		rec := it.record(charID, equip)
+		if c.Full || it.saved == nil || *it.saved != rec {
+			c.Upserts = append(c.Upserts, rec)
+		}
+	}
+	if !c.Full {
+		for objID := range inv.savedIDs {
+			if _, ok := present[objID]; !ok {
+				c.Deleted = append(c.Deleted, objID)
+			}
+		}
+	}
+	return c
+}
+
+// CommitSave records a successful save of c: written items take their record
+// as the new snapshot and deleted obj_ids are forgotten. Items changed after
+// PendingChanges keep differing from the snapshot and are written next time.
+func (inv *Inventory) CommitSave(c InventoryChanges) {
+	written := make(map[int32]ItemRecord, len(c.Upserts))
+	for _, rec := range c.Upserts {
+		written[rec.ObjID] = rec
+	}
+	for _, it := range inv.Items {
+		if rec, ok := written[it.ObjectID]; ok {
+			snap := rec
+			it.saved = &snap
+		}
+	}
+	if inv.savedIDs == nil || c.Full {
+		inv.savedIDs = make(map[int32]struct{}, len(c.Upserts))
+	}
+	for _, objID := range c.Deleted {
+		delete(inv.savedIDs, objID)
+	}
+	for objID := range written {
+		inv.savedIDs[objID] = struct{}{}
+	}
+	inv.synced = true
+}
@@ -0,0 +1,139 @@
+package world
+
+import (
+	"slices"
+	"testing"
+)
+
+// fillInventory 放入 n 件不可堆疊的物品。
+func fillInventory(inv *Inventory, n int) {
+	for range n {
+		inv.AddItem(20011, 1, "頭盔", 1, 100, false, 1)
+	}
+}
+
+func upsertIDs(c InventoryChanges) []int32 {
+	ids := make([]int32, len(c.Upserts))
+	for i, rec := range c.Upserts {
+		ids[i] = rec.ObjID
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func TestPendingChangesFirstSaveIsFull(t *testing.T) {
+	inv := NewInventory()
+	fillInventory(inv, 3)
+
+	c := inv.PendingChanges(1, nil)
+	if !c.Full || len(c.Upserts) != 3 || len(c.Deleted) != 0 || c.Total != 3 {
+		t.Fatalf("first save: %+v, want full with 3 upserts", c)
+	}
+	inv.CommitSave(c)
+
+	c = inv.PendingChanges(1, nil)
+	if c.Full || len(c.Upserts) != 0 || len(c.Deleted) != 0 {
+		t.Fatalf("unchanged save: %+v, want nothing written", c)
+	}
+}
+
+func TestPendingChangesIncremental(t *testing.T) {
+	inv := NewInventory()
+	fillInventory(inv, 3)
+	potion := inv.AddItem(40010, 5, "治癒藥水", 1, 10, true, 1)
+	inv.CommitSave(inv.PendingChanges(1, nil))
+
+	gone := inv.Items[0].ObjectID
+	inv.RemoveItem(gone, 1)
+	potion.Count += 5
+	added := inv.AddItem(40001, 1, "燈", 1, 10, false, 1)
+
+	c := inv.PendingChanges(1, nil)
+	if c.Full {
+		t.Fatal("second save is full")
+	}
+	if got, want := upsertIDs(c), []int32{potion.ObjectID, added.ObjectID}; !slices.Equal(got, want) {
+		t.Fatalf("upserts %v, want %v", got, want)
+	}
+	if !slices.Equal(c.Deleted, []int32{gone}) {
+		t.Fatalf("deleted %v, want [%d]", c.Deleted, gone)
+	}
+	inv.CommitSave(c)
+
+	if c = inv.PendingChanges(1, nil); len(c.Upserts) != 0 || len(c.Deleted) != 0 {
+		t.Fatalf("save after commit: %+v, want nothing written", c)
+	}
+}
+
+// 交易出去又換回來的物品，其列已屬於對方角色，回到原角色時須重新寫入。
+func TestPendingChangesTradedBack(t *testing.T) {
+	a, b := NewInventory(), NewInventory()
+	fillInventory(a, 2)
+	b.CommitSave(b.PendingChanges(2, nil))
+	a.CommitSave(a.PendingChanges(1, nil))
+
+	it := a.Items[0]
+	a.RemoveItem(it.ObjectID, 1)
+	b.Items = append(b.Items, it)
+
+	cb := b.PendingChanges(2, nil)
+	if !slices.Equal(upsertIDs(cb), []int32{it.ObjectID}) {
+		t.Fatalf("receiver upserts %v, want [%d]", upsertIDs(cb), it.ObjectID)
+	}
+	b.CommitSave(cb)
+	ca := a.PendingChanges(1, nil)
+	if len(ca.Upserts) != 0 || !slices.Equal(ca.Deleted, []int32{it.ObjectID}) {
+		t.Fatalf("giver changes %+v, want only delete %d", ca, it.ObjectID)
+	}
+	a.CommitSave(ca)
+
+	b.RemoveItem(it.ObjectID, 1)
+	a.Items = append(a.Items, it)
+	if ca = a.PendingChanges(1, nil); !slices.Equal(upsertIDs(ca), []int32{it.ObjectID}) {
+		t.Fatalf("traded back: upserts %v, want [%d]", upsertIDs(ca), it.ObjectID)
+	}
+}
+
+// PendingChanges 之後、CommitSave 之前的變更不可被視為已存檔。
+func TestCommitSaveKeepsLaterChanges(t *testing.T) {
+	inv := NewInventory()
+	potion := inv.AddItem(40010, 5, "治癒藥水", 1, 10, true, 1)
+	c := inv.PendingChanges(1, nil)
+	potion.Count = 3
+	inv.CommitSave(c)
+
+	c = inv.PendingChanges(1, nil)
+	if len(c.Upserts) != 1 || c.Upserts[0].Count != 3 {
+		t.Fatalf("changes %+v, want potion rewritten with count 3", c)
+	}
+}
+
+// BenchmarkInventorySave 比較 180 件物品的背包完整存檔與只改一件的增量存檔；
+// rows/op 為送往資料庫的列數（寫入 + 刪除），存檔耗時主要取決於此。
+func BenchmarkInventorySave(b *testing.B) {
+	const items = 180
+	run := func(b *testing.B, full bool) {
+		inv := NewInventory()
+		fillInventory(inv, items)
+		inv.CommitSave(inv.PendingChanges(1, nil))
+		var rows int
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := range b.N {
+			if full {
+				inv.synced = false
+			}
+			inv.Items[i%items].Durability ^= 1
+			c := inv.PendingChanges(1, nil)
+			rows += len(c.Upserts) + len(c.Deleted)
+			if c.Full {
+				// 完整存檔另以一道 DELETE 清除背包以外的列
+				rows++
+			}
+			inv.CommitSave(c)
+		}
+		b.ReportMetric(float64(rows)/float64(b.N), "rows/op")
+	}
+	b.Run("full", func(b *testing.B) { run(b, true) })
+	b.Run("incremental", func(b *testing.B) { run(b, false) })
+}
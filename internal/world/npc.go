@@ -77,6 +77,13 @@ type NpcInfo struct {
 	WanderDir    int16 // current wander heading (0-7)
 	WanderTimer  int   // ticks until next wander step
 
+	// A* 尋路快取（追擊/回家，system/pathfind.go）
+	Path PathCache
+
+	// AI 休眠：所在 AOI 鄰域無玩家時跳過 AI（system/npc_ai.go）
+	Dormant      bool
+	DormantTicks int // 本次休眠已經過的 tick 數（喚醒時補算計時器）
+
 	// 負面狀態（debuff）
 	Paralyzed     bool           // 麻痺/凍結/暈眩 — 跳過所有 AI 行為
 	Sleeped       bool           // 睡眠 — 跳過所有 AI 行為，受傷時解除
@@ -109,6 +116,26 @@ type NpcInfo struct {
 	IsMinion     bool          // true=隊員（不獨立重生）
 }
 
+// PathStep 是路徑上的一格。
+type PathStep struct {
+	X, Y int32
+}
+
+// PathCache 保存一次 A* 尋路的結果，供後續移動逐格取用。
+// 目標移動超過門檻、下一格被擋住或換地圖時由 system 重新計算。
+type PathCache struct {
+	Steps        []PathStep // 剩餘路徑（不含目前位置）
+	GoalX, GoalY int32      // 計算路徑時的目標座標
+	MapID        int16
+	Retry        int // 尋路失敗後，接下來幾次移動改用貪婪步進（避免每步重搜）
+}
+
+// Clear 丟棄快取路徑（瞬移、重生後呼叫）。
+func (c *PathCache) Clear() {
+	c.Steps = c.Steps[:0]
+	c.Retry = 0
+}
+
 // MobGroupInfo 怪物群體運行時狀態。
 // Java: L1MobGroupInfo — 記錄群體成員、隊長、解散規則。
 type MobGroupInfo struct {
@@ -0,0 +1,42 @@
+package world
+
+// NpcActivity 是單一地圖上 NPC AI 的活躍/休眠數量（NpcAISystem 每 tick 重新統計）。
+type NpcActivity struct {
+	Active   int
+	Sleeping int
+}
+
+// RefreshAwakeCells 依目前玩家位置重建「清醒格子」集合：
+// 有玩家的 AOI 格子及其 3x3 鄰格。每 tick 於 NPC AI 前呼叫一次，
+// 成本與玩家所在格子數成正比，而非 NPC 數量。
+func (s *State) RefreshAwakeCells() {
+	clear(s.awakeCells)
+	s.aoi.markNeighbourCells(s.awakeCells)
+}
+
+// IsAwakeAt 回傳該座標的 NPC 是否應執行 AI（其 AOI 鄰域內有玩家）。
+func (s *State) IsAwakeAt(x, y int32, mapID int16) bool {
+	_, ok := s.awakeCells[cellKey{mapID: mapID, cx: toCellCoord(x), cy: toCellCoord(y)}]
+	return ok
+}
+
+// ResetNpcActivity 清空各地圖的活躍/休眠計數（每 tick 統計前呼叫）。
+func (s *State) ResetNpcActivity() {
+	clear(s.npcActivity)
+}
+
+// CountNpcActivity 累計一隻 NPC 的活躍/休眠狀態。
+func (s *State) CountNpcActivity(mapID int16, awake bool) {
+	a := s.npcActivity[mapID]
+	if awake {
+		a.Active++
+	} else {
+		a.Sleeping++
+	}
+	s.npcActivity[mapID] = a
+}
+
+// NpcActivityByMap 回傳最近一次統計的各地圖計數（唯讀，勿保存引用）。
+func (s *State) NpcActivityByMap() map[int16]NpcActivity {
+	return s.npcActivity
+}
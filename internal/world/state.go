@@ -115,7 +115,14 @@ type PlayerInfo struct {
 	AbsoluteBarrier  bool // 絕對屏障（skill 78）— 免疫所有傷害，攻擊/施法/使用道具時解除
 	AttackView       bool // 浮動傷害數字開關（Java: is_attack_view，預設 true，聊天輸入 dmg 切換）
 
-	LastMoveTime int64 // time.Now().UnixNano() of last accepted move (0 = no throttle)
+	LastMoveTime  int64                   // time.Now().UnixNano() of last move (0 = reset speed validation, e.g. after teleport)
+	MoveWindow    ActionWindow            // 最近幾步的時間與預期間隔（移動加速偵測）
+	SpeedStrikes  StrikeCounter           // 移動加速違規記錄（滾動視窗）
+	AttackWindow  ActionWindow            // 最近幾次攻擊的時間與預期間隔（攻速偵測）
+	AttackStrikes StrikeCounter           // 攻速違規記錄
+	CastWindow    ActionWindow            // 最近幾次施法的時間與預期間隔（施法動畫速度偵測，所有技能共用）
+	CastStrikes   StrikeCounter           // 施法速度違規記錄
+	SkillWindows  map[int32]*ActionWindow // 各技能最近幾次施放（技能冷卻偵測，key = 技能 ID，首次施放時建立）
 
 	TempCharGfx int32 // 0=use ClassID; >0=current polymorph GFX sprite
 	PolyID      int32 // current polymorph poly_id (for equip/skill checks; 0=not polymorphed)
@@ -144,6 +151,8 @@ type PlayerInfo struct {
 	ScrollTPX    int32
 	ScrollTPY    int32
 	ScrollTPMap  int16
+	ScrollTPSrc  TeleportSource // 傳送來源（到時由 ScrollTeleportSystem 一併驗證）
+	ScrollTPFee  int32          // NPC 傳送費用（金幣），傳送成功後才扣除
 
 	// Teleport bookmarks
 	Bookmarks []Bookmark
@@ -297,6 +306,7 @@ type WarehouseCache struct {
 	Name       string
 	InvGfx     int32
 	Weight     int32
+	ObjID      int32 // warehouse_items.obj_id：非堆疊物品存入前的 ObjectID（領出時沿用）
 }
 
 // PrivateShopSell 個人商店出售清單項目。
@@ -582,21 +592,25 @@ type State struct {
 	// 可重用 AOI 查詢 buffer（遊戲迴圈單線程，無需鎖）
 	aoiBuf    []uint64
 	npcAoiBuf []int32
+
+	// NPC AI 休眠（npc_sleep.go）：有玩家的 AOI 格子及其 3x3 鄰格
+	awakeCells  map[cellKey]struct{}
+	npcActivity map[int16]NpcActivity
 }
 
 // RandomizeWeather picks a random weather with weighted distribution.
 // Java defaults weather to 4 (clear) and never auto-changes. In Go, we add
 // some variety but keep clear weather dominant (~60%) to avoid constant rain/snow.
 // Valid values: 0=clear, 1-3=snow, 17-19=rain (Java confirms 17-19, not 16).
-func (s *State) RandomizeWeather() {
-	roll := rand.Intn(10) // 0-9
+func (s *State) RandomizeWeather(rng *rand.Rand) {
+	roll := rng.Intn(10) // 0-9
 	switch {
 	case roll < 6: // 60% clear
 		s.Weather = 0
 	case roll < 8: // 20% snow (light)
-		s.Weather = byte(1 + rand.Intn(3)) // 1, 2, or 3
+		s.Weather = byte(1 + rng.Intn(3)) // 1, 2, or 3
 	default: // 20% rain (light)
-		s.Weather = byte(17 + rand.Intn(3)) // 17, 18, or 19
+		s.Weather = byte(17 + rng.Intn(3)) // 17, 18, or 19
 	}
 }
 
@@ -619,6 +633,8 @@ func NewState() *State {
 		followers:   make(map[int32]*FollowerInfo),
 		groundItems:   make(map[int32]*GroundItem),
 		furnitureNpcs: make(map[int32]int32),
+		awakeCells:    make(map[cellKey]struct{}),
+		npcActivity:   make(map[int16]NpcActivity),
 		LastHour:    -1,
 	}
 }
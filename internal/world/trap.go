@@ -39,6 +39,7 @@ type TrapManager struct {
 	allTraps       []*TrapInstance                 // 所有陷阱實例
 	pendingRespawn []trapRespawnEntry              // 待重生佇列
 	mapChecker     MapPassableChecker              // 地圖通行性檢查（可選）
+	rng            *rand.Rand                      // 隨機座標來源
 }
 
 // MapPassableChecker 地圖通行性檢查介面（用於隨機座標驗證）。
@@ -48,10 +49,11 @@ type MapPassableChecker interface {
 }
 
 // NewTrapManager 從陷阱資料建立管理器，生成所有陷阱實例。
-func NewTrapManager(trapData *data.TrapData, checker MapPassableChecker) *TrapManager {
+func NewTrapManager(trapData *data.TrapData, checker MapPassableChecker, rng *rand.Rand) *TrapManager {
 	mgr := &TrapManager{
 		byTile:     make(map[trapTileKey][]*TrapInstance),
 		mapChecker: checker,
+		rng:        rng,
 	}
 
 	for i := range trapData.Spawns {
@@ -104,8 +106,8 @@ func (mgr *TrapManager) randomizePosition(inst *TrapInstance) {
 
 	// 嘗試 50 次找到可通行座標（與 Java 一致）
 	for i := 0; i < 50; i++ {
-		dx := rand.Int31n(sp.RndX+1) * int32(randomSign())
-		dy := rand.Int31n(sp.RndY+1) * int32(randomSign())
+		dx := mgr.rng.Int31n(sp.RndX+1) * int32(mgr.randomSign())
+		dy := mgr.rng.Int31n(sp.RndY+1) * int32(mgr.randomSign())
 		nx := sp.X + dx
 		ny := sp.Y + dy
 
@@ -128,8 +130,8 @@ func (mgr *TrapManager) randomizePosition(inst *TrapInstance) {
 }
 
 // randomSign 隨機回傳 +1 或 -1。
-func randomSign() int {
-	if rand.Intn(2) == 0 {
+func (mgr *TrapManager) randomSign() int {
+	if mgr.rng.Intn(2) == 0 {
 		return 1
 	}
 	return -1
@@ -175,14 +177,14 @@ func (mgr *TrapManager) GetTrapsAt(x, y int32, mapID int16) []*TrapInstance {
 }
 
 // DisableTrap 觸發後停用陷阱 + 排入重生佇列。
-func (mgr *TrapManager) DisableTrap(inst *TrapInstance) {
+func (mgr *TrapManager) DisableTrap(inst *TrapInstance, now time.Time) {
 	inst.Alive = false
 	mgr.unregisterTile(inst)
 
 	if inst.SpanSec > 0 {
 		mgr.pendingRespawn = append(mgr.pendingRespawn, trapRespawnEntry{
 			Trap:      inst,
-			RespawnAt: time.Now().Add(time.Duration(inst.SpanSec) * time.Second),
+			RespawnAt: now.Add(time.Duration(inst.SpanSec) * time.Second),
 		})
 	}
 }
@@ -2,6 +2,10 @@
 -- Called once per tick per alive L1Monster NPC
 -- Receives AIContext table, returns array of AICommand tables
 --
+-- Templates can pick another profile with `ai: <name>` in npc_list.yaml; the
+-- profile is AI_PROFILES[name](ctx, state) (see dragon_boss.lua). Unknown
+-- profiles fall back to npc_ai. state is a per-NPC table kept across ticks.
+--
 -- Command types:
 --   "attack"         - melee attack current target
 --   "ranged_attack"  - ranged attack current target
@@ -0,0 +1,82 @@
+-- Dragon boss AI profile (npc_list.yaml: ai: dragon_boss)
+-- Receives (ctx, state); state is a per-NPC table kept across ticks and
+-- cleared on respawn.
+--
+-- Phases by HP:
+--   1 (> 70%)  - normal fight, mob skills at their configured rates
+--   2 (<= 70%) - skills on every attack opportunity, never repeats the last skill
+--   3 (<= 30%) - also ignores leash distance (keeps chasing its target)
+
+AI_PROFILES.dragon_boss = function(ctx, state)
+    local phase = dragon_phase(ctx)
+    if phase ~= state.phase then
+        state.phase = phase
+        state.phase_ticks = 0
+    end
+    state.phase_ticks = (state.phase_ticks or 0) + 1
+
+    if ctx.target_id == 0 then
+        -- Out of combat: stay near the lair
+        if ctx.spawn_dist > 0 and ctx.can_move then
+            return {{ type = "wander", dir = -2 }}
+        end
+        return {{ type = "idle" }}
+    end
+
+    if phase >= 2 and ctx.can_attack then
+        local cmd = dragon_pick_skill(ctx, state)
+        if cmd then
+            state.last_skill = cmd.skill_id
+            return { cmd }
+        end
+    end
+
+    if phase == 3 and ctx.target_dist > 15 then
+        if ctx.can_move then
+            return {{ type = "move_toward" }}
+        end
+        return {{ type = "idle" }}
+    end
+
+    local cmds = ai_with_target(ctx)
+    for _, cmd in ipairs(cmds) do
+        if cmd.type == "skill" then
+            state.last_skill = cmd.skill_id
+        end
+    end
+    return cmds
+end
+
+function dragon_phase(ctx)
+    if ctx.max_hp <= 0 then
+        return 1
+    end
+    local hp_pct = ctx.hp * 100 / ctx.max_hp
+    if hp_pct <= 30 then
+        return 3
+    elseif hp_pct <= 70 then
+        return 2
+    end
+    return 1
+end
+
+-- Pick a usable skill in range, skipping the one used last time.
+function dragon_pick_skill(ctx, state)
+    for _, sk in ipairs(ctx.skills or {}) do
+        local range = math.abs(sk.trigger_range)
+        if sk.skill_id ~= state.last_skill
+            and sk.type ~= 3
+            and (sk.mp_consume == 0 or sk.mp_consume <= ctx.mp)
+            and (range == 0 or ctx.target_dist <= range) then
+            return {
+                type = "skill",
+                skill_id = sk.skill_id,
+                act_id = sk.act_id,
+                gfx_id = sk.gfx_id,
+                leverage = sk.leverage or 0,
+                change_target = sk.change_target or 0,
+            }
+        end
+    end
+    return nil
+end
@@ -0,0 +1,28 @@
+-- World event hooks
+-- Optional globals called by the engine; leave undefined to skip.
+--
+-- The `game` module (also available via require("game")) exposes world actions:
+--   game.message(char_id, text)                         -> bool
+--   game.give_item(char_id, item_id [, count [, enchant]]) -> bool
+--   game.take_item(char_id, item_id [, count])          -> bool (no change if short)
+--   game.count_item(char_id, item_id)                   -> int
+--   game.spawn_npc(npc_id, x, y, map_id)                -> npc object id (0 = failed)
+--   game.teleport(char_id, x, y, map_id)                -> bool
+--   game.quest_step(char_id, quest_id)                  -> int (0 = not started)
+--   game.set_quest_step(char_id, quest_id, step)        -> bool (persisted)
+--   game.find_player(name)                              -> char_id (0 = offline)
+--   game.player_pos(char_id)                            -> x, y, map_id (nil if offline)
+--
+-- All calls act on online players only and return false/0 otherwise.
+
+-- on_npc_killed(ev) is called after an NPC dies.
+-- ev fields: npc_id (template), npc_obj_id, killer_id (char id, 0 = none),
+--            map_id, x, y
+--
+-- Example:
+-- function on_npc_killed(ev)
+--     if ev.npc_id == 45955 and ev.killer_id > 0 then
+--         game.message(ev.killer_id, "The ground trembles...")
+--         game.spawn_npc(45956, ev.x, ev.y, ev.map_id)
+--     end
+-- end